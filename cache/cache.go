@@ -0,0 +1,123 @@
+// Package cache defines a byte-oriented Cache interface with in-memory,
+// Redis and layered implementations. GetOrLoad collapses concurrent misses
+// for the same key into a single load, so an expired hot key does not send a
+// stampede of requests to the database or upstream API.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("cache")
+
+// ErrNotFound is returned by Get when the key is absent or expired
+var ErrNotFound = errors.New("cache: key not found")
+
+// Loader produces the value for a missing key
+type Loader func(ctx context.Context) ([]byte, error)
+
+// Cache stores byte values under string keys. A ttl of 0 means the entry
+// does not expire (it may still be evicted).
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// GetOrLoad returns the cached value or calls load, stores the result
+	// with ttl and returns it. Concurrent callers for one key share a load.
+	GetOrLoad(ctx context.Context, key string, ttl time.Duration, load Loader) ([]byte, error)
+}
+
+// Counter is implemented by caches that can increment a number atomically,
+// as rate limiters need. ttl is applied when Increment creates the key.
+type Counter interface {
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// getOrLoad implements GetOrLoad for any Cache. Read errors other than a
+// miss are logged and treated as misses, so a broken cache degrades to
+// loading from the source instead of failing requests.
+func getOrLoad(ctx context.Context, c Cache, group *Group[[]byte], key string, ttl time.Duration, load Loader) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		logger.Warn("Cache read failed, loading from source", "key", key, "error", err)
+	}
+
+	value, err, _ = group.Do(key, func() ([]byte, error) {
+		// The load runs with the first caller's context; a caller that
+		// joins later shares its result and its cancellation
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(ctx, key, value, ttl); err != nil {
+			logger.Warn("Cache write failed", "key", key, "error", err)
+		}
+		return value, nil
+	})
+	return value, err
+}
+
+// Typed stores values of type T in a Cache using a codec from the encoding
+// package
+type Typed[T any] struct {
+	cache Cache
+	codec encoding.Codec
+}
+
+// NewTyped wraps c; codec defaults to JSON when nil
+func NewTyped[T any](c Cache, codec encoding.Codec) *Typed[T] {
+	if codec == nil {
+		codec = encoding.JSON
+	}
+	return &Typed[T]{cache: c, codec: codec}
+}
+
+// Get decodes the cached value for key
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	data, err := t.cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	err = t.codec.Unmarshal(data, &value)
+	return value, err
+}
+
+// Set encodes and stores value
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.cache.Set(ctx, key, data, ttl)
+}
+
+// Delete removes key
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, key)
+}
+
+// GetOrLoad is Cache.GetOrLoad for typed values
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	data, err := t.cache.GetOrLoad(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return t.codec.Marshal(loaded)
+	})
+	if err != nil {
+		return value, err
+	}
+	err = t.codec.Unmarshal(data, &value)
+	return value, err
+}
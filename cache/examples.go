@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// MemoryExamples demonstrates TTLs, eviction and typed values
+func MemoryExamples() {
+	fmt.Println("\n=== In-Memory LRU Cache ===")
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mem := NewMemory(MemoryOptions{MaxEntries: 2, Now: func() time.Time { return now }})
+
+	mem.Set(ctx, "a", []byte("alpha"), time.Minute)
+	mem.Set(ctx, "b", []byte("beta"), 0)
+	value, _ := mem.Get(ctx, "a")
+	fmt.Printf("📥 a = %s\n", value)
+
+	mem.Set(ctx, "c", []byte("gamma"), 0) // evicts b, the least recently used
+	if _, err := mem.Get(ctx, "b"); errors.Is(err, ErrNotFound) {
+		fmt.Println("🗑️  b was evicted to make room for c")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := mem.Get(ctx, "a"); errors.Is(err, ErrNotFound) {
+		fmt.Println("⏰ a expired after its 1m TTL")
+	}
+	stats := mem.Stats()
+	fmt.Printf("📊 hits=%d misses=%d evictions=%d expirations=%d\n", stats.Hits, stats.Misses, stats.Evictions, stats.Expirations)
+
+	type Profile struct {
+		Name  string   `json:"name"`
+		Roles []string `json:"roles"`
+	}
+	profiles := NewTyped[Profile](mem, nil)
+	profiles.Set(ctx, "profile:1", Profile{Name: "Alice", Roles: []string{"admin"}}, 0)
+	profile, err := profiles.Get(ctx, "profile:1")
+	fmt.Printf("👤 typed value: %s (err: %v)\n", format.Dump(profile, format.DefaultDumpOptions), err)
+}
+
+// StampedeExamples shows GetOrLoad collapsing concurrent misses
+func StampedeExamples() {
+	fmt.Println("\n=== Stampede Protection ===")
+	ctx := context.Background()
+	mem := NewMemory(MemoryOptions{})
+
+	var loads atomic.Int32
+	slowQuery := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return []byte("expensive result"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mem.GetOrLoad(ctx, "report", time.Minute, slowQuery)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("🐘 100 concurrent GetOrLoad calls ran the loader %d time(s)\n", loads.Load())
+
+	value, _ := mem.GetOrLoad(ctx, "report", time.Minute, slowQuery)
+	fmt.Printf("✅ later call served from cache: %q (loads still %d)\n", value, loads.Load())
+
+	_, err := mem.GetOrLoad(ctx, "broken", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return nil, errors.New("database unavailable")
+	})
+	_, cached := mem.Get(ctx, "broken")
+	fmt.Printf("❌ failed load returns %q and is not cached: %t\n", err, errors.Is(cached, ErrNotFound))
+}
+
+// LayeredExamples demonstrates an L1 memory cache in front of Redis, or in
+// front of a second memory cache when no Redis server is reachable
+func LayeredExamples() {
+	fmt.Println("\n=== Layered Cache (L1 memory, L2 Redis) ===")
+	ctx := context.Background()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	var l2 Cache
+	redis := NewRedis(RedisOptions{Addr: addr, Prefix: "gopractice:", DialTimeout: 500 * time.Millisecond})
+	defer redis.Close()
+	pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+	err := redis.Ping(pingCtx)
+	cancel()
+	if err != nil {
+		fmt.Printf("⚠️  Redis at %s unavailable (%v); using memory as L2\n", addr, err)
+		l2 = NewMemory(MemoryOptions{})
+	} else {
+		fmt.Printf("🔗 Connected to Redis at %s\n", addr)
+		l2 = redis
+	}
+
+	layered := NewLayered(NewMemory(MemoryOptions{MaxEntries: 1000}), l2, 10*time.Second)
+	layered.Set(ctx, "greeting", []byte("hello"), time.Minute)
+	layered.L1.Delete(ctx, "greeting") // as if another process had started empty
+
+	for i := 0; i < 3; i++ {
+		value, _ := layered.Get(ctx, "greeting")
+		fmt.Printf("📥 greeting = %s\n", value)
+	}
+	layered.Get(ctx, "missing")
+	stats := layered.Stats()
+	fmt.Printf("📊 L1 hits=%d L2 hits=%d misses=%d\n", stats.L1Hits, stats.L2Hits, stats.Misses)
+
+	for i := 0; i < 3; i++ {
+		n, err := layered.Increment(ctx, "visits", 1, time.Minute)
+		if err != nil {
+			fmt.Printf("❌ increment failed: %v\n", err)
+			break
+		}
+		fmt.Printf("🔢 visits = %d\n", n)
+	}
+	layered.Delete(ctx, "greeting")
+	layered.Delete(ctx, "visits")
+}
+
+// RunAllCacheExamples runs all cache examples
+func RunAllCacheExamples() {
+	MemoryExamples()
+	StampedeExamples()
+	LayeredExamples()
+}
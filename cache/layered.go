@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Layered checks a fast local cache (L1) before a shared one (L2), e.g.
+// Memory in front of Redis. L1 entries live at most L1TTL, which bounds how
+// long another process's Delete can go unnoticed.
+type Layered struct {
+	L1, L2 Cache
+	L1TTL  time.Duration
+
+	group                  Group[[]byte]
+	l1Hits, l2Hits, misses atomic.Int64
+}
+
+// LayeredStats counts where Get found its values
+type LayeredStats struct {
+	L1Hits, L2Hits, Misses int64
+}
+
+// NewLayered combines l1 and l2; l1TTL defaults to one minute
+func NewLayered(l1, l2 Cache, l1TTL time.Duration) *Layered {
+	if l1TTL <= 0 {
+		l1TTL = time.Minute
+	}
+	return &Layered{L1: l1, L2: l2, L1TTL: l1TTL}
+}
+
+// Get reads L1, then L2, copying L2 hits into L1
+func (l *Layered) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := l.L1.Get(ctx, key); err == nil {
+		l.l1Hits.Add(1)
+		return value, nil
+	}
+	value, err := l.L2.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			l.misses.Add(1)
+		}
+		return nil, err
+	}
+	l.l2Hits.Add(1)
+	l.L1.Set(ctx, key, value, l.L1TTL)
+	return value, nil
+}
+
+// Set writes L2 first so L1 never holds a value L2 rejected
+func (l *Layered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := l.L2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return l.L1.Set(ctx, key, value, l.l1TTL(ttl))
+}
+
+// Delete removes key from both layers
+func (l *Layered) Delete(ctx context.Context, key string) error {
+	return errors.Join(l.L1.Delete(ctx, key), l.L2.Delete(ctx, key))
+}
+
+// GetOrLoad implements Cache
+func (l *Layered) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load Loader) ([]byte, error) {
+	return getOrLoad(ctx, l, &l.group, key, ttl, load)
+}
+
+// Increment delegates to L2, since counters must be shared to be useful
+func (l *Layered) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	counter, ok := l.L2.(Counter)
+	if !ok {
+		return 0, fmt.Errorf("cache: %T does not support counters", l.L2)
+	}
+	return counter.Increment(ctx, key, delta, ttl)
+}
+
+// Stats returns the hit counters
+func (l *Layered) Stats() LayeredStats {
+	return LayeredStats{L1Hits: l.l1Hits.Load(), L2Hits: l.l2Hits.Load(), Misses: l.misses.Load()}
+}
+
+func (l *Layered) l1TTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < l.L1TTL {
+		return ttl
+	}
+	return l.L1TTL
+}
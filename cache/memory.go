@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// MemoryOptions limits an in-process cache
+type MemoryOptions struct {
+	MaxEntries int              // 0 means unlimited
+	MaxBytes   int64            // key plus value bytes; 0 means unlimited
+	Now        func() time.Time // clock, defaults to time.Now
+}
+
+// Memory is an in-process LRU cache backed by data_structure.Cache
+type Memory struct {
+	lru     *data_structure.Cache[string, []byte]
+	now     func() time.Time
+	group   Group[[]byte]
+	counter sync.Mutex // serializes Increment's read-modify-write
+}
+
+// NewMemory creates an LRU cache
+func NewMemory(opts MemoryOptions) *Memory {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Memory{
+		lru: data_structure.NewLRUCache(data_structure.CacheOptions[string, []byte]{
+			MaxEntries: opts.MaxEntries,
+			MaxBytes:   opts.MaxBytes,
+			SizeOf:     func(key string, value []byte) int64 { return int64(len(key) + len(value)) },
+			Now:        opts.Now,
+		}),
+		now: opts.Now,
+	}
+}
+
+// Get returns a copy of the value for key
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	value, ok := m.lru.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Set stores a copy of value
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lru.SetWithTTL(key, append([]byte(nil), value...), ttl)
+	return nil
+}
+
+// Delete removes key
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.lru.Delete(key)
+	return nil
+}
+
+// GetOrLoad implements Cache
+func (m *Memory) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load Loader) ([]byte, error) {
+	return getOrLoad(ctx, m, &m.group, key, ttl, load)
+}
+
+// Increment adds delta to the decimal counter stored under key, keeping the
+// expiry set when it was created
+func (m *Memory) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.counter.Lock()
+	defer m.counter.Unlock()
+
+	var n int64
+	if value, ok := m.lru.Get(key); ok {
+		var err error
+		if n, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+			return 0, err
+		}
+		if expires, ok := m.lru.Expiry(key); ok && !expires.IsZero() {
+			ttl = max(expires.Sub(m.now()), time.Nanosecond)
+		} else {
+			ttl = 0
+		}
+	}
+	n += delta
+	m.lru.SetWithTTL(key, []byte(strconv.FormatInt(n, 10)), ttl)
+	return n, nil
+}
+
+// Stats returns hit, miss and eviction counters
+func (m *Memory) Stats() data_structure.CacheStats {
+	return m.lru.Stats()
+}
@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisOptions configures a Redis connection pool
+type RedisOptions struct {
+	Addr     string // host:port, defaults to localhost:6379
+	Password string
+	DB       int
+	// Prefix is prepended to every key so several apps can share a server
+	Prefix      string
+	PoolSize    int           // idle connections kept, defaults to 10
+	DialTimeout time.Duration // defaults to 5s
+	// Timeout bounds each command when ctx has no deadline, defaults to 3s
+	Timeout time.Duration
+}
+
+// Redis is a Cache backed by a Redis server. It speaks RESP2 directly over
+// TCP and supports the handful of commands the cache needs.
+type Redis struct {
+	opts  RedisOptions
+	idle  chan *redisConn
+	group Group[[]byte]
+}
+
+// RedisError is an error reply from the server, e.g. "WRONGTYPE ..."
+type RedisError string
+
+func (e RedisError) Error() string { return "redis: " + string(e) }
+
+// NewRedis creates a client; connections are opened on first use
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 3 * time.Second
+	}
+	return &Redis{opts: opts, idle: make(chan *redisConn, opts.PoolSize)}
+}
+
+// ParseRedisURL reads options from a URL such as
+// redis://:password@host:6379/2, where the path selects the database
+func ParseRedisURL(rawURL string) (RedisOptions, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RedisOptions{}, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return RedisOptions{}, fmt.Errorf("invalid redis URL: scheme must be redis, not %q", u.Scheme)
+	}
+	opts := RedisOptions{Addr: u.Host}
+	if u.Port() == "" && u.Hostname() != "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		opts.Password = password
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return RedisOptions{}, fmt.Errorf("invalid redis URL: database %q is not a number", db)
+		}
+	}
+	return opts, nil
+}
+
+// Ping checks that the server is reachable
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+// Get implements Cache
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", r.opts.Prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, nil
+}
+
+// Set implements Cache; the TTL is stored with millisecond precision
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", r.opts.Prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", max(ttl.Milliseconds(), 1))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Delete implements Cache
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", r.opts.Prefix+key)
+	return err
+}
+
+// GetOrLoad implements Cache. Loads are deduplicated within this process;
+// other processes sharing the server may still load the same key.
+func (r *Redis) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load Loader) ([]byte, error) {
+	return getOrLoad(ctx, r, &r.group, key, ttl, load)
+}
+
+// incrementScript increments and, for a new key, sets the expiry in one
+// atomic step
+const incrementScript = `local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if n == tonumber(ARGV[1]) and tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n`
+
+// Increment implements Counter
+func (r *Redis) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	reply, err := r.do(ctx, "EVAL", incrementScript, 1, r.opts.Prefix+key, delta, ttl.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCRBY reply %T", reply)
+	}
+	return n, nil
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Do sends a raw command, e.g. Do(ctx, "XADD", "events", "*", "k", "v"), and
+// returns its reply: []byte for bulk and simple strings, int64, nil, or []any.
+// Keys are passed through unchanged; Prefix is not applied.
+func (r *Redis) Do(ctx context.Context, args ...any) (any, error) {
+	return r.do(ctx, args...)
+}
+
+// do sends one command and reads its reply; error replies are returned as
+// a RedisError
+func (r *Redis) do(ctx context.Context, args ...any) (any, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(r.opts.Timeout)
+	}
+	conn.SetDeadline(deadline)
+
+	reply, err := conn.roundTrip(args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The stream may be out of sync; do not reuse the connection
+		conn.Close()
+		return nil, err
+	}
+	r.release(conn)
+	return reply, err
+}
+
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.opts.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, r: bufio.NewReader(netConn), w: bufio.NewWriter(netConn)}
+	conn.SetDeadline(time.Now().Add(r.opts.Timeout))
+
+	if r.opts.Password != "" {
+		if _, err := conn.roundTrip([]any{"AUTH", r.opts.Password}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.opts.DB != 0 {
+		if _, err := conn.roundTrip([]any{"SELECT", r.opts.DB}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (r *Redis) release(conn *redisConn) {
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (c *redisConn) roundTrip(args []any) (any, error) {
+	// Commands are arrays of bulk strings: *<n>\r\n$<len>\r\n<arg>\r\n...
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			return nil, fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+		fmt.Fprintf(c.w, "$%d\r\n", len(b))
+		c.w.Write(b)
+		c.w.WriteString("\r\n")
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return readReply(c.r)
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return []byte(body), nil
+	case '-':
+		return nil, RedisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				var redisErr RedisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				items[i] = redisErr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Group deduplicates concurrent calls: while a call for a key is running,
+// further calls for that key wait for it and receive the same result
+type Group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+type call[V any] struct {
+	done   chan struct{}
+	value  V
+	err    error
+	shared int
+}
+
+// Do runs fn once per key at a time. shared reports whether the result was
+// handed to more than one caller.
+func (g *Group[V]) Do(key string, fn func() (V, error)) (value V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[V])
+	}
+	if c, ok := g.calls[key]; ok {
+		c.shared++
+		g.mu.Unlock()
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		// A panic in fn is reported to waiters as an error and re-raised here
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("cache: load for %q panicked: %v", key, r)
+			g.finish(key, c)
+			panic(r)
+		}
+		g.finish(key, c)
+	}()
+	c.value, c.err = fn()
+
+	g.mu.Lock()
+	shared = c.shared > 0
+	g.mu.Unlock()
+	return c.value, c.err, shared
+}
+
+func (g *Group[V]) finish(key string, c *call[V]) {
+	g.mu.Lock()
+	// After Forget the key may belong to a newer call
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// Forget makes the next Do for key start a new call even if one is running
+func (g *Group[V]) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
@@ -0,0 +1,337 @@
+// Package cli dispatches subcommands such as "gopractice net tcp-server
+// --port 9000" with flags shared down the command tree, generated help and
+// shell completion.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Command is a node in the command tree. A command with subcommands may also
+// have Run, which handles arguments that do not name a subcommand.
+type Command struct {
+	Name    string
+	Aliases []string
+	// Usage is the argument synopsis shown after the command path, e.g. "<file>"
+	Usage string
+	Short string
+	Long  string
+	// Flags defines the command's flags; they are inherited by subcommands,
+	// so a name may be defined only once along any path
+	Flags func(fs *flag.FlagSet)
+	// ValidArgs are offered by shell completion for positional arguments
+	ValidArgs []string
+	Run       func(c *Context) error
+	Hidden    bool
+
+	parent      *Command
+	subcommands []*Command
+}
+
+// AddCommand attaches subcommands and returns c for chaining
+func (c *Command) AddCommand(subs ...*Command) *Command {
+	for _, sub := range subs {
+		sub.parent = c
+		c.subcommands = append(c.subcommands, sub)
+	}
+	return c
+}
+
+// Subcommands returns the visible subcommands
+func (c *Command) Subcommands() []*Command {
+	var visible []*Command
+	for _, sub := range c.subcommands {
+		if !sub.Hidden {
+			visible = append(visible, sub)
+		}
+	}
+	return visible
+}
+
+// Find returns the subcommand called name or one of its aliases
+func (c *Command) Find(name string) *Command {
+	for _, sub := range c.subcommands {
+		if sub.Name == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// Path returns the full command line prefix, e.g. "gopractice net tcp-server"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// lineage returns the commands from the root down to c
+func (c *Command) lineage() []*Command {
+	if c.parent == nil {
+		return []*Command{c}
+	}
+	return append(c.parent.lineage(), c)
+}
+
+// Context carries the parsed invocation into Command.Run. It is cancelled on
+// SIGINT or SIGTERM when the app is started with Main.
+type Context struct {
+	context.Context
+	App     *App
+	Command *Command
+	Args    []string
+	Flags   *flag.FlagSet
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+func (c *Context) value(name string) any {
+	f := c.Flags.Lookup(name)
+	if f == nil {
+		panic(fmt.Sprintf("cli: flag --%s is not defined for %q", name, c.Command.Path()))
+	}
+	return f.Value.(flag.Getter).Get()
+}
+
+// String returns the value of a string flag
+func (c *Context) String(name string) string { return c.value(name).(string) }
+
+// Int returns the value of an int flag
+func (c *Context) Int(name string) int { return c.value(name).(int) }
+
+// Bool returns the value of a bool flag
+func (c *Context) Bool(name string) bool { return c.value(name).(bool) }
+
+// Duration returns the value of a duration flag
+func (c *Context) Duration(name string) time.Duration { return c.value(name).(time.Duration) }
+
+// IsSet reports whether a flag was given on the command line
+func (c *Context) IsSet(name string) bool {
+	set := false
+	c.Flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Arg returns the i-th positional argument or "" if there are fewer
+func (c *Context) Arg(i int) string {
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return ""
+}
+
+// UsageError reports a malformed command line; Main prints the command's
+// usage after it and exits with status 2
+type UsageError struct {
+	Command *Command
+	Err     error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// Usagef returns a UsageError for the running command
+func (c *Context) Usagef(format string, args ...any) error {
+	return &UsageError{Command: c.Command, Err: fmt.Errorf(format, args...)}
+}
+
+// App is a command-line program: a root command plus built-in help,
+// completion and version commands
+type App struct {
+	Name    string
+	Version string
+	Root    *Command
+	// Before runs after flags are parsed and before the command, e.g. to
+	// apply global flags such as the log level
+	Before func(c *Context) error
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewApp creates an app whose root command is named after the binary
+func NewApp(name, short, version string) *App {
+	app := &App{
+		Name:    name,
+		Version: version,
+		Root:    &Command{Name: name, Short: short},
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	}
+	app.Root.AddCommand(app.helpCommand(), app.completionCommand(), app.versionCommand())
+	return app
+}
+
+// AddCommand attaches top-level commands
+func (a *App) AddCommand(cmds ...*Command) {
+	a.Root.AddCommand(cmds...)
+}
+
+// GlobalFlags defines flags available to every command
+func (a *App) GlobalFlags(fn func(fs *flag.FlagSet)) {
+	a.Root.Flags = fn
+}
+
+// resolve walks args down the command tree. Flags may appear anywhere, so
+// each command's flags are added to a single FlagSet as it is entered.
+func (a *App) resolve(args []string) (*Command, *flag.FlagSet, []string, error) {
+	fs := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	// Everything after "--" is positional
+	var trailing []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, trailing = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	cmd := a.Root
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return cmd, fs, nil, err
+			}
+			return cmd, fs, nil, &UsageError{Command: cmd, Err: err}
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// Descend while no positional argument has been seen
+		if sub := cmd.Find(rest[0]); sub != nil && len(positional) == 0 {
+			cmd = sub
+			if cmd.Flags != nil {
+				cmd.Flags(fs)
+			}
+		} else {
+			positional = append(positional, rest[0])
+		}
+		args = rest[1:]
+	}
+	return cmd, fs, append(positional, trailing...), nil
+}
+
+// Run executes the command named by args (without the program name)
+func (a *App) Run(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == completeCommandName {
+		// The words being completed may be partial flags, so they are not parsed
+		for _, candidate := range a.Complete(args[1:]) {
+			fmt.Fprintln(a.Stdout, candidate)
+		}
+		return nil
+	}
+
+	cmd, fs, positional, err := a.resolve(args)
+	if errors.Is(err, flag.ErrHelp) {
+		a.printHelp(a.Stdout, cmd)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A command with subcommands only takes arguments if it documents them,
+	// so "net tcp-servr" is reported instead of running "net"
+	if cmd.Run == nil || (len(cmd.subcommands) > 0 && cmd.Usage == "") {
+		if len(positional) > 0 {
+			return &UsageError{Command: cmd, Err: unknownCommandError(cmd, positional[0])}
+		}
+		a.printHelp(a.Stdout, cmd)
+		return nil
+	}
+
+	c := &Context{Context: ctx, App: a, Command: cmd, Args: positional, Flags: fs, Stdout: a.Stdout, Stderr: a.Stderr}
+	if a.Before != nil {
+		if err := a.Before(c); err != nil {
+			return err
+		}
+	}
+	return cmd.Run(c)
+}
+
+// Main runs the app with os.Args and exits: status 2 for usage errors, 1 for
+// other errors. SIGINT and SIGTERM cancel the command's context.
+func (a *App) Main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := a.Run(ctx, os.Args[1:])
+	stop()
+
+	var usageErr *UsageError
+	switch {
+	case err == nil:
+		return
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(a.Stderr, "Error: %v\n\n", err)
+		a.printUsage(a.Stderr, usageErr.Command)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(a.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func unknownCommandError(cmd *Command, name string) error {
+	err := fmt.Sprintf("unknown command %q for %q", name, cmd.Path())
+	if suggestions := suggest(cmd, name); len(suggestions) > 0 {
+		err += "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+	}
+	return errors.New(err)
+}
+
+// suggest returns visible subcommands within two edits of name or starting
+// with it
+func suggest(cmd *Command, name string) []string {
+	var matches []string
+	for _, sub := range cmd.Subcommands() {
+		if strings.HasPrefix(sub.Name, name) || editDistance(sub.Name, name) <= 2 {
+			matches = append(matches, sub.Name)
+		}
+	}
+	return matches
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completeCommandName is the hidden argument the shell scripts call back
+// with; it is followed by the words typed so far, the last being the one to
+// complete
+const completeCommandName = "__complete"
+
+func (a *App) completionCommand() *Command {
+	return &Command{
+		Name:      "completion",
+		Usage:     "<bash|zsh|fish>",
+		Short:     "Generate a shell completion script",
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Long: `Generate a shell completion script. For example:
+
+  bash:  source <(gopractice completion bash)
+  zsh:   gopractice completion zsh > "${fpath[1]}/_gopractice"
+  fish:  gopractice completion fish > ~/.config/fish/completions/gopractice.fish`,
+		Run: func(c *Context) error {
+			if len(c.Args) != 1 {
+				return c.Usagef("expected one shell name, got %d arguments", len(c.Args))
+			}
+			return a.WriteCompletion(c.Stdout, c.Arg(0))
+		},
+	}
+}
+
+// WriteCompletion writes the completion script for shell. The scripts are thin
+// wrappers that ask the binary itself for candidates, so they never go stale.
+func (a *App) WriteCompletion(w io.Writer, shell string) error {
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(a.Name) + "_complete"
+	var script string
+	switch shell {
+	case "bash":
+		script = fmt.Sprintf(`# bash completion for %[1]s
+%[2]s() {
+    local IFS=$'\n'
+    COMPREPLY=($(%[1]s %[3]s "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F %[2]s %[1]s
+`, a.Name, fn, completeCommandName)
+	case "zsh":
+		script = fmt.Sprintf(`#compdef %[1]s
+%[2]s() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s %[3]s "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    compadd -a candidates
+}
+compdef %[2]s %[1]s
+`, a.Name, fn, completeCommandName)
+	case "fish":
+		script = fmt.Sprintf(`# fish completion for %[1]s
+complete -c %[1]s -f -a '(%[1]s %[2]s (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`, a.Name, completeCommandName)
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", shell)
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// Complete returns the candidates for the last of words, the arguments typed
+// after the program name
+func (a *App) Complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, typed := words[len(words)-1], words[:len(words)-1]
+
+	fs := flag.NewFlagSet(a.Name, flag.ContinueOnError)
+	cmd := a.Root
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+	positional := 0
+	expectValue := false
+	for _, word := range typed {
+		switch {
+		case expectValue:
+			expectValue = false
+		case word == "--":
+			positional++
+		case strings.HasPrefix(word, "-"):
+			name := strings.TrimLeft(word, "-")
+			if _, _, hasValue := strings.Cut(name, "="); !hasValue {
+				expectValue = !isBoolFlag(fs.Lookup(name))
+			}
+		default:
+			if sub := cmd.Find(word); sub != nil && positional == 0 {
+				cmd = sub
+				if cmd.Flags != nil {
+					cmd.Flags(fs)
+				}
+			} else {
+				positional++
+			}
+		}
+	}
+	if expectValue {
+		// A flag value could be anything; let the shell fall back to files
+		return nil
+	}
+
+	var candidates []string
+	if strings.HasPrefix(current, "-") {
+		fs.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "--"+f.Name)
+		})
+	} else if positional == 0 {
+		for _, sub := range cmd.Subcommands() {
+			candidates = append(candidates, sub.Name)
+		}
+		candidates = append(candidates, cmd.ValidArgs...)
+	}
+
+	matches := candidates[:0]
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
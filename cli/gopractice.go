@@ -0,0 +1,652 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/cron"
+	"github.com/jerrychou/go-practice/data_structure"
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/email"
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/file"
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/grpc"
+	httpclient "github.com/jerrychou/go-practice/http"
+	"github.com/jerrychou/go-practice/io_ops"
+	jsonops "github.com/jerrychou/go-practice/json"
+	"github.com/jerrychou/go-practice/metrics"
+	"github.com/jerrychou/go-practice/net"
+	"github.com/jerrychou/go-practice/queue"
+	"github.com/jerrychou/go-practice/reflect"
+	"github.com/jerrychou/go-practice/security"
+	"github.com/jerrychou/go-practice/server"
+	"github.com/jerrychou/go-practice/string_op"
+	"github.com/jerrychou/go-practice/testingutil"
+	"github.com/jerrychou/go-practice/tracing"
+)
+
+// Version is reported by "gopractice version"; release builds set it with
+// -ldflags "-X github.com/jerrychou/go-practice/cli.Version=v1.2.3"
+var Version = "dev"
+
+// NewGoPracticeApp builds the gopractice binary, which replaces the separate
+// mains under run/ with one command per module
+func NewGoPracticeApp() *App {
+	app := NewApp("gopractice", "Run the go-practice examples and demo servers", Version)
+	app.GlobalFlags(func(fs *flag.FlagSet) {
+		fs.String("log-level", "", "Log level for every package: debug, info, warn or error")
+		fs.Bool("verbose", false, "Shorthand for --log-level=debug")
+	})
+	app.Before = applyLogLevel
+
+	app.AddCommand(
+		exampleCommand("cache", "Memory, Redis and layered caches", cache.RunAllCacheExamples),
+		concurrencyCommand(),
+		exampleCommand("cron", "Cron schedules, missed runs and run history", cron.RunAllCronExamples),
+		exampleCommand("data-structures", "Data structure examples", data_structure.RunAllDataStructureExamples),
+		databaseCommand(),
+		exampleCommand("email", "MIME messages, templates and SMTP sending", email.RunAllEmailExamples),
+		exampleCommand("encoding", "CSV, XML, protobuf and msgpack codecs", encoding.RunAllEncodingExamples),
+		exampleCommand("file", "File and OS operations", file.RunAllFileExamples),
+		exampleCommand("filestore", "Local disk and S3 object storage with signed URLs", filestore.RunAllFilestoreExamples),
+		exampleCommand("format", "Formatting, logging and terminal output", format.RunAllExamples),
+		exampleCommand("grpc", "gRPC server, interceptors and streaming client", grpc.RunAllGRPCExamples),
+		exampleCommand("http", "HTTP client examples", httpclient.RunAllExamples),
+		exampleCommand("io", "Streaming, atomic writes, locking and checksums", io_ops.RunAllIOExamples),
+		exampleCommand("json", "JSON encoding and decoding", jsonops.RunAllJSONExamples),
+		exampleCommand("metrics", "Counters, gauges, histograms and Prometheus exposition", metrics.RunAllMetricsExamples),
+		netCommand(),
+		exampleCommand("queue", "In-memory and Redis Streams message queues", queue.RunAllQueueExamples),
+		reflectCommand(),
+		securityCommand(),
+		serverCommand(),
+		exampleCommand("string", "String manipulation utilities", string_op.RunAllStringExamples),
+		exampleCommand("testing", "Shared test helpers", testingutil.RunAllTestingExamples),
+		exampleCommand("tracing", "Spans, trace propagation and OTLP export", tracing.RunAllTracingExamples),
+	)
+	return app
+}
+
+func applyLogLevel(c *Context) error {
+	name := c.String("log-level")
+	if c.Bool("verbose") {
+		name = "debug"
+	}
+	if name == "" {
+		return nil
+	}
+	level, err := format.ParseLevel(name)
+	if err != nil {
+		return c.Usagef("invalid --log-level: %v", err)
+	}
+	format.Default().SetLevel(level)
+	for _, pkg := range format.PackageLoggers() {
+		format.GetLogger(pkg).SetLevel(level)
+	}
+	return nil
+}
+
+// exampleCommand wraps a module's RunAll...Examples function
+func exampleCommand(name, short string, run func()) *Command {
+	return &Command{
+		Name:  name,
+		Short: short,
+		Run: func(c *Context) error {
+			if len(c.Args) > 0 {
+				return c.Usagef("%s takes no arguments", c.Command.Path())
+			}
+			run()
+			return nil
+		},
+	}
+}
+
+// topicCommand runs every topic, or only those named as arguments
+func topicCommand(name, short string, topics []string, run map[string]func()) *Command {
+	return &Command{
+		Name:      name,
+		Usage:     "[topic...]",
+		Short:     short,
+		Long:      short + "\n\nTopics: " + strings.Join(topics, ", "),
+		ValidArgs: topics,
+		Run: func(c *Context) error {
+			selected := c.Args
+			if len(selected) == 0 {
+				selected = topics
+			}
+			for _, topic := range selected {
+				if run[topic] == nil {
+					return c.Usagef("unknown topic %q (want one of %s)", topic, strings.Join(topics, ", "))
+				}
+			}
+			for _, topic := range selected {
+				run[topic]()
+			}
+			return nil
+		},
+	}
+}
+
+func concurrencyCommand() *Command {
+	return topicCommand("concurrency", "Goroutines, channels, sync and pipeline patterns",
+		[]string{"goroutines", "channels", "select", "waitgroups", "mutexes", "context", "context-utils", "worker-pools", "fan"},
+		map[string]func(){
+			"goroutines":    concurrency.RunAllGoroutineExamples,
+			"channels":      concurrency.RunAllChannelExamples,
+			"select":        concurrency.RunAllSelectExamples,
+			"waitgroups":    concurrency.RunAllWaitGroupExamples,
+			"mutexes":       concurrency.RunAllMutexExamples,
+			"context":       concurrency.RunAllContextExamples,
+			"context-utils": concurrency.RunAllContextUtilityExamples,
+			"worker-pools":  concurrency.RunAllWorkerPoolExamples,
+			"fan":           concurrency.RunAllFanPatternExamples,
+		})
+}
+
+func reflectCommand() *Command {
+	return topicCommand("reflect", "Reflection on types, structs, functions and interfaces",
+		[]string{"basic", "struct", "function", "interface", "practical"},
+		map[string]func(){
+			"basic":     reflect.BasicReflection,
+			"struct":    reflect.StructReflection,
+			"function":  reflect.FunctionReflection,
+			"interface": reflect.InterfaceReflection,
+			"practical": reflect.PracticalExamples,
+		})
+}
+
+// serveUntilDone runs a blocking server until it fails or ctx is cancelled;
+// stop, if not nil, is called on cancellation
+func serveUntilDone(ctx context.Context, start func() error, stop func() error) error {
+	errs := make(chan error, 1)
+	go func() { errs <- start() }()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		fmt.Println("\n👋 Shutting down")
+		if stop != nil {
+			return stop()
+		}
+		return nil
+	}
+}
+
+func netCommand() *Command {
+	address := func(c *Context) (string, string) { return c.String("address"), c.String("port") }
+	messages := []string{"Hello, Server!", "How are you?", "Goodbye!", "quit"}
+	// socket turns the --keepalive, --nodelay and other socket flags into
+	// options, leaving those not given at Go's defaults
+	socket := func(c *Context) ([]net.SocketOption, error) {
+		var opts []net.SocketOption
+		idle, interval, count := c.Duration("keepalive"), c.Duration("keepalive-interval"), c.Int("keepalive-count")
+		if idle < 0 {
+			opts = append(opts, net.WithoutKeepAlive())
+		} else if idle > 0 || interval > 0 || count > 0 {
+			opts = append(opts, net.WithKeepAlive(idle, interval, count))
+		}
+		if c.IsSet("nodelay") {
+			opts = append(opts, net.WithNoDelay(c.Bool("nodelay")))
+		}
+		if c.Bool("reuseport") {
+			opts = append(opts, net.WithReusePort())
+		}
+		var buffers [2]int
+		for i, name := range []string{"rcvbuf", "sndbuf"} {
+			if value := c.String(name); value != "" {
+				n, err := format.ParseBytes(value)
+				if err != nil {
+					return nil, c.Usagef("invalid --%s: %v", name, err)
+				}
+				buffers[i] = int(n)
+			}
+		}
+		if buffers[0] > 0 || buffers[1] > 0 {
+			opts = append(opts, net.WithBufferSizes(buffers[0], buffers[1]))
+		}
+		if linger := c.Duration("linger"); linger >= 0 {
+			opts = append(opts, net.WithLinger(linger))
+		}
+		return opts, nil
+	}
+
+	cmd := &Command{
+		Name:  "net",
+		Short: "TCP/UDP servers and clients, URL and network utilities",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("address", "localhost", "Server address")
+			fs.String("port", "8080", "Server port")
+			fs.Duration("keepalive", 0, "TCP: idle time before keep-alive probes (0 = Go's default of 15s, negative = no keep-alives)")
+			fs.Duration("keepalive-interval", 0, "TCP: time between keep-alive probes (0 = default)")
+			fs.Int("keepalive-count", 0, "TCP: unanswered keep-alive probes before the connection is dropped (0 = default)")
+			fs.Bool("nodelay", true, "TCP: set TCP_NODELAY; false lets Nagle's algorithm batch small writes")
+			fs.Bool("reuseport", false, "TCP servers: set SO_REUSEPORT so several servers can share the port")
+			fs.String("rcvbuf", "", "TCP: socket receive buffer, e.g. 256KiB (empty = OS default)")
+			fs.String("sndbuf", "", "TCP: socket send buffer, e.g. 256KiB (empty = OS default)")
+			fs.Duration("linger", -1, "TCP: SO_LINGER, how long Close waits to send unsent data (0 = reset at once, negative = OS default)")
+		},
+		Run: func(c *Context) error {
+			net.DemonstrateURLOperations()
+			net.DemonstrateNetworkOperations()
+			net.DemonstrateTCPOperations()
+			net.DemonstrateUDPOperations()
+			return nil
+		},
+	}
+	return cmd.AddCommand(
+		&Command{
+			Name:  "url",
+			Short: "URL parsing and building",
+			Run:   func(c *Context) error { net.DemonstrateURLOperations(); return nil },
+		},
+		&Command{
+			Name:  "network",
+			Short: "Hostname resolution and interface information",
+			Run: func(c *Context) error {
+				net.DemonstrateNetworkOperations()
+				net.PrintNetworkInfo()
+				return nil
+			},
+		},
+		&Command{
+			Name:  "tcp-server",
+			Short: "Start a TCP echo server",
+			Run: func(c *Context) error {
+				opts, err := socket(c)
+				if err != nil {
+					return err
+				}
+				host, port := address(c)
+				srv := net.NewTCPServer(host, port, opts...)
+				return serveUntilDone(c, srv.Start, srv.Stop)
+			},
+		},
+		&Command{
+			Name:  "tcp-client",
+			Usage: "[message...]",
+			Short: "Send messages to a TCP echo server",
+			Run: func(c *Context) error {
+				opts, err := socket(c)
+				if err != nil {
+					return err
+				}
+				host, port := address(c)
+				sent := messages
+				if len(c.Args) > 0 {
+					// The server closes the connection on "quit"
+					sent = append(append([]string(nil), c.Args...), "quit")
+				}
+				return net.SimpleEchoClient(host, port, sent, opts...)
+			},
+		},
+		&Command{
+			Name:  "udp-server",
+			Short: "Start a UDP echo server",
+			Run: func(c *Context) error {
+				return serveUntilDone(c, net.NewUDPServer(address(c)).Start, nil)
+			},
+		},
+		&Command{
+			Name:  "udp-client",
+			Usage: "[message...]",
+			Short: "Send datagrams to a UDP echo server",
+			Run: func(c *Context) error {
+				host, port := address(c)
+				sent := c.Args
+				if len(sent) == 0 {
+					sent = messages[:len(messages)-1]
+				}
+				return net.SimpleUDPEchoClient(host, port, sent)
+			},
+		},
+		&Command{
+			Name:  "chat",
+			Short: "Start a multi-client TCP chat server",
+			Run: func(c *Context) error {
+				opts, err := socket(c)
+				if err != nil {
+					return err
+				}
+				host, port := address(c)
+				return serveUntilDone(c, net.NewChatServer(host, port, opts...).Start, nil)
+			},
+		},
+		&Command{
+			Name:  "forward",
+			Usage: "<target host:port>",
+			Short: "Forward local TCP connections to a remote target",
+			Flags: func(fs *flag.FlagSet) {
+				fs.Bool("tls", false, "Connect to the target over TLS")
+				fs.String("socks5", "", "Reach the target through this SOCKS5 proxy (host:port)")
+				fs.Int("max-conns", 0, "Maximum concurrent connections (0 = unlimited)")
+				fs.Duration("idle-timeout", 5*time.Minute, "Close connections idle for this long (0 = never)")
+			},
+			Run: func(c *Context) error {
+				if len(c.Args) != 1 {
+					return c.Usagef("expected one target, got %d arguments", len(c.Args))
+				}
+				host, port := address(c)
+				fwd := net.NewForwarder(net.HostPort(host, port), c.Args[0])
+				fwd.MaxConns = c.Int("max-conns")
+				fwd.IdleTimeout = c.Duration("idle-timeout")
+				if c.Bool("tls") {
+					fwd.TLS = &tls.Config{}
+				}
+				if proxy := c.String("socks5"); proxy != "" {
+					fwd.Dialer = net.NewSOCKS5Dialer(proxy)
+				}
+				return serveUntilDone(c, fwd.Start, fwd.Stop)
+			},
+		},
+	)
+}
+
+func securityCommand() *Command {
+	cmd := &Command{
+		Name:  "security",
+		Short: "Security checks",
+	}
+	return cmd.AddCommand(&Command{
+		Name:  "check-headers",
+		Usage: "<url>",
+		Short: "Report security headers a live response is missing compared with a profile",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("profile", "web-app", "Profile to check against: strict-api, web-app or embedded-iframe")
+			fs.String("frame-origins", "", "Comma-separated origins allowed to frame the page (embedded-iframe)")
+		},
+		Run: func(c *Context) error {
+			if len(c.Args) != 1 {
+				return c.Usagef("expected one URL, got %d arguments", len(c.Args))
+			}
+			var origins []string
+			if list := c.String("frame-origins"); list != "" {
+				origins = strings.Split(list, ",")
+			}
+			profile, err := security.HeaderProfileByName(c.String("profile"), origins...)
+			if err != nil {
+				return c.Usagef("%v", err)
+			}
+			issues, err := security.SelfCheck(c, nil, c.Args[0], profile)
+			if err != nil {
+				return err
+			}
+			for _, issue := range issues {
+				fmt.Println("✗", issue)
+			}
+			if len(issues) > 0 {
+				return fmt.Errorf("%d header issues against the %s profile", len(issues), profile.Name)
+			}
+			fmt.Printf("✓ all %s headers present\n", profile.Name)
+			return nil
+		},
+	})
+}
+
+func serverCommand() *Command {
+	return &Command{
+		Name:  "server",
+		Short: "Start the demo HTTP server",
+		Flags: func(fs *flag.FlagSet) {
+			port := os.Getenv("PORT")
+			if port == "" {
+				port = "8080"
+			}
+			fs.String("port", port, "Port to listen on (defaults to $PORT)")
+			fs.Bool("no-middleware", false, "Serve the routes without logging, CORS, security and rate-limit middleware")
+			fs.String("config", "", "Build the whole application from a JSON, YAML or TOML config file instead")
+		},
+		Run: func(c *Context) error {
+			if path := c.String("config"); path != "" {
+				cfg, err := config.NewConfigLoader(path).Load()
+				if err != nil {
+					return err
+				}
+				app, err := server.Bootstrap(*cfg)
+				if err != nil {
+					return err
+				}
+				server.ConfigReloader = func(ctx context.Context) error {
+					cfg, err := config.NewConfigLoader(path).Load()
+					if err != nil {
+						return err
+					}
+					return app.Reload(*cfg)
+				}
+				return serveUntilDone(c, app.Start, func() error {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
+					return app.Stop(ctx)
+				})
+			}
+
+			srv := server.New(c.String("port"))
+			if c.Bool("no-middleware") {
+				srv.SetHandler(server.SetupRoutes())
+			} else {
+				srv.SetHandler(server.SetupRoutesWithMiddleware())
+			}
+			return serveUntilDone(c, srv.Start, nil)
+		},
+	}
+}
+
+func databaseCommand() *Command {
+	open := func(c *Context) (*sql.DB, *database.MigrationManager, error) {
+		driver := c.String("driver")
+		db, err := sql.Open(driver, c.String("dsn"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("open %s database: %w", driver, err)
+		}
+		if err := db.PingContext(c); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("connect to %s database: %w", driver, err)
+		}
+		return db, database.NewMigrationManager(db).SetDialect(driver), nil
+	}
+	// backups stores backups of the database in the FILESTORE_* store
+	backups := func(c *Context) (*sql.DB, *database.BackupManager, error) {
+		store, err := filestore.FromEnv("")
+		if err != nil {
+			return nil, nil, err
+		}
+		db, _, err := open(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch c.String("driver") {
+		case "sqlite3":
+			return db, database.NewSQLiteBackupManager(db, store), nil
+		case "postgres":
+			return db, database.NewPostgresBackupManager(c.String("dsn"), store), nil
+		}
+		db.Close()
+		return nil, nil, fmt.Errorf("backups need --driver sqlite3 or postgres")
+	}
+
+	cmd := &Command{
+		Name:    "db",
+		Aliases: []string{"database"},
+		Short:   "Database migrations and examples",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("driver", "sqlite3", "Database driver: sqlite3, postgres or mysql")
+			fs.String("dsn", "gopractice.db", "Data source name; a file path for sqlite3")
+		},
+	}
+	return cmd.AddCommand(
+		&Command{
+			Name:  "migrate",
+			Short: "Apply pending migrations, or roll back the latest with --down",
+			Flags: func(fs *flag.FlagSet) {
+				fs.Bool("down", false, "Roll back the most recently applied migration")
+				fs.Bool("dry-run", false, "Print and validate the SQL of pending migrations without running it")
+				fs.String("plan", "", "Plan file a dry run writes, and which migrating then requires pending migrations to match")
+			},
+			Run: func(c *Context) error {
+				db, mm, err := open(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				mm.SetPlanFile(c.String("plan"))
+				if c.Bool("dry-run") {
+					return mm.SetDryRun(os.Stdout).MigrateUp()
+				}
+				if c.Bool("down") {
+					err = mm.MigrateDown()
+				} else {
+					err = mm.MigrateUp()
+				}
+				if err != nil {
+					return err
+				}
+				return mm.GetMigrationStatus()
+			},
+		},
+		&Command{
+			Name:  "status",
+			Short: "Show applied and pending migrations",
+			Run: func(c *Context) error {
+				db, mm, err := open(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				return mm.GetMigrationStatus()
+			},
+		},
+		&Command{
+			Name:  "outbox",
+			Short: "Apply migrations, then write users with outbox events and dispatch them",
+			Run: func(c *Context) error {
+				db, mm, err := open(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := mm.MigrateUp(); err != nil {
+					return err
+				}
+				return database.NewDatabaseExamples().RunOutboxExamples(db, queue.NewMemory())
+			},
+		},
+		&Command{
+			Name:  "changes",
+			Short: "Apply migrations, then watch the users table with LISTEN/NOTIFY (postgres only)",
+			Run: func(c *Context) error {
+				if c.String("driver") != "postgres" {
+					return fmt.Errorf("change notifications need --driver postgres")
+				}
+				db, mm, err := open(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := mm.MigrateUp(); err != nil {
+					return err
+				}
+				return database.NewDatabaseExamples().RunChangeNotifierExamples(db, c.String("dsn"))
+			},
+		},
+		&Command{
+			Name:  "backup",
+			Short: "Back up the database to the file store, keeping the newest --keep backups",
+			Flags: func(fs *flag.FlagSet) {
+				fs.Int("keep", 0, "Number of backups to keep; 0 keeps all")
+			},
+			Run: func(c *Context) error {
+				db, bm, err := backups(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				backup, err := bm.Backup(c)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s  %d bytes  sha256 %s\n", backup.Key, backup.Size, backup.SHA256)
+				bm.Keep = c.Int("keep")
+				_, err = bm.Prune(c)
+				return err
+			},
+		},
+		&Command{
+			Name:  "backups",
+			Short: "List stored backups, newest first",
+			Run: func(c *Context) error {
+				db, bm, err := backups(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				list, err := bm.List(c)
+				if err != nil {
+					return err
+				}
+				for _, backup := range list {
+					fmt.Printf("%s  %s  %d bytes\n", backup.CreatedAt.Format(time.RFC3339), backup.Key, backup.Size)
+				}
+				return nil
+			},
+		},
+		&Command{
+			Name:  "verify",
+			Usage: "<key>",
+			Short: "Check a backup's checksum and integrity",
+			Run: func(c *Context) error {
+				if len(c.Args) != 1 {
+					return c.Usagef("expected one backup key, got %d arguments", len(c.Args))
+				}
+				db, bm, err := backups(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := bm.Verify(c, c.Args[0]); err != nil {
+					return err
+				}
+				fmt.Println("ok")
+				return nil
+			},
+		},
+		&Command{
+			Name:  "restore",
+			Usage: "<key>",
+			Short: "Replace the database's contents with a verified backup",
+			Run: func(c *Context) error {
+				if len(c.Args) != 1 {
+					return c.Usagef("expected one backup key, got %d arguments", len(c.Args))
+				}
+				db, bm, err := backups(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				return bm.Restore(c, c.Args[0])
+			},
+		},
+		&Command{
+			Name:  "examples",
+			Short: "Run the SQL, pooling, migration and transaction examples",
+			Run: func(c *Context) error {
+				return database.NewDatabaseExamples().RunAllExamples()
+			},
+		},
+	)
+}
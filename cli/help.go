@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// printHelp writes the full help for cmd
+func (a *App) printHelp(w io.Writer, cmd *Command) {
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	if description != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(description))
+	}
+
+	a.printUsage(w, cmd)
+
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases:\n  %s\n", strings.Join(append([]string{cmd.Name}, cmd.Aliases...), ", "))
+	}
+
+	if subs := cmd.Subcommands(); len(subs) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		for _, sub := range subs {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Short)
+		}
+		tw.Flush()
+	}
+
+	lineage := cmd.lineage()
+	writeFlags(w, "Flags", cmd)
+	for i := len(lineage) - 2; i >= 0; i-- {
+		title := "Inherited Flags (" + lineage[i].Path() + ")"
+		if i == 0 {
+			title = "Global Flags"
+		}
+		writeFlags(w, title, lineage[i])
+	}
+
+	if len(cmd.Subcommands()) > 0 {
+		fmt.Fprintf(w, "\nUse \"%s <command> --help\" for more information about a command.\n", cmd.Path())
+	}
+}
+
+// printUsage writes the synopsis lines for cmd
+func (a *App) printUsage(w io.Writer, cmd *Command) {
+	fmt.Fprintln(w, "Usage:")
+	if cmd.Run != nil {
+		line := cmd.Path() + " [flags]"
+		if cmd.Usage != "" {
+			line += " " + cmd.Usage
+		}
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	if len(cmd.Subcommands()) > 0 {
+		fmt.Fprintf(w, "  %s <command> [flags]\n", cmd.Path())
+	}
+}
+
+// writeFlags lists the flags defined by cmd itself
+func writeFlags(w io.Writer, title string, cmd *Command) {
+	if cmd.Flags == nil {
+		return
+	}
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	cmd.Flags(fs)
+
+	fmt.Fprintf(w, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		typeName, usage := flag.UnquoteUsage(f)
+		name := "--" + f.Name
+		if typeName != "" {
+			name += " " + typeName
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			if typeName == "string" {
+				usage += fmt.Sprintf(" (default %q)", f.DefValue)
+			} else {
+				usage += fmt.Sprintf(" (default %s)", f.DefValue)
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, usage)
+	})
+	tw.Flush()
+}
+
+func (a *App) helpCommand() *Command {
+	return &Command{
+		Name:  "help",
+		Usage: "[command...]",
+		Short: "Show help for a command",
+		Run: func(c *Context) error {
+			cmd := a.Root
+			for _, name := range c.Args {
+				sub := cmd.Find(name)
+				if sub == nil {
+					return c.Usagef("%v", unknownCommandError(cmd, name))
+				}
+				cmd = sub
+			}
+			a.printHelp(c.Stdout, cmd)
+			return nil
+		},
+	}
+}
+
+func (a *App) versionCommand() *Command {
+	return &Command{
+		Name:  "version",
+		Short: "Print the version",
+		Run: func(c *Context) error {
+			fmt.Fprintf(c.Stdout, "%s %s (%s %s/%s)\n", a.Name, a.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,191 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy says what a Broadcast does with a value for a receiver
+// whose buffer is full
+type OverflowPolicy int
+
+const (
+	// DropNewest makes the receiver miss the new value
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the receiver's oldest buffered value to make
+	// room, so a receiver with a buffer of one always has the latest
+	DropOldest
+	// Block makes Send wait until the receiver has room, so the slowest
+	// receiver sets the pace for all of them
+	Block
+	// Disconnect cancels the receiver, closing its channel, e.g. to drop a
+	// client that cannot keep up
+	Disconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	case Disconnect:
+		return "disconnect"
+	}
+	return "unknown"
+}
+
+// Broadcast sends every value to all of its current receivers, each with
+// its own buffer and overflow policy. Unlike a channel, where each value
+// goes to one receiver, and PubSub, which has topics, it is one stream
+// everybody sees in the same order.
+type Broadcast[T any] struct {
+	mu        sync.Mutex // held while sending, which keeps the order
+	receivers map[*Receiver[T]]struct{}
+	closed    bool
+}
+
+// Receiver gets a Broadcast's values on C until it is cancelled or the
+// Broadcast is closed, when C is closed
+type Receiver[T any] struct {
+	C <-chan T
+
+	ch           chan T
+	policy       OverflowPolicy
+	b            *Broadcast[T]
+	done         chan struct{}
+	once         sync.Once
+	dropped      atomic.Int64
+	disconnected atomic.Bool
+	closed       bool // guarded by b.mu
+}
+
+// NewBroadcast creates a broadcast with no receivers
+func NewBroadcast[T any]() *Broadcast[T] {
+	return &Broadcast[T]{receivers: make(map[*Receiver[T]]struct{})}
+}
+
+// Subscribe adds a receiver buffering up to buffer values, handling more
+// by policy. It gets the values sent from now on; subscribing to a closed
+// Broadcast returns a closed receiver. DropOldest needs somewhere to keep
+// the latest value, so its buffer is at least one.
+func (b *Broadcast[T]) Subscribe(buffer int, policy OverflowPolicy) *Receiver[T] {
+	if policy == DropOldest {
+		buffer = max(buffer, 1)
+	}
+	ch := make(chan T, max(buffer, 0))
+	r := &Receiver[T]{C: ch, ch: ch, policy: policy, b: b, done: make(chan struct{})}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		r.close()
+		return r
+	}
+	b.receivers[r] = struct{}{}
+	return r
+}
+
+// Send delivers v to every receiver and returns how many got it. With
+// Block receivers it can wait; SendContext bounds the wait.
+func (b *Broadcast[T]) Send(v T) int {
+	n, _ := b.SendContext(context.Background(), v)
+	return n
+}
+
+// SendContext is Send that gives up waiting for Block receivers once ctx
+// is done; receivers it had not reached by then miss v
+func (b *Broadcast[T]) SendContext(ctx context.Context, v T) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delivered := 0
+	for r := range b.receivers {
+		select {
+		case r.ch <- v:
+			delivered++
+			continue
+		default:
+		}
+
+		switch r.policy {
+		case DropNewest:
+			r.dropped.Add(1)
+		case DropOldest:
+			// Only senders, which hold b.mu, add to r.ch, so once a value is
+			// taken out of its non-empty buffer there is room; never block
+			// with b.mu held all the same
+			select {
+			case <-r.ch:
+				r.dropped.Add(1)
+			default:
+			}
+			select {
+			case r.ch <- v:
+				delivered++
+			default:
+				r.dropped.Add(1)
+			}
+		case Block:
+			select {
+			case r.ch <- v:
+				delivered++
+			case <-r.done:
+			case <-ctx.Done():
+				return delivered, ctx.Err()
+			}
+		case Disconnect:
+			r.dropped.Add(1)
+			r.disconnected.Store(true)
+			delete(b.receivers, r)
+			r.close()
+		}
+	}
+	return delivered, nil
+}
+
+// Receivers returns how many receivers the broadcast has
+func (b *Broadcast[T]) Receivers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.receivers)
+}
+
+// Close cancels every receiver; later Sends deliver nothing
+func (b *Broadcast[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for r := range b.receivers {
+		r.close()
+	}
+	clear(b.receivers)
+}
+
+// Dropped returns how many values the receiver missed or had discarded
+func (r *Receiver[T]) Dropped() int64 { return r.dropped.Load() }
+
+// Disconnected reports whether the receiver was cancelled for falling
+// behind under the Disconnect policy
+func (r *Receiver[T]) Disconnected() bool { return r.disconnected.Load() }
+
+// Cancel stops the receiver and closes C; it is safe to call twice, and
+// also while a Send is blocked on the receiver
+func (r *Receiver[T]) Cancel() {
+	r.once.Do(func() { close(r.done) })
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	delete(r.b.receivers, r)
+	r.close()
+}
+
+// close closes C once; r.b.mu must be held, or r never added
+func (r *Receiver[T]) close() {
+	r.once.Do(func() { close(r.done) })
+	if !r.closed {
+		r.closed = true
+		close(r.ch)
+	}
+}
@@ -0,0 +1,346 @@
+package concurrency
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the time source for timing-dependent code. Examples take one so
+// they can run on RealClock or, deterministically and without waiting, on
+// a VirtualClock.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	// WithTimeout is context.WithTimeout with the deadline on this clock
+	WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc)
+}
+
+// Timer is a *time.Timer on some Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a *time.Ticker on some Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// RealClock is backed by the time package
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+func (realClock) WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, d)
+}
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// VirtualClock only moves when told to, so timing-dependent code runs the
+// same way every time. Advance steps it by hand, e.g. in a test after
+// BlockUntil confirms the code under test is waiting; FastForward moves it
+// on its own whenever every goroutine is waiting for it.
+//
+// Timers fire in deadline order, timers due at the same instant in the
+// order they were created, and Now reads each one's deadline while it
+// fires, so a ticker advanced past several periods ticks at each of them.
+type VirtualClock struct {
+	// Settle is how long, in real time, the clock must go untouched before
+	// FastForward decides every goroutine is waiting and jumps to the next
+	// timer. Raise it if goroutines do slow work between clock calls.
+	Settle time.Duration
+
+	mu       sync.Mutex
+	now      time.Time
+	timers   []*virtualTimer
+	seq      int64
+	activity int64
+	changed  *sync.Cond
+}
+
+type virtualTimer struct {
+	clock    *VirtualClock
+	deadline time.Time
+	seq      int64
+	period   time.Duration // tickers only
+	ch       chan time.Time
+	fn       func() // contexts only
+}
+
+// NewVirtualClock creates a clock stopped at start
+func NewVirtualClock(start time.Time) *VirtualClock {
+	c := &VirtualClock{now: start, Settle: 2 * time.Millisecond}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the virtual time
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activity++
+	return c.now
+}
+
+// Since returns the virtual time elapsed since t
+func (c *VirtualClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Sleep blocks until the clock has moved on by d
+func (c *VirtualClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// After returns a channel that receives the virtual time once d has passed
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer creates a timer firing once d has passed
+func (c *VirtualClock) NewTimer(d time.Duration) Timer {
+	t := &virtualTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scheduleLocked(t, d)
+	return t
+}
+
+// NewTicker creates a ticker firing every d. Like a *time.Ticker it drops
+// ticks a slow receiver has no room for.
+func (c *VirtualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("concurrency: non-positive interval for NewTicker")
+	}
+	t := &virtualTimer{clock: c, ch: make(chan time.Time, 1), period: d}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scheduleLocked(t, d)
+	return virtualTicker{t}
+}
+
+// WithTimeout returns a context cancelled with context.DeadlineExceeded
+// once the clock has moved on by d
+func (c *VirtualClock) WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	t := &virtualTimer{clock: c, fn: func() { cancel(context.DeadlineExceeded) }}
+	c.mu.Lock()
+	deadline := c.now.Add(d)
+	c.scheduleLocked(t, d)
+	c.mu.Unlock()
+
+	return virtualDeadlineContext{Context: ctx, deadline: deadline}, func() {
+		t.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// virtualDeadlineContext reports a deadline on a VirtualClock and, once it
+// passed, context.DeadlineExceeded rather than context.Canceled
+type virtualDeadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+// Deadline returns the earlier of the virtual deadline and the parent's
+func (v virtualDeadlineContext) Deadline() (time.Time, bool) {
+	if d, ok := v.Context.Deadline(); ok && d.Before(v.deadline) {
+		return d, true
+	}
+	return v.deadline, true
+}
+
+// Err returns context.DeadlineExceeded when the virtual deadline passed
+func (v virtualDeadlineContext) Err() error {
+	err := v.Context.Err()
+	if err != nil && context.Cause(v.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// Advance moves the clock forward by d, firing every timer due on the way
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.AdvanceTo(c.Now().Add(d))
+}
+
+// AdvanceTo moves the clock to t, firing every timer due by then; moving it
+// backwards fires nothing
+func (c *VirtualClock) AdvanceTo(t time.Time) {
+	for {
+		c.mu.Lock()
+		if len(c.timers) == 0 || c.timers[0].deadline.After(t) {
+			if t.After(c.now) {
+				c.now = t
+			}
+			c.mu.Unlock()
+			return
+		}
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.deadline.After(c.now) {
+			c.now = timer.deadline
+		}
+		now := c.now
+		if timer.period > 0 {
+			c.insertLocked(timer, timer.deadline.Add(timer.period))
+		}
+		c.mu.Unlock()
+
+		if timer.fn != nil {
+			timer.fn()
+			continue
+		}
+		select {
+		case timer.ch <- now:
+		default:
+		}
+	}
+}
+
+// FastForward jumps the clock to the next timer whenever it has gone
+// untouched for Settle, until ctx is done. It is for demos and simulations:
+// a run takes as long as its goroutines compute, not as long as they wait.
+func (c *VirtualClock) FastForward(ctx context.Context) {
+	settle := time.NewTicker(c.Settle)
+	defer settle.Stop()
+	c.mu.Lock()
+	last := c.activity
+	c.mu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-settle.C:
+		}
+		c.mu.Lock()
+		idle := c.activity == last && len(c.timers) > 0
+		var next time.Time
+		if idle {
+			next = c.timers[0].deadline
+		}
+		last = c.activity
+		c.mu.Unlock()
+		if idle {
+			c.AdvanceTo(next)
+		}
+	}
+}
+
+// Pending reports how many timers, tickers and deadlines are waiting
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n timers are pending, so a test can be
+// sure a goroutine is waiting before it advances the clock
+func (c *VirtualClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// scheduleLocked starts t firing in d; c.mu must be held
+func (c *VirtualClock) scheduleLocked(t *virtualTimer, d time.Duration) {
+	c.activity++
+	if d <= 0 && t.period == 0 && t.fn == nil {
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		return
+	}
+	c.insertLocked(t, c.now.Add(d))
+	c.changed.Broadcast()
+}
+
+func (c *VirtualClock) insertLocked(t *virtualTimer, deadline time.Time) {
+	c.seq++
+	t.deadline, t.seq = deadline, c.seq
+	i := sort.Search(len(c.timers), func(i int) bool {
+		other := c.timers[i]
+		return other.deadline.After(deadline) || other.deadline.Equal(deadline) && other.seq > t.seq
+	})
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = t
+}
+
+// removeLocked unschedules t and reports whether it was pending
+func (c *VirtualClock) removeLocked(t *virtualTimer) bool {
+	c.activity++
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *virtualTimer) C() <-chan time.Time { return t.ch }
+
+// Stop keeps the timer from firing and reports whether it was pending
+func (t *virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+// Reset makes the timer fire in d and reports whether it was pending
+func (t *virtualTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := t.clock.removeLocked(t)
+	// Like a *time.Timer since Go 1.23, no stale value is left behind
+	select {
+	case <-t.ch:
+	default:
+	}
+	t.clock.scheduleLocked(t, d)
+	return pending
+}
+
+type virtualTicker struct{ *virtualTimer }
+
+// Stop turns the ticker off
+func (t virtualTicker) Stop() { t.virtualTimer.Stop() }
+
+// Reset stops the ticker and restarts it with period d
+func (t virtualTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("concurrency: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.removeLocked(t.virtualTimer)
+	t.period = d
+	t.clock.scheduleLocked(t.virtualTimer, d)
+}
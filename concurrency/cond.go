@@ -0,0 +1,78 @@
+package concurrency
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Cond is a sync.Cond whose waits can be given up with a context. Waiters
+// are woken in the order they started waiting.
+type Cond struct {
+	// L is held while checking the condition and is locked again before
+	// any Wait returns
+	L sync.Locker
+
+	mu      sync.Mutex
+	waiters []chan struct{}
+}
+
+// NewCond creates a condition variable guarded by l
+func NewCond(l sync.Locker) *Cond {
+	return &Cond{L: l}
+}
+
+// Wait unlocks L, waits for Signal or Broadcast and locks L again. As with
+// sync.Cond, call it in a loop that checks the condition.
+func (c *Cond) Wait() {
+	c.WaitContext(context.Background())
+}
+
+// WaitContext is Wait that returns ctx's error once ctx is done. L is
+// locked again either way. A wakeup that races with ctx counts, so a
+// Signal is never lost to a waiter that gave up.
+func (c *Cond) WaitContext(ctx context.Context) error {
+	wake := make(chan struct{})
+	c.mu.Lock()
+	c.waiters = append(c.waiters, wake)
+	c.mu.Unlock()
+
+	c.L.Unlock()
+	defer c.L.Lock()
+
+	select {
+	case <-wake:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		i := slices.Index(c.waiters, wake)
+		if i >= 0 {
+			c.waiters = slices.Delete(c.waiters, i, i+1)
+		}
+		c.mu.Unlock()
+		if i < 0 {
+			return nil // woken while giving up
+		}
+		return ctx.Err()
+	}
+}
+
+// Signal wakes the longest waiting goroutine, if any
+func (c *Cond) Signal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.waiters) > 0 {
+		close(c.waiters[0])
+		c.waiters = c.waiters[1:]
+	}
+}
+
+// Broadcast wakes every waiting goroutine
+func (c *Cond) Broadcast() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, wake := range c.waiters {
+		close(wake)
+	}
+	c.waiters = nil
+}
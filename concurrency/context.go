@@ -35,25 +35,33 @@ func BasicContext() {
 
 // ContextWithTimeout demonstrates context with timeout
 func ContextWithTimeout() {
+	contextWithTimeout(RealClock)
+}
+
+func contextWithTimeout(clock Clock) {
 	fmt.Println("\n=== Context with Timeout ===")
+	start := clock.Now()
 
 	// Create context with 1 second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := clock.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
 	// Start a goroutine that might take longer
+	finished := make(chan struct{})
 	go func() {
+		defer close(finished)
 		select {
-		case <-time.After(2 * time.Second):
-			fmt.Println("Long operation completed")
+		case <-clock.After(2 * time.Second):
+			fmt.Printf("[%v] Long operation completed\n", elapsed(clock, start))
 		case <-ctx.Done():
-			fmt.Printf("Operation cancelled: %v\n", ctx.Err())
+			fmt.Printf("[%v] Operation cancelled: %v\n", elapsed(clock, start), ctx.Err())
 		}
 	}()
 
 	// Wait for context to timeout
 	<-ctx.Done()
-	fmt.Printf("Context expired: %v\n", ctx.Err())
+	<-finished
+	fmt.Printf("[%v] Context expired: %v\n", elapsed(clock, start), ctx.Err())
 }
 
 // ContextWithDeadline demonstrates context with deadline
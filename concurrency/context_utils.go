@@ -0,0 +1,258 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// mergedContext carries the values of both parents and is cancelled when either one is
+type mergedContext struct {
+	context.Context
+	secondary context.Context
+}
+
+// Deadline returns the earlier deadline of the two parents
+func (m mergedContext) Deadline() (time.Time, bool) {
+	d1, ok1 := m.Context.Deadline()
+	d2, ok2 := m.secondary.Deadline()
+	switch {
+	case ok1 && ok2:
+		if d2.Before(d1) {
+			return d2, true
+		}
+		return d1, true
+	case ok2:
+		return d2, true
+	default:
+		return d1, ok1
+	}
+}
+
+// Value looks the key up in the primary context first and falls back to the secondary
+func (m mergedContext) Value(key any) any {
+	if v := m.Context.Value(key); v != nil {
+		return v
+	}
+	return m.secondary.Value(key)
+}
+
+// MergeCancel returns a context that is cancelled as soon as either ctx1 or ctx2 is done.
+// Values are resolved from ctx1 first, then ctx2, and the earlier deadline wins.
+func MergeCancel(ctx1, ctx2 context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx1)
+	stop := context.AfterFunc(ctx2, func() {
+		cancel(context.Cause(ctx2))
+	})
+
+	merged := mergedContext{Context: ctx, secondary: ctx2}
+	return merged, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// Detach returns a context that keeps all values of ctx but is never cancelled
+// and has no deadline. Useful for background work that must outlive a request.
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// valuesContext stores a batch of values in a single context node
+type valuesContext struct {
+	context.Context
+	values map[any]any
+}
+
+// Value returns the batched value for key, or defers to the parent
+func (v valuesContext) Value(key any) any {
+	if val, ok := v.values[key]; ok {
+		return val
+	}
+	return v.Context.Value(key)
+}
+
+// WithValues attaches several key/value pairs at once instead of nesting
+// one context.WithValue call per key
+func WithValues(parent context.Context, values map[any]any) context.Context {
+	if len(values) == 0 {
+		return parent
+	}
+
+	copied := make(map[any]any, len(values))
+	for k, v := range values {
+		if k == nil {
+			panic("concurrency: nil context key")
+		}
+		copied[k] = v
+	}
+	return valuesContext{Context: parent, values: copied}
+}
+
+// CopyValues returns dst extended with the values of the given keys taken from src.
+// Keys that are not present in src are skipped.
+func CopyValues(dst, src context.Context, keys ...any) context.Context {
+	values := make(map[any]any, len(keys))
+	for _, key := range keys {
+		if v := src.Value(key); v != nil {
+			values[key] = v
+		}
+	}
+	return WithValues(dst, values)
+}
+
+// contextKeyRegistry tracks the names of all typed context keys
+var contextKeyRegistry = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+// ContextKey is a typed context key, so values never need a type assertion at the call site
+type ContextKey[T any] struct {
+	name *string
+}
+
+// NewContextKey registers and returns a typed context key. It panics if the
+// name is already registered, which catches accidental key collisions early.
+func NewContextKey[T any](name string) ContextKey[T] {
+	contextKeyRegistry.Lock()
+	defer contextKeyRegistry.Unlock()
+
+	if contextKeyRegistry.names[name] {
+		panic(fmt.Sprintf("concurrency: context key %q already registered", name))
+	}
+	contextKeyRegistry.names[name] = true
+	return ContextKey[T]{name: &name}
+}
+
+// RegisteredContextKeys returns the names of all registered typed keys, sorted
+func RegisteredContextKeys() []string {
+	contextKeyRegistry.Lock()
+	defer contextKeyRegistry.Unlock()
+
+	names := make([]string, 0, len(contextKeyRegistry.names))
+	for name := range contextKeyRegistry.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the registered name of the key
+func (k ContextKey[T]) Name() string {
+	if k.name == nil {
+		return ""
+	}
+	return *k.name
+}
+
+// String implements fmt.Stringer
+func (k ContextKey[T]) String() string {
+	return "ContextKey(" + k.Name() + ")"
+}
+
+// WithValue returns a copy of ctx carrying v under this key
+func (k ContextKey[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value stored under this key and whether it was present
+func (k ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// ValueOr returns the value stored under this key, or def when it is missing
+func (k ContextKey[T]) ValueOr(ctx context.Context, def T) T {
+	if v, ok := k.Value(ctx); ok {
+		return v
+	}
+	return def
+}
+
+var (
+	userIDKey    = NewContextKey[string]("userID")
+	requestIDKey = NewContextKey[string]("requestID")
+	attemptKey   = NewContextKey[int]("attempt")
+)
+
+// MergeCancelExample demonstrates cancelling work when either of two contexts ends
+func MergeCancelExample() {
+	fmt.Println("\n=== Merged Context Cancellation ===")
+
+	requestCtx, cancelRequest := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancelRequest()
+
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+
+	ctx, cancel := MergeCancel(requestCtx, shutdownCtx)
+	defer cancel()
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		fmt.Println("Server shutting down...")
+		shutdown()
+	}()
+
+	<-ctx.Done()
+	fmt.Printf("Merged context done: %v (request ctx err: %v)\n", ctx.Err(), requestCtx.Err())
+}
+
+// DetachExample demonstrates keeping values while dropping cancellation
+func DetachExample() {
+	fmt.Println("\n=== Detached Context ===")
+
+	ctx, cancel := context.WithCancel(requestIDKey.WithValue(context.Background(), "req-42"))
+	detached := Detach(ctx)
+	cancel()
+
+	id, _ := requestIDKey.Value(detached)
+	fmt.Printf("Parent err: %v, detached err: %v, request ID: %s\n", ctx.Err(), detached.Err(), id)
+}
+
+// WithValuesExample demonstrates batching values and propagating them to another context
+func WithValuesExample() {
+	fmt.Println("\n=== Batched Context Values ===")
+
+	ctx := WithValues(context.Background(), map[any]any{
+		userIDKey:    "12345",
+		requestIDKey: "req-001",
+		attemptKey:   1,
+	})
+
+	user, _ := userIDKey.Value(ctx)
+	attempt := attemptKey.ValueOr(ctx, 0)
+	fmt.Printf("User %s, attempt %d\n", user, attempt)
+
+	// Propagate only the request ID into a fresh background context
+	bg := CopyValues(context.Background(), ctx, requestIDKey)
+	_, hasUser := userIDKey.Value(bg)
+	reqID, _ := requestIDKey.Value(bg)
+	fmt.Printf("Propagated request ID: %s, user propagated: %t\n", reqID, hasUser)
+}
+
+// TypedContextKeyExample demonstrates the typed key registry
+func TypedContextKeyExample() {
+	fmt.Println("\n=== Typed Context Keys ===")
+
+	ctx := attemptKey.WithValue(context.Background(), 3)
+	if attempt, ok := attemptKey.Value(ctx); ok {
+		fmt.Printf("%s = %d\n", attemptKey, attempt)
+	}
+
+	fmt.Printf("Registered keys: %v\n", RegisteredContextKeys())
+}
+
+// RunAllContextUtilityExamples runs all context utility examples
+func RunAllContextUtilityExamples() {
+	fmt.Println("Running Context Utility Examples...")
+
+	MergeCancelExample()
+	DetachExample()
+	WithValuesExample()
+	TypedContextKeyExample()
+
+	fmt.Println("\n=== All Context Utility Examples Completed ===")
+}
@@ -0,0 +1,285 @@
+package concurrency
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Goroutine is one entry of a goroutine dump
+type Goroutine struct {
+	ID    int64
+	State string // what it is waiting on, e.g. "chan receive"
+	// Function is where it currently is, CreatedBy where it was started
+	Function  string
+	CreatedBy string
+	Stack     string
+}
+
+func (g Goroutine) String() string {
+	s := fmt.Sprintf("goroutine %d [%s] in %s", g.ID, g.State, g.Function)
+	if g.CreatedBy != "" {
+		s += ", created by " + g.CreatedBy
+	}
+	return s
+}
+
+// Goroutines returns a dump of every goroutine
+func Goroutines() []Goroutine {
+	return parseGoroutines(allStacks())
+}
+
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutines splits runtime.Stack output into goroutines. Each block
+// starts with a header such as "goroutine 7 [chan receive, 2 minutes]:"
+func parseGoroutines(dump []byte) []Goroutine {
+	var goroutines []Goroutine
+	for _, block := range bytes.Split(bytes.TrimSpace(dump), []byte("\n\n")) {
+		lines := strings.Split(string(block), "\n")
+		header, ok := strings.CutPrefix(lines[0], "goroutine ")
+		if !ok {
+			continue
+		}
+		idStr, rest, _ := strings.Cut(header, " ")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		g := Goroutine{ID: id, Stack: string(block)}
+		if start, end := strings.Index(rest, "["), strings.Index(rest, "]"); start >= 0 && end > start {
+			g.State, _, _ = strings.Cut(rest[start+1:end], ",")
+		}
+		if len(lines) > 1 {
+			g.Function = funcName(lines[1])
+		}
+		for _, line := range lines {
+			if createdBy, ok := strings.CutPrefix(line, "created by "); ok {
+				createdBy, _, _ = strings.Cut(createdBy, " in goroutine ")
+				g.CreatedBy = createdBy
+			}
+		}
+		goroutines = append(goroutines, g)
+	}
+	return goroutines
+}
+
+// funcName strips the arguments from a stack frame line
+func funcName(frame string) string {
+	if i := strings.LastIndex(frame, "("); i > 0 {
+		return frame[:i]
+	}
+	return frame
+}
+
+// currentGoroutineID reads the caller's goroutine ID from its stack header.
+// It is slow, so only diagnostics use it.
+func currentGoroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	idStr, _, _ := strings.Cut(strings.TrimPrefix(string(buf), "goroutine "), " ")
+	id, _ := strconv.ParseInt(idStr, 10, 64)
+	return id
+}
+
+// Functions whose goroutines belong to the runtime or the testing package
+// and are never reported as leaks
+var ignoredGoroutines = []string{
+	"testing.tRunner(",
+	"testing.(*M).",
+	"testing.runFuzzing(",
+	"os/signal.signal_recv(",
+	"os/signal.loop(",
+	"runtime.ensureSigM",
+}
+
+// GoroutineIDs returns the IDs of the goroutines running now, for
+// LeakedGoroutines
+func GoroutineIDs() map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, g := range Goroutines() {
+		ids[g.ID] = true
+	}
+	return ids
+}
+
+// LeakedGoroutines returns the goroutines that are not in before. Goroutines
+// often exit just after the code that started them returns, so it polls
+// with backoff until none remain or grace elapses. ignore lists extra
+// function names to allow; a goroutine is ignored when any frame of its
+// stack contains one of them.
+func LeakedGoroutines(before map[int64]bool, grace time.Duration, ignore ...string) []Goroutine {
+	deadline := time.Now().Add(grace)
+	delay := time.Millisecond
+	for {
+		var leaked []Goroutine
+		for _, g := range Goroutines() {
+			if !before[g.ID] && !g.calls(ignoredGoroutines) && !g.calls(ignore) {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, 100*time.Millisecond)
+	}
+}
+
+// calls reports whether any frame of g's stack contains one of names
+func (g Goroutine) calls(names []string) bool {
+	// Skip the "goroutine N [state]:" header, it names no functions
+	_, frames, _ := strings.Cut(g.Stack, "\n")
+	for _, name := range names {
+		if strings.Contains(frames, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// LeakCheck runs fn and returns the goroutines it started that are still
+// running once it returns, allowing them up to grace to wind down.
+// Goroutines started meanwhile by unrelated code are reported too, so run
+// it while the rest of the program is quiet.
+func LeakCheck(grace time.Duration, fn func()) []Goroutine {
+	before := GoroutineIDs()
+	fn()
+	return LeakedGoroutines(before, grace)
+}
+
+// Watchdog runs fn and, if it has not returned after deadline, writes every
+// goroutine's stack to out so you can see what it is stuck on. fn is left
+// running; Watchdog still waits for it, and reports whether it overran.
+func Watchdog(out io.Writer, deadline time.Duration, fn func()) bool {
+	done := make(chan struct{})
+	dumped := make(chan struct{})
+	timer := time.AfterFunc(deadline, func() {
+		defer close(dumped)
+		select {
+		case <-done:
+			return
+		default:
+		}
+		fmt.Fprintf(out, "⏰ Watchdog: still running after %v, goroutine dump follows\n\n%s\n", deadline, allStacks())
+	})
+
+	start := time.Now()
+	fn()
+	close(done)
+	if !timer.Stop() {
+		<-dumped // let a dump in progress finish before out is used again
+	}
+	return time.Since(start) > deadline
+}
+
+// LockOrderViolation describes a goroutine locking a mutex while holding
+// one of equal or higher rank, which can deadlock against a goroutine that
+// takes them the other way round
+type LockOrderViolation struct {
+	Goroutine int64
+	Locking   string
+	Held      []string
+}
+
+func (v LockOrderViolation) Error() string {
+	return fmt.Sprintf("lock order violation: goroutine %d locking %s while holding %s",
+		v.Goroutine, v.Locking, strings.Join(v.Held, ", "))
+}
+
+// LockOrder checks that the mutexes it creates are always locked in
+// increasing rank, catching ordering mistakes on any run instead of only
+// when the goroutines happen to interleave into a deadlock
+type LockOrder struct {
+	// OnViolation is called before the offending Lock blocks; it panics
+	// with the violation by default
+	OnViolation func(LockOrderViolation)
+
+	mu   sync.Mutex
+	held map[int64][]*OrderedMutex
+}
+
+// NewLockOrder creates an empty lock order
+func NewLockOrder() *LockOrder {
+	return &LockOrder{
+		OnViolation: func(v LockOrderViolation) { panic(v) },
+		held:        make(map[int64][]*OrderedMutex),
+	}
+}
+
+// Mutex creates a mutex that must be locked after every mutex of lower rank
+func (lo *LockOrder) Mutex(name string, rank int) *OrderedMutex {
+	return &OrderedMutex{order: lo, name: name, rank: rank}
+}
+
+// OrderedMutex is a sync.Mutex whose Lock asserts the LockOrder it was
+// created from
+type OrderedMutex struct {
+	order *LockOrder
+	name  string
+	rank  int
+	mu    sync.Mutex
+}
+
+func (m *OrderedMutex) String() string {
+	return fmt.Sprintf("%s (rank %d)", m.name, m.rank)
+}
+
+func (m *OrderedMutex) Lock() {
+	lo := m.order
+	gid := currentGoroutineID()
+
+	lo.mu.Lock()
+	var held []string
+	for _, h := range lo.held[gid] {
+		if h.rank >= m.rank {
+			held = append(held, h.String())
+		}
+	}
+	lo.mu.Unlock()
+	if len(held) > 0 {
+		lo.OnViolation(LockOrderViolation{Goroutine: gid, Locking: m.String(), Held: held})
+	}
+
+	m.mu.Lock()
+	lo.mu.Lock()
+	lo.held[gid] = append(lo.held[gid], m)
+	lo.mu.Unlock()
+}
+
+// Unlock releases m. Unlike with sync.Mutex, the goroutine that locked it
+// must unlock it for the order checks to stay accurate.
+func (m *OrderedMutex) Unlock() {
+	lo := m.order
+	gid := currentGoroutineID()
+
+	lo.mu.Lock()
+	held := lo.held[gid]
+	if i := slices.Index(held, m); i >= 0 {
+		held = slices.Delete(held, i, i+1)
+	}
+	if len(held) == 0 {
+		delete(lo.held, gid)
+	} else {
+		lo.held[gid] = held
+	}
+	lo.mu.Unlock()
+
+	m.mu.Unlock()
+}
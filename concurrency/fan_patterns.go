@@ -607,6 +607,48 @@ func FanInWithPriority() {
 	}
 }
 
+// FanOutBroadcast sends every value to all receivers, not to one of them,
+// with each receiver choosing what happens when it falls behind
+func FanOutBroadcast() {
+	fmt.Println("\n=== Fan-Out Broadcast ===")
+
+	prices := NewBroadcast[int]()
+	// The dashboard only ever needs the latest price
+	dashboard := prices.Subscribe(1, DropOldest)
+	// The audit log must see every price, so it holds the sender back
+	audit := prices.Subscribe(2, Block)
+	// A client that cannot keep up is cut off
+	client := prices.Subscribe(2, Disconnect)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		count := 0
+		for range audit.C {
+			count++
+			time.Sleep(5 * time.Millisecond)
+		}
+		fmt.Printf("📒 Audit log recorded %d prices\n", count)
+	}()
+	go func() {
+		defer wg.Done()
+		for range client.C {
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Printf("📱 Client disconnected: %t\n", client.Disconnected())
+	}()
+
+	for price := 100; price < 110; price++ {
+		n := prices.Send(price)
+		fmt.Printf("📢 Price %d reached %d of %d receivers\n", price, n, prices.Receivers())
+	}
+	fmt.Printf("📊 Dashboard shows %d, skipped %d\n", <-dashboard.C, dashboard.Dropped())
+
+	prices.Close()
+	wg.Wait()
+}
+
 // RunAllFanPatternExamples runs all fan pattern examples
 func RunAllFanPatternExamples() {
 	fmt.Println("Running Fan Pattern Examples...")
@@ -620,6 +662,8 @@ func RunAllFanPatternExamples() {
 	//FanInWithBuffering()
 	//FanOutWithLoadBalancing()
 	//FanInWithPriority()
+	PubSubExample()
+	FanOutBroadcast()
 
 	fmt.Println("\n=== All Fan Pattern Examples Completed ===")
 }
@@ -1,6 +1,7 @@
 package concurrency
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -221,26 +222,36 @@ func MutexWithConditionalAccess() {
 	fmt.Println("\n=== Mutex with Conditional Access ===")
 
 	var mu sync.Mutex
+	ready := NewCond(&mu)
 	condition := false
 	waiting := 0
 
-	// Start goroutines that wait for condition
+	// Start goroutines that wait for condition; the third gives up first
+	var wg sync.WaitGroup
 	for i := 1; i <= 3; i++ {
+		wg.Add(1)
 		go func(id int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			if id == 3 {
+				ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			}
+			defer cancel()
+
 			mu.Lock()
+			defer mu.Unlock()
 			waiting++
 			fmt.Printf("Goroutine %d: waiting for condition (waiting: %d)\n", id, waiting)
+			defer func() { waiting-- }()
 
-			// Wait for condition (simplified - in real code use sync.Cond)
+			// Wait without polling; L is unlocked while waiting
 			for !condition {
-				mu.Unlock()
-				time.Sleep(10 * time.Millisecond)
-				mu.Lock()
+				if err := ready.WaitContext(ctx); err != nil {
+					fmt.Printf("Goroutine %d: gave up waiting: %v\n", id, err)
+					return
+				}
 			}
-
-			waiting--
 			fmt.Printf("Goroutine %d: condition met, proceeding\n", id)
-			mu.Unlock()
 		}(i)
 	}
 
@@ -249,19 +260,23 @@ func MutexWithConditionalAccess() {
 	mu.Lock()
 	condition = true
 	fmt.Println("Condition set to true")
+	ready.Broadcast()
 	mu.Unlock()
 
-	time.Sleep(200 * time.Millisecond)
+	wg.Wait()
 }
 
 // MutexWithDeadlockPrevention demonstrates deadlock prevention
 func MutexWithDeadlockPrevention() {
 	fmt.Println("\n=== Mutex with Deadlock Prevention ===")
 
-	var mu1, mu2 sync.Mutex
+	// Ranked mutexes assert the ordering on every Lock, so a mistake shows
+	// up on the first run rather than as a rare deadlock
+	order := NewLockOrder()
+	mu1, mu2 := order.Mutex("mu1", 1), order.Mutex("mu2", 2)
 
 	// Function to acquire locks in consistent order
-	acquireLocks := func(id int, mu1, mu2 *sync.Mutex) {
+	acquireLocks := func(id int, mu1, mu2 *OrderedMutex) {
 		// Always acquire mu1 first, then mu2
 		mu1.Lock()
 		defer mu1.Unlock()
@@ -278,12 +293,25 @@ func MutexWithDeadlockPrevention() {
 	}
 
 	// Start goroutines with consistent lock ordering
+	var wg sync.WaitGroup
 	for i := 1; i <= 3; i++ {
-		go acquireLocks(i, &mu1, &mu2)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			acquireLocks(id, mu1, mu2)
+		}(i)
 	}
-
-	time.Sleep(300 * time.Millisecond)
+	wg.Wait()
 	fmt.Println("All goroutines completed without deadlock")
+
+	// Taking them the other way round is reported before it can deadlock
+	order.OnViolation = func(v LockOrderViolation) {
+		fmt.Printf("Caught: %v\n", v)
+	}
+	mu2.Lock()
+	mu1.Lock()
+	mu1.Unlock()
+	mu2.Unlock()
 }
 
 // MutexWithResourcePool demonstrates mutex with resource pool
@@ -362,6 +390,7 @@ func RunAllMutexExamples() {
 	MutexWithConditionalAccess()
 	MutexWithDeadlockPrevention()
 	MutexWithResourcePool()
+	PersistentSnapshotSharing()
 
 	fmt.Println("\n=== All Mutex Examples Completed ===")
 }
@@ -0,0 +1,177 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ItemError is the error returned for one item of a parallel call
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e ItemError) Unwrap() error { return e.Err }
+
+// ParallelError collects the failures of a parallel call, in item order.
+// Items never started because the context was cancelled fail with its error.
+type ParallelError struct {
+	Total  int
+	Errors []ItemError
+}
+
+func (e *ParallelError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d of %d items failed: %s", len(e.Errors), e.Total, strings.Join(msgs, "; "))
+}
+
+// Unwrap lets errors.Is and errors.As look through every item error
+func (e *ParallelError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ParallelForEach calls fn for every item with at most maxConcurrency calls
+// running at once, or one per item if maxConcurrency is not positive. It
+// stops starting new items once ctx is done and returns a *ParallelError
+// if any item failed.
+func ParallelForEach[T any](ctx context.Context, items []T, fn func(ctx context.Context, item T) error, maxConcurrency int) error {
+	return parallel(ctx, items, maxConcurrency, func(ctx context.Context, _ int, item T) error {
+		return fn(ctx, item)
+	})
+}
+
+// ParallelMap is ParallelForEach collecting fn's results. results[i] belongs
+// to items[i] and is the zero value if that item failed, so the results are
+// usable even when the error is not nil.
+func ParallelMap[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, item T) (R, error), maxConcurrency int) ([]R, error) {
+	results := make([]R, len(items))
+	err := parallel(ctx, items, maxConcurrency, func(ctx context.Context, i int, item T) error {
+		result, err := fn(ctx, item)
+		if err == nil {
+			results[i] = result
+		}
+		return err
+	})
+	return results, err
+}
+
+// ParallelMapUnordered is ParallelMap returning only the successful results,
+// in the order they finished, for callers that use the first answers first
+// or do not care about order
+func ParallelMapUnordered[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, item T) (R, error), maxConcurrency int) ([]R, error) {
+	var (
+		mu      sync.Mutex
+		results = make([]R, 0, len(items))
+	)
+	err := parallel(ctx, items, maxConcurrency, func(ctx context.Context, _ int, item T) error {
+		result, err := fn(ctx, item)
+		if err == nil {
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}
+		return err
+	})
+	return results, err
+}
+
+func parallel[T any](ctx context.Context, items []T, maxConcurrency int, fn func(ctx context.Context, i int, item T) error) error {
+	if maxConcurrency <= 0 || maxConcurrency > len(items) {
+		maxConcurrency = len(items)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []ItemError
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, maxConcurrency)
+	)
+	fail := func(i int, err error) {
+		mu.Lock()
+		errs = append(errs, ItemError{Index: i, Err: err})
+		mu.Unlock()
+	}
+
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for j := i; j < len(items); j++ {
+				fail(j, err)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, i, item); err != nil {
+				fail(i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(a, b int) bool { return errs[a].Index < errs[b].Index })
+	return &ParallelError{Total: len(items), Errors: errs}
+}
+
+// ParallelMapExample squares numbers four at a time, with some failing and a
+// deadline cutting the rest short
+func ParallelMapExample() {
+	fmt.Println("\n=== Parallel Map ===")
+
+	square := func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-time.After(time.Duration(n) * 10 * time.Millisecond):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		if n%5 == 0 {
+			return 0, fmt.Errorf("%d is a multiple of 5", n)
+		}
+		return n * n, nil
+	}
+	numbers := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	results, err := ParallelMap(context.Background(), numbers, square, 4)
+	fmt.Printf("📦 Ordered results: %v\n", results)
+	fmt.Printf("❌ %v\n", err)
+
+	unordered, _ := ParallelMapUnordered(context.Background(), []int{9, 3, 6, 1}, square, 0)
+	fmt.Printf("🏁 In completion order: %v\n", unordered)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = ParallelForEach(ctx, numbers, func(ctx context.Context, n int) error {
+		_, err := square(ctx, n)
+		return err
+	}, 2)
+	var perr *ParallelError
+	if errors.As(err, &perr) {
+		fmt.Printf("⏰ With a 50ms deadline %d of %d items failed, deadline exceeded: %v\n",
+			len(perr.Errors), perr.Total, errors.Is(err, context.DeadlineExceeded))
+	}
+}
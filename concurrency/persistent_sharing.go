@@ -0,0 +1,98 @@
+package concurrency
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// PersistentSnapshotSharing demonstrates lock-free sharing of an immutable map:
+// readers load the current version with one atomic read, and writers publish
+// a new version with compare-and-swap instead of holding a mutex
+func PersistentSnapshotSharing() {
+	fmt.Println("\n=== Lock-Free Sharing with Persistent Collections ===")
+
+	var current atomic.Pointer[data_structure.PersistentMap[string, int]]
+	current.Store(data_structure.NewPersistentMap[string, int]())
+
+	update := func(key string, delta int) {
+		for {
+			old := current.Load()
+			value, _ := old.Get(key)
+			if current.CompareAndSwap(old, old.Set(key, value+delta)) {
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var reads atomic.Int64
+	stop := make(chan struct{})
+
+	// Readers never block writers and always see a consistent version
+	for r := 1; r <= 3; r++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snapshot := current.Load()
+				total := 0
+				snapshot.Range(func(_ string, count int) bool {
+					total += count
+					return true
+				})
+				if total%2 != 0 {
+					fmt.Printf("Reader %d: saw a torn update!\n", id)
+				}
+				reads.Add(1)
+			}
+		}(r)
+	}
+
+	// Each writer bumps two counters in one version, so the total stays even
+	var writers sync.WaitGroup
+	keys := []string{"apples", "pears", "plums"}
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(id int) {
+			defer writers.Done()
+			for i := 0; i < 500; i++ {
+				for {
+					old := current.Load()
+					from, to := keys[(id+i)%len(keys)], keys[(id+i+1)%len(keys)]
+					fromCount, _ := old.Get(from)
+					toCount, _ := old.Get(to)
+					next := old.Set(from, fromCount+1).Set(to, toCount+1)
+					if current.CompareAndSwap(old, next) {
+						break
+					}
+				}
+			}
+		}(w)
+	}
+
+	writers.Wait()
+	snapshot := current.Load()
+	update("apples", 2)
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	final := current.Load()
+	final.Range(func(key string, count int) bool {
+		fmt.Printf("  %s: %d\n", key, count)
+		return true
+	})
+	before, _ := snapshot.Get("apples")
+	after, _ := final.Get("apples")
+	fmt.Printf("Readers completed %d consistent reads; an older snapshot still sees apples=%d (now %d)\n",
+		reads.Load(), before, after)
+}
@@ -0,0 +1,281 @@
+package concurrency
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// Priority orders tasks in a PriorityPool: higher priorities run first
+type Priority int
+
+// The default lanes; WithPriorityLanes allows more
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("priority %d", int(p))
+}
+
+// PriorityPool is a worker pool with a first-in first-out queue per
+// priority lane. Strict priority starves the low lanes whenever urgent work
+// keeps arriving, so the pool can age tasks (see WithAging): each interval
+// a task waits raises its effective priority one lane, until it competes
+// with the newest urgent work and, being older, wins. Subtasks inherit the priority
+// of the task that spawned them. Queues are unbounded.
+type PriorityPool struct {
+	mu      sync.Mutex
+	ready   *sync.Cond
+	lanes   []priorityLane
+	queued  int
+	seq     uint64
+	closed  bool
+	pending sync.WaitGroup
+	done    sync.WaitGroup
+
+	ageEvery   time.Duration
+	maxBoost   int
+	preemption int
+	clock      Clock
+}
+
+type priorityLane struct {
+	queue []queuedTask
+	stats laneStats
+}
+
+type queuedTask struct {
+	task     Task
+	priority Priority
+	queued   time.Time
+	seq      uint64
+}
+
+// laneStats records the wait times of one priority's tasks in constant
+// memory, however long the pool runs
+type laneStats struct {
+	submitted int64
+	ran       int64
+	boosted   int64
+	wait      data_structure.RunningStats
+	p50, p99  *data_structure.P2Quantile
+}
+
+// PriorityStats reports one priority's tasks; waits are measured from
+// submission to the start of the task and grouped by submitted priority
+type PriorityStats struct {
+	Priority  Priority
+	Queued    int
+	Submitted int64
+	Ran       int64
+	// Boosted counts tasks that ran at a higher priority than they were
+	// submitted with, thanks to aging
+	Boosted                             int64
+	MeanWait, P50Wait, P99Wait, MaxWait time.Duration
+}
+
+// PriorityOption configures a PriorityPool
+type PriorityOption func(*PriorityPool)
+
+// WithPriorityLanes sets the number of priorities, 0 to n-1; the default
+// is 3, PriorityLow to PriorityHigh
+func WithPriorityLanes(n int) PriorityOption {
+	return func(p *PriorityPool) {
+		if n > 0 {
+			p.lanes = make([]priorityLane, n)
+		}
+	}
+}
+
+// WithAging raises a waiting task's priority one lane for every interval
+// it waits, by at most maxBoost lanes; maxBoost <= 0 lets it reach the top
+// lane. Without it the pool runs strict priority order.
+func WithAging(every time.Duration, maxBoost int) PriorityOption {
+	return func(p *PriorityPool) { p.ageEvery, p.maxBoost = every, maxBoost }
+}
+
+// WithPreemption sets how many lanes more urgent a task must be, after
+// aging, to run before a task queued earlier. The default 0 runs the most
+// urgent task first, and the oldest among equals; the number of lanes
+// minus one or more runs tasks in submission order.
+func WithPreemption(lanes int) PriorityOption {
+	return func(p *PriorityPool) { p.preemption = max(lanes, 0) }
+}
+
+// WithPriorityClock sets the clock that times waits and aging
+func WithPriorityClock(clock Clock) PriorityOption {
+	return func(p *PriorityPool) { p.clock = clock }
+}
+
+// NewPriorityPool starts n workers, or one per CPU when n <= 0
+func NewPriorityPool(n int, opts ...PriorityOption) *PriorityPool {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p := &PriorityPool{lanes: make([]priorityLane, 3), clock: RealClock}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for i := range p.lanes {
+		p.lanes[i].stats.p50 = data_structure.NewP2Quantile(0.5)
+		p.lanes[i].stats.p99 = data_structure.NewP2Quantile(0.99)
+	}
+	p.ready = sync.NewCond(&p.mu)
+	p.done.Add(n)
+	for i := 0; i < n; i++ {
+		go p.run()
+	}
+	return p
+}
+
+// Submit queues task in the middle lane, PriorityNormal by default
+func (p *PriorityPool) Submit(task Task) {
+	p.SubmitPriority(Priority(len(p.lanes)/2), task)
+}
+
+// SubmitPriority queues task at priority, clamped to the pool's lanes. It
+// panics after Close, like sending on a closed channel.
+func (p *PriorityPool) SubmitPriority(priority Priority, task Task) {
+	priority = min(max(priority, 0), Priority(len(p.lanes)-1))
+	p.pending.Add(1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.pending.Done()
+		panic("concurrency: SubmitPriority on a closed PriorityPool")
+	}
+	p.seq++
+	lane := &p.lanes[priority]
+	lane.queue = append(lane.queue, queuedTask{task: task, priority: priority, queued: p.clock.Now(), seq: p.seq})
+	lane.stats.submitted++
+	p.queued++
+	p.ready.Signal()
+}
+
+// Wait blocks until every submitted task and its subtasks have run
+func (p *PriorityPool) Wait() {
+	p.pending.Wait()
+}
+
+// Close stops the workers once the queues are empty
+func (p *PriorityPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.ready.Broadcast()
+	p.mu.Unlock()
+	p.done.Wait()
+}
+
+// Stats returns the counts and wait times of each priority, lowest first
+func (p *PriorityPool) Stats() []PriorityStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]PriorityStats, len(p.lanes))
+	for i, lane := range p.lanes {
+		s := lane.stats
+		stats[i] = PriorityStats{
+			Priority:  Priority(i),
+			Queued:    len(lane.queue),
+			Submitted: s.submitted,
+			Ran:       s.ran,
+			Boosted:   s.boosted,
+		}
+		if s.ran > 0 {
+			stats[i].MeanWait = time.Duration(s.wait.Mean())
+			stats[i].P50Wait = time.Duration(s.p50.Value())
+			stats[i].P99Wait = time.Duration(s.p99.Value())
+			stats[i].MaxWait = time.Duration(s.wait.Max())
+		}
+	}
+	return stats
+}
+
+func (p *PriorityPool) run() {
+	defer p.done.Done()
+	for {
+		p.mu.Lock()
+		for p.queued == 0 && !p.closed {
+			p.ready.Wait()
+		}
+		if p.queued == 0 {
+			p.mu.Unlock()
+			return
+		}
+		next := p.nextLocked()
+		p.mu.Unlock()
+
+		next.task(func(t Task) { p.SubmitPriority(next.priority, t) })
+		p.pending.Done()
+	}
+}
+
+// nextLocked dequeues the task to run next. Each lane's head is its oldest
+// and so most aged task, so only the heads compete: the most urgent one
+// runs unless it is no more than preemption lanes above the oldest.
+func (p *PriorityPool) nextLocked() queuedTask {
+	now := p.clock.Now()
+	oldest, best := -1, -1
+	var oldestLevel, bestLevel int
+	for i := range p.lanes {
+		if len(p.lanes[i].queue) == 0 {
+			continue
+		}
+		head := p.lanes[i].queue[0]
+		level := p.effective(head, now)
+		if oldest < 0 || head.seq < p.lanes[oldest].queue[0].seq {
+			oldest, oldestLevel = i, level
+		}
+		if best < 0 || level > bestLevel || level == bestLevel && head.seq < p.lanes[best].queue[0].seq {
+			best, bestLevel = i, level
+		}
+	}
+	pick, level := oldest, oldestLevel
+	if bestLevel-oldestLevel > p.preemption {
+		pick, level = best, bestLevel
+	}
+
+	lane := &p.lanes[pick]
+	next := lane.queue[0]
+	lane.queue[0] = queuedTask{}
+	lane.queue = lane.queue[1:]
+	p.queued--
+
+	wait := now.Sub(next.queued)
+	lane.stats.ran++
+	lane.stats.wait.Add(float64(wait))
+	lane.stats.p50.Add(float64(wait))
+	lane.stats.p99.Add(float64(wait))
+	if level > int(next.priority) {
+		lane.stats.boosted++
+	}
+	return next
+}
+
+// effective is the lane a task competes in after aging
+func (p *PriorityPool) effective(t queuedTask, now time.Time) int {
+	level := int(t.priority)
+	if p.ageEvery <= 0 {
+		return level
+	}
+	boost := int(now.Sub(t.queued) / p.ageEvery)
+	if p.maxBoost > 0 {
+		boost = min(boost, p.maxBoost)
+	}
+	return min(level+boost, len(p.lanes)-1)
+}
@@ -0,0 +1,152 @@
+package concurrency
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PubSub delivers messages published on a topic to every subscriber of
+// that topic. Publishing never blocks: a subscriber whose buffer is full
+// misses the message, which is counted in its Dropped, so one slow reader
+// cannot stall the publisher or the other subscribers.
+type PubSub[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription receives the messages of one topic on C until Cancel
+type Subscription[T any] struct {
+	C       <-chan T
+	ch      chan T
+	topic   string
+	ps      *PubSub[T]
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewPubSub creates a broker with no topics
+func NewPubSub[T any]() *PubSub[T] {
+	return &PubSub[T]{topics: make(map[string]map[*Subscription[T]]struct{})}
+}
+
+// Subscribe starts receiving topic's messages, buffering up to buffer of
+// them. Subscribing to a closed PubSub returns a closed subscription.
+func (ps *PubSub[T]) Subscribe(topic string, buffer int) *Subscription[T] {
+	ch := make(chan T, max(buffer, 0))
+	sub := &Subscription[T]{C: ch, ch: ch, topic: topic, ps: ps}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		close(ch)
+		return sub
+	}
+	if ps.topics[topic] == nil {
+		ps.topics[topic] = make(map[*Subscription[T]]struct{})
+	}
+	ps.topics[topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends msg to topic's subscribers and returns how many had room
+// for it
+func (ps *PubSub[T]) Publish(topic string, msg T) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	delivered := 0
+	for sub := range ps.topics[topic] {
+		select {
+		case sub.ch <- msg:
+			delivered++
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+	return delivered
+}
+
+// Subscribers returns how many subscriptions topic has
+func (ps *PubSub[T]) Subscribers(topic string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.topics[topic])
+}
+
+// Close ends every subscription, closing their channels
+func (ps *PubSub[T]) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return
+	}
+	ps.closed = true
+	for _, subs := range ps.topics {
+		for sub := range subs {
+			sub.once.Do(func() { close(sub.ch) })
+		}
+	}
+	ps.topics = nil
+}
+
+// Topic returns the topic the subscription receives
+func (s *Subscription[T]) Topic() string { return s.topic }
+
+// Dropped returns how many messages were missed because C was full
+func (s *Subscription[T]) Dropped() int64 { return s.dropped.Load() }
+
+// Cancel stops the subscription and closes C; it is safe to call twice
+func (s *Subscription[T]) Cancel() {
+	s.ps.mu.Lock()
+	defer s.ps.mu.Unlock()
+	if subs := s.ps.topics[s.topic]; subs != nil {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(s.ps.topics, s.topic)
+		}
+	}
+	s.once.Do(func() { close(s.ch) })
+}
+
+// PubSubExample publishes prices to a fast and a slow subscriber
+func PubSubExample() {
+	fmt.Println("=== Pub/Sub Example ===")
+
+	prices := NewPubSub[float64]()
+	fast := prices.Subscribe("BTC", 16)
+	slow := prices.Subscribe("BTC", 2)
+	other := prices.Subscribe("ETH", 16)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		count := 0
+		for range fast.C {
+			count++
+		}
+		fmt.Printf("⚡ Fast subscriber got %d prices, dropped %d\n", count, fast.Dropped())
+	}()
+	go func() {
+		defer wg.Done()
+		count := 0
+		for range slow.C {
+			count++
+			time.Sleep(20 * time.Millisecond)
+		}
+		fmt.Printf("🐢 Slow subscriber got %d prices, dropped %d\n", count, slow.Dropped())
+	}()
+
+	for i := 0; i < 10; i++ {
+		n := prices.Publish("BTC", 60000+float64(i)*10)
+		fmt.Printf("📢 Published BTC price %d to %d of %d subscribers\n", i+1, n, prices.Subscribers("BTC"))
+		time.Sleep(5 * time.Millisecond)
+	}
+	other.Cancel()
+	fmt.Printf("📭 ETH subscribers after cancel: %d\n", prices.Subscribers("ETH"))
+
+	prices.Close()
+	wg.Wait()
+}
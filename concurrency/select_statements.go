@@ -63,22 +63,27 @@ func SelectWithDefault() {
 
 // SelectWithTimeout demonstrates timeout using select
 func SelectWithTimeout() {
+	selectWithTimeout(RealClock)
+}
+
+func selectWithTimeout(clock Clock) {
 	fmt.Println("\n=== Select with Timeout ===")
+	start := clock.Now()
 
-	ch := make(chan string)
+	ch := make(chan string, 1)
 
 	// Start a goroutine that takes time
 	go func() {
-		time.Sleep(2 * time.Second)
+		clock.Sleep(2 * time.Second)
 		ch <- "Slow operation completed"
 	}()
 
 	// Wait for result with timeout
 	select {
 	case result := <-ch:
-		fmt.Printf("Received: %s\n", result)
-	case <-time.After(1 * time.Second):
-		fmt.Println("Operation timed out!")
+		fmt.Printf("[%v] Received: %s\n", elapsed(clock, start), result)
+	case <-clock.After(1 * time.Second):
+		fmt.Printf("[%v] Operation timed out!\n", elapsed(clock, start))
 	}
 }
 
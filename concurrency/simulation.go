@@ -0,0 +1,81 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// simulationStart is where every simulation's VirtualClock starts, so runs
+// print the same timeline
+var simulationStart = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// Simulate runs example on a fast-forwarding VirtualClock and returns how
+// much virtual time it took. The example sees its timers fire at exactly
+// their deadlines, in the same order on every run, however long the real
+// run takes.
+func Simulate(example func(clock Clock)) time.Duration {
+	clock := NewVirtualClock(simulationStart)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go clock.FastForward(ctx)
+
+	example(clock)
+	return clock.Since(simulationStart)
+}
+
+// elapsed is the time since start on clock, rounded so that runs on
+// RealClock print steady numbers too
+func elapsed(clock Clock, start time.Time) time.Duration {
+	return clock.Since(start).Round(10 * time.Millisecond)
+}
+
+// SimulationExamples replays the timing-dependent examples on a virtual
+// clock, then steps one by hand the way a test would
+func SimulationExamples() {
+	fmt.Println("\n=== Deterministic Simulation ===")
+
+	examples := []struct {
+		name string
+		run  func(Clock)
+	}{
+		{"rate limiting", workerPoolWithRateLimiting},
+		{"context timeout", contextWithTimeout},
+		{"select timeout", selectWithTimeout},
+	}
+	for _, example := range examples {
+		started := time.Now()
+		virtual := Simulate(example.run)
+		fmt.Printf("⏩ %s: %v of virtual time in %v\n", example.name, virtual, time.Since(started).Round(time.Millisecond))
+	}
+
+	// A test advances the clock itself, once the code under test waits
+	fmt.Println("\n--- Stepping a virtual clock ---")
+	clock := NewVirtualClock(simulationStart)
+	heartbeats := make(chan time.Duration, 10)
+	ticker := clock.NewTicker(time.Second)
+	ctx, cancel := clock.WithTimeout(context.Background(), 3500*time.Millisecond)
+	defer cancel()
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C():
+				heartbeats <- now.Sub(simulationStart)
+			case <-ctx.Done():
+				close(heartbeats)
+				return
+			}
+		}
+	}()
+
+	clock.BlockUntil(2) // the ticker and the deadline
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		fmt.Printf("Heartbeat at %v\n", <-heartbeats)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if _, open := <-heartbeats; !open {
+		fmt.Printf("Stopped at %v: %v\n", clock.Since(simulationStart), ctx.Err())
+	}
+}
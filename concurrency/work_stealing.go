@@ -0,0 +1,423 @@
+package concurrency
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task is a unit of work for a Scheduler. spawn queues a subtask, which is
+// how divide-and-conquer work fans out.
+type Task func(spawn func(Task))
+
+// Scheduler runs Tasks on a fixed set of workers
+type Scheduler interface {
+	Submit(task Task)
+	// Wait blocks until every submitted task and its subtasks have run
+	Wait()
+	Close()
+}
+
+// taskDeque is a worker's double-ended queue: the owner pushes and pops
+// at the bottom (newest first, which keeps its caches warm), thieves take
+// from the top (oldest first, usually the biggest pieces of work)
+type taskDeque struct {
+	mu    sync.Mutex
+	tasks []Task
+}
+
+func (d *taskDeque) push(t Task) {
+	d.mu.Lock()
+	d.tasks = append(d.tasks, t)
+	d.mu.Unlock()
+}
+
+func (d *taskDeque) pop() (Task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.tasks)
+	if n == 0 {
+		return nil, false
+	}
+	t := d.tasks[n-1]
+	d.tasks[n-1] = nil
+	d.tasks = d.tasks[:n-1]
+	return t, true
+}
+
+func (d *taskDeque) steal() (Task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.tasks) == 0 {
+		return nil, false
+	}
+	t := d.tasks[0]
+	d.tasks[0] = nil
+	d.tasks = d.tasks[1:]
+	return t, true
+}
+
+// WorkStealingScheduler gives each worker its own deque. Subtasks go on the
+// spawning worker's deque, and idle workers steal from busy ones, so load
+// evens out without every task passing through one shared channel.
+// Submitted tasks wait in a shared first-in first-out queue that workers
+// check when their own deque is empty, so they start in order.
+type WorkStealingScheduler struct {
+	workers []*taskDeque
+	inject  taskDeque    // submitted tasks, taken oldest first
+	queued  atomic.Int64 // tasks waiting in any queue
+	pending sync.WaitGroup
+
+	mu     sync.Mutex
+	wake   *sync.Cond
+	closed bool
+	done   sync.WaitGroup
+
+	executed atomic.Int64
+	steals   atomic.Int64
+}
+
+// NewWorkStealingScheduler starts n workers, or one per CPU when n <= 0
+func NewWorkStealingScheduler(n int) *WorkStealingScheduler {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	s := &WorkStealingScheduler{workers: make([]*taskDeque, n)}
+	s.wake = sync.NewCond(&s.mu)
+	for i := range s.workers {
+		s.workers[i] = &taskDeque{}
+	}
+	s.done.Add(n)
+	for i := range s.workers {
+		go s.run(i)
+	}
+	return s
+}
+
+// Submit queues task for the next free worker
+func (s *WorkStealingScheduler) Submit(task Task) {
+	s.enqueue(&s.inject, task)
+}
+
+func (s *WorkStealingScheduler) enqueue(queue *taskDeque, task Task) {
+	s.pending.Add(1)
+	s.queued.Add(1)
+	queue.push(task)
+	// Taking the lock orders this with a worker deciding to sleep, so the
+	// signal cannot slip in between its check and its Wait
+	s.mu.Lock()
+	s.wake.Signal()
+	s.mu.Unlock()
+}
+
+func (s *WorkStealingScheduler) Wait() {
+	s.pending.Wait()
+}
+
+// Close stops the workers once every queue is empty
+func (s *WorkStealingScheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.wake.Broadcast()
+	s.mu.Unlock()
+	s.done.Wait()
+}
+
+// Stats returns how many tasks ran and how many of them were stolen
+func (s *WorkStealingScheduler) Stats() (executed, stolen int64) {
+	return s.executed.Load(), s.steals.Load()
+}
+
+func (s *WorkStealingScheduler) run(id int) {
+	defer s.done.Done()
+	rng := rand.New(rand.NewSource(int64(id) + 1))
+	local := s.workers[id]
+	spawn := func(t Task) { s.enqueue(local, t) }
+
+	for {
+		task, ok := local.pop()
+		if !ok {
+			task, ok = s.inject.steal()
+		}
+		if !ok {
+			task, ok = s.stealFrom(id, rng)
+		}
+		if ok {
+			s.queued.Add(-1)
+			task(spawn)
+			s.executed.Add(1)
+			s.pending.Done()
+			continue
+		}
+
+		s.mu.Lock()
+		for s.queued.Load() == 0 && !s.closed {
+			s.wake.Wait()
+		}
+		closed := s.closed && s.queued.Load() == 0
+		s.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// stealFrom tries every other worker once, starting at a random one so
+// thieves spread out
+func (s *WorkStealingScheduler) stealFrom(id int, rng *rand.Rand) (Task, bool) {
+	n := len(s.workers)
+	start := rng.Intn(n)
+	for i := 0; i < n; i++ {
+		victim := (start + i) % n
+		if victim == id {
+			continue
+		}
+		if task, ok := s.workers[victim].steal(); ok {
+			s.steals.Add(1)
+			return task, true
+		}
+	}
+	return nil, false
+}
+
+// ChannelPool is the classic worker pool: every task, including subtasks,
+// goes through one shared channel
+type ChannelPool struct {
+	tasks   chan Task
+	pending sync.WaitGroup
+	done    sync.WaitGroup
+}
+
+// NewChannelPool starts n workers, or one per CPU when n <= 0
+func NewChannelPool(n int) *ChannelPool {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p := &ChannelPool{tasks: make(chan Task, 4096)}
+	p.done.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer p.done.Done()
+			for task := range p.tasks {
+				task(p.spawn)
+				p.pending.Done()
+			}
+		}()
+	}
+	return p
+}
+
+func (p *ChannelPool) Submit(task Task) {
+	p.pending.Add(1)
+	p.tasks <- task
+}
+
+// spawn queues a subtask, running it inline when the channel is full:
+// blocking there could deadlock with every worker waiting to send
+func (p *ChannelPool) spawn(task Task) {
+	p.pending.Add(1)
+	select {
+	case p.tasks <- task:
+	default:
+		task(p.spawn)
+		p.pending.Done()
+	}
+}
+
+func (p *ChannelPool) Wait() {
+	p.pending.Wait()
+}
+
+func (p *ChannelPool) Close() {
+	close(p.tasks)
+	p.done.Wait()
+}
+
+// spinSink keeps the compiler from optimising spin away
+var spinSink atomic.Uint64
+
+// spin burns CPU for roughly n iterations, standing in for real work
+func spin(n int) {
+	x := uint64(n) | 1
+	for i := 0; i < n; i++ {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	spinSink.Add(x & 1)
+}
+
+// Workload submits tasks to a scheduler; record must wrap each task, so
+// the benchmark can measure how long it waited in a queue
+type Workload struct {
+	Name string
+	Run  func(s Scheduler, record func(Task) Task)
+}
+
+// MixedWorkloads are the workloads CompareSchedulers uses: many equal small
+// tasks, a few heavy tasks among light ones, a fork-join tree, and all
+// three at once
+func MixedWorkloads() []Workload {
+	uniform := func(s Scheduler, record func(Task) Task) {
+		for i := 0; i < 20000; i++ {
+			s.Submit(record(func(func(Task)) { spin(2000) }))
+		}
+	}
+	skewed := func(s Scheduler, record func(Task) Task) {
+		for i := 0; i < 2000; i++ {
+			cost := 2000
+			if i%50 == 0 {
+				cost = 2000000
+			}
+			s.Submit(record(func(func(Task)) { spin(cost) }))
+		}
+	}
+	var tree func(depth int, record func(Task) Task) Task
+	tree = func(depth int, record func(Task) Task) Task {
+		return record(func(spawn func(Task)) {
+			if depth == 0 {
+				spin(5000)
+				return
+			}
+			spawn(tree(depth-1, record))
+			spawn(tree(depth-1, record))
+			spin(500)
+		})
+	}
+	forkJoin := func(s Scheduler, record func(Task) Task) {
+		s.Submit(tree(14, record))
+	}
+
+	return []Workload{
+		{"uniform", uniform},
+		{"skewed", skewed},
+		{"fork-join", forkJoin},
+		{"mixed", func(s Scheduler, record func(Task) Task) {
+			forkJoin(s, record)
+			skewed(s, record)
+			uniform(s, record)
+		}},
+	}
+}
+
+// SchedulerResult is one scheduler's run of one workload
+type SchedulerResult struct {
+	Scheduler string
+	Workload  string
+	Tasks     int
+	Elapsed   time.Duration
+	// Latency is the time tasks waited between being queued and starting
+	P50, P99 time.Duration
+	Steals   int64
+}
+
+// Throughput is tasks completed per second
+func (r SchedulerResult) Throughput() float64 {
+	return float64(r.Tasks) / r.Elapsed.Seconds()
+}
+
+// BenchmarkScheduler runs workload on a fresh scheduler from newScheduler
+func BenchmarkScheduler(name string, newScheduler func() Scheduler, workload Workload) SchedulerResult {
+	s := newScheduler()
+	defer s.Close()
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	record := func(t Task) Task {
+		queued := time.Now()
+		return func(spawn func(Task)) {
+			waited := time.Since(queued)
+			mu.Lock()
+			latencies = append(latencies, waited)
+			mu.Unlock()
+			t(spawn)
+		}
+	}
+
+	start := time.Now()
+	workload.Run(s, record)
+	s.Wait()
+	result := SchedulerResult{Scheduler: name, Workload: workload.Name, Elapsed: time.Since(start), Tasks: len(latencies)}
+
+	slices.Sort(latencies)
+	if len(latencies) > 0 {
+		result.P50 = latencies[len(latencies)/2]
+		result.P99 = latencies[len(latencies)*99/100]
+	}
+	if ws, ok := s.(*WorkStealingScheduler); ok {
+		_, result.Steals = ws.Stats()
+	}
+	return result
+}
+
+// CompareSchedulers runs every mixed workload on the channel pool, the
+// work-stealing scheduler and the priority pool with the same number of
+// workers, and prints throughput and queueing latency side by side. The
+// channel pool's bounded buffer blocks bulk submitters, which caps its
+// queueing time for the uniform workload; the other queues are unbounded.
+// The priority pool runs everything in one lane, so it shows the cost of
+// its single locked queue and of aging bookkeeping.
+func CompareSchedulers(workers int) []SchedulerResult {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	fmt.Printf("=== Scheduler Benchmark (%d workers, %d CPUs) ===\n", workers, runtime.NumCPU())
+	fmt.Printf("%-10s %-14s %7s %10s %12s %10s %10s %7s\n",
+		"Workload", "Scheduler", "Tasks", "Elapsed", "Tasks/sec", "p50 wait", "p99 wait", "Steals")
+
+	schedulers := []struct {
+		name string
+		new  func() Scheduler
+	}{
+		{"channel-pool", func() Scheduler { return NewChannelPool(workers) }},
+		{"work-stealing", func() Scheduler { return NewWorkStealingScheduler(workers) }},
+		{"priority-pool", func() Scheduler { return NewPriorityPool(workers, WithAging(10*time.Millisecond, 0)) }},
+	}
+
+	var results []SchedulerResult
+	for _, workload := range MixedWorkloads() {
+		for _, sched := range schedulers {
+			r := BenchmarkScheduler(sched.name, sched.new, workload)
+			fmt.Printf("%-10s %-14s %7d %10v %12.0f %10v %10v %7d\n",
+				r.Workload, r.Scheduler, r.Tasks, r.Elapsed.Round(time.Millisecond), r.Throughput(),
+				r.P50.Round(time.Microsecond), r.P99.Round(time.Microsecond), r.Steals)
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// WorkStealingExample runs a fork-join task tree and shows how much of it
+// idle workers stole
+func WorkStealingExample() {
+	fmt.Println("\n=== Work-Stealing Scheduler ===")
+
+	s := NewWorkStealingScheduler(4)
+	defer s.Close()
+
+	var leaves atomic.Int64
+	var sum func(lo, hi int) Task
+	sum = func(lo, hi int) Task {
+		return func(spawn func(Task)) {
+			if hi-lo <= 1000 {
+				for i := lo; i < hi; i++ {
+					leaves.Add(int64(i))
+				}
+				return
+			}
+			mid := (lo + hi) / 2
+			spawn(sum(lo, mid))
+			spawn(sum(mid, hi))
+		}
+	}
+
+	s.Submit(sum(0, 1_000_000))
+	s.Wait()
+	executed, stolen := s.Stats()
+	fmt.Printf("Sum of 0..999999 = %d\n", leaves.Load())
+	fmt.Printf("%d tasks ran on 4 workers, %d of them stolen by idle workers\n", executed, stolen)
+}
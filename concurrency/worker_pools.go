@@ -198,6 +198,10 @@ func WorkerPoolWithErrorHandling() {
 
 // WorkerPoolWithRateLimiting demonstrates worker pool with rate limiting
 func WorkerPoolWithRateLimiting() {
+	workerPoolWithRateLimiting(RealClock)
+}
+
+func workerPoolWithRateLimiting(clock Clock) {
 	fmt.Println("\n=== Worker Pool with Rate Limiting ===")
 
 	const numWorkers = 2
@@ -205,21 +209,33 @@ func WorkerPoolWithRateLimiting() {
 
 	jobs := make(chan int, numJobs)
 	results := make(chan int, numJobs)
+	done := make(chan struct{})
+	defer close(done)
+	start := clock.Now()
 
-	// Rate limiter: process at most 1 job per 200ms per worker
-	rateLimiter := time.NewTicker(200 * time.Millisecond)
+	// Rate limiter: the pool starts at most 1 job per 200ms
+	rateLimiter := clock.NewTicker(200 * time.Millisecond)
 	defer rateLimiter.Stop()
 
-	// Start workers with rate limiting
+	// Start workers with rate limiting. A worker takes a job only after a
+	// tick, so jobs start in order whichever worker gets the tick.
 	for w := 1; w <= numWorkers; w++ {
-		go func(id int) {
-			for job := range jobs {
-				<-rateLimiter.C // Wait for rate limit
-				fmt.Printf("Worker %d processing job %d (rate limited)\n", id, job)
-				time.Sleep(50 * time.Millisecond)
+		go func() {
+			for {
+				select {
+				case <-rateLimiter.C(): // Wait for rate limit
+				case <-done:
+					return
+				}
+				job, ok := <-jobs
+				if !ok {
+					return
+				}
+				fmt.Printf("[%v] Processing job %d (rate limited)\n", elapsed(clock, start), job)
+				clock.Sleep(50 * time.Millisecond)
 				results <- job * 4
 			}
-		}(w)
+		}()
 	}
 
 	// Send jobs
@@ -231,82 +247,53 @@ func WorkerPoolWithRateLimiting() {
 	// Collect results
 	for r := 1; r <= numJobs; r++ {
 		result := <-results
-		fmt.Printf("Result: %d\n", result)
+		fmt.Printf("[%v] Result: %d\n", elapsed(clock, start), result)
 	}
 }
 
-// WorkerPoolWithPriority demonstrates worker pool with priority queues
+// WorkerPoolWithPriority keeps a PriorityPool busy with a steady stream of
+// high-priority tasks while a few low-priority ones wait, first in strict
+// priority order and then with aging, and prints each priority's waits
 func WorkerPoolWithPriority() {
 	fmt.Println("\n=== Worker Pool with Priority ===")
 
-	type PriorityJob struct {
-		ID       int
-		Priority int
-		Data     string
+	runs := []struct {
+		name string
+		opts []PriorityOption
+	}{
+		{"strict priority", nil},
+		{"aging every 25ms", []PriorityOption{WithAging(25*time.Millisecond, 0)}},
 	}
-
-	const numWorkers = 2
-	highPriorityJobs := make(chan PriorityJob, 5)
-	lowPriorityJobs := make(chan PriorityJob, 5)
-	results := make(chan string, 10)
-
-	// Start workers that prioritize high priority jobs
-	for w := 1; w <= numWorkers; w++ {
-		go func(id int) {
-			for {
-				select {
-				case job, ok := <-highPriorityJobs:
-					if !ok {
-						highPriorityJobs = nil
-					} else {
-						fmt.Printf("Worker %d processing HIGH priority job %d\n", id, job.ID)
-						time.Sleep(100 * time.Millisecond)
-						results <- fmt.Sprintf("HIGH-%d: %s", job.ID, job.Data)
-					}
-				default:
-					select {
-					case job, ok := <-lowPriorityJobs:
-						if !ok {
-							lowPriorityJobs = nil
-						} else {
-							fmt.Printf("Worker %d processing LOW priority job %d\n", id, job.ID)
-							time.Sleep(100 * time.Millisecond)
-							results <- fmt.Sprintf("LOW-%d: %s", job.ID, job.Data)
-						}
-					case <-time.After(50 * time.Millisecond):
-						// No jobs available
-					}
-				}
-
-				// Exit when both channels are closed
-				if highPriorityJobs == nil && lowPriorityJobs == nil {
-					return
-				}
-			}
-		}(w)
-	}
-
-	// Send jobs with different priorities
-	go func() {
-		for i := 1; i <= 3; i++ {
-			highPriorityJobs <- PriorityJob{ID: i, Priority: 1, Data: fmt.Sprintf("urgent-%d", i)}
+	for _, run := range runs {
+		pool := NewPriorityPool(2, run.opts...)
+		var lowFinished []time.Duration
+		var mu sync.Mutex
+		start := time.Now()
+
+		// Half a second of urgent work, arriving faster than two workers
+		// can run it, so the high lane never empties until it stops
+		for i := 0; i < 4; i++ {
+			pool.SubmitPriority(PriorityLow, func(func(Task)) {
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				lowFinished = append(lowFinished, time.Since(start).Round(time.Millisecond))
+				mu.Unlock()
+			})
 		}
-		close(highPriorityJobs)
-	}()
-
-	go func() {
-		for i := 4; i <= 6; i++ {
-			lowPriorityJobs <- PriorityJob{ID: i, Priority: 2, Data: fmt.Sprintf("normal-%d", i)}
+		for time.Since(start) < 500*time.Millisecond {
+			pool.SubmitPriority(PriorityHigh, func(func(Task)) { time.Sleep(5 * time.Millisecond) })
+			pool.Submit(func(func(Task)) { time.Sleep(time.Millisecond) })
+			time.Sleep(2 * time.Millisecond)
+		}
+		pool.Wait()
+		pool.Close()
+
+		fmt.Printf("\n%s: low-priority tasks finished at %v\n", run.name, lowFinished)
+		fmt.Printf("  %-8s %5s %8s %10s %10s %10s\n", "Priority", "Ran", "Boosted", "p50 wait", "p99 wait", "max wait")
+		for _, s := range pool.Stats() {
+			fmt.Printf("  %-8s %5d %8d %10v %10v %10v\n", s.Priority, s.Ran, s.Boosted,
+				s.P50Wait.Round(time.Millisecond), s.P99Wait.Round(time.Millisecond), s.MaxWait.Round(time.Millisecond))
 		}
-		close(lowPriorityJobs)
-	}()
-
-	// Collect results
-	time.Sleep(1 * time.Second)
-	close(results)
-
-	for result := range results {
-		fmt.Printf("Result: %s\n", result)
 	}
 }
 
@@ -504,6 +491,8 @@ func RunAllWorkerPoolExamples() {
 	//WorkerPoolWithBatching()
 	//WorkerPoolWithMetrics()
 	WorkerPoolWithDynamicScaling()
+	WorkStealingExample()
+	ParallelMapExample()
 
 	fmt.Println("\n=== All Worker Pool Examples Completed ===")
 }
@@ -0,0 +1,692 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnsupportedFormat is returned by Convert for a path whose extension is
+// not .json, .yaml, .yml or .toml
+var ErrUnsupportedFormat = errors.New("config: unsupported config file format")
+
+// Convert rewrites the config file at inputPath in the format named by
+// outputPath's extension: JSON, YAML or TOML. Keys keep their order from
+// the input, except that TOML needs a table's plain values before its
+// sub-tables, and keys FileConfig does not know are carried over too.
+//
+// The result is checked before it is written: it must read back as the
+// same document and the same FileConfig as the input, and pass
+// ValidateFileConfig. outputPath is replaced in one rename, so a failed
+// conversion leaves it as it was.
+func Convert(inputPath, outputPath string) error {
+	from, err := configFormat(inputPath)
+	if err != nil {
+		return err
+	}
+	to, err := configFormat(outputPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	doc, err := decodeDocument(from, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", inputPath, err)
+	}
+	out, err := encodeDocument(to, doc)
+	if err != nil {
+		return fmt.Errorf("cannot write %s as %s: %w", inputPath, to, err)
+	}
+	if err := checkConversion(from, data, to, out, doc); err != nil {
+		return fmt.Errorf("converting %s to %s: %w", inputPath, to, err)
+	}
+	return writeFileAtomic(outputPath, out)
+}
+
+// configFormat returns the format of a config file from its extension
+func configFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".toml":
+		return "toml", nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+}
+
+// unmarshalConfig decodes data in format into v
+func unmarshalConfig(format string, data []byte, v interface{}) error {
+	switch format {
+	case "yaml":
+		return yaml.Unmarshal(data, v)
+	case "toml":
+		return toml.Unmarshal(data, v)
+	case "json":
+		return json.Unmarshal(data, v)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// checkConversion makes sure out says what the input said
+func checkConversion(from string, in []byte, to string, out []byte, doc yaml.MapSlice) error {
+	reread, err := decodeDocument(to, out)
+	if err != nil {
+		return fmt.Errorf("the %s output does not parse: %w", to, err)
+	}
+	if path, differs := documentDiff(doc, reread, ""); differs {
+		return fmt.Errorf("the %s output reads back differently at %q", to, path)
+	}
+
+	var before, after FileConfig
+	if err := unmarshalConfig(from, in, &before); err != nil {
+		return fmt.Errorf("the input does not load as a config: %w", err)
+	}
+	if err := unmarshalConfig(to, out, &after); err != nil {
+		return fmt.Errorf("the %s output does not load as a config: %w", to, err)
+	}
+	report, err := CompareConfigs(&before, &after, to)
+	if err != nil {
+		return err
+	}
+	if report.Drifted() {
+		paths := make([]string, len(report.Changes))
+		for i, change := range report.Changes {
+			paths[i] = change.Path
+		}
+		return fmt.Errorf("the %s output loads differently at %s", to, strings.Join(paths, ", "))
+	}
+	if err := ValidateFileConfig(&after); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// decodeDocument decodes a config file keeping its key order: tables are
+// yaml.MapSlice with string keys, lists are []interface{} and values are
+// nil, bool, int64, float64 or string
+func decodeDocument(format string, data []byte) (yaml.MapSlice, error) {
+	var doc interface{}
+	var err error
+	switch format {
+	case "json":
+		doc, err = decodeJSONDocument(data)
+	case "yaml":
+		doc, err = decodeYAMLDocument(data)
+	case "toml":
+		doc, err = decodeTOMLDocument(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	table, ok := doc.(yaml.MapSlice)
+	if !ok {
+		return nil, fmt.Errorf("the top level must be a table, not %s", describeValue(doc))
+	}
+	return table, nil
+}
+
+func encodeDocument(format string, doc yaml.MapSlice) ([]byte, error) {
+	switch format {
+	case "json":
+		var b bytes.Buffer
+		if err := writeJSONValue(&b, doc, ""); err != nil {
+			return nil, err
+		}
+		b.WriteByte('\n')
+		return b.Bytes(), nil
+	case "yaml":
+		return yaml.Marshal(doc)
+	case "toml":
+		var b bytes.Buffer
+		if err := writeTOMLTable(&b, nil, doc); err != nil {
+			return nil, err
+		}
+		return bytes.TrimLeft(b.Bytes(), "\n"), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// setKey sets key in table, keeping its place if it is already there
+func setKey(table yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range table {
+		if table[i].Key == key {
+			table[i].Value = value
+			return table
+		}
+	}
+	return append(table, yaml.MapItem{Key: key, Value: value})
+}
+
+func lookupKey(table yaml.MapSlice, key interface{}) (interface{}, bool) {
+	for _, item := range table {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+func decodeJSONDocument(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	doc, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return doc, nil
+}
+
+// readJSONValue reads one value token by token, so objects keep their
+// key order
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' {
+			table := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				table = setKey(table, key.(string), value)
+			}
+			_, err := dec.Token()
+			return table, err
+		}
+		list := []interface{}{}
+		for dec.More() {
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	case json.Number:
+		if n, err := token.Int64(); err == nil {
+			return n, nil
+		}
+		return token.Float64()
+	default:
+		return token, nil
+	}
+}
+
+func decodeYAMLDocument(data []byte) (interface{}, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return normalizeYAML(doc, "")
+}
+
+// normalizeYAML gives YAML values the types of a document
+func normalizeYAML(value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		table := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			child, err := normalizeYAML(item.Value, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			table = setKey(table, key, child)
+		}
+		return table, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			child, err := normalizeYAML(item, joinPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = child
+		}
+		return list, nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("%s: %d is too large", path, v)
+		}
+		return int64(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return value, nil
+}
+
+func decodeTOMLDocument(data []byte) (interface{}, error) {
+	var tree map[string]interface{}
+	meta, err := toml.Decode(string(data), &tree)
+	if err != nil {
+		return nil, err
+	}
+	// Keys lists every key in the order it appears in the file, but not
+	// tables such as services that are only named in [services.redis]
+	order := make(map[string]int)
+	for i, key := range meta.Keys() {
+		for n := 1; n <= len(key); n++ {
+			if _, ok := order[strings.Join(key[:n], "\x00")]; !ok {
+				order[strings.Join(key[:n], "\x00")] = i
+			}
+		}
+	}
+	return orderTOML(tree, nil, order), nil
+}
+
+// orderTOML turns decoded TOML into a document, putting each table's keys
+// back in file order. Datetimes become RFC 3339 strings, as JSON and YAML
+// have no such type.
+func orderTOML(value interface{}, path []string, order map[string]int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		position := func(key string) int {
+			if i, ok := order[strings.Join(append(path, key), "\x00")]; ok {
+				return i
+			}
+			return len(order)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if pi, pj := position(keys[i]), position(keys[j]); pi != pj {
+				return pi < pj
+			}
+			return keys[i] < keys[j]
+		})
+		table := make(yaml.MapSlice, len(keys))
+		for i, key := range keys {
+			table[i] = yaml.MapItem{Key: key, Value: orderTOML(v[key], append(path[:len(path):len(path)], key), order)}
+		}
+		return table
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = orderTOML(item, path, order)
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = orderTOML(item, path, order)
+		}
+		return list
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// writeJSONValue writes value as indented JSON
+func writeJSONValue(b *bytes.Buffer, value interface{}, indent string) error {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i, item := range v {
+			b.WriteString(indent + "  ")
+			writeJSONString(b, item.Key.(string))
+			b.WriteString(": ")
+			if err := writeJSONValue(b, item.Value, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, item := range v {
+			b.WriteString(indent + "  ")
+			if err := writeJSONValue(b, item, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+	case string:
+		writeJSONString(b, v)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Errorf("JSON has no %v", v)
+		}
+		b.WriteString(formatFloat(v))
+	case nil:
+		b.WriteString("null")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	return nil
+}
+
+// writeJSONString writes s quoted, leaving <, > and & as they are
+func writeJSONString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	b.Truncate(b.Len() - 1)
+}
+
+// writeTOMLTable writes a table's plain values, then its sub-tables and
+// arrays of tables under their own headers
+func writeTOMLTable(b *bytes.Buffer, path []string, table yaml.MapSlice) error {
+	for _, item := range table {
+		key := item.Key.(string)
+		if isTOMLTable(item.Value) || isTOMLTableArray(item.Value) {
+			continue
+		}
+		value, err := tomlValue(item.Value, joinPath(strings.Join(path, "."), key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s = %s\n", tomlKey(key), value)
+	}
+
+	for _, item := range table {
+		key := item.Key.(string)
+		sub := append(path[:len(path):len(path)], key)
+		switch v := item.Value.(type) {
+		case yaml.MapSlice:
+			// A table holding only tables needs no header of its own
+			if len(v) == 0 || hasTOMLValues(v) {
+				fmt.Fprintf(b, "\n[%s]\n", tomlHeader(sub))
+			}
+			if err := writeTOMLTable(b, sub, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			if !isTOMLTableArray(v) {
+				continue
+			}
+			for _, element := range v {
+				fmt.Fprintf(b, "\n[[%s]]\n", tomlHeader(sub))
+				if err := writeTOMLTable(b, sub, element.(yaml.MapSlice)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isTOMLTable(value interface{}) bool {
+	_, ok := value.(yaml.MapSlice)
+	return ok
+}
+
+// isTOMLTableArray reports whether value is a non-empty list of tables,
+// written as [[name]] sections
+func isTOMLTableArray(value interface{}) bool {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return false
+	}
+	for _, element := range list {
+		if !isTOMLTable(element) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTOMLValues(table yaml.MapSlice) bool {
+	for _, item := range table {
+		if !isTOMLTable(item.Value) && !isTOMLTableArray(item.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// tomlValue formats a value written inline: a scalar, an array, or a
+// table inside an array
+func tomlValue(value interface{}, path string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("%s is null, which TOML cannot express", path)
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan", nil
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		}
+		return formatFloat(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := tomlValue(item, joinPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case yaml.MapSlice:
+		items := make([]string, len(v))
+		for i, item := range v {
+			key := item.Key.(string)
+			s, err := tomlValue(item.Value, joinPath(path, key))
+			if err != nil {
+				return "", err
+			}
+			items[i] = tomlKey(key) + " = " + s
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("%s: cannot write %T as TOML", path, value)
+}
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlHeader(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// tomlString quotes s as a TOML basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// formatFloat writes f with a decimal point or exponent, so it reads back
+// as a float and not an integer
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0"
+	}
+	return s
+}
+
+// documentDiff returns the first path at which two documents differ; key
+// order and the difference between 2 and 2.0 do not count
+func documentDiff(a, b interface{}, path string) (string, bool) {
+	switch a := a.(type) {
+	case yaml.MapSlice:
+		other, ok := b.(yaml.MapSlice)
+		if !ok {
+			return path, true
+		}
+		for _, item := range a {
+			value, ok := lookupKey(other, item.Key)
+			if !ok {
+				return joinPath(path, item.Key.(string)), true
+			}
+			if diff, differs := documentDiff(item.Value, value, joinPath(path, item.Key.(string))); differs {
+				return diff, true
+			}
+		}
+		for _, item := range other {
+			if _, ok := lookupKey(a, item.Key); !ok {
+				return joinPath(path, item.Key.(string)), true
+			}
+		}
+		return "", false
+	case []interface{}:
+		other, ok := b.([]interface{})
+		if !ok || len(a) != len(other) {
+			return path, true
+		}
+		for i := range a {
+			if diff, differs := documentDiff(a[i], other[i], joinPath(path, strconv.Itoa(i))); differs {
+				return diff, true
+			}
+		}
+		return "", false
+	case int64, float64:
+		x, xok := asFloat(a)
+		y, yok := asFloat(b)
+		return path, !xok || !yok || (x != y && !(math.IsNaN(x) && math.IsNaN(y)))
+	}
+	return path, a != b
+}
+
+func asFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}:
+		return "a list"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("a %T", value)
+}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	reflectops "github.com/jerrychou/go-practice/reflect"
 )
 
 // EnvConfig handles environment variable configuration following 12-factor app principles
@@ -20,10 +22,13 @@ type EnvConfig struct {
 	ServerPort int
 
 	// Database settings
-	DatabaseURL      string
+	DatabaseURL      string `sensitive:"url"`
 	DatabaseMaxConns int
 	DatabaseTimeout  time.Duration
 
+	// Upload limit, e.g. MAX_UPLOAD_SIZE=100MB
+	MaxUploadSize ByteSize
+
 	// Logging settings
 	LogLevel  string
 	LogFormat string
@@ -34,12 +39,12 @@ type EnvConfig struct {
 	EnableCORS    bool
 
 	// External services
-	RedisURL     string
+	RedisURL     string `sensitive:"url"`
 	CacheTimeout time.Duration
 
 	// Security
-	JWTSecret     string
-	SessionSecret string
+	JWTSecret     string `sensitive:"true"`
+	SessionSecret string `sensitive:"true"`
 }
 
 // LoadFromEnv loads configuration from environment variables
@@ -59,6 +64,7 @@ func LoadFromEnv() (*EnvConfig, error) {
 	config.DatabaseURL = getEnv("DATABASE_URL", "postgres://localhost:5432/mydb")
 	config.DatabaseMaxConns = getEnvAsInt("DATABASE_MAX_CONNS", 10)
 	config.DatabaseTimeout = getEnvAsDuration("DATABASE_TIMEOUT", 30*time.Second)
+	config.MaxUploadSize = getEnvAsByteSize("MAX_UPLOAD_SIZE", 32*MiB)
 
 	// Logging settings
 	config.LogLevel = getEnv("LOG_LEVEL", "info")
@@ -188,7 +194,7 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 		return defaultValue
 	}
 
-	value, err := time.ParseDuration(valueStr)
+	value, err := ParseDuration(valueStr)
 	if err != nil {
 		fmt.Printf("Warning: Invalid duration value for %s: %s, using default: %v\n", key, valueStr, defaultValue)
 		return defaultValue
@@ -197,6 +203,21 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+func getEnvAsByteSize(key string, defaultValue ByteSize) ByteSize {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := ParseByteSize(valueStr)
+	if err != nil {
+		fmt.Printf("Warning: Invalid size value for %s: %s, using default: %v\n", key, valueStr, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -213,7 +234,7 @@ func (c *EnvConfig) PrintConfig() {
 	fmt.Printf("App Version: %s\n", c.AppVersion)
 	fmt.Printf("Environment: %s\n", c.AppEnvironment)
 	fmt.Printf("Server: %s\n", c.GetServerAddress())
-	fmt.Printf("Database URL: %s\n", maskSensitiveData(c.DatabaseURL))
+	fmt.Printf("Database URL: %s\n", reflectops.MaskURL(c.DatabaseURL))
 	fmt.Printf("Database Max Connections: %d\n", c.DatabaseMaxConns)
 	fmt.Printf("Database Timeout: %v\n", c.DatabaseTimeout)
 	fmt.Printf("Log Level: %s\n", c.LogLevel)
@@ -221,25 +242,9 @@ func (c *EnvConfig) PrintConfig() {
 	fmt.Printf("Metrics Enabled: %t\n", c.EnableMetrics)
 	fmt.Printf("Debug Enabled: %t\n", c.EnableDebug)
 	fmt.Printf("CORS Enabled: %t\n", c.EnableCORS)
-	fmt.Printf("Redis URL: %s\n", maskSensitiveData(c.RedisURL))
+	fmt.Printf("Redis URL: %s\n", reflectops.MaskURL(c.RedisURL))
 	fmt.Printf("Cache Timeout: %v\n", c.CacheTimeout)
+	fmt.Printf("Max Upload Size: %v\n", c.MaxUploadSize)
 	fmt.Printf("JWT Secret Set: %t\n", c.JWTSecret != "")
 	fmt.Printf("Session Secret Set: %t\n", c.SessionSecret != "")
 }
-
-// maskSensitiveData masks sensitive information in URLs
-func maskSensitiveData(url string) string {
-	if url == "" {
-		return ""
-	}
-
-	// Simple masking for demonstration - in production, use proper URL parsing
-	if strings.Contains(url, "@") {
-		parts := strings.Split(url, "@")
-		if len(parts) == 2 {
-			return "***@" + parts[1]
-		}
-	}
-
-	return url
-}
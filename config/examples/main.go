@@ -3,21 +3,34 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/reflect"
 )
 
+var logger = format.GetLogger("main")
+
 func main() {
+	// go run main.go convert production.toml production.yaml
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		runConvert(os.Args[2:])
+		return
+	}
+
 	fmt.Println("=== Configuration Management Package Demo ===")
 	fmt.Println("This demonstrates all features of the config package:")
 	fmt.Println("- Environment Variables (12-factor app)")
 	fmt.Println("- Configuration Files (JSON, TOML, YAML)")
 	fmt.Println("- Configuration Validation")
 	fmt.Println("- Hot Reloading")
+	fmt.Println("- Snapshots and Drift Detection")
+	fmt.Println("- Format Conversion")
+	fmt.Println("- Temporary Overrides")
 
 	// Example 1: Environment Variables
 	fmt.Println("\n1. Environment Variables Configuration")
@@ -35,6 +48,18 @@ func main() {
 	fmt.Println("\n4. Hot Reloading")
 	exampleHotReload()
 
+	// Example 5: Snapshots and Drift Detection
+	fmt.Println("\n5. Snapshots and Drift Detection")
+	exampleDriftDetection()
+
+	// Example 6: Format Conversion
+	fmt.Println("\n6. Format Conversion")
+	exampleConversion()
+
+	// Example 7: Temporary Overrides
+	fmt.Println("\n7. Temporary Overrides")
+	exampleOverrides()
+
 	fmt.Println("\n=== Demo Complete ===")
 	fmt.Println("For more examples, see the README.md files in this directory.")
 }
@@ -53,7 +78,7 @@ func exampleEnvironmentConfig() {
 	// Load configuration from environment
 	envConfig, err := config.LoadFromEnv()
 	if err != nil {
-		log.Printf("Failed to load environment configuration: %v", err)
+		logger.Errorf("Failed to load environment configuration: %v", err)
 		return
 	}
 
@@ -61,6 +86,7 @@ func exampleEnvironmentConfig() {
 	fmt.Printf("  App: %s v%s (%s)\n", envConfig.AppName, envConfig.AppVersion, envConfig.AppEnvironment)
 	fmt.Printf("  Server: %s\n", envConfig.GetServerAddress())
 	fmt.Printf("  Debug: %t\n", envConfig.EnableDebug)
+	fmt.Printf("  Redacted: %s\n", reflect.Dump(envConfig))
 
 	// Validate
 	if err := config.ValidateEnvConfig(envConfig); err != nil {
@@ -180,7 +206,7 @@ func exampleHotReload() {
 
 	// Create initial configuration
 	if err := config.CreateDefaultConfig(configPath); err != nil {
-		log.Printf("Failed to create test config: %v", err)
+		logger.Errorf("Failed to create test config: %v", err)
 		return
 	}
 
@@ -188,7 +214,7 @@ func exampleHotReload() {
 	validator := config.CreateDefaultSchema()
 	reloadableConfig, err := config.NewReloadableConfig(configPath, &config.FileConfig{}, validator)
 	if err != nil {
-		log.Printf("Failed to create reloadable config: %v", err)
+		logger.Errorf("Failed to create reloadable config: %v", err)
 		return
 	}
 
@@ -200,21 +226,39 @@ func exampleHotReload() {
 		}
 		return nil
 	})
+	reloadableConfig.AddChangeCallback(func(oldCfg, newCfg interface{}) error {
+		patch, err := reflect.Diff(oldCfg, newCfg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("       %d %s:\n", len(patch), format.Plural(len(patch), "change", "changes"))
+		fmt.Print(indent(patch.Render(true), "         "))
+		return nil
+	})
 
 	// Initial load
 	if err := reloadableConfig.Reload(); err != nil {
-		log.Printf("Failed to load initial config: %v", err)
+		logger.Errorf("Failed to load initial config: %v", err)
 		return
 	}
 
 	fmt.Println("  ✓ Initial configuration loaded")
 
+	// A goroutine owning the server settings reacts to them in a select loop
+	serverUpdates, stopWatching := config.Watch[config.ServerConfig](reloadableConfig, "server")
+	defer stopWatching()
+	go func() {
+		for update := range serverUpdates {
+			fmt.Printf("       👀 server settings %v: port %d -> %d\n", update.Changed, update.Old.Port, update.New.Port)
+		}
+	}()
+
 	// Create hot reload manager
 	manager := config.NewHotReloadManager()
 
 	// Add configuration to hot reload
 	if err := manager.AddConfig("demo", configPath, reloadableConfig.Reload); err != nil {
-		log.Printf("Failed to add config to hot reload: %v", err)
+		logger.Errorf("Failed to add config to hot reload: %v", err)
 		return
 	}
 
@@ -223,7 +267,7 @@ func exampleHotReload() {
 	defer cancel()
 
 	if err := manager.StartAll(ctx); err != nil {
-		log.Printf("Failed to start hot reload: %v", err)
+		logger.Errorf("Failed to start hot reload: %v", err)
 		return
 	}
 
@@ -236,7 +280,7 @@ func exampleHotReload() {
 	loader := config.NewConfigLoader(configPath)
 	currentConfig, err := loader.Load()
 	if err != nil {
-		log.Printf("Failed to load current config: %v", err)
+		logger.Errorf("Failed to load current config: %v", err)
 		return
 	}
 
@@ -245,20 +289,191 @@ func exampleHotReload() {
 	currentConfig.Server.Port = 9999
 
 	// Save modified configuration
+	original, _ := os.ReadFile(configPath)
 	if err := loader.Save(currentConfig); err != nil {
-		log.Printf("Failed to save modified config: %v", err)
+		logger.Errorf("Failed to save modified config: %v", err)
 		return
 	}
+	modified, _ := os.ReadFile(configPath)
 
 	fmt.Println("  ✓ Configuration file modified")
+	fmt.Print(indent(format.ColorizeDiff(format.UnifiedDiff("a/"+filepath.Base(configPath), "b/"+filepath.Base(configPath), string(original), string(modified), 1)), "    "))
 
 	// Wait for reload
 	time.Sleep(2 * time.Second)
 
 	// Stop hot reload
 	if err := manager.StopAll(); err != nil {
-		log.Printf("Failed to stop hot reload: %v", err)
+		logger.Errorf("Failed to stop hot reload: %v", err)
 	} else {
 		fmt.Println("  ✓ Hot reload stopped")
 	}
 }
+
+func exampleDriftDetection() {
+	dir, err := os.MkdirTemp("", "config-drift")
+	if err != nil {
+		logger.Errorf("Failed to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := config.CreateDefaultConfig(configPath); err != nil {
+		logger.Errorf("Failed to create config: %v", err)
+		return
+	}
+	loader := config.NewConfigLoader(configPath)
+	running, err := loader.Load()
+	if err != nil {
+		logger.Errorf("Failed to load config: %v", err)
+		return
+	}
+
+	store, err := config.NewFileSnapshotStore(filepath.Join(dir, "snapshots"))
+	if err != nil {
+		logger.Errorf("Failed to create snapshot store: %v", err)
+		return
+	}
+	detector := config.NewDriftDetector(func() interface{} { return running }, config.FileSource(configPath), store, time.Minute)
+	detector.OnDrift(func(report *config.DriftReport) {
+		fmt.Print(indent(report.String(), "    ⚠️  "))
+	})
+
+	ctx := context.Background()
+	if report, err := detector.Check(ctx); err == nil {
+		fmt.Printf("  ✓ Running config %.12s matches the file: %t\n", report.Running.Hash, !report.Drifted())
+	}
+
+	// Someone edits the file, but this process keeps its old settings
+	edited := *running
+	edited.Server.Port = 9090
+	edited.Security.JWTSecret = "rotated-secret"
+	if err := loader.Save(&edited); err != nil {
+		logger.Errorf("Failed to save config: %v", err)
+		return
+	}
+	if _, err := detector.Check(ctx); err != nil {
+		logger.Errorf("Drift check failed: %v", err)
+	}
+
+	// The process picks up the change and a new snapshot is recorded
+	running = &edited
+	if report, err := detector.Check(ctx); err == nil {
+		fmt.Printf("  ✓ After reloading, drifted: %t\n", report.Drifted())
+	}
+	snapshots, _ := store.List(ctx, 0)
+	for _, snapshot := range snapshots {
+		fmt.Printf("  📸 %s %.12s %s\n", snapshot.Time.Format(time.RFC3339), snapshot.Hash, snapshot.Source)
+	}
+}
+
+func exampleConversion() {
+	dir, err := os.MkdirTemp("", "config-convert")
+	if err != nil {
+		logger.Errorf("Failed to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// TOML to YAML to JSON and back, each step checked before it is written
+	steps := []struct{ from, to string }{
+		{"production.toml", filepath.Join(dir, "production.yaml")},
+		{filepath.Join(dir, "production.yaml"), filepath.Join(dir, "production.json")},
+		{filepath.Join(dir, "production.json"), filepath.Join(dir, "production.toml")},
+	}
+	for _, step := range steps {
+		if err := config.Convert(step.from, step.to); err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  ✓ %s -> %s\n", filepath.Base(step.from), filepath.Base(step.to))
+	}
+	original, _ := os.ReadFile("production.toml")
+	converted, _ := os.ReadFile(filepath.Join(dir, "production.toml"))
+	fmt.Printf("  ✓ Round trip gives back the same TOML: %t\n", string(original) == string(converted))
+
+	yamlConfig, _ := os.ReadFile(filepath.Join(dir, "production.yaml"))
+	lines := strings.SplitN(string(yamlConfig), "\n", 8)
+	fmt.Print(indent(strings.Join(lines[:7], "\n"), "    "))
+
+	// TOML has no null, so this conversion is refused and nothing is written
+	nullPath := filepath.Join(dir, "null.json")
+	os.WriteFile(nullPath, []byte(`{"app": {"name": "demo", "version": "1.0.0", "environment": "development"}, "logging": {"filename": null}}`), 0644)
+	if err := config.Convert(nullPath, filepath.Join(dir, "null.toml")); err != nil {
+		fmt.Printf("  ✓ Refused: %v\n", err)
+	}
+	// An invalid config converts to nothing either
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(invalidPath, []byte("app:\n  name: demo\n  version: one\n"), 0644)
+	if err := config.Convert(invalidPath, filepath.Join(dir, "invalid.json")); err != nil {
+		fmt.Printf("  ✓ Refused: %.100s...\n", err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "invalid.json"))
+	fmt.Printf("  ✓ Nothing written for refused conversions: %t\n", os.IsNotExist(err))
+}
+
+func exampleOverrides() {
+	base, err := config.NewConfigLoader("production.toml").Load()
+	if err != nil {
+		logger.Errorf("Failed to load config: %v", err)
+		return
+	}
+	overrides := config.NewOverrides(*base)
+	defer overrides.Stop()
+	overrides.OnChange(func(cfg config.FileConfig) error {
+		fmt.Printf("    🔄 Applied: log level %s, read timeout %v\n", cfg.Logging.Level, cfg.Server.ReadTimeout)
+		return nil
+	})
+
+	// Debug logging while an incident is looked at, reverting on its own
+	if _, err := overrides.Set("logging.level", "debug", 300*time.Millisecond, "alice", "tracing INC-42"); err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	overrides.Set("server.read_timeout", "2m", time.Hour, "bob", "slow uploads")
+	for _, override := range overrides.Active() {
+		fmt.Printf("  ✓ %s = %v by %s until %s\n", override.Path, override.Value, override.SetBy, override.ExpiresAt.Format(time.TimeOnly))
+	}
+	fmt.Printf("  ✓ Effective log level: %s, underneath: %s\n", overrides.Get().Logging.Level, overrides.Base().Logging.Level)
+
+	// Settings that do not exist or values of the wrong type are refused
+	if _, err := overrides.Set("server.prot", 9000, time.Minute, "bob", ""); err != nil {
+		fmt.Printf("  ✓ Refused: %v\n", err)
+	}
+	if _, err := overrides.Set("server.port", "high", time.Minute, "bob", ""); err != nil {
+		fmt.Printf("  ✓ Refused: %.80s...\n", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	fmt.Printf("  ✓ After the TTL the log level is %s again\n", overrides.Get().Logging.Level)
+	overrides.Clear("server.read_timeout", "bob")
+
+	fmt.Println("  📜 Audit trail:")
+	for _, event := range overrides.History(0) {
+		fmt.Printf("     %s %-7s %s = %v (set by %s) %s\n", event.Time.Format(time.TimeOnly), event.Action,
+			event.Override.Path, event.Override.Value, event.Override.SetBy, event.By)
+	}
+}
+
+// runConvert converts a config file to another format, e.g.
+// convert development.yaml development.toml
+func runConvert(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: convert <input.json|yaml|toml> <output.json|yaml|toml>")
+		os.Exit(2)
+	}
+	if err := config.Convert(args[0], args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Converted %s to %s and validated it\n", args[0], args[1])
+}
+
+// indent prefixes every line of text with prefix
+func indent(text, prefix string) string {
+	if text == "" {
+		return ""
+	}
+	return prefix + strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n"+prefix) + "\n"
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v2"
+
+	reflectops "github.com/jerrychou/go-practice/reflect"
 )
 
 // FileConfig represents configuration loaded from files
@@ -25,32 +27,48 @@ type FileConfig struct {
 }
 
 type AppConfig struct {
-	Name        string `json:"name" yaml:"name" toml:"name"`
-	Version     string `json:"version" yaml:"version" toml:"version"`
-	Environment string `json:"environment" yaml:"environment" toml:"environment"`
+	Name        string `json:"name" yaml:"name" toml:"name" validate:"required,min=1,max=50"`
+	Version     string `json:"version" yaml:"version" toml:"version" validate:"required,pattern=^[0-9]+[.][0-9]+[.][0-9]+$"`
+	Environment string `json:"environment" yaml:"environment" toml:"environment" validate:"required,oneof=development staging production"`
 	Debug       bool   `json:"debug" yaml:"debug" toml:"debug"`
 }
 
 type ServerConfig struct {
-	Host         string        `json:"host" yaml:"host" toml:"host"`
-	Port         int           `json:"port" yaml:"port" toml:"port"`
-	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout"`
-	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout"`
-	IdleTimeout  time.Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`
+	Host         string   `json:"host" yaml:"host" toml:"host" validate:"required,pattern=^[a-zA-Z0-9.-]+$"`
+	Port         int      `json:"port" yaml:"port" toml:"port" validate:"required,min=1,max=65535"`
+	ReadTimeout  Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`
+	// MaxUploadSize caps request bodies of uploads, e.g. "32MiB"
+	MaxUploadSize ByteSize `json:"max_upload_size" yaml:"max_upload_size" toml:"max_upload_size"`
 }
 
 type DatabaseConfig struct {
-	URL               string        `json:"url" yaml:"url" toml:"url"`
-	MaxConnections    int           `json:"max_connections" yaml:"max_connections" toml:"max_connections"`
-	MinConnections    int           `json:"min_connections" yaml:"min_connections" toml:"min_connections"`
-	ConnectionTimeout time.Duration `json:"connection_timeout" yaml:"connection_timeout" toml:"connection_timeout"`
-	QueryTimeout      time.Duration `json:"query_timeout" yaml:"query_timeout" toml:"query_timeout"`
-	SSLMode           string        `json:"ssl_mode" yaml:"ssl_mode" toml:"ssl_mode"`
+	URL               string   `json:"url" yaml:"url" toml:"url" sensitive:"url"`
+	MaxConnections    int      `json:"max_connections" yaml:"max_connections" toml:"max_connections" validate:"required,min=1,max=100"`
+	MinConnections    int      `json:"min_connections" yaml:"min_connections" toml:"min_connections"`
+	ConnectionTimeout Duration `json:"connection_timeout" yaml:"connection_timeout" toml:"connection_timeout"`
+	QueryTimeout      Duration `json:"query_timeout" yaml:"query_timeout" toml:"query_timeout"`
+	SSLMode           string   `json:"ssl_mode" yaml:"ssl_mode" toml:"ssl_mode"`
+	// Profile records every query for the admin API; it is always on in
+	// the development environment
+	Profile QueryProfileConfig `json:"profile" yaml:"profile" toml:"profile"`
+}
+
+// QueryProfileConfig controls query profiling. Queries slower than
+// SlowThreshold are flagged and, with Explain, explained; Analyze uses
+// EXPLAIN ANALYZE, which runs them again.
+type QueryProfileConfig struct {
+	Enabled       bool     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	Capacity      int      `json:"capacity" yaml:"capacity" toml:"capacity"`
+	SlowThreshold Duration `json:"slow_threshold" yaml:"slow_threshold" toml:"slow_threshold"`
+	Explain       bool     `json:"explain" yaml:"explain" toml:"explain"`
+	Analyze       bool     `json:"analyze" yaml:"analyze" toml:"analyze"`
 }
 
 type LoggingConfig struct {
-	Level    string `json:"level" yaml:"level" toml:"level"`
-	Format   string `json:"format" yaml:"format" toml:"format"`
+	Level    string `json:"level" yaml:"level" toml:"level" validate:"required,oneof=debug info warn error fatal"`
+	Format   string `json:"format" yaml:"format" toml:"format" validate:"required,oneof=json text"`
 	Output   string `json:"output" yaml:"output" toml:"output"`
 	Filename string `json:"filename" yaml:"filename" toml:"filename"`
 	MaxSize  int    `json:"max_size" yaml:"max_size" toml:"max_size"`
@@ -63,6 +81,9 @@ type FeatureConfig struct {
 	EnableCORS      bool `json:"enable_cors" yaml:"enable_cors" toml:"enable_cors"`
 	EnableCache     bool `json:"enable_cache" yaml:"enable_cache" toml:"enable_cache"`
 	EnableRateLimit bool `json:"enable_rate_limit" yaml:"enable_rate_limit" toml:"enable_rate_limit"`
+	// EnableAdminAPI serves /admin/runtime/, where admins change log
+	// levels and feature flags, reload config and drain the server
+	EnableAdminAPI bool `json:"enable_admin_api" yaml:"enable_admin_api" toml:"enable_admin_api"`
 }
 
 type ServiceConfig struct {
@@ -71,22 +92,73 @@ type ServiceConfig struct {
 }
 
 type RedisConfig struct {
-	URL      string        `json:"url" yaml:"url" toml:"url"`
-	Timeout  time.Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
-	PoolSize int           `json:"pool_size" yaml:"pool_size" toml:"pool_size"`
+	URL      string   `json:"url" yaml:"url" toml:"url" sensitive:"url"`
+	Timeout  Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	PoolSize int      `json:"pool_size" yaml:"pool_size" toml:"pool_size"`
 }
 
 type CacheConfig struct {
-	TTL      time.Duration `json:"ttl" yaml:"ttl" toml:"ttl"`
-	MaxSize  int           `json:"max_size" yaml:"max_size" toml:"max_size"`
-	Strategy string        `json:"strategy" yaml:"strategy" toml:"strategy"`
+	TTL      Duration `json:"ttl" yaml:"ttl" toml:"ttl"`
+	MaxSize  int      `json:"max_size" yaml:"max_size" toml:"max_size"`
+	MaxBytes ByteSize `json:"max_bytes" yaml:"max_bytes" toml:"max_bytes"`
+	Strategy string   `json:"strategy" yaml:"strategy" toml:"strategy"`
 }
 
 type SecurityConfig struct {
-	JWTSecret     string        `json:"jwt_secret" yaml:"jwt_secret" toml:"jwt_secret"`
-	SessionSecret string        `json:"session_secret" yaml:"session_secret" toml:"session_secret"`
-	TokenExpiry   time.Duration `json:"token_expiry" yaml:"token_expiry" toml:"token_expiry"`
-	BCryptCost    int           `json:"bcrypt_cost" yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	JWTSecret     string           `json:"jwt_secret" yaml:"jwt_secret" toml:"jwt_secret" sensitive:"true" validate:"min=32"`
+	SessionSecret string           `json:"session_secret" yaml:"session_secret" toml:"session_secret" sensitive:"true"`
+	TokenExpiry   Duration         `json:"token_expiry" yaml:"token_expiry" toml:"token_expiry"`
+	BCryptCost    int              `json:"bcrypt_cost" yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	IPFilter      IPFilterConfig   `json:"ip_filter" yaml:"ip_filter" toml:"ip_filter"`
+	CORS          CORSConfig       `json:"cors" yaml:"cors" toml:"cors"`
+	UploadScan    UploadScanConfig `json:"upload_scan" yaml:"upload_scan" toml:"upload_scan"`
+}
+
+// UploadScanConfig tunes the checks run on uploads before they are stored.
+// Command adds an external scanner, e.g. ["clamdscan", "--no-summary"],
+// which gets the file's path and exits 1 when it finds something.
+type UploadScanConfig struct {
+	Command []string `json:"command" yaml:"command" toml:"command"`
+	Timeout Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	// MaxCompressionRatio and MaxUnpackedSize bound what zip and gzip
+	// uploads may unpack to, 100 and "1GiB" if unset
+	MaxCompressionRatio float64  `json:"max_compression_ratio" yaml:"max_compression_ratio" toml:"max_compression_ratio"`
+	MaxUnpackedSize     ByteSize `json:"max_unpacked_size" yaml:"max_unpacked_size" toml:"max_unpacked_size"`
+	// FailOpen stores uploads the external scanner could not check
+	FailOpen bool `json:"fail_open" yaml:"fail_open" toml:"fail_open"`
+}
+
+// IPFilterConfig holds client IP access rules. Addresses are single IPs or
+// CIDR ranges; countries are ISO 3166-1 alpha-2 codes.
+type IPFilterConfig struct {
+	Allow          []string `json:"allow" yaml:"allow" toml:"allow"`
+	Deny           []string `json:"deny" yaml:"deny" toml:"deny"`
+	AllowCountries []string `json:"allow_countries" yaml:"allow_countries" toml:"allow_countries"`
+	DenyCountries  []string `json:"deny_countries" yaml:"deny_countries" toml:"deny_countries"`
+	// TrustedProxies may set X-Forwarded-For; it is ignored from anyone else
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// CORSConfig holds the cross-origin rules of the server: Default for every
+// path, and Routes for paths starting with a prefix, the longest winning.
+// With Audit set, requests the rules would block are only logged.
+type CORSConfig struct {
+	Default CORSRuleConfig            `json:"default" yaml:"default" toml:"default"`
+	Routes  map[string]CORSRuleConfig `json:"routes" yaml:"routes" toml:"routes"`
+	Audit   bool                      `json:"audit" yaml:"audit" toml:"audit"`
+}
+
+// CORSRuleConfig lists what cross-origin callers may do. Origins are
+// "*", exact origins such as "https://app.example.com" or subdomain
+// wildcards such as "https://*.example.com".
+type CORSRuleConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins" yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods" yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers" yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers" yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials" yaml:"allow_credentials" toml:"allow_credentials"`
+	// MaxAge is how long browsers may cache a preflight answer, e.g. "10m"
+	MaxAge Duration `json:"max_age" yaml:"max_age" toml:"max_age"`
 }
 
 // ConfigLoader handles loading configuration from various file formats
@@ -127,19 +199,7 @@ func (cl *ConfigLoader) Load() (*FileConfig, error) {
 	}
 
 	config := &FileConfig{}
-
-	switch cl.configType {
-	case "yaml":
-		err = yaml.Unmarshal(data, config)
-	case "toml":
-		err = toml.Unmarshal(data, config)
-	case "json":
-		err = json.Unmarshal(data, config)
-	default:
-		return nil, fmt.Errorf("unsupported config file format: %s", cl.configType)
-	}
-
-	if err != nil {
+	if err := unmarshalConfig(cl.configType, data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
@@ -199,18 +259,19 @@ func CreateDefaultConfig(configPath string) error {
 			Debug:       true,
 		},
 		Server: ServerConfig{
-			Host:         "localhost",
-			Port:         8080,
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 30 * time.Second,
-			IdleTimeout:  60 * time.Second,
+			Host:          "localhost",
+			Port:          8080,
+			ReadTimeout:   Duration(30 * time.Second),
+			WriteTimeout:  Duration(30 * time.Second),
+			IdleTimeout:   Duration(60 * time.Second),
+			MaxUploadSize: 32 * MiB,
 		},
 		Database: DatabaseConfig{
 			URL:               "postgres://localhost:5432/mydb",
 			MaxConnections:    10,
 			MinConnections:    1,
-			ConnectionTimeout: 30 * time.Second,
-			QueryTimeout:      30 * time.Second,
+			ConnectionTimeout: Duration(30 * time.Second),
+			QueryTimeout:      Duration(30 * time.Second),
 			SSLMode:           "disable",
 		},
 		Logging: LoggingConfig{
@@ -227,23 +288,25 @@ func CreateDefaultConfig(configPath string) error {
 			EnableCORS:      true,
 			EnableCache:     true,
 			EnableRateLimit: false,
+			EnableAdminAPI:  false,
 		},
 		Services: ServiceConfig{
 			Redis: RedisConfig{
 				URL:      "redis://localhost:6379",
-				Timeout:  5 * time.Second,
+				Timeout:  Duration(5 * time.Second),
 				PoolSize: 10,
 			},
 			Cache: CacheConfig{
-				TTL:      5 * time.Minute,
+				TTL:      Duration(5 * time.Minute),
 				MaxSize:  1000,
+				MaxBytes: 64 * MiB,
 				Strategy: "lru",
 			},
 		},
 		Security: SecurityConfig{
 			JWTSecret:     "",
 			SessionSecret: "",
-			TokenExpiry:   24 * time.Hour,
+			TokenExpiry:   Duration(24 * time.Hour),
 			BCryptCost:    12,
 		},
 	}
@@ -320,14 +383,14 @@ func (fc *FileConfig) PrintConfig() {
 	fmt.Printf("Environment: %s\n", fc.App.Environment)
 	fmt.Printf("Debug: %t\n", fc.App.Debug)
 	fmt.Printf("Server: %s\n", fc.GetServerAddress())
-	fmt.Printf("Database URL: %s\n", maskSensitiveData(fc.Database.URL))
+	fmt.Printf("Database URL: %s\n", reflectops.MaskURL(fc.Database.URL))
 	fmt.Printf("Database Max Connections: %d\n", fc.Database.MaxConnections)
 	fmt.Printf("Log Level: %s\n", fc.Logging.Level)
 	fmt.Printf("Log Format: %s\n", fc.Logging.Format)
 	fmt.Printf("Metrics Enabled: %t\n", fc.Features.EnableMetrics)
 	fmt.Printf("CORS Enabled: %t\n", fc.Features.EnableCORS)
 	fmt.Printf("Cache Enabled: %t\n", fc.Features.EnableCache)
-	fmt.Printf("Redis URL: %s\n", maskSensitiveData(fc.Services.Redis.URL))
+	fmt.Printf("Redis URL: %s\n", reflectops.MaskURL(fc.Services.Redis.URL))
 	fmt.Printf("Cache TTL: %v\n", fc.Services.Cache.TTL)
 	fmt.Printf("JWT Secret Set: %t\n", fc.Security.JWTSecret != "")
 	fmt.Printf("Session Secret Set: %t\n", fc.Security.SessionSecret != "")
@@ -271,14 +271,21 @@ func (hrm *HotReloadManager) GetStatus() map[string]bool {
 // ConfigReloadCallback defines a callback function for configuration reloads
 type ConfigReloadCallback func(config interface{}) error
 
+// ConfigChangeCallback is called after a reload with the previous and the new
+// configuration, e.g. to report what changed
+type ConfigChangeCallback func(old, new interface{}) error
+
 // ReloadableConfig represents a configuration that can be hot reloaded
 type ReloadableConfig struct {
-	config     interface{}
-	loader     *ConfigLoader
-	validator  *SchemaValidator
-	callbacks  []ConfigReloadCallback
-	mu         sync.RWMutex
-	reloadTime time.Time
+	config          interface{}
+	loader          *ConfigLoader
+	validator       *SchemaValidator
+	callbacks       []ConfigReloadCallback
+	changeCallbacks []ConfigChangeCallback
+	watchers        map[int]func(old, new interface{}, at time.Time)
+	nextWatcher     int
+	mu              sync.RWMutex
+	reloadTime      time.Time
 }
 
 // NewReloadableConfig creates a new reloadable configuration
@@ -300,6 +307,35 @@ func (rc *ReloadableConfig) AddCallback(callback ConfigReloadCallback) {
 	rc.callbacks = append(rc.callbacks, callback)
 }
 
+// AddChangeCallback adds a callback that receives the old and new configuration
+// on every reload after the initial load
+func (rc *ReloadableConfig) AddChangeCallback(callback ConfigChangeCallback) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.changeCallbacks = append(rc.changeCallbacks, callback)
+}
+
+// watch registers fn to run after every reload but the initial one and
+// returns the id to remove it with
+func (rc *ReloadableConfig) watch(fn func(old, new interface{}, at time.Time)) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.watchers == nil {
+		rc.watchers = make(map[int]func(old, new interface{}, at time.Time))
+	}
+	rc.nextWatcher++
+	rc.watchers[rc.nextWatcher] = fn
+	return rc.nextWatcher
+}
+
+// unwatch removes a watcher; once it returns the watcher is not running and
+// will not run again
+func (rc *ReloadableConfig) unwatch(id int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.watchers, id)
+}
+
 // Reload reloads the configuration from file
 func (rc *ReloadableConfig) Reload() error {
 	rc.mu.Lock()
@@ -319,6 +355,7 @@ func (rc *ReloadableConfig) Reload() error {
 	}
 
 	// Update configuration
+	oldConfig, initial := rc.config, rc.reloadTime.IsZero()
 	rc.config = newConfig
 	rc.reloadTime = time.Now()
 
@@ -328,6 +365,16 @@ func (rc *ReloadableConfig) Reload() error {
 			fmt.Printf("Warning: callback failed during config reload: %v\n", err)
 		}
 	}
+	if !initial {
+		for _, callback := range rc.changeCallbacks {
+			if err := callback(oldConfig, rc.config); err != nil {
+				fmt.Printf("Warning: change callback failed during config reload: %v\n", err)
+			}
+		}
+		for _, watcher := range rc.watchers {
+			watcher(oldConfig, rc.config, rc.reloadTime)
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,393 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOverrideTTL is the longest an override may last, so a forgotten one
+// cannot quietly become permanent
+const MaxOverrideTTL = 24 * time.Hour
+
+// overrideHistoryLimit is how many audit events Overrides keeps
+const overrideHistoryLimit = 200
+
+var (
+	// ErrInvalidOverride is returned by Overrides.Set for a path the
+	// configuration does not have, a value of the wrong type, or a TTL
+	// outside (0, MaxOverrideTTL]
+	ErrInvalidOverride = errors.New("config: invalid override")
+	// ErrNoOverride is returned by Overrides.Clear when the path has no
+	// active override
+	ErrNoOverride = errors.New("config: no override for path")
+)
+
+// Override is a temporary value for one setting
+type Override struct {
+	// Path is the dotted path of the setting, such as "logging.level"
+	Path      string      `json:"path"`
+	Value     interface{} `json:"value"`
+	SetBy     string      `json:"set_by"`
+	Reason    string      `json:"reason,omitempty"`
+	SetAt     time.Time   `json:"set_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+
+	id uint64
+}
+
+// OverrideEvent is one entry of the audit trail: an override being set,
+// cleared by someone, or expiring
+type OverrideEvent struct {
+	Time time.Time `json:"time"`
+	// Action is "set", "cleared" or "expired"; "dropped" when a new base
+	// no longer has the setting, and "revert_failed" when applying the
+	// configuration without an expired override failed
+	Action   string   `json:"action"`
+	Override Override `json:"override"`
+	// By is who cleared the override; for "set" it is Override.SetBy
+	By    string `json:"by,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Overrides layers temporary values over a configuration, such as a debug
+// log level for 15 minutes while an incident is looked at. Reads go
+// through Get, which returns the configuration with the active overrides
+// applied; each override reverts on its own when its TTL runs out. A new
+// base from a reload keeps the overrides on top of it.
+//
+// Overrides are applied to the configuration's JSON form, so paths are
+// the JSON keys and values are written as in a JSON config file:
+//
+//	overrides.Set("logging.level", "debug", 15*time.Minute, "alice", "tracing INC-42")
+//	overrides.Set("server.read_timeout", "2m", time.Hour, "bob", "")
+type Overrides[T any] struct {
+	// applyMu makes changes, with their OnChange calls, happen one at a
+	// time; mu guards the state Get reads
+	applyMu   sync.Mutex
+	mu        sync.RWMutex
+	base      T
+	effective T
+	active    []Override
+	timers    map[uint64]*time.Timer
+	history   []OverrideEvent
+	nextID    uint64
+	listeners []func(T) error
+}
+
+// NewOverrides returns an Overrides over base with none active
+func NewOverrides[T any](base T) *Overrides[T] {
+	return &Overrides[T]{base: base, effective: base, timers: make(map[uint64]*time.Timer)}
+}
+
+// Get returns the effective configuration
+func (o *Overrides[T]) Get() T {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.effective
+}
+
+// Base returns the configuration without overrides
+func (o *Overrides[T]) Base() T {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.base
+}
+
+// OnChange registers fn to apply the effective configuration whenever it
+// changes: an override is set, cleared or expires, or SetBase is called.
+// If fn fails for a set, clear or new base, the change is undone and fn is
+// called again with the configuration from before it. An expiry is not
+// undone; its error is only recorded in the audit trail.
+func (o *Overrides[T]) OnChange(fn func(cfg T) error) {
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+	o.listeners = append(o.listeners, fn)
+}
+
+// SetBase replaces the configuration underneath the overrides, as a reload
+// does. Overrides that no longer fit the new base are dropped.
+func (o *Overrides[T]) SetBase(base T) error {
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+
+	o.mu.RLock()
+	active := append([]Override(nil), o.active...)
+	o.mu.RUnlock()
+	effective, kept, err := o.layer(base, active)
+	if err != nil {
+		return err
+	}
+	return o.change(base, effective, kept, nil)
+}
+
+// Set overrides the setting at path with value for ttl, replacing any
+// override of it. by and reason are recorded in the audit trail.
+func (o *Overrides[T]) Set(path string, value interface{}, ttl time.Duration, by, reason string) (Override, error) {
+	if ttl <= 0 || ttl > MaxOverrideTTL {
+		return Override{}, fmt.Errorf("%w: TTL must be between 0 and %v, not %v", ErrInvalidOverride, MaxOverrideTTL, ttl)
+	}
+	path = strings.Trim(path, ".")
+	if path == "" {
+		return Override{}, fmt.Errorf("%w: empty path", ErrInvalidOverride)
+	}
+	// Store the value as it would come out of JSON, so it compares and
+	// prints the same as the configuration's own values
+	normalized, err := configValues(value)
+	if err != nil {
+		return Override{}, fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+	}
+
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+
+	now := time.Now().UTC()
+	o.mu.Lock()
+	o.nextID++
+	override := Override{
+		Path: path, Value: normalized, SetBy: by, Reason: reason,
+		SetAt: now, ExpiresAt: now.Add(ttl), id: o.nextID,
+	}
+	base := o.base
+	active := make([]Override, 0, len(o.active)+1)
+	for _, existing := range o.active {
+		if existing.Path != path {
+			active = append(active, existing)
+		}
+	}
+	active = append(active, override)
+	o.mu.Unlock()
+
+	effective, err := applyOverrides(base, active)
+	if err != nil {
+		return Override{}, fmt.Errorf("%w: %s: %v", ErrInvalidOverride, path, err)
+	}
+	event := OverrideEvent{Time: now, Action: "set", Override: override, By: by}
+	if err := o.change(base, effective, active, &event); err != nil {
+		return Override{}, err
+	}
+	return override, nil
+}
+
+// Clear removes the override of path before it expires
+func (o *Overrides[T]) Clear(path, by string) error {
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+	return o.remove(strings.Trim(path, "."), 0, "cleared", by)
+}
+
+// Active returns the overrides in effect, soonest to expire first
+func (o *Overrides[T]) Active() []Override {
+	o.mu.RLock()
+	active := append([]Override{}, o.active...)
+	o.mu.RUnlock()
+	sort.SliceStable(active, func(i, j int) bool { return active[i].ExpiresAt.Before(active[j].ExpiresAt) })
+	return active
+}
+
+// History returns up to limit audit events, newest first; limit <= 0
+// returns all that are kept
+func (o *Overrides[T]) History(limit int) []OverrideEvent {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if limit <= 0 || limit > len(o.history) {
+		limit = len(o.history)
+	}
+	events := make([]OverrideEvent, limit)
+	for i := range events {
+		events[i] = o.history[len(o.history)-1-i]
+	}
+	return events
+}
+
+// Stop cancels the pending expiries, leaving the active overrides in place
+// until the next change schedules them again
+func (o *Overrides[T]) Stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id, timer := range o.timers {
+		timer.Stop()
+		delete(o.timers, id)
+	}
+}
+
+// expire removes an override whose TTL ran out, unless it was replaced or
+// cleared in the meantime
+func (o *Overrides[T]) expire(path string, id uint64) {
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+	o.remove(path, id, "expired", "")
+}
+
+// remove drops the override of path, or only the one with id when id is
+// not 0. Callers hold applyMu.
+func (o *Overrides[T]) remove(path string, id uint64, action, by string) error {
+	o.mu.RLock()
+	base := o.base
+	var removed *Override
+	active := make([]Override, 0, len(o.active))
+	for _, existing := range o.active {
+		if existing.Path == path && (id == 0 || existing.id == id) {
+			removed = &existing
+			continue
+		}
+		active = append(active, existing)
+	}
+	o.mu.RUnlock()
+	if removed == nil {
+		return fmt.Errorf("%w %q", ErrNoOverride, path)
+	}
+
+	// The others applied with it before, so they still do
+	effective, err := applyOverrides(base, active)
+	if err != nil {
+		return err
+	}
+	event := OverrideEvent{Time: time.Now().UTC(), Action: action, Override: *removed, By: by}
+	if action == "expired" {
+		o.commit(base, effective, active, &event)
+		if err := o.notify(effective); err != nil {
+			o.record(OverrideEvent{Time: event.Time, Action: "revert_failed", Override: *removed, Error: err.Error()})
+		}
+		return nil
+	}
+	return o.change(base, effective, active, &event)
+}
+
+// change makes base, effective and active current and tells the
+// listeners, going back to the previous state if one of them fails.
+// Callers hold applyMu.
+func (o *Overrides[T]) change(base, effective T, active []Override, event *OverrideEvent) error {
+	o.mu.RLock()
+	prevBase, prevEffective, prevActive := o.base, o.effective, o.active
+	o.mu.RUnlock()
+
+	o.commit(base, effective, active, nil)
+	if err := o.notify(effective); err != nil {
+		o.commit(prevBase, prevEffective, prevActive, nil)
+		o.notify(prevEffective)
+		return fmt.Errorf("config: applying the change failed, nothing changed: %w", err)
+	}
+	if event != nil {
+		o.record(*event)
+	}
+	return nil
+}
+
+// commit stores the new state and schedules the expiry of overrides that
+// have none yet, stopping those of overrides no longer active
+func (o *Overrides[T]) commit(base, effective T, active []Override, event *OverrideEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.base, o.effective, o.active = base, effective, active
+
+	live := make(map[uint64]bool, len(active))
+	for _, override := range active {
+		live[override.id] = true
+		if _, ok := o.timers[override.id]; !ok {
+			path, id := override.Path, override.id
+			o.timers[id] = time.AfterFunc(time.Until(override.ExpiresAt), func() { o.expire(path, id) })
+		}
+	}
+	for id, timer := range o.timers {
+		if !live[id] {
+			timer.Stop()
+			delete(o.timers, id)
+		}
+	}
+	if event != nil {
+		o.appendHistory(*event)
+	}
+}
+
+func (o *Overrides[T]) record(event OverrideEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.appendHistory(event)
+}
+
+func (o *Overrides[T]) appendHistory(event OverrideEvent) {
+	o.history = append(o.history, event)
+	if len(o.history) > overrideHistoryLimit {
+		o.history = append([]OverrideEvent(nil), o.history[len(o.history)-overrideHistoryLimit:]...)
+	}
+}
+
+func (o *Overrides[T]) notify(cfg T) error {
+	for _, fn := range o.listeners {
+		if err := fn(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layer applies active to a new base, dropping the overrides that no
+// longer fit it
+func (o *Overrides[T]) layer(base T, active []Override) (T, []Override, error) {
+	var kept []Override
+	for _, override := range active {
+		if _, err := applyOverrides(base, append(kept, override)); err != nil {
+			o.record(OverrideEvent{Time: time.Now().UTC(), Action: "dropped", Override: override, Error: err.Error()})
+			continue
+		}
+		kept = append(kept, override)
+	}
+	effective, err := applyOverrides(base, kept)
+	return effective, kept, err
+}
+
+// applyOverrides returns base with each override's value set at its path
+func applyOverrides[T any](base T, active []Override) (T, error) {
+	if len(active) == 0 {
+		return base, nil
+	}
+	var out T
+	values, err := configValues(base)
+	if err != nil {
+		return out, err
+	}
+	for _, override := range active {
+		if err := setPath(values, override.Path, override.Value); err != nil {
+			return out, err
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return out, err
+	}
+	// Unknown fields catch a misspelled last key, which setPath allows as
+	// maps take new keys
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// setPath sets the value at a dotted path of generic JSON values; every
+// key but the last must already lead to an object
+func setPath(values interface{}, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+	for i, key := range keys[:len(keys)-1] {
+		m, ok := values.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not a section", strings.Join(keys[:i], "."))
+		}
+		if values, ok = m[key]; !ok {
+			return fmt.Errorf("no setting %s", strings.Join(keys[:i+1], "."))
+		}
+	}
+	m, ok := values.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s is not a section", strings.Join(keys[:len(keys)-1], "."))
+	}
+	m[keys[len(keys)-1]] = value
+	return nil
+}
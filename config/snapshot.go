@@ -0,0 +1,505 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	reflectops "github.com/jerrychou/go-practice/reflect"
+)
+
+// Snapshot records the effective configuration at a point in time
+type Snapshot struct {
+	Hash string    `json:"hash"`
+	Time time.Time `json:"time"`
+	// Source says where the configuration came from, e.g. "running",
+	// "file:/etc/app/config.yaml" or "remote:https://..."
+	Source string `json:"source"`
+	Host   string `json:"host,omitempty"`
+	// Config is the configuration as JSON with secrets masked
+	Config json.RawMessage `json:"config"`
+}
+
+// NewSnapshot hashes cfg and captures it with secrets masked. The hash
+// covers the secrets too, so rotating one still shows up as a change.
+func NewSnapshot(cfg interface{}, source string) (*Snapshot, error) {
+	values, err := configValues(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := HashConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	masked, err := json.Marshal(maskSecrets("", values))
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	return &Snapshot{Hash: hash, Time: time.Now().UTC(), Source: source, Host: host, Config: masked}, nil
+}
+
+// HashConfig returns the SHA-256 of cfg's canonical JSON form, so equal
+// configurations hash the same whatever file format they came from
+func HashConfig(cfg interface{}) (string, error) {
+	values, err := configValues(cfg)
+	if err != nil {
+		return "", err
+	}
+	// Maps marshal with sorted keys, which makes this canonical
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// configValues converts cfg to generic JSON values
+func configValues(cfg interface{}) (interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var values interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return values, nil
+}
+
+// isSecretKey reports whether a config key holds a credential
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "secret") || strings.Contains(key, "password") || strings.HasSuffix(key, "_key")
+}
+
+func maskSecrets(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			masked[k] = maskSecrets(k, item)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskSecrets(key, item)
+		}
+		return masked
+	case string:
+		if isSecretKey(key) && v != "" {
+			return "***"
+		}
+		return reflectops.MaskURL(v)
+	}
+	return value
+}
+
+// DriftChange is one setting that differs between two configurations
+type DriftChange struct {
+	Path    string      `json:"path"`
+	Running interface{} `json:"running"`
+	Source  interface{} `json:"source"`
+}
+
+// DriftReport compares the running configuration with its source
+type DriftReport struct {
+	Running *Snapshot     `json:"running"`
+	Source  *Snapshot     `json:"source"`
+	Changes []DriftChange `json:"changes"`
+}
+
+// Drifted reports whether the running configuration differs from its source
+func (r *DriftReport) Drifted() bool {
+	return r.Running.Hash != r.Source.Hash
+}
+
+// String lists the changed settings, one per line
+func (r *DriftReport) String() string {
+	if !r.Drifted() {
+		return "no drift"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "running %.12s differs from %s %.12s:\n", r.Running.Hash, r.Source.Source, r.Source.Hash)
+	for _, change := range r.Changes {
+		fmt.Fprintf(&sb, "  %s: %v → %v\n", change.Path, change.Running, change.Source)
+	}
+	return sb.String()
+}
+
+// CompareConfigs reports how running differs from source, which is
+// where it was loaded from
+func CompareConfigs(running, source interface{}, sourceName string) (*DriftReport, error) {
+	runningSnap, err := NewSnapshot(running, "running")
+	if err != nil {
+		return nil, err
+	}
+	sourceSnap, err := NewSnapshot(source, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	report := &DriftReport{Running: runningSnap, Source: sourceSnap}
+	if !report.Drifted() {
+		return report, nil
+	}
+
+	a, err := configValues(running)
+	if err != nil {
+		return nil, err
+	}
+	b, err := configValues(source)
+	if err != nil {
+		return nil, err
+	}
+	flatA, flatB := map[string]interface{}{}, map[string]interface{}{}
+	flattenConfig("", a, flatA)
+	flattenConfig("", b, flatB)
+	for _, path := range changedPaths(flatA, flatB) {
+		value, other := flatA[path], flatB[path]
+		if isSecretKey(path[strings.LastIndex(path, ".")+1:]) {
+			value, other = "***", "*** (changed)"
+		}
+		report.Changes = append(report.Changes, DriftChange{
+			Path:    path,
+			Running: maskSecrets("", value),
+			Source:  maskSecrets("", other),
+		})
+	}
+	return report, nil
+}
+
+// changedPaths returns the sorted paths whose values differ between two
+// flattened configurations; a missing path counts as null
+func changedPaths(a, b map[string]interface{}) []string {
+	var paths []string
+	for path, value := range a {
+		if !reflect.DeepEqual(value, b[path]) {
+			paths = append(paths, path)
+		}
+	}
+	for path, value := range b {
+		if _, ok := a[path]; !ok && value != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// flattenConfig maps dotted paths such as "server.port" to leaf values;
+// lists are compared whole
+func flattenConfig(prefix string, value interface{}, out map[string]interface{}) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		out[prefix] = value
+		return
+	}
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flattenConfig(path, v, out)
+	}
+}
+
+// SnapshotStore keeps recorded snapshots
+type SnapshotStore interface {
+	Save(ctx context.Context, snapshot *Snapshot) error
+	// Latest returns the newest snapshot, or nil when there is none
+	Latest(ctx context.Context) (*Snapshot, error)
+	// List returns up to limit snapshots, newest first
+	List(ctx context.Context, limit int) ([]Snapshot, error)
+}
+
+// FileSnapshotStore keeps snapshots as JSON files in a directory
+type FileSnapshotStore struct {
+	dir string
+	// Keep is how many snapshots to keep; older ones are deleted on Save.
+	// Zero keeps them all.
+	Keep int
+}
+
+// NewFileSnapshotStore creates a store in dir, creating it if needed
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+// Save writes snapshot to a file named after its time and hash
+func (s *FileSnapshotStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	name := fmt.Sprintf("%s-%.12s.json", snapshot.Time.UTC().Format("20060102T150405.000000000Z"), snapshot.Hash)
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if s.Keep > 0 {
+		names, err := s.names()
+		if err != nil {
+			return err
+		}
+		for i := s.Keep; i < len(names); i++ {
+			os.Remove(filepath.Join(s.dir, names[i]))
+		}
+	}
+	return nil
+}
+
+// Latest returns the newest snapshot
+func (s *FileSnapshotStore) Latest(ctx context.Context) (*Snapshot, error) {
+	snapshots, err := s.List(ctx, 1)
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return &snapshots[0], nil
+}
+
+// List returns up to limit snapshots, newest first
+func (s *FileSnapshotStore) List(ctx context.Context, limit int) ([]Snapshot, error) {
+	names, err := s.names()
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	snapshots := make([]Snapshot, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot %s: %w", name, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// names returns the snapshot file names, newest first
+func (s *FileSnapshotStore) names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// ConfigSource loads the configuration the running one should match and
+// names where it came from
+type ConfigSource func(ctx context.Context) (cfg interface{}, source string, err error)
+
+// FileSource reads a FileConfig from path
+func FileSource(path string) ConfigSource {
+	return func(ctx context.Context) (interface{}, string, error) {
+		cfg, err := NewConfigLoader(path).Load()
+		if err != nil {
+			return nil, "", err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		return cfg, "file:" + abs, nil
+	}
+}
+
+// RemoteSource fetches a FileConfig over HTTP, such as from a config
+// service. The format comes from the Content-Type, then the URL's
+// extension, and defaults to JSON.
+func RemoteSource(url string, client *http.Client) ConfigSource {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return func(ctx context.Context) (interface{}, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch config: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("failed to fetch config: %s", resp.Status)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read config: %w", err)
+		}
+
+		contentType := resp.Header.Get("Content-Type")
+		cfg := &FileConfig{}
+		switch {
+		case strings.Contains(contentType, "yaml"):
+			err = yaml.Unmarshal(data, cfg)
+		case strings.Contains(contentType, "toml"):
+			err = toml.Unmarshal(data, cfg)
+		case strings.Contains(contentType, "json"):
+			err = json.Unmarshal(data, cfg)
+		default:
+			switch NewConfigLoader(req.URL.Path).GetConfigType() {
+			case "yaml":
+				err = yaml.Unmarshal(data, cfg)
+			case "toml":
+				err = toml.Unmarshal(data, cfg)
+			default:
+				err = json.Unmarshal(data, cfg)
+			}
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse config: %w", err)
+		}
+		return cfg, "remote:" + reflectops.MaskURL(url), nil
+	}
+}
+
+// DriftCallback is called when the running configuration drifts from its
+// source, or drifts differently than last reported
+type DriftCallback func(report *DriftReport)
+
+// DriftDetector periodically snapshots the running configuration and
+// compares it with its source. Drift happens when the file is edited but
+// a reload failed validation, when settings that can't be hot reloaded
+// change, or when someone changed the running values by hand.
+type DriftDetector struct {
+	running  func() interface{}
+	source   ConfigSource
+	store    SnapshotStore
+	interval time.Duration
+	onDrift  []DriftCallback
+
+	mu           sync.Mutex
+	lastRecorded string
+	lastReported string
+	stop         chan struct{}
+}
+
+// NewDriftDetector checks running, e.g. ReloadableConfig.GetConfig, against
+// source every interval. Snapshots of the running configuration are saved
+// to store whenever its hash changes; store may be nil.
+func NewDriftDetector(running func() interface{}, source ConfigSource, store SnapshotStore, interval time.Duration) *DriftDetector {
+	return &DriftDetector{running: running, source: source, store: store, interval: interval}
+}
+
+// OnDrift adds a callback fired when drift is detected
+func (d *DriftDetector) OnDrift(callback DriftCallback) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onDrift = append(d.onDrift, callback)
+}
+
+// Check snapshots the running configuration, compares it with the source
+// and fires the drift callbacks if they have not seen this drift yet
+func (d *DriftDetector) Check(ctx context.Context) (*DriftReport, error) {
+	source, name, err := d.source(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config source: %w", err)
+	}
+	report, err := CompareConfigs(d.running(), source, name)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.store != nil && report.Running.Hash != d.lastRecorded {
+		if d.lastRecorded == "" {
+			if latest, err := d.store.Latest(ctx); err == nil && latest != nil {
+				d.lastRecorded = latest.Hash
+			}
+		}
+		if report.Running.Hash != d.lastRecorded {
+			if err := d.store.Save(ctx, report.Running); err != nil {
+				return nil, fmt.Errorf("failed to record snapshot: %w", err)
+			}
+			d.lastRecorded = report.Running.Hash
+		}
+	}
+
+	if !report.Drifted() {
+		d.lastReported = ""
+		return report, nil
+	}
+	key := report.Running.Hash + report.Source.Hash
+	if key != d.lastReported {
+		d.lastReported = key
+		for _, callback := range d.onDrift {
+			callback(report)
+		}
+	}
+	return report, nil
+}
+
+// Start checks for drift every interval until ctx is done or Stop is called
+func (d *DriftDetector) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return fmt.Errorf("drift detector is already running")
+	}
+	if d.interval <= 0 {
+		return fmt.Errorf("drift detector interval must be positive")
+	}
+	d.stop = make(chan struct{})
+	go d.loop(ctx, d.stop)
+	return nil
+}
+
+// Stop stops the periodic checks
+func (d *DriftDetector) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+	}
+}
+
+func (d *DriftDetector) loop(ctx context.Context, stop chan struct{}) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.Check(ctx); err != nil {
+			fmt.Printf("Config drift check failed: %v\n", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// Duration is a time.Duration that reads and writes as a string such as
+// "30s", "5m" or "7d" in every config format. Plain numbers are read as
+// nanoseconds, matching how time.Duration was stored before.
+type Duration time.Duration
+
+// ParseDuration parses a duration the way time.ParseDuration does, also
+// accepting days ("7d", "1d12h")
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.ParseFloat(days, 64)
+	if err != nil || strings.HasPrefix(days, "+") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	d := time.Duration(n * float64(24*time.Hour))
+	if rest != "" {
+		more, err := time.ParseDuration(rest)
+		if err != nil || strings.HasPrefix(rest, "-") {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		if n < 0 {
+			more = -more
+		}
+		d += more
+	}
+	return d, nil
+}
+
+// Duration returns d as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats d without zero units, e.g. "5m" rather than "5m0s"
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	// TOML hands integers to UnmarshalText as digits
+	if n, err := strconv.ParseInt(string(text), 10, 64); err == nil {
+		*d = Duration(n)
+		return nil
+	}
+	parsed, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return d.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\" or nanoseconds: %s", data)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case int:
+		*d = Duration(v)
+		return nil
+	}
+	return fmt.Errorf("duration must be a string such as \"30s\" or nanoseconds, got %v", value)
+}
+
+// ByteSize is a number of bytes that reads and writes as a string such as
+// "512KB", "100MB" or "2GiB". K, M, G and T, with or without a B, are powers
+// of 1000, KiB, MiB, GiB and TiB powers of 1024; plain numbers are bytes.
+type ByteSize int64
+
+const (
+	Byte ByteSize = 1
+	KB            = 1000 * Byte
+	MB            = 1000 * KB
+	GB            = 1000 * MB
+	TB            = 1000 * GB
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+)
+
+// ParseByteSize parses sizes such as "100MB", "1.5 GiB" or "4096" with
+// format.ParseBytes, so sizes mean the same in config files as on the
+// command line. The single-letter units K, M, G and T are decimal, like KB.
+func ParseByteSize(s string) (ByteSize, error) {
+	n, err := format.ParseBytes(s)
+	if err != nil {
+		return 0, err
+	}
+	return ByteSize(n), nil
+}
+
+// Bytes returns b as an int64
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
+
+// String formats b in the largest unit that divides it exactly, preferring
+// binary units, so it parses back to the same value: 2GiB, 100MB, 1500B
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	for _, unit := range []struct {
+		size ByteSize
+		name string
+	}{
+		{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+		{TB, "TB"}, {GB, "GB"}, {MB, "MB"}, {KB, "KB"},
+	} {
+		if b%unit.size == 0 {
+			return strconv.FormatInt(int64(b/unit.size), 10) + unit.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	parsed, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return b.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("size must be a string such as \"100MB\" or bytes: %s", data)
+	}
+	*b = ByteSize(n)
+	return nil
+}
+
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		return b.UnmarshalText([]byte(v))
+	case int:
+		*b = ByteSize(v)
+		return nil
+	}
+	return fmt.Errorf("size must be a string such as \"100MB\" or bytes, got %v", value)
+}
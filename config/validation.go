@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // ValidationError represents a configuration validation error
@@ -42,11 +44,153 @@ func NewSchemaValidator() *SchemaValidator {
 	}
 }
 
-// AddRule adds a validation rule for a field
+// NewSchemaValidatorFromTags creates a schema validator with the rules of
+// config's validate tags; see AddRulesFromTags
+func NewSchemaValidatorFromTags(config interface{}) (*SchemaValidator, error) {
+	validator := NewSchemaValidator()
+	if err := validator.AddRulesFromTags(config); err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+// AddRule adds a validation rule for a field, replacing any rule the field
+// already has, including one from a validate tag
 func (sv *SchemaValidator) AddRule(rule ValidationRule) {
 	sv.rules[rule.Field] = rule
 }
 
+// AddRulesFromTags adds a rule for every field of config, a struct or a
+// pointer to one, with a validate tag such as
+//
+//	Port int `json:"port" validate:"required,min=1,max=65535"`
+//
+// The options are required, min=N and max=N (the length of strings,
+// slices and maps), oneof=a b c and pattern=REGEXP. A pattern takes the
+// rest of the tag, commas included, so it goes last. Fields that already
+// have a rule keep it, so programmatic rules win whichever is added first.
+func (sv *SchemaValidator) AddRulesFromTags(config interface{}) error {
+	t := reflect.TypeOf(config)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("configuration must be a struct")
+	}
+	return sv.addTagRules(t, "")
+}
+
+func (sv *SchemaValidator) addTagRules(t reflect.Type, parentPath string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := sv.getFieldPath(field, parentPath)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			if _, exists := sv.rules[fieldPath]; !exists {
+				rule, err := parseValidateTag(fieldPath, field.Type, tag)
+				if err != nil {
+					return err
+				}
+				sv.rules[fieldPath] = rule
+			}
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := sv.addTagRules(field.Type, fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseValidateTag turns a validate tag into the rule for a field of type t
+func parseValidateTag(fieldPath string, t reflect.Type, tag string) (ValidationRule, error) {
+	rule := ValidationRule{Field: fieldPath, Type: t}
+	for tag != "" {
+		var option string
+		if strings.HasPrefix(tag, "pattern=") {
+			option, tag = tag, ""
+		} else {
+			option, tag, _ = strings.Cut(tag, ",")
+		}
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+
+		switch key {
+		case "":
+		case "required":
+			rule.Required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return rule, fmt.Errorf("invalid validate tag on %s: %s=%q is not a number", fieldPath, key, value)
+			}
+			if key == "min" {
+				rule.Min = floatPtr(n)
+			} else {
+				rule.Max = floatPtr(n)
+			}
+		case "oneof":
+			for _, item := range strings.Fields(value) {
+				v, err := parseTagValue(item, t)
+				if err != nil {
+					return rule, fmt.Errorf("invalid validate tag on %s: oneof %q: %w", fieldPath, item, err)
+				}
+				rule.Enum = append(rule.Enum, v)
+			}
+		case "pattern":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return rule, fmt.Errorf("invalid validate tag on %s: %w", fieldPath, err)
+			}
+			rule.Pattern = pattern
+		default:
+			return rule, fmt.Errorf("invalid validate tag on %s: unknown option %q", fieldPath, key)
+		}
+	}
+	return rule, nil
+}
+
+// parseTagValue converts a oneof value to the field type t, so that it
+// compares equal to the field's values
+func parseTagValue(s string, t reflect.Type) (interface{}, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		v.SetBool(b)
+	default:
+		return nil, fmt.Errorf("oneof is not supported for %s", t)
+	}
+	return v.Interface(), nil
+}
+
 // Validate validates a configuration struct against the schema
 func (sv *SchemaValidator) Validate(config interface{}) error {
 	val := reflect.ValueOf(config)
@@ -175,7 +319,8 @@ func (sv *SchemaValidator) validateNestedStruct(structValue reflect.Value, paren
 	return errors
 }
 
-// validateNumeric validates numeric constraints
+// validateNumeric validates numeric constraints; for strings, slices and
+// maps they apply to the length
 func (sv *SchemaValidator) validateNumeric(fieldValue reflect.Value, rule ValidationRule) error {
 	var num float64
 
@@ -186,6 +331,10 @@ func (sv *SchemaValidator) validateNumeric(fieldValue reflect.Value, rule Valida
 		num = float64(fieldValue.Uint())
 	case reflect.Float32, reflect.Float64:
 		num = fieldValue.Float()
+	case reflect.String:
+		return sv.validateLength(utf8.RuneCountInString(fieldValue.String()), rule)
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return sv.validateLength(fieldValue.Len(), rule)
 	default:
 		return fmt.Errorf("field is not numeric")
 	}
@@ -201,6 +350,16 @@ func (sv *SchemaValidator) validateNumeric(fieldValue reflect.Value, rule Valida
 	return nil
 }
 
+func (sv *SchemaValidator) validateLength(length int, rule ValidationRule) error {
+	if rule.Min != nil && float64(length) < *rule.Min {
+		return fmt.Errorf("length %d is less than minimum %v", length, *rule.Min)
+	}
+	if rule.Max != nil && float64(length) > *rule.Max {
+		return fmt.Errorf("length %d is greater than maximum %v", length, *rule.Max)
+	}
+	return nil
+}
+
 // validateEnum validates enum constraints
 func (sv *SchemaValidator) validateEnum(fieldValue reflect.Value, enum []interface{}) error {
 	value := fieldValue.Interface()
@@ -235,9 +394,13 @@ func (sv *SchemaValidator) isEmpty(fieldValue reflect.Value) bool {
 	return false
 }
 
-// getFieldPath constructs the field path for nested structs
+// getFieldPath constructs the field path for nested structs from the JSON
+// names of the fields, e.g. "database.max_connections"
 func (sv *SchemaValidator) getFieldPath(field reflect.StructField, parentPath string) string {
-	fieldName := strings.ToLower(field.Name)
+	fieldName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if fieldName == "" || fieldName == "-" {
+		fieldName = strings.ToLower(field.Name)
+	}
 
 	if parentPath == "" {
 		return fieldName
@@ -247,55 +410,13 @@ func (sv *SchemaValidator) getFieldPath(field reflect.StructField, parentPath st
 }
 
 // CreateDefaultSchema creates a default validation schema for FileConfig
+// from its validate tags, with rules that need code added on top
 func CreateDefaultSchema() *SchemaValidator {
-	validator := NewSchemaValidator()
-
-	// App configuration rules
-	validator.AddRule(ValidationRule{
-		Field:    "app.name",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Custom: func(value interface{}) error {
-			str, ok := value.(string)
-			if !ok {
-				return fmt.Errorf("must be a string")
-			}
-			if len(str) < 1 || len(str) > 50 {
-				return fmt.Errorf("must be between 1 and 50 characters")
-			}
-			return nil
-		},
-	})
-
-	validator.AddRule(ValidationRule{
-		Field:    "app.version",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Pattern:  regexp.MustCompile(`^\d+\.\d+\.\d+$`),
-	})
-
-	validator.AddRule(ValidationRule{
-		Field:    "app.environment",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Enum:     []interface{}{"development", "staging", "production"},
-	})
-
-	// Server configuration rules
-	validator.AddRule(ValidationRule{
-		Field:    "server.port",
-		Required: true,
-		Type:     reflect.TypeOf(0),
-		Min:      floatPtr(1),
-		Max:      floatPtr(65535),
-	})
-
-	validator.AddRule(ValidationRule{
-		Field:    "server.host",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Pattern:  regexp.MustCompile(`^[a-zA-Z0-9.-]+$`),
-	})
+	validator, err := NewSchemaValidatorFromTags(&FileConfig{})
+	if err != nil {
+		// The tags are fixed at compile time
+		panic(err)
+	}
 
 	// Database configuration rules
 	validator.AddRule(ValidationRule{
@@ -314,45 +435,6 @@ func CreateDefaultSchema() *SchemaValidator {
 		},
 	})
 
-	validator.AddRule(ValidationRule{
-		Field:    "database.max_connections",
-		Required: true,
-		Type:     reflect.TypeOf(0),
-		Min:      floatPtr(1),
-		Max:      floatPtr(100),
-	})
-
-	// Logging configuration rules
-	validator.AddRule(ValidationRule{
-		Field:    "logging.level",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Enum:     []interface{}{"debug", "info", "warn", "error", "fatal"},
-	})
-
-	validator.AddRule(ValidationRule{
-		Field:    "logging.format",
-		Required: true,
-		Type:     reflect.TypeOf(""),
-		Enum:     []interface{}{"json", "text"},
-	})
-
-	// Security configuration rules
-	validator.AddRule(ValidationRule{
-		Field: "security.jwt_secret",
-		Type:  reflect.TypeOf(""),
-		Custom: func(value interface{}) error {
-			str, ok := value.(string)
-			if !ok {
-				return fmt.Errorf("must be a string")
-			}
-			if str != "" && len(str) < 32 {
-				return fmt.Errorf("must be at least 32 characters long")
-			}
-			return nil
-		},
-	})
-
 	return validator
 }
 
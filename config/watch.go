@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigUpdate is one reload as seen by a Watch: the watched part of the
+// configuration before and after, and which settings changed
+type ConfigUpdate[T any] struct {
+	Old T
+	New T
+	// Changed are the dotted paths of the settings that changed, such as
+	// "server.port", sorted
+	Changed []string
+	Time    time.Time
+}
+
+// Watch returns a channel receiving an update whenever a reload of rc
+// changes something under path, a dotted path such as "server" or "" for
+// the whole configuration. The part at path is decoded into T through its
+// JSON form, so T can be e.g. ServerConfig or a struct of just the fields a
+// goroutine needs; when path is "" and T is the configuration type, values
+// are passed as they are.
+//
+// The channel holds one update. A receiver that falls behind gets only the
+// latest; its Old is the value just before that reload, not necessarily the
+// New it last received. Cancel stops the updates and closes the channel.
+//
+//	updates, cancel := config.Watch[config.ServerConfig](reloadable, "server")
+//	defer cancel()
+//	for {
+//		select {
+//		case update := <-updates:
+//			srv.SetTimeouts(update.New.ReadTimeout, update.New.WriteTimeout)
+//		case <-ctx.Done():
+//			return
+//		}
+//	}
+func Watch[T any](rc *ReloadableConfig, path string) (<-chan ConfigUpdate[T], context.CancelFunc) {
+	updates := make(chan ConfigUpdate[T], 1)
+	id := rc.watch(func(oldCfg, newCfg interface{}, at time.Time) {
+		update, err := watchUpdate[T](oldCfg, newCfg, path)
+		if err != nil {
+			fmt.Printf("Warning: config watch of %q failed: %v\n", path, err)
+			return
+		}
+		if len(update.Changed) == 0 {
+			return
+		}
+		update.Time = at
+		// Reload is the only sender and holds rc.mu, so after draining a
+		// stale update the send cannot block
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- update
+	})
+
+	var once sync.Once
+	return updates, func() {
+		once.Do(func() {
+			rc.unwatch(id)
+			close(updates)
+		})
+	}
+}
+
+// watchUpdate compares two configurations under path and decodes that part
+// of each into T
+func watchUpdate[T any](oldCfg, newCfg interface{}, path string) (ConfigUpdate[T], error) {
+	var update ConfigUpdate[T]
+	a, err := configValues(oldCfg)
+	if err != nil {
+		return update, err
+	}
+	b, err := configValues(newCfg)
+	if err != nil {
+		return update, err
+	}
+	flatA, flatB := map[string]interface{}{}, map[string]interface{}{}
+	flattenConfig("", a, flatA)
+	flattenConfig("", b, flatB)
+	for _, changed := range changedPaths(flatA, flatB) {
+		if path == "" || changed == path || strings.HasPrefix(changed, path+".") {
+			update.Changed = append(update.Changed, changed)
+		}
+	}
+	if len(update.Changed) == 0 {
+		return update, nil
+	}
+
+	if update.Old, err = decodeAt[T](oldCfg, a, path); err != nil {
+		return update, err
+	}
+	if update.New, err = decodeAt[T](newCfg, b, path); err != nil {
+		return update, err
+	}
+	return update, nil
+}
+
+// decodeAt returns the part of cfg at path as a T; values are cfg converted
+// by configValues. A missing path gives the zero T.
+func decodeAt[T any](cfg, values interface{}, path string) (T, error) {
+	var out T
+	if path == "" {
+		switch v := cfg.(type) {
+		case T:
+			return v, nil
+		case *T:
+			if v != nil {
+				return *v, nil
+			}
+		}
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		m, ok := values.(map[string]interface{})
+		if !ok {
+			return out, nil
+		}
+		values = m[key]
+	}
+	if values == nil {
+		return out, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("failed to decode %q: %w", path, err)
+	}
+	return out, nil
+}
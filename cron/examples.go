@@ -0,0 +1,177 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ParseExamples parses common specs and prints their next runs
+func ParseExamples() {
+	fmt.Println("\n=== Parsing Cron Specs ===")
+	from := time.Date(2024, time.March, 8, 16, 20, 0, 0, time.UTC)
+	fmt.Printf("🕐 from %s\n", from.Format("Mon 2006-01-02 15:04"))
+	for _, spec := range []string{
+		"*/15 * * * *",
+		"0 9 * * mon-fri",
+		"30 2 1,15 * *",
+		"0 0 13 * fri", // either the 13th or a Friday
+		"@monthly",
+		"@every 90m",
+	} {
+		schedule, err := ParseInLocation(spec, time.UTC)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		fmt.Printf("   %-18s →", spec)
+		t := from
+		for range 3 {
+			t = schedule.Next(t)
+			fmt.Printf("  %s", t.Format("Mon 01-02 15:04"))
+		}
+		fmt.Println()
+	}
+
+	for _, spec := range []string{"61 * * * *", "* * * *", "0 0 * * funday", "@every -1s", "5-1 * * * *"} {
+		_, err := Parse(spec)
+		fmt.Printf("🚫 %q: %v\n", spec, err)
+	}
+}
+
+// TimezoneExamples evaluates a spec in different zones, across a DST change
+func TimezoneExamples() {
+	fmt.Println("\n=== Time Zones and DST ===")
+	from := time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC)
+	for _, spec := range []string{
+		"0 9 * * *",
+		"CRON_TZ=America/New_York 0 9 * * *",
+		"CRON_TZ=Asia/Tokyo 0 9 * * *",
+	} {
+		schedule, err := ParseInLocation(spec, time.UTC)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		fmt.Printf("   %-36s →", spec)
+		t := from
+		for range 2 {
+			t = schedule.Next(t)
+			fmt.Printf("  %s", t.UTC().Format("01-02 15:04Z"))
+		}
+		fmt.Println()
+	}
+	fmt.Println("   (New York moves to daylight time on 03-10, so 9am comes an hour earlier in UTC)")
+
+	// 02:30 does not exist on the spring-forward day in New York, so that
+	// day has no run
+	schedule := MustParse("CRON_TZ=America/New_York 30 2 * * *")
+	t := time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC)
+	fmt.Print("⏭️  30 2 * * * in New York →")
+	for range 2 {
+		t = schedule.Next(t)
+		fmt.Printf("  %s", t.In(schedule.(*SpecSchedule).Location).Format("01-02 15:04 MST"))
+	}
+	fmt.Println()
+}
+
+// SchedulerExamples runs jobs, triggers one by hand, pauses one and reads
+// the run history
+func SchedulerExamples() {
+	fmt.Println("\n=== Scheduler ===")
+	scheduler := New(Options{})
+	var ticks, reports atomic.Int32
+	scheduler.Add("tick", "@every 100ms", func(ctx context.Context) error {
+		ticks.Add(1)
+		return nil
+	})
+	scheduler.Add("report", "0 0 1 * *", func(ctx context.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		if reports.Add(1) == 1 {
+			return errors.New("warehouse unavailable")
+		}
+		return nil
+	})
+	scheduler.Add("slow", "@every 100ms", func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, WithTimeout(150*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler.Start(ctx)
+	time.Sleep(350 * time.Millisecond)
+
+	// The monthly report is far off, so run it now
+	fmt.Printf("▶️  trigger report: %v\n", scheduler.Trigger("report"))
+	fmt.Printf("▶️  trigger while running: %v\n", scheduler.Trigger("report"))
+	time.Sleep(50 * time.Millisecond)
+	info, _ := scheduler.Get("report")
+	fmt.Printf("▶️  trigger after %q: %v\n", info.LastError, scheduler.Trigger("report"))
+	fmt.Printf("▶️  trigger unknown: %v\n", scheduler.Trigger("nope"))
+
+	scheduler.Pause("tick")
+	paused := ticks.Load()
+	time.Sleep(250 * time.Millisecond)
+	fmt.Printf("⏸️  tick ran %d times, %d while paused\n", paused, ticks.Load()-paused)
+	scheduler.Resume("tick")
+
+	for _, info := range scheduler.List() {
+		next := "-"
+		if !info.Next.IsZero() {
+			next = time.Until(info.Next).Round(time.Millisecond).String()
+		}
+		fmt.Printf("📋 %-7s %-12s next in %-8s running=%-5v last error=%q\n", info.Name, info.Spec, next, info.Running, info.LastError)
+	}
+	scheduler.Stop()
+
+	runs, _ := scheduler.History(context.Background(), "slow", 3)
+	fmt.Println("📜 slow job history (newest first):")
+	for _, run := range runs {
+		fmt.Printf("   #%d %s took %v, error=%q\n", run.ID, run.Trigger, run.Duration().Round(10*time.Millisecond), run.Error)
+	}
+}
+
+// MissedRunExamples starts schedulers whose history shows they were down
+// through several runs
+func MissedRunExamples() {
+	fmt.Println("\n=== Missed Runs ===")
+	for _, policy := range []MissedRunPolicy{MissedSkip, MissedRunOnce, MissedRunAll} {
+		// The last run was 5 minutes ago on a one-minute schedule
+		history := NewMemoryHistory(0)
+		last := time.Now().Add(-5 * time.Minute).Truncate(time.Minute)
+		history.Record(context.Background(), RunRecord{Job: "sync", Trigger: TriggerSchedule, ScheduledAt: last})
+
+		scheduler := New(Options{History: history})
+		var caughtUp []string
+		scheduler.Add("sync", "* * * * *", func(ctx context.Context) error {
+			return nil
+		}, WithMissedRuns(policy))
+		scheduler.Start(context.Background())
+		time.Sleep(50 * time.Millisecond)
+		scheduler.Stop()
+
+		runs, _ := history.Runs(context.Background(), "sync", 0)
+		for _, run := range runs {
+			if run.Trigger == TriggerCatchUp {
+				caughtUp = append([]string{run.ScheduledAt.Format("15:04")}, caughtUp...)
+			}
+		}
+		info, _ := scheduler.Get("sync")
+		fmt.Printf("   %-8s missed %d, caught up %d %v\n", policy, info.Missed, len(caughtUp), caughtUp)
+	}
+}
+
+// RunAllCronExamples runs all cron examples
+func RunAllCronExamples() {
+	ParseExamples()
+	TimezoneExamples()
+	SchedulerExamples()
+	MissedRunExamples()
+}
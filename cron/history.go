@@ -0,0 +1,95 @@
+package cron
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Trigger values record why a job ran
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerCatchUp  = "catch-up" // a missed run made up on start
+)
+
+// RunRecord is one execution of a job
+type RunRecord struct {
+	ID          int64     `json:"id"`
+	Job         string    `json:"job"`
+	Trigger     string    `json:"trigger"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Duration returns how long the run took
+func (r RunRecord) Duration() time.Duration { return r.FinishedAt.Sub(r.StartedAt) }
+
+// HistoryStore persists run records. The scheduler reads the last
+// scheduled run on start to find runs missed while it was down.
+type HistoryStore interface {
+	Record(ctx context.Context, run RunRecord) error
+	// Runs returns up to limit runs of job, newest first
+	Runs(ctx context.Context, job string, limit int) ([]RunRecord, error)
+	// LastScheduled returns the ScheduledAt of the job's latest scheduled
+	// or catch-up run, ignoring manual runs, or the zero time
+	LastScheduled(ctx context.Context, job string) (time.Time, error)
+}
+
+// MemoryHistory keeps the latest runs of each job in memory
+type MemoryHistory struct {
+	mu     sync.Mutex
+	limit  int
+	nextID int64
+	runs   map[string][]RunRecord // oldest first
+}
+
+// NewMemoryHistory keeps up to limit runs per job, 100 if limit <= 0
+func NewMemoryHistory(limit int) *MemoryHistory {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &MemoryHistory{limit: limit, runs: make(map[string][]RunRecord)}
+}
+
+// Record implements HistoryStore
+func (h *MemoryHistory) Record(ctx context.Context, run RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	run.ID = h.nextID
+	runs := append(h.runs[run.Job], run)
+	if len(runs) > h.limit {
+		runs = slices.Delete(runs, 0, len(runs)-h.limit)
+	}
+	h.runs[run.Job] = runs
+	return nil
+}
+
+// Runs implements HistoryStore
+func (h *MemoryHistory) Runs(ctx context.Context, job string, limit int) ([]RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	runs := slices.Clone(h.runs[job])
+	slices.Reverse(runs)
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// LastScheduled implements HistoryStore
+func (h *MemoryHistory) LastScheduled(ctx context.Context, job string) (time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var last time.Time
+	for _, run := range h.runs[job] {
+		if run.Trigger != TriggerManual && run.ScheduledAt.After(last) {
+			last = run.ScheduledAt
+		}
+	}
+	return last, nil
+}
@@ -0,0 +1,297 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // zone names work in containers without /usr/share/zoneinfo
+)
+
+// Schedule computes when a job runs next
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time
+	// if there is none within five years
+	Next(t time.Time) time.Time
+}
+
+// SpecSchedule is a standard five-field schedule: minute, hour, day of
+// month, month and day of week
+type SpecSchedule struct {
+	Minute, Hour, Dom, Month, Dow uint64 // bit i set when value i matches
+	Location                      *time.Location
+	// Vixie cron rule: when both day fields are restricted, a day matches
+	// if either does; when one is "*", only the other counts
+	domStar, dowStar bool
+}
+
+// ConstantDelay is an "@every" schedule
+type ConstantDelay struct {
+	Delay time.Duration
+}
+
+// Next implements Schedule
+func (c ConstantDelay) Next(t time.Time) time.Time {
+	next := t.Add(c.Delay)
+	if c.Delay >= time.Second {
+		next = next.Truncate(time.Second)
+	}
+	return next
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as Sunday and folded onto 0
+	dows = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a schedule in the local time zone; see ParseInLocation
+func Parse(spec string) (Schedule, error) {
+	return ParseInLocation(spec, time.Local)
+}
+
+// ParseInLocation parses a schedule evaluated in loc. It accepts
+//
+//   - five fields such as "*/15 9-17 * * mon-fri", with lists, ranges,
+//     steps and month and weekday names
+//   - @yearly, @monthly, @weekly, @daily, @midnight and @hourly
+//   - "@every 90s" or any other time.ParseDuration value
+//
+// A "CRON_TZ=Europe/Berlin " or "TZ=..." prefix overrides loc.
+func ParseInLocation(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		zone, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(zone, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cron: unknown time zone %q", name)
+		}
+		loc, spec = l, strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("cron: invalid @every duration in %q", spec)
+		}
+		return ConstantDelay{Delay: d}, nil
+	}
+	if expanded, ok := shortcuts[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields in %q, got %d", spec, len(fields))
+	}
+	s := &SpecSchedule{Location: loc}
+	var err error
+	if s.Minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.Hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.Dom, err = parseField(fields[2], doms); err != nil {
+		return nil, err
+	}
+	if s.Month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.Dow, err = parseField(fields[4], dows); err != nil {
+		return nil, err
+	}
+	if s.Dow&(1<<7) != 0 {
+		s.Dow = s.Dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// MustParse is Parse that panics on error, for specs fixed in code
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField turns a comma-separated list of "*", "n", "a-b" and their
+// "/step" forms into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		lo, hi := b.min, b.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = b.value(from); err != nil {
+				return 0, err
+			}
+			if hi, err = b.value(to); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := b.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/10" means from 5 to the end in steps of 10
+			if !hasStep {
+				hi = v
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("cron: range %q runs backwards", item)
+		}
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("cron: invalid step in %q", item)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (b bounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cron: invalid value %q", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("cron: %d out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// allHours is the Hour set of a spec whose hour field is "*"
+const allHours = 1<<24 - 1
+
+// Next implements Schedule. It walks forward field by field, resetting
+// the smaller fields whenever a larger one advances.
+//
+// Across daylight saving changes, times that do not exist on the
+// spring-forward day are skipped, and a job with a fixed hour runs only
+// in the first pass through an hour that repeats on the fall-back day.
+func (s *SpecSchedule) Next(t time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	next := s.next(t, loc)
+	if local := next.In(loc); !next.IsZero() && s.Hour != allHours && local.Add(-time.Hour).Hour() == local.Hour() {
+		return s.next(next, loc)
+	}
+	return next
+}
+
+func (s *SpecSchedule) next(t time.Time, loc *time.Location) time.Time {
+	origLoc := t.Location()
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	added := false
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.Month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// Midnight may not exist on a DST change; AddDate then lands on
+		// another hour, so pull back to the start of the day
+		if h := t.Hour(); h != 0 {
+			if h > 12 {
+				t = t.Add(time.Duration(24-h) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(h) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.Hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.Minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t.In(origLoc)
+}
+
+func (s *SpecSchedule) dayMatches(t time.Time) bool {
+	dom := s.Dom&(1<<uint(t.Day())) != 0
+	dow := s.Dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
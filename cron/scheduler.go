@@ -0,0 +1,467 @@
+// Package cron runs jobs on cron schedules, with manual triggers, pausing,
+// run history and a policy for runs missed while the process was down.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("cron")
+
+var (
+	ErrJobNotFound = errors.New("cron: job not found")
+	ErrJobExists   = errors.New("cron: job already exists")
+	ErrJobRunning  = errors.New("cron: job is already running")
+)
+
+// JobFunc is the work done on each run. ctx is cancelled when the
+// scheduler stops or the job's timeout expires.
+type JobFunc func(ctx context.Context) error
+
+// MissedRunPolicy decides what happens on Start to runs that fell due
+// while the scheduler was not running. It needs a HistoryStore that
+// outlives the process to see them.
+type MissedRunPolicy int
+
+const (
+	// MissedSkip drops missed runs and waits for the next one
+	MissedSkip MissedRunPolicy = iota
+	// MissedRunOnce makes up for any number of missed runs with one run
+	MissedRunOnce
+	// MissedRunAll makes up each missed run, oldest first, keeping only
+	// the latest maxCatchUp
+	MissedRunAll
+)
+
+const maxCatchUp = 10
+
+func (p MissedRunPolicy) String() string {
+	switch p {
+	case MissedSkip:
+		return "skip"
+	case MissedRunOnce:
+		return "run-once"
+	case MissedRunAll:
+		return "run-all"
+	}
+	return fmt.Sprintf("MissedRunPolicy(%d)", int(p))
+}
+
+// JobOption configures a job passed to Add
+type JobOption func(*job)
+
+// WithMissedRuns sets the job's missed-run policy, MissedSkip by default
+func WithMissedRuns(policy MissedRunPolicy) JobOption {
+	return func(j *job) { j.policy = policy }
+}
+
+// WithTimeout cancels a run's context after d
+func WithTimeout(d time.Duration) JobOption {
+	return func(j *job) { j.timeout = d }
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// Location evaluates specs without a CRON_TZ= prefix, defaults to
+	// time.Local
+	Location *time.Location
+	// History records runs, defaults to NewMemoryHistory(100)
+	History HistoryStore
+}
+
+type job struct {
+	name, spec string
+	schedule   Schedule
+	fn         JobFunc
+	policy     MissedRunPolicy
+	timeout    time.Duration
+
+	next, prev time.Time
+	paused     bool
+	running    bool
+	lastErr    string
+	missed     int
+}
+
+// JobInfo is a snapshot of a job's state
+type JobInfo struct {
+	Name       string    `json:"name"`
+	Spec       string    `json:"spec"`
+	MissedRuns string    `json:"missed_runs_policy"`
+	Next       time.Time `json:"next,omitzero"`
+	Prev       time.Time `json:"prev,omitzero"`
+	Paused     bool      `json:"paused"`
+	Running    bool      `json:"running"`
+	LastError  string    `json:"last_error,omitempty"`
+	// Missed counts runs found missed on Start
+	Missed int `json:"missed"`
+}
+
+// Scheduler runs registered jobs when their schedules fall due. A job
+// never overlaps itself: a run that falls due while the previous one is
+// still going is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	loc     *time.Location
+	history HistoryStore
+	jobs    map[string]*job
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	wake    chan struct{}
+	done    chan struct{}
+	running sync.WaitGroup
+}
+
+// New creates a stopped scheduler
+func New(opts Options) *Scheduler {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.History == nil {
+		opts.History = NewMemoryHistory(100)
+	}
+	return &Scheduler{
+		loc:     opts.Location,
+		history: opts.History,
+		jobs:    make(map[string]*job),
+		ctx:     context.Background(),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add registers fn to run on spec; see ParseInLocation for the syntax
+func (s *Scheduler) Add(name, spec string, fn JobFunc, opts ...JobOption) error {
+	schedule, err := ParseInLocation(spec, s.loc)
+	if err != nil {
+		return err
+	}
+	j := &job{name: name, spec: spec, schedule: schedule, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	s.jobs[name] = j
+	if s.started {
+		s.plan(j, time.Now())
+		s.notify()
+	}
+	return nil
+}
+
+// Remove unregisters a job; a run in progress finishes
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	delete(s.jobs, name)
+	s.notify()
+	return nil
+}
+
+// SetHistory replaces the history store; call it before Start
+func (s *Scheduler) SetHistory(history HistoryStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = history
+}
+
+// Start applies each job's missed-run policy and begins scheduling in the
+// background until ctx is done or Stop is called
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("cron: scheduler already started")
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	now := time.Now()
+	for _, j := range s.jobs {
+		s.plan(j, now)
+	}
+	go s.loop()
+	logger.Info("Scheduler started", "jobs", len(s.jobs))
+	return nil
+}
+
+// Stop stops scheduling and waits for running jobs to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	s.cancel()
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+	s.running.Wait()
+	logger.Info("Scheduler stopped")
+}
+
+// plan sets the job's next run and starts any catch-up runs its policy
+// calls for. The caller holds s.mu.
+func (s *Scheduler) plan(j *job, now time.Time) {
+	j.next = j.schedule.Next(now)
+
+	last, err := s.history.LastScheduled(s.ctx, j.name)
+	if err != nil {
+		logger.Error("Failed to read last run", "job", j.name, "error", err)
+		return
+	}
+	if last.IsZero() {
+		return
+	}
+	// Keep the latest maxCatchUp missed times; the loop bound stops a
+	// short @every after a long outage from spinning
+	var missed []time.Time
+	count := 0
+	for t := j.schedule.Next(last); !t.IsZero() && !t.After(now) && count < 100000; t = j.schedule.Next(t) {
+		count++
+		if missed = append(missed, t); len(missed) > maxCatchUp {
+			missed = missed[1:]
+		}
+	}
+	j.missed = count
+	if count == 0 {
+		return
+	}
+
+	switch j.policy {
+	case MissedRunOnce:
+		missed = missed[len(missed)-1:]
+	case MissedRunAll:
+	default:
+		logger.Warn("Skipping missed runs", "job", j.name, "missed", count, "last", last)
+		return
+	}
+	logger.Info("Catching up missed runs", "job", j.name, "missed", count, "running", len(missed))
+	s.dispatch(j, missed, TriggerCatchUp)
+}
+
+// notify wakes the loop to recompute its timer
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.done)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		var earliest time.Time
+		for _, j := range s.jobs {
+			if j.paused || j.next.IsZero() {
+				continue
+			}
+			if !j.next.After(now) {
+				if j.running {
+					logger.Warn("Skipping run, previous run still going", "job", j.name, "scheduled", j.next)
+				} else {
+					s.dispatch(j, []time.Time{j.next}, TriggerSchedule)
+				}
+				// Runs that fell due while the process was suspended
+				// collapse into the one above
+				next := j.schedule.Next(j.next)
+				if !next.After(now) {
+					next = j.schedule.Next(now)
+				}
+				j.next = next
+			}
+			if !j.next.IsZero() && (earliest.IsZero() || j.next.Before(earliest)) {
+				earliest = j.next
+			}
+		}
+		s.mu.Unlock()
+
+		wait := time.Hour
+		if !earliest.IsZero() {
+			wait = time.Until(earliest)
+		}
+		timer.Reset(wait)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatch runs j once per scheduled time, one after another, in a new
+// goroutine. The caller holds s.mu and has checked j is not running.
+func (s *Scheduler) dispatch(j *job, scheduled []time.Time, trigger string) {
+	j.running = true
+	j.prev = time.Now()
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		var lastErr string
+		for _, at := range scheduled {
+			run := s.execute(j, at, trigger)
+			lastErr = run.Error
+		}
+		s.mu.Lock()
+		j.running = false
+		j.lastErr = lastErr
+		s.mu.Unlock()
+	}()
+}
+
+func (s *Scheduler) execute(j *job, scheduledAt time.Time, trigger string) RunRecord {
+	ctx := s.ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	run := RunRecord{Job: j.name, Trigger: trigger, ScheduledAt: scheduledAt, StartedAt: time.Now()}
+	err := call(ctx, j.fn)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+		logger.Warn("Job failed", "job", j.name, "trigger", trigger, "duration", run.Duration(), "error", err)
+	} else {
+		logger.Debug("Job finished", "job", j.name, "trigger", trigger, "duration", run.Duration())
+	}
+
+	// Record even when the scheduler is stopping
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), 5*time.Second)
+	defer cancel()
+	s.mu.Lock()
+	history := s.history
+	s.mu.Unlock()
+	if err := history.Record(recordCtx, run); err != nil {
+		logger.Error("Failed to record run", "job", j.name, "error", err)
+	}
+	return run
+}
+
+func call(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Trigger starts a manual run of the job now
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.running {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	s.dispatch(j, []time.Time{time.Now()}, TriggerManual)
+	return nil
+}
+
+// Pause stops scheduled runs of the job; a run in progress finishes and
+// manual triggers still work
+func (s *Scheduler) Pause(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	j.paused = true
+	return nil
+}
+
+// Resume restarts scheduled runs of a paused job from now on, skipping
+// those that fell due while it was paused
+func (s *Scheduler) Resume(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.paused {
+		j.paused = false
+		j.next = j.schedule.Next(time.Now())
+		s.notify()
+	}
+	return nil
+}
+
+// Get returns a snapshot of one job
+func (s *Scheduler) Get(name string) (JobInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return JobInfo{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return j.info(s.started), nil
+}
+
+// List returns a snapshot of every job, sorted by name
+func (s *Scheduler) List() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		infos = append(infos, j.info(s.started))
+	}
+	slices.SortFunc(infos, func(a, b JobInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos
+}
+
+// History returns up to limit runs of the job, newest first
+func (s *Scheduler) History(ctx context.Context, name string, limit int) ([]RunRecord, error) {
+	s.mu.Lock()
+	history := s.history
+	s.mu.Unlock()
+	return history.Runs(ctx, name, limit)
+}
+
+func (j *job) info(started bool) JobInfo {
+	info := JobInfo{
+		Name:       j.name,
+		Spec:       j.spec,
+		MissedRuns: j.policy.String(),
+		Prev:       j.prev,
+		Paused:     j.paused,
+		Running:    j.running,
+		LastError:  j.lastErr,
+		Missed:     j.missed,
+	}
+	if started && !j.paused {
+		info.Next = j.next
+	}
+	return info
+}
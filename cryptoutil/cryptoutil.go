@@ -0,0 +1,100 @@
+// Package cryptoutil holds the small cryptographic helpers security code
+// keeps needing: comparisons that take the same time whatever the input,
+// random numbers from crypto/rand, HMACs and key derivation; random bytes
+// and tokens come from string_op. Comparing a secret with == returns at the
+// first differing byte, which lets an attacker who can time the answer
+// guess a token one byte at a time.
+package cryptoutil
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Equal reports whether a and b are the same, in time that depends on
+// neither their contents nor their lengths. Both are hashed first, since
+// subtle.ConstantTimeCompare returns at once for different lengths.
+func Equal(a, b string) bool {
+	return EqualBytes([]byte(a), []byte(b))
+}
+
+// EqualBytes is Equal for byte slices
+func EqualBytes(a, b []byte) bool {
+	ha, hb := sha256.Sum256(a), sha256.Sum256(b)
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// RandomInt returns a uniform random number in [0, max). It panics if max
+// is not positive.
+func RandomInt(max int64) int64 {
+	if max <= 0 {
+		panic("cryptoutil: RandomInt max must be positive")
+	}
+	n, _ := rand.Int(rand.Reader, big.NewInt(max))
+	return n.Int64()
+}
+
+// HMAC returns the HMAC-SHA256 of data under key
+func HMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// HMACHex returns the HMAC-SHA256 of data under key as lowercase hex
+func HMACHex(key, data []byte) string {
+	return hex.EncodeToString(HMAC(key, data))
+}
+
+// VerifyHMAC reports whether mac is the HMAC-SHA256 of data under key
+func VerifyHMAC(key, data, mac []byte) bool {
+	return hmac.Equal(mac, HMAC(key, data))
+}
+
+// VerifyHMACHex is VerifyHMAC for a hex-encoded mac, in either case
+func VerifyHMACHex(key, data []byte, mac string) bool {
+	decoded, err := hex.DecodeString(mac)
+	return err == nil && VerifyHMAC(key, data, decoded)
+}
+
+// DeriveKey derives a key of length bytes from a high-entropy secret with
+// HKDF-SHA256. Different info strings, such as "csrf" and "signed-urls",
+// give independent keys from one configured secret. It is not for
+// passwords; use PasswordKey for those.
+func DeriveKey(secret, salt []byte, info string, length int) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, secret, salt, info, length)
+	if err != nil {
+		return nil, fmt.Errorf("cryptoutil: derive key: %w", err)
+	}
+	return key, nil
+}
+
+// PasswordKey derives a key of length bytes from a password with scrypt
+// at the parameters recommended for interactive logins (N=32768, r=8,
+// p=1). salt should be at least 16 random bytes, stored next to the key.
+func PasswordKey(password, salt []byte, length int) ([]byte, error) {
+	key, err := scrypt.Key(password, salt, 1<<15, 8, 1, length)
+	if err != nil {
+		return nil, fmt.Errorf("cryptoutil: password key: %w", err)
+	}
+	return key, nil
+}
+
+// PBKDF2Key derives a key from a password with PBKDF2-HMAC-SHA256, for
+// formats that call for it. OWASP recommends 600000 iterations.
+func PBKDF2Key(password string, salt []byte, iterations, length int) ([]byte, error) {
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, length)
+	if err != nil {
+		return nil, fmt.Errorf("cryptoutil: pbkdf2 key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,413 @@
+package data_structure
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EvictReason tells an eviction callback why an entry left the cache
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota // removed to respect MaxEntries or MaxBytes
+	EvictExpired                     // TTL elapsed
+	EvictDeleted                     // removed with Delete or Purge
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// CacheOptions configures limits, expiry and callbacks for a Cache
+type CacheOptions[K comparable, V any] struct {
+	MaxEntries int                        // 0 means unlimited
+	MaxBytes   int64                      // 0 means unlimited, requires SizeOf
+	TTL        time.Duration              // 0 means entries never expire
+	SizeOf     func(key K, value V) int64 // size of an entry for MaxBytes
+	OnEvict    func(key K, value V, reason EvictReason)
+	Now        func() time.Time // clock, defaults to time.Now
+}
+
+// CacheStats holds cache counters
+type CacheStats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64
+	Expirations int64
+	Entries     int
+	Bytes       int64
+}
+
+// HitRate returns hits / (hits + misses)
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	size    int64
+	expires time.Time
+	freq    int
+	seq     uint64
+	elem    *list.Element
+	handle  *PQHandle[*cacheEntry[K, V]]
+}
+
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// evictionPolicy decides which entry to drop when the cache is full
+type evictionPolicy[K comparable, V any] interface {
+	added(e *cacheEntry[K, V])
+	accessed(e *cacheEntry[K, V])
+	removed(e *cacheEntry[K, V])
+	victim() *cacheEntry[K, V]
+}
+
+// Cache is a thread-safe generic cache whose eviction order is set by its policy
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	opts    CacheOptions[K, V]
+	entries map[K]*cacheEntry[K, V]
+	policy  evictionPolicy[K, V]
+	bytes   int64
+	seq     uint64
+	stats   CacheStats
+}
+
+func newCache[K comparable, V any](opts CacheOptions[K, V], policy evictionPolicy[K, V]) *Cache[K, V] {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Cache[K, V]{opts: opts, entries: make(map[K]*cacheEntry[K, V]), policy: policy}
+}
+
+// NewLRUCache creates a cache that evicts the least recently used entry
+func NewLRUCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	return newCache(opts, &lruPolicy[K, V]{order: list.New()})
+}
+
+// NewLFUCache creates a cache that evicts the least frequently used entry,
+// breaking ties by least recent use
+func NewLFUCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	return newCache[K, V](opts, &lfuPolicy[K, V]{queue: NewPriorityQueue(func(a, b *cacheEntry[K, V]) bool {
+		if a.freq != b.freq {
+			return a.freq < b.freq
+		}
+		return a.seq < b.seq
+	})})
+}
+
+// NewTTLCache creates a cache that evicts the entry closest to expiring
+func NewTTLCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	return newCache[K, V](opts, &ttlPolicy[K, V]{queue: NewPriorityQueue(func(a, b *cacheEntry[K, V]) bool {
+		if a.expires.IsZero() != b.expires.IsZero() {
+			return b.expires.IsZero()
+		}
+		if !a.expires.Equal(b.expires) {
+			return a.expires.Before(b.expires)
+		}
+		return a.seq < b.seq
+	})})
+}
+
+// NewCache creates a cache for a strategy name as used by config.CacheConfig:
+// "lru", "lfu" or "ttl"
+func NewCache[K comparable, V any](strategy string, opts CacheOptions[K, V]) (*Cache[K, V], error) {
+	switch strategy {
+	case "lru", "":
+		return NewLRUCache(opts), nil
+	case "lfu":
+		return NewLFUCache(opts), nil
+	case "ttl":
+		return NewTTLCache(opts), nil
+	}
+	return nil, fmt.Errorf("unknown cache strategy: %s", strategy)
+}
+
+// Get returns the value for key, counting a hit or miss
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && entry.expired(c.opts.Now()) {
+		c.removeEntry(entry, EvictExpired)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+
+	c.stats.Hits++
+	c.seq++
+	entry.seq = c.seq
+	entry.freq++
+	c.policy.accessed(entry)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting entries as needed to stay within limits
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value with its own TTL; 0 means it never expires
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	if c.opts.SizeOf != nil {
+		size = c.opts.SizeOf(key, value)
+	}
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.opts.Now().Add(ttl)
+	}
+
+	c.seq++
+	if entry, ok := c.entries[key]; ok {
+		c.bytes += size - entry.size
+		entry.value, entry.size, entry.expires, entry.seq = value, size, expires, c.seq
+		entry.freq++
+		c.policy.accessed(entry)
+	} else {
+		entry := &cacheEntry[K, V]{key: key, value: value, size: size, expires: expires, freq: 1, seq: c.seq}
+		c.entries[key] = entry
+		c.bytes += size
+		c.policy.added(entry)
+	}
+	c.enforceLimits()
+}
+
+// Delete removes key and reports whether it was present
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok {
+		c.removeEntry(entry, EvictDeleted)
+	}
+	return ok
+}
+
+// Expiry returns when key expires without counting a hit or miss; the zero
+// time means it never expires. ok is false if key is absent or expired.
+func (c *Cache[K, V]) Expiry(key K) (expires time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.expired(c.opts.Now()) {
+		return time.Time{}, false
+	}
+	return entry.expires, true
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// RemoveExpired drops all expired entries and returns how many were removed
+func (c *Cache[K, V]) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.opts.Now()
+	removed := 0
+	for _, entry := range c.entries {
+		if entry.expired(now) {
+			c.removeEntry(entry, EvictExpired)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Purge removes every entry
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		c.removeEntry(entry, EvictDeleted)
+	}
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *Cache[K, V]) enforceLimits() {
+	overLimit := func() bool {
+		return (c.opts.MaxEntries > 0 && len(c.entries) > c.opts.MaxEntries) ||
+			(c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes)
+	}
+	if !overLimit() {
+		return
+	}
+
+	// Expired entries go first so live ones are not evicted needlessly
+	now := c.opts.Now()
+	for _, entry := range c.entries {
+		if entry.expired(now) {
+			c.removeEntry(entry, EvictExpired)
+		}
+	}
+	for overLimit() {
+		victim := c.policy.victim()
+		if victim == nil {
+			return
+		}
+		c.removeEntry(victim, EvictCapacity)
+	}
+}
+
+func (c *Cache[K, V]) removeEntry(entry *cacheEntry[K, V], reason EvictReason) {
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+	c.policy.removed(entry)
+
+	switch reason {
+	case EvictCapacity:
+		c.stats.Evictions++
+	case EvictExpired:
+		c.stats.Expirations++
+	}
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(entry.key, entry.value, reason)
+	}
+}
+
+// lruPolicy keeps entries in a list with the most recently used at the front
+type lruPolicy[K comparable, V any] struct {
+	order *list.List
+}
+
+func (p *lruPolicy[K, V]) added(e *cacheEntry[K, V])    { e.elem = p.order.PushFront(e) }
+func (p *lruPolicy[K, V]) accessed(e *cacheEntry[K, V]) { p.order.MoveToFront(e.elem) }
+func (p *lruPolicy[K, V]) removed(e *cacheEntry[K, V])  { p.order.Remove(e.elem) }
+
+func (p *lruPolicy[K, V]) victim() *cacheEntry[K, V] {
+	if back := p.order.Back(); back != nil {
+		return back.Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+// lfuPolicy orders entries by use count, then by last use
+type lfuPolicy[K comparable, V any] struct {
+	queue *PriorityQueue[*cacheEntry[K, V]]
+}
+
+func (p *lfuPolicy[K, V]) added(e *cacheEntry[K, V])    { e.handle = p.queue.Push(e) }
+func (p *lfuPolicy[K, V]) accessed(e *cacheEntry[K, V]) { p.queue.Update(e.handle, e) }
+func (p *lfuPolicy[K, V]) removed(e *cacheEntry[K, V])  { p.queue.Remove(e.handle) }
+
+func (p *lfuPolicy[K, V]) victim() *cacheEntry[K, V] {
+	e, _ := p.queue.Peek()
+	return e
+}
+
+// ttlPolicy orders entries by expiry; entries without a TTL are evicted last
+type ttlPolicy[K comparable, V any] struct {
+	queue *PriorityQueue[*cacheEntry[K, V]]
+}
+
+func (p *ttlPolicy[K, V]) added(e *cacheEntry[K, V])    { e.handle = p.queue.Push(e) }
+func (p *ttlPolicy[K, V]) accessed(e *cacheEntry[K, V]) { p.queue.Update(e.handle, e) }
+func (p *ttlPolicy[K, V]) removed(e *cacheEntry[K, V])  { p.queue.Remove(e.handle) }
+
+func (p *ttlPolicy[K, V]) victim() *cacheEntry[K, V] {
+	e, _ := p.queue.Peek()
+	return e
+}
+
+// CacheOperations demonstrates the LRU, LFU and TTL caches
+func CacheOperations() {
+	fmt.Println("\n=== LRU / LFU / TTL Caches ===")
+
+	logEvict := func(key string, value int, reason EvictReason) {
+		fmt.Printf("  🗑️  evicted %s=%d (%s)\n", key, value, reason)
+	}
+
+	fmt.Println("📋 LRU cache (max 3 entries):")
+	lru := NewLRUCache(CacheOptions[string, int]{MaxEntries: 3, OnEvict: logEvict})
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Set("c", 3)
+	lru.Get("a") // "a" becomes most recently used
+	lru.Set("d", 4)
+	_, hasB := lru.Get("b")
+	fmt.Printf("  'b' present: %t\n", hasB)
+
+	fmt.Println("📋 LFU cache (max 3 entries):")
+	lfu := NewLFUCache(CacheOptions[string, int]{MaxEntries: 3, OnEvict: logEvict})
+	lfu.Set("a", 1)
+	lfu.Set("b", 2)
+	lfu.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		lfu.Get("a")
+		lfu.Get("c")
+	}
+	lfu.Set("d", 4)
+
+	fmt.Println("📋 Byte-limited cache (max 10 bytes):")
+	sized := NewLRUCache(CacheOptions[string, string]{
+		MaxBytes: 10,
+		SizeOf:   func(key, value string) int64 { return int64(len(value)) },
+	})
+	for _, word := range []string{"alpha", "beta", "gamma"} {
+		sized.Set(word, word)
+	}
+	stats := sized.Stats()
+	fmt.Printf("  entries=%d bytes=%d evictions=%d\n", stats.Entries, stats.Bytes, stats.Evictions)
+
+	fmt.Println("📋 TTL cache with a controllable clock:")
+	now := time.Now()
+	ttl, _ := NewCache[string, int]("ttl", CacheOptions[string, int]{
+		TTL:     time.Minute,
+		OnEvict: logEvict,
+		Now:     func() time.Time { return now },
+	})
+	ttl.Set("session", 42)
+	ttl.SetWithTTL("token", 7, 10*time.Second)
+	now = now.Add(30 * time.Second)
+	_, hasToken := ttl.Get("token")
+	value, hasSession := ttl.Get("session")
+	fmt.Printf("  token present: %t, session=%d present: %t\n", hasToken, value, hasSession)
+
+	stats = ttl.Stats()
+	fmt.Printf("📊 TTL stats: hits=%d misses=%d expirations=%d hit rate=%.0f%%\n",
+		stats.Hits, stats.Misses, stats.Expirations, stats.HitRate()*100)
+}
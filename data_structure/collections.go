@@ -0,0 +1,301 @@
+package data_structure
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Map returns fn applied to every element of s
+func Map[T, R any](s []T, fn func(T) R) []R {
+	result := make([]R, len(s))
+	for i, v := range s {
+		result[i] = fn(v)
+	}
+	return result
+}
+
+// Filter returns the elements of s for which keep returns true
+func Filter[T any](s []T, keep func(T) bool) []T {
+	var result []T
+	for _, v := range s {
+		if keep(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Reduce folds s into a single value starting from initial
+func Reduce[T, A any](s []T, initial A, fn func(acc A, v T) A) A {
+	acc := initial
+	for _, v := range s {
+		acc = fn(acc, v)
+	}
+	return acc
+}
+
+// GroupBy buckets the elements of s by key, keeping their order within each group
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, v := range s {
+		k := key(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Chunk splits s into consecutive slices of at most size elements
+func Chunk[T any](s []T, size int) [][]T {
+	if size <= 0 {
+		panic("data_structure: chunk size must be positive")
+	}
+	chunks := make([][]T, 0, (len(s)+size-1)/size)
+	for start := 0; start < len(s); start += size {
+		chunks = append(chunks, s[start:min(start+size, len(s))])
+	}
+	return chunks
+}
+
+// Unique returns the elements of s without duplicates, keeping first occurrences
+func Unique[T comparable](s []T) []T {
+	seen := make(map[T]struct{}, len(s))
+	var result []T
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// SortBy returns a copy of s stably sorted by key
+func SortBy[T any, K cmp.Ordered](s []T, key func(T) K) []T {
+	sorted := slices.Clone(s)
+	slices.SortStableFunc(sorted, func(a, b T) int { return cmp.Compare(key(a), key(b)) })
+	return sorted
+}
+
+// Pair holds two values, as produced by Zip
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs up the elements of a and b, stopping at the shorter slice
+func Zip[A, B any](a []A, b []B) []Pair[A, B] {
+	pairs := make([]Pair[A, B], min(len(a), len(b)))
+	for i := range pairs {
+		pairs[i] = Pair[A, B]{a[i], b[i]}
+	}
+	return pairs
+}
+
+// Keys returns the keys of m in unspecified order
+func Keys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// SortedKeys returns the keys of m in ascending order
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := Keys(m)
+	slices.Sort(keys)
+	return keys
+}
+
+// Values returns the values of m in unspecified order
+func Values[K comparable, V any](m map[K]V) []V {
+	values := make([]V, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Invert swaps keys and values; for duplicate values an arbitrary key wins
+func Invert[K, V comparable](m map[K]V) map[V]K {
+	inverted := make(map[V]K, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+	return inverted
+}
+
+// Merge combines maps into a new one; later maps override earlier ones
+func Merge[K comparable, V any](maps ...map[K]V) map[K]V {
+	merged := make(map[K]V)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// MapSeq lazily applies fn to every element of seq
+func MapSeq[T, R any](seq iter.Seq[T], fn func(T) R) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for v := range seq {
+			if !yield(fn(v)) {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeq lazily yields the elements of seq for which keep returns true
+func FilterSeq[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// TakeSeq yields at most n elements of seq
+func TakeSeq[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		taken := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if taken++; taken == n {
+				return
+			}
+		}
+	}
+}
+
+// UniqueSeq lazily drops elements that were already yielded
+func UniqueSeq[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		for v := range seq {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ChunkSeq lazily groups seq into slices of at most size elements
+func ChunkSeq[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		var chunk []T
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = nil
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// ZipSeq lazily pairs up two sequences, stopping at the shorter one
+func ZipSeq[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq[Pair[A, B]] {
+	return func(yield func(Pair[A, B]) bool) {
+		nextB, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := nextB()
+			if !ok || !yield(Pair[A, B]{va, vb}) {
+				return
+			}
+		}
+	}
+}
+
+// ReduceSeq folds seq into a single value starting from initial
+func ReduceSeq[T, A any](seq iter.Seq[T], initial A, fn func(acc A, v T) A) A {
+	acc := initial
+	for v := range seq {
+		acc = fn(acc, v)
+	}
+	return acc
+}
+
+// CollectionUtilityOperations demonstrates the generic collection helpers
+func CollectionUtilityOperations() {
+	fmt.Println("\n=== Generic Collection Utilities ===")
+
+	type employee struct {
+		Name   string
+		Team   string
+		Salary int
+	}
+	staff := []employee{
+		{"Alice", "platform", 140}, {"Bob", "web", 120}, {"Carol", "platform", 150},
+		{"Dan", "data", 130}, {"Eve", "web", 125}, {"Frank", "data", 110},
+	}
+
+	fmt.Println("📋 Slice helpers:")
+	names := Map(staff, func(e employee) string { return e.Name })
+	fmt.Printf("  names: %v\n", names)
+	wellPaid := Filter(staff, func(e employee) bool { return e.Salary >= 130 })
+	fmt.Printf("  salary >= 130: %v\n", Map(wellPaid, func(e employee) string { return e.Name }))
+	total := Reduce(staff, 0, func(sum int, e employee) int { return sum + e.Salary })
+	fmt.Printf("  total payroll: %d\n", total)
+
+	byTeam := GroupBy(staff, func(e employee) string { return e.Team })
+	for _, team := range SortedKeys(byTeam) {
+		fmt.Printf("  team %-8s -> %v\n", team, Map(byTeam[team], func(e employee) string { return e.Name }))
+	}
+	fmt.Printf("  by salary: %v\n", Map(SortBy(staff, func(e employee) int { return e.Salary }), func(e employee) string { return e.Name }))
+	fmt.Printf("  chunks of 4: %v\n", Chunk(names, 4))
+	fmt.Printf("  unique teams: %v\n", Unique(Map(staff, func(e employee) string { return e.Team })))
+	fmt.Printf("  zip: %v\n", Zip(names[:3], []int{1, 2, 3}))
+
+	fmt.Println("📋 Map helpers:")
+	codes := map[string]int{"ok": 200, "created": 201, "not_found": 404}
+	fmt.Printf("  keys: %v\n", SortedKeys(codes))
+	fmt.Printf("  inverted[404]: %s\n", Invert(codes)[404])
+	merged := Merge(codes, map[string]int{"not_found": 410, "teapot": 418})
+	fmt.Printf("  merged: %d entries, not_found=%d\n", len(merged), merged["not_found"])
+
+	fmt.Println("📋 Lazy iterators (only evaluate what is consumed):")
+	evaluated := 0
+	squares := MapSeq(func(yield func(int) bool) {
+		for i := 1; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}, func(i int) int {
+		evaluated++
+		return i * i
+	})
+	odd := FilterSeq(squares, func(n int) bool { return n%2 == 1 })
+	fmt.Printf("  first 5 odd squares of an infinite sequence: %v (evaluated %d)\n",
+		slices.Collect(TakeSeq(odd, 5)), evaluated)
+
+	words := slices.Values(strings.Fields("the quick brown fox jumps over the lazy dog the end"))
+	for chunk := range ChunkSeq(UniqueSeq(words), 3) {
+		fmt.Printf("  chunk: %v\n", chunk)
+	}
+	for pair := range ZipSeq(slices.Values(names), slices.Values([]string{"gold", "silver", "bronze"})) {
+		fmt.Printf("  %s wins %s\n", pair.First, pair.Second)
+	}
+}
@@ -639,6 +639,15 @@ func RunAllDataStructureExamples() {
 	ListOperations()
 	ListAdvancedOperations()
 	HeapOperations()
+	PriorityQueueOperations()
+	CacheOperations()
+	TimingWheelOperations()
+	OrderedCollectionOperations()
+	TrieOperations()
+	RangeStructureOperations()
+	PersistentCollectionOperations()
+	StreamingStatsOperations()
+	CollectionUtilityOperations()
 	RingOperations()
 	SortOperations()
 	BuiltInPackageExamples()
@@ -0,0 +1,376 @@
+package data_structure
+
+import (
+	"cmp"
+	"container/list"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// OrderedMap is a map that remembers insertion order
+type OrderedMap[K comparable, V any] struct {
+	entries map[K]*list.Element
+	order   *list.List
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewOrderedMap creates an empty ordered map
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{entries: make(map[K]*list.Element), order: list.New()}
+}
+
+// Set stores value under key; updating a key keeps its original position
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if elem, ok := m.entries[key]; ok {
+		elem.Value.(*orderedEntry[K, V]).value = value
+		return
+	}
+	m.entries[key] = m.order.PushBack(&orderedEntry[K, V]{key: key, value: value})
+}
+
+// Get returns the value for key
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if elem, ok := m.entries[key]; ok {
+		return elem.Value.(*orderedEntry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key and reports whether it was present
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	elem, ok := m.entries[key]
+	if ok {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+	return ok
+}
+
+// Len returns the number of entries
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+// Keys returns the keys in insertion order
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, len(m.entries))
+	m.Range(func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range calls fn for each entry in insertion order until fn returns false
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for elem := m.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*orderedEntry[K, V])
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+const skipListMaxLevel = 16
+
+type skipNode[K any, V any] struct {
+	key   K
+	value V
+	next  []*skipNode[K, V]
+}
+
+// TreeMap is a sorted map backed by a skip list, giving O(log n) expected
+// lookups, inserts and deletes plus ordered range queries
+type TreeMap[K any, V any] struct {
+	head    *skipNode[K, V]
+	level   int
+	length  int
+	compare func(a, b K) int
+	rng     *rand.Rand
+}
+
+// NewTreeMap creates a sorted map for naturally ordered keys
+func NewTreeMap[K cmp.Ordered, V any]() *TreeMap[K, V] {
+	return NewTreeMapFunc[K, V](cmp.Compare[K])
+}
+
+// NewTreeMapFunc creates a sorted map ordered by compare
+func NewTreeMapFunc[K any, V any](compare func(a, b K) int) *TreeMap[K, V] {
+	return &TreeMap[K, V]{
+		head:    &skipNode[K, V]{next: make([]*skipNode[K, V], skipListMaxLevel)},
+		level:   1,
+		compare: compare,
+		rng:     rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (t *TreeMap[K, V]) randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && t.rng.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
+
+// findPrev fills update with the last node before key on every level
+func (t *TreeMap[K, V]) findPrev(key K, update []*skipNode[K, V]) *skipNode[K, V] {
+	node := t.head
+	for i := t.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && t.compare(node.next[i].key, key) < 0 {
+			node = node.next[i]
+		}
+		if update != nil {
+			update[i] = node
+		}
+	}
+	return node
+}
+
+// Put stores value under key
+func (t *TreeMap[K, V]) Put(key K, value V) {
+	update := make([]*skipNode[K, V], skipListMaxLevel)
+	prev := t.findPrev(key, update)
+	if next := prev.next[0]; next != nil && t.compare(next.key, key) == 0 {
+		next.value = value
+		return
+	}
+
+	level := t.randomLevel()
+	for i := t.level; i < level; i++ {
+		update[i] = t.head
+	}
+	if level > t.level {
+		t.level = level
+	}
+
+	node := &skipNode[K, V]{key: key, value: value, next: make([]*skipNode[K, V], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	t.length++
+}
+
+// Get returns the value for key
+func (t *TreeMap[K, V]) Get(key K) (V, bool) {
+	if next := t.findPrev(key, nil).next[0]; next != nil && t.compare(next.key, key) == 0 {
+		return next.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key and reports whether it was present
+func (t *TreeMap[K, V]) Delete(key K) bool {
+	update := make([]*skipNode[K, V], skipListMaxLevel)
+	node := t.findPrev(key, update).next[0]
+	if node == nil || t.compare(node.key, key) != 0 {
+		return false
+	}
+	for i := 0; i < len(node.next); i++ {
+		update[i].next[i] = node.next[i]
+	}
+	for t.level > 1 && t.head.next[t.level-1] == nil {
+		t.level--
+	}
+	t.length--
+	return true
+}
+
+// Len returns the number of entries
+func (t *TreeMap[K, V]) Len() int {
+	return t.length
+}
+
+// Min returns the smallest key
+func (t *TreeMap[K, V]) Min() (K, V, bool) {
+	return t.entry(t.head.next[0])
+}
+
+// Max returns the largest key
+func (t *TreeMap[K, V]) Max() (K, V, bool) {
+	node := t.head
+	for i := t.level - 1; i >= 0; i-- {
+		for node.next[i] != nil {
+			node = node.next[i]
+		}
+	}
+	if node == t.head {
+		return t.entry(nil)
+	}
+	return t.entry(node)
+}
+
+// Floor returns the greatest key less than or equal to key
+func (t *TreeMap[K, V]) Floor(key K) (K, V, bool) {
+	prev := t.findPrev(key, nil)
+	if next := prev.next[0]; next != nil && t.compare(next.key, key) == 0 {
+		return t.entry(next)
+	}
+	if prev == t.head {
+		return t.entry(nil)
+	}
+	return t.entry(prev)
+}
+
+// Ceiling returns the smallest key greater than or equal to key
+func (t *TreeMap[K, V]) Ceiling(key K) (K, V, bool) {
+	return t.entry(t.findPrev(key, nil).next[0])
+}
+
+// Range calls fn for keys in [from, to] in ascending order until fn returns false
+func (t *TreeMap[K, V]) Range(from, to K, fn func(key K, value V) bool) {
+	for node := t.findPrev(from, nil).next[0]; node != nil && t.compare(node.key, to) <= 0; node = node.next[0] {
+		if !fn(node.key, node.value) {
+			return
+		}
+	}
+}
+
+// Each calls fn for every entry in ascending key order until fn returns false
+func (t *TreeMap[K, V]) Each(fn func(key K, value V) bool) {
+	for node := t.head.next[0]; node != nil; node = node.next[0] {
+		if !fn(node.key, node.value) {
+			return
+		}
+	}
+}
+
+// Keys returns all keys in ascending order
+func (t *TreeMap[K, V]) Keys() []K {
+	keys := make([]K, 0, t.length)
+	t.Each(func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func (t *TreeMap[K, V]) entry(node *skipNode[K, V]) (K, V, bool) {
+	if node == nil {
+		var key K
+		var value V
+		return key, value, false
+	}
+	return node.key, node.value, true
+}
+
+// SortedSet is a set whose members are kept in ascending order
+type SortedSet[T any] struct {
+	tree *TreeMap[T, struct{}]
+}
+
+// NewSortedSet creates a sorted set for naturally ordered members
+func NewSortedSet[T cmp.Ordered](members ...T) *SortedSet[T] {
+	return NewSortedSetFunc(cmp.Compare[T], members...)
+}
+
+// NewSortedSetFunc creates a sorted set ordered by compare
+func NewSortedSetFunc[T any](compare func(a, b T) int, members ...T) *SortedSet[T] {
+	s := &SortedSet[T]{tree: NewTreeMapFunc[T, struct{}](compare)}
+	for _, member := range members {
+		s.Add(member)
+	}
+	return s
+}
+
+// Add inserts member
+func (s *SortedSet[T]) Add(member T) {
+	s.tree.Put(member, struct{}{})
+}
+
+// Remove deletes member and reports whether it was present
+func (s *SortedSet[T]) Remove(member T) bool {
+	return s.tree.Delete(member)
+}
+
+// Contains reports whether member is in the set
+func (s *SortedSet[T]) Contains(member T) bool {
+	_, ok := s.tree.Get(member)
+	return ok
+}
+
+// Len returns the number of members
+func (s *SortedSet[T]) Len() int {
+	return s.tree.Len()
+}
+
+// Floor returns the greatest member less than or equal to member
+func (s *SortedSet[T]) Floor(member T) (T, bool) {
+	found, _, ok := s.tree.Floor(member)
+	return found, ok
+}
+
+// Ceiling returns the smallest member greater than or equal to member
+func (s *SortedSet[T]) Ceiling(member T) (T, bool) {
+	found, _, ok := s.tree.Ceiling(member)
+	return found, ok
+}
+
+// Range returns the members in [from, to]
+func (s *SortedSet[T]) Range(from, to T) []T {
+	var members []T
+	s.tree.Range(from, to, func(member T, _ struct{}) bool {
+		members = append(members, member)
+		return true
+	})
+	return members
+}
+
+// Members returns all members in ascending order
+func (s *SortedSet[T]) Members() []T {
+	return s.tree.Keys()
+}
+
+// OrderedCollectionOperations demonstrates OrderedMap, TreeMap and SortedSet
+func OrderedCollectionOperations() {
+	fmt.Println("\n=== Ordered Map, Tree Map and Sorted Set ===")
+
+	fmt.Println("📋 OrderedMap keeps insertion order:")
+	headers := NewOrderedMap[string, string]()
+	headers.Set("Host", "example.com")
+	headers.Set("Accept", "application/json")
+	headers.Set("User-Agent", "go-practice")
+	headers.Set("Accept", "text/html") // update keeps position
+	headers.Delete("User-Agent")
+	headers.Range(func(key, value string) bool {
+		fmt.Printf("  %s: %s\n", key, value)
+		return true
+	})
+
+	fmt.Println("📋 TreeMap with range queries:")
+	scores := NewTreeMap[int, string]()
+	for score, grade := range map[int]string{90: "A", 80: "B", 70: "C", 60: "D", 0: "F"} {
+		scores.Put(score, grade)
+	}
+	for _, score := range []int{95, 83, 61, 42} {
+		threshold, grade, _ := scores.Floor(score)
+		fmt.Printf("  score %d -> grade %s (threshold %d)\n", score, grade, threshold)
+	}
+	var between []string
+	scores.Range(60, 80, func(score int, grade string) bool {
+		between = append(between, fmt.Sprintf("%d=%s", score, grade))
+		return true
+	})
+	fmt.Printf("  thresholds in [60, 80]: %s\n", strings.Join(between, ", "))
+
+	fmt.Println("📋 SortedSet:")
+	set := NewSortedSet("pear", "apple", "fig", "banana", "cherry", "apple")
+	fmt.Printf("  members: %v (len %d)\n", set.Members(), set.Len())
+	next, _ := set.Ceiling("c")
+	fmt.Printf("  first member >= \"c\": %s\n", next)
+	fmt.Printf("  members in [b, g]: %v\n", set.Range("b", "g"))
+	set.Remove("fig")
+	fmt.Printf("  contains fig after remove: %t\n", set.Contains("fig"))
+
+	byLength := NewSortedSetFunc(func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	}, set.Members()...)
+	fmt.Printf("  ordered by length: %v\n", byLength.Members())
+}
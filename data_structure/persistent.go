@@ -0,0 +1,391 @@
+package data_structure
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math/bits"
+	"slices"
+)
+
+const (
+	persistentBits  = 5
+	persistentWidth = 1 << persistentBits
+	persistentMask  = persistentWidth - 1
+)
+
+// PersistentVector is an immutable indexed sequence stored as a 32-way trie.
+// Set and Append copy only the O(log32 n) nodes on the path to the changed
+// element and share the rest with the previous version, so old versions stay
+// valid and can be read from any goroutine without locking.
+type PersistentVector[T any] struct {
+	root   *vectorNode[T]
+	shift  uint
+	length int
+}
+
+type vectorNode[T any] struct {
+	children []*vectorNode[T]
+	values   []T
+}
+
+// NewPersistentVector creates a vector holding values
+func NewPersistentVector[T any](values ...T) *PersistentVector[T] {
+	v := &PersistentVector[T]{root: &vectorNode[T]{}}
+	for _, value := range values {
+		v = v.Append(value)
+	}
+	return v
+}
+
+// Len returns the number of elements
+func (v *PersistentVector[T]) Len() int {
+	return v.length
+}
+
+// Get returns the element at index i; it panics if i is out of range
+func (v *PersistentVector[T]) Get(i int) T {
+	if i < 0 || i >= v.length {
+		panic(fmt.Sprintf("persistent vector: index %d out of range [0:%d]", i, v.length))
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= persistentBits {
+		node = node.children[(i>>level)&persistentMask]
+	}
+	return node.values[i&persistentMask]
+}
+
+// Set returns a new vector with the element at index i replaced
+func (v *PersistentVector[T]) Set(i int, value T) *PersistentVector[T] {
+	if i < 0 || i >= v.length {
+		panic(fmt.Sprintf("persistent vector: index %d out of range [0:%d]", i, v.length))
+	}
+	return &PersistentVector[T]{root: v.root.set(v.shift, i, value), shift: v.shift, length: v.length}
+}
+
+func (n *vectorNode[T]) set(level uint, i int, value T) *vectorNode[T] {
+	copied := &vectorNode[T]{}
+	if level == 0 {
+		copied.values = slices.Clone(n.values)
+		copied.values[i&persistentMask] = value
+		return copied
+	}
+	copied.children = slices.Clone(n.children)
+	idx := (i >> level) & persistentMask
+	copied.children[idx] = n.children[idx].set(level-persistentBits, i, value)
+	return copied
+}
+
+// Append returns a new vector with value added at the end
+func (v *PersistentVector[T]) Append(value T) *PersistentVector[T] {
+	if v.length == 1<<(v.shift+persistentBits) {
+		// The trie is full: grow a level with the old root as its first child
+		root := &vectorNode[T]{children: []*vectorNode[T]{v.root}}
+		return &PersistentVector[T]{
+			root:   root.appendValue(v.shift+persistentBits, v.length, value),
+			shift:  v.shift + persistentBits,
+			length: v.length + 1,
+		}
+	}
+	return &PersistentVector[T]{root: v.root.appendValue(v.shift, v.length, value), shift: v.shift, length: v.length + 1}
+}
+
+func (n *vectorNode[T]) appendValue(level uint, i int, value T) *vectorNode[T] {
+	copied := &vectorNode[T]{}
+	if n == nil {
+		n = copied
+	}
+	if level == 0 {
+		copied.values = append(slices.Clip(n.values), value)
+		return copied
+	}
+	copied.children = slices.Clone(n.children)
+	idx := (i >> level) & persistentMask
+	if idx == len(copied.children) {
+		copied.children = append(copied.children, nil)
+	}
+	copied.children[idx] = copied.children[idx].appendValue(level-persistentBits, i, value)
+	return copied
+}
+
+// Each calls fn for every element in order until fn returns false
+func (v *PersistentVector[T]) Each(fn func(i int, value T) bool) {
+	i := 0
+	var walk func(n *vectorNode[T], level uint) bool
+	walk = func(n *vectorNode[T], level uint) bool {
+		if level == 0 {
+			for _, value := range n.values {
+				if !fn(i, value) {
+					return false
+				}
+				i++
+			}
+			return true
+		}
+		for _, child := range n.children {
+			if !walk(child, level-persistentBits) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(v.root, v.shift)
+}
+
+// ToSlice copies the elements into a new slice
+func (v *PersistentVector[T]) ToSlice() []T {
+	values := make([]T, 0, v.length)
+	v.Each(func(_ int, value T) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// PersistentMap is an immutable hash array mapped trie (HAMT). Set and Delete
+// return a new map that shares all untouched nodes with the old one.
+type PersistentMap[K comparable, V any] struct {
+	root   *hamtNode[K, V]
+	seed   maphash.Seed
+	length int
+}
+
+type hamtNode[K comparable, V any] struct {
+	bitmap    uint32
+	entries   []hamtEntry[K, V]
+	collision bool // all entries share a full 64-bit hash; searched linearly
+}
+
+type hamtEntry[K comparable, V any] struct {
+	hash  uint64
+	key   K
+	value V
+	child *hamtNode[K, V]
+}
+
+// NewPersistentMap creates an empty map
+func NewPersistentMap[K comparable, V any]() *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{root: &hamtNode[K, V]{}, seed: maphash.MakeSeed()}
+}
+
+// Len returns the number of entries
+func (m *PersistentMap[K, V]) Len() int {
+	return m.length
+}
+
+// Get returns the value stored under key
+func (m *PersistentMap[K, V]) Get(key K) (V, bool) {
+	hash := maphash.Comparable(m.seed, key)
+	node := m.root
+	for shift := uint(0); ; shift += persistentBits {
+		if node.collision {
+			for _, e := range node.entries {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			break
+		}
+		bit := uint32(1) << ((hash >> shift) & persistentMask)
+		if node.bitmap&bit == 0 {
+			break
+		}
+		e := node.entries[bits.OnesCount32(node.bitmap&(bit-1))]
+		if e.child == nil {
+			if e.key == key {
+				return e.value, true
+			}
+			break
+		}
+		node = e.child
+	}
+	var zero V
+	return zero, false
+}
+
+// Set returns a new map with value stored under key
+func (m *PersistentMap[K, V]) Set(key K, value V) *PersistentMap[K, V] {
+	root, added := m.root.set(0, hamtEntry[K, V]{hash: maphash.Comparable(m.seed, key), key: key, value: value})
+	length := m.length
+	if added {
+		length++
+	}
+	return &PersistentMap[K, V]{root: root, seed: m.seed, length: length}
+}
+
+func (n *hamtNode[K, V]) set(shift uint, leaf hamtEntry[K, V]) (*hamtNode[K, V], bool) {
+	if n.collision {
+		entries := slices.Clone(n.entries)
+		for i, e := range entries {
+			if e.key == leaf.key {
+				entries[i] = leaf
+				return &hamtNode[K, V]{entries: entries, collision: true}, false
+			}
+		}
+		return &hamtNode[K, V]{entries: append(entries, leaf), collision: true}, true
+	}
+
+	bit := uint32(1) << ((leaf.hash >> shift) & persistentMask)
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+	if n.bitmap&bit == 0 {
+		return &hamtNode[K, V]{bitmap: n.bitmap | bit, entries: slices.Insert(slices.Clip(n.entries), idx, leaf)}, true
+	}
+
+	entries := slices.Clone(n.entries)
+	added := false
+	switch e := entries[idx]; {
+	case e.child != nil:
+		entries[idx] = hamtEntry[K, V]{}
+		entries[idx].child, added = e.child.set(shift+persistentBits, leaf)
+	case e.key == leaf.key:
+		entries[idx] = leaf
+	default:
+		entries[idx] = hamtEntry[K, V]{child: mergeLeaves(shift+persistentBits, e, leaf)}
+		added = true
+	}
+	return &hamtNode[K, V]{bitmap: n.bitmap, entries: entries}, added
+}
+
+// mergeLeaves builds the subtree holding two leaves whose hashes agree up to shift
+func mergeLeaves[K comparable, V any](shift uint, a, b hamtEntry[K, V]) *hamtNode[K, V] {
+	if shift >= 64 {
+		return &hamtNode[K, V]{entries: []hamtEntry[K, V]{a, b}, collision: true}
+	}
+	ia, ib := (a.hash>>shift)&persistentMask, (b.hash>>shift)&persistentMask
+	if ia == ib {
+		child := mergeLeaves(shift+persistentBits, a, b)
+		return &hamtNode[K, V]{bitmap: 1 << ia, entries: []hamtEntry[K, V]{{child: child}}}
+	}
+	if ia > ib {
+		a, b = b, a
+	}
+	return &hamtNode[K, V]{bitmap: 1<<ia | 1<<ib, entries: []hamtEntry[K, V]{a, b}}
+}
+
+// Delete returns a new map without key
+func (m *PersistentMap[K, V]) Delete(key K) *PersistentMap[K, V] {
+	root, removed := m.root.delete(0, maphash.Comparable(m.seed, key), key)
+	if !removed {
+		return m
+	}
+	if root == nil {
+		root = &hamtNode[K, V]{}
+	}
+	return &PersistentMap[K, V]{root: root, seed: m.seed, length: m.length - 1}
+}
+
+// delete returns the replacement node, or nil when the node becomes empty
+func (n *hamtNode[K, V]) delete(shift uint, hash uint64, key K) (*hamtNode[K, V], bool) {
+	if n.collision {
+		for i, e := range n.entries {
+			if e.key == key {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				return &hamtNode[K, V]{entries: slices.Delete(slices.Clone(n.entries), i, i+1), collision: true}, true
+			}
+		}
+		return n, false
+	}
+
+	bit := uint32(1) << ((hash >> shift) & persistentMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+	e := n.entries[idx]
+
+	if e.child == nil {
+		if e.key != key {
+			return n, false
+		}
+		if len(n.entries) == 1 {
+			return nil, true
+		}
+		return &hamtNode[K, V]{bitmap: n.bitmap &^ bit, entries: slices.Delete(slices.Clone(n.entries), idx, idx+1)}, true
+	}
+
+	child, removed := e.child.delete(shift+persistentBits, hash, key)
+	if !removed {
+		return n, false
+	}
+	entries := slices.Clone(n.entries)
+	switch {
+	case child == nil:
+		if len(entries) == 1 {
+			return nil, true
+		}
+		return &hamtNode[K, V]{bitmap: n.bitmap &^ bit, entries: slices.Delete(entries, idx, idx+1)}, true
+	case len(child.entries) == 1 && child.entries[0].child == nil:
+		// Pull a lone leaf up so the trie stays as shallow as possible
+		entries[idx] = child.entries[0]
+	default:
+		entries[idx] = hamtEntry[K, V]{child: child}
+	}
+	return &hamtNode[K, V]{bitmap: n.bitmap, entries: entries}, true
+}
+
+// Range calls fn for every entry, in no particular order, until fn returns false
+func (m *PersistentMap[K, V]) Range(fn func(key K, value V) bool) {
+	m.root.walk(fn)
+}
+
+func (n *hamtNode[K, V]) walk(fn func(K, V) bool) bool {
+	for _, e := range n.entries {
+		if e.child != nil {
+			if !e.child.walk(fn) {
+				return false
+			}
+		} else if !fn(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// PersistentCollectionOperations demonstrates structural sharing between versions
+func PersistentCollectionOperations() {
+	fmt.Println("\n=== Persistent Vector and Map ===")
+
+	fmt.Println("📋 PersistentVector versions share structure:")
+	v1 := NewPersistentVector("a", "b", "c")
+	v2 := v1.Append("d")
+	v3 := v2.Set(0, "A")
+	fmt.Printf("  v1=%v v2=%v v3=%v\n", v1.ToSlice(), v2.ToSlice(), v3.ToSlice())
+
+	big := NewPersistentVector[int]()
+	for i := 0; i < 100_000; i++ {
+		big = big.Append(i)
+	}
+	updated := big.Set(54_321, -1)
+	fmt.Printf("  100k elements: old[54321]=%d new[54321]=%d, trie depth %d\n",
+		big.Get(54_321), updated.Get(54_321), big.shift/persistentBits+1)
+
+	fmt.Println("📋 PersistentMap (HAMT):")
+	config := NewPersistentMap[string, string]().
+		Set("env", "staging").
+		Set("region", "us-east-1").
+		Set("replicas", "3")
+	production := config.Set("env", "production").Delete("replicas")
+
+	for _, version := range []struct {
+		name string
+		m    *PersistentMap[string, string]
+	}{{"config", config}, {"production", production}} {
+		env, _ := version.m.Get("env")
+		_, hasReplicas := version.m.Get("replicas")
+		fmt.Printf("  %-10s len=%d env=%s has replicas=%t\n", version.name, version.m.Len(), env, hasReplicas)
+	}
+
+	counts := NewPersistentMap[int, int]()
+	snapshots := []*PersistentMap[int, int]{counts}
+	for i := 0; i < 10_000; i++ {
+		counts = counts.Set(i%1000, i)
+		if i%2500 == 0 {
+			snapshots = append(snapshots, counts)
+		}
+	}
+	for i, snapshot := range snapshots {
+		value, _ := snapshot.Get(0)
+		fmt.Printf("  snapshot %d: len=%d value[0]=%d\n", i, snapshot.Len(), value)
+	}
+}
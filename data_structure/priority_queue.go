@@ -0,0 +1,239 @@
+package data_structure
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// PQHandle refers to an element inside a PriorityQueue so it can be updated
+// or removed later without searching for it
+type PQHandle[T any] struct {
+	value T
+	index int    // position in the heap, -1 once the element has left the queue
+	seq   uint64 // insertion order, which breaks ties between equal elements
+}
+
+// Value returns the element the handle refers to
+func (h *PQHandle[T]) Value() T {
+	return h.value
+}
+
+// pqHeap adapts the handle slice to container/heap
+type pqHeap[T any] struct {
+	items []*PQHandle[T]
+	less  func(a, b T) bool
+	seq   uint64 // last insertion number handed out
+}
+
+func (h *pqHeap[T]) Len() int { return len(h.items) }
+
+// Less orders equal elements first in, first out
+func (h *pqHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.value, b.value) {
+		return true
+	}
+	if h.less(b.value, a.value) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *pqHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *pqHeap[T]) Push(x interface{}) {
+	item := x.(*PQHandle[T])
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *pqHeap[T]) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	h.items = old[:n-1]
+	return item
+}
+
+// PriorityQueue is a generic priority queue ordered by less: the element for
+// which less reports true against all others is popped first, and elements
+// that are equal under less pop in the order they were pushed
+type PriorityQueue[T any] struct {
+	h *pqHeap[T]
+}
+
+// NewPriorityQueue creates an empty queue ordered by less
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{h: &pqHeap[T]{less: less}}
+}
+
+// Len returns the number of queued elements
+func (pq *PriorityQueue[T]) Len() int {
+	return pq.h.Len()
+}
+
+// Push adds value and returns a handle to it
+func (pq *PriorityQueue[T]) Push(value T) *PQHandle[T] {
+	pq.h.seq++
+	item := &PQHandle[T]{value: value, seq: pq.h.seq}
+	heap.Push(pq.h, item)
+	return item
+}
+
+// Peek returns the highest-priority element without removing it
+func (pq *PriorityQueue[T]) Peek() (T, bool) {
+	if pq.h.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return pq.h.items[0].value, true
+}
+
+// Pop removes and returns the highest-priority element
+func (pq *PriorityQueue[T]) Pop() (T, bool) {
+	if pq.h.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(pq.h).(*PQHandle[T]).value, true
+}
+
+// Contains reports whether the handle's element is still queued
+func (pq *PriorityQueue[T]) Contains(h *PQHandle[T]) bool {
+	return h != nil && h.index >= 0 && h.index < pq.h.Len() && pq.h.items[h.index] == h
+}
+
+// Update replaces the handle's element and restores heap order. The element
+// keeps its place among equals. It returns false if the element is no longer in the queue.
+func (pq *PriorityQueue[T]) Update(h *PQHandle[T], value T) bool {
+	if !pq.Contains(h) {
+		return false
+	}
+	h.value = value
+	heap.Fix(pq.h, h.index)
+	return true
+}
+
+// Remove deletes the handle's element from the queue
+func (pq *PriorityQueue[T]) Remove(h *PQHandle[T]) (T, bool) {
+	if !pq.Contains(h) {
+		var zero T
+		return zero, false
+	}
+	return heap.Remove(pq.h, h.index).(*PQHandle[T]).value, true
+}
+
+// PriorityQueueOperations demonstrates the generic priority queue with handles
+func PriorityQueueOperations() {
+	fmt.Println("\n=== Generic Priority Queue with Handles ===")
+
+	pq := NewPriorityQueue(func(a, b Task) bool { return a.Priority > b.Priority })
+
+	handles := map[string]*PQHandle[Task]{}
+	for _, task := range []Task{
+		{"Write docs", 2},
+		{"Fix bug", 5},
+		{"Code review", 3},
+		{"Deploy", 1},
+		{"Refactor", 4},
+	} {
+		handles[task.Name] = pq.Push(task)
+	}
+	fmt.Printf("✅ Queued %d tasks\n", pq.Len())
+
+	if top, ok := pq.Peek(); ok {
+		fmt.Printf("📊 Highest priority: %s (%d)\n", top.Name, top.Priority)
+	}
+
+	pq.Update(handles["Deploy"], Task{"Deploy", 10})
+	fmt.Println("✅ Raised 'Deploy' priority to 10")
+
+	if removed, ok := pq.Remove(handles["Code review"]); ok {
+		fmt.Printf("✅ Removed '%s' by handle\n", removed.Name)
+	}
+	fmt.Printf("📊 'Code review' still queued: %t\n", pq.Contains(handles["Code review"]))
+
+	fmt.Println("📋 Processing in priority order:")
+	for pq.Len() > 0 {
+		task, _ := pq.Pop()
+		fmt.Printf("  %s (priority: %d)\n", task.Name, task.Priority)
+	}
+
+	fmt.Printf("📊 Update after pop succeeds: %t\n", pq.Update(handles["Fix bug"], Task{"Fix bug", 99}))
+
+	fifo := NewPriorityQueue(func(a, b Task) bool { return a.Priority > b.Priority })
+	for _, name := range []string{"first", "second", "third"} {
+		fifo.Push(Task{name, 1})
+	}
+	fmt.Print("📋 Equal priorities pop in push order:")
+	for fifo.Len() > 0 {
+		task, _ := fifo.Pop()
+		fmt.Printf(" %s", task.Name)
+	}
+	fmt.Println()
+
+	benchmarkPriorityQueue()
+}
+
+// benchmarkPriorityQueue times pushes and pops, and compares updating by
+// handle with finding the element by a linear scan before heap.Fix
+func benchmarkPriorityQueue() {
+	const n, updates = 100_000, 1_000
+	fmt.Printf("📊 Benchmarks (%dk elements):\n", n/1000)
+	rng := rand.New(rand.NewSource(1))
+
+	pq := NewPriorityQueue(func(a, b int) bool { return a < b })
+	handles := make([]*PQHandle[int], n)
+	start := time.Now()
+	for i := range handles {
+		handles[i] = pq.Push(rng.Intn(n))
+	}
+	pushTime := time.Since(start)
+
+	targets := make([]int, updates)
+	for i := range targets {
+		targets[i] = rng.Intn(n)
+	}
+	start = time.Now()
+	for i, t := range targets {
+		pq.Update(handles[t], i)
+	}
+	handleTime := time.Since(start)
+
+	// The same updates on a plain heap, which has to search for each element
+	scan := &pqHeap[int]{less: func(a, b int) bool { return a < b }}
+	for _, h := range handles {
+		heap.Push(scan, &PQHandle[int]{value: h.value, seq: h.seq})
+	}
+	start = time.Now()
+	for i, t := range targets {
+		for j, item := range scan.items {
+			if item.seq == handles[t].seq {
+				scan.items[j].value = i
+				heap.Fix(scan, j)
+				break
+			}
+		}
+	}
+	scanTime := time.Since(start)
+
+	start = time.Now()
+	sorted := true
+	for last := -1; pq.Len() > 0; {
+		v, _ := pq.Pop()
+		sorted = sorted && v >= last
+		last = v
+	}
+	popTime := time.Since(start)
+
+	fmt.Printf("  %d pushes %v, %d pops %v (in order: %t)\n", n, pushTime, n, popTime, sorted)
+	fmt.Printf("  %d updates: by handle %v, linear scan + heap.Fix %v\n", updates, handleTime, scanTime)
+}
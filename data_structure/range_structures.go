@@ -0,0 +1,429 @@
+package data_structure
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DisjointSet is a union-find structure over the elements 0..n-1 using
+// path compression and union by rank
+type DisjointSet struct {
+	parent []int
+	rank   []int
+	size   []int
+	count  int
+}
+
+// NewDisjointSet creates n singleton sets
+func NewDisjointSet(n int) *DisjointSet {
+	ds := &DisjointSet{parent: make([]int, n), rank: make([]int, n), size: make([]int, n), count: n}
+	for i := range ds.parent {
+		ds.parent[i] = i
+		ds.size[i] = 1
+	}
+	return ds
+}
+
+// Find returns the representative of x's set
+func (ds *DisjointSet) Find(x int) int {
+	root := x
+	for ds.parent[root] != root {
+		root = ds.parent[root]
+	}
+	for ds.parent[x] != root {
+		ds.parent[x], x = root, ds.parent[x]
+	}
+	return root
+}
+
+// Union merges the sets of x and y and reports whether they were separate
+func (ds *DisjointSet) Union(x, y int) bool {
+	rx, ry := ds.Find(x), ds.Find(y)
+	if rx == ry {
+		return false
+	}
+	if ds.rank[rx] < ds.rank[ry] {
+		rx, ry = ry, rx
+	}
+	ds.parent[ry] = rx
+	ds.size[rx] += ds.size[ry]
+	if ds.rank[rx] == ds.rank[ry] {
+		ds.rank[rx]++
+	}
+	ds.count--
+	return true
+}
+
+// Connected reports whether x and y are in the same set
+func (ds *DisjointSet) Connected(x, y int) bool {
+	return ds.Find(x) == ds.Find(y)
+}
+
+// SetSize returns the number of elements in x's set
+func (ds *DisjointSet) SetSize(x int) int {
+	return ds.size[ds.Find(x)]
+}
+
+// Count returns the number of disjoint sets
+func (ds *DisjointSet) Count() int {
+	return ds.count
+}
+
+// Interval is a closed range [Start, End] with an attached value
+type Interval[V any] struct {
+	Start, End int
+	Value      V
+}
+
+// IntervalTree stores intervals in a treap keyed by start, where every node
+// also tracks the largest end in its subtree so non-overlapping subtrees can
+// be skipped
+type IntervalTree[V any] struct {
+	root   *intervalNode[V]
+	length int
+	rng    *rand.Rand
+}
+
+type intervalNode[V any] struct {
+	interval    Interval[V]
+	maxEnd      int
+	priority    int64
+	left, right *intervalNode[V]
+}
+
+// NewIntervalTree creates an empty interval tree
+func NewIntervalTree[V any]() *IntervalTree[V] {
+	return &IntervalTree[V]{rng: rand.New(rand.NewSource(rand.Int63()))}
+}
+
+func (n *intervalNode[V]) update() {
+	n.maxEnd = n.interval.End
+	if n.left != nil && n.left.maxEnd > n.maxEnd {
+		n.maxEnd = n.left.maxEnd
+	}
+	if n.right != nil && n.right.maxEnd > n.maxEnd {
+		n.maxEnd = n.right.maxEnd
+	}
+}
+
+func rotateRight[V any](n *intervalNode[V]) *intervalNode[V] {
+	l := n.left
+	n.left, l.right = l.right, n
+	n.update()
+	l.update()
+	return l
+}
+
+func rotateLeft[V any](n *intervalNode[V]) *intervalNode[V] {
+	r := n.right
+	n.right, r.left = r.left, n
+	n.update()
+	r.update()
+	return r
+}
+
+// Insert adds the interval [start, end]; start must not exceed end
+func (t *IntervalTree[V]) Insert(start, end int, value V) {
+	if start > end {
+		start, end = end, start
+	}
+	node := &intervalNode[V]{interval: Interval[V]{start, end, value}, maxEnd: end, priority: t.rng.Int63()}
+	t.root = t.insert(t.root, node)
+	t.length++
+}
+
+func (t *IntervalTree[V]) insert(root, node *intervalNode[V]) *intervalNode[V] {
+	if root == nil {
+		return node
+	}
+	if node.interval.Start < root.interval.Start {
+		root.left = t.insert(root.left, node)
+		if root.left.priority > root.priority {
+			return rotateRight(root)
+		}
+	} else {
+		root.right = t.insert(root.right, node)
+		if root.right.priority > root.priority {
+			return rotateLeft(root)
+		}
+	}
+	root.update()
+	return root
+}
+
+// Delete removes one interval with exactly these bounds
+func (t *IntervalTree[V]) Delete(start, end int) bool {
+	var deleted bool
+	t.root = t.delete(t.root, start, end, &deleted)
+	if deleted {
+		t.length--
+	}
+	return deleted
+}
+
+func (t *IntervalTree[V]) delete(root *intervalNode[V], start, end int, deleted *bool) *intervalNode[V] {
+	if root == nil {
+		return nil
+	}
+	switch {
+	case root.interval.Start == start && root.interval.End == end:
+		*deleted = true
+		return mergeTreaps(root.left, root.right)
+	case start < root.interval.Start:
+		root.left = t.delete(root.left, start, end, deleted)
+	default:
+		// equal starts may sit on either side after rotations
+		root.right = t.delete(root.right, start, end, deleted)
+		if !*deleted && start == root.interval.Start {
+			root.left = t.delete(root.left, start, end, deleted)
+		}
+	}
+	root.update()
+	return root
+}
+
+func mergeTreaps[V any](left, right *intervalNode[V]) *intervalNode[V] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	if left.priority > right.priority {
+		left.right = mergeTreaps(left.right, right)
+		left.update()
+		return left
+	}
+	right.left = mergeTreaps(left, right.left)
+	right.update()
+	return right
+}
+
+// Len returns the number of stored intervals
+func (t *IntervalTree[V]) Len() int {
+	return t.length
+}
+
+// Stab returns all intervals containing point, ordered by start
+func (t *IntervalTree[V]) Stab(point int) []Interval[V] {
+	return t.Overlaps(point, point)
+}
+
+// Overlaps returns all intervals that share at least one point with
+// [start, end], ordered by start
+func (t *IntervalTree[V]) Overlaps(start, end int) []Interval[V] {
+	var result []Interval[V]
+	var visit func(n *intervalNode[V])
+	visit = func(n *intervalNode[V]) {
+		if n == nil || n.maxEnd < start {
+			return
+		}
+		visit(n.left)
+		if n.interval.Start > end {
+			return // everything to the right starts even later
+		}
+		if n.interval.End >= start {
+			result = append(result, n.interval)
+		}
+		visit(n.right)
+	}
+	visit(t.root)
+	return result
+}
+
+// SegmentTree answers range queries for an associative combine function and
+// supports point updates, both in O(log n)
+type SegmentTree[T any] struct {
+	n        int
+	tree     []T
+	combine  func(a, b T) T
+	identity T
+}
+
+// NewSegmentTree builds a segment tree over values. identity must satisfy
+// combine(identity, x) == x, e.g. 0 for sums or +Inf for minimums.
+func NewSegmentTree[T any](values []T, combine func(a, b T) T, identity T) *SegmentTree[T] {
+	n := len(values)
+	st := &SegmentTree[T]{n: n, tree: make([]T, 2*n), combine: combine, identity: identity}
+	copy(st.tree[n:], values)
+	for i := n - 1; i > 0; i-- {
+		st.tree[i] = combine(st.tree[2*i], st.tree[2*i+1])
+	}
+	return st
+}
+
+// NewSumSegmentTree builds a segment tree for range sums
+func NewSumSegmentTree(values []int) *SegmentTree[int] {
+	return NewSegmentTree(values, func(a, b int) int { return a + b }, 0)
+}
+
+// NewMinSegmentTree builds a segment tree for range minimums
+func NewMinSegmentTree(values []int) *SegmentTree[int] {
+	return NewSegmentTree(values, func(a, b int) int { return min(a, b) }, math.MaxInt)
+}
+
+// Len returns the number of elements
+func (st *SegmentTree[T]) Len() int {
+	return st.n
+}
+
+// Get returns the element at index i
+func (st *SegmentTree[T]) Get(i int) T {
+	return st.tree[st.n+i]
+}
+
+// Update sets the element at index i
+func (st *SegmentTree[T]) Update(i int, value T) {
+	i += st.n
+	st.tree[i] = value
+	for i > 1 {
+		i /= 2
+		st.tree[i] = st.combine(st.tree[2*i], st.tree[2*i+1])
+	}
+}
+
+// Query combines the elements in the half-open range [from, to)
+func (st *SegmentTree[T]) Query(from, to int) T {
+	if from < 0 {
+		from = 0
+	}
+	if to > st.n {
+		to = st.n
+	}
+	left, right := st.identity, st.identity
+	for l, r := from+st.n, to+st.n; l < r; l, r = l/2, r/2 {
+		if l%2 == 1 {
+			left = st.combine(left, st.tree[l])
+			l++
+		}
+		if r%2 == 1 {
+			r--
+			right = st.combine(st.tree[r], right)
+		}
+	}
+	return st.combine(left, right)
+}
+
+// RangeStructureOperations demonstrates union-find, interval and segment trees
+func RangeStructureOperations() {
+	fmt.Println("\n=== Union-Find, Interval Tree and Segment Tree ===")
+
+	fmt.Println("📋 DisjointSet: grouping servers connected by network links")
+	servers := []string{"web-1", "web-2", "db-1", "db-2", "cache-1", "batch-1"}
+	links := [][2]int{{0, 1}, {2, 3}, {1, 4}, {3, 2}}
+	ds := NewDisjointSet(len(servers))
+	for _, link := range links {
+		if !ds.Union(link[0], link[1]) {
+			fmt.Printf("  redundant link %s <-> %s\n", servers[link[0]], servers[link[1]])
+		}
+	}
+	fmt.Printf("  %d network segments; web-1 reaches cache-1: %t; web-1 reaches db-1: %t\n",
+		ds.Count(), ds.Connected(0, 4), ds.Connected(0, 2))
+	fmt.Printf("  segment of web-2 has %d servers\n", ds.SetSize(1))
+
+	fmt.Println("📋 IntervalTree: meeting room bookings (minutes since 9:00)")
+	bookings := NewIntervalTree[string]()
+	bookings.Insert(0, 60, "standup + planning")
+	bookings.Insert(30, 90, "design review")
+	bookings.Insert(120, 150, "1:1")
+	bookings.Insert(140, 200, "interview")
+	bookings.Insert(300, 360, "retro")
+	for _, minute := range []int{45, 145, 240} {
+		var names []string
+		for _, booking := range bookings.Stab(minute) {
+			names = append(names, booking.Value)
+		}
+		fmt.Printf("  at minute %3d: %v\n", minute, names)
+	}
+	conflicts := bookings.Overlaps(80, 130)
+	fmt.Printf("  a booking for [80, 130] conflicts with %d meetings\n", len(conflicts))
+	bookings.Delete(120, 150)
+	fmt.Printf("  after cancelling the 1:1: %d conflicts\n", len(bookings.Overlaps(80, 130)))
+
+	fmt.Println("📋 SegmentTree: hourly request counts")
+	hourly := []int{120, 80, 60, 40, 90, 300, 450, 500, 420, 380, 200, 150}
+	sums := NewSumSegmentTree(hourly)
+	mins := NewMinSegmentTree(hourly)
+	fmt.Printf("  requests in hours [5, 10): %d, quietest hour in [0, 6): %d\n", sums.Query(5, 10), mins.Query(0, 6))
+	sums.Update(3, 1000)
+	mins.Update(3, 1000)
+	fmt.Printf("  after spike in hour 3: sum [0, 6) = %d, min [0, 6) = %d\n", sums.Query(0, 6), mins.Query(0, 6))
+
+	benchmarkRangeStructures()
+}
+
+// benchmarkRangeStructures compares the trees with naive linear scans
+func benchmarkRangeStructures() {
+	fmt.Println("📊 Benchmarks (100k elements):")
+	const n, ops, stabs = 100_000, 10_000, 1_000
+	rng := rand.New(rand.NewSource(1))
+
+	values := make([]int, n)
+	for i := range values {
+		values[i] = rng.Intn(1000)
+	}
+	queries := make([][2]int, ops)
+	for i := range queries {
+		from := rng.Intn(n)
+		queries[i] = [2]int{from, from + rng.Intn(n-from) + 1}
+	}
+
+	st := NewSumSegmentTree(values)
+	start := time.Now()
+	checksum := 0
+	for i, q := range queries {
+		st.Update(q[0], i)
+		checksum += st.Query(q[0], q[1])
+	}
+	treeTime := time.Since(start)
+
+	naive := append([]int(nil), values...)
+	start = time.Now()
+	naiveChecksum := 0
+	for i, q := range queries {
+		naive[q[0]] = i
+		for _, v := range naive[q[0]:q[1]] {
+			naiveChecksum += v
+		}
+	}
+	naiveTime := time.Since(start)
+	fmt.Printf("  %d range sums + updates: segment tree %v, linear scan %v (results match: %t)\n",
+		ops, treeTime, naiveTime, checksum == naiveChecksum)
+
+	it := NewIntervalTree[int]()
+	intervals := make([][2]int, n)
+	for i := range intervals {
+		s := rng.Intn(10 * n)
+		intervals[i] = [2]int{s, s + rng.Intn(100)}
+		it.Insert(intervals[i][0], intervals[i][1], i)
+	}
+	start = time.Now()
+	found := 0
+	for i := 0; i < stabs; i++ {
+		found += len(it.Stab(queries[i][0] * 10))
+	}
+	treeTime = time.Since(start)
+
+	start = time.Now()
+	naiveFound := 0
+	for i := 0; i < stabs; i++ {
+		p := queries[i][0] * 10
+		for _, iv := range intervals {
+			if iv[0] <= p && p <= iv[1] {
+				naiveFound++
+			}
+		}
+	}
+	naiveTime = time.Since(start)
+	fmt.Printf("  %d stabbing queries: interval tree %v, linear scan %v (results match: %t)\n",
+		stabs, treeTime, naiveTime, found == naiveFound)
+
+	ds := NewDisjointSet(n)
+	start = time.Now()
+	for i := 0; i < n; i++ {
+		ds.Union(rng.Intn(n), rng.Intn(n))
+	}
+	fmt.Printf("  %d unions: union-find %v, %d sets remain\n", n, time.Since(start), ds.Count())
+}
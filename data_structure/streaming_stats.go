@@ -0,0 +1,336 @@
+package data_structure
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunningStats accumulates count, mean and variance in one pass using
+// Welford's algorithm, which stays numerically stable for long streams
+type RunningStats struct {
+	count    int64
+	mean     float64
+	m2       float64
+	min, max float64
+}
+
+// Add records one observation
+func (s *RunningStats) Add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min, s.max = math.Min(s.min, x), math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+}
+
+// Merge folds other into s, e.g. to combine per-goroutine accumulators
+func (s *RunningStats) Merge(other RunningStats) {
+	if other.count == 0 {
+		return
+	}
+	if s.count == 0 {
+		*s = other
+		return
+	}
+	total := s.count + other.count
+	delta := other.mean - s.mean
+	s.m2 += other.m2 + delta*delta*float64(s.count)*float64(other.count)/float64(total)
+	s.mean += delta * float64(other.count) / float64(total)
+	s.count = total
+	s.min, s.max = math.Min(s.min, other.min), math.Max(s.max, other.max)
+}
+
+// Count returns the number of observations
+func (s *RunningStats) Count() int64 { return s.count }
+
+// Mean returns the average observation
+func (s *RunningStats) Mean() float64 { return s.mean }
+
+// Min returns the smallest observation
+func (s *RunningStats) Min() float64 { return s.min }
+
+// Max returns the largest observation
+func (s *RunningStats) Max() float64 { return s.max }
+
+// Variance returns the sample variance
+func (s *RunningStats) Variance() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.count-1)
+}
+
+// StdDev returns the sample standard deviation
+func (s *RunningStats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// P2Quantile estimates a single quantile in constant memory with the P²
+// algorithm (Jain & Chlamtac), tracking five markers instead of every sample
+type P2Quantile struct {
+	p       float64
+	count   int
+	heights [5]float64
+	pos     [5]float64
+	desired [5]float64
+	incr    [5]float64
+}
+
+// NewP2Quantile creates an estimator for quantile p in (0, 1), e.g. 0.99
+func NewP2Quantile(p float64) *P2Quantile {
+	return &P2Quantile{
+		p:       p,
+		pos:     [5]float64{1, 2, 3, 4, 5},
+		desired: [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		incr:    [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// Add records one observation
+func (q *P2Quantile) Add(x float64) {
+	if q.count < 5 {
+		q.heights[q.count] = x
+		q.count++
+		if q.count == 5 {
+			sort.Float64s(q.heights[:])
+		}
+		return
+	}
+	q.count++
+
+	var k int
+	switch {
+	case x < q.heights[0]:
+		q.heights[0] = x
+		k = 0
+	case x >= q.heights[4]:
+		q.heights[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= q.heights[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		q.pos[i]++
+	}
+	for i := range q.desired {
+		q.desired[i] += q.incr[i]
+	}
+
+	for i := 1; i < 4; i++ {
+		d := q.desired[i] - q.pos[i]
+		if (d >= 1 && q.pos[i+1]-q.pos[i] > 1) || (d <= -1 && q.pos[i-1]-q.pos[i] < -1) {
+			sign := math.Copysign(1, d)
+			height := q.parabolic(i, sign)
+			if q.heights[i-1] >= height || height >= q.heights[i+1] {
+				height = q.linear(i, sign)
+			}
+			q.heights[i] = height
+			q.pos[i] += sign
+		}
+	}
+}
+
+func (q *P2Quantile) parabolic(i int, d float64) float64 {
+	n, h := q.pos, q.heights
+	return h[i] + d/(n[i+1]-n[i-1])*((n[i]-n[i-1]+d)*(h[i+1]-h[i])/(n[i+1]-n[i])+
+		(n[i+1]-n[i]-d)*(h[i]-h[i-1])/(n[i]-n[i-1]))
+}
+
+func (q *P2Quantile) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return q.heights[i] + d*(q.heights[j]-q.heights[i])/(q.pos[j]-q.pos[i])
+}
+
+// Value returns the current estimate
+func (q *P2Quantile) Value() float64 {
+	if q.count == 0 {
+		return 0
+	}
+	if q.count < 5 {
+		// Too few samples for markers: use the exact quantile
+		samples := append([]float64(nil), q.heights[:q.count]...)
+		sort.Float64s(samples)
+		return samples[int(math.Round(q.p*float64(q.count-1)))]
+	}
+	return q.heights[2]
+}
+
+// Count returns the number of observations
+func (q *P2Quantile) Count() int {
+	return q.count
+}
+
+// SlidingWindow aggregates timestamped observations over the last window of
+// time; it is safe for concurrent use
+type SlidingWindow struct {
+	mu      sync.Mutex
+	window  time.Duration
+	samples []windowSample // ordered by time
+	sum     float64
+	now     func() time.Time
+}
+
+type windowSample struct {
+	at    time.Time
+	value float64
+}
+
+// NewSlidingWindow creates a window covering the last window of time
+func NewSlidingWindow(window time.Duration) *SlidingWindow {
+	return &SlidingWindow{window: window, now: time.Now}
+}
+
+// SetClock replaces the clock used to timestamp and expire samples
+func (w *SlidingWindow) SetClock(now func() time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.now = now
+}
+
+// Add records value at the current time
+func (w *SlidingWindow) Add(value float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.samples = append(w.samples, windowSample{at: now, value: value})
+	w.sum += value
+	w.expire(now)
+}
+
+func (w *SlidingWindow) expire(now time.Time) {
+	cutoff := now.Add(-w.window)
+	drop := 0
+	for drop < len(w.samples) && !w.samples[drop].at.After(cutoff) {
+		w.sum -= w.samples[drop].value
+		drop++
+	}
+	if drop > 0 {
+		w.samples = append(w.samples[:0], w.samples[drop:]...)
+	}
+	if len(w.samples) == 0 {
+		w.sum = 0 // drop accumulated rounding error
+	}
+}
+
+// Count returns the number of observations in the window
+func (w *SlidingWindow) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(w.now())
+	return len(w.samples)
+}
+
+// Sum returns the total of the observations in the window
+func (w *SlidingWindow) Sum() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(w.now())
+	return w.sum
+}
+
+// Rate returns observations per second over the window
+func (w *SlidingWindow) Rate() float64 {
+	return float64(w.Count()) / w.window.Seconds()
+}
+
+// Mean returns the average observation in the window
+func (w *SlidingWindow) Mean() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(w.now())
+	if len(w.samples) == 0 {
+		return 0
+	}
+	return w.sum / float64(len(w.samples))
+}
+
+// Percentiles returns the exact value for each requested percentile (0-100)
+// among the observations in the window
+func (w *SlidingWindow) Percentiles(ps ...float64) []float64 {
+	w.mu.Lock()
+	w.expire(w.now())
+	values := make([]float64, len(w.samples))
+	for i, sample := range w.samples {
+		values[i] = sample.value
+	}
+	w.mu.Unlock()
+
+	result := make([]float64, len(ps))
+	if len(values) == 0 {
+		return result
+	}
+	sort.Float64s(values)
+	for i, p := range ps {
+		idx := int(math.Ceil(p/100*float64(len(values)))) - 1
+		result[i] = values[max(0, min(idx, len(values)-1))]
+	}
+	return result
+}
+
+// StreamingStatsOperations demonstrates the streaming statistics types
+func StreamingStatsOperations() {
+	fmt.Println("\n=== Streaming Statistics and Sliding Windows ===")
+
+	rng := rand.New(rand.NewSource(42))
+	latency := func() float64 {
+		// Mostly fast responses with a long tail
+		if rng.Float64() < 0.05 {
+			return 200 + rng.ExpFloat64()*300
+		}
+		return math.Max(1, 20+rng.NormFloat64()*5)
+	}
+
+	fmt.Println("📋 RunningStats + P² quantiles over 100k latencies (ms):")
+	var stats, shardA, shardB RunningStats
+	p50, p99 := NewP2Quantile(0.5), NewP2Quantile(0.99)
+	all := make([]float64, 0, 100_000)
+	for i := 0; i < 100_000; i++ {
+		x := latency()
+		stats.Add(x)
+		if i%2 == 0 {
+			shardA.Add(x)
+		} else {
+			shardB.Add(x)
+		}
+		p50.Add(x)
+		p99.Add(x)
+		all = append(all, x)
+	}
+	sort.Float64s(all)
+	fmt.Printf("  count=%d mean=%.2f stddev=%.2f min=%.2f max=%.2f\n",
+		stats.Count(), stats.Mean(), stats.StdDev(), stats.Min(), stats.Max())
+	shardA.Merge(shardB)
+	fmt.Printf("  merged shards: mean=%.2f stddev=%.2f\n", shardA.Mean(), shardA.StdDev())
+	fmt.Printf("  p50 estimate=%.2f exact=%.2f | p99 estimate=%.2f exact=%.2f\n",
+		p50.Value(), all[len(all)/2], p99.Value(), all[len(all)*99/100])
+
+	fmt.Println("📋 One-minute sliding window with a simulated clock:")
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := NewSlidingWindow(time.Minute)
+	window.SetClock(func() time.Time { return now })
+	for second := 0; second < 90; second++ {
+		now = now.Add(time.Second)
+		requests := 5
+		if second >= 60 {
+			requests = 20 // traffic spike in the last 30 seconds
+		}
+		for i := 0; i < requests; i++ {
+			window.Add(latency())
+		}
+	}
+	percentiles := window.Percentiles(50, 95, 99)
+	fmt.Printf("  last minute: %d requests, %.1f req/s, mean %.2fms\n", window.Count(), window.Rate(), window.Mean())
+	fmt.Printf("  p50=%.2fms p95=%.2fms p99=%.2fms\n", percentiles[0], percentiles[1], percentiles[2])
+	now = now.Add(45 * time.Second)
+	fmt.Printf("  45s later: %d requests remain, sum %.0fms\n", window.Count(), window.Sum())
+}
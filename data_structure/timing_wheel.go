@@ -0,0 +1,434 @@
+package data_structure
+
+import (
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TimingWheelOptions sizes a TimingWheel
+type TimingWheelOptions struct {
+	// Tick is the resolution; timers fire on the first tick at or after
+	// their deadline, never early. Defaults to 10ms.
+	Tick time.Duration
+	// Slots per level, rounded up to a power of two. Defaults to 256.
+	Slots int
+	// Levels of wheels; each one spans Slots times the one below. Timers
+	// further out than the top level covers wait in its last slot and are
+	// placed again when it comes round. Defaults to 4.
+	Levels int
+	// Workers run the callbacks, so a slow one holds up others only once
+	// every worker is busy. Defaults to GOMAXPROCS.
+	Workers int
+}
+
+// TimingWheel schedules callbacks the way the Linux kernel's timer wheel
+// does: timers hang in per-tick buckets, so scheduling and cancelling are
+// O(1) whatever the number pending, and far-off timers sit in coarser
+// levels until they cascade down. It suits millions of timeouts that are
+// mostly cancelled before they fire, such as connection idle timeouts,
+// where every time.AfterFunc would cost a runtime timer on a heap.
+type TimingWheel struct {
+	tick  time.Duration
+	bits  uint
+	mask  int64
+	start time.Time
+	jobs  chan func()
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	levels  [][]wheelBucket
+	current int64 // ticks since start that have been processed
+	pending int
+	stopped bool
+
+	fired atomic.Int64
+}
+
+// wheelBucket is an intrusive list of timers, so removal needs no search
+type wheelBucket struct {
+	head *WheelTimer
+}
+
+// WheelTimer is a callback scheduled on a TimingWheel
+type WheelTimer struct {
+	wheel      *TimingWheel
+	fn         func()
+	expires    int64 // tick
+	bucket     *wheelBucket
+	prev, next *WheelTimer
+}
+
+// NewTimingWheel starts a wheel; Stop it when done
+func NewTimingWheel(opts TimingWheelOptions) *TimingWheel {
+	if opts.Tick <= 0 {
+		opts.Tick = 10 * time.Millisecond
+	}
+	if opts.Slots <= 1 {
+		opts.Slots = 256
+	}
+	if opts.Levels <= 0 {
+		opts.Levels = 4
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	slotBits := uint(bits.Len(uint(opts.Slots - 1)))
+	if int(slotBits)*opts.Levels > 62 {
+		opts.Levels = 62 / int(slotBits)
+	}
+
+	w := &TimingWheel{
+		tick:   opts.Tick,
+		bits:   slotBits,
+		mask:   1<<slotBits - 1,
+		start:  time.Now(),
+		jobs:   make(chan func(), 1024*opts.Workers),
+		stop:   make(chan struct{}),
+		levels: make([][]wheelBucket, opts.Levels),
+	}
+	for i := range w.levels {
+		w.levels[i] = make([]wheelBucket, 1<<slotBits)
+	}
+
+	w.wg.Add(opts.Workers)
+	for range opts.Workers {
+		go w.work()
+	}
+	go w.run()
+	return w
+}
+
+// Tick returns the wheel's resolution
+func (w *TimingWheel) Tick() time.Duration { return w.tick }
+
+// Span returns how far ahead the wheel can place a timer directly; longer
+// ones still fire on time after extra trips round the top level
+func (w *TimingWheel) Span() time.Duration {
+	return w.tick * time.Duration(int64(1)<<(w.bits*uint(len(w.levels))))
+}
+
+// Schedule runs fn on a worker once d has passed. On a stopped wheel the
+// timer never fires.
+func (w *TimingWheel) Schedule(d time.Duration, fn func()) *WheelTimer {
+	t := &WheelTimer{wheel: w, fn: fn}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.addLocked(t, d)
+	return t
+}
+
+// Len returns how many timers are pending
+func (w *TimingWheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending
+}
+
+// Fired returns how many callbacks the wheel has started
+func (w *TimingWheel) Fired() int64 { return w.fired.Load() }
+
+// Stop drops every pending timer and waits for running callbacks to return
+func (w *TimingWheel) Stop() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.stopped = true
+	for _, level := range w.levels {
+		for i := range level {
+			for t := level[i].head; t != nil; t = t.next {
+				t.bucket = nil
+			}
+			level[i].head = nil
+		}
+	}
+	w.pending = 0
+	w.mu.Unlock()
+
+	close(w.stop)
+	w.wg.Wait()
+}
+
+// Cancel keeps the timer from firing and reports whether it was pending;
+// false means its callback has started or it was already cancelled
+func (t *WheelTimer) Cancel() bool {
+	w := t.wheel
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.bucket == nil {
+		return false
+	}
+	w.unlinkLocked(t)
+	return true
+}
+
+// Reset makes the timer fire once d has passed from now, whether or not it
+// had fired, and reports whether it was pending
+func (t *WheelTimer) Reset(d time.Duration) bool {
+	w := t.wheel
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := t.bucket != nil
+	if pending {
+		w.unlinkLocked(t)
+	}
+	w.addLocked(t, d)
+	return pending
+}
+
+// addLocked works out t's deadline in ticks from the wall clock rather
+// than from current, which may lag while the wheel catches up, and rounds
+// it up so the timer never fires early
+func (w *TimingWheel) addLocked(t *WheelTimer, d time.Duration) {
+	if w.stopped {
+		return
+	}
+	due := time.Since(w.start) + d
+	t.expires = int64((due + w.tick - 1) / w.tick)
+	if t.expires <= w.current {
+		t.expires = w.current + 1
+	}
+	w.placeLocked(t)
+	w.pending++
+}
+
+// placeLocked hangs t in the bucket of the lowest level whose span reaches
+// its deadline
+func (w *TimingWheel) placeLocked(t *WheelTimer) {
+	delta := t.expires - w.current
+	expires := t.expires
+	level := 0
+	for level < len(w.levels)-1 && delta >= int64(1)<<(w.bits*uint(level+1)) {
+		level++
+	}
+	if top := int64(1)<<(w.bits*uint(len(w.levels))) - 1; delta > top {
+		expires = w.current + top
+	}
+	bucket := &w.levels[level][(expires>>(w.bits*uint(level)))&w.mask]
+
+	t.bucket = bucket
+	t.prev = nil
+	t.next = bucket.head
+	if bucket.head != nil {
+		bucket.head.prev = t
+	}
+	bucket.head = t
+}
+
+func (w *TimingWheel) unlinkLocked(t *WheelTimer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		t.bucket.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.bucket, t.prev, t.next = nil, nil, nil
+	w.pending--
+}
+
+// run advances the wheel one tick at a time up to the wall clock, so a
+// late wake-up catches up rather than skipping buckets
+func (w *TimingWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			close(w.jobs)
+			return
+		case <-ticker.C:
+		}
+		target := int64(time.Since(w.start) / w.tick)
+		for {
+			w.mu.Lock()
+			if w.stopped || w.current >= target {
+				w.mu.Unlock()
+				break
+			}
+			due := w.advanceLocked()
+			w.mu.Unlock()
+			for _, fn := range due {
+				select {
+				case w.jobs <- fn:
+				case <-w.stop:
+				}
+			}
+		}
+	}
+}
+
+// advanceLocked moves to the next tick, cascading higher levels whose
+// slot comes round, and returns the callbacks due on it
+func (w *TimingWheel) advanceLocked() []func() {
+	w.current++
+	for level := 1; level < len(w.levels); level++ {
+		if (w.current>>(w.bits*uint(level-1)))&w.mask != 0 {
+			break
+		}
+		bucket := &w.levels[level][(w.current>>(w.bits*uint(level)))&w.mask]
+		t := bucket.head
+		bucket.head = nil
+		for t != nil {
+			next := t.next
+			w.placeLocked(t)
+			t = next
+		}
+	}
+
+	bucket := &w.levels[0][w.current&w.mask]
+	var due []func()
+	for t := bucket.head; t != nil; {
+		next := t.next
+		t.bucket, t.prev, t.next = nil, nil, nil
+		due = append(due, t.fn)
+		t = next
+	}
+	bucket.head = nil
+	w.pending -= len(due)
+	return due
+}
+
+func (w *TimingWheel) work() {
+	defer w.wg.Done()
+	for fn := range w.jobs {
+		w.fired.Add(1)
+		fn()
+	}
+}
+
+// TimerBenchmarkResult is one timer implementation's run of one workload
+type TimerBenchmarkResult struct {
+	Timers   string
+	Workload string
+	N        int
+	Elapsed  time.Duration
+	// Bytes allocated per timer
+	BytesPerOp uint64
+}
+
+// NsPerOp is the time spent per timer
+func (r TimerBenchmarkResult) NsPerOp() float64 {
+	return float64(r.Elapsed.Nanoseconds()) / float64(r.N)
+}
+
+// timerBackend schedules with one timer implementation; cancel undoes a
+// schedule
+type timerBackend struct {
+	name     string
+	schedule func(d time.Duration, fn func()) (cancel func() bool)
+}
+
+// BenchmarkTimers measures scheduling and cancelling n timeouts, as
+// connections that see traffic before they go idle do, and scheduling n
+// and waiting for them all to fire, on a TimingWheel and on
+// time.AfterFunc
+func BenchmarkTimers(n int) []TimerBenchmarkResult {
+	wheel := NewTimingWheel(TimingWheelOptions{Tick: time.Millisecond})
+	defer wheel.Stop()
+	backends := []timerBackend{
+		{"timing-wheel", func(d time.Duration, fn func()) func() bool { return wheel.Schedule(d, fn).Cancel }},
+		{"time.AfterFunc", func(d time.Duration, fn func()) func() bool { return time.AfterFunc(d, fn).Stop }},
+	}
+
+	var results []TimerBenchmarkResult
+	for _, b := range backends {
+		results = append(results, measureTimers(b.name, "schedule+cancel", n, func() {
+			cancels := make([]func() bool, n)
+			for i := range cancels {
+				// Spread deadlines over a minute like real idle timeouts
+				cancels[i] = b.schedule(30*time.Second+time.Duration(i%60000)*time.Millisecond, func() {})
+			}
+			for _, cancel := range cancels {
+				cancel()
+			}
+		}))
+	}
+	for _, b := range backends {
+		results = append(results, measureTimers(b.name, "schedule+fire", n, func() {
+			var wg sync.WaitGroup
+			wg.Add(n)
+			for i := range n {
+				b.schedule(time.Duration(i%50)*time.Millisecond, wg.Done)
+			}
+			wg.Wait()
+		}))
+	}
+	return results
+}
+
+func measureTimers(name, workload string, n int, run func()) TimerBenchmarkResult {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	run()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return TimerBenchmarkResult{
+		Timers:     name,
+		Workload:   workload,
+		N:          n,
+		Elapsed:    elapsed,
+		BytesPerOp: (after.TotalAlloc - before.TotalAlloc) / uint64(n),
+	}
+}
+
+// CompareTimers prints BenchmarkTimers side by side
+func CompareTimers(n int) []TimerBenchmarkResult {
+	fmt.Printf("📊 %d timers (%d CPUs):\n", n, runtime.NumCPU())
+	fmt.Printf("  %-16s %-15s %10s %10s %8s\n", "Workload", "Timers", "Elapsed", "ns/timer", "B/timer")
+	results := BenchmarkTimers(n)
+	for _, r := range results {
+		fmt.Printf("  %-16s %-15s %10v %10.0f %8d\n",
+			r.Workload, r.Timers, r.Elapsed.Round(time.Millisecond), r.NsPerOp(), r.BytesPerOp)
+	}
+	return results
+}
+
+// TimingWheelOperations demonstrates scheduling, resetting and cancelling
+// on a timing wheel, then benchmarks it against time.AfterFunc
+func TimingWheelOperations() {
+	fmt.Println("\n=== Timing Wheel ===")
+
+	wheel := NewTimingWheel(TimingWheelOptions{Tick: 5 * time.Millisecond, Slots: 64, Workers: 2})
+	defer wheel.Stop()
+	fmt.Printf("⏱️  tick=%s, direct reach=%s\n", wheel.Tick(), wheel.Span().Round(time.Second))
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	record := func(name string) func() {
+		wg.Add(1)
+		return func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			wg.Done()
+		}
+	}
+
+	start := time.Now()
+	wheel.Schedule(60*time.Millisecond, record("60ms"))
+	wheel.Schedule(20*time.Millisecond, record("20ms"))
+	// Far enough out to start in the second level and cascade down
+	wheel.Schedule(400*time.Millisecond, record("400ms"))
+	idle := wheel.Schedule(30*time.Millisecond, record("idle (reset to 100ms)"))
+	cancelled := wheel.Schedule(40*time.Millisecond, func() { fmt.Println("  ❌ cancelled timer fired") })
+	fmt.Printf("  pending: %d\n", wheel.Len())
+
+	idle.Reset(100 * time.Millisecond)
+	fmt.Printf("  cancel 40ms timer: %t\n", cancelled.Cancel())
+	wg.Wait()
+	fmt.Printf("  fired in order %v after %s\n", order, time.Since(start).Round(10*time.Millisecond))
+	fmt.Printf("  cancel after firing: %t, pending: %d\n", idle.Cancel(), wheel.Len())
+
+	CompareTimers(200000)
+}
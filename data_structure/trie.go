@@ -0,0 +1,383 @@
+package data_structure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Trie is a rune-per-node prefix tree mapping string keys to values
+type Trie[V any] struct {
+	root   *trieNode[V]
+	length int
+}
+
+type trieNode[V any] struct {
+	children map[rune]*trieNode[V]
+	value    V
+	hasValue bool
+}
+
+// NewTrie creates an empty trie
+func NewTrie[V any]() *Trie[V] {
+	return &Trie[V]{root: &trieNode[V]{}}
+}
+
+// Insert stores value under key
+func (t *Trie[V]) Insert(key string, value V) {
+	node := t.root
+	for _, r := range key {
+		if node.children == nil {
+			node.children = make(map[rune]*trieNode[V])
+		}
+		child, ok := node.children[r]
+		if !ok {
+			child = &trieNode[V]{}
+			node.children[r] = child
+		}
+		node = child
+	}
+	if !node.hasValue {
+		t.length++
+	}
+	node.value, node.hasValue = value, true
+}
+
+// Get returns the value stored under key
+func (t *Trie[V]) Get(key string) (V, bool) {
+	node := t.root
+	for _, r := range key {
+		if node = node.children[r]; node == nil {
+			var zero V
+			return zero, false
+		}
+	}
+	return node.value, node.hasValue
+}
+
+// Delete removes key, pruning nodes that no longer lead to a value
+func (t *Trie[V]) Delete(key string) bool {
+	runes := []rune(key)
+	path := make([]*trieNode[V], 0, len(runes)+1)
+	node := t.root
+	path = append(path, node)
+	for _, r := range runes {
+		if node = node.children[r]; node == nil {
+			return false
+		}
+		path = append(path, node)
+	}
+	if !node.hasValue {
+		return false
+	}
+
+	var zero V
+	node.value, node.hasValue = zero, false
+	t.length--
+	for i := len(runes) - 1; i >= 0; i-- {
+		child := path[i+1]
+		if child.hasValue || len(child.children) > 0 {
+			break
+		}
+		delete(path[i].children, runes[i])
+	}
+	return true
+}
+
+// Len returns the number of keys
+func (t *Trie[V]) Len() int {
+	return t.length
+}
+
+// LongestPrefixMatch returns the longest stored key that is a prefix of s
+func (t *Trie[V]) LongestPrefixMatch(s string) (string, V, bool) {
+	var (
+		bestLen   = -1
+		bestValue V
+	)
+	node := t.root
+	if node.hasValue {
+		bestLen, bestValue = 0, node.value
+	}
+	for i, r := range s {
+		if node = node.children[r]; node == nil {
+			break
+		}
+		if node.hasValue {
+			bestLen, bestValue = i+len(string(r)), node.value
+		}
+	}
+	if bestLen < 0 {
+		return "", bestValue, false
+	}
+	return s[:bestLen], bestValue, true
+}
+
+// WalkPrefix calls fn for every key starting with prefix, in lexical order,
+// until fn returns false
+func (t *Trie[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	node := t.root
+	for _, r := range prefix {
+		if node = node.children[r]; node == nil {
+			return
+		}
+	}
+	node.walk([]rune(prefix), fn)
+}
+
+func (n *trieNode[V]) walk(key []rune, fn func(string, V) bool) bool {
+	if n.hasValue && !fn(string(key), n.value) {
+		return false
+	}
+	for _, r := range SortedKeys(n.children) {
+		if !n.children[r].walk(append(key, r), fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// RadixTree is a compressed trie: chains of single-child nodes are merged into
+// one edge, so lookups compare whole substrings instead of one rune at a time
+type RadixTree[V any] struct {
+	root   *radixNode[V]
+	length int
+}
+
+type radixNode[V any] struct {
+	prefix   string
+	children []*radixNode[V] // sorted by first byte of prefix
+	value    V
+	hasValue bool
+}
+
+// NewRadixTree creates an empty radix tree
+func NewRadixTree[V any]() *RadixTree[V] {
+	return &RadixTree[V]{root: &radixNode[V]{}}
+}
+
+func (n *radixNode[V]) child(b byte) (int, *radixNode[V]) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].prefix[0] >= b })
+	if i < len(n.children) && n.children[i].prefix[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func (n *radixNode[V]) addChild(child *radixNode[V]) {
+	i, _ := n.child(child.prefix[0])
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// Insert stores value under key
+func (t *RadixTree[V]) Insert(key string, value V) {
+	node := t.root
+	for {
+		if key == "" {
+			if !node.hasValue {
+				t.length++
+			}
+			node.value, node.hasValue = value, true
+			return
+		}
+
+		_, child := node.child(key[0])
+		if child == nil {
+			node.addChild(&radixNode[V]{prefix: key, value: value, hasValue: true})
+			t.length++
+			return
+		}
+
+		common := commonPrefixLen(key, child.prefix)
+		if common < len(child.prefix) {
+			// Split the edge: child keeps the tail under a new intermediate node
+			split := &radixNode[V]{prefix: child.prefix[:common]}
+			i, _ := node.child(key[0])
+			node.children[i] = split
+			child.prefix = child.prefix[common:]
+			split.addChild(child)
+			child = split
+		}
+		node, key = child, key[common:]
+	}
+}
+
+// Get returns the value stored under key
+func (t *RadixTree[V]) Get(key string) (V, bool) {
+	node := t.root
+	for key != "" {
+		_, child := node.child(key[0])
+		if child == nil || !strings.HasPrefix(key, child.prefix) {
+			var zero V
+			return zero, false
+		}
+		node, key = child, key[len(child.prefix):]
+	}
+	return node.value, node.hasValue
+}
+
+// Delete removes key, merging nodes left with a single child
+func (t *RadixTree[V]) Delete(key string) bool {
+	var parent *radixNode[V]
+	node := t.root
+	for key != "" {
+		_, child := node.child(key[0])
+		if child == nil || !strings.HasPrefix(key, child.prefix) {
+			return false
+		}
+		parent, node, key = node, child, key[len(child.prefix):]
+	}
+	if !node.hasValue {
+		return false
+	}
+
+	var zero V
+	node.value, node.hasValue = zero, false
+	t.length--
+
+	if node == t.root {
+		return true
+	}
+	switch len(node.children) {
+	case 0:
+		i, _ := parent.child(node.prefix[0])
+		parent.children = append(parent.children[:i], parent.children[i+1:]...)
+		if parent != t.root && !parent.hasValue && len(parent.children) == 1 {
+			parent.mergeChild()
+		}
+	case 1:
+		node.mergeChild()
+	}
+	return true
+}
+
+// mergeChild folds the node's only child into it
+func (n *radixNode[V]) mergeChild() {
+	child := n.children[0]
+	n.prefix += child.prefix
+	n.children = child.children
+	n.value, n.hasValue = child.value, child.hasValue
+}
+
+// Len returns the number of keys
+func (t *RadixTree[V]) Len() int {
+	return t.length
+}
+
+// LongestPrefixMatch returns the longest stored key that is a prefix of s
+func (t *RadixTree[V]) LongestPrefixMatch(s string) (string, V, bool) {
+	var (
+		bestLen   = -1
+		bestValue V
+		consumed  int
+	)
+	node := t.root
+	for {
+		if node.hasValue {
+			bestLen, bestValue = consumed, node.value
+		}
+		rest := s[consumed:]
+		if rest == "" {
+			break
+		}
+		_, child := node.child(rest[0])
+		if child == nil || !strings.HasPrefix(rest, child.prefix) {
+			break
+		}
+		node, consumed = child, consumed+len(child.prefix)
+	}
+	if bestLen < 0 {
+		return "", bestValue, false
+	}
+	return s[:bestLen], bestValue, true
+}
+
+// WalkPrefix calls fn for every key starting with prefix, in lexical order,
+// until fn returns false
+func (t *RadixTree[V]) WalkPrefix(prefix string, fn func(key string, value V) bool) {
+	node, path := t.root, ""
+	for rest := prefix; rest != ""; {
+		_, child := node.child(rest[0])
+		if child == nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(rest, child.prefix):
+			rest = rest[len(child.prefix):]
+		case strings.HasPrefix(child.prefix, rest):
+			// prefix ends inside this edge: everything below matches
+			rest = ""
+		default:
+			return
+		}
+		node, path = child, path+child.prefix
+	}
+	node.walk(path, fn)
+}
+
+func (n *radixNode[V]) walk(key string, fn func(string, V) bool) bool {
+	if n.hasValue && !fn(key, n.value) {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.walk(key+child.prefix, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// TrieOperations demonstrates the trie and radix tree
+func TrieOperations() {
+	fmt.Println("\n=== Trie and Radix Tree ===")
+
+	words := []string{"go", "gopher", "golang", "google", "good", "grpc", "gRPC", "tree", "trie"}
+
+	fmt.Println("📋 Trie autocomplete:")
+	trie := NewTrie[int]()
+	for i, word := range words {
+		trie.Insert(word, i)
+	}
+	var completions []string
+	trie.WalkPrefix("go", func(key string, _ int) bool {
+		completions = append(completions, key)
+		return true
+	})
+	fmt.Printf("  words starting with \"go\": %v\n", completions)
+	trie.Delete("google")
+	_, hasGoogle := trie.Get("google")
+	fmt.Printf("  after delete: google present=%t, len=%d\n", hasGoogle, trie.Len())
+
+	fmt.Println("📋 Radix tree longest-prefix routing:")
+	routes := NewRadixTree[string]()
+	routes.Insert("/", "home")
+	routes.Insert("/api/", "api root")
+	routes.Insert("/api/users/", "user api")
+	routes.Insert("/api/users/admin/", "admin api")
+	routes.Insert("/static/", "files")
+	for _, path := range []string{"/api/users/42", "/api/users/admin/settings", "/api/orders", "/static/app.js", "/about"} {
+		prefix, handler, _ := routes.LongestPrefixMatch(path)
+		fmt.Printf("  %-26s -> %-10s (matched %s)\n", path, handler, prefix)
+	}
+
+	var apiRoutes []string
+	routes.WalkPrefix("/api/u", func(key, _ string) bool {
+		apiRoutes = append(apiRoutes, key)
+		return true
+	})
+	fmt.Printf("  routes under /api/u: %v\n", apiRoutes)
+
+	routes.Delete("/api/users/")
+	prefix, handler, _ := routes.LongestPrefixMatch("/api/users/42")
+	fmt.Printf("  after deleting /api/users/: /api/users/42 -> %s (matched %s)\n", handler, prefix)
+}
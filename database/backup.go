@@ -0,0 +1,399 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/jerrychou/go-practice/cron"
+	"github.com/jerrychou/go-practice/filestore"
+)
+
+// ErrBackupCorrupt is returned when a backup does not match its manifest
+// or the database inside it fails its integrity check
+var ErrBackupCorrupt = errors.New("database: backup is corrupt")
+
+// Backup describes a stored backup. It is saved as a JSON manifest next to
+// the dump, under the dump's key plus ".json".
+type Backup struct {
+	Key       string    `json:"key"`
+	Driver    string    `json:"driver"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupManager copies a database into a filestore.Store and back. SQLite
+// databases are copied page by page with SQLite's online backup API, so
+// writers are not blocked for long; PostgreSQL databases are dumped with
+// pg_dump in its custom format and restored with pg_restore, streaming to
+// and from the store without temporary files.
+type BackupManager struct {
+	db     *sql.DB
+	driver string
+	dsn    string
+	store  filestore.Store
+
+	// Prefix is prepended to backup keys. Defaults to "backups/".
+	Prefix string
+	// Keep is how many backups Prune leaves; 0 keeps them all
+	Keep int
+	// PgDump and PgRestore are the PostgreSQL client programs
+	PgDump    string
+	PgRestore string
+}
+
+// NewSQLiteBackupManager backs up the SQLite database db into store
+func NewSQLiteBackupManager(db *sql.DB, store filestore.Store) *BackupManager {
+	return &BackupManager{db: db, driver: "sqlite3", store: store, Prefix: "backups/"}
+}
+
+// NewPostgresBackupManager backs up the PostgreSQL database at dsn into
+// store. pg_dump and pg_restore must be on the PATH.
+func NewPostgresBackupManager(dsn string, store filestore.Store) *BackupManager {
+	return &BackupManager{driver: "postgres", dsn: dsn, store: store, Prefix: "backups/", PgDump: "pg_dump", PgRestore: "pg_restore"}
+}
+
+// Backup takes a backup, stores it with its manifest and returns the
+// manifest
+func (m *BackupManager) Backup(ctx context.Context) (Backup, error) {
+	backup := Backup{Driver: m.driver, CreatedAt: time.Now().UTC()}
+	stamp := backup.CreatedAt.Format("20060102T150405.000Z")
+
+	var err error
+	switch m.driver {
+	case "sqlite3":
+		backup.Key = m.Prefix + "sqlite-" + stamp + ".db"
+		err = m.backupSQLite(ctx, &backup)
+	case "postgres":
+		backup.Key = m.Prefix + "postgres-" + stamp + ".dump"
+		err = m.backupPostgres(ctx, &backup)
+	default:
+		err = fmt.Errorf("unsupported driver %q", m.driver)
+	}
+	if err != nil {
+		m.store.Delete(context.WithoutCancel(ctx), backup.Key)
+		return Backup{}, fmt.Errorf("backup failed: %w", err)
+	}
+
+	manifest, _ := json.MarshalIndent(backup, "", "  ")
+	if _, err := m.store.Put(ctx, backup.Key+".json", bytes.NewReader(manifest), filestore.PutOptions{ContentType: "application/json"}); err != nil {
+		m.store.Delete(context.WithoutCancel(ctx), backup.Key)
+		return Backup{}, fmt.Errorf("failed to store backup manifest: %w", err)
+	}
+	logger.Info("Backup stored", "key", backup.Key, "size", backup.Size, "sha256", backup.SHA256)
+	return backup, nil
+}
+
+// backupSQLite snapshots the database to a temporary file, checks the copy
+// and uploads it
+func (m *BackupManager) backupSQLite(ctx context.Context, backup *Backup) error {
+	dir, err := os.MkdirTemp("", "sqlite-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.db")
+
+	snapshot, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	if err := copySQLite(ctx, snapshot, m.db); err != nil {
+		return err
+	}
+	if err := sqliteIntegrityCheck(ctx, snapshot); err != nil {
+		return err
+	}
+	snapshot.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return m.put(ctx, backup, file, "application/vnd.sqlite3")
+}
+
+// backupPostgres streams pg_dump's output into the store
+func (m *BackupManager) backupPostgres(ctx context.Context, backup *Backup) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.PgDump, "--format=custom", "--no-owner", "--dbname="+m.dsn)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start pg_dump: %w", err)
+	}
+	putErr := m.put(ctx, backup, stdout, "application/octet-stream")
+	if putErr != nil {
+		io.Copy(io.Discard, stdout) // let pg_dump exit
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("pg_dump: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return putErr
+}
+
+// put stores r under backup.Key, recording its size and checksum
+func (m *BackupManager) put(ctx context.Context, backup *Backup, r io.Reader, contentType string) error {
+	hash := sha256.New()
+	info, err := m.store.Put(ctx, backup.Key, io.TeeReader(r, hash), filestore.PutOptions{ContentType: contentType})
+	if err != nil {
+		return err
+	}
+	backup.Size = info.Size
+	backup.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// List returns the stored backups, newest first
+func (m *BackupManager) List(ctx context.Context) ([]Backup, error) {
+	objects, err := m.store.List(ctx, m.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var backups []Backup
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, ".json") {
+			continue
+		}
+		backup, err := m.manifest(ctx, strings.TrimSuffix(object.Key, ".json"))
+		if err != nil {
+			logger.Warn("Skipping unreadable backup manifest", "key", object.Key, "error", err)
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+func (m *BackupManager) manifest(ctx context.Context, key string) (Backup, error) {
+	r, _, err := m.store.Get(ctx, key+".json")
+	if err != nil {
+		return Backup{}, err
+	}
+	defer r.Close()
+	var backup Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return Backup{}, fmt.Errorf("%w: unreadable manifest: %v", ErrBackupCorrupt, err)
+	}
+	return backup, nil
+}
+
+// Verify checks that the backup at key still matches the size and checksum
+// in its manifest, and that the database in it is readable: SQLite's
+// integrity check passes, or pg_restore can list the dump's contents
+func (m *BackupManager) Verify(ctx context.Context, key string) error {
+	return m.withVerified(ctx, key, func(backup Backup, path string) error {
+		return nil
+	})
+}
+
+// Restore replaces the managed database's contents with the backup at key,
+// after verifying it. A SQLite database is overwritten through the backup
+// API, so open connections see the restored data; for PostgreSQL,
+// pg_restore drops and recreates the objects in the dump.
+func (m *BackupManager) Restore(ctx context.Context, key string) error {
+	err := m.withVerified(ctx, key, func(backup Backup, path string) error {
+		switch backup.Driver {
+		case "sqlite3":
+			src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			return copySQLite(ctx, m.db, src)
+		case "postgres":
+			return m.pgRestore(ctx, path, "--clean", "--if-exists", "--no-owner", "--single-transaction", "--dbname="+m.dsn)
+		}
+		return fmt.Errorf("unsupported driver %q", backup.Driver)
+	})
+	if err != nil {
+		return fmt.Errorf("restore of %s failed: %w", key, err)
+	}
+	logger.Info("Backup restored", "key", key)
+	return nil
+}
+
+// withVerified downloads the backup at key to a temporary file, checks it
+// and calls fn with the file
+func (m *BackupManager) withVerified(ctx context.Context, key string, fn func(backup Backup, path string) error) error {
+	backup, err := m.manifest(ctx, key)
+	if err != nil {
+		return err
+	}
+	if backup.Driver != m.driver {
+		return fmt.Errorf("backup %s is for %s, not %s", key, backup.Driver, m.driver)
+	}
+
+	r, _, err := m.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	file, err := os.CreateTemp("", "backup-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); size != backup.Size || sum != backup.SHA256 {
+		return fmt.Errorf("%w: %s has %d bytes with sha256 %s, manifest says %d bytes with %s",
+			ErrBackupCorrupt, key, size, sum, backup.Size, backup.SHA256)
+	}
+
+	switch backup.Driver {
+	case "sqlite3":
+		db, err := sql.Open("sqlite3", "file:"+file.Name()+"?mode=ro")
+		if err != nil {
+			return err
+		}
+		err = sqliteIntegrityCheck(ctx, db)
+		db.Close()
+		if err != nil {
+			return err
+		}
+	case "postgres":
+		if err := m.pgRestore(ctx, file.Name(), "--list"); err != nil {
+			return fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+		}
+	}
+	return fn(backup, file.Name())
+}
+
+func (m *BackupManager) pgRestore(ctx context.Context, path string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.PgRestore, append(args, path)...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Prune deletes all but the newest Keep backups and returns how many it
+// deleted
+func (m *BackupManager) Prune(ctx context.Context) (int, error) {
+	if m.Keep <= 0 {
+		return 0, nil
+	}
+	backups, err := m.List(ctx)
+	if err != nil || len(backups) <= m.Keep {
+		return 0, err
+	}
+	deleted := 0
+	for _, backup := range backups[m.Keep:] {
+		if err := m.store.Delete(ctx, backup.Key); err != nil {
+			return deleted, fmt.Errorf("failed to delete backup %s: %w", backup.Key, err)
+		}
+		if err := m.store.Delete(ctx, backup.Key+".json"); err != nil {
+			return deleted, fmt.Errorf("failed to delete backup manifest %s: %w", backup.Key, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Schedule adds a job named name to scheduler that takes a backup on spec,
+// verifies it and prunes old ones. A backup missed while the process was
+// down is taken once at startup.
+func (m *BackupManager) Schedule(scheduler *cron.Scheduler, name, spec string) error {
+	return scheduler.Add(name, spec, func(ctx context.Context) error {
+		backup, err := m.Backup(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.Verify(ctx, backup.Key); err != nil {
+			return err
+		}
+		if n, err := m.Prune(ctx); err != nil {
+			return err
+		} else if n > 0 {
+			logger.Info("Pruned old backups", "count", n, "kept", m.Keep)
+		}
+		return nil
+	}, cron.WithMissedRuns(cron.MissedRunOnce))
+}
+
+// copySQLite copies every page of src's main database over dst's with the
+// SQLite backup API, a slice at a time so other connections can interleave
+func copySQLite(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dstDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			dstSQLite, ok1 := dstDriver.(*sqlite3.SQLiteConn)
+			srcSQLite, ok2 := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok1 || !ok2 {
+				return errors.New("not a SQLite connection")
+			}
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(256)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+				if err := ctx.Err(); err != nil {
+					backup.Finish()
+					return err
+				}
+			}
+		})
+	})
+}
+
+func sqliteIntegrityCheck(ctx context.Context, db *sql.DB) error {
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check: %s", ErrBackupCorrupt, result)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+)
+
+// CachedUserStore reads users through a cache (the cache-aside pattern):
+// lookups try the cache first and fill it from the database on a miss,
+// while writes go to the database and then invalidate the cached copy
+type CachedUserStore struct {
+	store *SQLBasics
+	users *cache.Typed[User]
+	ttl   time.Duration
+}
+
+// NewCachedUserStore caches users from db in c for ttl
+func NewCachedUserStore(db *sql.DB, c cache.Cache, ttl time.Duration) *CachedUserStore {
+	return &CachedUserStore{store: NewSQLBasics(db), users: cache.NewTyped[User](c, nil), ttl: ttl}
+}
+
+func userCacheKey(id int) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// GetUser returns a user from the cache, loading it from the database on a miss
+func (s *CachedUserStore) GetUser(ctx context.Context, id int) (*User, error) {
+	user, err := s.users.GetOrLoad(ctx, userCacheKey(id), s.ttl, func(ctx context.Context) (User, error) {
+		logger.Debugf("Cache miss for user %d, querying database", id)
+		user, err := s.store.GetUserByID(id)
+		if err != nil {
+			return User{}, err
+		}
+		return *user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser writes to the database and invalidates the cached user.
+// Invalidating rather than writing the new value avoids caching a stale
+// row if two updates race.
+func (s *CachedUserStore) UpdateUser(ctx context.Context, id int, name, email string, age int) (*User, error) {
+	user, err := s.store.UpdateUser(id, name, email, age)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, id)
+	return user, nil
+}
+
+// DeleteUser removes the user from the database and the cache
+func (s *CachedUserStore) DeleteUser(ctx context.Context, id int) error {
+	if err := s.store.DeleteUser(id); err != nil {
+		return err
+	}
+	s.invalidate(ctx, id)
+	return nil
+}
+
+func (s *CachedUserStore) invalidate(ctx context.Context, id int) {
+	if err := s.users.Delete(ctx, userCacheKey(id)); err != nil {
+		// The entry will still expire after the TTL
+		logger.Warn("Failed to invalidate cached user", "id", id, "error", err)
+	}
+}
+
+// ApplyChange invalidates the user a ChangeNotifier event on the users
+// table refers to, so writes made elsewhere, by another instance or by
+// hand in psql, stop being served from the cache
+func (s *CachedUserStore) ApplyChange(ctx context.Context, event ChangeEvent) {
+	switch {
+	case event.Op == ChangeResync:
+		logger.Warn("Missed change notifications, cached users may be stale until they expire", "ttl", s.ttl)
+	case event.Table == "users":
+		id, err := event.IntKey()
+		if err != nil {
+			logger.Warn("Change event without a user ID", "key", string(event.Key), "error", err)
+			return
+		}
+		s.invalidate(ctx, id)
+	}
+}
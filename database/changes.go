@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangeOp is the kind of row change in a ChangeEvent
+type ChangeOp string
+
+const (
+	ChangeInsert ChangeOp = "INSERT"
+	ChangeUpdate ChangeOp = "UPDATE"
+	ChangeDelete ChangeOp = "DELETE"
+	// ChangeResync follows a reconnect: notifications sent while the
+	// listener was away are lost, so anything derived from them, such as
+	// cached rows, should be reloaded
+	ChangeResync ChangeOp = "RESYNC"
+)
+
+// ErrRowOmitted is returned by ChangeEvent.Decode when the row was too
+// large for a NOTIFY payload and only its key was sent
+var ErrRowOmitted = errors.New("database: changed row omitted from notification")
+
+// ChangeEvent is one row change reported by a ChangeNotifier trigger
+type ChangeEvent struct {
+	Table string          `json:"table"`
+	Op    ChangeOp        `json:"op"`
+	Key   json.RawMessage `json:"key"`
+	// Row is the new row, or the old one for a delete. NOTIFY payloads are
+	// limited to 8000 bytes, so a larger row is left out.
+	Row json.RawMessage `json:"row,omitempty"`
+}
+
+// Decode unmarshals the changed row into v, whose JSON field names must
+// match the table's column names
+func (e ChangeEvent) Decode(v any) error {
+	if len(e.Row) == 0 {
+		return ErrRowOmitted
+	}
+	return json.Unmarshal(e.Row, v)
+}
+
+// IntKey returns the key of a table with an integer primary key
+func (e ChangeEvent) IntKey() (int, error) {
+	var id int
+	err := json.Unmarshal(e.Key, &id)
+	return id, err
+}
+
+// notifyFunction sends each changed row as JSON on the channel named by
+// the trigger's first argument, keyed by the column named by the second
+const notifyFunction = `
+CREATE OR REPLACE FUNCTION go_practice_notify_change() RETURNS trigger AS $$
+DECLARE
+	row_data json;
+	payload text;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		row_data := row_to_json(OLD);
+	ELSE
+		row_data := row_to_json(NEW);
+	END IF;
+	payload := json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'key', row_data -> TG_ARGV[1], 'row', row_data)::text;
+	IF octet_length(payload) > 7900 THEN
+		payload := json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'key', row_data -> TG_ARGV[1])::text;
+	END IF;
+	PERFORM pg_notify(TG_ARGV[0], payload);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`
+
+// ChangeNotifier installs PostgreSQL triggers that NOTIFY a channel with
+// every insert, update and delete on the watched tables. Notifications are
+// sent when the writing transaction commits, and never for one that rolls
+// back.
+type ChangeNotifier struct {
+	db      *sql.DB
+	channel string
+}
+
+// NewChangeNotifier notifies channel of changes to tables in db
+func NewChangeNotifier(db *sql.DB, channel string) *ChangeNotifier {
+	return &ChangeNotifier{db: db, channel: channel}
+}
+
+// Watch creates or replaces the trigger on table, identifying rows by
+// keyColumn (usually the primary key)
+func (n *ChangeNotifier) Watch(ctx context.Context, table, keyColumn string) error {
+	if _, err := n.db.ExecContext(ctx, notifyFunction); err != nil {
+		return fmt.Errorf("failed to create notify function: %w", err)
+	}
+	trigger := pq.QuoteIdentifier(table + "_notify_change")
+	statements := []string{
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, trigger, pq.QuoteIdentifier(table)),
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s
+			FOR EACH ROW EXECUTE PROCEDURE go_practice_notify_change(%s, %s)`,
+			trigger, pq.QuoteIdentifier(table), pq.QuoteLiteral(n.channel), pq.QuoteLiteral(keyColumn)),
+	}
+	for _, stmt := range statements {
+		if _, err := n.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", table, err)
+		}
+	}
+	logger.Info("Watching table for changes", "table", table, "channel", n.channel)
+	return nil
+}
+
+// Unwatch drops the trigger on table
+func (n *ChangeNotifier) Unwatch(ctx context.Context, table string) error {
+	stmt := fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`,
+		pq.QuoteIdentifier(table+"_notify_change"), pq.QuoteIdentifier(table))
+	if _, err := n.db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to unwatch %s: %w", table, err)
+	}
+	return nil
+}
+
+// ChangeListener receives the notifications of a ChangeNotifier on a
+// dedicated connection. A dropped connection is re-established with
+// exponential backoff between MinBackoff and MaxBackoff, after which a
+// ChangeResync event is delivered.
+type ChangeListener struct {
+	dsn     string
+	channel string
+
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// PingInterval checks a quiet connection, so a silently dropped one is
+	// noticed and replaced
+	PingInterval time.Duration
+}
+
+// NewChangeListener listens on channel of the PostgreSQL database at dsn
+func NewChangeListener(dsn, channel string) *ChangeListener {
+	return &ChangeListener{
+		dsn:          dsn,
+		channel:      channel,
+		MinBackoff:   time.Second,
+		MaxBackoff:   time.Minute,
+		PingInterval: 90 * time.Second,
+	}
+}
+
+// Listen delivers change events until ctx is cancelled, then closes the
+// returned channel. It connects in the background and keeps retrying, so
+// an unreachable database shows up in the logs rather than as an error.
+func (l *ChangeListener) Listen(ctx context.Context) <-chan ChangeEvent {
+	events := make(chan ChangeEvent, 64)
+	listener := pq.NewListener(l.dsn, l.MinBackoff, l.MaxBackoff, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnected:
+			logger.Info("Change listener connected", "channel", l.channel)
+		case pq.ListenerEventDisconnected:
+			logger.Warn("Change listener disconnected", "channel", l.channel, "error", err)
+		case pq.ListenerEventReconnected:
+			logger.Info("Change listener reconnected", "channel", l.channel)
+		case pq.ListenerEventConnectionAttemptFailed:
+			logger.Warn("Change listener failed to connect, retrying", "channel", l.channel, "error", err)
+		}
+	})
+
+	go func() {
+		defer close(events)
+		defer listener.Close()
+
+		// Listen blocks until the first connection succeeds; Close above
+		// releases it when ctx ends first
+		subscribed := make(chan error, 1)
+		go func() { subscribed <- listener.Listen(l.channel) }()
+		select {
+		case err := <-subscribed:
+			if err != nil {
+				logger.Error("Change listener failed to subscribe", "channel", l.channel, "error", err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		ping := time.NewTicker(l.PingInterval)
+		defer ping.Stop()
+		for {
+			var event ChangeEvent
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				if err := listener.Ping(); err != nil {
+					logger.Warn("Change listener ping failed", "channel", l.channel, "error", err)
+				}
+				continue
+			case n := <-listener.Notify:
+				ping.Reset(l.PingInterval)
+				if n == nil {
+					event = ChangeEvent{Op: ChangeResync}
+				} else if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+					logger.Warn("Ignoring malformed change notification", "channel", l.channel, "error", err)
+					continue
+				}
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jerrychou/go-practice/config"
+)
+
+// ConfigSnapshots stores configuration snapshots in the config_snapshots
+// table, so every instance's config history is in one place
+type ConfigSnapshots struct {
+	db *sql.DB
+}
+
+// NewConfigSnapshots creates a snapshot store on a migrated database
+func NewConfigSnapshots(db *sql.DB) *ConfigSnapshots {
+	return &ConfigSnapshots{db: db}
+}
+
+// Save implements config.SnapshotStore
+func (s *ConfigSnapshots) Save(ctx context.Context, snapshot *config.Snapshot) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO config_snapshots (hash, source, host, config, taken_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		snapshot.Hash, snapshot.Source, snapshot.Host, string(snapshot.Config), snapshot.Time.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record config snapshot: %w", err)
+	}
+	return nil
+}
+
+// Latest implements config.SnapshotStore
+func (s *ConfigSnapshots) Latest(ctx context.Context) (*config.Snapshot, error) {
+	snapshots, err := s.List(ctx, 1)
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return &snapshots[0], nil
+}
+
+// List implements config.SnapshotStore
+func (s *ConfigSnapshots) List(ctx context.Context, limit int) ([]config.Snapshot, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT hash, source, host, config, taken_at FROM config_snapshots
+		ORDER BY taken_at DESC, id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query config snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []config.Snapshot
+	for rows.Next() {
+		var snapshot config.Snapshot
+		var cfg string
+		if err := rows.Scan(&snapshot.Hash, &snapshot.Source, &snapshot.Host, &cfg, &snapshot.Time); err != nil {
+			return nil, fmt.Errorf("failed to scan config snapshot: %w", err)
+		}
+		snapshot.Config = []byte(cfg)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
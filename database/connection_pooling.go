@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"sync"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/metrics"
 )
 
 // PoolConfig holds connection pool configuration
@@ -16,6 +18,8 @@ type PoolConfig struct {
 	ConnMaxLifetime  time.Duration `json:"conn_max_lifetime"`
 	ConnMaxIdleTime  time.Duration `json:"conn_max_idle_time"`
 	HealthCheckDelay time.Duration `json:"health_check_delay"`
+	// MetricsName labels the pool's metrics, defaults to "default"
+	MetricsName string `json:"metrics_name"`
 }
 
 // ConnectionPoolManager manages database connection pools
@@ -56,6 +60,9 @@ func NewConnectionPoolManager(db *sql.DB, config PoolConfig) *ConnectionPoolMana
 	// Configure the database connection pool
 	manager.configurePool()
 
+	// Export pool statistics for scraping
+	metrics.RegisterDBStats(manager.metricsName(), db)
+
 	// Start health monitoring
 	go manager.startHealthMonitoring()
 
@@ -69,7 +76,7 @@ func (cpm *ConnectionPoolManager) configurePool() {
 	cpm.db.SetConnMaxLifetime(cpm.config.ConnMaxLifetime)
 	cpm.db.SetConnMaxIdleTime(cpm.config.ConnMaxIdleTime)
 
-	log.Printf("Connection pool configured: MaxOpen=%d, MaxIdle=%d, MaxLifetime=%v, MaxIdleTime=%v",
+	logger.Infof("Connection pool configured: MaxOpen=%d, MaxIdle=%d, MaxLifetime=%v, MaxIdleTime=%v",
 		cpm.config.MaxOpenConns, cpm.config.MaxIdleConns, cpm.config.ConnMaxLifetime, cpm.config.ConnMaxIdleTime)
 }
 
@@ -81,7 +88,7 @@ func (cpm *ConnectionPoolManager) startHealthMonitoring() {
 	for {
 		select {
 		case <-cpm.ctx.Done():
-			log.Println("Health monitoring stopped")
+			logger.Info("Health monitoring stopped")
 			return
 		case <-ticker.C:
 			cpm.updateStats()
@@ -117,15 +124,15 @@ func (cpm *ConnectionPoolManager) checkHealth() {
 
 	// Check for potential issues
 	if stats.WaitCount > 100 {
-		log.Printf("WARNING: High wait count detected: %d", stats.WaitCount)
+		logger.Warnf("High wait count detected: %d", stats.WaitCount)
 	}
 
 	if stats.WaitDuration > 5*time.Second {
-		log.Printf("WARNING: High wait duration detected: %v", stats.WaitDuration)
+		logger.Warnf("High wait duration detected: %v", stats.WaitDuration)
 	}
 
 	if stats.OpenConnections >= cpm.config.MaxOpenConns {
-		log.Printf("WARNING: Connection pool at maximum capacity: %d/%d",
+		logger.Warnf("Connection pool at maximum capacity: %d/%d",
 			stats.OpenConnections, cpm.config.MaxOpenConns)
 	}
 }
@@ -157,12 +164,12 @@ func (cpm *ConnectionPoolManager) PingWithRetry(maxRetries int, retryDelay time.
 	for i := 0; i < maxRetries; i++ {
 		if err := cpm.Ping(); err != nil {
 			lastErr = err
-			log.Printf("Ping attempt %d/%d failed: %v", i+1, maxRetries, err)
+			logger.Warnf("Ping attempt %d/%d failed: %v", i+1, maxRetries, err)
 			if i < maxRetries-1 {
 				time.Sleep(retryDelay)
 			}
 		} else {
-			log.Printf("Ping successful on attempt %d", i+1)
+			logger.Infof("Ping successful on attempt %d", i+1)
 			return nil
 		}
 	}
@@ -202,9 +209,17 @@ func (cpm *ConnectionPoolManager) GetConnectionWithTimeout(timeout time.Duration
 	return cpm.db.Conn(ctx)
 }
 
+func (cpm *ConnectionPoolManager) metricsName() string {
+	if cpm.config.MetricsName == "" {
+		return "default"
+	}
+	return cpm.config.MetricsName
+}
+
 // Close closes the connection pool manager
 func (cpm *ConnectionPoolManager) Close() error {
 	cpm.cancel()
+	metrics.UnregisterDBStats(cpm.metricsName())
 	return cpm.db.Close()
 }
 
@@ -212,17 +227,21 @@ func (cpm *ConnectionPoolManager) Close() error {
 func (cpm *ConnectionPoolManager) PrintStats() {
 	stats := cpm.GetStats()
 
-	log.Println("=== Connection Pool Statistics ===")
-	log.Printf("Open Connections: %d", stats.OpenConnections)
-	log.Printf("In Use: %d", stats.InUse)
-	log.Printf("Idle: %d", stats.Idle)
-	log.Printf("Wait Count: %d", stats.WaitCount)
-	log.Printf("Wait Duration: %v", stats.WaitDuration)
-	log.Printf("Max Idle Closed: %d", stats.MaxIdleClosed)
-	log.Printf("Max Idle Time Closed: %d", stats.MaxIdleTimeClosed)
-	log.Printf("Max Lifetime Closed: %d", stats.MaxLifetimeClosed)
-	log.Printf("Last Updated: %v", stats.LastUpdated)
-	log.Println("==================================")
+	logger.Info("=== Connection Pool Statistics ===")
+	logger.Infof("Open Connections: %d", stats.OpenConnections)
+	logger.Infof("In Use: %d", stats.InUse)
+	logger.Infof("Idle: %d", stats.Idle)
+	logger.Infof("Wait Count: %s", format.Comma(stats.WaitCount))
+	logger.Infof("Wait Duration: %s", format.HumanizeDuration(stats.WaitDuration))
+	logger.Infof("Max Idle Closed: %s", format.Comma(stats.MaxIdleClosed))
+	logger.Infof("Max Idle Time Closed: %s", format.Comma(stats.MaxIdleTimeClosed))
+	logger.Infof("Max Lifetime Closed: %s", format.Comma(stats.MaxLifetimeClosed))
+	if stats.LastUpdated.IsZero() {
+		logger.Info("Last Updated: never")
+	} else {
+		logger.Infof("Last Updated: %s", format.RelTime(stats.LastUpdated))
+	}
+	logger.Info("==================================")
 }
 
 // GetDefaultPoolConfig returns default connection pool configuration
@@ -282,7 +301,7 @@ func (cpb *ConnectionPoolBenchmark) BenchmarkConcurrentConnections(numGoroutines
 				query := "SELECT 1"
 				_, err := cpb.manager.QueryWithTimeout(query, 5*time.Second)
 				if err != nil {
-					log.Printf("Goroutine %d, Query %d failed: %v", goroutineID, j, err)
+					logger.Warnf("Goroutine %d, Query %d failed: %v", goroutineID, j, err)
 					return
 				}
 			}
@@ -292,7 +311,7 @@ func (cpb *ConnectionPoolBenchmark) BenchmarkConcurrentConnections(numGoroutines
 	wg.Wait()
 	duration := time.Since(start)
 
-	log.Printf("Benchmark completed: %d goroutines, %d queries each, took %v",
+	logger.Infof("Benchmark completed: %d goroutines, %d queries each, took %v",
 		numGoroutines, queriesPerGoroutine, duration)
 
 	cpb.manager.PrintStats()
@@ -314,7 +333,7 @@ func (cpb *ConnectionPoolBenchmark) BenchmarkConnectionAcquisition(numConnection
 	duration := time.Since(start)
 	avgTime := duration / time.Duration(numConnections)
 
-	log.Printf("Connection acquisition benchmark: %d connections, avg time: %v",
+	logger.Infof("Connection acquisition benchmark: %d connections, avg time: %v",
 		numConnections, avgTime)
 
 	return nil
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jerrychou/go-practice/cron"
+)
+
+// CronHistory stores cron run records in the cron_runs table so missed
+// runs can be detected across restarts
+type CronHistory struct {
+	db *sql.DB
+}
+
+// NewCronHistory creates a history store on a migrated database
+func NewCronHistory(db *sql.DB) *CronHistory {
+	return &CronHistory{db: db}
+}
+
+// Record implements cron.HistoryStore. Times are stored in UTC so they
+// sort correctly on databases that keep timestamps as text.
+func (h *CronHistory) Record(ctx context.Context, run cron.RunRecord) error {
+	_, err := h.db.ExecContext(ctx, `
+		INSERT INTO cron_runs (job, trigger_type, scheduled_at, started_at, finished_at, error)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		run.Job, run.Trigger, run.ScheduledAt.UTC(), run.StartedAt.UTC(), run.FinishedAt.UTC(), run.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record cron run: %w", err)
+	}
+	return nil
+}
+
+// Runs implements cron.HistoryStore
+func (h *CronHistory) Runs(ctx context.Context, job string, limit int) ([]cron.RunRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id, job, trigger_type, scheduled_at, started_at, finished_at, error FROM cron_runs
+		WHERE job = $1
+		ORDER BY started_at DESC, id DESC
+		LIMIT $2`, job, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cron runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []cron.RunRecord
+	for rows.Next() {
+		var run cron.RunRecord
+		if err := rows.Scan(&run.ID, &run.Job, &run.Trigger, &run.ScheduledAt, &run.StartedAt, &run.FinishedAt, &run.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan cron run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// LastScheduled implements cron.HistoryStore
+func (h *CronHistory) LastScheduled(ctx context.Context, job string) (time.Time, error) {
+	var last time.Time
+	err := h.db.QueryRowContext(ctx, `
+		SELECT scheduled_at FROM cron_runs
+		WHERE job = $1 AND trigger_type <> $2
+		ORDER BY scheduled_at DESC
+		LIMIT 1`, job, cron.TriggerManual).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query last cron run: %w", err)
+	}
+	return last, nil
+}
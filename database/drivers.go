@@ -3,14 +3,17 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql" // MySQL driver
 	_ "github.com/lib/pq"              // PostgreSQL driver
 	_ "github.com/mattn/go-sqlite3"    // SQLite driver
+
+	"github.com/jerrychou/go-practice/format"
 )
 
+var logger = format.GetLogger("database")
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Host     string
@@ -56,7 +59,7 @@ func (d *DatabaseDrivers) ConnectPostgreSQL(config DatabaseConfig) error {
 	}
 
 	d.postgresDB = db
-	log.Println("PostgreSQL connection established successfully")
+	logger.Info("PostgreSQL connection established successfully")
 	return nil
 }
 
@@ -81,7 +84,7 @@ func (d *DatabaseDrivers) ConnectMySQL(config DatabaseConfig) error {
 	}
 
 	d.mysqlDB = db
-	log.Println("MySQL connection established successfully")
+	logger.Info("MySQL connection established successfully")
 	return nil
 }
 
@@ -105,7 +108,7 @@ func (d *DatabaseDrivers) ConnectSQLite(dbPath string) error {
 	}
 
 	d.sqliteDB = db
-	log.Println("SQLite connection established successfully")
+	logger.Info("SQLite connection established successfully")
 	return nil
 }
 
@@ -138,7 +141,7 @@ func (d *DatabaseDrivers) TestPostgreSQLConnection() error {
 		return fmt.Errorf("failed to get PostgreSQL version: %w", err)
 	}
 
-	log.Printf("PostgreSQL version: %s", version)
+	logger.Infof("PostgreSQL version: %s", version)
 	return nil
 }
 
@@ -156,7 +159,7 @@ func (d *DatabaseDrivers) TestMySQLConnection() error {
 		return fmt.Errorf("failed to get MySQL version: %w", err)
 	}
 
-	log.Printf("MySQL version: %s", version)
+	logger.Infof("MySQL version: %s", version)
 	return nil
 }
 
@@ -174,22 +177,22 @@ func (d *DatabaseDrivers) TestSQLiteConnection() error {
 		return fmt.Errorf("failed to get SQLite version: %w", err)
 	}
 
-	log.Printf("SQLite version: %s", version)
+	logger.Infof("SQLite version: %s", version)
 	return nil
 }
 
 // GetConnectionStats returns connection pool statistics
 func (d *DatabaseDrivers) GetConnectionStats(db *sql.DB, dbType string) {
 	stats := db.Stats()
-	log.Printf("%s Connection Stats:", dbType)
-	log.Printf("  Open Connections: %d", stats.OpenConnections)
-	log.Printf("  In Use: %d", stats.InUse)
-	log.Printf("  Idle: %d", stats.Idle)
-	log.Printf("  Wait Count: %d", stats.WaitCount)
-	log.Printf("  Wait Duration: %v", stats.WaitDuration)
-	log.Printf("  Max Idle Closed: %d", stats.MaxIdleClosed)
-	log.Printf("  Max Idle Time Closed: %d", stats.MaxIdleTimeClosed)
-	log.Printf("  Max Lifetime Closed: %d", stats.MaxLifetimeClosed)
+	logger.Infof("%s Connection Stats:", dbType)
+	logger.Infof("  Open Connections: %d", stats.OpenConnections)
+	logger.Infof("  In Use: %d", stats.InUse)
+	logger.Infof("  Idle: %d", stats.Idle)
+	logger.Infof("  Wait Count: %s", format.Comma(stats.WaitCount))
+	logger.Infof("  Wait Duration: %s", format.HumanizeDuration(stats.WaitDuration))
+	logger.Infof("  Max Idle Closed: %s", format.Comma(stats.MaxIdleClosed))
+	logger.Infof("  Max Idle Time Closed: %s", format.Comma(stats.MaxIdleTimeClosed))
+	logger.Infof("  Max Lifetime Closed: %s", format.Comma(stats.MaxLifetimeClosed))
 }
 
 // CloseAllConnections closes all database connections
@@ -200,7 +203,7 @@ func (d *DatabaseDrivers) CloseAllConnections() error {
 		if err := d.postgresDB.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close PostgreSQL: %w", err))
 		} else {
-			log.Println("PostgreSQL connection closed")
+			logger.Info("PostgreSQL connection closed")
 		}
 	}
 
@@ -208,7 +211,7 @@ func (d *DatabaseDrivers) CloseAllConnections() error {
 		if err := d.mysqlDB.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close MySQL: %w", err))
 		} else {
-			log.Println("MySQL connection closed")
+			logger.Info("MySQL connection closed")
 		}
 	}
 
@@ -216,7 +219,7 @@ func (d *DatabaseDrivers) CloseAllConnections() error {
 		if err := d.sqliteDB.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close SQLite: %w", err))
 		} else {
-			log.Println("SQLite connection closed")
+			logger.Info("SQLite connection closed")
 		}
 	}
 
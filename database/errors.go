@@ -0,0 +1,51 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// IsNotFound reports whether err means a query matched no rows, from
+// database/sql or GORM
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// IsConflict reports whether err is a unique, primary key or foreign key
+// constraint violation from PostgreSQL, MySQL, SQLite or GORM, meaning the
+// write clashes with data already stored rather than being malformed
+func IsConflict(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) || errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505", "23503", "23P01": // unique, foreign key, exclusion
+			return true
+		}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1062, 1451, 1452: // duplicate entry, row referenced, no parent row
+			return true
+		}
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintForeignKey:
+			return true
+		}
+	}
+	return false
+}
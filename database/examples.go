@@ -1,10 +1,21 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/queue"
+	reflectops "github.com/jerrychou/go-practice/reflect"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // DatabaseExamples demonstrates all database operations
@@ -24,7 +35,7 @@ func NewDatabaseExamples() *DatabaseExamples {
 
 // RunSQLBasicsExamples demonstrates SQL basics operations
 func (de *DatabaseExamples) RunSQLBasicsExamples(db *sql.DB) error {
-	log.Println("=== Running SQL Basics Examples ===")
+	logger.Info("=== Running SQL Basics Examples ===")
 
 	de.sqlBasics = NewSQLBasics(db)
 
@@ -33,7 +44,8 @@ func (de *DatabaseExamples) RunSQLBasicsExamples(db *sql.DB) error {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
 
-	// Insert users
+	// Upsert users, so a run interrupted before the cleanup can be repeated
+	// without tripping over the unique emails
 	users := []struct {
 		name  string
 		email string
@@ -46,9 +58,9 @@ func (de *DatabaseExamples) RunSQLBasicsExamples(db *sql.DB) error {
 	}
 
 	for _, u := range users {
-		_, err := de.sqlBasics.InsertUser(u.name, u.email, u.age)
+		_, err := de.sqlBasics.UpsertUser(u.name, u.email, u.age)
 		if err != nil {
-			return fmt.Errorf("failed to insert user %s: %w", u.name, err)
+			return fmt.Errorf("failed to upsert user %s: %w", u.name, err)
 		}
 	}
 
@@ -57,74 +69,90 @@ func (de *DatabaseExamples) RunSQLBasicsExamples(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to get all users: %w", err)
 	}
-	log.Printf("Retrieved %d users", len(allUsers))
+	logger.Infof("Retrieved %d users", len(allUsers))
 
 	// Search users
 	searchResults, err := de.sqlBasics.SearchUsers("john")
 	if err != nil {
 		return fmt.Errorf("failed to search users: %w", err)
 	}
-	log.Printf("Search results: %d users found", len(searchResults))
+	logger.Infof("Search results: %d users found", len(searchResults))
 
 	// Update user
 	updatedUser, err := de.sqlBasics.UpdateUser(1, "John Updated", "john.updated@example.com", 31)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
-	log.Printf("Updated user: %+v", updatedUser)
+	logger.Infof("Updated user: %+v", updatedUser)
 
 	// Get user count
 	count, err := de.sqlBasics.GetUserCount()
 	if err != nil {
 		return fmt.Errorf("failed to get user count: %w", err)
 	}
-	log.Printf("Total users: %d", count)
+	logger.Infof("Total users: %d", count)
 
 	// Get users by age range
 	ageRangeUsers, err := de.sqlBasics.GetUsersByAgeRange(25, 30)
 	if err != nil {
 		return fmt.Errorf("failed to get users by age range: %w", err)
 	}
-	log.Printf("Users in age range 25-30: %d", len(ageRangeUsers))
+	logger.Infof("Users in age range 25-30: %d", len(ageRangeUsers))
+
+	// Page through users two at a time, following the cursors
+	req := PageRequest{Limit: 2, Count: CountFirstPage}
+	for {
+		page, err := de.sqlBasics.GetUsersPage(req)
+		if err != nil {
+			return fmt.Errorf("failed to get users page: %w", err)
+		}
+		for _, u := range page.Items {
+			logger.Infof("Page item: %s (%s)", u.Name, u.Email)
+		}
+		if !page.HasMore {
+			break
+		}
+		req = PageRequest{Limit: 2, Cursor: page.NextCursor}
+	}
 
 	// Clean up
 	if err := de.sqlBasics.CleanupTable(); err != nil {
 		return fmt.Errorf("failed to cleanup table: %w", err)
 	}
 
-	log.Println("SQL Basics Examples completed successfully")
+	logger.Info("SQL Basics Examples completed successfully")
 	return nil
 }
 
 // RunORMExamples demonstrates ORM operations
 func (de *DatabaseExamples) RunORMExamples(gormDB interface{}) error {
-	log.Println("=== Running ORM Examples ===")
+	logger.Info("=== Running ORM Examples ===")
 
 	// Note: This would require GORM to be properly imported and configured
 	// For demonstration purposes, we'll show the structure without execution
 
-	log.Println("ORM Examples structure:")
-	log.Println("- Model definitions with GORM tags")
-	log.Println("- CRUD operations with GORM")
-	log.Println("- Associations (one-to-one, one-to-many)")
-	log.Println("- Migrations with AutoMigrate")
-	log.Println("- Soft deletes")
-	log.Println("- Pagination")
-	log.Println("- Search functionality")
-
-	log.Println("To run ORM examples:")
-	log.Println("1. Install GORM: go get gorm.io/gorm")
-	log.Println("2. Install GORM drivers")
-	log.Println("3. Set up database connection")
-	log.Println("4. Uncomment the ORM code in examples.go")
-
-	log.Println("ORM Examples completed successfully")
+	logger.Info("ORM Examples structure:")
+	logger.Info("- Model definitions with GORM tags")
+	logger.Info("- CRUD operations with GORM")
+	logger.Info("- Associations (one-to-one, one-to-many)")
+	logger.Info("- Migrations with AutoMigrate")
+	logger.Info("- Soft deletes")
+	logger.Info("- Pagination")
+	logger.Info("- Search functionality")
+
+	logger.Info("To run ORM examples:")
+	logger.Info("1. Install GORM: go get gorm.io/gorm")
+	logger.Info("2. Install GORM drivers")
+	logger.Info("3. Set up database connection")
+	logger.Info("4. Uncomment the ORM code in examples.go")
+
+	logger.Info("ORM Examples completed successfully")
 	return nil
 }
 
 // RunConnectionPoolExamples demonstrates connection pooling
 func (de *DatabaseExamples) RunConnectionPoolExamples(db *sql.DB) error {
-	log.Println("=== Running Connection Pool Examples ===")
+	logger.Info("=== Running Connection Pool Examples ===")
 
 	// Create connection pool manager
 	poolConfig := GetDefaultPoolConfig()
@@ -157,13 +185,13 @@ func (de *DatabaseExamples) RunConnectionPoolExamples(db *sql.DB) error {
 		return fmt.Errorf("failed to close connection pool: %w", err)
 	}
 
-	log.Println("Connection Pool Examples completed successfully")
+	logger.Info("Connection Pool Examples completed successfully")
 	return nil
 }
 
 // RunMigrationExamples demonstrates database migrations
 func (de *DatabaseExamples) RunMigrationExamples(db *sql.DB) error {
-	log.Println("=== Running Migration Examples ===")
+	logger.Info("=== Running Migration Examples ===")
 
 	de.migrationManager = NewMigrationManager(db)
 
@@ -177,10 +205,21 @@ func (de *DatabaseExamples) RunMigrationExamples(db *sql.DB) error {
 		return fmt.Errorf("failed to get migration status: %w", err)
 	}
 
+	// Print, validate and save the plan without applying it, then apply
+	// exactly the reviewed plan
+	planFile := filepath.Join(os.TempDir(), "migration-plan.json")
+	defer os.Remove(planFile)
+	de.migrationManager.SetPlanFile(planFile).SetDryRun(os.Stdout)
+	if err := de.migrationManager.MigrateUp(); err != nil {
+		return fmt.Errorf("failed to dry run migrations: %w", err)
+	}
+	de.migrationManager.SetDryRun(nil)
+
 	// Apply migrations
 	if err := de.migrationManager.MigrateUp(); err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
+	de.migrationManager.SetPlanFile("")
 
 	// Get migration status after applying
 	if err := de.migrationManager.GetMigrationStatus(); err != nil {
@@ -202,13 +241,13 @@ func (de *DatabaseExamples) RunMigrationExamples(db *sql.DB) error {
 		return fmt.Errorf("failed to re-apply migrations: %w", err)
 	}
 
-	log.Println("Migration Examples completed successfully")
+	logger.Info("Migration Examples completed successfully")
 	return nil
 }
 
 // RunTransactionExamples demonstrates database transactions
 func (de *DatabaseExamples) RunTransactionExamples(db *sql.DB) error {
-	log.Println("=== Running Transaction Examples ===")
+	logger.Info("=== Running Transaction Examples ===")
 
 	de.transactionManager = NewTransactionManager(db)
 
@@ -229,7 +268,7 @@ func (de *DatabaseExamples) RunTransactionExamples(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to create user with profile: %w", err)
 	}
-	log.Printf("Created user: %+v, profile: %+v", user, profile)
+	logger.Infof("Created user: %+v, profile: %+v", user, profile)
 
 	// Test batch insert
 	batchUsers := []User{
@@ -246,7 +285,7 @@ func (de *DatabaseExamples) RunTransactionExamples(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to read-only transaction: %w", err)
 	}
-	log.Printf("Read-only transaction retrieved %d users", len(users))
+	logger.Infof("Read-only transaction retrieved %d users", len(users))
 
 	// Test transaction with retry
 	if err := de.transactionManager.TransactionWithRetry(func(tx *sql.Tx) error {
@@ -261,10 +300,124 @@ func (de *DatabaseExamples) RunTransactionExamples(db *sql.DB) error {
 		return fmt.Errorf("failed to cleanup test data: %w", err)
 	}
 
-	log.Println("Transaction Examples completed successfully")
+	logger.Info("Transaction Examples completed successfully")
 	return nil
 }
 
+// RunOutboxExamples demonstrates the transactional outbox: events written
+// with the data they describe and published to broker afterwards. It expects
+// the default migrations to have been applied.
+func (de *DatabaseExamples) RunOutboxExamples(db *sql.DB, broker queue.Broker) error {
+	logger.Info("=== Running Outbox Examples ===")
+
+	de.transactionManager = NewTransactionManager(db)
+	ctx := context.Background()
+
+	consumer, err := broker.Consumer(queue.ConsumerOptions{Topic: "user.created", Group: "welcome-emails"})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	for _, name := range []string{"Outbox User 1", "Outbox User 2"} {
+		email := strings.ToLower(strings.ReplaceAll(name, " ", ".")) + "@example.com"
+		user, err := de.transactionManager.CreateUserWithEvent(name, email, 30)
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		logger.Infof("Created user %d with a pending user.created event", user.ID)
+	}
+
+	dispatcher := NewOutboxDispatcher(db, broker)
+	n, err := dispatcher.DispatchOnce(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to dispatch outbox: %w", err)
+	}
+	logger.Infof("Dispatched %d outbox messages", n)
+
+	for i := 0; i < n; i++ {
+		receiveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		msg, err := consumer.Receive(receiveCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to receive event: %w", err)
+		}
+		logger.Infof("Received %s event (outbox id %s): %s", msg.Topic, msg.Headers[HeaderOutboxID], msg.Body)
+		if err := consumer.Ack(ctx, msg); err != nil {
+			return fmt.Errorf("failed to ack event: %w", err)
+		}
+	}
+
+	logger.Info("Outbox Examples completed successfully")
+	return nil
+}
+
+// RunChangeNotifierExamples demonstrates change data capture with
+// LISTEN/NOTIFY: a user updated behind the cache's back is invalidated as
+// soon as the change is committed. It needs PostgreSQL with the default
+// migrations applied; dsn is the connection string db was opened with.
+func (de *DatabaseExamples) RunChangeNotifierExamples(db *sql.DB, dsn string) error {
+	logger.Info("=== Running Change Notifier Examples ===")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifier := NewChangeNotifier(db, "example_changes")
+	if err := notifier.Watch(ctx, "users", "id"); err != nil {
+		return err
+	}
+	defer notifier.Unwatch(context.Background(), "users")
+	events := NewChangeListener(dsn, "example_changes").Listen(ctx)
+
+	var id int
+	err := db.QueryRowContext(ctx, `INSERT INTO users (name, email, age) VALUES ($1, $2, $3) RETURNING id`,
+		"CDC User", fmt.Sprintf("cdc.%d@example.com", time.Now().UnixNano()), 30).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, id)
+
+	store := NewCachedUserStore(db, cache.NewMemory(cache.MemoryOptions{}), time.Hour)
+	user, err := store.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Cached user %d as %q", id, user.Name)
+
+	// Another writer renames the user without going through the store
+	if _, err := db.ExecContext(ctx, `UPDATE users SET name = $1 WHERE id = $2`, "CDC User (renamed)", id); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("change listener stopped")
+			}
+			var row struct {
+				Name string `json:"name"`
+			}
+			if err := event.Decode(&row); err == nil {
+				logger.Infof("Received %s on %s for %q", event.Op, event.Table, row.Name)
+			}
+			store.ApplyChange(ctx, event)
+			if event.Op != ChangeUpdate {
+				continue
+			}
+			if user, err = store.GetUser(ctx, id); err != nil {
+				return err
+			}
+			logger.Infof("Cache now returns %q", user.Name)
+			logger.Info("Change Notifier Examples completed successfully")
+			return nil
+		case <-timeout:
+			return fmt.Errorf("no change notification within 10s")
+		}
+	}
+}
+
 // createTestData creates test data for transaction examples
 func (de *DatabaseExamples) createTestData(db *sql.DB) error {
 	// Create accounts table
@@ -310,19 +463,134 @@ func (de *DatabaseExamples) cleanupTestData(db *sql.DB) error {
 	return nil
 }
 
+// RunQueryProfilerExamples demonstrates the query profiler on an
+// in-memory SQLite database, with a threshold so low that every query is
+// slow and explained
+func (de *DatabaseExamples) RunQueryProfilerExamples() error {
+	logger.Info("=== Running Query Profiler Examples ===")
+
+	profiler := NewQueryProfiler(ProfilerOptions{SlowThreshold: time.Microsecond, Explain: true})
+	db, err := profiler.Open("sqlite3", ":memory:")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT, total REAL)`); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	db.Exec(`CREATE INDEX orders_customer ON orders (customer)`)
+	for i := range 50 {
+		db.Exec(`INSERT INTO orders (customer, total) VALUES (?, ?)`, fmt.Sprintf("customer-%d", i%5), float64(i)*9.5)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM orders WHERE customer = ?`, "customer-3").Scan(&count)
+	var sum float64
+	db.QueryRow(`SELECT SUM(total) FROM orders WHERE total > ?`, 100.0).Scan(&sum)
+	db.Exec(`SELECT nonsense FROM orders`)
+
+	// Plans are filled in by a background EXPLAIN
+	time.Sleep(100 * time.Millisecond)
+	for _, q := range profiler.Slowest(3) {
+		logger.Infof("Slowest: %v, %d rows: %s", q.Duration, q.Rows, q.Query)
+	}
+	for _, q := range profiler.Recent(3) {
+		logger.Infof("Recent #%d: %v, %d rows: %s", q.ID, q.Duration, q.Rows, q.Query)
+		switch {
+		case q.Error != "":
+			logger.Infof("  error: %s", q.Error)
+		case q.Plan != "":
+			logger.Infof("  plan: %s", strings.ReplaceAll(q.Plan, "\n", "; "))
+		}
+	}
+
+	logger.Info("Query Profiler Examples completed successfully")
+	return nil
+}
+
+// RunColumnTypeExamples demonstrates enum, JSON and array columns on an
+// in-memory SQLite database: a signup request is mapped onto a GORMUser,
+// saved, read back, and refused when its status is not a UserStatus
+func (de *DatabaseExamples) RunColumnTypeExamples() error {
+	logger.Info("=== Running Column Type Examples ===")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.AutoMigrate(&GORMUser{}); err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	type signup struct {
+		Name        string
+		Email       string
+		Status      string
+		Plan        string
+		Preferences map[string]any
+		Tags        []string
+	}
+	request := signup{
+		Name:   "Dana",
+		Email:  "dana@example.com",
+		Status: "invited",
+		Plan:   "2",
+		Preferences: map[string]any{
+			"Theme":         "dark",
+			"Notifications": map[string]any{"email": true, "sms": false},
+		},
+		Tags: []string{"beta", `says "hi", often`},
+	}
+
+	var user GORMUser
+	if err := reflectops.MapStruct(request, &user, reflectops.MapOptions{}); err != nil {
+		return fmt.Errorf("failed to map signup: %w", err)
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	var stored struct {
+		Status, Preferences, Tags string
+		Plan                      int
+	}
+	db.Raw(`SELECT status, plan, preferences, tags FROM gorm_users WHERE id = ?`, user.ID).Scan(&stored)
+	logger.Infof("Stored columns: status=%s plan=%d preferences=%s tags=%s", stored.Status, stored.Plan, stored.Preferences, stored.Tags)
+
+	var loaded GORMUser
+	if err := db.First(&loaded, user.ID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	logger.Infof("Loaded: status=%s plan=%d theme=%s notifications=%v tags=%q",
+		loaded.Status, loaded.Plan, loaded.Preferences.Data.Theme, loaded.Preferences.Data.Notifications, loaded.Tags)
+
+	request.Status = "deleted"
+	err = reflectops.MapStruct(request, &GORMUser{}, reflectops.MapOptions{})
+	logger.Infof("Mapping status %q: %v (ErrInvalidEnum: %t)", request.Status, err, errors.Is(err, ErrInvalidEnum))
+
+	db.Exec(`UPDATE gorm_users SET plan = 9 WHERE id = ?`, user.ID)
+	err = db.First(&loaded, user.ID).Error
+	logger.Infof("Reading plan 9 written behind the model's back: %v", err)
+
+	logger.Info("Column Type Examples completed successfully")
+	return nil
+}
+
 // RunAllExamples runs all database examples
 func (de *DatabaseExamples) RunAllExamples() error {
-	log.Println("=== Starting Database Examples ===")
+	logger.Info("=== Starting Database Examples ===")
 
 	// Note: In a real application, you would connect to actual databases
 	// For this example, we'll demonstrate the structure and patterns
 
-	log.Println("Database examples structure created successfully!")
-	log.Println("To run these examples with real databases:")
-	log.Println("1. Install database drivers: go mod tidy")
-	log.Println("2. Set up PostgreSQL, MySQL, or SQLite database")
-	log.Println("3. Update connection strings in the examples")
-	log.Println("4. Run the examples with actual database connections")
+	logger.Info("Database examples structure created successfully!")
+	logger.Info("To run these examples with real databases:")
+	logger.Info("1. Install database drivers: go mod tidy")
+	logger.Info("2. Set up PostgreSQL, MySQL, or SQLite database")
+	logger.Info("3. Update connection strings in the examples")
+	logger.Info("4. Run the examples with actual database connections")
 
 	return nil
 }
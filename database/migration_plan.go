@@ -0,0 +1,369 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPlanMismatch is returned by MigrateUp when the approved plan file no
+// longer describes the pending migrations, or was edited after it was made
+var ErrPlanMismatch = errors.New("database: migration plan does not match pending migrations")
+
+// recordMigrationSQL marks a migration as applied, in the same transaction
+const recordMigrationSQL = `INSERT INTO schema_migrations (version, name, applied_at, created_at) VALUES ($1, $2, $3, $4)`
+
+// MigrationPlan is what MigrateUp would do: the pending migrations, split
+// into the statements that run one by one inside each migration's
+// transaction. A dry run writes it as JSON so it can be reviewed, and
+// committed, before MigrateUp is pointed at it to apply exactly that.
+type MigrationPlan struct {
+	Dialect    string             `json:"dialect"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Migrations []PlannedMigration `json:"migrations"`
+	// Warnings are things a reviewer should know, such as DDL that MySQL
+	// commits on its own, breaking the migration's transaction
+	Warnings []string `json:"warnings,omitempty"`
+	// Checksum covers the dialect and every statement
+	Checksum string `json:"checksum"`
+}
+
+// PlannedMigration is one migration of a MigrationPlan
+type PlannedMigration struct {
+	Version    int      `json:"version"`
+	Name       string   `json:"name"`
+	Statements []string `json:"statements"`
+}
+
+// SetDryRun makes MigrateUp validate the pending migrations and print the
+// SQL it would run to w, without running any of it. A nil w turns dry runs
+// off.
+func (mm *MigrationManager) SetDryRun(w io.Writer) *MigrationManager {
+	mm.dryRun = w
+	return mm
+}
+
+// SetPlanFile names the plan file a dry run writes. Outside a dry run,
+// MigrateUp reads it back and applies the pending migrations only if they
+// are still the ones in the plan, so what runs is what was reviewed.
+func (mm *MigrationManager) SetPlanFile(path string) *MigrationManager {
+	mm.planFile = path
+	return mm
+}
+
+// Plan returns the plan for the pending migrations
+func (mm *MigrationManager) Plan() (*MigrationPlan, error) {
+	pending, err := mm.GetPendingMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending migrations: %w", err)
+	}
+
+	plan := &MigrationPlan{Dialect: mm.dialectName(), CreatedAt: time.Now().UTC(), Migrations: []PlannedMigration{}}
+	for _, migration := range pending {
+		statements := splitStatements(mm.translate(migration.UpSQL))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("migration %d (%s) has no statements", migration.Version, migration.Name)
+		}
+		plan.Migrations = append(plan.Migrations, PlannedMigration{Version: migration.Version, Name: migration.Name, Statements: statements})
+		if mm.dialect == "mysql" {
+			for _, statement := range statements {
+				if isDDL(statement) {
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+						"migration %d: MySQL commits DDL implicitly, so a failure part way through leaves earlier statements applied", migration.Version))
+					break
+				}
+			}
+		}
+	}
+	plan.Checksum = plan.checksum()
+	return plan, nil
+}
+
+// ValidatePlan prepares every statement of plan against the database
+// without executing it, so the server's parser checks the SQL in its own
+// dialect. Statements that refer to tables or columns an earlier statement
+// of the plan creates cannot be checked before that one runs, and are
+// skipped. The returned error lists every statement that failed.
+func (mm *MigrationManager) ValidatePlan(plan *MigrationPlan) error {
+	var problems []string
+	var created []string
+	for _, migration := range plan.Migrations {
+		for i, statement := range migration.Statements {
+			stmt, err := mm.db.Prepare(statement)
+			if err == nil {
+				stmt.Close()
+			} else if name := mentionsAny(err.Error(), created); name != "" {
+				logger.Debugf("Migration %d statement %d depends on %s, created earlier in the plan", migration.Version, i+1, name)
+			} else {
+				problems = append(problems, fmt.Sprintf("migration %d statement %d: %v\n\t%s", migration.Version, i+1, err, firstLine(statement)))
+			}
+			created = append(created, createdNames(statement)...)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("migration plan is invalid for %s:\n%s", plan.Dialect, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// WriteSQL writes plan as the SQL script MigrateUp runs, with each
+// migration's transaction and the statement recording it
+func (plan *MigrationPlan) WriteSQL(w io.Writer) error {
+	statements := 0
+	for _, migration := range plan.Migrations {
+		statements += len(migration.Statements)
+	}
+	fmt.Fprintf(w, "-- Migration plan for %s: %d migrations, %d statements\n", plan.Dialect, len(plan.Migrations), statements)
+	fmt.Fprintf(w, "-- Checksum %s\n", plan.Checksum)
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(w, "-- WARNING: %s\n", warning)
+	}
+	for _, migration := range plan.Migrations {
+		fmt.Fprintf(w, "\n-- Migration %d: %s\nBEGIN;\n", migration.Version, migration.Name)
+		for _, statement := range migration.Statements {
+			fmt.Fprintf(w, "%s;\n", statement)
+		}
+		fmt.Fprintf(w, "%s; -- %d, %s, now, created_at\n", recordMigrationSQL, migration.Version, strconv.Quote(migration.Name))
+		if _, err := fmt.Fprintln(w, "COMMIT;"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMigrationPlan saves plan as indented JSON
+func WriteMigrationPlan(path string, plan *MigrationPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+	return nil
+}
+
+// ReadMigrationPlan loads a plan saved by WriteMigrationPlan, refusing one
+// whose statements were edited after it was made
+func ReadMigrationPlan(path string) (*MigrationPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration plan: %w", err)
+	}
+	var plan MigrationPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse migration plan %s: %w", path, err)
+	}
+	if plan.checksum() != plan.Checksum {
+		return nil, fmt.Errorf("%w: %s was edited after it was made", ErrPlanMismatch, path)
+	}
+	return &plan, nil
+}
+
+// dryRunMigrations is MigrateUp in a dry run
+func (mm *MigrationManager) dryRunMigrations() error {
+	plan, err := mm.Plan()
+	if err != nil {
+		return err
+	}
+	if err := plan.WriteSQL(mm.dryRun); err != nil {
+		return fmt.Errorf("failed to write migration plan: %w", err)
+	}
+	if err := mm.ValidatePlan(plan); err != nil {
+		return err
+	}
+	logger.Infof("Dry run: %d migrations validated against %s, nothing applied", len(plan.Migrations), plan.Dialect)
+
+	if mm.planFile != "" {
+		if err := WriteMigrationPlan(mm.planFile, plan); err != nil {
+			return err
+		}
+		logger.Infof("Migration plan written to %s for review", mm.planFile)
+	}
+	return nil
+}
+
+// approvedPlan checks the plan file against the pending migrations and
+// returns them in the plan's order
+func (mm *MigrationManager) approvedPlan() (*MigrationPlan, error) {
+	approved, err := ReadMigrationPlan(mm.planFile)
+	if err != nil {
+		return nil, err
+	}
+	current, err := mm.Plan()
+	if err != nil {
+		return nil, err
+	}
+	if approved.Checksum != current.Checksum {
+		return nil, fmt.Errorf("%w: %s plans %s, pending now are %s; make a new plan with a dry run",
+			ErrPlanMismatch, mm.planFile, approved.versions(), current.versions())
+	}
+	logger.Infof("Applying approved migration plan %s", mm.planFile)
+	return approved, nil
+}
+
+func (mm *MigrationManager) dialectName() string {
+	if mm.dialect == "" {
+		return "postgres"
+	}
+	return mm.dialect
+}
+
+func (plan *MigrationPlan) checksum() string {
+	h := sha256.New()
+	io.WriteString(h, plan.Dialect+"\n")
+	for _, migration := range plan.Migrations {
+		fmt.Fprintf(h, "%d %s %d\n", migration.Version, migration.Name, len(migration.Statements))
+		for _, statement := range migration.Statements {
+			fmt.Fprintf(h, "%d:%s\n", len(statement), statement)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// versions lists the planned versions, e.g. "[4 5]"
+func (plan *MigrationPlan) versions() string {
+	versions := make([]int, len(plan.Migrations))
+	for i, migration := range plan.Migrations {
+		versions[i] = migration.Version
+	}
+	return fmt.Sprint(versions)
+}
+
+// splitStatements splits a migration's SQL on the semicolons that end its
+// statements, leaving those inside quotes, comments, dollar-quoted function
+// bodies and trigger bodies, and trims each statement's indentation
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := dedent(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(script) && script[end] != c {
+				end++
+			}
+			current.WriteString(script[i:min(end+1, len(script))])
+			i = end
+			continue
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end - 1
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 4
+			}
+			current.WriteString(script[i : i+end+4])
+			i += end + 3
+			continue
+		case c == '$':
+			if tag := dollarTag.FindString(script[i:]); tag != "" {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					end = len(script) - i - 2*len(tag)
+				}
+				current.WriteString(script[i : i+2*len(tag)+end])
+				i += 2*len(tag) + end - 1
+				continue
+			}
+		case c == ';':
+			// A trigger body's statements end in semicolons too
+			upper := strings.ToUpper(strings.TrimSpace(current.String()))
+			if triggerStart.MatchString(upper) && !strings.HasSuffix(upper, "END") {
+				break
+			}
+			flush()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+	return statements
+}
+
+var (
+	dollarTag    = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+	triggerStart = regexp.MustCompile(`^CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+	createdName  = regexp.MustCompile(`(?i)\b(?:CREATE\s+(?:UNIQUE\s+)?(?:TABLE|INDEX|VIEW)|ADD\s+COLUMN)\s+(?:IF\s+NOT\s+EXISTS\s+)?["` + "`" + `]?(\w+)`)
+)
+
+// dedent removes surrounding blank space and the indentation common to
+// every line
+func dedent(statement string) string {
+	lines := strings.Split(strings.Trim(statement, "\n\r"), "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// createdNames returns the tables, indexes, views and columns a statement
+// creates
+func createdNames(statement string) []string {
+	var names []string
+	for _, match := range createdName.FindAllStringSubmatch(statement, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// mentionsAny returns the first of names that appears as a word in s
+func mentionsAny(s string, names []string) string {
+	for _, name := range names {
+		if regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).MatchString(s) {
+			return name
+		}
+	}
+	return ""
+}
+
+func isDDL(statement string) bool {
+	keyword, _, _ := strings.Cut(strings.ToUpper(statement), " ")
+	switch keyword {
+	case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE":
+		return true
+	}
+	return false
+}
+
+func firstLine(statement string) string {
+	line, _, more := strings.Cut(statement, "\n")
+	if more {
+		return line + " …"
+	}
+	return line
+}
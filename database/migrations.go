@@ -3,9 +3,13 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"io"
+	"os"
 	"sort"
+	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
 )
 
 // Migration represents a database migration
@@ -22,6 +26,9 @@ type Migration struct {
 type MigrationManager struct {
 	db         *sql.DB
 	migrations []Migration
+	dialect    string
+	dryRun     io.Writer
+	planFile   string
 }
 
 // NewMigrationManager creates a new migration manager
@@ -40,6 +47,25 @@ func NewMigrationManager(db *sql.DB) *MigrationManager {
 	return mm
 }
 
+// SetDialect adapts the PostgreSQL-flavoured migration SQL to another driver.
+// Only "sqlite3" needs rewriting: SERIAL columns become auto-incrementing
+// rowid aliases and DROP COLUMN loses its IF EXISTS.
+func (mm *MigrationManager) SetDialect(dialect string) *MigrationManager {
+	mm.dialect = dialect
+	return mm
+}
+
+// translate rewrites migration SQL for the configured dialect
+func (mm *MigrationManager) translate(query string) string {
+	if mm.dialect != "sqlite3" {
+		return query
+	}
+	return strings.NewReplacer(
+		"SERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"DROP COLUMN IF EXISTS", "DROP COLUMN",
+	).Replace(query)
+}
+
 // createMigrationsTable creates the migrations tracking table
 func (mm *MigrationManager) createMigrationsTable() error {
 	query := `
@@ -55,7 +81,7 @@ func (mm *MigrationManager) createMigrationsTable() error {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	log.Println("Migrations table created/verified")
+	logger.Info("Migrations table created/verified")
 	return nil
 }
 
@@ -142,12 +168,90 @@ func (mm *MigrationManager) registerDefaultMigrations() {
 			ALTER TABLE users DROP COLUMN IF EXISTS deleted_at`,
 		CreatedAt: time.Now(),
 	})
+
+	// Migration 6: Create transactional outbox table
+	mm.AddMigration(Migration{
+		Version: 6,
+		Name:    "create_outbox_table",
+		UpSQL: `
+			CREATE TABLE outbox (
+				id SERIAL PRIMARY KEY,
+				topic VARCHAR(200) NOT NULL,
+				payload TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				published_at TIMESTAMP
+			);
+			CREATE INDEX idx_outbox_unpublished ON outbox(published_at, id)`,
+		DownSQL: `
+			DROP INDEX IF EXISTS idx_outbox_unpublished;
+			DROP TABLE IF EXISTS outbox`,
+		CreatedAt: time.Now(),
+	})
+
+	mm.AddMigration(Migration{
+		Version: 7,
+		Name:    "create_cron_runs_table",
+		UpSQL: `
+			CREATE TABLE cron_runs (
+				id SERIAL PRIMARY KEY,
+				job VARCHAR(200) NOT NULL,
+				trigger_type VARCHAR(20) NOT NULL,
+				scheduled_at TIMESTAMP NOT NULL,
+				started_at TIMESTAMP NOT NULL,
+				finished_at TIMESTAMP NOT NULL,
+				error TEXT NOT NULL DEFAULT ''
+			);
+			CREATE INDEX idx_cron_runs_job ON cron_runs(job, started_at)`,
+		DownSQL: `
+			DROP INDEX IF EXISTS idx_cron_runs_job;
+			DROP TABLE IF EXISTS cron_runs`,
+		CreatedAt: time.Now(),
+	})
+
+	mm.AddMigration(Migration{
+		Version: 8,
+		Name:    "create_config_snapshots_table",
+		UpSQL: `
+			CREATE TABLE config_snapshots (
+				id SERIAL PRIMARY KEY,
+				hash VARCHAR(64) NOT NULL,
+				source VARCHAR(500) NOT NULL,
+				host VARCHAR(255) NOT NULL DEFAULT '',
+				config TEXT NOT NULL,
+				taken_at TIMESTAMP NOT NULL
+			);
+			CREATE INDEX idx_config_snapshots_taken_at ON config_snapshots(taken_at)`,
+		DownSQL: `
+			DROP INDEX IF EXISTS idx_config_snapshots_taken_at;
+			DROP TABLE IF EXISTS config_snapshots`,
+		CreatedAt: time.Now(),
+	})
+
+	mm.AddMigration(Migration{
+		Version: 9,
+		Name:    "create_password_reset_tokens_table",
+		UpSQL: `
+			CREATE TABLE password_reset_tokens (
+				selector VARCHAR(64) PRIMARY KEY,
+				verifier_hash VARCHAR(64) NOT NULL,
+				subject VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				used_at TIMESTAMP,
+				attempts INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX idx_password_reset_tokens_subject ON password_reset_tokens(subject, created_at)`,
+		DownSQL: `
+			DROP INDEX IF EXISTS idx_password_reset_tokens_subject;
+			DROP TABLE IF EXISTS password_reset_tokens`,
+		CreatedAt: time.Now(),
+	})
 }
 
 // AddMigration adds a migration to the manager
 func (mm *MigrationManager) AddMigration(migration Migration) {
 	mm.migrations = append(mm.migrations, migration)
-	log.Printf("Migration added: %d - %s", migration.Version, migration.Name)
+	logger.Infof("Migration added: %d - %s", migration.Version, migration.Name)
 }
 
 // GetAppliedMigrations returns list of applied migrations
@@ -200,27 +304,45 @@ func (mm *MigrationManager) GetPendingMigrations() ([]Migration, error) {
 	return pending, nil
 }
 
-// MigrateUp applies all pending migrations
+// MigrateUp applies all pending migrations, each in a transaction of its
+// own. In a dry run it only prints and validates them; with a plan file it
+// applies them only if they match the plan.
 func (mm *MigrationManager) MigrateUp() error {
-	pending, err := mm.GetPendingMigrations()
+	if mm.dryRun != nil {
+		return mm.dryRunMigrations()
+	}
+
+	var plan *MigrationPlan
+	var err error
+	if mm.planFile != "" {
+		plan, err = mm.approvedPlan()
+	} else {
+		plan, err = mm.Plan()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get pending migrations: %w", err)
+		return err
 	}
+	pending := plan.Migrations
 
 	if len(pending) == 0 {
-		log.Println("No pending migrations")
+		logger.Info("No pending migrations")
 		return nil
 	}
 
-	log.Printf("Applying %d pending migrations", len(pending))
+	logger.Infof("Applying %d pending migrations", len(pending))
 
+	bar := format.NewProgressBar(os.Stdout, int64(len(pending)), "Migrating")
+	bar.Unit = "migrations"
 	for _, migration := range pending {
 		if err := mm.applyMigration(migration); err != nil {
+			fmt.Println()
 			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
 		}
+		bar.Increment()
 	}
+	bar.Finish()
 
-	log.Println("All migrations applied successfully")
+	logger.Info("All migrations applied successfully")
 	return nil
 }
 
@@ -232,7 +354,7 @@ func (mm *MigrationManager) MigrateDown() error {
 	}
 
 	if len(applied) == 0 {
-		log.Println("No migrations to rollback")
+		logger.Info("No migrations to rollback")
 		return nil
 	}
 
@@ -252,10 +374,10 @@ func (mm *MigrationManager) MigrateDown() error {
 		return fmt.Errorf("migration definition not found for version %d", lastMigration.Version)
 	}
 
-	log.Printf("Rolling back migration %d: %s", migrationDef.Version, migrationDef.Name)
+	logger.Infof("Rolling back migration %d: %s", migrationDef.Version, migrationDef.Name)
 
 	// Execute down migration
-	if _, err := mm.db.Exec(migrationDef.DownSQL); err != nil {
+	if _, err := mm.db.Exec(mm.translate(migrationDef.DownSQL)); err != nil {
 		return fmt.Errorf("failed to execute down migration: %w", err)
 	}
 
@@ -264,13 +386,19 @@ func (mm *MigrationManager) MigrateDown() error {
 		return fmt.Errorf("failed to remove applied migration: %w", err)
 	}
 
-	log.Printf("Migration %d rolled back successfully", migrationDef.Version)
+	logger.Infof("Migration %d rolled back successfully", migrationDef.Version)
 	return nil
 }
 
-// applyMigration applies a single migration
-func (mm *MigrationManager) applyMigration(migration Migration) error {
-	log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+// applyMigration runs a planned migration's statements one by one
+func (mm *MigrationManager) applyMigration(migration PlannedMigration) error {
+	logger.Debugf("Applying migration %d: %s", migration.Version, migration.Name)
+	createdAt := time.Now()
+	for _, m := range mm.migrations {
+		if m.Version == migration.Version {
+			createdAt = m.CreatedAt
+		}
+	}
 
 	// Start transaction
 	tx, err := mm.db.Begin()
@@ -280,13 +408,14 @@ func (mm *MigrationManager) applyMigration(migration Migration) error {
 	defer tx.Rollback()
 
 	// Execute migration SQL
-	if _, err := tx.Exec(migration.UpSQL); err != nil {
-		return fmt.Errorf("failed to execute migration SQL: %w", err)
+	for i, statement := range migration.Statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to execute statement %d of migration SQL: %w", i+1, err)
+		}
 	}
 
 	// Record migration as applied
-	insertQuery := `INSERT INTO schema_migrations (version, name, applied_at, created_at) VALUES ($1, $2, $3, $4)`
-	_, err = tx.Exec(insertQuery, migration.Version, migration.Name, time.Now(), migration.CreatedAt)
+	_, err = tx.Exec(recordMigrationSQL, migration.Version, migration.Name, time.Now(), createdAt)
 	if err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
@@ -296,7 +425,7 @@ func (mm *MigrationManager) applyMigration(migration Migration) error {
 		return fmt.Errorf("failed to commit migration: %w", err)
 	}
 
-	log.Printf("Migration %d applied successfully", migration.Version)
+	logger.Debugf("Migration %d applied successfully", migration.Version)
 	return nil
 }
 
@@ -323,24 +452,30 @@ func (mm *MigrationManager) GetMigrationStatus() error {
 		return fmt.Errorf("failed to get pending migrations: %w", err)
 	}
 
-	log.Println("=== Migration Status ===")
-	log.Printf("Applied migrations: %d", len(applied))
+	logger.Infof("Migration status: %d applied, %d pending", len(applied), len(pending))
+	table := format.NewTable("Version", "Name", "Status", "Applied At").
+		SetAlign(0, format.AlignRight).
+		SetMaxWidth(1, 40).
+		SetColor(2, func(cell string) string {
+			if cell == "applied" {
+				return format.Green
+			}
+			return format.Yellow
+		})
 	for _, migration := range applied {
-		log.Printf("  ✓ %d - %s (applied at %v)", migration.Version, migration.Name, migration.AppliedAt)
+		table.AddRow(migration.Version, migration.Name, "applied", migration.AppliedAt.Format(time.DateTime))
 	}
-
-	log.Printf("Pending migrations: %d", len(pending))
 	for _, migration := range pending {
-		log.Printf("  ○ %d - %s", migration.Version, migration.Name)
+		table.AddRow(migration.Version, migration.Name, "pending", "-")
 	}
-	log.Println("========================")
+	table.Render(os.Stdout)
 
 	return nil
 }
 
 // ResetMigrations removes all applied migrations (dangerous!)
 func (mm *MigrationManager) ResetMigrations() error {
-	log.Println("WARNING: Resetting all migrations - this will remove all migration records!")
+	logger.Warn("Resetting all migrations - this will remove all migration records!")
 
 	query := `DELETE FROM schema_migrations`
 	_, err := mm.db.Exec(query)
@@ -348,13 +483,13 @@ func (mm *MigrationManager) ResetMigrations() error {
 		return fmt.Errorf("failed to reset migrations: %w", err)
 	}
 
-	log.Println("All migration records removed")
+	logger.Info("All migration records removed")
 	return nil
 }
 
 // ValidateMigrations validates that all migrations are properly defined
 func (mm *MigrationManager) ValidateMigrations() error {
-	log.Println("Validating migrations...")
+	logger.Info("Validating migrations...")
 
 	// Check for duplicate versions
 	versions := make(map[int]bool)
@@ -375,7 +510,7 @@ func (mm *MigrationManager) ValidateMigrations() error {
 		}
 	}
 
-	log.Printf("Validation passed: %d migrations are valid", len(mm.migrations))
+	logger.Infof("Validation passed: %d migrations are valid", len(mm.migrations))
 	return nil
 }
 
@@ -390,5 +525,5 @@ func (mm *MigrationManager) CreateCustomMigration(version int, name, upSQL, down
 	}
 
 	mm.AddMigration(migration)
-	log.Printf("Custom migration created: %d - %s", version, name)
+	logger.Infof("Custom migration created: %d - %s", version, name)
 }
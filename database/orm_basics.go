@@ -1,28 +1,74 @@
 package database
 
 import (
+	"context"
+	"database/sql/driver"
 	"fmt"
-	"log"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // GORMUser model for GORM (extends the base User struct)
 type GORMUser struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"size:100;not null" json:"name"`
-	Email     string         `gorm:"size:100;uniqueIndex;not null" json:"email"`
-	Age       int            `json:"age"`
-	Profile   Profile        `gorm:"foreignKey:UserID" json:"profile"`
-	Posts     []Post         `gorm:"foreignKey:UserID" json:"posts"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID          uint                  `gorm:"primaryKey" json:"id"`
+	Name        string                `gorm:"size:100;not null" json:"name"`
+	Email       string                `gorm:"size:100;uniqueIndex;not null" json:"email"`
+	Age         int                   `json:"age"`
+	Status      UserStatus            `gorm:"size:20;not null;default:active" json:"status"`
+	Plan        UserPlan              `gorm:"not null;default:1" json:"plan"`
+	Preferences JSON[UserPreferences] `json:"preferences"`
+	Tags        Array[string]         `json:"tags"`
+	Profile     Profile               `gorm:"foreignKey:UserID" json:"profile"`
+	Posts       []Post                `gorm:"foreignKey:UserID" json:"posts"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt        `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// UserStatus is where a GORMUser is in its lifecycle, stored as text
+type UserStatus string
+
+const (
+	UserActive    UserStatus = "active"
+	UserInvited   UserStatus = "invited"
+	UserSuspended UserStatus = "suspended"
+)
+
+var userStatuses = NewEnum("user status", UserActive, UserInvited, UserSuspended)
+
+// Value implements driver.Valuer
+func (s UserStatus) Value() (driver.Value, error) { return userStatuses.Value(s) }
+
+// Scan implements sql.Scanner
+func (s *UserStatus) Scan(src any) error { return userStatuses.Scan(s, src) }
+
+// UserPlan is what a GORMUser pays for, stored as an integer
+type UserPlan int
+
+const (
+	PlanFree UserPlan = iota + 1
+	PlanPro
+	PlanTeam
+)
+
+var userPlans = NewEnum("user plan", PlanFree, PlanPro, PlanTeam)
+
+// Value implements driver.Valuer
+func (p UserPlan) Value() (driver.Value, error) { return userPlans.Value(p) }
+
+// Scan implements sql.Scanner
+func (p *UserPlan) Scan(src any) error { return userPlans.Scan(p, src) }
+
+// UserPreferences are a GORMUser's settings, kept in one JSON column
+type UserPreferences struct {
+	Theme         string          `json:"theme,omitempty"`
+	Language      string          `json:"language,omitempty"`
+	Notifications map[string]bool `json:"notifications,omitempty"`
 }
 
 // Profile model for one-to-one relationship
@@ -58,39 +104,39 @@ func NewORMBasics(db *gorm.DB) *ORMBasics {
 // ConnectPostgreSQL connects to PostgreSQL using GORM
 func ConnectPostgreSQL(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormlogger.Default.LogMode(gormlogger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
-	log.Println("GORM PostgreSQL connection established")
+	logger.Info("GORM PostgreSQL connection established")
 	return db, nil
 }
 
 // ConnectMySQL connects to MySQL using GORM
 func ConnectMySQL(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormlogger.Default.LogMode(gormlogger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
 	}
 
-	log.Println("GORM MySQL connection established")
+	logger.Info("GORM MySQL connection established")
 	return db, nil
 }
 
 // ConnectSQLite connects to SQLite using GORM
 func ConnectSQLite(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormlogger.Default.LogMode(gormlogger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to SQLite: %w", err)
 	}
 
-	log.Println("GORM SQLite connection established")
+	logger.Info("GORM SQLite connection established")
 	return db, nil
 }
 
@@ -101,16 +147,18 @@ func (o *ORMBasics) AutoMigrate() error {
 		return fmt.Errorf("failed to migrate: %w", err)
 	}
 
-	log.Println("Database migration completed successfully")
+	logger.Info("Database migration completed successfully")
 	return nil
 }
 
 // CreateUser demonstrates GORM Create operation
 func (o *ORMBasics) CreateUser(name, email string, age int) (*GORMUser, error) {
 	user := &GORMUser{
-		Name:  name,
-		Email: email,
-		Age:   age,
+		Name:   name,
+		Email:  email,
+		Age:    age,
+		Status: UserActive,
+		Plan:   PlanFree,
 	}
 
 	result := o.db.Create(user)
@@ -118,7 +166,7 @@ func (o *ORMBasics) CreateUser(name, email string, age int) (*GORMUser, error) {
 		return nil, fmt.Errorf("failed to create user: %w", result.Error)
 	}
 
-	log.Printf("User created with ID: %d", user.ID)
+	logger.Infof("User created with ID: %d", user.ID)
 	return user, nil
 }
 
@@ -144,7 +192,7 @@ func (o *ORMBasics) GetAllUsers() ([]GORMUser, error) {
 		return nil, fmt.Errorf("failed to get users: %w", result.Error)
 	}
 
-	log.Printf("Retrieved %d users", len(users))
+	logger.Infof("Retrieved %d users", len(users))
 	return users, nil
 }
 
@@ -168,7 +216,7 @@ func (o *ORMBasics) UpdateUser(id uint, name, email string, age int) (*GORMUser,
 		return nil, fmt.Errorf("failed to update user: %w", result.Error)
 	}
 
-	log.Printf("User updated: %+v", user)
+	logger.Infof("User updated: %+v", user)
 	return &user, nil
 }
 
@@ -183,7 +231,7 @@ func (o *ORMBasics) DeleteUser(id uint) error {
 		return fmt.Errorf("user with id %d not found", id)
 	}
 
-	log.Printf("User with id %d deleted successfully", id)
+	logger.Infof("User with id %d deleted successfully", id)
 	return nil
 }
 
@@ -196,7 +244,7 @@ func (o *ORMBasics) SearchUsers(searchTerm string) ([]GORMUser, error) {
 		return nil, fmt.Errorf("failed to search users: %w", result.Error)
 	}
 
-	log.Printf("Found %d users matching '%s'", len(users), searchTerm)
+	logger.Infof("Found %d users matching '%s'", len(users), searchTerm)
 	return users, nil
 }
 
@@ -208,16 +256,18 @@ func (o *ORMBasics) GetUsersByAgeRange(minAge, maxAge int) ([]GORMUser, error) {
 		return nil, fmt.Errorf("failed to get users by age range: %w", result.Error)
 	}
 
-	log.Printf("Found %d users between ages %d and %d", len(users), minAge, maxAge)
+	logger.Infof("Found %d users between ages %d and %d", len(users), minAge, maxAge)
 	return users, nil
 }
 
 // CreateUserWithProfile demonstrates GORM associations
 func (o *ORMBasics) CreateUserWithProfile(name, email string, age int, bio, website, location string) (*GORMUser, error) {
 	user := &GORMUser{
-		Name:  name,
-		Email: email,
-		Age:   age,
+		Name:   name,
+		Email:  email,
+		Age:    age,
+		Status: UserActive,
+		Plan:   PlanFree,
 		Profile: Profile{
 			Bio:      bio,
 			Website:  website,
@@ -230,7 +280,7 @@ func (o *ORMBasics) CreateUserWithProfile(name, email string, age int, bio, webs
 		return nil, fmt.Errorf("failed to create user with profile: %w", result.Error)
 	}
 
-	log.Printf("User with profile created with ID: %d", user.ID)
+	logger.Infof("User with profile created with ID: %d", user.ID)
 	return user, nil
 }
 
@@ -262,7 +312,7 @@ func (o *ORMBasics) CreatePost(userID uint, title, content string) (*Post, error
 		return nil, fmt.Errorf("failed to create post: %w", result.Error)
 	}
 
-	log.Printf("Post created with ID: %d", post.ID)
+	logger.Infof("Post created with ID: %d", post.ID)
 	return post, nil
 }
 
@@ -288,11 +338,12 @@ func (o *ORMBasics) GetUserCount() (int64, error) {
 		return 0, fmt.Errorf("failed to count users: %w", result.Error)
 	}
 
-	log.Printf("Total users: %d", count)
+	logger.Infof("Total users: %d", count)
 	return count, nil
 }
 
-// GetUsersWithPagination demonstrates GORM Limit and Offset
+// GetUsersWithPagination demonstrates GORM Limit and Offset. Deep pages
+// get slow, as the database reads every skipped row; GetUsersPage does not.
 func (o *ORMBasics) GetUsersWithPagination(page, pageSize int) ([]GORMUser, error) {
 	var users []GORMUser
 	offset := (page - 1) * pageSize
@@ -302,10 +353,26 @@ func (o *ORMBasics) GetUsersWithPagination(page, pageSize int) ([]GORMUser, erro
 		return nil, fmt.Errorf("failed to get users with pagination: %w", result.Error)
 	}
 
-	log.Printf("Retrieved %d users for page %d", len(users), page)
+	logger.Infof("Retrieved %d users for page %d", len(users), page)
 	return users, nil
 }
 
+// GetUsersPage demonstrates keyset pagination with GORM, ordering users
+// by id
+func (o *ORMBasics) GetUsersPage(req PageRequest) (PageResult[GORMUser], error) {
+	pages := Pagination[GORMUser]{
+		Keys: []SortKey{{Column: "id"}},
+		Key:  func(u GORMUser) []any { return []any{u.ID} },
+	}
+	page, err := pages.GORM(context.Background(), o.db, req)
+	if err != nil {
+		return page, fmt.Errorf("failed to get users page: %w", err)
+	}
+
+	logger.Infof("Retrieved %d users, more: %t", len(page.Items), page.HasMore)
+	return page, nil
+}
+
 // SoftDeleteUser demonstrates GORM soft delete
 func (o *ORMBasics) SoftDeleteUser(id uint) error {
 	result := o.db.Delete(&GORMUser{}, id)
@@ -317,7 +384,7 @@ func (o *ORMBasics) SoftDeleteUser(id uint) error {
 		return fmt.Errorf("user with id %d not found", id)
 	}
 
-	log.Printf("User with id %d soft deleted successfully", id)
+	logger.Infof("User with id %d soft deleted successfully", id)
 	return nil
 }
 
@@ -329,7 +396,7 @@ func (o *ORMBasics) GetDeletedUsers() ([]GORMUser, error) {
 		return nil, fmt.Errorf("failed to get deleted users: %w", result.Error)
 	}
 
-	log.Printf("Found %d deleted users", len(users))
+	logger.Infof("Found %d deleted users", len(users))
 	return users, nil
 }
 
@@ -348,6 +415,6 @@ func (o *ORMBasics) CleanupDatabase() error {
 		return fmt.Errorf("failed to cleanup users: %w", err)
 	}
 
-	log.Println("Database cleaned up successfully")
+	logger.Info("Database cleaned up successfully")
 	return nil
 }
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jerrychou/go-practice/queue"
+)
+
+// HeaderOutboxID carries the outbox row ID so consumers can drop duplicates
+const HeaderOutboxID = "x-outbox-id"
+
+// OutboxMessage is an event stored in the outbox table
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// EnqueueOutbox records an event in the same transaction as the change it
+// describes, so the event is published if and only if tx commits
+func EnqueueOutbox(ctx context.Context, tx *sql.Tx, topic string, payload []byte) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO outbox (topic, payload) VALUES ($1, $2)`, topic, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	return nil
+}
+
+// OutboxDispatcher publishes outbox rows to a queue in ID order. Delivery is
+// at-least-once: a row published just before a crash is published again.
+// Run a single dispatcher per database.
+type OutboxDispatcher struct {
+	db        *sql.DB
+	producer  queue.Producer
+	BatchSize int
+	Interval  time.Duration
+}
+
+// NewOutboxDispatcher creates a dispatcher that polls every second
+func NewOutboxDispatcher(db *sql.DB, producer queue.Producer) *OutboxDispatcher {
+	return &OutboxDispatcher{db: db, producer: producer, BatchSize: 100, Interval: time.Second}
+}
+
+// DispatchOnce publishes up to BatchSize pending rows and returns how many
+// were published. It stops at the first failure to preserve ordering.
+func (d *OutboxDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	pending, err := d.pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range pending {
+		headers := map[string]string{HeaderOutboxID: strconv.FormatInt(msg.ID, 10)}
+		if _, err := d.producer.Publish(ctx, msg.Topic, msg.Payload, headers); err != nil {
+			return i, fmt.Errorf("failed to publish outbox message %d: %w", msg.ID, err)
+		}
+		_, err := d.db.ExecContext(ctx, `UPDATE outbox SET published_at = $1 WHERE id = $2`, time.Now(), msg.ID)
+		if err != nil {
+			return i, fmt.Errorf("failed to mark outbox message %d published: %w", msg.ID, err)
+		}
+	}
+	return len(pending), nil
+}
+
+func (d *OutboxDispatcher) pending(ctx context.Context) ([]OutboxMessage, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, topic, payload, created_at FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1`, d.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var payload string
+		if err := rows.Scan(&msg.ID, &msg.Topic, &payload, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		msg.Payload = []byte(payload)
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Run dispatches until ctx is cancelled, draining full batches immediately
+// and otherwise waiting Interval between polls
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	logger.Infof("Outbox dispatcher started (interval %v)", d.Interval)
+	for {
+		n, err := d.DispatchOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Outbox dispatch failed", "error", err)
+		}
+		if n == d.BatchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("Outbox dispatcher stopped")
+			return nil
+		case <-time.After(d.Interval):
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for a cursor that does not hold a value for
+// each sort key. Cursors are not signed; they only carry a position.
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// SortKey is a column rows are ordered by
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// CountMode says when a page also counts every row, which costs a second
+// query over the whole result
+type CountMode int
+
+const (
+	// CountNone leaves PageResult.Total nil
+	CountNone CountMode = iota
+	// CountFirstPage counts only for the first page; clients keep the total
+	CountFirstPage
+	// CountAlways counts for every page
+	CountAlways
+)
+
+// PageRequest asks for one page. Without Page it is a keyset request: the
+// first page when Cursor and After are empty, otherwise the rows following
+// the position they give.
+type PageRequest struct {
+	Limit int
+	// Cursor is the NextCursor of the previous page, as handed to clients
+	Cursor string
+	// After holds the sort key values of the last row seen, for callers
+	// that walk the rows themselves instead of passing cursors around
+	After []any
+	// Page, counted from 1, switches to OFFSET paging, which rereads every
+	// skipped row and so slows down on deep pages
+	Page  int
+	Count CountMode
+}
+
+// PageResult is one page of rows
+type PageResult[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor continues after the last item; empty on the last page and
+	// when paging by offset
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Total      *int64 `json:"total,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+}
+
+// Pagination pages through rows ordered by Keys. Keyset paging continues
+// with a WHERE on the sort keys of the last row instead of an OFFSET, so
+// every page costs the same, and rows inserted meanwhile neither repeat nor
+// go missing. The last key must be unique, such as the primary key, and no
+// key may be NULL.
+type Pagination[T any] struct {
+	Keys []SortKey
+	// Key returns a row's values for Keys, in order
+	Key func(row T) []any
+	// DefaultLimit is used when a request has none, 20 if zero; MaxLimit
+	// caps requests, 100 if zero
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Query pages through query, a SELECT without ORDER BY or LIMIT, run with
+// args on db. It is wrapped as a subquery, so its columns must include the
+// sort keys. scan reads one row.
+func (p Pagination[T]) Query(ctx context.Context, db DBTX, dialect, query string, args []any, req PageRequest, scan func(*sql.Rows) (T, error)) (PageResult[T], error) {
+	result := PageResult[T]{Items: []T{}}
+	pageQuery, pageArgs, err := p.Build(dialect, query, args, req)
+	if err != nil {
+		return result, err
+	}
+	rows, err := db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return result, fmt.Errorf("failed to query page: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan row: %w", err)
+		}
+		result.Items = append(result.Items, row)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if p.counts(req) {
+		var total int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") AS page_source", args...).Scan(&total); err != nil {
+			return result, fmt.Errorf("failed to count rows: %w", err)
+		}
+		result.Total = &total
+	}
+	return p.finish(result, req)
+}
+
+// Build returns the statement Query runs: query wrapped with the keyset
+// WHERE, ORDER BY and LIMIT for dialect ("postgres", "sqlite3" or "mysql"),
+// and its arguments. One row more than the limit is fetched to tell whether
+// another page follows.
+func (p Pagination[T]) Build(dialect, query string, args []any, req PageRequest) (string, []any, error) {
+	quote, placeholder := quoteDouble, dollarPlaceholder
+	switch dialect {
+	case "postgres", "sqlite3":
+	case "mysql":
+		quote, placeholder = quoteBacktick, questionPlaceholder
+	default:
+		return "", nil, fmt.Errorf("pagination: unsupported dialect %q", dialect)
+	}
+	where, whereArgs, err := p.keysetWhere(req, quote)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM (" + query + ") AS page_source")
+	if where != "" {
+		// Number the ? of the condition after the query's own arguments
+		sb.WriteString(" WHERE ")
+		for i, part := range strings.Split(where, "?") {
+			if i > 0 {
+				sb.WriteString(placeholder(len(args) + i))
+			}
+			sb.WriteString(part)
+		}
+		args = append(args[:len(args):len(args)], whereArgs...)
+	}
+	sb.WriteString(" ORDER BY " + p.orderBy(quote))
+	limit := p.limit(req)
+	fmt.Fprintf(&sb, " LIMIT %d", limit+1)
+	if req.Page > 0 {
+		fmt.Fprintf(&sb, " OFFSET %d", (req.Page-1)*limit)
+	}
+	return sb.String(), args, nil
+}
+
+// GORM pages through the rows db selects, such as o.db or
+// o.db.Where("age > ?", 30), as T unless Model or Table says otherwise
+func (p Pagination[T]) GORM(ctx context.Context, db *gorm.DB, req PageRequest) (PageResult[T], error) {
+	result := PageResult[T]{Items: []T{}}
+	quote := func(name string) string { return name }
+	where, whereArgs, err := p.keysetWhere(req, quote)
+	if err != nil {
+		return result, err
+	}
+	db = db.WithContext(ctx)
+	if db.Statement.Model == nil && db.Statement.Table == "" {
+		db = db.Model(new(T))
+	}
+
+	if p.counts(req) {
+		var total int64
+		if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return result, fmt.Errorf("failed to count rows: %w", err)
+		}
+		result.Total = &total
+	}
+
+	query := db.Session(&gorm.Session{})
+	if where != "" {
+		query = query.Where(where, whereArgs...)
+	}
+	limit := p.limit(req)
+	query = query.Order(p.orderBy(quote)).Limit(limit + 1)
+	if req.Page > 0 {
+		query = query.Offset((req.Page - 1) * limit)
+	}
+	if err := query.Find(&result.Items).Error; err != nil {
+		return result, fmt.Errorf("failed to query page: %w", err)
+	}
+	return p.finish(result, req)
+}
+
+// Slice pages through rows already sorted by Keys, such as a small table
+// kept in memory
+func (p Pagination[T]) Slice(rows []T, req PageRequest) (PageResult[T], error) {
+	result := PageResult[T]{Items: []T{}}
+	after, err := p.position(req)
+	if err != nil {
+		return result, err
+	}
+	start := 0
+	limit := p.limit(req)
+	switch {
+	case req.Page > 0:
+		start = min((req.Page-1)*limit, len(rows))
+	case after != nil:
+		start = len(rows)
+		for i, row := range rows {
+			if p.compare(p.Key(row), after) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+limit+1, len(rows))
+	result.Items = append(result.Items, rows[start:end]...)
+	if p.counts(req) {
+		total := int64(len(rows))
+		result.Total = &total
+	}
+	return p.finish(result, req)
+}
+
+// Cursor encodes row's position as an opaque string for NextCursor
+func (p Pagination[T]) Cursor(row T) string {
+	values := p.Key(row)
+	encoded := make([]any, len(values))
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			// Tagged so that it decodes back to a time.Time, which drivers
+			// bind as a timestamp rather than a string
+			encoded[i] = map[string]string{"t": t.Format(time.RFC3339Nano)}
+		} else {
+			encoded[i] = v
+		}
+	}
+	data, _ := json.Marshal(encoded)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the sort key values held in cursor
+func (p Pagination[T]) DecodeCursor(cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw []any
+	if err := dec.Decode(&raw); err != nil || len(raw) != len(p.Keys) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, len(raw))
+	for i, v := range raw {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case map[string]any:
+			s, _ := v["t"].(string)
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			values[i] = t
+		case string, bool:
+			values[i] = v
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// position returns the key values to continue after, nil for a first page
+func (p Pagination[T]) position(req PageRequest) ([]any, error) {
+	if len(p.Keys) == 0 || p.Key == nil {
+		return nil, errors.New("pagination: Keys and Key are required")
+	}
+	switch {
+	case req.Page > 0:
+		return nil, nil
+	case req.Cursor != "":
+		return p.DecodeCursor(req.Cursor)
+	case req.After != nil:
+		if len(req.After) != len(p.Keys) {
+			return nil, fmt.Errorf("pagination: After has %d values for %d keys", len(req.After), len(p.Keys))
+		}
+		return req.After, nil
+	}
+	return nil, nil
+}
+
+// keysetWhere expands the position into a condition with ? placeholders,
+// spelled out rather than as a row comparison so that keys may sort in
+// different directions:
+//
+//	(a > ?) OR (a = ? AND b > ?)
+func (p Pagination[T]) keysetWhere(req PageRequest, quote func(string) string) (string, []any, error) {
+	after, err := p.position(req)
+	if err != nil || after == nil {
+		return "", nil, err
+	}
+	var ors []string
+	var args []any
+	for i, key := range p.Keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, quote(p.Keys[j].Column)+" = ?")
+			args = append(args, after[j])
+		}
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, quote(key.Column)+op)
+		args = append(args, after[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args, nil
+}
+
+func (p Pagination[T]) orderBy(quote func(string) string) string {
+	parts := make([]string, len(p.Keys))
+	for i, key := range p.Keys {
+		parts[i] = quote(key.Column)
+		if key.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p Pagination[T]) limit(req PageRequest) int {
+	maxLimit := p.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = p.DefaultLimit
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return min(limit, maxLimit)
+}
+
+func (p Pagination[T]) counts(req PageRequest) bool {
+	switch req.Count {
+	case CountAlways:
+		return true
+	case CountFirstPage:
+		return req.Cursor == "" && req.After == nil && req.Page <= 1
+	}
+	return false
+}
+
+// finish drops the extra row fetched to detect another page and sets the
+// cursor for it
+func (p Pagination[T]) finish(result PageResult[T], req PageRequest) (PageResult[T], error) {
+	result.Limit = p.limit(req)
+	if req.Page > 0 {
+		result.Page = req.Page
+	}
+	if len(result.Items) > result.Limit {
+		result.Items = result.Items[:result.Limit]
+		result.HasMore = true
+		if req.Page == 0 {
+			result.NextCursor = p.Cursor(result.Items[len(result.Items)-1])
+		}
+	}
+	return result, nil
+}
+
+// compare orders the key values a and b as the Keys sort them
+func (p Pagination[T]) compare(a, b []any) int {
+	for i, key := range p.Keys {
+		c := compareValues(a[i], b[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders numbers, times and, by their text, anything else;
+// the numbers decoded from a cursor are int64 or float64 whatever the row
+// holds
+func compareValues(a, b any) int {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jerrychou/go-practice/security"
+)
+
+// PasswordResetStore keeps the tokens of a security.PasswordResetService
+// in the password_reset_tokens table, so reset links survive restarts and
+// every instance enforces the same request limits
+type PasswordResetStore struct {
+	db *sql.DB
+}
+
+// NewPasswordResetStore creates a reset token store on a migrated database
+func NewPasswordResetStore(db *sql.DB) *PasswordResetStore {
+	return &PasswordResetStore{db: db}
+}
+
+// SaveResetToken implements security.ResetStore. Times are stored in UTC
+// so they compare correctly on databases that keep timestamps as text. The
+// count and the insert share a serializable transaction: PostgreSQL aborts
+// one of two racing requests and SQLite lets only one write, so the loser
+// fails instead of going past the limit.
+func (s *PasswordResetStore) SaveResetToken(ctx context.Context, record security.ResetRecord, since time.Time, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM password_reset_tokens
+		WHERE subject = $1 AND created_at >= $2`, record.Subject, since.UTC()).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count reset tokens: %w", err)
+	}
+	if limit > 0 && n >= limit {
+		return n, security.ErrResetRateLimited
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = $1
+		WHERE subject = $2 AND used_at IS NULL`,
+		record.CreatedAt.UTC(), record.Subject); err != nil {
+		return n, fmt.Errorf("failed to replace reset tokens: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (selector, verifier_hash, subject, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		record.Selector, record.VerifierHash, record.Subject, record.CreatedAt.UTC(), record.ExpiresAt.UTC()); err != nil {
+		return n, fmt.Errorf("failed to save reset token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return n, fmt.Errorf("failed to save reset token: %w", err)
+	}
+	return n, nil
+}
+
+// FindResetToken implements security.ResetStore
+func (s *PasswordResetStore) FindResetToken(ctx context.Context, selector string) (security.ResetRecord, error) {
+	record := security.ResetRecord{Selector: selector}
+	var usedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT verifier_hash, subject, created_at, expires_at, used_at, attempts
+		FROM password_reset_tokens WHERE selector = $1`, selector).
+		Scan(&record.VerifierHash, &record.Subject, &record.CreatedAt, &record.ExpiresAt, &usedAt, &record.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, security.ErrInvalidResetToken
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to find reset token: %w", err)
+	}
+	record.UsedAt = usedAt.Time
+	return record, nil
+}
+
+// UseResetToken implements security.ResetStore; the used_at condition
+// lets only one of two concurrent resets through
+func (s *PasswordResetStore) UseResetToken(ctx context.Context, selector string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = $1
+		WHERE selector = $2 AND used_at IS NULL`, at.UTC(), selector)
+	if err != nil {
+		return fmt.Errorf("failed to use reset token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to use reset token: %w", err)
+	} else if n == 0 {
+		return security.ErrInvalidResetToken
+	}
+	return nil
+}
+
+// RecordResetFailure implements security.ResetStore
+func (s *PasswordResetStore) RecordResetFailure(ctx context.Context, selector string) (int, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET attempts = attempts + 1 WHERE selector = $1`, selector); err != nil {
+		return 0, fmt.Errorf("failed to record reset failure: %w", err)
+	}
+	var attempts int
+	err := s.db.QueryRowContext(ctx, `SELECT attempts FROM password_reset_tokens WHERE selector = $1`, selector).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, security.ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to record reset failure: %w", err)
+	}
+	return attempts, nil
+}
+
+// PurgeResetTokens implements security.ResetStore
+func (s *PasswordResetStore) PurgeResetTokens(ctx context.Context, before, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM password_reset_tokens
+		WHERE created_at < $1 AND (used_at IS NOT NULL OR expires_at < $2)`, before.UTC(), now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge reset tokens: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,535 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryProfile is one statement run on a profiled database
+type QueryProfile struct {
+	ID       uint64        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Query    string        `json:"query"`
+	Duration time.Duration `json:"duration"`
+	// Rows is how many rows a query returned, or a statement affected
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
+	Slow  bool   `json:"slow,omitempty"`
+	// Plan is the EXPLAIN output of a slow query, filled in shortly after
+	// it is recorded
+	Plan string `json:"plan,omitempty"`
+}
+
+// ProfilerOptions configures a QueryProfiler
+type ProfilerOptions struct {
+	// Capacity is how many statements are kept, the oldest giving way; 200
+	// if zero
+	Capacity int
+	// SlowThreshold marks statements that take longer as slow; zero marks
+	// none
+	SlowThreshold time.Duration
+	// Explain runs EXPLAIN for slow SELECT queries; other statements, WITH
+	// queries included, may write and are left alone
+	Explain bool
+	// Analyze runs EXPLAIN ANALYZE instead, which executes the query a
+	// second time to measure it. SQLite has no ANALYZE and falls back to
+	// EXPLAIN QUERY PLAN.
+	Analyze bool
+}
+
+// QueryProfiler records the duration and row count of every statement run
+// on the databases it opens, keeping the latest in a ring buffer. It is
+// meant for development: it sees every query, and with Explain on runs
+// more of its own.
+type QueryProfiler struct {
+	opts ProfilerOptions
+
+	mu     sync.Mutex
+	ring   []QueryProfile
+	next   int
+	lastID uint64
+
+	db      *sql.DB
+	dialect string
+	// pending queries are explained one at a time by a goroutine that
+	// exits once it has none left
+	pending    []explainJob
+	explaining bool
+}
+
+type explainJob struct {
+	id    uint64
+	query string
+	args  []driver.NamedValue
+}
+
+// maxPendingExplains bounds the EXPLAIN queue; slow queries beyond it go
+// without a plan
+const maxPendingExplains = 32
+
+// skipProfileKey marks the profiler's own EXPLAIN queries
+type skipProfileKey struct{}
+
+// NewQueryProfiler creates a profiler; open databases with Open
+func NewQueryProfiler(opts ProfilerOptions) *QueryProfiler {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 200
+	}
+	return &QueryProfiler{opts: opts}
+}
+
+// Open is sql.Open with every statement on the returned database profiled.
+// A profiler explains the queries of one database, the last opened.
+func (p *QueryProfiler) Open(driverName, dsn string) (*sql.DB, error) {
+	plain, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := plain.Driver()
+	plain.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	db := sql.OpenDB(&profiledConnector{Connector: connector, p: p})
+	p.mu.Lock()
+	p.db, p.dialect = db, dialectOf(driverName)
+	p.mu.Unlock()
+	return db, nil
+}
+
+// Recent returns up to n statements, newest first
+func (p *QueryProfiler) Recent(n int) []QueryProfile {
+	all := p.snapshot()
+	slices.Reverse(all)
+	return all[:min(n, len(all))]
+}
+
+// Slowest returns up to n of the kept statements, slowest first
+func (p *QueryProfiler) Slowest(n int) []QueryProfile {
+	all := p.snapshot()
+	slices.SortStableFunc(all, func(a, b QueryProfile) int { return cmp.Compare(b.Duration, a.Duration) })
+	return all[:min(n, len(all))]
+}
+
+// Reset forgets every recorded statement
+func (p *QueryProfiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ring, p.next = nil, 0
+}
+
+// snapshot returns the kept statements, oldest first
+func (p *QueryProfiler) snapshot() []QueryProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.ring) < p.opts.Capacity {
+		return slices.Clone(p.ring)
+	}
+	return append(slices.Clone(p.ring[p.next:]), p.ring[:p.next]...)
+}
+
+func (p *QueryProfiler) record(query string, args []driver.NamedValue, start time.Time, duration time.Duration, rows int64, err error) {
+	profile := QueryProfile{
+		Time:     start,
+		Query:    strings.Join(strings.Fields(query), " "),
+		Duration: duration,
+		Rows:     rows,
+		Slow:     p.opts.SlowThreshold > 0 && duration >= p.opts.SlowThreshold,
+	}
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		profile.Error = err.Error()
+	}
+
+	p.mu.Lock()
+	p.lastID++
+	profile.ID = p.lastID
+	if len(p.ring) < p.opts.Capacity {
+		p.ring = append(p.ring, profile)
+	} else {
+		p.ring[p.next] = profile
+		p.next = (p.next + 1) % p.opts.Capacity
+	}
+	if profile.Slow && err == nil && p.opts.Explain && p.db != nil && isSelect(query) && len(p.pending) < maxPendingExplains {
+		p.pending = append(p.pending, explainJob{id: profile.ID, query: query, args: args})
+		if !p.explaining {
+			p.explaining = true
+			go p.explainPending()
+		}
+	}
+	p.mu.Unlock()
+}
+
+func (p *QueryProfiler) explainPending() {
+	for {
+		p.mu.Lock()
+		if len(p.pending) == 0 {
+			p.explaining = false
+			p.mu.Unlock()
+			return
+		}
+		job := p.pending[0]
+		p.pending = p.pending[1:]
+		db, dialect := p.db, p.dialect
+		p.mu.Unlock()
+		p.explain(db, dialect, job)
+	}
+}
+
+// explain runs EXPLAIN for a recorded query and stores the plan with it
+func (p *QueryProfiler) explain(db *sql.DB, dialect string, job explainJob) {
+	prefix := "EXPLAIN "
+	switch {
+	case dialect == "sqlite3":
+		prefix = "EXPLAIN QUERY PLAN "
+	case p.opts.Analyze:
+		prefix = "EXPLAIN ANALYZE "
+	}
+	args := make([]any, len(job.args))
+	for i, arg := range job.args {
+		args[i] = arg.Value
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), skipProfileKey{}, true), 10*time.Second)
+	defer cancel()
+	plan, err := queryPlan(ctx, db, prefix+job.query, args)
+	if err != nil {
+		plan = "EXPLAIN failed: " + err.Error()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.ring {
+		if p.ring[i].ID == job.id {
+			p.ring[i].Plan = plan
+		}
+	}
+}
+
+// queryPlan renders EXPLAIN output as lines: the single column of
+// PostgreSQL and EXPLAIN ANALYZE, SQLite's detail column, or the
+// name=value pairs of MySQL's table
+func queryPlan(ctx context.Context, db *sql.DB, query string, args []any) (string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	detail := slices.Index(columns, "detail")
+
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		switch {
+		case len(columns) == 1:
+			lines = append(lines, values[0].String)
+		case detail >= 0:
+			lines = append(lines, values[detail].String)
+		default:
+			var pairs []string
+			for i, column := range columns {
+				if values[i].Valid {
+					pairs = append(pairs, column+"="+values[i].String)
+				}
+			}
+			lines = append(lines, strings.Join(pairs, " "))
+		}
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+func isSelect(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "SELECT")
+}
+
+func dialectOf(driverName string) string {
+	switch driverName {
+	case "postgres", "pgx":
+		return "postgres"
+	case "sqlite", "sqlite3":
+		return "sqlite3"
+	}
+	return driverName
+}
+
+// dsnConnector opens connections of drivers without OpenConnector
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                       { return c.driver }
+
+type profiledConnector struct {
+	driver.Connector
+	p *QueryProfiler
+}
+
+func (c *profiledConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &profiledConn{Conn: conn, p: c.p}, nil
+}
+
+// profiledConn times the statements of a driver connection, passing on
+// the optional interfaces the driver implements and answering
+// driver.ErrSkip, which makes database/sql fall back, for the rest
+type profiledConn struct {
+	driver.Conn
+	p *QueryProfiler
+}
+
+func (c *profiledConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if ctx.Value(skipProfileKey{}) != nil {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		if err != driver.ErrSkip {
+			c.p.record(query, args, start, time.Since(start), 0, err)
+		}
+		return nil, err
+	}
+	return &profiledRows{Rows: rows, p: c.p, query: query, args: args, start: start, elapsed: time.Since(start)}, nil
+}
+
+func (c *profiledConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.p.record(query, args, start, time.Since(start), rowsAffected(result, err), err)
+	}
+	return result, err
+}
+
+func (c *profiledConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profiledStmt{Stmt: stmt, p: c.p, query: query}, nil
+}
+
+func (c *profiledConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *profiledConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *profiledConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *profiledConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *profiledConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *profiledConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type profiledStmt struct {
+	driver.Stmt
+	p     *QueryProfiler
+	query string
+}
+
+func (s *profiledStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValues(args))
+	}
+	s.p.record(s.query, args, start, time.Since(start), rowsAffected(result, err), err)
+	return result, err
+}
+
+func (s *profiledStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	if err != nil {
+		s.p.record(s.query, args, start, time.Since(start), 0, err)
+		return nil, err
+	}
+	if ctx.Value(skipProfileKey{}) != nil {
+		return rows, nil
+	}
+	return &profiledRows{Rows: rows, p: s.p, query: s.query, args: args, start: start, elapsed: time.Since(start)}, nil
+}
+
+func (s *profiledStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// profiledRows counts rows and adds the time spent fetching them, but not
+// the caller's time between them, to the query's duration; the query is
+// recorded on Close
+type profiledRows struct {
+	driver.Rows
+	p       *QueryProfiler
+	query   string
+	args    []driver.NamedValue
+	start   time.Time
+	elapsed time.Duration
+	count   int64
+	err     error
+}
+
+func (r *profiledRows) Next(dest []driver.Value) error {
+	start := time.Now()
+	err := r.Rows.Next(dest)
+	r.elapsed += time.Since(start)
+	switch {
+	case err == nil:
+		r.count++
+	case err != io.EOF:
+		r.err = err
+	}
+	return err
+}
+
+func (r *profiledRows) Close() error {
+	err := r.Rows.Close()
+	r.p.record(r.query, r.args, r.start, r.elapsed, r.count, r.err)
+	return err
+}
+
+func (r *profiledRows) HasNextResultSet() bool {
+	if multi, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return multi.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *profiledRows) NextResultSet() error {
+	if multi, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return multi.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *profiledRows) ColumnTypeScanType(index int) reflect.Type {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typed.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *profiledRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typed.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *profiledRows) ColumnTypeLength(index int) (int64, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return typed.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *profiledRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return typed.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *profiledRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return typed.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func rowsAffected(result driver.Result, err error) int64 {
+	if err != nil || result == nil {
+		return 0
+	}
+	n, _ := result.RowsAffected()
+	return n
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
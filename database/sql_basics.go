@@ -1,9 +1,9 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"time"
 )
 
@@ -16,13 +16,25 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// DBTX is the query surface shared by *sql.DB and *sql.Tx, so code written
+// against it runs the same inside or outside a transaction
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // SQLBasics demonstrates basic SQL operations
 type SQLBasics struct {
-	db *sql.DB
+	db DBTX
 }
 
-// NewSQLBasics creates a new SQLBasics instance
-func NewSQLBasics(db *sql.DB) *SQLBasics {
+// NewSQLBasics creates a new SQLBasics instance on a database or a
+// transaction
+func NewSQLBasics(db DBTX) *SQLBasics {
 	return &SQLBasics{db: db}
 }
 
@@ -42,7 +54,7 @@ func (s *SQLBasics) CreateTable() error {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
 
-	log.Println("Table 'users' created successfully")
+	logger.Info("Table 'users' created successfully")
 	return nil
 }
 
@@ -61,7 +73,32 @@ func (s *SQLBasics) InsertUser(name, email string, age int) (*User, error) {
 		return nil, fmt.Errorf("failed to insert user: %w", err)
 	}
 
-	log.Printf("User inserted: %+v", user)
+	logger.Infof("User inserted: %+v", user)
+	return &user, nil
+}
+
+// UpsertUser inserts a user, or updates the name and age of the user that
+// already has the email. The statement is valid for PostgreSQL and SQLite.
+func (s *SQLBasics) UpsertUser(name, email string, age int) (*User, error) {
+	query, args, err := Upsert{
+		Table:           "users",
+		Columns:         []string{"name", "email", "age"},
+		ConflictColumns: []string{"email"},
+		Returning:       []string{"id", "name", "email", "age", "created_at"},
+	}.Build("postgres", []any{name, email, age})
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	err = s.db.QueryRow(query, args...).Scan(
+		&user.ID, &user.Name, &user.Email, &user.Age, &user.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert user: %w", err)
+	}
+
+	logger.Infof("User upserted: %+v", user)
 	return &user, nil
 }
 
@@ -107,10 +144,34 @@ func (s *SQLBasics) GetAllUsers() ([]User, error) {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	log.Printf("Retrieved %d users", len(users))
+	logger.Infof("Retrieved %d users", len(users))
 	return users, nil
 }
 
+// userPages orders users from the oldest, and by id among those of an age
+var userPages = Pagination[User]{
+	Keys: []SortKey{{Column: "age", Desc: true}, {Column: "id"}},
+	Key:  func(u User) []any { return []any{u.Age, u.ID} },
+}
+
+// GetUsersPage demonstrates keyset pagination: pass the NextCursor of a
+// page to get the next one
+func (s *SQLBasics) GetUsersPage(req PageRequest) (PageResult[User], error) {
+	page, err := userPages.Query(context.Background(), s.db, "postgres",
+		`SELECT id, name, email, age, created_at FROM users`, nil, req,
+		func(rows *sql.Rows) (User, error) {
+			var user User
+			err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Age, &user.CreatedAt)
+			return user, err
+		})
+	if err != nil {
+		return page, fmt.Errorf("failed to get users page: %w", err)
+	}
+
+	logger.Infof("Retrieved %d users, more: %t", len(page.Items), page.HasMore)
+	return page, nil
+}
+
 // UpdateUser demonstrates UPDATE operation
 func (s *SQLBasics) UpdateUser(id int, name, email string, age int) (*User, error) {
 	query := `
@@ -130,7 +191,7 @@ func (s *SQLBasics) UpdateUser(id int, name, email string, age int) (*User, erro
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
-	log.Printf("User updated: %+v", user)
+	logger.Infof("User updated: %+v", user)
 	return &user, nil
 }
 
@@ -152,7 +213,7 @@ func (s *SQLBasics) DeleteUser(id int) error {
 		return fmt.Errorf("user with id %d not found", id)
 	}
 
-	log.Printf("User with id %d deleted successfully", id)
+	logger.Infof("User with id %d deleted successfully", id)
 	return nil
 }
 
@@ -185,7 +246,7 @@ func (s *SQLBasics) SearchUsers(searchTerm string) ([]User, error) {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	log.Printf("Found %d users matching '%s'", len(users), searchTerm)
+	logger.Infof("Found %d users matching '%s'", len(users), searchTerm)
 	return users, nil
 }
 
@@ -199,7 +260,7 @@ func (s *SQLBasics) GetUserCount() (int, error) {
 		return 0, fmt.Errorf("failed to get user count: %w", err)
 	}
 
-	log.Printf("Total users: %d", count)
+	logger.Infof("Total users: %d", count)
 	return count, nil
 }
 
@@ -231,7 +292,7 @@ func (s *SQLBasics) GetUsersByAgeRange(minAge, maxAge int) ([]User, error) {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	log.Printf("Found %d users between ages %d and %d", len(users), minAge, maxAge)
+	logger.Infof("Found %d users between ages %d and %d", len(users), minAge, maxAge)
 	return users, nil
 }
 
@@ -244,6 +305,6 @@ func (s *SQLBasics) CleanupTable() error {
 		return fmt.Errorf("failed to cleanup table: %w", err)
 	}
 
-	log.Println("Table cleaned up successfully")
+	logger.Info("Table cleaned up successfully")
 	return nil
 }
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jerrychou/go-practice/tracing"
+)
+
+// TracedDB wraps a *sql.DB so each query becomes a client span in the
+// caller's trace. Only the *Context methods are traced, since the others
+// have no trace to join.
+type TracedDB struct {
+	*sql.DB
+	system string // db.system attribute, e.g. "sqlite" or "postgresql"
+}
+
+// NewTracedDB wraps db; system names the database product
+func NewTracedDB(db *sql.DB, system string) *TracedDB {
+	return &TracedDB{DB: db, system: system}
+}
+
+// QueryContext runs a query inside a span
+func (t *TracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	rows, err := t.DB.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query inside a span. The span ends
+// before Scan, so scan errors are not recorded on it.
+func (t *TracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	row := t.DB.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != sql.ErrNoRows {
+		span.RecordError(err)
+	}
+	return row
+}
+
+// ExecContext runs a statement inside a span, recording rows affected
+func (t *TracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	result, err := t.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		span.SetAttributes("db.rows_affected", n)
+	}
+	return result, nil
+}
+
+// BeginTx starts a transaction whose statements are traced as children of
+// a "db.transaction" span, ended by Commit or Rollback
+func (t *TracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*TracedTx, error) {
+	ctx, span := tracing.Start(ctx, "db.transaction", tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes("db.system", t.system))
+	tx, err := t.DB.BeginTx(ctx, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	return &TracedTx{Tx: tx, db: t, span: span}, nil
+}
+
+// start names the span after the SQL verb, keeping the full statement
+// (with placeholders, never arguments) as an attribute
+func (t *TracedDB) start(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	name := "db.query"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		name = "db." + strings.ToLower(fields[0])
+	}
+	return tracing.Start(ctx, name, tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes("db.system", t.system, "db.statement", strings.Join(strings.Fields(query), " ")))
+}
+
+// TracedTx is a transaction started by TracedDB.BeginTx
+type TracedTx struct {
+	*sql.Tx
+	db   *TracedDB
+	span *tracing.Span
+}
+
+// QueryContext runs a query inside a span under the transaction's span
+func (t *TracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.db.start(t.parent(ctx), query)
+	defer span.End()
+	rows, err := t.Tx.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query inside a span under the
+// transaction's span
+func (t *TracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.db.start(t.parent(ctx), query)
+	defer span.End()
+	row := t.Tx.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != sql.ErrNoRows {
+		span.RecordError(err)
+	}
+	return row
+}
+
+// ExecContext runs a statement inside a span under the transaction's span
+func (t *TracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.db.start(t.parent(ctx), query)
+	defer span.End()
+	result, err := t.Tx.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return result, err
+}
+
+// Commit commits and ends the transaction span
+func (t *TracedTx) Commit() error {
+	err := t.Tx.Commit()
+	t.span.RecordError(err)
+	t.span.End()
+	return err
+}
+
+// Rollback rolls back and ends the transaction span. Rolling back after
+// Commit is a no-op, so it is safe to defer.
+func (t *TracedTx) Rollback() error {
+	err := t.Tx.Rollback()
+	if err != sql.ErrTxDone {
+		t.span.AddEvent("rollback")
+		t.span.RecordError(err)
+	}
+	t.span.End()
+	return err
+}
+
+// parent makes statements children of the transaction span while keeping
+// ctx's deadline and cancellation
+func (t *TracedTx) parent(ctx context.Context) context.Context {
+	return tracing.ContextWithSpan(ctx, t.span)
+}
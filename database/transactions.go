@@ -3,8 +3,8 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 )
 
@@ -99,7 +99,7 @@ func (tm *TransactionManager) TransferMoney(fromAccountID, toAccountID int, amou
 			return fmt.Errorf("failed to record transaction: %w", err)
 		}
 
-		log.Printf("Transfer successful: $%.2f from account %d to account %d", amount, fromAccountID, toAccountID)
+		logger.Infof("Transfer successful: $%.2f from account %d to account %d", amount, fromAccountID, toAccountID)
 		return nil
 	}, opts)
 }
@@ -140,13 +140,45 @@ func (tm *TransactionManager) CreateUserWithProfile(name, email string, age int,
 		}
 		createdProfile = &profile
 
-		log.Printf("User and profile created successfully: User ID %d, Profile ID %d", user.ID, profile.ID)
+		logger.Infof("User and profile created successfully: User ID %d, Profile ID %d", user.ID, profile.ID)
 		return nil
 	}, opts)
 
 	return createdUser, createdProfile, err
 }
 
+// CreateUserWithEvent creates a user and records a "user.created" outbox
+// event in the same transaction
+func (tm *TransactionManager) CreateUserWithEvent(name, email string, age int) (*User, error) {
+	var createdUser *User
+
+	err := tm.ExecuteTransaction(func(tx *sql.Tx) error {
+		userQuery := `
+			INSERT INTO users (name, email, age, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, name, email, age, created_at`
+
+		var user User
+		err := tx.QueryRow(userQuery, name, email, age, time.Now(), time.Now()).Scan(
+			&user.ID, &user.Name, &user.Email, &user.Age, &user.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		payload, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		if err := EnqueueOutbox(context.Background(), tx, "user.created", payload); err != nil {
+			return err
+		}
+		createdUser = &user
+		return nil
+	}, GetDefaultTransactionOptions())
+
+	return createdUser, err
+}
+
 // BatchInsertUsers demonstrates batch operations in a transaction
 func (tm *TransactionManager) BatchInsertUsers(users []User) error {
 	opts := GetDefaultTransactionOptions()
@@ -167,7 +199,7 @@ func (tm *TransactionManager) BatchInsertUsers(users []User) error {
 			}
 		}
 
-		log.Printf("Batch inserted %d users successfully", len(users))
+		logger.Infof("Batch inserted %d users successfully", len(users))
 		return nil
 	}, opts)
 }
@@ -198,7 +230,7 @@ func (tm *TransactionManager) UpdateUserWithPosts(userID int, newName, newEmail
 			}
 		}
 
-		log.Printf("Updated user %d and added %d posts", userID, len(postsToAdd))
+		logger.Infof("Updated user %d and added %d posts", userID, len(postsToAdd))
 		return nil
 	}, opts)
 }
@@ -235,7 +267,7 @@ func (tm *TransactionManager) DeleteUserCascade(userID int) error {
 			return fmt.Errorf("user with id %d not found", userID)
 		}
 
-		log.Printf("User %d and all related records deleted successfully", userID)
+		logger.Infof("User %d and all related records deleted successfully", userID)
 		return nil
 	}, opts)
 }
@@ -277,7 +309,7 @@ func (tm *TransactionManager) NestedTransaction() error {
 	opts := GetDefaultTransactionOptions()
 
 	return tm.ExecuteTransaction(func(outerTx *sql.Tx) error {
-		log.Println("Outer transaction started")
+		logger.Info("Outer transaction started")
 
 		// Create a user in outer transaction
 		_, err := outerTx.Exec(`
@@ -289,12 +321,12 @@ func (tm *TransactionManager) NestedTransaction() error {
 		}
 
 		// Simulate nested transaction (in real scenario, this would be a savepoint)
-		log.Println("Simulating nested transaction...")
+		logger.Info("Simulating nested transaction...")
 
 		// This would typically use savepoints in a real implementation
 		// For demonstration, we'll use a separate transaction
 		return tm.ExecuteTransaction(func(innerTx *sql.Tx) error {
-			log.Println("Inner transaction started")
+			logger.Info("Inner transaction started")
 
 			// Create a user in inner transaction
 			_, err := innerTx.Exec(`
@@ -305,7 +337,7 @@ func (tm *TransactionManager) NestedTransaction() error {
 				return fmt.Errorf("failed to create inner user: %w", err)
 			}
 
-			log.Println("Inner transaction completed")
+			logger.Info("Inner transaction completed")
 			return nil
 		}, opts)
 	}, opts)
@@ -320,12 +352,12 @@ func (tm *TransactionManager) TransactionWithRetry(fn func(*sql.Tx) error, maxRe
 
 		err := tm.ExecuteTransaction(fn, opts)
 		if err == nil {
-			log.Printf("Transaction succeeded on attempt %d", i+1)
+			logger.Infof("Transaction succeeded on attempt %d", i+1)
 			return nil
 		}
 
 		lastErr = err
-		log.Printf("Transaction attempt %d failed: %v", i+1, err)
+		logger.Warnf("Transaction attempt %d failed: %v", i+1, err)
 
 		if i < maxRetries-1 {
 			time.Sleep(retryDelay)
@@ -353,7 +385,7 @@ func (tm *TransactionManager) SetTransactionIsolationLevel(level string) error {
 		return fmt.Errorf("failed to set isolation level: %w", err)
 	}
 
-	log.Printf("Transaction isolation level set to: %s", level)
+	logger.Infof("Transaction isolation level set to: %s", level)
 	return nil
 }
 
@@ -398,10 +430,10 @@ func (tb *TransactionBenchmark) BenchmarkConcurrentTransactions(numTransactions
 	duration := time.Since(start)
 	avgTime := duration / time.Duration(numTransactions)
 
-	log.Printf("Transaction benchmark completed: %d transactions, avg time: %v", numTransactions, avgTime)
+	logger.Infof("Transaction benchmark completed: %d transactions, avg time: %v", numTransactions, avgTime)
 
 	if len(errors) > 0 {
-		log.Printf("Errors occurred: %d", len(errors))
+		logger.Errorf("Errors occurred: %d", len(errors))
 		return fmt.Errorf("benchmark completed with %d errors", len(errors))
 	}
 
@@ -0,0 +1,377 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidEnum is returned when a value is not one of an enum's constants,
+// whether it is being written or was read from a column
+var ErrInvalidEnum = errors.New("database: invalid enum value")
+
+// EnumKind is what an enum may be declared as
+type EnumKind interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint8 | ~uint16 | ~uint32
+}
+
+// Enum holds the constants of a Go enum so the enum type can be stored in a
+// column and read back with two one-line methods:
+//
+//	var userStatuses = database.NewEnum("user status", UserActive, UserSuspended)
+//
+//	func (s UserStatus) Value() (driver.Value, error) { return userStatuses.Value(s) }
+//	func (s *UserStatus) Scan(src any) error          { return userStatuses.Scan(s, src) }
+//
+// String-backed enums are stored as text and int-backed ones as integers.
+// Values that are not constants are refused both ways with ErrInvalidEnum.
+type Enum[T EnumKind] struct {
+	name   string
+	values []T
+	valid  map[T]bool
+}
+
+// NewEnum returns an Enum of values; name is used in errors
+func NewEnum[T EnumKind](name string, values ...T) *Enum[T] {
+	e := &Enum[T]{name: name, values: values, valid: make(map[T]bool, len(values))}
+	for _, v := range values {
+		e.valid[v] = true
+	}
+	return e
+}
+
+// Values returns the constants in the order they were given
+func (e *Enum[T]) Values() []T {
+	return append([]T(nil), e.values...)
+}
+
+// Valid reports whether v is one of the constants
+func (e *Enum[T]) Valid(v T) bool {
+	return e.valid[v]
+}
+
+// Parse returns the constant written as s, such as "active" or "2"
+func (e *Enum[T]) Parse(s string) (T, error) {
+	var v T
+	if err := e.Scan(&v, s); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Value returns v as a driver value, for the enum type's Value method
+func (e *Enum[T]) Value(v T) (driver.Value, error) {
+	if !e.valid[v] {
+		return nil, fmt.Errorf("%w: %v is not a %s", ErrInvalidEnum, v, e.name)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil
+	default:
+		return rv.Int(), nil
+	}
+}
+
+// Scan sets dst from a column value, for the enum type's Scan method. A
+// NULL sets the zero value.
+func (e *Enum[T]) Scan(dst *T, src any) error {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	switch src := src.(type) {
+	case nil:
+		*dst = v
+		return nil
+	case []byte:
+		return e.Scan(dst, string(src))
+	case string:
+		switch rv.Kind() {
+		case reflect.String:
+			rv.SetString(src)
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			n, err := strconv.ParseUint(strings.TrimSpace(src), 10, rv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("%w: %q is not a %s", ErrInvalidEnum, src, e.name)
+			}
+			rv.SetUint(n)
+		default:
+			n, err := strconv.ParseInt(strings.TrimSpace(src), 10, rv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("%w: %q is not a %s", ErrInvalidEnum, src, e.name)
+			}
+			rv.SetInt(n)
+		}
+	case int64:
+		switch rv.Kind() {
+		case reflect.String:
+			return fmt.Errorf("%w: %d is not a %s", ErrInvalidEnum, src, e.name)
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			if src < 0 || rv.OverflowUint(uint64(src)) {
+				return fmt.Errorf("%w: %d is not a %s", ErrInvalidEnum, src, e.name)
+			}
+			rv.SetUint(uint64(src))
+		default:
+			if rv.OverflowInt(src) {
+				return fmt.Errorf("%w: %d is not a %s", ErrInvalidEnum, src, e.name)
+			}
+			rv.SetInt(src)
+		}
+	default:
+		return fmt.Errorf("cannot scan %T into %s", src, e.name)
+	}
+
+	if !e.valid[v] {
+		return fmt.Errorf("%w: %v is not a %s", ErrInvalidEnum, v, e.name)
+	}
+	*dst = v
+	return nil
+}
+
+// JSON stores any value as a JSON document: JSONB on PostgreSQL and JSON on
+// MySQL and SQLite. It encodes to JSON as the value itself, so models can
+// be returned from handlers as they are. A NULL column reads as the zero
+// value.
+type JSON[T any] struct {
+	Data T
+}
+
+// NewJSON wraps data for a JSON column
+func NewJSON[T any](data T) JSON[T] {
+	return JSON[T]{Data: data}
+}
+
+// Value implements driver.Valuer
+func (j JSON[T]) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding JSON column: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (j *JSON[T]) Scan(src any) error {
+	var data T
+	switch src := src.(type) {
+	case nil:
+	case []byte:
+		if err := json.Unmarshal(src, &data); err != nil {
+			return fmt.Errorf("decoding JSON column: %w", err)
+		}
+	case string:
+		if err := json.Unmarshal([]byte(src), &data); err != nil {
+			return fmt.Errorf("decoding JSON column: %w", err)
+		}
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON column", src)
+	}
+	j.Data = data
+	return nil
+}
+
+// MarshalJSON encodes the wrapped value
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Data)
+}
+
+// UnmarshalJSON decodes into the wrapped value
+func (j *JSON[T]) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &j.Data)
+}
+
+// HeldValue lets reflect.MapStruct map the wrapped value, so a DTO field of
+// type T or a map fills a JSON[T] field and the other way around
+func (j *JSON[T]) HeldValue() any {
+	return &j.Data
+}
+
+// GormDataType tells GORM the general type of the column
+func (JSON[T]) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType picks the column type for the dialect AutoMigrate creates
+// the table in
+func (JSON[T]) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+	return "JSON"
+}
+
+// ArrayElement is what an Array may hold
+type ArrayElement interface {
+	~string | ~int | ~int32 | ~int64 | ~float64 | ~bool
+}
+
+// Array stores a slice in a PostgreSQL array column, such as text[] or
+// bigint[]. Other databases get a text column holding the same array
+// literal, e.g. {go,"hello, world"}, which keeps the model portable but
+// cannot be queried with array operators. NULL elements are refused.
+type Array[T ArrayElement] []T
+
+// Value implements driver.Valuer
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.String:
+			b.WriteByte('"')
+			b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(rv.String()))
+			b.WriteByte('"')
+		case reflect.Float64:
+			b.WriteString(strconv.FormatFloat(rv.Float(), 'g', -1, 64))
+		case reflect.Bool:
+			b.WriteString(strconv.FormatBool(rv.Bool()))
+		default:
+			b.WriteString(strconv.FormatInt(rv.Int(), 10))
+		}
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Scan implements sql.Scanner
+func (a *Array[T]) Scan(src any) error {
+	var literal string
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		literal = string(src)
+	case string:
+		literal = src
+	default:
+		return fmt.Errorf("cannot scan %T into an array column", src)
+	}
+
+	elements, err := parseArrayLiteral(literal)
+	if err != nil {
+		return err
+	}
+	out := make(Array[T], len(elements))
+	for i, element := range elements {
+		rv := reflect.ValueOf(&out[i]).Elem()
+		switch rv.Kind() {
+		case reflect.String:
+			rv.SetString(element)
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(element, 64)
+			if err != nil {
+				return fmt.Errorf("array element %d: %w", i, err)
+			}
+			rv.SetFloat(f)
+		case reflect.Bool:
+			// PostgreSQL writes booleans as t and f
+			switch element {
+			case "t", "true":
+				rv.SetBool(true)
+			case "f", "false":
+			default:
+				return fmt.Errorf("array element %d: %q is not a boolean", i, element)
+			}
+		default:
+			n, err := strconv.ParseInt(element, 10, rv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("array element %d: %w", i, err)
+			}
+			rv.SetInt(n)
+		}
+	}
+	*a = out
+	return nil
+}
+
+// GormDataType tells GORM the general type of the column
+func (Array[T]) GormDataType() string {
+	return "array"
+}
+
+// GormDBDataType picks the column type for the dialect AutoMigrate creates
+// the table in
+func (Array[T]) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() != "postgres" {
+		return "TEXT"
+	}
+	var zero T
+	switch reflect.TypeOf(zero).Kind() {
+	case reflect.String:
+		return "text[]"
+	case reflect.Float64:
+		return "double precision[]"
+	case reflect.Bool:
+		return "boolean[]"
+	case reflect.Int32:
+		return "integer[]"
+	default:
+		return "bigint[]"
+	}
+}
+
+// parseArrayLiteral splits a one-dimensional PostgreSQL array literal such
+// as {a,"b,c",d} into its unquoted elements
+func parseArrayLiteral(literal string) ([]string, error) {
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal %q", literal)
+	}
+	body := literal[1 : len(literal)-1]
+	if body == "" {
+		return []string{}, nil
+	}
+
+	var elements []string
+	for i := 0; i <= len(body); {
+		var element strings.Builder
+		quoted := i < len(body) && body[i] == '"'
+		if quoted {
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' {
+					i++
+				}
+				if i < len(body) {
+					element.WriteByte(body[i])
+				}
+			}
+			if i >= len(body) {
+				return nil, fmt.Errorf("invalid array literal %q: unterminated quote", literal)
+			}
+			i++
+		} else {
+			for ; i < len(body) && body[i] != ','; i++ {
+				if body[i] == '{' {
+					return nil, fmt.Errorf("invalid array literal %q: nested arrays are not supported", literal)
+				}
+				element.WriteByte(body[i])
+			}
+			if strings.EqualFold(element.String(), "NULL") {
+				return nil, fmt.Errorf("invalid array literal %q: NULL elements are not supported", literal)
+			}
+		}
+		elements = append(elements, element.String())
+
+		if i < len(body) && body[i] != ',' {
+			return nil, fmt.Errorf("invalid array literal %q", literal)
+		}
+		i++
+	}
+	return elements, nil
+}
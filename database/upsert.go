@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Upsert builds an INSERT that updates the existing row instead of failing
+// when it clashes with a unique key, replacing a SELECT to check for the row
+// followed by an INSERT or UPDATE, which races with concurrent writers:
+//
+//	postgres, sqlite3: INSERT ... ON CONFLICT (keys) DO UPDATE SET c = excluded.c
+//	mysql:             INSERT ... ON DUPLICATE KEY UPDATE c = VALUES(c)
+type Upsert struct {
+	Table   string
+	Columns []string
+	// ConflictColumns name the unique key that turns the insert into an
+	// update. MySQL does not take a target and reacts to any unique key.
+	ConflictColumns []string
+	// UpdateColumns are overwritten with the inserted values on a conflict.
+	// Nil means every column outside ConflictColumns; an empty slice keeps
+	// the existing row as it is (DO NOTHING).
+	UpdateColumns []string
+	// Returning lists columns to return from the inserted or updated row.
+	// MySQL has no RETURNING clause.
+	Returning []string
+}
+
+// Build returns the statement for dialect ("postgres", "sqlite3" or
+// "mysql") inserting rows, each holding a value per column, and the
+// flattened arguments
+func (u Upsert) Build(dialect string, rows ...[]any) (string, []any, error) {
+	if u.Table == "" || len(u.Columns) == 0 {
+		return "", nil, errors.New("upsert: table and columns are required")
+	}
+	if len(rows) == 0 {
+		return "", nil, errors.New("upsert: no rows")
+	}
+	quote, placeholder := quoteDouble, dollarPlaceholder
+	switch dialect {
+	case "postgres", "sqlite3":
+		if len(u.ConflictColumns) == 0 {
+			return "", nil, fmt.Errorf("upsert: %s needs conflict columns", dialect)
+		}
+	case "mysql":
+		quote, placeholder = quoteBacktick, questionPlaceholder
+		if len(u.Returning) > 0 {
+			return "", nil, errors.New("upsert: mysql does not support RETURNING")
+		}
+	default:
+		return "", nil, fmt.Errorf("upsert: unsupported dialect %q", dialect)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES ", quote(u.Table), quoteList(u.Columns, quote))
+	args := make([]any, 0, len(rows)*len(u.Columns))
+	for i, row := range rows {
+		if len(row) != len(u.Columns) {
+			return "", nil, fmt.Errorf("upsert: row %d has %d values for %d columns", i, len(row), len(u.Columns))
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			args = append(args, value)
+			sb.WriteString(placeholder(len(args)))
+		}
+		sb.WriteByte(')')
+	}
+
+	updates := u.UpdateColumns
+	if updates == nil {
+		for _, column := range u.Columns {
+			if !slices.Contains(u.ConflictColumns, column) {
+				updates = append(updates, column)
+			}
+		}
+	}
+
+	if dialect == "mysql" {
+		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
+		if len(updates) == 0 {
+			// Assigning a column to itself leaves the row unchanged, unlike
+			// INSERT IGNORE, which also swallows unrelated errors
+			column := quote(u.Columns[0])
+			sb.WriteString(column + " = " + column)
+		}
+		for i, column := range updates {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s = VALUES(%s)", quote(column), quote(column))
+		}
+		return sb.String(), args, nil
+	}
+
+	fmt.Fprintf(&sb, " ON CONFLICT (%s) ", quoteList(u.ConflictColumns, quote))
+	if len(updates) == 0 {
+		sb.WriteString("DO NOTHING")
+	} else {
+		sb.WriteString("DO UPDATE SET ")
+		for i, column := range updates {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s = excluded.%s", quote(column), quote(column))
+		}
+	}
+	if len(u.Returning) > 0 {
+		sb.WriteString(" RETURNING " + quoteList(u.Returning, quote))
+	}
+	return sb.String(), args, nil
+}
+
+func quoteDouble(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteBacktick(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteList(names []string, quote func(string) string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func dollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func questionPlaceholder(int) string {
+	return "?"
+}
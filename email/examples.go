@@ -0,0 +1,220 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// A 1x1 transparent PNG standing in for a logo
+var logoPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae,
+	0x42, 0x60, 0x82,
+}
+
+// MessageBuilderExamples builds a message with every kind of part
+func MessageBuilderExamples() {
+	fmt.Println("\n=== Building MIME Messages ===")
+	msg, err := NewMessage().
+		From("Go Practice <noreply@example.com>").
+		To("Ann Lee <ann@example.com>").
+		Cc("team@example.com").
+		Bcc("audit@example.com").
+		Subject("Your report is ready ✅").
+		Text("Hi Ann,\n\nThe monthly report is attached.").
+		HTML(`<p>Hi Ann,</p><p><img src="cid:logo.png" alt="logo"> The monthly report is attached.</p>`).
+		Embed("logo.png", logoPNG).
+		Attach("report.csv", []byte("month,orders\n2024-01,42\n")).
+		Build()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Printf("📬 envelope recipients (Bcc included): %v\n", msg.Recipients())
+
+	// Parse the message back to show its headers and MIME structure
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg.Bytes()))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Println("📄 message outline:")
+	for _, key := range []string{"From", "To", "Cc", "Subject"} {
+		value, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get(key))
+		fmt.Printf("   %s: %s\n", key, value)
+	}
+	printMIMETree(parsed.Header.Get("Content-Type"), textproto.MIMEHeader{}, parsed.Body, 1)
+
+	_, err = NewMessage().From("not an address").Subject("Hi\r\nBcc: victim@example.com").Build()
+	fmt.Printf("🚫 invalid message rejected:\n   %v\n", strings.ReplaceAll(err.Error(), "\n", "\n   "))
+}
+
+// printMIMETree prints one line per part, descending into multiparts
+func printMIMETree(contentType string, header textproto.MIMEHeader, body io.Reader, depth int) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	line := mediaType
+	if disposition := header.Get("Content-Disposition"); disposition != "" {
+		line += " (" + disposition + ")"
+	}
+	fmt.Printf("   %s%s\n", strings.Repeat("  ", depth-1), line)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return
+	}
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			return
+		}
+		printMIMETree(part.Header.Get("Content-Type"), part.Header, part, depth+1)
+	}
+}
+
+// TemplateExamples renders text and HTML bodies from templates
+func TemplateExamples() {
+	fmt.Println("\n=== Template Bodies ===")
+	html := htmltemplate.Must(htmltemplate.New("order.html").Parse(
+		`<p>Thanks, {{.Name}}! Order <b>#{{.Order}}</b> ships {{.When}}.</p>`))
+	text := texttemplate.Must(texttemplate.New("order.txt").Parse(
+		"Thanks, {{.Name}}! Order #{{.Order}} ships {{.When}}."))
+	data := map[string]any{"Name": "Ann <script>alert(1)</script>", "Order": 1042, "When": "tomorrow"}
+
+	msg, err := NewMessage().
+		From("shop@example.com").
+		To("ann@example.com").
+		Subject("Order confirmed").
+		RenderText(text, "order.txt", data).
+		RenderHTML(html, "order.html", data).
+		Build()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Printf("📝 text: %s\n", msg.Text)
+	fmt.Printf("🌐 html: %s\n", msg.HTML)
+
+	_, err = NewMessage().From("shop@example.com").To("ann@example.com").
+		RenderHTML(html, "missing.html", data).Build()
+	fmt.Printf("🚫 %v\n", err)
+}
+
+// SMTPExamples sends a message to a local SMTP server
+func SMTPExamples() {
+	fmt.Println("\n=== Sending over SMTP ===")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer ln.Close()
+	transcript := make(chan []string, 1)
+	go serveSMTP(ln, transcript)
+
+	// A real server would use SecurityStartTLS or SecurityTLS; PlainAuth
+	// allows clear text only because the server is on localhost
+	port := ln.Addr().(*net.TCPAddr).Port
+	transport := NewSMTPTransport("127.0.0.1", port, "demo", "secret")
+	transport.Security = SecurityNone
+
+	msg, _ := NewMessage().From("noreply@example.com").To("ann@example.com", "bob@example.com").
+		Subject("Hello over SMTP").Text("It works!").Build()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := transport.Send(ctx, msg); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Println("📡 SMTP conversation as seen by the server:")
+	for _, line := range <-transcript {
+		fmt.Println("   " + line)
+	}
+
+	transport.Security = SecurityStartTLS
+	go serveSMTP(ln, transcript)
+	err = transport.Send(ctx, msg)
+	fmt.Printf("🔒 STARTTLS required but not offered: %v\n", err)
+}
+
+// serveSMTP accepts one connection and plays a minimal SMTP server,
+// reporting the commands it received
+func serveSMTP(ln net.Listener, transcript chan<- []string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	var commands []string
+	defer func() { transcript <- commands }()
+
+	tp.PrintfLine("220 localhost ESMTP demo")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		if verb == "AUTH" {
+			commands = append(commands, "AUTH PLAIN ****")
+		} else {
+			commands = append(commands, line)
+		}
+		switch verb {
+		case "EHLO":
+			tp.PrintfLine("250-localhost\r\n250-AUTH PLAIN\r\n250 8BITMIME")
+		case "AUTH":
+			tp.PrintfLine("235 2.7.0 Authentication successful")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, _ := io.ReadAll(tp.DotReader())
+			headers, _ := textproto.NewReader(bufio.NewReader(strings.NewReader(string(data)))).ReadMIMEHeader()
+			commands = append(commands, fmt.Sprintf("  (%d bytes, Subject: %s)", len(data), headers.Get("Subject")))
+			tp.PrintfLine("250 2.0.0 Ok: queued")
+		case "QUIT":
+			tp.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			tp.PrintfLine("250 2.0.0 Ok")
+		}
+	}
+}
+
+// MockTransportExamples records messages instead of sending them
+func MockTransportExamples() {
+	fmt.Println("\n=== Mock Transport for Tests ===")
+	mock := NewMockTransport()
+	var transport Transport = mock
+
+	for _, to := range []string{"ann@example.com", "bob@example.com"} {
+		msg, _ := NewMessage().From("noreply@example.com").To(to).Subject("Weekly digest").Text("...").Build()
+		transport.Send(context.Background(), msg)
+	}
+	fmt.Printf("📥 captured %d messages, last to %v\n", len(mock.Messages()), mock.Last().Recipients())
+
+	mock.Err = errors.New("550 mailbox unavailable")
+	msg, _ := NewMessage().From("noreply@example.com").To("gone@example.com").Build()
+	fmt.Printf("🧪 simulated failure: %v\n", transport.Send(context.Background(), msg))
+}
+
+// RunAllEmailExamples runs all email examples
+func RunAllEmailExamples() {
+	MessageBuilderExamples()
+	TemplateExamples()
+	SMTPExamples()
+	MockTransportExamples()
+}
@@ -0,0 +1,442 @@
+// Package email builds MIME messages with text and HTML alternatives,
+// attachments and inline images, renders bodies from templates, and sends
+// them over SMTP or to an in-memory transport for tests.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+var logger = format.GetLogger("email")
+
+// Attachment is a file sent with a message. Inline attachments are images
+// referenced from the HTML body as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	Inline      bool
+	ContentID   string
+}
+
+// Message is a validated email ready to send
+type Message struct {
+	From        mail.Address
+	To, Cc, Bcc []mail.Address
+	ReplyTo     *mail.Address
+	Subject     string
+	Headers     map[string]string // extra headers such as List-Unsubscribe
+	Text, HTML  string
+	Attachments []Attachment
+	Date        time.Time
+	MessageID   string
+}
+
+// Recipients returns the envelope recipients, including Bcc
+func (m *Message) Recipients() []string {
+	var out []string
+	for _, list := range [][]mail.Address{m.To, m.Cc, m.Bcc} {
+		for _, addr := range list {
+			out = append(out, addr.Address)
+		}
+	}
+	return out
+}
+
+// Bytes renders the message in RFC 5322 format
+func (m *Message) Bytes() []byte {
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo writes the headers and MIME body. Bcc is never written.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	root := m.body()
+
+	h := textproto.MIMEHeader{}
+	h.Set("From", m.From.String())
+	if len(m.To) > 0 {
+		h.Set("To", joinAddresses(m.To))
+	}
+	if len(m.Cc) > 0 {
+		h.Set("Cc", joinAddresses(m.Cc))
+	}
+	if m.ReplyTo != nil {
+		h.Set("Reply-To", m.ReplyTo.String())
+	}
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	h.Set("Date", m.Date.Format(time.RFC1123Z))
+	h.Set("Message-ID", m.MessageID)
+	h.Set("MIME-Version", "1.0")
+	for k, v := range m.Headers {
+		h.Set(k, mime.QEncoding.Encode("utf-8", v))
+	}
+	for k, v := range root.header() {
+		h[k] = v
+	}
+	writeHeader(cw, h)
+	err := root.writeBody(cw)
+	return cw.n, err
+}
+
+// body arranges the parts as mixed(related(alternative(text, html),
+// inline...), attachments...), leaving out any level with one child
+func (m *Message) body() mimePart {
+	var alternatives []mimePart
+	if m.Text != "" || m.HTML == "" {
+		alternatives = append(alternatives, textPart("text/plain", m.Text))
+	}
+	if m.HTML != "" {
+		alternatives = append(alternatives, textPart("text/html", m.HTML))
+	}
+
+	var inline, attached []mimePart
+	for _, a := range m.Attachments {
+		if a.Inline {
+			inline = append(inline, attachmentPart(a))
+		} else {
+			attached = append(attached, attachmentPart(a))
+		}
+	}
+
+	content := multipartOf("alternative", alternatives)
+	if len(inline) > 0 {
+		content = multipartOf("related", append([]mimePart{content}, inline...))
+	}
+	if len(attached) > 0 {
+		content = multipartOf("mixed", append([]mimePart{content}, attached...))
+	}
+	return content
+}
+
+func joinAddresses(addrs []mail.Address) string {
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeHeader(w io.Writer, h textproto.MIMEHeader) {
+	for _, k := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", k, v)
+		}
+	}
+	io.WriteString(w, "\r\n")
+}
+
+// mimePart is a leaf or multipart node of the message body
+type mimePart interface {
+	header() textproto.MIMEHeader
+	writeBody(w io.Writer) error
+}
+
+type leafPart struct {
+	h    textproto.MIMEHeader
+	data []byte
+	// base64 for binary data, quoted-printable for text
+	base64 bool
+}
+
+func textPart(contentType, body string) *leafPart {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType+"; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return &leafPart{h: h, data: []byte(body)}
+}
+
+func attachmentPart(a Attachment) *leafPart {
+	h := textproto.MIMEHeader{}
+	disposition := "attachment"
+	if a.Inline {
+		disposition = "inline"
+		h.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	// The type may carry parameters of its own, such as a charset
+	mediaType, params, err := mime.ParseMediaType(a.ContentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = a.Filename
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	h.Set("Content-Transfer-Encoding", "base64")
+	return &leafPart{h: h, data: a.Data, base64: true}
+}
+
+func (p *leafPart) header() textproto.MIMEHeader { return p.h }
+
+func (p *leafPart) writeBody(w io.Writer) error {
+	if !p.base64 {
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(p.data); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+	// RFC 2045 limits encoded lines to 76 characters
+	encoded := base64.StdEncoding.EncodeToString(p.data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+type multipartPart struct {
+	subtype  string
+	boundary string
+	parts    []mimePart
+}
+
+// multipartOf wraps parts, or returns the only part unwrapped
+func multipartOf(subtype string, parts []mimePart) mimePart {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return &multipartPart{subtype: subtype, boundary: string_op.HexToken(16), parts: parts}
+}
+
+func (p *multipartPart) header() textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType("multipart/"+p.subtype, map[string]string{"boundary": p.boundary}))
+	return h
+}
+
+func (p *multipartPart) writeBody(w io.Writer) error {
+	for _, part := range p.parts {
+		fmt.Fprintf(w, "--%s\r\n", p.boundary)
+		writeHeader(w, part.header())
+		if err := part.writeBody(w); err != nil {
+			return err
+		}
+		io.WriteString(w, "\r\n")
+	}
+	_, err := fmt.Fprintf(w, "--%s--\r\n", p.boundary)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Renderer executes a named template; *html/template.Template,
+// *text/template.Template and server.Renderer all satisfy it
+type Renderer interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// MessageBuilder assembles a Message. Errors, such as an invalid address
+// or a failing template, are collected and returned by Build, so calls can
+// be chained.
+type MessageBuilder struct {
+	msg  Message
+	errs []error
+}
+
+// NewMessage starts an empty message
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{msg: Message{Headers: make(map[string]string)}}
+}
+
+// From sets the sender, e.g. "Go Practice <noreply@example.com>"
+func (b *MessageBuilder) From(address string) *MessageBuilder {
+	if addr := b.parse("From", address); addr != nil {
+		b.msg.From = *addr
+	}
+	return b
+}
+
+// To adds recipients
+func (b *MessageBuilder) To(addresses ...string) *MessageBuilder {
+	b.msg.To = b.appendAll("To", b.msg.To, addresses)
+	return b
+}
+
+// Cc adds copied recipients
+func (b *MessageBuilder) Cc(addresses ...string) *MessageBuilder {
+	b.msg.Cc = b.appendAll("Cc", b.msg.Cc, addresses)
+	return b
+}
+
+// Bcc adds hidden recipients, which get the message but are not listed in it
+func (b *MessageBuilder) Bcc(addresses ...string) *MessageBuilder {
+	b.msg.Bcc = b.appendAll("Bcc", b.msg.Bcc, addresses)
+	return b
+}
+
+// ReplyTo sets where replies go
+func (b *MessageBuilder) ReplyTo(address string) *MessageBuilder {
+	b.msg.ReplyTo = b.parse("Reply-To", address)
+	return b
+}
+
+// Subject sets the subject line; non-ASCII text is encoded
+func (b *MessageBuilder) Subject(subject string) *MessageBuilder {
+	b.msg.Subject = subject
+	return b
+}
+
+// Header sets an extra header
+func (b *MessageBuilder) Header(key, value string) *MessageBuilder {
+	b.msg.Headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+	return b
+}
+
+// Text sets the plain text body
+func (b *MessageBuilder) Text(body string) *MessageBuilder {
+	b.msg.Text = body
+	return b
+}
+
+// HTML sets the HTML body; clients that cannot show it use the text body
+func (b *MessageBuilder) HTML(body string) *MessageBuilder {
+	b.msg.HTML = body
+	return b
+}
+
+// RenderText sets the text body from a template
+func (b *MessageBuilder) RenderText(r Renderer, name string, data any) *MessageBuilder {
+	if body, ok := b.render(r, name, data); ok {
+		b.msg.Text = body
+	}
+	return b
+}
+
+// RenderHTML sets the HTML body from a template, which should be an
+// html/template so data is escaped
+func (b *MessageBuilder) RenderHTML(r Renderer, name string, data any) *MessageBuilder {
+	if body, ok := b.render(r, name, data); ok {
+		b.msg.HTML = body
+	}
+	return b
+}
+
+// Attach adds a file; the content type is guessed from the extension
+func (b *MessageBuilder) Attach(filename string, data []byte) *MessageBuilder {
+	b.msg.Attachments = append(b.msg.Attachments, Attachment{
+		Filename:    filepath.Base(filename),
+		ContentType: contentType(filename),
+		Data:        data,
+	})
+	return b
+}
+
+// AttachFile reads and attaches the file at path
+func (b *MessageBuilder) AttachFile(path string) *MessageBuilder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("attach %s: %w", path, err))
+		return b
+	}
+	return b.Attach(path, data)
+}
+
+// Embed adds an inline image that the HTML body shows with
+// <img src="cid:filename">
+func (b *MessageBuilder) Embed(filename string, data []byte) *MessageBuilder {
+	name := filepath.Base(filename)
+	b.msg.Attachments = append(b.msg.Attachments, Attachment{
+		Filename:    name,
+		ContentType: contentType(filename),
+		Data:        data,
+		Inline:      true,
+		ContentID:   name,
+	})
+	return b
+}
+
+// Build validates the message and stamps its Date and Message-ID
+func (b *MessageBuilder) Build() (*Message, error) {
+	errs := slices.Clone(b.errs)
+	if b.msg.From.Address == "" {
+		errs = append(errs, errors.New("missing From address"))
+	}
+	if len(b.msg.To)+len(b.msg.Cc)+len(b.msg.Bcc) == 0 {
+		errs = append(errs, errors.New("no recipients"))
+	}
+	// CR or LF in a header would let user input inject extra headers
+	if strings.ContainsAny(b.msg.Subject, "\r\n") {
+		errs = append(errs, errors.New("subject contains a line break"))
+	}
+	for k, v := range b.msg.Headers {
+		if strings.ContainsAny(k+v, "\r\n") {
+			errs = append(errs, fmt.Errorf("header %s contains a line break", k))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+
+	msg := b.msg
+	msg.Headers = maps.Clone(b.msg.Headers)
+	msg.Attachments = slices.Clone(b.msg.Attachments)
+	msg.Date = time.Now()
+	_, domain, _ := strings.Cut(msg.From.Address, "@")
+	msg.MessageID = "<" + string_op.NewUUIDv4().String() + "@" + domain + ">"
+	return &msg, nil
+}
+
+func (b *MessageBuilder) parse(field, address string) *mail.Address {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("%s %q: %w", field, address, err))
+		return nil
+	}
+	return addr
+}
+
+func (b *MessageBuilder) appendAll(field string, list []mail.Address, addresses []string) []mail.Address {
+	for _, address := range addresses {
+		if addr := b.parse(field, address); addr != nil {
+			list = append(list, *addr)
+		}
+	}
+	return list
+}
+
+func (b *MessageBuilder) render(r Renderer, name string, data any) (string, bool) {
+	var buf strings.Builder
+	if err := r.ExecuteTemplate(&buf, name, data); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("render %s: %w", name, err))
+		return "", false
+	}
+	return buf.String(), true
+}
+
+func contentType(filename string) string {
+	if t := mime.TypeByExtension(filepath.Ext(filename)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,232 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport delivers messages
+type Transport interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Security selects how an SMTP connection is encrypted
+type Security int
+
+const (
+	// SecurityStartTLS upgrades a plain connection and fails if the server
+	// does not offer STARTTLS; the usual mode on port 587
+	SecurityStartTLS Security = iota
+	// SecurityTLS connects with TLS from the start, usually on port 465
+	SecurityTLS
+	// SecurityNone sends in clear text, only for local test servers such
+	// as MailHog
+	SecurityNone
+)
+
+// SMTPTransport sends each message over a new SMTP connection
+type SMTPTransport struct {
+	Host      string
+	Port      int
+	Username  string // no AUTH when empty
+	Password  string
+	Security  Security
+	TLSConfig *tls.Config // defaults to verifying Host
+	LocalName string      // sent with EHLO, defaults to "localhost"
+	Timeout   time.Duration
+}
+
+// NewSMTPTransport creates a transport using implicit TLS on port 465
+// and STARTTLS otherwise
+func NewSMTPTransport(host string, port int, username, password string) *SMTPTransport {
+	security := SecurityStartTLS
+	if port == 465 {
+		security = SecurityTLS
+	}
+	return &SMTPTransport{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		Security: security,
+		Timeout:  30 * time.Second,
+	}
+}
+
+// Send delivers msg, aborting when ctx is done
+func (t *SMTPTransport) Send(ctx context.Context, msg *Message) error {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	dialer := &net.Dialer{Timeout: t.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("email: dial %s: %w", addr, err)
+	}
+	if t.Security == SecurityTLS {
+		conn = tls.Client(conn, t.tlsConfig())
+	}
+	if t.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(t.Timeout))
+	}
+	// net/smtp has no context support, so closing the connection is how
+	// cancellation interrupts a blocked read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := t.send(conn, msg); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("email: send via %s: %w", addr, err)
+	}
+	logger.Info("Email sent", "to", msg.Recipients(), "subject", msg.Subject, "server", addr)
+	return nil
+}
+
+func (t *SMTPTransport) send(conn net.Conn, msg *Message) error {
+	c, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	localName := t.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+	if err := c.Hello(localName); err != nil {
+		return err
+	}
+	if t.Security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(t.tlsConfig()); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		// PlainAuth refuses to send the password unless the connection is
+		// encrypted or to localhost
+		if err := c.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(msg.From.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (t *SMTPTransport) tlsConfig() *tls.Config {
+	if t.TLSConfig != nil {
+		return t.TLSConfig
+	}
+	return &tls.Config{ServerName: t.Host, MinVersion: tls.VersionTLS12}
+}
+
+// MockTransport records messages instead of sending them, for tests and
+// local development
+type MockTransport struct {
+	mu       sync.Mutex
+	messages []*Message
+	// Err, if set, is returned by Send and the message is not recorded
+	Err error
+}
+
+// NewMockTransport creates an empty mock transport
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// Send implements Transport
+func (m *MockTransport) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.messages = append(m.messages, msg)
+	logger.Info("Email captured by mock transport", "to", msg.Recipients(), "subject", msg.Subject)
+	return nil
+}
+
+// Messages returns the recorded messages, oldest first
+func (m *MockTransport) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// Last returns the most recent message, or nil
+func (m *MockTransport) Last() *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		return nil
+	}
+	return m.messages[len(m.messages)-1]
+}
+
+// Reset drops the recorded messages
+func (m *MockTransport) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// TransportFromEnv returns an SMTPTransport configured by SMTP_HOST,
+// SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD and SMTP_SECURITY
+// ("starttls", "tls" or "none"), or a MockTransport when SMTP_HOST is unset
+func TransportFromEnv() (Transport, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return NewMockTransport(), nil
+	}
+	port := 587
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("email: invalid SMTP_PORT %q", v)
+		}
+		port = p
+	}
+	t := NewSMTPTransport(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	switch strings.ToLower(os.Getenv("SMTP_SECURITY")) {
+	case "":
+	case "starttls":
+		t.Security = SecurityStartTLS
+	case "tls":
+		t.Security = SecurityTLS
+	case "none":
+		t.Security = SecurityNone
+	default:
+		return nil, fmt.Errorf("email: invalid SMTP_SECURITY %q", os.Getenv("SMTP_SECURITY"))
+	}
+	return t, nil
+}
@@ -0,0 +1,135 @@
+package encoding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Codec converts values to and from a wire format. Codecs are safe for
+// concurrent use and are shared by the frame helpers in this package and the
+// http client.
+type Codec interface {
+	Name() string
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) ContentType() string                { return "application/json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) Name() string        { return "xml" }
+func (xmlCodec) ContentType() string { return "application/xml" }
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	return MarshalXML(v, false)
+}
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                       { return "msgpack" }
+func (msgpackCodec) ContentType() string                { return "application/msgpack" }
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return MarshalMsgpack(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return UnmarshalMsgpack(data, v) }
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string                       { return "protobuf" }
+func (protobufCodec) ContentType() string                { return "application/x-protobuf" }
+func (protobufCodec) Marshal(v any) ([]byte, error)      { return MarshalProto(v) }
+func (protobufCodec) Unmarshal(data []byte, v any) error { return UnmarshalProto(data, v) }
+
+type csvCodec struct{ opts CSVOptions }
+
+func (csvCodec) Name() string                         { return "csv" }
+func (csvCodec) ContentType() string                  { return "text/csv" }
+func (c csvCodec) Marshal(v any) ([]byte, error)      { return MarshalCSV(v, c.opts) }
+func (c csvCodec) Unmarshal(data []byte, v any) error { return UnmarshalCSV(data, v, c.opts) }
+
+// Built-in codecs
+var (
+	JSON     Codec = jsonCodec{}
+	XML      Codec = xmlCodec{}
+	Msgpack  Codec = msgpackCodec{}
+	Protobuf Codec = protobufCodec{}
+	// CSV marshals slices of structs with a header row
+	CSV Codec = csvCodec{}
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Codec{}
+)
+
+func init() {
+	for _, c := range []Codec{JSON, XML, Msgpack, Protobuf, CSV} {
+		Register(c)
+	}
+}
+
+// Register makes a codec available to Lookup and ForContentType, replacing
+// any codec with the same name
+func Register(c Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[c.Name()] = c
+}
+
+// Lookup returns the codec registered under name
+func Lookup(name string) (Codec, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if c, ok := registry[strings.ToLower(name)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown codec %q", name)
+}
+
+// ForContentType returns the codec for a Content-Type header value, ignoring
+// parameters such as charset. "+json" and "+xml" suffixes map to JSON and XML.
+func ForContentType(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, c := range registry {
+		if c.ContentType() == mediaType {
+			return c, true
+		}
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"), mediaType == "text/json":
+		return JSON, true
+	case strings.HasSuffix(mediaType, "+xml"), mediaType == "text/xml":
+		return XML, true
+	case mediaType == "application/x-msgpack":
+		return Msgpack, true
+	}
+	return nil, false
+}
+
+// Codecs lists the registered codec names
+func Codecs() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
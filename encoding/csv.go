@@ -0,0 +1,381 @@
+package encoding
+
+import (
+	"bytes"
+	stdencoding "encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOptions configures struct↔CSV mapping. Columns come from `csv:"name"`
+// tags (or the field name); `csv:"-"` skips a field.
+type CSVOptions struct {
+	Comma      rune   // field delimiter; ',' when zero
+	NoHeader   bool   // columns are in field order and there is no header row
+	TimeLayout string // layout for time.Time fields; RFC 3339 when empty
+}
+
+func (o CSVOptions) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+func (o CSVOptions) timeLayout() string {
+	if o.TimeLayout == "" {
+		return time.RFC3339
+	}
+	return o.TimeLayout
+}
+
+type csvField struct {
+	name  string
+	index int
+}
+
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: %s is not a struct", t)
+	}
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, csvField{name: name, index: i})
+	}
+	return fields, nil
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*stdencoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*stdencoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func formatCSVValue(v reflect.Value, layout string) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case v.Type() == durationType:
+		return v.Interface().(time.Duration).String(), nil
+	case v.Type().Implements(textMarshalerType):
+		b, err := v.Interface().(stdencoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func parseCSVValue(v reflect.Value, s string, layout string) error {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		if s == "" {
+			v.SetZero()
+			return nil
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(stdencoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if s == "" && v.Kind() != reflect.String {
+		v.SetZero()
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// CSVEncoder writes structs as CSV rows, preceded by a header row unless
+// NoHeader is set
+type CSVEncoder struct {
+	w      *csv.Writer
+	opts   CSVOptions
+	typ    reflect.Type
+	fields []csvField
+}
+
+// NewCSVEncoder creates an encoder writing to w
+func NewCSVEncoder(w io.Writer, opts CSVOptions) *CSVEncoder {
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.comma()
+	return &CSVEncoder{w: cw, opts: opts}
+}
+
+// Encode writes one struct (or pointer to struct) as a row; every call must
+// pass the same type
+func (e *CSVEncoder) Encode(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if e.typ == nil {
+		fields, err := csvFields(rv.Type())
+		if err != nil {
+			return err
+		}
+		e.typ, e.fields = rv.Type(), fields
+		if !e.opts.NoHeader {
+			header := make([]string, len(fields))
+			for i, f := range fields {
+				header[i] = f.name
+			}
+			if err := e.w.Write(header); err != nil {
+				return err
+			}
+		}
+	} else if rv.Type() != e.typ {
+		return fmt.Errorf("csv: cannot encode %s after %s", rv.Type(), e.typ)
+	}
+
+	record := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		s, err := formatCSVValue(rv.Field(f.index), e.opts.timeLayout())
+		if err != nil {
+			return fmt.Errorf("csv: column %q: %w", f.name, err)
+		}
+		record[i] = s
+	}
+	return e.w.Write(record)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (e *CSVEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// CSVDecoder reads CSV rows into structs. With a header, columns are matched
+// to fields by name (case-insensitively) in any order and unknown columns are
+// ignored.
+type CSVDecoder struct {
+	r       *csv.Reader
+	opts    CSVOptions
+	header  []string
+	typ     reflect.Type
+	columns []int // column -> field index, -1 when ignored
+}
+
+// NewCSVDecoder creates a decoder reading from r
+func NewCSVDecoder(r io.Reader, opts CSVOptions) *CSVDecoder {
+	cr := csv.NewReader(r)
+	cr.Comma = opts.comma()
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return &CSVDecoder{r: cr, opts: opts}
+}
+
+// Header returns the header row once the first record has been decoded
+func (d *CSVDecoder) Header() []string {
+	return d.header
+}
+
+// Decode reads the next row into v, a pointer to a struct. It returns io.EOF
+// when there are no more rows.
+func (d *CSVDecoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("csv: Decode requires a non-nil pointer")
+	}
+	rv = rv.Elem()
+
+	if d.typ == nil {
+		if err := d.bind(rv.Type()); err != nil {
+			return err
+		}
+	} else if rv.Type() != d.typ {
+		return fmt.Errorf("csv: cannot decode into %s after %s", rv.Type(), d.typ)
+	}
+
+	record, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	line, _ := d.r.FieldPos(0)
+	for col, value := range record {
+		if col >= len(d.columns) || d.columns[col] < 0 {
+			continue
+		}
+		if err := parseCSVValue(rv.Field(d.columns[col]), value, d.opts.timeLayout()); err != nil {
+			name := strconv.Itoa(col + 1)
+			if col < len(d.header) {
+				name = d.header[col]
+			}
+			return fmt.Errorf("csv: line %d, column %s: %w", line, name, err)
+		}
+	}
+	return nil
+}
+
+func (d *CSVDecoder) bind(t reflect.Type) error {
+	fields, err := csvFields(t)
+	if err != nil {
+		return err
+	}
+	d.typ = t
+
+	if d.opts.NoHeader {
+		d.columns = make([]int, len(fields))
+		for i, f := range fields {
+			d.columns[i] = f.index
+		}
+		return nil
+	}
+
+	header, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	d.header = header
+	d.columns = make([]int, len(header))
+	for col, name := range header {
+		d.columns[col] = -1
+		for _, f := range fields {
+			if strings.EqualFold(strings.TrimSpace(name), f.name) {
+				d.columns[col] = f.index
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalCSV encodes a slice of structs (or struct pointers)
+func MarshalCSV(v any, opts CSVOptions) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv: MarshalCSV requires a slice, got %T", v)
+	}
+	var buf bytes.Buffer
+	enc := NewCSVEncoder(&buf, opts)
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCSV decodes rows into v, a pointer to a slice of structs or struct
+// pointers
+func UnmarshalCSV(data []byte, v any, opts CSVOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("csv: UnmarshalCSV requires a pointer to a slice, got %T", v)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Pointer
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	dec := NewCSVDecoder(bytes.NewReader(data), opts)
+	for {
+		item := reflect.New(elemType)
+		err := dec.Decode(item.Interface())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+}
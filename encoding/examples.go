@@ -0,0 +1,307 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jerrychou/go-practice/encoding/pb"
+)
+
+// Employee is the record used by the CSV, msgpack and framing examples
+type Employee struct {
+	ID      int           `csv:"id" json:"id"`
+	Name    string        `csv:"name" json:"name"`
+	Email   string        `csv:"email" json:"email"`
+	Salary  float64       `csv:"salary" json:"salary"`
+	Active  bool          `csv:"active" json:"active"`
+	Started time.Time     `csv:"started" json:"started"`
+	Shift   time.Duration `csv:"shift" json:"shift"`
+	Manager *int          `csv:"manager_id" json:"manager_id,omitempty"`
+}
+
+// UserProto and ChatMessage mirror the schema in pb/chat.proto, from which
+// protoc generates pb.User and pb.ChatMessage
+type UserProto struct {
+	ID      int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id"`
+	Name    string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name"`
+	Email   string   `protobuf:"bytes,3,opt,name=email,proto3" json:"email"`
+	Roles   []string `protobuf:"bytes,4,rep,name=roles,proto3" json:"roles"`
+	Scores  []int32  `protobuf:"varint,5,rep,name=scores,proto3" json:"scores"`
+	Balance int64    `protobuf:"zigzag64,6,opt,name=balance,proto3" json:"balance"`
+	Rating  float64  `protobuf:"fixed64,7,opt,name=rating,proto3" json:"rating"`
+}
+
+type ChatMessage struct {
+	From    *UserProto `protobuf:"bytes,1,opt,name=from,proto3" json:"from"`
+	Text    string     `protobuf:"bytes,2,opt,name=text,proto3" json:"text"`
+	SentAt  int64      `protobuf:"varint,3,opt,name=sent_at,proto3" json:"sent_at"`
+	Private bool       `protobuf:"varint,4,opt,name=private,proto3" json:"private"`
+}
+
+// CSVExamples demonstrates struct↔CSV mapping
+func CSVExamples() {
+	fmt.Println("\n=== CSV ===")
+
+	manager := 1
+	started := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+	employees := []Employee{
+		{ID: 1, Name: "Alice", Email: "alice@example.com", Salary: 120000, Active: true, Started: started, Shift: 8 * time.Hour},
+		{ID: 2, Name: "Bob, Jr.", Email: "bob@example.com", Salary: 95000.5, Started: started.AddDate(1, 0, 0), Shift: 6 * time.Hour, Manager: &manager},
+	}
+
+	data, err := MarshalCSV(employees, CSVOptions{})
+	if err != nil {
+		fmt.Printf("Error marshaling CSV: %v\n", err)
+		return
+	}
+	fmt.Printf("📄 Comma-separated:\n%s", data)
+
+	semicolon, _ := MarshalCSV(employees, CSVOptions{Comma: ';', TimeLayout: "2006-01-02"})
+	fmt.Printf("📄 Semicolon-separated with dates:\n%s", semicolon)
+
+	// Columns are matched by header name, so order and extra columns do not matter
+	input := "email,id,department,name,manager_id\ncarol@example.com,3,Sales,Carol,1\ndave@example.com,4,Ops,Dave,\n"
+	var decoded []Employee
+	if err := UnmarshalCSV([]byte(input), &decoded, CSVOptions{}); err != nil {
+		fmt.Printf("Error unmarshaling CSV: %v\n", err)
+		return
+	}
+	for _, e := range decoded {
+		managerID := "none"
+		if e.Manager != nil {
+			managerID = fmt.Sprint(*e.Manager)
+		}
+		fmt.Printf("Decoded: id=%d name=%s email=%s manager=%s\n", e.ID, e.Name, e.Email, managerID)
+	}
+
+	bad := "id,name\nx,Eve\n"
+	var invalid []Employee
+	fmt.Printf("Invalid input: %v\n", UnmarshalCSV([]byte(bad), &invalid, CSVOptions{}))
+
+	// Streaming decode, one row at a time
+	dec := NewCSVDecoder(strings.NewReader(string(data)), CSVOptions{})
+	rows := 0
+	for {
+		var e Employee
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			break
+		}
+		rows++
+	}
+	fmt.Printf("Streamed %d rows with header %v\n", rows, dec.Header())
+}
+
+// AtomFeed is a namespaced XML document
+type AtomFeed struct {
+	XMLName    xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Namespaces []xml.Attr  `xml:",any,attr"`
+	Title      string      `xml:"title"`
+	Entries    []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Title   string `xml:"title"`
+	ID      string `xml:"id"`
+	Updated string `xml:"updated"`
+	Rating  string `xml:"http://purl.org/rating/1.0 rating,omitempty"`
+}
+
+// XMLExamples demonstrates namespaced XML and streaming element decoding
+func XMLExamples() {
+	fmt.Println("\n=== XML ===")
+
+	feed := AtomFeed{
+		Namespaces: NamespaceAttrs(map[string]string{"r": "http://purl.org/rating/1.0"}),
+		Title:      "Go Practice",
+		Entries: []AtomEntry{
+			{Title: "Codecs", ID: "urn:1", Updated: "2024-01-01T00:00:00Z", Rating: "5"},
+			{Title: "Framing", ID: "urn:2", Updated: "2024-01-02T00:00:00Z"},
+		},
+	}
+	data, err := MarshalXML(feed, true)
+	if err != nil {
+		fmt.Printf("Error marshaling XML: %v\n", err)
+		return
+	}
+	fmt.Printf("%s", data)
+
+	var decoded AtomFeed
+	if err := XML.Unmarshal(data, &decoded); err != nil {
+		fmt.Printf("Error unmarshaling XML: %v\n", err)
+		return
+	}
+	fmt.Printf("Decoded feed %q (namespace %s) with %d entries\n", decoded.Title, decoded.XMLName.Space, len(decoded.Entries))
+
+	fmt.Println("Streaming entries:")
+	DecodeXMLElements(bytes.NewReader(data), "http://www.w3.org/2005/Atom", "entry",
+		func() any { return &AtomEntry{} },
+		func(v any) error {
+			entry := v.(*AtomEntry)
+			fmt.Printf("  • %s (%s) rating=%q\n", entry.Title, entry.ID, entry.Rating)
+			return nil
+		})
+}
+
+// ProtobufExamples demonstrates the tag-driven protobuf codec
+func ProtobufExamples() {
+	fmt.Println("\n=== Protocol Buffers ===")
+
+	schema, err := GenerateProto("practice.encoding.v1", ChatMessage{})
+	if err != nil {
+		fmt.Printf("Error generating schema: %v\n", err)
+		return
+	}
+	fmt.Printf("Generated schema:\n%s", schema)
+
+	msg := ChatMessage{
+		From: &UserProto{ID: 42, Name: "Alice", Email: "alice@example.com", Roles: []string{"admin", "editor"},
+			Scores: []int32{90, 85, -1}, Balance: -1500, Rating: 4.75},
+		Text:   "Hello, protobuf!",
+		SentAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Unix(),
+	}
+	data, err := MarshalProto(msg)
+	if err != nil {
+		fmt.Printf("Error marshaling: %v\n", err)
+		return
+	}
+	jsonData, _ := json.Marshal(msg)
+	fmt.Printf("Protobuf: %d bytes, JSON: %d bytes\n", len(data), len(jsonData))
+	fmt.Printf("Wire bytes: % x…\n", data[:16])
+
+	var decoded ChatMessage
+	if err := UnmarshalProto(data, &decoded); err != nil {
+		fmt.Printf("Error unmarshaling: %v\n", err)
+		return
+	}
+	fmt.Printf("Decoded: %s from %s, roles=%v scores=%v balance=%d rating=%.2f\n",
+		decoded.Text, decoded.From.Name, decoded.From.Roles, decoded.From.Scores, decoded.From.Balance, decoded.From.Rating)
+
+	// The code protoc generates from pb/chat.proto speaks the same wire format
+	generated := &pb.ChatMessage{
+		From: &pb.User{Id: 42, Name: "Alice", Email: "alice@example.com", Roles: []string{"admin", "editor"},
+			Scores: []int32{90, 85, -1}, Balance: -1500, Rating: 4.75},
+		Text:   "Hello, protobuf!",
+		SentAt: msg.SentAt,
+	}
+	generatedData, err := proto.Marshal(generated)
+	if err != nil {
+		fmt.Printf("Error marshaling generated message: %v\n", err)
+		return
+	}
+	fmt.Printf("Generated pb.ChatMessage: %d bytes, identical to the tagged struct: %v\n", len(generatedData), bytes.Equal(generatedData, data))
+	var fromTagged pb.ChatMessage
+	if err := proto.Unmarshal(data, &fromTagged); err != nil {
+		fmt.Printf("Error unmarshaling into generated message: %v\n", err)
+		return
+	}
+	fmt.Printf("Generated decode of the tagged bytes: %s from %s, balance=%d\n",
+		fromTagged.GetText(), fromTagged.GetFrom().GetName(), fromTagged.GetFrom().GetBalance())
+
+	// Older readers skip fields they do not know about
+	var text struct {
+		Text string `protobuf:"bytes,2,opt,name=text,proto3"`
+	}
+	UnmarshalProto(data, &text)
+	fmt.Printf("Reader with only field 2: %q\n", text.Text)
+}
+
+// MsgpackExamples demonstrates the MessagePack codec
+func MsgpackExamples() {
+	fmt.Println("\n=== MessagePack ===")
+
+	e := Employee{ID: 7, Name: "Grace", Email: "grace@example.com", Salary: 150000, Active: true,
+		Started: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), Shift: 90 * time.Minute}
+	data, err := MarshalMsgpack(e)
+	if err != nil {
+		fmt.Printf("Error marshaling: %v\n", err)
+		return
+	}
+	jsonData, _ := json.Marshal(e)
+	fmt.Printf("Msgpack: %d bytes, JSON: %d bytes\n", len(data), len(jsonData))
+
+	var decoded Employee
+	if err := UnmarshalMsgpack(data, &decoded); err != nil {
+		fmt.Printf("Error unmarshaling: %v\n", err)
+		return
+	}
+	fmt.Printf("Typed decode: %s <%s> started %s, shift %s\n", decoded.Name, decoded.Email, decoded.Started.UTC().Format("2006-01-02"), decoded.Shift)
+
+	var generic any
+	UnmarshalMsgpack(data, &generic)
+	fmt.Printf("Generic decode: name=%v active=%v salary=%v\n",
+		generic.(map[string]any)["name"], generic.(map[string]any)["active"], generic.(map[string]any)["salary"])
+
+	mixed := map[string]any{"small": 5, "negative": -200, "big": uint64(1) << 40, "pi": 3.14159, "blob": []byte{0xde, 0xad}, "list": []any{"a", nil, true}}
+	packed, _ := MarshalMsgpack(mixed)
+	var roundTrip map[string]any
+	UnmarshalMsgpack(packed, &roundTrip)
+	fmt.Printf("Mixed map (%d bytes): %v\n", len(packed), roundTrip)
+}
+
+// FramingExamples sends the same message over a connection with each codec
+func FramingExamples() {
+	fmt.Println("\n=== Framed Messages over a Connection ===")
+
+	msg := UserProto{ID: 1, Name: "Alice", Roles: []string{"admin"}, Scores: []int32{1, 2, 3}}
+	for _, name := range []string{"json", "msgpack", "protobuf"} {
+		codec, _ := Lookup(name)
+		server, client := net.Pipe()
+
+		go func() {
+			defer client.Close()
+			for i := 0; i < 2; i++ {
+				WriteFrame(client, codec, msg)
+			}
+		}()
+
+		frames := 0
+		var got UserProto
+		for {
+			if err := ReadFrame(server, codec, &got); err != nil {
+				if err != io.EOF {
+					fmt.Printf("Error: %v\n", err)
+				}
+				break
+			}
+			frames++
+		}
+		server.Close()
+		payload, _ := codec.Marshal(msg)
+		fmt.Printf("%-9s %-24s %d frames of %3d bytes, decoded %s %v\n", codec.Name(), codec.ContentType(), frames, len(payload), got.Name, got.Scores)
+	}
+
+	for _, ct := range []string{"application/json; charset=utf-8", "application/problem+json", "application/x-msgpack", "text/plain"} {
+		codec, ok := ForContentType(ct)
+		name := "none"
+		if ok {
+			name = codec.Name()
+		}
+		fmt.Printf("Codec for %q: %s\n", ct, name)
+	}
+}
+
+// RunAllEncodingExamples runs every encoding example
+func RunAllEncodingExamples() {
+	fmt.Println("🎯 Go Encoding Examples")
+	fmt.Println("=======================")
+	fmt.Printf("Registered codecs: %v\n", Codecs())
+
+	CSVExamples()
+	XMLExamples()
+	ProtobufExamples()
+	MsgpackExamples()
+	FramingExamples()
+
+	fmt.Println("\n✅ All encoding examples completed!")
+}
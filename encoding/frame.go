@@ -0,0 +1,87 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxFrameSize bounds frames read by ReadFrame so a corrupt or hostile
+// length prefix cannot trigger a huge allocation
+const MaxFrameSize = 16 << 20
+
+// ErrFrameTooLarge is returned for frames over MaxFrameSize, when writing
+// them and when reading their length prefix
+var ErrFrameTooLarge = errors.New("frame exceeds MaxFrameSize")
+
+// frameChunk is the largest payload ReadFramePayload allocates up front
+const frameChunk = 64 << 10
+
+// WriteFrame marshals v with codec and writes it to w prefixed by its length
+// as a 4-byte big-endian integer, the framing used for messages on streams
+// such as TCP connections
+func WriteFrame(w io.Writer, codec Codec, v any) error {
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteFramePayload(w, payload)
+}
+
+// WriteFramePayload writes payload to w prefixed by its length, in a
+// single Write so concurrent writers that serialise calls do not interleave
+func WriteFramePayload(w io.Writer, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrFrameTooLarge, len(payload), MaxFrameSize)
+	}
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads one length-prefixed frame from r and unmarshals it into v.
+// It returns io.EOF only when r ends cleanly between frames.
+func ReadFrame(r io.Reader, codec Codec, v any) error {
+	payload, err := ReadFramePayload(r)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(payload, v)
+}
+
+// ReadFramePayload reads one length-prefixed frame from r and returns its
+// payload undecoded. Like ReadFrame it returns io.EOF only between frames.
+func ReadFramePayload(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrFrameTooLarge, size, MaxFrameSize)
+	}
+	if size <= frameChunk {
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return payload, nil
+	}
+	// Large frames grow as the payload arrives, so a length prefix that
+	// promises more than the peer sends costs only what was sent
+	var payload bytes.Buffer
+	if _, err := io.CopyN(&payload, r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload.Bytes(), nil
+}
@@ -0,0 +1,604 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessagePack (https://msgpack.org) is a compact binary format with the same
+// data model as JSON plus binary strings and timestamps. Structs are encoded
+// as maps keyed by the `msgpack` tag, falling back to the `json` tag and then
+// the field name; ",omitempty" is honoured.
+
+type msgpackField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func msgpackFields(t reflect.Type) []msgpackField {
+	var fields []msgpackField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, ok := f.Tag.Lookup("msgpack")
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, msgpackField{name: name, index: i, omitEmpty: strings.Contains(opts, "omitempty")})
+	}
+	return fields
+}
+
+// MarshalMsgpack encodes v as MessagePack. Map keys are sorted so the output
+// is deterministic.
+func MarshalMsgpack(v any) ([]byte, error) {
+	var e msgpackEncoder
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) byte1(b byte) { e.buf = append(e.buf, b) }
+
+func (e *msgpackEncoder) uint(prefix byte, n uint64, size int) {
+	e.buf = append(e.buf, prefix)
+	for i := size - 1; i >= 0; i-- {
+		e.buf = append(e.buf, byte(n>>(8*i)))
+	}
+}
+
+func (e *msgpackEncoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.byte1(byte(n))
+	case n >= math.MinInt8:
+		e.uint(0xd0, uint64(n), 1)
+	case n >= math.MinInt16:
+		e.uint(0xd1, uint64(n), 2)
+	case n >= math.MinInt32:
+		e.uint(0xd2, uint64(n), 4)
+	default:
+		e.uint(0xd3, uint64(n), 8)
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(n uint64) {
+	switch {
+	case n < 128:
+		e.byte1(byte(n))
+	case n <= math.MaxUint8:
+		e.uint(0xcc, n, 1)
+	case n <= math.MaxUint16:
+		e.uint(0xcd, n, 2)
+	case n <= math.MaxUint32:
+		e.uint(0xce, n, 4)
+	default:
+		e.uint(0xcf, n, 8)
+	}
+}
+
+// header writes a length-prefixed type header, choosing the fix, 8, 16 or
+// 32-bit form; fixBase/fixMax describe the fix form (fixMax 0 for none)
+func (e *msgpackEncoder) header(n int, fixBase byte, fixMax int, p8, p16, p32 byte) {
+	switch {
+	case n <= fixMax:
+		e.byte1(fixBase | byte(n))
+	case p8 != 0 && n <= math.MaxUint8:
+		e.uint(p8, uint64(n), 1)
+	case n <= math.MaxUint16:
+		e.uint(p16, uint64(n), 2)
+	default:
+		e.uint(p32, uint64(n), 4)
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	e.header(len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeTime(t time.Time) {
+	// Timestamp extension (type -1) in the 96-bit form: nanoseconds, seconds
+	e.buf = append(e.buf, 0xc7, 12, 0xff)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(t.Nanosecond()))
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(t.Unix()))
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.byte1(0xc0)
+		return nil
+	}
+	if v.Type() == timeType {
+		e.encodeTime(v.Interface().(time.Time))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.byte1(0xc3)
+		} else {
+			e.byte1(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.uint(0xca, uint64(math.Float32bits(float32(v.Float()))), 4)
+	case reflect.Float64:
+		e.uint(0xcb, math.Float64bits(v.Float()), 8)
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.header(v.Len(), 0, -1, 0xc4, 0xc5, 0xc6)
+			for i := 0; i < v.Len(); i++ {
+				e.byte1(byte(v.Index(i).Uint()))
+			}
+			return nil
+		}
+		e.header(v.Len(), 0x90, 15, 0, 0xdc, 0xdd)
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		e.header(len(keys), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			if err := e.encode(key); err != nil {
+				return err
+			}
+			if err := e.encode(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		var present []msgpackField
+		for _, f := range fields {
+			if f.omitEmpty && v.Field(f.index).IsZero() {
+				continue
+			}
+			present = append(present, f)
+		}
+		e.header(len(present), 0x80, 15, 0, 0xde, 0xdf)
+		for _, f := range present {
+			e.encodeString(f.name)
+			if err := e.encode(v.Field(f.index)); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// UnmarshalMsgpack decodes MessagePack data into v, which must be a non-nil
+// pointer. Decoding into an interface{} yields nil, bool, int64, uint64,
+// float64, string, []byte, time.Time, []any and map[string]any (non-string
+// keys are formatted with fmt.Sprint).
+func UnmarshalMsgpack(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("msgpack: Unmarshal requires a non-nil pointer")
+	}
+	d := msgpackDecoder{data: data}
+	value, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(data)-d.pos)
+	}
+	return assignValue(rv.Elem(), value)
+}
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uintN(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *msgpackDecoder) decode() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uintN(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.next(int(n))
+		return append([]byte(nil), raw...), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uintN(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		n, err := d.uintN(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uintN(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uintN(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uintN(size)
+		// Sign-extend from size bytes
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uintN(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uintN(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uintN(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: invalid type byte 0x%02x", c)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) decodeArray(n int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	items := make([]any, n)
+	for i := range items {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) decodeExt(n int) (any, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", int8(typ[0]))
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(payload)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(payload)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(payload[4:])), int64(binary.BigEndian.Uint32(payload))), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
+
+// assignValue stores a generic decoded value into dst, converting numbers,
+// strings and containers to dst's type
+func assignValue(dst reflect.Value, src any) error {
+	if src == nil {
+		dst.SetZero()
+		return nil
+	}
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignValue(dst.Elem(), src)
+	}
+	if dst.Type() == timeType {
+		t, ok := src.(time.Time)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to time.Time", src)
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+	}
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch s := src.(type) {
+		case int64:
+			n = s
+		case uint64:
+			if s > math.MaxInt64 {
+				return fmt.Errorf("%d overflows %s", s, dst.Type())
+			}
+			n = int64(s)
+		default:
+			return mismatch()
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, dst.Type())
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch s := src.(type) {
+		case uint64:
+			n = s
+		case int64:
+			if s < 0 {
+				return fmt.Errorf("%d overflows %s", s, dst.Type())
+			}
+			n = uint64(s)
+		default:
+			return mismatch()
+		}
+		if dst.OverflowUint(n) {
+			return fmt.Errorf("%d overflows %s", n, dst.Type())
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch s := src.(type) {
+		case float64:
+			dst.SetFloat(s)
+		case int64:
+			dst.SetFloat(float64(s))
+		case uint64:
+			dst.SetFloat(float64(s))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+		case []byte:
+			dst.SetString(string(s))
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch s := src.(type) {
+			case []byte:
+				dst.SetBytes(append([]byte(nil), s...))
+				return nil
+			case string:
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		items, ok := src.([]any)
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assignValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		dst.Set(slice)
+	case reflect.Array:
+		items, ok := src.([]any)
+		if !ok {
+			return mismatch()
+		}
+		for i := 0; i < dst.Len() && i < len(items); i++ {
+			if err := assignValue(dst.Index(i), items[i]); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	case reflect.Map:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		result := reflect.MakeMapWithSize(dst.Type(), len(m))
+		for k, item := range m {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := assignMapKey(key, k); err != nil {
+				return err
+			}
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignValue(value, item); err != nil {
+				return fmt.Errorf("[%q]: %w", k, err)
+			}
+			result.SetMapIndex(key, value)
+		}
+		dst.Set(result)
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for _, f := range msgpackFields(dst.Type()) {
+			item, ok := m[f.name]
+			if !ok {
+				// Fall back to a case-insensitive match, as encoding/json does
+				for k, v := range m {
+					if strings.EqualFold(k, f.name) {
+						item, ok = v, true
+						break
+					}
+				}
+			}
+			if !ok {
+				continue
+			}
+			if err := assignValue(dst.Field(f.index), item); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", dst.Type())
+	}
+	return nil
+}
+
+func assignMapKey(dst reflect.Value, key string) error {
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(key, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	default:
+		return fmt.Errorf("msgpack: unsupported map key type %s", dst.Type())
+	}
+	return nil
+}
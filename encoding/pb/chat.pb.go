@@ -0,0 +1,253 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: encoding/pb/chat.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// User is mirrored by the tagged encoding.UserProto struct
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Roles         []string               `protobuf:"bytes,4,rep,name=roles,proto3" json:"roles,omitempty"`
+	Scores        []int32                `protobuf:"varint,5,rep,packed,name=scores,proto3" json:"scores,omitempty"`
+	Balance       int64                  `protobuf:"zigzag64,6,opt,name=balance,proto3" json:"balance,omitempty"`
+	Rating        float64                `protobuf:"fixed64,7,opt,name=rating,proto3" json:"rating,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_encoding_pb_chat_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_encoding_pb_chat_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_encoding_pb_chat_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *User) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *User) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *User) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *User) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *User) GetScores() []int32 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+func (x *User) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *User) GetRating() float64 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+// ChatMessage is mirrored by the tagged encoding.ChatMessage struct
+type ChatMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          *User                  `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	SentAt        int64                  `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	Private       bool                   `protobuf:"varint,4,opt,name=private,proto3" json:"private,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatMessage) Reset() {
+	*x = ChatMessage{}
+	mi := &file_encoding_pb_chat_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatMessage) ProtoMessage() {}
+
+func (x *ChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_encoding_pb_chat_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
+func (*ChatMessage) Descriptor() ([]byte, []int) {
+	return file_encoding_pb_chat_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChatMessage) GetFrom() *User {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ChatMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetSentAt() int64 {
+	if x != nil {
+		return x.SentAt
+	}
+	return 0
+}
+
+func (x *ChatMessage) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+var File_encoding_pb_chat_proto protoreflect.FileDescriptor
+
+const file_encoding_pb_chat_proto_rawDesc = "" +
+	"\n" +
+	"\x16encoding/pb/chat.proto\x12\x14practice.encoding.v1\"\xa0\x01\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x14\n" +
+	"\x05roles\x18\x04 \x03(\tR\x05roles\x12\x16\n" +
+	"\x06scores\x18\x05 \x03(\x05R\x06scores\x12\x18\n" +
+	"\abalance\x18\x06 \x01(\x12R\abalance\x12\x16\n" +
+	"\x06rating\x18\a \x01(\x01R\x06rating\"\x84\x01\n" +
+	"\vChatMessage\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.practice.encoding.v1.UserR\x04from\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x17\n" +
+	"\asent_at\x18\x03 \x01(\x03R\x06sentAt\x12\x18\n" +
+	"\aprivate\x18\x04 \x01(\bR\aprivateB.Z,github.com/jerrychou/go-practice/encoding/pbb\x06proto3"
+
+var (
+	file_encoding_pb_chat_proto_rawDescOnce sync.Once
+	file_encoding_pb_chat_proto_rawDescData []byte
+)
+
+func file_encoding_pb_chat_proto_rawDescGZIP() []byte {
+	file_encoding_pb_chat_proto_rawDescOnce.Do(func() {
+		file_encoding_pb_chat_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_encoding_pb_chat_proto_rawDesc), len(file_encoding_pb_chat_proto_rawDesc)))
+	})
+	return file_encoding_pb_chat_proto_rawDescData
+}
+
+var file_encoding_pb_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_encoding_pb_chat_proto_goTypes = []any{
+	(*User)(nil),        // 0: practice.encoding.v1.User
+	(*ChatMessage)(nil), // 1: practice.encoding.v1.ChatMessage
+}
+var file_encoding_pb_chat_proto_depIdxs = []int32{
+	0, // 0: practice.encoding.v1.ChatMessage.from:type_name -> practice.encoding.v1.User
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_encoding_pb_chat_proto_init() }
+func file_encoding_pb_chat_proto_init() {
+	if File_encoding_pb_chat_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_encoding_pb_chat_proto_rawDesc), len(file_encoding_pb_chat_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_encoding_pb_chat_proto_goTypes,
+		DependencyIndexes: file_encoding_pb_chat_proto_depIdxs,
+		MessageInfos:      file_encoding_pb_chat_proto_msgTypes,
+	}.Build()
+	File_encoding_pb_chat_proto = out.File
+	file_encoding_pb_chat_proto_goTypes = nil
+	file_encoding_pb_chat_proto_depIdxs = nil
+}
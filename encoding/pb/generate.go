@@ -0,0 +1,11 @@
+// Package pb holds the Go types protoc generates from chat.proto. The
+// encoding package's hand-written, tag-driven codec produces the same bytes
+// for its mirror structs, which its examples check against these.
+package pb
+
+// Regenerate with protoc and protoc-gen-go on the PATH:
+//
+//	go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.10
+//	go generate ./encoding/pb
+//
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative encoding/pb/chat.proto
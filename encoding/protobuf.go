@@ -0,0 +1,487 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A minimal Protocol Buffers (proto3) codec driven by struct tags in the same
+// format protoc-gen-go emits, so hand-written types stay compatible with
+// generated ones:
+//
+//	ID    int64   `protobuf:"varint,1,opt,name=id,proto3"`
+//	Tags  []string `protobuf:"bytes,2,rep,name=tags,proto3"`
+//
+// Encodings are varint, zigzag32, zigzag64, fixed32, fixed64 and bytes (also
+// used for nested messages). Repeated numeric fields are packed. Maps and
+// oneofs are not supported.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type protoField struct {
+	name     string
+	number   int
+	encoding string
+	repeated bool
+	index    int
+}
+
+func (f protoField) wireType() int {
+	switch f.encoding {
+	case "fixed64":
+		return wireFixed64
+	case "fixed32":
+		return wireFixed32
+	case "bytes":
+		return wireBytes
+	}
+	return wireVarint
+}
+
+func protoFields(t reflect.Type) ([]protoField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("protobuf: %s is not a struct", t)
+	}
+	var fields []protoField
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("protobuf")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("protobuf: invalid tag %q on %s.%s", tag, t, t.Field(i).Name)
+		}
+		number, err := strconv.Atoi(parts[1])
+		if err != nil || number <= 0 {
+			return nil, fmt.Errorf("protobuf: invalid field number in tag %q", tag)
+		}
+		f := protoField{name: t.Field(i).Name, number: number, encoding: parts[0], index: i}
+		for _, part := range parts[2:] {
+			switch {
+			case part == "rep":
+				f.repeated = true
+			case strings.HasPrefix(part, "name="):
+				f.name = strings.TrimPrefix(part, "name=")
+			}
+		}
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].number < fields[j].number })
+	return fields, nil
+}
+
+// MarshalProto encodes a struct (or pointer to struct) in protobuf wire format
+func MarshalProto(v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, errors.New("protobuf: cannot marshal nil")
+	}
+	return appendMessage(nil, rv)
+}
+
+func appendTag(b []byte, number, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wireType))
+}
+
+func appendMessage(b []byte, v reflect.Value) ([]byte, error) {
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if f.repeated {
+			if fv.Len() == 0 {
+				continue
+			}
+			if f.encoding != "bytes" {
+				// Packed: one length-delimited record holding every value
+				var packed []byte
+				for i := 0; i < fv.Len(); i++ {
+					if packed, err = appendScalar(packed, f.encoding, fv.Index(i)); err != nil {
+						return nil, err
+					}
+				}
+				b = appendTag(b, f.number, wireBytes)
+				b = binary.AppendUvarint(b, uint64(len(packed)))
+				b = append(b, packed...)
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				b = appendTag(b, f.number, wireBytes)
+				if b, err = appendBytesValue(b, fv.Index(i)); err != nil {
+					return nil, fmt.Errorf("field %s: %w", f.name, err)
+				}
+			}
+			continue
+		}
+
+		// proto3 omits default values
+		if fv.IsZero() {
+			continue
+		}
+		b = appendTag(b, f.number, f.wireType())
+		if f.encoding == "bytes" {
+			b, err = appendBytesValue(b, fv)
+		} else {
+			b, err = appendScalar(b, f.encoding, fv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return b, nil
+}
+
+func appendScalar(b []byte, encoding string, v reflect.Value) ([]byte, error) {
+	switch encoding {
+	case "varint":
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				return append(b, 1), nil
+			}
+			return append(b, 0), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// Negative values are sign-extended to 64 bits (10 bytes)
+			return binary.AppendUvarint(b, uint64(v.Int())), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return binary.AppendUvarint(b, v.Uint()), nil
+		}
+	case "zigzag32", "zigzag64":
+		if v.CanInt() {
+			return binary.AppendVarint(b, v.Int()), nil
+		}
+	case "fixed32":
+		switch v.Kind() {
+		case reflect.Float32:
+			return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v.Float()))), nil
+		case reflect.Int32:
+			return binary.LittleEndian.AppendUint32(b, uint32(v.Int())), nil
+		case reflect.Uint32:
+			return binary.LittleEndian.AppendUint32(b, uint32(v.Uint())), nil
+		}
+	case "fixed64":
+		switch v.Kind() {
+		case reflect.Float64:
+			return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float())), nil
+		case reflect.Int64:
+			return binary.LittleEndian.AppendUint64(b, uint64(v.Int())), nil
+		case reflect.Uint64:
+			return binary.LittleEndian.AppendUint64(b, v.Uint()), nil
+		}
+	}
+	return nil, fmt.Errorf("protobuf: cannot encode %s as %s", v.Type(), encoding)
+}
+
+func appendBytesValue(b []byte, v reflect.Value) ([]byte, error) {
+	var payload []byte
+	switch {
+	case v.Kind() == reflect.String:
+		payload = []byte(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		payload = v.Bytes()
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+		fallthrough
+	case v.Kind() == reflect.Struct:
+		var err error
+		if payload, err = appendMessage(nil, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("protobuf: cannot encode %s as bytes", v.Type())
+	}
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	return append(b, payload...), nil
+}
+
+// UnmarshalProto decodes protobuf wire data into v, a pointer to a struct,
+// resetting it first. Unknown fields are skipped, as the protobuf spec
+// requires.
+func UnmarshalProto(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("protobuf: Unmarshal requires a non-nil pointer to a struct")
+	}
+	rv.Elem().SetZero()
+	return decodeMessage(data, rv.Elem())
+}
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+func decodeMessage(data []byte, v reflect.Value) error {
+	fields, err := protoFields(v.Type())
+	if err != nil {
+		return err
+	}
+	byNumber := make(map[int]protoField, len(fields))
+	for _, f := range fields {
+		byNumber[f.number] = f
+	}
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+		number, wireType := int(key>>3), int(key&7)
+
+		var raw []byte
+		switch wireType {
+		case wireVarint:
+			_, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			length, m := binary.Uvarint(data)
+			if m <= 0 || length > uint64(len(data)-m) {
+				return errProtoTruncated
+			}
+			data = data[m:]
+			n = int(length)
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", wireType)
+		}
+		if n > len(data) {
+			return errProtoTruncated
+		}
+		raw, data = data[:n], data[n:]
+
+		f, ok := byNumber[number]
+		if !ok {
+			continue
+		}
+		if err := decodeField(f, wireType, raw, v.Field(f.index)); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func decodeField(f protoField, wireType int, raw []byte, fv reflect.Value) error {
+	if !f.repeated {
+		if wireType != f.wireType() {
+			return fmt.Errorf("wire type %d, want %d", wireType, f.wireType())
+		}
+		_, err := decodeValue(f.encoding, raw, fv)
+		return err
+	}
+
+	elem := reflect.New(fv.Type().Elem()).Elem()
+	if f.encoding == "bytes" || wireType != wireBytes {
+		if _, err := decodeValue(f.encoding, raw, elem); err != nil {
+			return err
+		}
+		fv.Set(reflect.Append(fv, elem))
+		return nil
+	}
+	// Packed repeated scalars
+	for len(raw) > 0 {
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		n, err := decodeValue(f.encoding, raw, elem)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.Append(fv, elem))
+		raw = raw[n:]
+	}
+	return nil
+}
+
+// decodeValue decodes one value from the start of raw and returns the number
+// of bytes consumed
+func decodeValue(encoding string, raw []byte, v reflect.Value) (int, error) {
+	switch encoding {
+	case "varint", "zigzag32", "zigzag64":
+		u, n := binary.Uvarint(raw)
+		if n <= 0 {
+			return 0, errProtoTruncated
+		}
+		switch {
+		case v.Kind() == reflect.Bool:
+			v.SetBool(u != 0)
+		case encoding != "varint" && v.CanInt():
+			v.SetInt(int64(u>>1) ^ -int64(u&1))
+		case v.CanInt():
+			v.SetInt(int64(u))
+		case v.CanUint():
+			v.SetUint(u)
+		default:
+			return 0, fmt.Errorf("cannot decode %s into %s", encoding, v.Type())
+		}
+		return n, nil
+	case "fixed32":
+		if len(raw) < 4 {
+			return 0, errProtoTruncated
+		}
+		u := binary.LittleEndian.Uint32(raw)
+		switch v.Kind() {
+		case reflect.Float32:
+			v.SetFloat(float64(math.Float32frombits(u)))
+		case reflect.Int32:
+			v.SetInt(int64(int32(u)))
+		case reflect.Uint32:
+			v.SetUint(uint64(u))
+		default:
+			return 0, fmt.Errorf("cannot decode fixed32 into %s", v.Type())
+		}
+		return 4, nil
+	case "fixed64":
+		if len(raw) < 8 {
+			return 0, errProtoTruncated
+		}
+		u := binary.LittleEndian.Uint64(raw)
+		switch v.Kind() {
+		case reflect.Float64:
+			v.SetFloat(math.Float64frombits(u))
+		case reflect.Int64:
+			v.SetInt(int64(u))
+		case reflect.Uint64:
+			v.SetUint(u)
+		default:
+			return 0, fmt.Errorf("cannot decode fixed64 into %s", v.Type())
+		}
+		return 8, nil
+	case "bytes":
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(raw))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), raw...))
+		case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return len(raw), decodeMessage(raw, v.Elem())
+		case v.Kind() == reflect.Struct:
+			return len(raw), decodeMessage(raw, v)
+		default:
+			return 0, fmt.Errorf("cannot decode bytes into %s", v.Type())
+		}
+		return len(raw), nil
+	}
+	return 0, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// GenerateProto writes a proto3 schema for the given tagged structs and every
+// message type they reference, for sharing the contract with other languages.
+// protoc compiles such a schema into equivalent Go types; pb/chat.proto and
+// its generated pb.ChatMessage are the example's.
+func GenerateProto(pkg string, messages ...any) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "syntax = \"proto3\";\n\npackage %s;\n", pkg)
+
+	seen := map[reflect.Type]bool{}
+	queue := make([]reflect.Type, 0, len(messages))
+	for _, m := range messages {
+		queue = append(queue, reflect.Indirect(reflect.ValueOf(m)).Type())
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		fields, err := protoFields(t)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\nmessage %s {\n", t.Name())
+		for _, f := range fields {
+			ft := t.Field(f.index).Type
+			if f.repeated {
+				ft = ft.Elem()
+			}
+			typeName, nested, err := protoTypeName(f.encoding, ft)
+			if err != nil {
+				return "", fmt.Errorf("%s.%s: %w", t.Name(), t.Field(f.index).Name, err)
+			}
+			if nested != nil {
+				queue = append(queue, nested)
+			}
+			label := ""
+			if f.repeated {
+				label = "repeated "
+			}
+			fmt.Fprintf(&sb, "  %s%s %s = %d;\n", label, typeName, f.name, f.number)
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String(), nil
+}
+
+func protoTypeName(encoding string, t reflect.Type) (string, reflect.Type, error) {
+	switch encoding {
+	case "varint":
+		switch t.Kind() {
+		case reflect.Bool:
+			return "bool", nil, nil
+		case reflect.Int32:
+			return "int32", nil, nil
+		case reflect.Int, reflect.Int64:
+			return "int64", nil, nil
+		case reflect.Uint32:
+			return "uint32", nil, nil
+		case reflect.Uint, reflect.Uint64:
+			return "uint64", nil, nil
+		}
+	case "zigzag32":
+		return "sint32", nil, nil
+	case "zigzag64":
+		return "sint64", nil, nil
+	case "fixed32":
+		switch t.Kind() {
+		case reflect.Float32:
+			return "float", nil, nil
+		case reflect.Int32:
+			return "sfixed32", nil, nil
+		case reflect.Uint32:
+			return "fixed32", nil, nil
+		}
+	case "fixed64":
+		switch t.Kind() {
+		case reflect.Float64:
+			return "double", nil, nil
+		case reflect.Int64:
+			return "sfixed64", nil, nil
+		case reflect.Uint64:
+			return "fixed64", nil, nil
+		}
+	case "bytes":
+		switch {
+		case t.Kind() == reflect.String:
+			return "string", nil, nil
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			return "bytes", nil, nil
+		case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct:
+			return t.Elem().Name(), t.Elem(), nil
+		case t.Kind() == reflect.Struct:
+			return t.Name(), t, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unsupported %s field of type %s", encoding, t)
+}
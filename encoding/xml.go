@@ -0,0 +1,77 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+)
+
+// MarshalXML encodes v with an XML declaration, indented when indent is set
+func MarshalXML(v any, indent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if indent {
+		enc.Indent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// NamespaceAttrs returns xmlns declarations for the given prefix → URI map,
+// sorted by prefix. Assign the result to a field tagged `xml:",any,attr"` on
+// the root element; an empty prefix declares the default namespace.
+func NamespaceAttrs(namespaces map[string]string) []xml.Attr {
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	attrs := make([]xml.Attr, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		// encoding/xml would treat a Space of "xmlns" as a namespace URI and
+		// declare it, so the prefixed name goes in Local
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: namespaces[prefix]})
+	}
+	return attrs
+}
+
+// DecodeXMLElements streams r and decodes every element named local in
+// namespace space (any namespace when space is empty) into a fresh value from
+// newValue, passing it to fn. Only one element is in memory at a time, so it
+// suits large feeds and exports.
+func DecodeXMLElements(r io.Reader, space, local string, newValue func() any, fn func(v any) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != local || (space != "" && start.Name.Space != space) {
+			continue
+		}
+		v := newValue()
+		if err := dec.DecodeElement(v, &start); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package filestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when reading content whose bytes no
+// longer match the SHA-256 in its key
+var ErrChecksumMismatch = errors.New("filestore: content does not match its checksum")
+
+// ContentStore keys objects by the SHA-256 of their content, so storing the
+// same bytes twice keeps one copy and a key always names the same content
+type ContentStore struct {
+	store  Store
+	prefix string
+}
+
+// NewContentStore stores content in store under prefix, such as "blobs/"
+func NewContentStore(store Store, prefix string) *ContentStore {
+	return &ContentStore{store: store, prefix: prefix}
+}
+
+// Key returns the key for content with the given hex SHA-256. The first two
+// digits form a directory, to keep directories small on local disk.
+func (c *ContentStore) Key(sum string) string {
+	return c.prefix + sum[:2] + "/" + sum
+}
+
+// Sum returns the hex SHA-256 in a key made by Key
+func (c *ContentStore) Sum(key string) (string, error) {
+	rest, ok := strings.CutPrefix(key, c.prefix)
+	_, sum, found := strings.Cut(rest, "/")
+	if !ok || !found || len(sum) != sha256.Size*2 || c.Key(sum) != key {
+		return "", fmt.Errorf("%w: %q is not a content key", ErrInvalidKey, key)
+	}
+	return sum, nil
+}
+
+// Put stores r and returns its info; Key holds the content's key. The body
+// is spooled to a temporary file while it is hashed, since the key is only
+// known at the end; content already stored is not uploaded again.
+func (c *ContentStore) Put(ctx context.Context, r io.Reader, opts PutOptions) (info ObjectInfo, existed bool, err error) {
+	return c.put(ctx, r, opts, "")
+}
+
+// PutSum is Put for content the caller has a hex SHA-256 for, such as one
+// sent with an upload. Content that does not match sum is not stored and
+// ErrChecksumMismatch is returned.
+func (c *ContentStore) PutSum(ctx context.Context, r io.Reader, opts PutOptions, sum string) (info ObjectInfo, existed bool, err error) {
+	if sum == "" {
+		return ObjectInfo{}, false, fmt.Errorf("%w: empty checksum", ErrChecksumMismatch)
+	}
+	return c.put(ctx, r, opts, strings.ToLower(sum))
+}
+
+func (c *ContentStore) put(ctx context.Context, r io.Reader, opts PutOptions, want string) (info ObjectInfo, existed bool, err error) {
+	spool, err := os.CreateTemp("", "filestore-spool-*")
+	if err != nil {
+		return ObjectInfo{}, false, fmt.Errorf("filestore: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), contextReader{ctx, r})
+	if err != nil {
+		return ObjectInfo{}, false, fmt.Errorf("filestore: spooling upload: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if want != "" && sum != want {
+		return ObjectInfo{}, false, fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, sum, want)
+	}
+	key := c.Key(sum)
+
+	if info, err := c.store.Stat(ctx, key); err == nil {
+		return info, true, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return ObjectInfo{}, false, err
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return ObjectInfo{}, false, fmt.Errorf("filestore: %w", err)
+	}
+	opts.Size = size
+	info, err = c.store.Put(ctx, key, spool, opts)
+	return info, false, err
+}
+
+// Get opens content by key. The reader returns ErrChecksumMismatch at the
+// end if the stored bytes were corrupted.
+func (c *ContentStore) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	sum, err := c.Sum(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	body, info, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return &verifyingReader{ReadCloser: body, hash: sha256.New(), want: sum}, info, nil
+}
+
+// Store returns the underlying store, for listing, deleting and signing
+// URLs
+func (c *ContentStore) Store() Store { return c.store }
+
+type verifyingReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	want string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	v.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) && hex.EncodeToString(v.hash.Sum(nil)) != v.want {
+		return n, ErrChecksumMismatch
+	}
+	return n, err
+}
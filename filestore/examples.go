@@ -0,0 +1,353 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalStoreExamples stores, lists and serves files from a directory
+func LocalStoreExamples() {
+	fmt.Println("\n=== Local Disk Store ===")
+	dir, err := os.MkdirTemp("", "filestore-local")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	store, err := NewLocalStore(dir, LocalOptions{BaseURL: srv.URL + "/files"})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	mux.Handle("/files/", http.StripPrefix("/files", store.Handler()))
+
+	ctx := context.Background()
+	for key, body := range map[string]string{
+		"reports/2024/q1.csv": "quarter,revenue\nQ1,120\n",
+		"reports/2024/q2.csv": "quarter,revenue\nQ2,135\n",
+		"avatars/ann.png":     "\x89PNG...",
+	} {
+		if _, err := store.Put(ctx, key, strings.NewReader(body), PutOptions{}); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+	objects, _ := store.List(ctx, "reports/")
+	fmt.Println("📂 reports/:")
+	for _, o := range objects {
+		fmt.Printf("   %-22s %3d bytes  %s\n", o.Key, o.Size, o.ContentType)
+	}
+
+	for _, key := range []string{"../etc/passwd", "reports//q1.csv", `reports\q1.csv`} {
+		_, err := store.Put(ctx, key, strings.NewReader("x"), PutOptions{})
+		fmt.Printf("🚫 %v\n", err)
+	}
+
+	signed, _ := store.SignedURL(ctx, http.MethodGet, "reports/2024/q1.csv", time.Minute)
+	fmt.Printf("🔗 signed URL: %s\n", strings.Replace(signed, srv.URL, "http://localhost:8080", 1))
+	body, status := fetch(http.MethodGet, signed, nil)
+	fmt.Printf("   GET → %d %q\n", status, body)
+	_, status = fetch(http.MethodGet, strings.Replace(signed, "q1.csv", "q2.csv", 1), nil)
+	fmt.Printf("   GET another key with the same signature → %d\n", status)
+
+	upload, _ := store.SignedURL(ctx, http.MethodPut, "inbox/scan.txt", time.Minute)
+	_, status = fetch(http.MethodPut, upload, strings.NewReader("uploaded without credentials"))
+	info, err := store.Stat(ctx, "inbox/scan.txt")
+	fmt.Printf("   PUT → %d, stored %d bytes (err=%v)\n", status, info.Size, err)
+
+	store.Delete(ctx, "avatars/ann.png")
+	_, _, err = store.Get(ctx, "avatars/ann.png")
+	_, statErr := os.Stat(filepath.Join(dir, "avatars"))
+	fmt.Printf("🗑️  deleted: %v, empty directory removed: %v\n", errors.Is(err, ErrNotFound), os.IsNotExist(statErr))
+}
+
+// S3StoreExamples talks to an in-process S3 stand-in, uploading a stream
+// of unknown length in parts
+func S3StoreExamples() {
+	fmt.Println("\n=== S3-Compatible Store ===")
+	fake := newFakeS3("demo-access", "demo-secret")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "uploads",
+		AccessKey: "demo-access",
+		SecretKey: "demo-secret",
+		PartSize:  64 << 10, // tiny parts for the demo; S3 wants 5 MiB or more
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	ctx := context.Background()
+
+	info, err := store.Put(ctx, "notes/hello world.txt", strings.NewReader("hello, object storage"),
+		PutOptions{ContentType: "text/plain", Size: 21})
+	fmt.Printf("⬆️  single PUT: %s %d bytes etag=%s err=%v\n", info.Key, info.Size, info.ETag, err)
+
+	// An io.Reader with no known length, like a request body being relayed
+	big := bytes.Repeat([]byte("0123456789abcdef"), 14000)
+	info, err = store.Put(ctx, "backups/db.dump", io.MultiReader(bytes.NewReader(big)), PutOptions{})
+	fmt.Printf("⬆️  multipart: %s %d bytes in %d parts, etag=%s err=%v\n", info.Key, info.Size, fake.lastParts, info.ETag, err)
+
+	for i := range 3 {
+		store.Put(ctx, fmt.Sprintf("notes/%d.txt", i), strings.NewReader("note"), PutOptions{Size: 4})
+	}
+	objects, err := store.List(ctx, "notes/")
+	fmt.Printf("📂 notes/ (%d list requests for pages of 2):\n", fake.listCalls)
+	for _, o := range objects {
+		fmt.Printf("   %-22s %d bytes\n", o.Key, o.Size)
+	}
+
+	body, info, err := store.Get(ctx, "backups/db.dump")
+	if err == nil {
+		data, _ := io.ReadAll(body)
+		body.Close()
+		fmt.Printf("⬇️  GET backups/db.dump: %d bytes, intact=%v\n", info.Size, bytes.Equal(data, big))
+	}
+
+	signed, _ := store.SignedURL(ctx, http.MethodGet, "notes/hello world.txt", 15*time.Minute)
+	text, status := fetch(http.MethodGet, signed, nil)
+	fmt.Printf("🔗 presigned GET → %d %q\n", status, text)
+	_, status = fetch(http.MethodGet, strings.Replace(signed, "X-Amz-Expires=900", "X-Amz-Expires=9000", 1), nil)
+	fmt.Printf("🔗 tampered expiry → %d\n", status)
+
+	store.Delete(ctx, "backups/db.dump")
+	_, err = store.Stat(ctx, "backups/db.dump")
+	fmt.Printf("🗑️  after delete: %v\n", err)
+
+	bad, _ := NewS3Store(S3Options{Endpoint: srv.URL, Bucket: "uploads", AccessKey: "demo-access", SecretKey: "wrong"})
+	_, err = bad.Put(ctx, "x.txt", strings.NewReader("x"), PutOptions{Size: 1})
+	fmt.Printf("🔑 wrong secret: %v\n", err)
+}
+
+// ContentStoreExamples deduplicates by SHA-256 and detects corruption
+func ContentStoreExamples() {
+	fmt.Println("\n=== Content-Addressable Storage ===")
+	dir, err := os.MkdirTemp("", "filestore-cas")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	local, _ := NewLocalStore(dir, LocalOptions{})
+	blobs := NewContentStore(local, "blobs/")
+	ctx := context.Background()
+
+	for _, content := range []string{"same bytes", "same bytes", "other bytes"} {
+		info, existed, err := blobs.Put(ctx, strings.NewReader(content), PutOptions{})
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		fmt.Printf("📦 %-12q → %s… existed=%v\n", content, info.Key[:24], existed)
+	}
+	all, _ := local.List(ctx, "blobs/")
+	fmt.Printf("   %d objects stored for 3 uploads\n", len(all))
+
+	// A checksum sent by the uploader is checked before anything is stored
+	claimed, _ := blobs.Sum(all[0].Key)
+	_, _, err = blobs.PutSum(ctx, strings.NewReader("corrupted in transit"), PutOptions{}, claimed)
+	fmt.Printf("🧮 upload with the wrong checksum: %v\n", errors.Is(err, ErrChecksumMismatch))
+
+	// Flip the stored bytes behind the store's back
+	key := all[0].Key
+	os.WriteFile(filepath.Join(dir, filepath.FromSlash(key)), []byte("tampered!!"), 0o644)
+	body, _, err := blobs.Get(ctx, key)
+	if err == nil {
+		_, err = io.ReadAll(body)
+		body.Close()
+	}
+	fmt.Printf("🛡️  reading tampered blob: %v\n", err)
+	_, err = blobs.Sum("blobs/not-a-hash")
+	fmt.Printf("🚫 %v\n", err)
+}
+
+func fetch(method, rawURL string, body io.Reader) (string, int) {
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return err.Error(), 0
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err.Error(), 0
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return string(data), resp.StatusCode
+}
+
+// fakeS3 implements enough of the S3 API for the examples and checks
+// request signatures with the same algorithm as the client
+type fakeS3 struct {
+	signer    signer
+	mu        sync.Mutex
+	objects   map[string][]byte // by "bucket/key"
+	uploads   map[string]map[int][]byte
+	lastParts int
+	listCalls int
+}
+
+func newFakeS3(accessKey, secretKey string) *fakeS3 {
+	return &fakeS3{
+		signer:  signer{accessKey: accessKey, secretKey: secretKey, region: "us-east-1", service: "s3"},
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int][]byte),
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		s3Fail(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	name := bucket + "/" + key
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, bucket, query)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		f.uploads[query.Get("uploadId")][number] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		var data []byte
+		for number := 1; number <= len(parts); number++ {
+			data = append(data, parts[number]...)
+		}
+		f.objects[name], f.lastParts = data, len(parts)
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><ETag>\"%x-%d\"</ETag></CompleteMultipartUploadResult>", md5.Sum(data), len(parts))
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[name] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[name]
+		if !ok {
+			s3Fail(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("ETag", etag(data))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, name)
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Fail(w, http.StatusNotImplemented, "NotImplemented", r.Method)
+	}
+}
+
+// list answers ListObjectsV2 two keys at a time to exercise paging
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, query url.Values) {
+	f.listCalls++
+	var keys []string
+	for name := range f.objects {
+		if key, ok := strings.CutPrefix(name, bucket+"/"); ok && strings.HasPrefix(key, query.Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	end := min(start+2, len(keys))
+
+	var result listBucketResult
+	for _, key := range keys[start:end] {
+		data := f.objects[bucket+"/"+key]
+		result.Contents = append(result.Contents, struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			ETag         string    `xml:"ETag"`
+			LastModified time.Time `xml:"LastModified"`
+		}{key, int64(len(data)), etag(data), time.Now().UTC()})
+	}
+	if end < len(keys) {
+		result.IsTruncated, result.NextContinuationToken = true, strconv.Itoa(end)
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: result})
+}
+
+// authorized re-signs the request and compares signatures
+func (f *fakeS3) authorized(r *http.Request) bool {
+	u := *r.URL
+	u.Scheme, u.Host = "http", r.Host
+	query := u.Query()
+
+	if signature := query.Get("X-Amz-Signature"); signature != "" {
+		now, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+		expires, _ := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || time.Since(now) > time.Duration(expires)*time.Second {
+			return false
+		}
+		for name := range query {
+			if strings.HasPrefix(name, "X-Amz-") {
+				query.Del(name)
+			}
+		}
+		u.RawQuery = canonicalQuery(query)
+		resigned, _ := url.Parse(f.signer.presign(r.Method, &u, time.Duration(expires)*time.Second, now))
+		return resigned.Query().Get("X-Amz-Signature") == signature
+	}
+
+	now, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	req := &http.Request{Method: r.Method, URL: &u, Header: http.Header{}}
+	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	f.signer.sign(req, r.Header.Get("X-Amz-Content-Sha256"), now)
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func s3Fail(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// RunAllFilestoreExamples runs all filestore examples
+func RunAllFilestoreExamples() {
+	LocalStoreExamples()
+	S3StoreExamples()
+	ContentStoreExamples()
+}
@@ -0,0 +1,322 @@
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/io_ops"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// LocalOptions configures a LocalStore
+type LocalOptions struct {
+	// BaseURL is where Handler is mounted, such as
+	// "http://localhost:8080/files"; SignedURL needs it
+	BaseURL string
+	// SigningKey signs URLs. A random key is used if empty, so signed URLs
+	// stop working when the process restarts.
+	SigningKey []byte
+}
+
+// LocalStore keeps objects as files under a root directory. Writes go to a
+// temporary file that is renamed into place, so readers never see a
+// partial object. Content types are not stored and come from the key's
+// extension instead.
+type LocalStore struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// NewLocalStore creates root if needed and stores objects beneath it
+func NewLocalStore(root string, opts LocalOptions) (*LocalStore, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("filestore: %w", err)
+	}
+	if len(opts.SigningKey) == 0 {
+		opts.SigningKey = string_op.RandomBytes(32)
+	}
+	return &LocalStore{root: abs, baseURL: strings.TrimSuffix(opts.BaseURL, "/"), signingKey: opts.SigningKey}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put implements Store
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: %w", err)
+	}
+	f, err := io_ops.CreateAtomic(p, 0o644)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: %w", err)
+	}
+	defer f.Abort()
+
+	hash := md5.New()
+	n, err := io.Copy(io.MultiWriter(f, hash), contextReader{ctx, r})
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: writing %s: %w", key, err)
+	}
+	if err := f.Commit(); err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: writing %s: %w", key, err)
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = contentTypeOf(key)
+	}
+	return ObjectInfo{
+		Key:          key,
+		Size:         n,
+		ContentType:  contentType,
+		ETag:         hex.EncodeToString(hash.Sum(nil)),
+		LastModified: time.Now(),
+	}, nil
+}
+
+// Get implements Store
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	f, info, err := s.open(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return f, info, nil
+}
+
+func (s *LocalStore) open(key string) (*os.File, ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, fmt.Errorf("filestore: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		f.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return f, fileInfo(key, fi), nil
+}
+
+// Stat implements Store. The ETag is left empty, since it would mean
+// reading the whole file.
+func (s *LocalStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && fi.IsDir() {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: %w", err)
+	}
+	return fileInfo(key, fi), nil
+}
+
+// Delete implements Store. Directories left empty are removed too.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("filestore: %w", err)
+	}
+	for dir := filepath.Dir(p); dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// List implements Store
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || isTempFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		objects = append(objects, fileInfo(key, fi))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filestore: %w", err)
+	}
+	// WalkDir orders by name within each directory, which is not key order
+	// ("a/b" sorts after "a-b")
+	slices.SortFunc(objects, func(a, b ObjectInfo) int { return strings.Compare(a.Key, b.Key) })
+	return objects, nil
+}
+
+// SignedURL implements Store with an HMAC over the method, key and expiry,
+// checked by Handler
+func (s *LocalStore) SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if s.baseURL == "" {
+		return "", errors.New("filestore: LocalOptions.BaseURL is needed for signed URLs")
+	}
+	if method != http.MethodGet && method != http.MethodPut {
+		return "", fmt.Errorf("filestore: cannot sign %s URLs", method)
+	}
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	expiry := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{"expires": {expiry}, "signature": {s.sign(method, key, expiry)}}
+	return s.baseURL + "/" + uriEncode(key, false) + "?" + query.Encode(), nil
+}
+
+func (s *LocalStore) sign(method, key, expiry string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(method + "\n" + key + "\n" + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a signed URL's query for method on key
+func (s *LocalStore) verify(method, key string, query url.Values) error {
+	expiry := query.Get("expires")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return errors.New("missing or malformed expiry")
+	}
+	if time.Now().Unix() > unix {
+		return errors.New("URL has expired")
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(s.sign(method, key, expiry))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// Handler serves signed URLs: GET downloads an object, with Range support,
+// and PUT uploads one. Mount it at BaseURL's path with http.StripPrefix.
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		if method != http.MethodGet && method != http.MethodPut {
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.verify(method, key, r.URL.Query()); err != nil {
+			http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if method == http.MethodPut {
+			info, err := s.Put(r.Context(), key, r.Body, PutOptions{ContentType: r.Header.Get("Content-Type"), Size: r.ContentLength})
+			if err != nil {
+				logger.Error("Signed upload failed", "key", key, "error", err)
+				http.Error(w, "Upload failed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", strconv.Quote(info.ETag))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(info)
+			return
+		}
+
+		f, info, err := s.open(key)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Download failed", http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Type", info.ContentType)
+		http.ServeContent(w, r, path.Base(key), info.LastModified, f)
+	})
+}
+
+func fileInfo(key string, fi fs.FileInfo) ObjectInfo {
+	return ObjectInfo{Key: key, Size: fi.Size(), ContentType: contentTypeOf(key), LastModified: fi.ModTime()}
+}
+
+func contentTypeOf(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// isTempFile matches the in-progress files made by io_ops.CreateAtomic
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// contextReader stops a copy once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,364 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3Store
+type S3Options struct {
+	// Endpoint is the server's base URL, such as "http://localhost:9000"
+	// for MinIO. Buckets are addressed in the path when it is set, as most
+	// S3-compatible servers expect, and as a subdomain of the regional AWS
+	// endpoint when it is empty.
+	Endpoint string
+	// Region defaults to "us-east-1"
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PartSize is the size of multipart upload parts, defaults to 8 MiB.
+	// Bodies of unknown size or larger than a part are uploaded in parts;
+	// S3 requires every part but the last to be at least 5 MiB.
+	PartSize int64
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// S3Store keeps objects in an S3 bucket, signing requests with AWS
+// Signature Version 4
+type S3Store struct {
+	base     *url.URL // bucket URL
+	partSize int64
+	client   *http.Client
+	signer   signer
+}
+
+// NewS3Store creates a store for an existing bucket
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("filestore: S3Options.Bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.PartSize <= 0 {
+		opts.PartSize = 8 << 20
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	var base *url.URL
+	if opts.Endpoint == "" {
+		base = &url.URL{Scheme: "https", Host: opts.Bucket + ".s3." + opts.Region + ".amazonaws.com", Path: "/"}
+	} else {
+		u, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("filestore: invalid S3 endpoint %q", opts.Endpoint)
+		}
+		u.Path += "/" + opts.Bucket + "/"
+		base = u
+	}
+	return &S3Store{
+		base:     base,
+		partSize: opts.PartSize,
+		client:   opts.Client,
+		signer:   signer{accessKey: opts.AccessKey, secretKey: opts.SecretKey, region: opts.Region, service: "s3"},
+	}, nil
+}
+
+// objectURL returns the canonical URL of key, or of the bucket if key is
+// empty, with query
+func (s *S3Store) objectURL(key string, query url.Values) *url.URL {
+	u := *s.base
+	u.Path += key
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request and turns S3 error responses into errors
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("filestore: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.signer.sign(req, unsignedPayload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("filestore: s3 %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s3Error(resp, method, key)
+	}
+	return resp, nil
+}
+
+type s3ErrorBody struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func s3Error(resp *http.Response, method, key string) error {
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	var body s3ErrorBody
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &body) != nil || body.Code == "" {
+		body.Code = resp.Status
+	}
+	return fmt.Errorf("filestore: s3 %s %s: %s %s", method, key, body.Code, body.Message)
+}
+
+// Put implements Store. Bodies of unknown size are read a part at a time,
+// so a stream of any length is uploaded with one part in memory.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error) {
+	if err := ValidateKey(key); err != nil {
+		return ObjectInfo{}, err
+	}
+	header := http.Header{}
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.Size > 0 && opts.Size <= s.partSize {
+		return s.putObject(ctx, key, io.LimitReader(r, opts.Size), opts.Size, header)
+	}
+
+	// Read one part to find out whether the body fits in a single request
+	part := make([]byte, s.partSize)
+	n, err := io.ReadFull(r, part)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s.putObject(ctx, key, bytes.NewReader(part[:n]), int64(n), header)
+	}
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("filestore: reading %s: %w", key, err)
+	}
+	return s.putMultipart(ctx, key, part, r, header)
+}
+
+func (s *S3Store) putObject(ctx context.Context, key string, body io.Reader, size int64, header http.Header) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodPut, key, nil, body, size, header)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	return ObjectInfo{
+		Key:          key,
+		Size:         size,
+		ContentType:  header.Get("Content-Type"),
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		LastModified: time.Now(),
+	}, nil
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putMultipart uploads first, which is a full part, and then the rest of r
+// in parts, aborting the upload if any part fails
+func (s *S3Store) putMultipart(ctx context.Context, key string, first []byte, r io.Reader, header http.Header) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, header)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return ObjectInfo{}, fmt.Errorf("filestore: s3 multipart upload of %s: no upload ID", key)
+	}
+	uploadID := initiated.UploadID
+
+	abort := func(cause error) (ObjectInfo, error) {
+		// A fresh context, since ctx may be why the upload failed
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if resp, err := s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, 0, nil); err != nil {
+			logger.Warn("Failed to abort multipart upload", "key", key, "upload_id", uploadID, "error", err)
+		} else {
+			resp.Body.Close()
+		}
+		return ObjectInfo{}, cause
+	}
+
+	var complete completeMultipartUpload
+	var size int64
+	buf, n := first, len(first)
+	for number := 1; n > 0; number++ {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, bytes.NewReader(buf[:n]), int64(n), nil)
+		if err != nil {
+			return abort(err)
+		}
+		resp.Body.Close()
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		size += int64(n)
+		logger.Debug("Uploaded part", "key", key, "part", number, "bytes", n)
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return abort(fmt.Errorf("filestore: reading %s: %w", key, err))
+		}
+	}
+
+	body, _ := xml.Marshal(complete)
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), nil)
+	if err != nil {
+		return abort(err)
+	}
+	defer resp.Body.Close()
+	// S3 can report a failed completion in the body of a 200 response
+	data, _ := io.ReadAll(resp.Body)
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		s3ErrorBody
+	}
+	if xml.Unmarshal(data, &result) == nil && result.XMLName.Local == "Error" {
+		return abort(fmt.Errorf("filestore: s3 completing %s: %s %s", key, result.Code, result.Message))
+	}
+	logger.Info("Multipart upload complete", "key", key, "parts", len(complete.Parts), "size", size)
+	return ObjectInfo{
+		Key:          key,
+		Size:         size,
+		ContentType:  header.Get("Content-Type"),
+		ETag:         strings.Trim(result.ETag, `"`),
+		LastModified: time.Now(),
+	}, nil
+}
+
+// Get implements Store
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, nil)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return resp.Body, headerInfo(key, resp), nil
+}
+
+// Stat implements Store
+func (s *S3Store) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	if err := ValidateKey(key); err != nil {
+		return ObjectInfo{}, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, 0, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	return headerInfo(key, resp), nil
+}
+
+func headerInfo(key string, resp *http.Response) ObjectInfo {
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return ObjectInfo{
+		Key:          key,
+		Size:         resp.ContentLength,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		LastModified: modified,
+	}
+}
+
+// Delete implements Store
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Store, following continuation tokens through every page
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("filestore: s3 list %q: %w", prefix, err)
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          c.Key,
+				Size:         c.Size,
+				ETag:         strings.Trim(c.ETag, `"`),
+				LastModified: c.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// SignedURL implements Store with a presigned URL, valid for up to 7 days
+func (s *S3Store) SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("filestore: S3 signed URLs expire after 1s to 7 days, not %v", expires)
+	}
+	return s.signer.presign(method, s.objectURL(key, nil), expires, time.Now()), nil
+}
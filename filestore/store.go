@@ -0,0 +1,97 @@
+// Package filestore stores objects, blobs of bytes addressed by a key such
+// as "avatars/42.png", on local disk or in S3-compatible object storage
+// such as AWS S3 and MinIO.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("filestore")
+
+var (
+	ErrNotFound   = errors.New("filestore: object not found")
+	ErrInvalidKey = errors.New("filestore: invalid key")
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// PutOptions describes an object being written
+type PutOptions struct {
+	ContentType string
+	// Size is the length of the body, or 0 if unknown. Stores may use it
+	// to avoid buffering.
+	Size int64
+}
+
+// Store is an object store. Keys are slash-separated paths; see ValidateKey.
+type Store interface {
+	// Put streams r into the object at key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error)
+	// Get opens the object for reading; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	// Stat returns the object's info without its contents
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// SignedURL returns a URL that allows method (GET or PUT) on key
+	// without credentials until it expires
+	SignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error)
+}
+
+// ValidateKey rejects keys that could escape a store's root or are not
+// portable between stores: empty keys and segments, "." and "..",
+// backslashes, control characters and keys over 1024 bytes
+func ValidateKey(key string) error {
+	if key == "" || len(key) > 1024 {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	if strings.ContainsRune(key, '\\') || strings.ContainsFunc(key, unicode.IsControl) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+// FromEnv returns an S3 store when FILESTORE_S3_BUCKET is set, configured
+// by FILESTORE_S3_ENDPOINT, FILESTORE_S3_REGION, AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, and otherwise a local store in FILESTORE_DIR
+// (default "./data/files") whose signed URLs start with baseURL
+func FromEnv(baseURL string) (Store, error) {
+	if bucket := os.Getenv("FILESTORE_S3_BUCKET"); bucket != "" {
+		return NewS3Store(S3Options{
+			Endpoint:  os.Getenv("FILESTORE_S3_ENDPOINT"),
+			Region:    os.Getenv("FILESTORE_S3_REGION"),
+			Bucket:    bucket,
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	}
+	dir := os.Getenv("FILESTORE_DIR")
+	if dir == "" {
+		dir = "./data/files"
+	}
+	return NewLocalStore(dir, LocalOptions{BaseURL: baseURL})
+}
@@ -0,0 +1,229 @@
+package format
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DiffOp is the kind of a line in a diff
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffLine is one line of a line-based diff. OldLine and NewLine are 1-based
+// line numbers, or 0 when the line does not exist on that side.
+type DiffLine struct {
+	Op      DiffOp
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// DiffLines computes the shortest edit script between a and b using Myers'
+// O(ND) algorithm
+func DiffLines(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit == 0 {
+		return nil
+	}
+
+	offset := limit
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+search:
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards from (n, m) to recover the edits
+	var reversed []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: DiffEqual, Text: a[x-1], OldLine: x, NewLine: y})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffLine{Op: DiffInsert, Text: b[y-1], NewLine: y})
+			} else {
+				reversed = append(reversed, DiffLine{Op: DiffDelete, Text: a[x-1], OldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// UnifiedDiff renders the differences between two texts in unified format
+// with the given number of context lines; it returns "" when they are equal
+func UnifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	lines := DiffLines(splitLines(oldText), splitLines(newText))
+
+	var changed []int
+	for i, line := range lines {
+		if line.Op != DiffEqual {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(changed); {
+		// Grow the hunk while the next change is within reach of its context
+		start, end := changed[i], changed[i]
+		for i++; i < len(changed) && changed[i]-end <= 2*context; i++ {
+			end = changed[i]
+		}
+		start, end = max(0, start-context), min(len(lines)-1, end+context)
+		writeHunk(&sb, lines, start, end)
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, lines []DiffLine, start, end int) {
+	oldStart, newStart := 1, 1
+	for _, line := range lines[:start] {
+		if line.Op != DiffInsert {
+			oldStart++
+		}
+		if line.Op != DiffDelete {
+			newStart++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, line := range lines[start : end+1] {
+		if line.Op != DiffInsert {
+			oldCount++
+		}
+		if line.Op != DiffDelete {
+			newCount++
+		}
+	}
+	// An empty range names the line before it, as in GNU diff
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, line := range lines[start : end+1] {
+		sb.WriteString(string(" -+"[line.Op]) + line.Text + "\n")
+	}
+}
+
+// DiffFiles returns a unified diff of two files
+func DiffFiles(oldPath, newPath string, context int) (string, error) {
+	oldText, err := os.ReadFile(oldPath)
+	if err != nil {
+		return "", err
+	}
+	newText, err := os.ReadFile(newPath)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(oldPath, newPath, string(oldText), string(newText), context), nil
+}
+
+// ColorizeDiff adds ANSI colors to a unified diff
+func ColorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			lines[i] = Colorize(strings.TrimSuffix(line, "\n"), Bold) + "\n"
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = Colorize(strings.TrimSuffix(line, "\n"), Cyan) + "\n"
+		case strings.HasPrefix(line, "-"):
+			lines[i] = Colorize(strings.TrimSuffix(line, "\n"), Red) + "\n"
+		case strings.HasPrefix(line, "+"):
+			lines[i] = Colorize(strings.TrimSuffix(line, "\n"), Green) + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// SideBySide renders two texts in columns of the given width, marking changed
+// lines with "|", deletions with "<" and insertions with ">"
+func SideBySide(oldText, newText string, width int) string {
+	lines := DiffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	row := func(left, marker, right string) {
+		sb.WriteString(strings.TrimRight(pad(Truncate(left, width), width, AlignLeft)+" "+marker+" "+Truncate(right, width), " ") + "\n")
+	}
+	for i := 0; i < len(lines); {
+		if lines[i].Op == DiffEqual {
+			row(lines[i].Text, " ", lines[i].Text)
+			i++
+			continue
+		}
+		// Pair a run of deletions with the insertions that follow it
+		var deleted, inserted []string
+		for ; i < len(lines) && lines[i].Op == DiffDelete; i++ {
+			deleted = append(deleted, lines[i].Text)
+		}
+		for ; i < len(lines) && lines[i].Op == DiffInsert; i++ {
+			inserted = append(inserted, lines[i].Text)
+		}
+		for j := 0; j < max(len(deleted), len(inserted)); j++ {
+			switch {
+			case j < len(deleted) && j < len(inserted):
+				row(deleted[j], "|", inserted[j])
+			case j < len(deleted):
+				row(deleted[j], "<", "")
+			default:
+				row("", ">", inserted[j])
+			}
+		}
+	}
+	return sb.String()
+}
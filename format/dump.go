@@ -0,0 +1,353 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DumpOptions controls how much of a value Dump shows and how
+type DumpOptions struct {
+	// MaxDepth stops descending below that many levels of nesting, showing
+	// "{…}" instead. Zero means no limit.
+	MaxDepth int
+	// MaxElements shows at most that many slice, array and map elements,
+	// then how many were left out. Zero means all of them.
+	MaxElements int
+	// MaxStringLen truncates longer strings and byte slices. Zero means no
+	// limit.
+	MaxStringLen int
+	// Types annotates composite values with their type, as in Go syntax,
+	// and scalars with theirs when held in an interface
+	Types bool
+	// Indent is repeated once per level of nesting; empty puts the whole
+	// value on one line, e.g. for a log message
+	Indent string
+	// Redact, when set, is applied to v first; reflect.Redact masks fields
+	// tagged `sensitive`, keeping URLs readable and card numbers' last four
+	// digits. Without it, tagged fields that are set show as "[redacted]".
+	Redact func(v interface{}) interface{}
+}
+
+// DefaultDumpOptions suit printing a value in a demo or a debug log
+var DefaultDumpOptions = DumpOptions{MaxDepth: 8, MaxElements: 20, MaxStringLen: 200, Types: true, Indent: "  "}
+
+// Dump renders v readably: one field, element or map entry per line,
+// indented by nesting, map keys sorted, pointers followed (a pointer back
+// to a value being printed shows as <cycle>), and values implementing
+// error or fmt.Stringer shown as such. It is the %+v of nested data.
+func Dump(v interface{}, opts DumpOptions) string {
+	if opts.Redact != nil {
+		v = opts.Redact(v)
+	}
+	d := &dumper{opts: opts, visiting: map[uintptr]bool{}}
+	d.value(reflect.ValueOf(v), 0, true)
+	return d.sb.String()
+}
+
+type dumper struct {
+	opts     DumpOptions
+	sb       strings.Builder
+	visiting map[uintptr]bool // pointers on the path from the root
+}
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// value writes v; inInterface says v was held by an interface, so its
+// type is not evident from the enclosing one
+func (d *dumper) value(v reflect.Value, depth int, inInterface bool) {
+	if !v.IsValid() {
+		d.sb.WriteString("nil")
+		return
+	}
+	if text, ok := d.stringer(v); ok {
+		d.typed(v.Type(), text, inInterface)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			d.sb.WriteString("nil")
+			return
+		}
+		d.value(v.Elem(), depth, true)
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.typed(v.Type(), "nil", inInterface)
+			return
+		}
+		if d.visiting[v.Pointer()] {
+			fmt.Fprintf(&d.sb, "<cycle %s>", v.Type())
+			return
+		}
+		d.visiting[v.Pointer()] = true
+		defer delete(d.visiting, v.Pointer())
+		d.sb.WriteString("&")
+		d.value(v.Elem(), depth, false)
+	case reflect.Struct:
+		d.structValue(v, depth)
+	case reflect.Map:
+		d.mapValue(v, depth)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			d.typed(v.Type(), "nil", inInterface)
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			d.bytes(v)
+			return
+		}
+		d.listValue(v, depth)
+	case reflect.String:
+		d.typed(v.Type(), d.quote(v.String()), inInterface && v.Type() != reflect.TypeOf(""))
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			d.typed(v.Type(), "nil", true)
+			return
+		}
+		d.typed(v.Type(), fmt.Sprintf("%#x", v.Pointer()), true)
+	default:
+		d.typed(v.Type(), scalar(v), inInterface)
+	}
+}
+
+// typed writes text, wrapped in its type name when annotate is set
+func (d *dumper) typed(t reflect.Type, text string, annotate bool) {
+	if d.opts.Types && annotate {
+		fmt.Fprintf(&d.sb, "%s(%s)", t, text)
+		return
+	}
+	d.sb.WriteString(text)
+}
+
+// stringer formats values whose type says how to print them, such as
+// time.Time or net.IP. Nil pointers are left to the caller, and so are
+// values it cannot call methods on: those in unexported fields.
+func (d *dumper) stringer(v reflect.Value) (text string, ok bool) {
+	if !v.CanInterface() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+	if !v.Type().Implements(errorType) && !v.Type().Implements(stringerType) {
+		return "", false
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			text = fmt.Sprintf("<%s panicked: %v>", v.Type(), p)
+		}
+	}()
+	if err, isErr := v.Interface().(error); isErr {
+		return d.quote(err.Error()), true
+	}
+	return v.Interface().(fmt.Stringer).String(), true
+}
+
+func (d *dumper) structValue(v reflect.Value, depth int) {
+	t := v.Type()
+	d.open(t, "{")
+	if v.NumField() == 0 {
+		d.sb.WriteString("}")
+		return
+	}
+	if d.tooDeep(depth) {
+		d.sb.WriteString("…}")
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		d.item(depth+1, i == 0)
+		d.sb.WriteString(field.Name + ": ")
+		if mode, ok := field.Tag.Lookup("sensitive"); ok && mode != "false" && d.opts.Redact == nil && !v.Field(i).IsZero() {
+			d.sb.WriteString(strconv.Quote("[redacted]"))
+		} else {
+			d.value(v.Field(i), depth+1, false)
+		}
+	}
+	d.close(depth, "}")
+}
+
+func (d *dumper) mapValue(v reflect.Value, depth int) {
+	if v.IsNil() {
+		d.typed(v.Type(), "nil", true)
+		return
+	}
+	d.open(v.Type(), "{")
+	if v.Len() == 0 {
+		d.sb.WriteString("}")
+		return
+	}
+	if d.tooDeep(depth) {
+		fmt.Fprintf(&d.sb, "…%d entries}", v.Len())
+		return
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Kind() == b.Kind() {
+			switch a.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return a.Int() < b.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return a.Uint() < b.Uint()
+			case reflect.Float32, reflect.Float64:
+				return a.Float() < b.Float()
+			}
+		}
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	for i, key := range keys {
+		if d.opts.MaxElements > 0 && i == d.opts.MaxElements {
+			d.item(depth+1, false)
+			fmt.Fprintf(&d.sb, "…%d more", len(keys)-i)
+			break
+		}
+		d.item(depth+1, i == 0)
+		d.value(key, depth+1, false)
+		d.sb.WriteString(": ")
+		d.value(v.MapIndex(key), depth+1, false)
+	}
+	d.close(depth, "}")
+}
+
+// listValue writes slices and arrays; those of scalars go on one line
+func (d *dumper) listValue(v reflect.Value, depth int) {
+	d.open(v.Type(), "{")
+	if v.Len() == 0 {
+		d.sb.WriteString("}")
+		return
+	}
+	if d.tooDeep(depth) {
+		fmt.Fprintf(&d.sb, "…%d elements}", v.Len())
+		return
+	}
+	n := v.Len()
+	if d.opts.MaxElements > 0 {
+		n = min(n, d.opts.MaxElements)
+	}
+
+	if isScalar(v.Type().Elem()) {
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				d.sb.WriteString(", ")
+			}
+			d.value(v.Index(i), depth+1, false)
+		}
+		if n < v.Len() {
+			fmt.Fprintf(&d.sb, ", …%d more", v.Len()-n)
+		}
+		d.sb.WriteString("}")
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		d.item(depth+1, i == 0)
+		d.value(v.Index(i), depth+1, false)
+	}
+	if n < v.Len() {
+		d.item(depth+1, false)
+		fmt.Fprintf(&d.sb, "…%d more", v.Len()-n)
+	}
+	d.close(depth, "}")
+}
+
+// bytes writes a byte slice or array as a string when it is text, in hex
+// otherwise
+func (d *dumper) bytes(v reflect.Value) {
+	b := make([]byte, v.Len())
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	limit := len(b)
+	if d.opts.MaxStringLen > 0 {
+		limit = min(limit, d.opts.MaxStringLen)
+	}
+	var text string
+	if utf8.Valid(b) {
+		text = strconv.Quote(string(b[:limit]))
+	} else {
+		text = fmt.Sprintf("0x%x", b[:limit])
+	}
+	if limit < len(b) {
+		text += fmt.Sprintf("…(%d bytes)", len(b))
+	}
+	d.typed(v.Type(), text, true)
+}
+
+func (d *dumper) quote(s string) string {
+	if d.opts.MaxStringLen > 0 && utf8.RuneCountInString(s) > d.opts.MaxStringLen {
+		runes := []rune(s)
+		return strconv.Quote(string(runes[:d.opts.MaxStringLen])) + fmt.Sprintf("…(%d chars)", len(runes))
+	}
+	return strconv.Quote(s)
+}
+
+func (d *dumper) tooDeep(depth int) bool {
+	return d.opts.MaxDepth > 0 && depth >= d.opts.MaxDepth
+}
+
+// open writes the type, when annotating, and the opening brace
+func (d *dumper) open(t reflect.Type, brace string) {
+	if d.opts.Types {
+		d.sb.WriteString(t.String())
+	}
+	d.sb.WriteString(brace)
+}
+
+// item starts a field, element or entry at depth
+func (d *dumper) item(depth int, first bool) {
+	if !first {
+		d.sb.WriteString(",")
+	}
+	if d.opts.Indent == "" {
+		if !first {
+			d.sb.WriteString(" ")
+		}
+		return
+	}
+	d.sb.WriteString("\n" + strings.Repeat(d.opts.Indent, depth))
+}
+
+// close ends a composite opened at depth
+func (d *dumper) close(depth int, brace string) {
+	if d.opts.Indent != "" {
+		d.sb.WriteString(",\n" + strings.Repeat(d.opts.Indent, depth))
+	}
+	d.sb.WriteString(brace)
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// scalar formats a basic value without Interface, so values read from
+// unexported fields work too
+func scalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128)
+	}
+	return "?"
+}
@@ -3,6 +3,7 @@ package format
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 type Person struct {
@@ -64,13 +65,16 @@ func StringFormatting() {
 
 func FileOutput() {
 	fmt.Println("\n=== Output to File & Error Stream ===")
-	file, err := os.Create("output.txt")
+	// A temporary file, so running the example leaves nothing behind
+	file, err := os.CreateTemp("", "output-*.txt")
 	if err != nil {
 		fmt.Println("Failed to create file:", err)
 		return
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
 	fmt.Fprint(file, "Hello File\n")
+	fmt.Println("Wrote", file.Name())
 	fmt.Fprintf(os.Stderr, "error: %s\n", "something went wrong")
 }
 
@@ -125,6 +129,60 @@ func CustomFormatting() {
 	person2 := Person{"Alice", 30}
 	fmt.Printf("Person: %v\n", person2)
 	fmt.Printf("Person (detailed): %+v\n", person2)
+	fmt.Println(MustExpand("Named fields: {Name} is {Age} {Age|plural:year,years} old", person2))
+}
+
+func HumanizeExamples() {
+	fmt.Println("\n=== Humanized Values ===")
+	for _, n := range []int64{512, 1536, 734003, 5 * 1024 * 1024 * 1024} {
+		fmt.Printf("%d bytes -> %s (SI: %s)\n", n, HumanizeBytes(n), HumanizeBytesSI(n))
+	}
+	for _, s := range []string{"1.5GiB", "10 MB", "512", "2k"} {
+		n, err := ParseBytes(s)
+		fmt.Printf("ParseBytes(%q) = %s bytes, err=%v\n", s, Comma(n), err)
+	}
+	fmt.Println("Durations:", HumanizeDuration(205*time.Second), "|", HumanizeDuration(50*time.Hour), "|", HumanizeDuration(1500*time.Microsecond))
+	fmt.Println("Numbers:", Comma(1234567), CommaFloat(-9876543.219, 2), SI(2.2e9, "Hz"), SI(0.000047, "F"))
+	value, unit, _ := ParseSI("2.2 GHz")
+	fmt.Printf("ParseSI(\"2.2 GHz\") = %g %s\n", value, unit)
+	fmt.Println("Ordinals:", Ordinal(1), Ordinal(2), Ordinal(3), Ordinal(11), Ordinal(22), Ordinal(113))
+	now := time.Now()
+	fmt.Println("Relative:", RelTime(now.Add(-3*time.Minute)), "|", RelTime(now.Add(49*time.Hour)), "|", RelTime(now))
+}
+
+func TemplateFormatting() {
+	fmt.Println("\n=== Templated Formatting ===")
+	Printf("Downloaded %H of %.2H in %D (%#H/s)\n", 1536, 5*1024*1024*1024, 3*time.Minute+25*time.Second, 2_500_000)
+	Printf("Uptime: %.3D, type of arg still %T\n", 90061*time.Second, 90061*time.Second)
+
+	upload := map[string]interface{}{
+		"user":  Person{"Jerry", 25},
+		"files": []string{"report.pdf", "photo.png"},
+		"size":  734003,
+		"count": 2,
+	}
+	fmt.Println(MustExpand("{user.Name} uploaded {count} {count|plural:file,files} ({size:%H}), first: {files.0}", upload))
+	fmt.Println(MustExpand("{user.Name|upper} {{escaped braces}}", upload))
+	if _, err := Expand("{user.Email}", upload); err != nil {
+		fmt.Println("Expand error:", err)
+	}
+
+	RegisterVerb('P', func(f fmt.State, verb rune, value interface{}) {
+		ratio, _ := value.(float64)
+		fmt.Fprintf(f, "%.1f%%", ratio*100)
+	})
+	Printf("Custom %%P verb: %P complete\n", 0.873)
+
+	bundle := NewBundle("en")
+	bundle.Add("en", "inbox", Message{Zero: "No new messages", One: "{Count} new message for {Name}", Other: "{Count} new messages for {Name}"})
+	bundle.Add("fr", "inbox", Message{One: "{Count} nouveau message pour {Name}", Other: "{Count} nouveaux messages pour {Name}"})
+	bundle.Add("ja", "inbox", Message{Other: "{Name}さんに新着メッセージ{Count}件"})
+	bundle.AddMessages("en", map[string]string{"greeting": "Hello, {Name}!"})
+	bundle.AddMessages("fr", map[string]string{"greeting": "Bonjour, {Name} !"})
+	for _, locale := range []string{"en", "fr-CA", "ja", "de"} {
+		l := bundle.Localizer(locale)
+		fmt.Printf("[%s] %s %s | %s | %s\n", locale, l.T("greeting", Person{"Jerry", 25}), l.N("inbox", 0, Person{"Jerry", 25}), l.N("inbox", 1, Person{"Jerry", 25}), l.N("inbox", 5, Person{"Jerry", 25}))
+	}
 }
 
 func ScanVariations() {
@@ -140,6 +198,151 @@ func ScanVariations() {
 	fmt.Printf("Words: %q, %q, %q\n", word1, word2, word3)
 }
 
+func LoggingExamples() {
+	fmt.Println("\n=== Structured Logging ===")
+	text := NewLogger(os.Stdout, TextEncoder{TimeFormat: "15:04:05"}, InfoLevel)
+	text.Debug("hidden below info level")
+	text.Info("user signed in", "user", "jerry", "attempts", 2)
+	requestLog := text.Named("http").With(Fields{"request_id": "req-42"})
+	requestLog.Warnf("slow response: %dms", 1250)
+
+	jsonLog := NewLogger(os.Stdout, JSONEncoder{}, DebugLevel).Named("worker")
+	jsonLog.Debug("job started", "job", "resize-images", "batch", 3)
+	jsonLog.Error("job failed", "error", fmt.Errorf("disk full"))
+
+	dir, err := os.MkdirTemp("", "logs")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	file, err := NewRotatingFile(dir+"/app.log", 1, 7, true)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer file.Close()
+	file.SetMaxBytes(512)
+	fileLog := NewLogger(file, JSONEncoder{}, InfoLevel)
+	for i := 0; i < 20; i++ {
+		fileLog.Info("processing record", "record", i)
+	}
+	backups, _ := file.Backups()
+	fmt.Printf("Rotated log files after 20 entries: %d\n", len(backups))
+
+	SetPackageLevel("database", WarnLevel)
+	fmt.Printf("Package loggers: %v (database level: %s)\n", PackageLoggers(), GetLogger("database").Level())
+}
+
+func TerminalRenderingExamples() {
+	fmt.Println("\n=== Terminal Rendering ===")
+	table := NewTable("Service", "Status", "Latency", "Endpoint").
+		SetAlign(2, AlignRight).
+		SetMaxWidth(3, 24).
+		SetColor(1, func(cell string) string {
+			if cell == "up" {
+				return Green
+			}
+			return Red
+		})
+	table.AddRow("api", "up", "12ms", "https://api.example.com/v1/health")
+	table.AddRow("database", "up", "3ms", "postgres://localhost:5432")
+	table.AddRow("cache", "down", "-", "redis://localhost:6379")
+	table.Render(os.Stdout)
+
+	config := map[string]interface{}{
+		"server":   map[string]interface{}{"host": "localhost", "port": 8080},
+		"database": map[string]interface{}{"driver": "postgres", "replicas": []string{"db-1", "db-2"}},
+		"debug":    true,
+	}
+	fmt.Print(TreeFromValue("config", config))
+
+	bar := NewProgressBar(os.Stdout, 20, "Processing")
+	bar.Unit = "items"
+	for i := 0; i < 20; i++ {
+		time.Sleep(10 * time.Millisecond)
+		bar.Increment()
+	}
+	bar.Finish()
+
+	spinner := NewSpinner(os.Stdout, "Waiting for job")
+	spinner.Start()
+	time.Sleep(300 * time.Millisecond)
+	spinner.Stop("✅ Job finished")
+}
+
+type dumpDemoServer struct {
+	Name     string
+	Started  time.Time
+	Timeout  time.Duration
+	Tags     []string
+	Limits   map[string]int
+	Backends []*dumpDemoServer
+	Parent   *dumpDemoServer
+	Password string `sensitive:"true"`
+	Extra    interface{}
+	lastErr  error
+}
+
+// DumpExamples pretty-prints a nested value with a cycle, a secret and a
+// long slice, compared with %+v
+func DumpExamples() {
+	fmt.Println("\n=== Dumping Values ===")
+	root := &dumpDemoServer{
+		Name:     "gateway",
+		Started:  time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		Timeout:  1500 * time.Millisecond,
+		Tags:     []string{"edge", "public", "eu-west-1", "canary", "v2"},
+		Limits:   map[string]int{"rps": 500, "burst": 50},
+		Password: "hunter2",
+		Extra:    map[string]interface{}{"retries": 3, "ratio": 0.25},
+	}
+	root.Backends = []*dumpDemoServer{{Name: "api-1", Parent: root}, {Name: "api-2", Parent: root}}
+
+	fmt.Printf("%%+v: %+v\n", *root)
+	fmt.Println("Dump:")
+	fmt.Println(Dump(root, DefaultDumpOptions))
+
+	opts := DumpOptions{MaxDepth: 1, MaxElements: 2}
+	fmt.Printf("One line, depth 1, 2 elements, no types: %s\n", Dump(root, opts))
+}
+
+// StyleExamples renders styles for each color profile and prints themed
+// messages, which come out plain when piped or with NO_COLOR set
+func StyleExamples() {
+	fmt.Println("\n=== Styles and Themes ===")
+	fmt.Printf("Standard output: %s (terminal: %t)\n", Stdout.Profile, IsTerminal(os.Stdout))
+
+	orange, _ := HexColor("#ff8800")
+	styles := []struct {
+		name  string
+		style Style
+	}{
+		{"bold underline", NewStyle().Bold().Underline()},
+		{"basic red", NewStyle().Foreground(ColorRed)},
+		{"palette 208", NewStyle().Foreground(PaletteColor(208))},
+		{"rgb #ff8800", NewStyle().Foreground(orange)},
+		{"reverse on blue", NewStyle().Reverse().Background(ColorBlue)},
+	}
+	table := NewTable("Style", "16 colors", "256 colors", "Truecolor", "Sample")
+	for _, st := range styles {
+		table.AddRow(st.name,
+			fmt.Sprintf("%q", st.style.Sequence(ANSI)),
+			fmt.Sprintf("%q", st.style.Sequence(ANSI256)),
+			fmt.Sprintf("%q", st.style.Sequence(TrueColor)),
+			st.style.Render("sample"))
+	}
+	table.Render(os.Stdout)
+
+	Stdout.Title("🎨", "Themed Output")
+	Stdout.Options("1", "Start server", "2", "Run client", "q", "Quit")
+	Stdout.Success("Saved %d records", 3)
+	Stdout.Warn("Disk %d%% full", 91)
+	Stdout.Error("Connection refused")
+	Stdout.Info("Set NO_COLOR=1 to turn colors off")
+	Stdout.Hint("Piped output is plain unless FORCE_COLOR is set")
+}
+
 func RunAllExamples() {
 	BasicOutput()
 	FormattedOutput()
@@ -152,5 +355,11 @@ func RunAllExamples() {
 	StringAndRuneFormatting()
 	PointerAndInterfaceFormatting()
 	CustomFormatting()
+	TemplateFormatting()
+	HumanizeExamples()
+	LoggingExamples()
+	TerminalRenderingExamples()
+	StyleExamples()
+	DumpExamples()
 	ScanVariations()
 }
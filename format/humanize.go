@@ -0,0 +1,246 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// HumanizeBytes formats n with binary units, e.g. 1536 -> "1.5 KiB"
+func HumanizeBytes(n int64) string {
+	return humanizeBytes(float64(n), false, 1)
+}
+
+// HumanizeBytesSI formats n with decimal units, e.g. 1500 -> "1.5 kB"
+func HumanizeBytesSI(n int64) string {
+	return humanizeBytes(float64(n), true, 1)
+}
+
+func humanizeBytes(n float64, si bool, precision int) string {
+	base, units := 1024.0, iecUnits
+	if si {
+		base, units = 1000, siUnits
+	}
+	exp := 0
+	for math.Abs(n) >= base && exp < len(units)-1 {
+		n /= base
+		exp++
+	}
+	if exp == 0 {
+		precision = 0
+	}
+	return strconv.FormatFloat(n, 'f', precision, 64) + " " + units[exp]
+}
+
+// byteMultipliers maps lower-case unit suffixes to their size. Following the
+// IEC convention, "k"/"kb" are powers of 1000 and "ki"/"kib" powers of 1024.
+var byteMultipliers = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "ki": 1 << 10, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mi": 1 << 20, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gi": 1 << 30, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "ti": 1 << 40, "tib": 1 << 40,
+	"p": 1e15, "pb": 1e15, "pi": 1 << 50, "pib": 1 << 50,
+	"e": 1e18, "eb": 1e18, "ei": 1 << 60, "eib": 1 << 60,
+}
+
+// ParseBytes parses a size such as "1.5GiB", "10 MB" or "512" into bytes
+func ParseBytes(s string) (int64, error) {
+	number, unit := splitNumber(s)
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	multiplier, ok := byteMultipliers[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q in %q", unit, s)
+	}
+	// Whole numbers stay exact beyond float64's 53 bits
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil && n >= 0 && n <= math.MaxInt64/int64(multiplier) {
+		return n * int64(multiplier), nil
+	}
+	bytes := value * multiplier
+	if bytes < 0 || bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q out of range", s)
+	}
+	return int64(bytes), nil
+}
+
+// splitNumber separates a leading number (with optional sign, decimal point
+// and commas) from the unit that follows it
+func splitNumber(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && !strings.ContainsRune("+-.,", r)
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	return strings.ReplaceAll(s[:end], ",", ""), strings.TrimSpace(s[end:])
+}
+
+var durationUnits = []struct {
+	size time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{7 * 24 * time.Hour, "week"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+	{time.Second, "second"},
+}
+
+// HumanizeDuration spells out d using its two largest units, e.g.
+// "3 minutes 25 seconds"; durations under a second keep Go's notation
+func HumanizeDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + HumanizeDuration(-d)
+	}
+	if d < time.Second {
+		return d.Round(time.Microsecond).String()
+	}
+	var parts []string
+	for _, unit := range durationUnits {
+		if len(parts) == 2 {
+			break
+		}
+		if count := int(d / unit.size); count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, Plural(count, unit.name, unit.name+"s")))
+			d -= time.Duration(count) * unit.size
+		} else if len(parts) > 0 {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Comma formats n with thousands separators, e.g. 1234567 -> "1,234,567"
+func Comma(n int64) string {
+	if n < 0 {
+		if n == math.MinInt64 {
+			return "-9,223,372,036,854,775,808"
+		}
+		return "-" + Comma(-n)
+	}
+	digits := strconv.FormatInt(n, 10)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
+
+// CommaFloat formats f with thousands separators and the given decimals
+func CommaFloat(f float64, decimals int) string {
+	text := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(text, ".")
+	wholeValue, _ := strconv.ParseInt(whole, 10, 64)
+	result := Comma(wholeValue)
+	if fraction != "" {
+		result += "." + fraction
+	}
+	if f < 0 {
+		result = "-" + result
+	}
+	return result
+}
+
+var siPrefixes = []struct {
+	exponent int
+	prefix   string
+}{
+	{18, "E"}, {15, "P"}, {12, "T"}, {9, "G"}, {6, "M"}, {3, "k"},
+	{0, ""}, {-3, "m"}, {-6, "µ"}, {-9, "n"}, {-12, "p"},
+}
+
+// SI formats value with a metric prefix, e.g. SI(2.2e9, "Hz") -> "2.2 GHz"
+func SI(value float64, unit string) string {
+	if value == 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return strings.TrimSpace(strconv.FormatFloat(value, 'g', -1, 64) + " " + unit)
+	}
+	chosen := siPrefixes[len(siPrefixes)-1]
+	for _, p := range siPrefixes {
+		if math.Abs(value) >= math.Pow10(p.exponent) {
+			chosen = p
+			break
+		}
+	}
+	scaled := value / math.Pow10(chosen.exponent)
+	number := strconv.FormatFloat(scaled, 'f', 2, 64)
+	number = strings.TrimRight(strings.TrimRight(number, "0"), ".")
+	return number + " " + chosen.prefix + unit
+}
+
+// ParseSI parses a value such as "2.2 GHz" or "500ms" back into the base
+// value and its unit
+func ParseSI(s string) (float64, string, error) {
+	number, rest := splitNumber(s)
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid number in %q", s)
+	}
+	for _, p := range siPrefixes {
+		// a lone prefix letter with no unit is treated as the unit itself
+		if p.prefix != "" && strings.HasPrefix(rest, p.prefix) && len(rest) > len(p.prefix) {
+			return value * math.Pow10(p.exponent), rest[len(p.prefix):], nil
+		}
+	}
+	return value, rest, nil
+}
+
+// Ordinal returns n with its English ordinal suffix, e.g. 2 -> "2nd"
+func Ordinal(n int) string {
+	suffix := "th"
+	switch abs := int(math.Abs(float64(n))); {
+	case abs%100 >= 11 && abs%100 <= 13:
+	case abs%10 == 1:
+		suffix = "st"
+	case abs%10 == 2:
+		suffix = "nd"
+	case abs%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// RelTime describes t relative to now, e.g. "3 minutes ago" or "in 2 days"
+func RelTime(t time.Time) string {
+	return RelTimeFrom(t, time.Now())
+}
+
+// RelTimeFrom describes t relative to now using the largest whole unit
+func RelTimeFrom(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < 10*time.Second {
+		return "just now"
+	}
+
+	phrase := ""
+	for _, unit := range durationUnits {
+		if count := int(d / unit.size); count > 0 {
+			phrase = fmt.Sprintf("%d %s", count, Plural(count, unit.name, unit.name+"s"))
+			break
+		}
+	}
+	if future {
+		return "in " + phrase
+	}
+	return phrase + " ago"
+}
@@ -0,0 +1,496 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel converts a level name such as "info" or "WARN" to a Level
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level: %s", s)
+}
+
+// Fields are key/value pairs attached to log entries
+type Fields map[string]interface{}
+
+// Entry is a single log record handed to an Encoder
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Logger  string
+	Message string
+	Fields  Fields
+}
+
+// Encoder turns an entry into bytes, including the trailing newline
+type Encoder interface {
+	Encode(entry Entry) ([]byte, error)
+}
+
+// JSONEncoder writes one JSON object per line
+type JSONEncoder struct{}
+
+// Encode implements Encoder
+func (JSONEncoder) Encode(entry Entry) ([]byte, error) {
+	record := make(map[string]interface{}, len(entry.Fields)+4)
+	for k, v := range entry.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		record[k] = v
+	}
+	record["time"] = entry.Time.Format(time.RFC3339Nano)
+	record["level"] = entry.Level.String()
+	record["msg"] = entry.Message
+	if entry.Logger != "" {
+		record["logger"] = entry.Logger
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// TextEncoder writes human-readable lines: time, level, logger, message, fields
+type TextEncoder struct {
+	TimeFormat string // defaults to "2006-01-02 15:04:05.000"
+}
+
+// Encode implements Encoder
+func (e TextEncoder) Encode(entry Entry) ([]byte, error) {
+	timeFormat := e.TimeFormat
+	if timeFormat == "" {
+		timeFormat = "2006-01-02 15:04:05.000"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %-5s ", entry.Time.Format(timeFormat), strings.ToUpper(entry.Level.String()))
+	if entry.Logger != "" {
+		fmt.Fprintf(&buf, "[%s] ", entry.Logger)
+	}
+	buf.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := fmt.Sprint(entry.Fields[k])
+		if strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&buf, " %s=%s", k, value)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// logCore is the output shared by a logger and everything derived from it
+type logCore struct {
+	mu      sync.Mutex
+	out     io.Writer
+	encoder Encoder
+}
+
+func (c *logCore) write(entry Entry) {
+	data, err := c.encoder.Encode(entry)
+	if err != nil {
+		data = []byte(fmt.Sprintf("log encoding failed: %v: %s\n", err, entry.Message))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out.Write(data)
+}
+
+// Logger writes leveled, structured log entries. Loggers derived with With or
+// Named share their parent's output.
+type Logger struct {
+	core   *atomic.Pointer[logCore]
+	level  *atomic.Int32
+	name   string
+	fields Fields
+	exit   func(code int)
+}
+
+// NewLogger creates a logger writing entries at or above level to out
+func NewLogger(out io.Writer, encoder Encoder, level Level) *Logger {
+	core := &atomic.Pointer[logCore]{}
+	core.Store(&logCore{out: out, encoder: encoder})
+	l := &Logger{core: core, level: &atomic.Int32{}, exit: os.Exit}
+	l.level.Store(int32(level))
+	return l
+}
+
+// LoggerOptions describes a logger in plain values. It has the fields of
+// config.LoggingConfig, so a loaded config converts to it directly, without
+// this package importing config.
+type LoggerOptions struct {
+	Level    string
+	Format   string
+	Output   string
+	Filename string
+	MaxSize  int
+	MaxAge   int
+	Compress bool
+}
+
+// NewLoggerFromOptions builds a logger from opts. Output may be "stdout",
+// "stderr" or "file"; file output rotates by MaxSize (MB) and removes
+// backups older than MaxAge (days).
+func NewLoggerFromOptions(cfg LoggerOptions) (*Logger, io.Closer, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var encoder Encoder = TextEncoder{}
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		encoder = JSONEncoder{}
+	case "text", "":
+	default:
+		return nil, nil, fmt.Errorf("unknown log format: %s", cfg.Format)
+	}
+
+	var out io.Writer
+	var closer io.Closer = nopCloser{}
+	switch strings.ToLower(cfg.Output) {
+	case "stdout", "":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	case "file":
+		file, err := NewRotatingFile(cfg.Filename, cfg.MaxSize, cfg.MaxAge, cfg.Compress)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closer = file, file
+	default:
+		return nil, nil, fmt.Errorf("unknown log output: %s", cfg.Output)
+	}
+
+	return NewLogger(out, encoder, level), closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// Level returns the minimum level the logger writes
+func (l *Logger) Level() Level {
+	return Level(l.level.Load())
+}
+
+// SetLevel changes the minimum level; derived loggers share the setting
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// Enabled reports whether entries at level would be written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// With returns a logger that adds fields to every entry
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	child := *l
+	child.fields = merged
+	return &child
+}
+
+// Named returns a logger whose entries carry name, joined to the parent's with "."
+func (l *Logger) Named(name string) *Logger {
+	child := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child.name = name
+	return &child
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := l.fields
+	if len(keyvals) > 0 {
+		fields = make(Fields, len(l.fields)+len(keyvals)/2)
+		for k, v := range l.fields {
+			fields[k] = v
+		}
+		for i := 0; i < len(keyvals); i += 2 {
+			key := fmt.Sprint(keyvals[i])
+			if i+1 < len(keyvals) {
+				fields[key] = keyvals[i+1]
+			} else {
+				fields["!BADKEY"] = keyvals[i]
+			}
+		}
+	}
+
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Logger:  l.name,
+		Message: strings.TrimSuffix(msg, "\n"),
+		Fields:  fields,
+	}
+	l.core.Load().write(entry)
+	if hs := hooks.Load(); hs != nil {
+		for _, h := range *hs {
+			h.fn(entry)
+		}
+	}
+	if level == FatalLevel {
+		l.exit(1)
+	}
+}
+
+type hook struct{ fn func(Entry) }
+
+var (
+	hooksMu sync.Mutex
+	hooks   atomic.Pointer[[]*hook]
+)
+
+// AddHook calls fn with every entry written by any logger, after it is
+// written, e.g. to show recent lines on a dashboard. fn runs on the logging
+// goroutine, so it must be quick and must not log. The returned function
+// removes the hook.
+func AddHook(fn func(Entry)) (remove func()) {
+	h := &hook{fn: fn}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	var current []*hook
+	if hs := hooks.Load(); hs != nil {
+		current = *hs
+	}
+	updated := append(current[:len(current):len(current)], h)
+	hooks.Store(&updated)
+
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		var kept []*hook
+		for _, other := range *hooks.Load() {
+			if other != h {
+				kept = append(kept, other)
+			}
+		}
+		hooks.Store(&kept)
+	}
+}
+
+// Debug logs msg with alternating key/value pairs
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.log(DebugLevel, msg, keyvals) }
+
+// Info logs msg with alternating key/value pairs
+func (l *Logger) Info(msg string, keyvals ...interface{}) { l.log(InfoLevel, msg, keyvals) }
+
+// Warn logs msg with alternating key/value pairs
+func (l *Logger) Warn(msg string, keyvals ...interface{}) { l.log(WarnLevel, msg, keyvals) }
+
+// Error logs msg with alternating key/value pairs
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.log(ErrorLevel, msg, keyvals) }
+
+// Fatal logs msg and exits the process
+func (l *Logger) Fatal(msg string, keyvals ...interface{}) { l.log(FatalLevel, msg, keyvals) }
+
+// Debugf logs a formatted message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.Enabled(DebugLevel) {
+		l.log(DebugLevel, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Infof logs a formatted message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.log(InfoLevel, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Warnf logs a formatted message
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.Enabled(WarnLevel) {
+		l.log(WarnLevel, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Errorf logs a formatted message
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.Enabled(ErrorLevel) {
+		l.log(ErrorLevel, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Fatalf logs a formatted message and exits the process
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(FatalLevel, fmt.Sprintf(format, args...), nil)
+}
+
+var (
+	defaultLogger = NewLogger(os.Stderr, TextEncoder{}, InfoLevel)
+
+	registryMu     sync.Mutex
+	packageLoggers = make(map[string]*Logger)
+)
+
+// Default returns the global logger
+func Default() *Logger {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the global logger's output, encoder and level. Package
+// loggers obtained from GetLogger switch to the new output too, keeping any
+// level set with SetPackageLevel.
+func SetDefault(l *Logger) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	defaultLogger.core.Store(l.core.Load())
+	defaultLogger.SetLevel(l.Level())
+	for _, pkg := range packageLoggers {
+		if !pkg.ownLevel() {
+			pkg.SetLevel(l.Level())
+		}
+	}
+}
+
+// SetLevel changes the global logger's level and that of every package
+// logger without one set by SetPackageLevel, e.g. from an admin endpoint
+// while the process runs
+func SetLevel(level Level) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	defaultLogger.SetLevel(level)
+	for _, pkg := range packageLoggers {
+		if !pkg.ownLevel() {
+			pkg.SetLevel(level)
+		}
+	}
+}
+
+// GetLogger returns the logger for a package or component, creating it on
+// first use. It writes through the global logger's output.
+func GetLogger(name string) *Logger {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if l, ok := packageLoggers[name]; ok {
+		return l
+	}
+	l := &Logger{
+		core:  defaultLogger.core,
+		level: &atomic.Int32{},
+		name:  name,
+		exit:  defaultLogger.exit,
+	}
+	l.level.Store(int32(defaultLogger.Level()))
+	packageLoggers[name] = l
+	return l
+}
+
+// packageLevels records package loggers whose level was set explicitly
+var packageLevels sync.Map
+
+func (l *Logger) ownLevel() bool {
+	_, ok := packageLevels.Load(l.name)
+	return ok
+}
+
+// SetPackageLevel overrides the level of one package logger, e.g. to enable
+// debug output for "database" only
+func SetPackageLevel(name string, level Level) {
+	packageLevels.Store(name, level)
+	GetLogger(name).SetLevel(level)
+}
+
+// PackageLoggers lists the names of all package loggers
+func PackageLoggers() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(packageLoggers))
+	for name := range packageLoggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Debugf logs with the global logger
+func Debugf(format string, args ...interface{}) { Default().Debugf(format, args...) }
+
+// Infof logs with the global logger
+func Infof(format string, args ...interface{}) { Default().Infof(format, args...) }
+
+// Warnf logs with the global logger
+func Warnf(format string, args ...interface{}) { Default().Warnf(format, args...) }
+
+// Errorf logs with the global logger
+func Errorf(format string, args ...interface{}) { Default().Errorf(format, args...) }
+
+// Fatalf logs with the global logger and exits the process
+func Fatalf(format string, args ...interface{}) { Default().Fatalf(format, args...) }
@@ -0,0 +1,218 @@
+package format
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is an io.WriteCloser that starts a new file once the current
+// one reaches MaxSize megabytes. Rotated files are renamed with a timestamp,
+// optionally gzipped, and deleted after MaxAge days.
+type RotatingFile struct {
+	mu       sync.Mutex
+	filename string
+	maxSize  int64
+	maxAge   time.Duration
+	compress bool
+	file     *os.File
+	size     int64
+	now      func() time.Time
+}
+
+// NewRotatingFile opens (or appends to) filename. A zero maxSizeMB disables
+// size-based rotation and a zero maxAgeDays keeps backups forever.
+func NewRotatingFile(filename string, maxSizeMB, maxAgeDays int, compress bool) (*RotatingFile, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("log filename is required for file output")
+	}
+	r := &RotatingFile{
+		filename: filename,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		maxAge:   time.Duration(maxAgeDays) * 24 * time.Hour,
+		compress: compress,
+		now:      time.Now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetMaxBytes overrides the size limit with an exact byte count
+func (r *RotatingFile) SetMaxBytes(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSize = n
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would push the file past its limit
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate forces the current file to be rotated
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return errors.Join(err, r.open())
+	}
+
+	ext := filepath.Ext(r.filename)
+	base := strings.TrimSuffix(r.filename, ext)
+	stamp := r.now().Format(backupStamp)
+	backup := fmt.Sprintf("%s-%s%s", base, stamp, ext)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s-%s.%d%s", base, stamp, i, ext)
+	}
+	if err := os.Rename(r.filename, backup); err != nil {
+		// Keep writing to the file that could not be moved aside
+		return errors.Join(err, r.open())
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.compress {
+		if err := gzipFile(backup); err != nil {
+			return err
+		}
+	}
+	return r.removeExpired()
+}
+
+// backupStamp is the time format in the names of rotated files
+const backupStamp = "20060102T150405.000"
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Backups lists rotated files, oldest first. Only names rotate produces
+// match, so the files of another logger sharing the prefix, such as
+// app-access.log next to app.log, are left alone.
+func (r *RotatingFile) Backups() ([]string, error) {
+	dir, name := filepath.Split(r.filename)
+	ext := filepath.Ext(name)
+	pattern, err := regexp.Compile(`^` + regexp.QuoteMeta(strings.TrimSuffix(name, ext)) +
+		`-\d{8}T\d{6}\.\d{3}(\.\d+)?` + regexp.QuoteMeta(ext) + `(\.gz)?$`)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && pattern.MatchString(entry.Name()) {
+			backups = append(backups, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (r *RotatingFile) removeExpired() error {
+	if r.maxAge <= 0 {
+		return nil
+	}
+	backups, err := r.Backups()
+	if err != nil {
+		return err
+	}
+	cutoff := r.now().Add(-r.maxAge)
+	for _, backup := range backups {
+		info, err := os.Stat(backup)
+		if err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(backup)
+		}
+	}
+	return nil
+}
+
+// Close closes the current file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
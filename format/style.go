@@ -0,0 +1,448 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorProfile is how much styling a terminal can show
+type ColorProfile int
+
+const (
+	// NoColor writes plain text, for pipes, files and NO_COLOR
+	NoColor ColorProfile = iota
+	// ANSI has the 16 basic colors and text attributes
+	ANSI
+	// ANSI256 adds the 256-color palette
+	ANSI256
+	// TrueColor adds 24-bit RGB
+	TrueColor
+)
+
+func (p ColorProfile) String() string {
+	switch p {
+	case NoColor:
+		return "no color"
+	case ANSI:
+		return "16 colors"
+	case ANSI256:
+		return "256 colors"
+	case TrueColor:
+		return "truecolor"
+	}
+	return "unknown"
+}
+
+// DetectProfile works out what w can show. Anything but a terminal gets
+// NoColor, as does every writer when NO_COLOR is set (https://no-color.org)
+// or TERM is "dumb"; FORCE_COLOR styles output that is not a terminal, for
+// example when piped to `less -R`. COLORTERM and TERM tell the rest apart.
+func DetectProfile(w io.Writer) ColorProfile {
+	if os.Getenv("NO_COLOR") != "" {
+		return NoColor
+	}
+	if os.Getenv("FORCE_COLOR") == "" && (!IsTerminal(w) || os.Getenv("TERM") == "dumb") {
+		return NoColor
+	}
+	switch colorTerm := os.Getenv("COLORTERM"); {
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		return TrueColor
+	case strings.Contains(os.Getenv("TERM"), "256color"):
+		return ANSI256
+	}
+	return ANSI
+}
+
+// IsTerminal reports whether w is a file open on a terminal
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+type colorKind uint8
+
+const (
+	colorDefault colorKind = iota
+	colorBasic
+	color256
+	colorRGB
+)
+
+// Color is a basic, 256-palette or RGB color. The zero Color is the
+// terminal's default. Colors a profile cannot show are mapped to the
+// nearest it can.
+type Color struct {
+	kind    colorKind
+	index   uint8
+	r, g, b uint8
+}
+
+// The 16 basic colors
+var (
+	ColorBlack         = BasicColor(0)
+	ColorRed           = BasicColor(1)
+	ColorGreen         = BasicColor(2)
+	ColorYellow        = BasicColor(3)
+	ColorBlue          = BasicColor(4)
+	ColorMagenta       = BasicColor(5)
+	ColorCyan          = BasicColor(6)
+	ColorWhite         = BasicColor(7)
+	ColorBrightBlack   = BasicColor(8)
+	ColorBrightRed     = BasicColor(9)
+	ColorBrightGreen   = BasicColor(10)
+	ColorBrightYellow  = BasicColor(11)
+	ColorBrightBlue    = BasicColor(12)
+	ColorBrightMagenta = BasicColor(13)
+	ColorBrightCyan    = BasicColor(14)
+	ColorBrightWhite   = BasicColor(15)
+)
+
+// BasicColor is one of the 16 basic colors, 8-15 being the bright ones
+func BasicColor(n uint8) Color {
+	return Color{kind: colorBasic, index: n % 16}
+}
+
+// PaletteColor is a color from the 256-color palette
+func PaletteColor(n uint8) Color {
+	return Color{kind: color256, index: n}
+}
+
+// RGB is a 24-bit color
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// HexColor parses "#ff8800" or "#f80"
+func HexColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return Color{}, fmt.Errorf("format: invalid hex color %q", s)
+	}
+	return RGB(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+// basicRGB approximates the 16 basic colors as xterm draws them
+var basicRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func (c Color) rgb() (r, g, b uint8) {
+	switch c.kind {
+	case colorRGB:
+		return c.r, c.g, c.b
+	case colorBasic:
+		v := basicRGB[c.index]
+		return v[0], v[1], v[2]
+	}
+	switch n := c.index; {
+	case n < 16:
+		v := basicRGB[n]
+		return v[0], v[1], v[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		gray := 8 + 10*(n-232)
+		return gray, gray, gray
+	}
+}
+
+// to256 picks the nearest palette entry from the color cube or the
+// grayscale ramp
+func (c Color) to256() uint8 {
+	r, g, b := c.rgb()
+	cube := func(v uint8) uint8 {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	ri, gi, bi := cube(r), cube(g), cube(b)
+	cubeIndex := 16 + 36*ri + 6*gi + bi
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayIndex := uint8(232)
+	if avg > 238 {
+		grayIndex = 255
+	} else if avg > 8 {
+		grayIndex = uint8(232 + (avg-8)/10)
+	}
+	if distance(PaletteColor(grayIndex), r, g, b) < distance(PaletteColor(cubeIndex), r, g, b) {
+		return grayIndex
+	}
+	return cubeIndex
+}
+
+// toBasic picks the nearest of the 16 basic colors
+func (c Color) toBasic() uint8 {
+	if c.kind == colorBasic {
+		return c.index
+	}
+	r, g, b := c.rgb()
+	best, bestDistance := uint8(0), -1
+	for i := range basicRGB {
+		if d := distance(BasicColor(uint8(i)), r, g, b); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = uint8(i), d
+		}
+	}
+	return best
+}
+
+func distance(c Color, r, g, b uint8) int {
+	cr, cg, cb := c.rgb()
+	dr, dg, db := int(cr)-int(r), int(cg)-int(g), int(cb)-int(b)
+	return dr*dr + dg*dg + db*db
+}
+
+// sgr returns the SGR parameters selecting c as the foreground, or the
+// background when background is set
+func (c Color) sgr(profile ColorProfile, background bool) string {
+	if c.kind == colorDefault || profile == NoColor {
+		return ""
+	}
+	base := 30
+	if background {
+		base = 40
+	}
+	switch {
+	case profile == TrueColor && c.kind == colorRGB:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.r, c.g, c.b)
+	case profile >= ANSI256 && c.kind != colorBasic:
+		n := c.index
+		if c.kind == colorRGB {
+			n = c.to256()
+		}
+		return fmt.Sprintf("%d;5;%d", base+8, n)
+	}
+	n := c.toBasic()
+	if n >= 8 {
+		return strconv.Itoa(base + 60 + int(n-8))
+	}
+	return strconv.Itoa(base + int(n))
+}
+
+type attribute uint8
+
+const (
+	attrBold attribute = 1 << iota
+	attrDim
+	attrItalic
+	attrUnderline
+	attrReverse
+	attrStrikethrough
+)
+
+// attributeCodes are the SGR parameters of each attribute, in bit order
+var attributeCodes = []string{"1", "2", "3", "4", "7", "9"}
+
+// Style is a set of colors and text attributes. Styles are values: every
+// method returns a changed copy, so a shared style can be built on.
+//
+//	warning := format.NewStyle().Bold().Foreground(format.ColorYellow)
+//	fmt.Println(warning.Render("disk almost full"))
+type Style struct {
+	fg, bg Color
+	attrs  attribute
+}
+
+// NewStyle returns an empty style, which renders text unchanged
+func NewStyle() Style { return Style{} }
+
+// Bold, Dim, Italic, Underline, Reverse and Strikethrough add a text
+// attribute; terminals without italic or strikethrough ignore them
+func (s Style) Bold() Style          { s.attrs |= attrBold; return s }
+func (s Style) Dim() Style           { s.attrs |= attrDim; return s }
+func (s Style) Italic() Style        { s.attrs |= attrItalic; return s }
+func (s Style) Underline() Style     { s.attrs |= attrUnderline; return s }
+func (s Style) Reverse() Style       { s.attrs |= attrReverse; return s }
+func (s Style) Strikethrough() Style { s.attrs |= attrStrikethrough; return s }
+
+// Foreground sets the text color
+func (s Style) Foreground(c Color) Style { s.fg = c; return s }
+
+// Background sets the color behind the text
+func (s Style) Background(c Color) Style { s.bg = c; return s }
+
+// Sequence returns the escape sequence that turns the style on for
+// profile, or "" when there is nothing to turn on
+func (s Style) Sequence(profile ColorProfile) string {
+	if profile == NoColor {
+		return ""
+	}
+	var params []string
+	for i, code := range attributeCodes {
+		if s.attrs&(1<<i) != 0 {
+			params = append(params, code)
+		}
+	}
+	if fg := s.fg.sgr(profile, false); fg != "" {
+		params = append(params, fg)
+	}
+	if bg := s.bg.sgr(profile, true); bg != "" {
+		params = append(params, bg)
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// RenderFor styles text for profile. Every line is styled on its own, so
+// the styling survives a pager or a terminal that resets it per line.
+func (s Style) RenderFor(profile ColorProfile, text string) string {
+	seq := s.Sequence(profile)
+	if seq == "" || text == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = seq + line + Reset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render styles text for standard output
+func (s Style) Render(text string) string {
+	return s.RenderFor(Stdout.Profile, text)
+}
+
+// Theme is the set of styles an Output's printers use
+type Theme struct {
+	Title   Style
+	Heading Style
+	Key     Style // menu keys and commands
+	Muted   Style // secondary text such as hints and descriptions
+	Success Style
+	Warning Style
+	Error   Style
+	Info    Style
+}
+
+// DefaultTheme uses basic colors, so it looks the same on every terminal
+var DefaultTheme = Theme{
+	Title:   NewStyle().Bold().Foreground(ColorCyan),
+	Heading: NewStyle().Bold(),
+	Key:     NewStyle().Bold().Foreground(ColorGreen),
+	Muted:   NewStyle().Dim(),
+	Success: NewStyle().Foreground(ColorGreen),
+	Warning: NewStyle().Foreground(ColorYellow),
+	Error:   NewStyle().Bold().Foreground(ColorRed),
+	Info:    NewStyle().Foreground(ColorBlue),
+}
+
+// Output prints themed messages to W, styled as far as Profile allows
+type Output struct {
+	W       io.Writer
+	Profile ColorProfile
+	Theme   Theme
+}
+
+// NewOutput creates an Output for w with the profile detected for it
+func NewOutput(w io.Writer) *Output {
+	return &Output{W: w, Profile: DetectProfile(w), Theme: DefaultTheme}
+}
+
+// Stdout and Stderr are Outputs for the standard streams
+var (
+	Stdout = NewOutput(os.Stdout)
+	Stderr = NewOutput(os.Stderr)
+)
+
+// Style renders text in s for this output
+func (o *Output) Style(s Style, text string) string {
+	return s.RenderFor(o.Profile, text)
+}
+
+// Title prints a title underlined with "=", prefixed by icon if not empty
+func (o *Output) Title(icon, text string) {
+	width := VisibleWidth(text)
+	if icon != "" {
+		text = icon + " " + text
+		width += 3 // most emoji are two columns wide
+	}
+	fmt.Fprintln(o.W, o.Style(o.Theme.Title, text))
+	fmt.Fprintln(o.W, o.Style(o.Theme.Muted, strings.Repeat("=", width)))
+}
+
+// Heading prints a section heading after a blank line
+func (o *Output) Heading(icon, text string) {
+	if icon != "" {
+		text = icon + " " + text
+	}
+	fmt.Fprintln(o.W, "\n"+o.Style(o.Theme.Heading, text))
+}
+
+// Options prints keys and their descriptions as an aligned, indented list
+// from alternating key, description arguments, e.g. a menu's choices or a
+// command's modes
+func (o *Output) Options(keysAndDescriptions ...string) {
+	width := 0
+	for i := 0; i < len(keysAndDescriptions); i += 2 {
+		width = max(width, VisibleWidth(keysAndDescriptions[i]))
+	}
+	for i := 0; i < len(keysAndDescriptions); i += 2 {
+		key := keysAndDescriptions[i]
+		line := "  " + pad(o.Style(o.Theme.Key, key), width, AlignLeft)
+		if i+1 < len(keysAndDescriptions) && keysAndDescriptions[i+1] != "" {
+			line += "  " + keysAndDescriptions[i+1]
+		}
+		fmt.Fprintln(o.W, line)
+	}
+}
+
+// Commands prints shell commands to try, each on its own indented line
+func (o *Output) Commands(commands ...string) {
+	for _, command := range commands {
+		fmt.Fprintln(o.W, "  "+o.Style(o.Theme.Key, command))
+	}
+}
+
+// Hint prints a dimmed line of secondary information
+func (o *Output) Hint(format string, args ...interface{}) {
+	fmt.Fprintln(o.W, o.Style(o.Theme.Muted, fmt.Sprintf(format, args...)))
+}
+
+// Success prints a message with ✅ in the theme's success style
+func (o *Output) Success(format string, args ...interface{}) {
+	o.message("✅", o.Theme.Success, format, args)
+}
+
+// Warn prints a message with ⚠️ in the theme's warning style
+func (o *Output) Warn(format string, args ...interface{}) {
+	o.message("⚠️ ", o.Theme.Warning, format, args)
+}
+
+// Error prints a message with ❌ in the theme's error style
+func (o *Output) Error(format string, args ...interface{}) {
+	o.message("❌", o.Theme.Error, format, args)
+}
+
+// Info prints a message with 💡 in the theme's info style
+func (o *Output) Info(format string, args ...interface{}) {
+	o.message("💡", o.Theme.Info, format, args)
+}
+
+func (o *Output) message(icon string, style Style, format string, args []interface{}) {
+	fmt.Fprintln(o.W, icon+" "+o.Style(style, fmt.Sprintf(format, args...)))
+}
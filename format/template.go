@@ -0,0 +1,535 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// VerbFunc renders value for a custom verb. Flags, width and precision are
+// available through f, exactly as in a fmt.Formatter.
+type VerbFunc func(f fmt.State, verb rune, value interface{})
+
+// Printer is a fmt-compatible formatter with custom verbs and named
+// placeholders. Verbs not registered on the printer behave as in fmt.
+type Printer struct {
+	mu    sync.RWMutex
+	verbs map[rune]VerbFunc
+}
+
+// NewPrinter creates a printer with the built-in verbs:
+//
+//	%H  humanized byte count ("1.5 KiB"; %#H uses SI units, precision sets decimals)
+//	%D  duration rounded to its largest units ("1h2m"; precision sets the unit count)
+func NewPrinter() *Printer {
+	p := &Printer{verbs: make(map[rune]VerbFunc)}
+	p.RegisterVerb('H', formatBytesVerb)
+	p.RegisterVerb('D', formatDurationVerb)
+	return p
+}
+
+// RegisterVerb adds or replaces a custom verb
+func (p *Printer) RegisterVerb(verb rune, fn VerbFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.verbs[verb] = fn
+}
+
+func (p *Printer) verb(verb rune) (VerbFunc, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	fn, ok := p.verbs[verb]
+	return fn, ok
+}
+
+// customArg defers formatting of one argument to a registered verb
+type customArg struct {
+	printer *Printer
+	value   interface{}
+}
+
+func (a customArg) Format(f fmt.State, verb rune) {
+	if fn, ok := a.printer.verb(verb); ok {
+		fn(f, verb, a.value)
+		return
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), a.value)
+}
+
+// Sprintf formats like fmt.Sprintf, dispatching registered verbs to their
+// VerbFunc. Only arguments consumed by custom verbs are wrapped, so %T and %p
+// still see the original values.
+func (p *Printer) Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(format, p.wrapArgs(format, args)...)
+}
+
+// Fprintf writes formatted output to w
+func (p *Printer) Fprintf(w io.Writer, format string, args ...interface{}) (int, error) {
+	return fmt.Fprintf(w, format, p.wrapArgs(format, args)...)
+}
+
+// Printf writes formatted output to standard output
+func (p *Printer) Printf(format string, args ...interface{}) (int, error) {
+	return fmt.Printf(format, p.wrapArgs(format, args)...)
+}
+
+// wrapArgs walks the directives in format the same way fmt does (including
+// * widths and [n] argument indexes) and wraps arguments used by custom verbs
+func (p *Printer) wrapArgs(format string, args []interface{}) []interface{} {
+	wrapped, copied := args, false
+	argNum := 0
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		// width and precision, either of which may be * or preceded by [n]
+		for part := 0; part < 2; part++ {
+			i, argNum = parseArgIndex(format, i, argNum)
+			if i < len(format) && format[i] == '*' {
+				i++
+				argNum++
+			}
+			for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+			if part == 0 && i < len(format) && format[i] == '.' {
+				i++
+				continue
+			}
+			break
+		}
+		i, argNum = parseArgIndex(format, i, argNum)
+		if i >= len(format) {
+			break
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size
+		if verb == '%' {
+			continue
+		}
+		if _, ok := p.verb(verb); ok && argNum < len(args) {
+			if !copied {
+				wrapped, copied = append([]interface{}(nil), args...), true
+			}
+			wrapped[argNum] = customArg{printer: p, value: args[argNum]}
+		}
+		argNum++
+	}
+	return wrapped
+}
+
+func parseArgIndex(format string, i, argNum int) (int, int) {
+	if i >= len(format) || format[i] != '[' {
+		return i, argNum
+	}
+	end := strings.IndexByte(format[i:], ']')
+	if end < 0 {
+		return i, argNum
+	}
+	n, err := strconv.Atoi(format[i+1 : i+end])
+	if err != nil || n < 1 {
+		return i + end + 1, argNum
+	}
+	return i + end + 1, n - 1
+}
+
+// Expand replaces named placeholders in template with values from data.
+//
+// A placeholder is {path}, {path:spec} or {path|filter:args}:
+//   - path is a dotted lookup through struct fields, map keys and slice
+//     indexes, e.g. {user.Name} or {items.0}
+//   - spec is a format directive passed to Sprintf, e.g. {size:%H} or {ratio:.1f}
+//   - filters are plural:one,other (chooses a form from a numeric value),
+//     upper and lower
+//
+// Use {{ and }} for literal braces.
+func (p *Printer) Expand(template string, data interface{}) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(template); {
+		switch {
+		case strings.HasPrefix(template[i:], "{{"):
+			sb.WriteByte('{')
+			i += 2
+		case strings.HasPrefix(template[i:], "}}"):
+			sb.WriteByte('}')
+			i += 2
+		case template[i] == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed placeholder at offset %d", i)
+			}
+			text, err := p.expandPlaceholder(template[i+1:i+end], data)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(text)
+			i += end + 1
+		default:
+			sb.WriteByte(template[i])
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// MustExpand is like Expand but renders errors inline instead of returning them
+func (p *Printer) MustExpand(template string, data interface{}) string {
+	text, err := p.Expand(template, data)
+	if err != nil {
+		return fmt.Sprintf("%%!(EXPAND %v)", err)
+	}
+	return text
+}
+
+func (p *Printer) expandPlaceholder(placeholder string, data interface{}) (string, error) {
+	expr, filters, _ := strings.Cut(placeholder, "|")
+	path, spec, hasSpec := strings.Cut(strings.TrimSpace(expr), ":")
+
+	value, err := Lookup(data, path)
+	if err != nil {
+		return "", err
+	}
+
+	text := fmt.Sprint(value)
+	if hasSpec {
+		if !strings.HasPrefix(spec, "%") {
+			spec = "%" + spec
+		}
+		text = p.Sprintf(spec, value)
+	}
+
+	if filters == "" {
+		return text, nil
+	}
+	for _, filter := range strings.Split(filters, "|") {
+		name, args, _ := strings.Cut(strings.TrimSpace(filter), ":")
+		switch name {
+		case "plural":
+			n, ok := toFloat(value)
+			if !ok {
+				return "", fmt.Errorf("plural filter needs a number, got %T for %q", value, path)
+			}
+			one, other, _ := strings.Cut(args, ",")
+			text = Plural(int(n), one, other)
+		case "upper":
+			text = strings.ToUpper(text)
+		case "lower":
+			text = strings.ToLower(text)
+		default:
+			return "", fmt.Errorf("unknown filter %q in {%s}", name, placeholder)
+		}
+	}
+	return text, nil
+}
+
+// Lookup resolves a dotted path against nested structs, maps and slices
+func Lookup(data interface{}, path string) (interface{}, error) {
+	if path == "" || path == "." {
+		return data, nil
+	}
+	if scope, ok := data.(countScope); ok {
+		if path == "Count" {
+			return scope.count, nil
+		}
+		data = scope.data
+	}
+
+	v := reflect.ValueOf(data)
+	for _, part := range strings.Split(path, ".") {
+		for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+			if v.IsNil() {
+				return nil, fmt.Errorf("nil value before %q in %q", part, path)
+			}
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			return nil, fmt.Errorf("no value for %q", path)
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field := v.FieldByName(part)
+			if !field.IsValid() || !field.CanInterface() {
+				return nil, fmt.Errorf("unknown field %q in %q", part, path)
+			}
+			v = field
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("map keys must be strings to resolve %q", path)
+			}
+			entry := v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+			if !entry.IsValid() {
+				return nil, fmt.Errorf("missing key %q in %q", part, path)
+			}
+			v = entry
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= v.Len() {
+				return nil, fmt.Errorf("invalid index %q in %q", part, path)
+			}
+			v = v.Index(index)
+		default:
+			return nil, fmt.Errorf("cannot resolve %q on %s in %q", part, v.Kind(), path)
+		}
+	}
+	return v.Interface(), nil
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// Plural returns one when n is 1 and other otherwise
+func Plural(n int, one, other string) string {
+	if n == 1 {
+		return one
+	}
+	return other
+}
+
+// PluralRule maps a count to a CLDR plural category ("zero", "one" or "other")
+type PluralRule func(n int) string
+
+var pluralRules = map[string]PluralRule{
+	"en": func(n int) string { return Plural(n, "one", "other") },
+	"de": func(n int) string { return Plural(n, "one", "other") },
+	"fr": func(n int) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	"ja": func(int) string { return "other" },
+	"zh": func(int) string { return "other" },
+}
+
+// RegisterPluralRule sets the plural rule for a language
+func RegisterPluralRule(language string, rule PluralRule) {
+	pluralRules[language] = rule
+}
+
+// Message holds the plural forms of one translated template. Zero is used
+// for a count of 0 when set; One and Other follow the locale's plural rule.
+type Message struct {
+	Zero  string
+	One   string
+	Other string
+}
+
+func (m Message) form(category string, n int) string {
+	switch {
+	case n == 0 && m.Zero != "":
+		return m.Zero
+	case category == "one" && m.One != "":
+		return m.One
+	}
+	return m.Other
+}
+
+// Bundle stores message templates per locale with fallback to a default locale
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]Message
+	printer  *Printer
+}
+
+// NewBundle creates a bundle that falls back to the fallback locale
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{fallback: fallback, messages: make(map[string]map[string]Message), printer: NewPrinter()}
+}
+
+// Add registers a message with plural forms for locale
+func (b *Bundle) Add(locale, key string, message Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[locale] == nil {
+		b.messages[locale] = make(map[string]Message)
+	}
+	b.messages[locale][key] = message
+}
+
+// AddMessages registers simple messages without plural forms
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	for key, text := range messages {
+		b.Add(locale, key, Message{Other: text})
+	}
+}
+
+// Localizer resolves messages for one locale, e.g. "fr" or "fr-CA"
+func (b *Bundle) Localizer(locale string) *Localizer {
+	return &Localizer{bundle: b, locale: locale}
+}
+
+func (b *Bundle) lookup(locale, key string) (Message, string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	language, _, _ := strings.Cut(locale, "-")
+	for _, candidate := range []string{locale, language, b.fallback} {
+		if message, ok := b.messages[candidate][key]; ok {
+			return message, candidate, true
+		}
+	}
+	return Message{}, "", false
+}
+
+// Localizer renders bundle messages in a fixed locale
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// countScope exposes {Count} to plural message templates
+type countScope struct {
+	count int
+	data  interface{}
+}
+
+// T renders the message for key with data; missing keys render as the key
+func (l *Localizer) T(key string, data interface{}) string {
+	message, _, ok := l.bundle.lookup(l.locale, key)
+	if !ok {
+		return key
+	}
+	return l.bundle.printer.MustExpand(message.Other, data)
+}
+
+// N renders the plural form of key for count; templates may use {Count}
+func (l *Localizer) N(key string, count int, data interface{}) string {
+	message, locale, ok := l.bundle.lookup(l.locale, key)
+	if !ok {
+		return key
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	rule, ok := pluralRules[language]
+	if !ok {
+		rule = pluralRules["en"]
+	}
+	return l.bundle.printer.MustExpand(message.form(rule(count), count), countScope{count: count, data: data})
+}
+
+var defaultPrinter = NewPrinter()
+
+// RegisterVerb adds a custom verb to the default printer
+func RegisterVerb(verb rune, fn VerbFunc) {
+	defaultPrinter.RegisterVerb(verb, fn)
+}
+
+// Sprintf formats with the default printer
+func Sprintf(format string, args ...interface{}) string {
+	return defaultPrinter.Sprintf(format, args...)
+}
+
+// Printf writes to standard output with the default printer
+func Printf(format string, args ...interface{}) (int, error) {
+	return defaultPrinter.Printf(format, args...)
+}
+
+// Expand fills template placeholders with the default printer
+func Expand(template string, data interface{}) (string, error) {
+	return defaultPrinter.Expand(template, data)
+}
+
+// MustExpand fills template placeholders with the default printer, rendering
+// errors inline
+func MustExpand(template string, data interface{}) string {
+	return defaultPrinter.MustExpand(template, data)
+}
+
+// writePadded applies the width and '-' flag of f to text
+func writePadded(f fmt.State, text string) {
+	width, ok := f.Width()
+	if !ok || utf8.RuneCountInString(text) >= width {
+		io.WriteString(f, text)
+		return
+	}
+	padding := strings.Repeat(" ", width-utf8.RuneCountInString(text))
+	if f.Flag('-') {
+		io.WriteString(f, text+padding)
+	} else {
+		io.WriteString(f, padding+text)
+	}
+}
+
+func formatBytesVerb(f fmt.State, verb rune, value interface{}) {
+	n, ok := toFloat(value)
+	if !ok {
+		fmt.Fprintf(f, "%%!%c(%T=%v)", verb, value, value)
+		return
+	}
+	precision, ok := f.Precision()
+	if !ok {
+		precision = 1
+	}
+	writePadded(f, humanizeBytes(n, f.Flag('#'), precision))
+}
+
+func formatDurationVerb(f fmt.State, verb rune, value interface{}) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	default:
+		seconds, ok := toFloat(value)
+		if !ok {
+			fmt.Fprintf(f, "%%!%c(%T=%v)", verb, value, value)
+			return
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	units, ok := f.Precision()
+	if !ok {
+		units = 2
+	}
+	writePadded(f, shortDuration(d, units))
+}
+
+// shortDuration renders d using at most units of its largest components
+func shortDuration(d time.Duration, units int) string {
+	if d < 0 {
+		return "-" + shortDuration(-d, units)
+	}
+	if d < time.Second {
+		return d.Round(time.Microsecond).String()
+	}
+	parts := []struct {
+		size time.Duration
+		unit string
+	}{
+		{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"},
+	}
+	var sb strings.Builder
+	used := 0
+	for _, part := range parts {
+		if used == units {
+			break
+		}
+		if count := d / part.size; count > 0 || used > 0 {
+			if count > 0 {
+				fmt.Fprintf(&sb, "%d%s", count, part.unit)
+			}
+			d -= count * part.size
+			used++
+		}
+	}
+	return sb.String()
+}
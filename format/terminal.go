@@ -0,0 +1,417 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences for terminal colors
+const (
+	Reset   = "\033[0m"
+	Bold    = "\033[1m"
+	Dim     = "\033[2m"
+	Red     = "\033[31m"
+	Green   = "\033[32m"
+	Yellow  = "\033[33m"
+	Blue    = "\033[34m"
+	Magenta = "\033[35m"
+	Cyan    = "\033[36m"
+)
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Colorize wraps text in the given ANSI codes, unless standard output is
+// not a terminal or NO_COLOR is set; see DetectProfile. Style offers 256
+// and RGB colors.
+func Colorize(text string, codes ...string) string {
+	if len(codes) == 0 || Stdout.Profile == NoColor {
+		return text
+	}
+	return strings.Join(codes, "") + text + Reset
+}
+
+// StripANSI removes ANSI escape sequences
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// VisibleWidth returns the number of runes shown on screen, ignoring ANSI codes
+func VisibleWidth(s string) int {
+	return utf8.RuneCountInString(StripANSI(s))
+}
+
+// Truncate shortens s to at most width visible runes, ending with "…"
+func Truncate(s string, width int) string {
+	if width <= 0 || VisibleWidth(s) <= width {
+		return s
+	}
+	plain := []rune(StripANSI(s))
+	if width == 1 {
+		return "…"
+	}
+	return string(plain[:width-1]) + "…"
+}
+
+// Align is the horizontal alignment of a table column
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+func pad(s string, width int, align Align) string {
+	gap := width - VisibleWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", gap) + s
+	case AlignCenter:
+		left := gap / 2
+		return strings.Repeat(" ", left) + s + strings.Repeat(" ", gap-left)
+	}
+	return s + strings.Repeat(" ", gap)
+}
+
+// Table renders rows of cells in aligned columns with box-drawing borders
+type Table struct {
+	headers     []string
+	rows        [][]string
+	aligns      map[int]Align
+	maxWidths   map[int]int
+	colors      map[int]func(cell string) string
+	HeaderColor string // ANSI codes applied to header cells
+}
+
+// NewTable creates a table with the given column headers
+func NewTable(headers ...string) *Table {
+	return &Table{
+		headers:     headers,
+		aligns:      make(map[int]Align),
+		maxWidths:   make(map[int]int),
+		colors:      make(map[int]func(string) string),
+		HeaderColor: Bold,
+	}
+}
+
+// SetAlign sets the alignment of column col
+func (t *Table) SetAlign(col int, align Align) *Table {
+	t.aligns[col] = align
+	return t
+}
+
+// SetMaxWidth truncates cells in column col to width runes
+func (t *Table) SetMaxWidth(col, width int) *Table {
+	t.maxWidths[col] = width
+	return t
+}
+
+// SetColor colors cells in column col; color receives the plain cell text and
+// returns the ANSI codes to apply, or "" for none
+func (t *Table) SetColor(col int, color func(cell string) string) *Table {
+	t.colors[col] = color
+	return t
+}
+
+// AddRow appends a row; values are formatted with fmt.Sprint
+func (t *Table) AddRow(cells ...interface{}) *Table {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = fmt.Sprint(cell)
+	}
+	t.rows = append(t.rows, row)
+	return t
+}
+
+// Render writes the table to w
+func (t *Table) Render(w io.Writer) {
+	io.WriteString(w, t.String())
+}
+
+// String returns the rendered table
+func (t *Table) String() string {
+	columns := len(t.headers)
+	for _, row := range t.rows {
+		columns = max(columns, len(row))
+	}
+
+	cell := func(row []string, col int) string {
+		if col >= len(row) {
+			return ""
+		}
+		if limit, ok := t.maxWidths[col]; ok {
+			return Truncate(row[col], limit)
+		}
+		return row[col]
+	}
+
+	widths := make([]int, columns)
+	for col := range widths {
+		widths[col] = VisibleWidth(cell(t.headers, col))
+		for _, row := range t.rows {
+			widths[col] = max(widths[col], VisibleWidth(cell(row, col)))
+		}
+	}
+
+	var sb strings.Builder
+	border := func(left, mid, right string) {
+		sb.WriteString(left)
+		for col, width := range widths {
+			if col > 0 {
+				sb.WriteString(mid)
+			}
+			sb.WriteString(strings.Repeat("─", width+2))
+		}
+		sb.WriteString(right + "\n")
+	}
+	line := func(row []string, header bool) {
+		sb.WriteString("│")
+		for col, width := range widths {
+			text := cell(row, col)
+			align := t.aligns[col]
+			padded := pad(text, width, align)
+			switch {
+			case text == "":
+			case header && t.HeaderColor != "":
+				padded = pad(Colorize(text, t.HeaderColor), width, align)
+			case !header && t.colors[col] != nil:
+				if codes := t.colors[col](StripANSI(text)); codes != "" {
+					padded = pad(Colorize(text, codes), width, align)
+				}
+			}
+			sb.WriteString(" " + padded + " │")
+		}
+		sb.WriteString("\n")
+	}
+
+	border("┌", "┬", "┐")
+	if len(t.headers) > 0 {
+		line(t.headers, true)
+		border("├", "┼", "┤")
+	}
+	for _, row := range t.rows {
+		line(row, false)
+	}
+	border("└", "┴", "┘")
+	return sb.String()
+}
+
+// Tree is a labelled node with children, rendered like the `tree` command
+type Tree struct {
+	Label    string
+	Children []*Tree
+}
+
+// NewTree creates a root node
+func NewTree(label string) *Tree {
+	return &Tree{Label: label}
+}
+
+// Add appends a child and returns it so branches can be built fluently
+func (t *Tree) Add(label string) *Tree {
+	child := &Tree{Label: label}
+	t.Children = append(t.Children, child)
+	return child
+}
+
+// String renders the tree with box-drawing connectors
+func (t *Tree) String() string {
+	var sb strings.Builder
+	sb.WriteString(t.Label + "\n")
+	t.render(&sb, "")
+	return sb.String()
+}
+
+func (t *Tree) render(sb *strings.Builder, prefix string) {
+	for i, child := range t.Children {
+		connector, indent := "├── ", "│   "
+		if i == len(t.Children)-1 {
+			connector, indent = "└── ", "    "
+		}
+		sb.WriteString(prefix + connector + child.Label + "\n")
+		child.render(sb, prefix+indent)
+	}
+}
+
+// TreeFromValue builds a tree from nested maps, slices and structs, e.g. a
+// decoded JSON document. Map keys are sorted.
+func TreeFromValue(label string, v interface{}) *Tree {
+	root := NewTree(label)
+	addValue(root, reflect.ValueOf(v))
+	return root
+}
+
+func addValue(node *Tree, v reflect.Value) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			node.Label += ": <nil>"
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		node.Label += ": <nil>"
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			addValue(node.Add(fmt.Sprint(key)), v.MapIndex(key))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			addValue(node.Add(fmt.Sprintf("[%d]", i)), v.Index(i))
+		}
+	case reflect.Struct:
+		if _, ok := v.Interface().(fmt.Stringer); ok {
+			node.Label += ": " + fmt.Sprint(v.Interface())
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				addValue(node.Add(v.Type().Field(i).Name), v.Field(i))
+			}
+		}
+	default:
+		node.Label += ": " + fmt.Sprint(v.Interface())
+	}
+}
+
+// ProgressBar draws a single-line progress bar with rate and ETA, redrawn in
+// place with a carriage return
+type ProgressBar struct {
+	mu          sync.Mutex
+	out         io.Writer
+	total       int64
+	current     int64
+	start       time.Time
+	lastDraw    time.Time
+	Width       int    // bar width in characters
+	Description string // shown before the bar
+	Unit        string // e.g. "req" or "ports", shown in the rate
+	now         func() time.Time
+}
+
+// NewProgressBar creates a bar for total units of work
+func NewProgressBar(out io.Writer, total int64, description string) *ProgressBar {
+	return &ProgressBar{out: out, total: total, Width: 30, Description: description, Unit: "it", now: time.Now, start: time.Now()}
+}
+
+// Add advances the bar by n units; safe for concurrent use
+func (p *ProgressBar) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = min(p.current+n, p.total)
+	// Limit redraws so fast loops do not flood the terminal
+	if now := p.now(); p.current == p.total || now.Sub(p.lastDraw) >= 50*time.Millisecond {
+		p.lastDraw = now
+		p.draw()
+	}
+}
+
+// Increment advances the bar by one unit
+func (p *ProgressBar) Increment() {
+	p.Add(1)
+}
+
+// Finish completes the bar and moves to the next line
+func (p *ProgressBar) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = p.total
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+// Line returns the current rendering of the bar without drawing it
+func (p *ProgressBar) Line() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.line()
+}
+
+func (p *ProgressBar) draw() {
+	fmt.Fprint(p.out, "\r"+p.line())
+}
+
+func (p *ProgressBar) line() string {
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = float64(p.current) / float64(p.total)
+	}
+	filled := int(ratio * float64(p.Width))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", p.Width-filled)
+
+	elapsed := p.now().Sub(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.current) / elapsed.Seconds()
+	}
+	eta := "--"
+	if p.current >= p.total {
+		eta = "done"
+	} else if rate > 0 {
+		eta = (time.Duration(float64(p.total-p.current)/rate) * time.Second).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s %s %3.0f%% %d/%d %.1f %s/s ETA %s",
+		p.Description, bar, ratio*100, p.current, p.total, rate, p.Unit, eta)
+}
+
+// Spinner shows an animated indicator for work of unknown length
+type Spinner struct {
+	out     io.Writer
+	message string
+	frames  []string
+	stop    chan struct{}
+	done    chan struct{}
+	start   time.Time
+}
+
+// NewSpinner creates a spinner showing message
+func NewSpinner(out io.Writer, message string) *Spinner {
+	return &Spinner{out: out, message: message, frames: []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}}
+}
+
+// Start begins animating in a background goroutine
+func (s *Spinner) Start() {
+	s.stop, s.done, s.start = make(chan struct{}), make(chan struct{}), time.Now()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(s.out, "\r%s %s (%s)", s.frames[i%len(s.frames)], s.message, time.Since(s.start).Round(time.Second))
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the animation and replaces it with final
+func (s *Spinner) Stop(final string) {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	elapsed := time.Since(s.start).Round(time.Millisecond)
+	fmt.Fprintf(s.out, "\r%s (%s)%s\n", final, elapsed, strings.Repeat(" ", 10))
+	s.stop = nil
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcencoding "google.golang.org/grpc/encoding"
+
+	"github.com/jerrychou/go-practice/encoding"
+)
+
+// The JSON codec rides alongside protobuf as the "application/grpc+json"
+// content subtype; it marshals the generated messages through their json tags
+func init() {
+	grpcencoding.RegisterCodec(encoding.JSON)
+}
+
+// RetryPolicy retries unary calls that fail with one of RetryableCodes,
+// waiting an exponentially growing, jittered backoff between attempts. The
+// call's deadline covers all attempts. grpc-go caps MaxAttempts at 5.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy retries Unavailable up to 4 attempts in total
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Multiplier:     2,
+	RetryableCodes: []codes.Code{codes.Unavailable},
+}
+
+// ServiceConfig returns the policy as a gRPC service config applying to
+// every method of the given services, e.g. "practice.users.v1.UserService"
+func (p RetryPolicy) ServiceConfig(services ...string) string {
+	type name struct {
+		Service string `json:"service"`
+	}
+	type retryPolicy struct {
+		MaxAttempts          int          `json:"maxAttempts"`
+		InitialBackoff       string       `json:"initialBackoff"`
+		MaxBackoff           string       `json:"maxBackoff"`
+		BackoffMultiplier    float64      `json:"backoffMultiplier"`
+		RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name        []name      `json:"name"`
+		RetryPolicy retryPolicy `json:"retryPolicy"`
+	}
+
+	names := make([]name, len(services))
+	for i, service := range services {
+		names[i] = name{Service: service}
+	}
+	seconds := func(d time.Duration) string { return fmt.Sprintf("%gs", d.Seconds()) }
+	config := map[string][]methodConfig{"methodConfig": {{
+		Name: names,
+		RetryPolicy: retryPolicy{
+			MaxAttempts:          p.MaxAttempts,
+			InitialBackoff:       seconds(p.InitialBackoff),
+			MaxBackoff:           seconds(p.MaxBackoff),
+			BackoffMultiplier:    p.Multiplier,
+			RetryableStatusCodes: p.RetryableCodes,
+		},
+	}}}
+	data, _ := json.Marshal(config)
+	return string(data)
+}
+
+// WithRetryPolicy retries the unary calls to the given services according
+// to policy; the retries happen inside grpc-go, below the interceptors
+func WithRetryPolicy(policy RetryPolicy, services ...string) grpc.DialOption {
+	return grpc.WithDefaultServiceConfig(policy.ServiceConfig(services...))
+}
+
+// WithDefaultTimeout sets a deadline for unary calls whose context has none
+func WithDefaultTimeout(d time.Duration) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+// WithCodec selects the message codec by its content subtype; protobuf is
+// the default. The codec must be registered with grpc's encoding package,
+// as encoding.JSON is.
+func WithCodec(codec encoding.Codec) grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codec.Name()))
+}
+
+// bearerToken sends "authorization: Bearer <token>" with every call
+type bearerToken func(ctx context.Context) (string, error)
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity is false so the plaintext demo can send tokens;
+// a real deployment would dial with TLS credentials and return true
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithBearerToken sends "authorization: Bearer <token>" with every call
+func WithBearerToken(token func(ctx context.Context) (string, error)) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(bearerToken(token))
+}
+
+// Dial creates a client for the plaintext server at target ("host:port").
+// Like grpc.NewClient, which it wraps, it does not connect until the first
+// call.
+func Dial(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	return grpc.NewClient(target, opts...)
+}
@@ -0,0 +1,257 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/grpc/pb"
+	"github.com/jerrychou/go-practice/security"
+)
+
+const methodPanic = "/practice.debug.v1.DebugService/Panic"
+
+// debugService has a single method that panics, registered by hand rather
+// than from a .proto to show what the generated RegisterXServer calls do
+var debugService = grpc.ServiceDesc{
+	ServiceName: "practice.debug.v1.DebugService",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Panic",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(emptypb.Empty)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				var users map[string]*pb.User
+				users["boom"].Name = "nil map" // deliberate nil pointer panic
+				return &emptypb.Empty{}, nil
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodPanic}, handler)
+		},
+	}},
+}
+
+func startDemoServer(auth *security.JWTAuth, users *UserService) (*grpc.Server, string, error) {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(RecoveryUnaryInterceptor(), LoggingUnaryInterceptor(),
+			AuthUnaryInterceptor(auth, pb.UserService_GetUser_FullMethodName, pb.UserService_GenerateReport_FullMethodName, "/practice.debug.v1.DebugService/")),
+		grpc.ChainStreamInterceptor(RecoveryStreamInterceptor(), LoggingStreamInterceptor(), AuthStreamInterceptor(auth)),
+	)
+	pb.RegisterUserServiceServer(server, users)
+	pb.RegisterChatServiceServer(server, NewChatRoom())
+	server.RegisterService(&debugService, struct{}{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	go server.Serve(lis)
+	return server, lis.Addr().String(), nil
+}
+
+func printResult(label string, v any, err error) {
+	if err != nil {
+		st := status.Convert(err)
+		fmt.Printf("%-34s ❌ %s: %s\n", label, st.Code(), st.Message())
+		return
+	}
+	fmt.Printf("%-34s ✅ %v\n", label, v)
+}
+
+// UnaryExamples demonstrates unary calls, errors, auth and codecs
+func UnaryExamples(addr string, auth *security.JWTAuth) {
+	fmt.Println("\n=== Unary Calls ===")
+
+	aliceToken, _ := auth.GenerateToken("1", "alice", []string{"admin"}, 1)
+	bobToken, _ := auth.GenerateToken("2", "bob", []string{"user"}, 1)
+	bearer := func(token string) grpc.DialOption {
+		return WithBearerToken(func(ctx context.Context) (string, error) { return token, nil })
+	}
+
+	anonymousConn, _ := Dial(addr, WithDefaultTimeout(2*time.Second))
+	aliceConn, _ := Dial(addr, bearer(aliceToken))
+	bobConn, _ := Dial(addr, bearer(bobToken))
+	jsonConn, _ := Dial(addr, WithCodec(encoding.JSON))
+	defer anonymousConn.Close()
+	defer aliceConn.Close()
+	defer bobConn.Close()
+	defer jsonConn.Close()
+	anonymous := pb.NewUserServiceClient(anonymousConn)
+	alice := pb.NewUserServiceClient(aliceConn)
+	bob := pb.NewUserServiceClient(bobConn)
+
+	ctx := context.Background()
+	user, err := anonymous.GetUser(ctx, &pb.GetUserRequest{Id: 1})
+	printResult("GetUser(1):", user, err)
+
+	_, err = anonymous.GetUser(ctx, &pb.GetUserRequest{Id: 99})
+	printResult("GetUser(99):", nil, err)
+
+	_, err = anonymous.GetUser(ctx, &pb.GetUserRequest{Id: -1})
+	printResult("GetUser(-1):", nil, err)
+
+	user, err = pb.NewUserServiceClient(jsonConn).GetUser(ctx, &pb.GetUserRequest{Id: 2})
+	printResult("GetUser(2) with JSON codec:", user, err)
+
+	req := &pb.CreateUserRequest{Name: "Carol", Email: "carol@example.com", Roles: []string{"user"}}
+	_, err = anonymous.CreateUser(ctx, req)
+	printResult("CreateUser without token:", nil, err)
+
+	_, err = bob.CreateUser(ctx, req)
+	printResult("CreateUser as bob (user):", nil, err)
+
+	user, err = alice.CreateUser(ctx, req)
+	printResult("CreateUser as alice (admin):", user, err)
+
+	_, err = alice.CreateUser(ctx, req)
+	printResult("CreateUser duplicate:", nil, err)
+
+	err = anonymousConn.Invoke(ctx, methodPanic, &emptypb.Empty{}, &emptypb.Empty{})
+	printResult("Panicking handler:", nil, err)
+
+	err = anonymousConn.Invoke(ctx, "/practice.users.v1.UserService/DeleteUser", &pb.GetUserRequest{Id: 1}, &emptypb.Empty{})
+	printResult("Unknown method:", nil, err)
+}
+
+// RetryAndDeadlineExamples demonstrates retries with backoff and deadlines
+func RetryAndDeadlineExamples(addr string, users *UserService) {
+	fmt.Println("\n=== Retries and Deadlines ===")
+
+	fmt.Printf("Retry service config: %s\n", DefaultRetryPolicy.ServiceConfig("practice.users.v1.UserService"))
+	noRetryConn, _ := Dial(addr)
+	withRetryConn, _ := Dial(addr, WithRetryPolicy(DefaultRetryPolicy, "practice.users.v1.UserService"))
+	defer noRetryConn.Close()
+	defer withRetryConn.Close()
+	noRetry := pb.NewUserServiceClient(noRetryConn)
+	withRetry := pb.NewUserServiceClient(withRetryConn)
+	ctx := context.Background()
+
+	users.FailNext(2)
+	_, err := noRetry.GetUser(ctx, &pb.GetUserRequest{Id: 1})
+	printResult("Warming backend, no retries:", nil, err)
+
+	users.FailNext(2)
+	start := time.Now()
+	user, err := withRetry.GetUser(ctx, &pb.GetUserRequest{Id: 1})
+	printResult("Warming backend, with retries:", user.GetName(), err)
+	fmt.Printf("  (succeeded on attempt 3 of %d after %s)\n", DefaultRetryPolicy.MaxAttempts, time.Since(start).Round(time.Millisecond))
+
+	users.FailNext(10)
+	_, err = withRetry.GetUser(ctx, &pb.GetUserRequest{Id: 1})
+	printResult("Backend down, retries exhausted:", nil, err)
+	users.FailNext(0)
+
+	shortCtx, cancel := context.WithTimeout(ctx, 120*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = noRetry.GenerateReport(shortCtx, &pb.ReportRequest{Pages: 10})
+	printResult("10-page report, 120ms deadline:", nil, err)
+	fmt.Printf("  (gave up after %s; the deadline travels to the server as grpc-timeout)\n", time.Since(start).Round(10*time.Millisecond))
+
+	longCtx, cancelLong := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelLong()
+	report, err := noRetry.GenerateReport(longCtx, &pb.ReportRequest{Pages: 3})
+	printResult("3-page report, 2s deadline:", report.GetSummary(), err)
+}
+
+// StreamingExamples demonstrates a bidirectional chat stream
+func StreamingExamples(addr string, auth *security.JWTAuth) {
+	fmt.Println("\n=== Bidirectional Streaming Chat ===")
+
+	connect := func(name string) (*grpc.ClientConn, pb.ChatService_ChatClient, error) {
+		token, _ := auth.GenerateToken(name, name, []string{"user"}, 1)
+		cc, _ := Dial(addr, WithBearerToken(func(ctx context.Context) (string, error) { return token, nil }))
+		stream, err := pb.NewChatServiceClient(cc).Chat(context.Background())
+		return cc, stream, err
+	}
+	receive := func(who string, stream pb.ChatService_ChatClient) {
+		msg, err := stream.Recv()
+		if err != nil {
+			fmt.Printf("  [%s] stream ended: %v\n", who, err)
+			return
+		}
+		fmt.Printf("  [%s] %s: %s\n", who, msg.GetFrom(), msg.GetText())
+	}
+
+	anonymous, _ := Dial(addr)
+	defer anonymous.Close()
+	if stream, err := pb.NewChatServiceClient(anonymous).Chat(context.Background()); err == nil {
+		stream.CloseSend()
+		_, err := stream.Recv()
+		printResult("Chat without token:", nil, err)
+	}
+
+	aliceConn, alice, err := connect("alice")
+	if err != nil {
+		fmt.Printf("Error connecting: %v\n", err)
+		return
+	}
+	defer aliceConn.Close()
+	receive("alice", alice) // alice joined
+
+	bobConn, bob, err := connect("bob")
+	if err != nil {
+		fmt.Printf("Error connecting: %v\n", err)
+		return
+	}
+	defer bobConn.Close()
+	receive("alice", alice) // bob joined
+	receive("bob", bob)
+
+	alice.Send(&pb.ChatMessage{Text: "Hi Bob! 👋"})
+	receive("alice", alice)
+	receive("bob", bob)
+
+	// The server ignores the From field and uses the JWT username
+	bob.Send(&pb.ChatMessage{From: "mallory", Text: "Hey Alice, streaming works"})
+	receive("alice", alice)
+	receive("bob", bob)
+
+	bob.CloseSend()
+	receive("bob", bob)     // io.EOF: the server finished the call with OK
+	receive("alice", alice) // bob left
+
+	alice.CloseSend()
+	if _, err := alice.Recv(); errors.Is(err, io.EOF) {
+		fmt.Println("  [alice] stream closed cleanly")
+	}
+}
+
+// RunAllGRPCExamples starts a demo server and exercises it with clients
+func RunAllGRPCExamples() {
+	fmt.Println("🎯 Go gRPC Examples")
+	fmt.Println("===================")
+
+	fmt.Println("\n=== Service Definition ===")
+	fmt.Print(ServiceProto())
+
+	auth := security.NewJWTAuth("grpc-demo-secret")
+	users := NewUserService()
+	server, addr, err := startDemoServer(auth, users)
+	if err != nil {
+		fmt.Printf("Error starting server: %v\n", err)
+		return
+	}
+	fmt.Printf("\n🚀 gRPC server listening on %s\n", addr)
+
+	UnaryExamples(addr, auth)
+	RetryAndDeadlineExamples(addr, users)
+	StreamingExamples(addr, auth)
+
+	server.GracefulStop()
+
+	fmt.Println("\n✅ All gRPC examples completed!")
+}
@@ -0,0 +1,154 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/security"
+)
+
+var logger = format.GetLogger("grpc")
+
+// LoggingUnaryInterceptor logs every unary call with its status and latency
+func LoggingUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Info("unary call", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start).Round(time.Microsecond))
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor logs every stream when it ends
+func LoggingStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logger.Info("stream call", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start).Round(time.Microsecond))
+		return err
+	}
+}
+
+// RecoveryUnaryInterceptor turns a panicking handler into an Internal error
+// instead of crashing the server
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("panic in handler", "method", info.FullMethod, "panic", r)
+				logger.Debug("panic stack", "stack", string(debug.Stack()))
+				resp, err = nil, status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor is RecoveryUnaryInterceptor for streams
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("panic in stream handler", "method", info.FullMethod, "panic", r)
+				logger.Debug("panic stack", "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the JWT claims of an authenticated call
+func ClaimsFromContext(ctx context.Context) (*security.JWTClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*security.JWTClaims)
+	return claims, ok
+}
+
+// authenticate validates the bearer token in the call metadata
+func authenticate(ctx context.Context, auth *security.JWTAuth) (context.Context, error) {
+	var header string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		header = values[0]
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
+
+func isPublic(method string, public []string) bool {
+	for _, p := range public {
+		if method == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(method, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthUnaryInterceptor requires a valid JWT from security.JWTAuth on every
+// call except the public methods (a trailing "/" matches a whole service)
+func AuthUnaryInterceptor(auth *security.JWTAuth, public ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isPublic(info.FullMethod, public) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is AuthUnaryInterceptor for streams
+func AuthStreamInterceptor(auth *security.JWTAuth, public ...string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod, public) {
+			return handler(srv, stream)
+		}
+		ctx, err := authenticate(stream.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// RequireRole returns an error unless the authenticated caller has role
+func RequireRole(ctx context.Context, role string) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "not authenticated")
+	}
+	for _, r := range claims.Roles {
+		if r == role {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "role %q required", role)
+}
+
+// contextStream overrides the context of a wrapped stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,145 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: grpc/pb/chat.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ChatMessage is sent in both directions on the Chat stream
+type ChatMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	SentAt        int64                  `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatMessage) Reset() {
+	*x = ChatMessage{}
+	mi := &file_grpc_pb_chat_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatMessage) ProtoMessage() {}
+
+func (x *ChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_pb_chat_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
+func (*ChatMessage) Descriptor() ([]byte, []int) {
+	return file_grpc_pb_chat_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ChatMessage) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetSentAt() int64 {
+	if x != nil {
+		return x.SentAt
+	}
+	return 0
+}
+
+var File_grpc_pb_chat_proto protoreflect.FileDescriptor
+
+const file_grpc_pb_chat_proto_rawDesc = "" +
+	"\n" +
+	"\x12grpc/pb/chat.proto\x12\x10practice.chat.v1\"N\n" +
+	"\vChatMessage\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x17\n" +
+	"\asent_at\x18\x03 \x01(\x03R\x06sentAt2W\n" +
+	"\vChatService\x12H\n" +
+	"\x04Chat\x12\x1d.practice.chat.v1.ChatMessage\x1a\x1d.practice.chat.v1.ChatMessage(\x010\x01B*Z(github.com/jerrychou/go-practice/grpc/pbb\x06proto3"
+
+var (
+	file_grpc_pb_chat_proto_rawDescOnce sync.Once
+	file_grpc_pb_chat_proto_rawDescData []byte
+)
+
+func file_grpc_pb_chat_proto_rawDescGZIP() []byte {
+	file_grpc_pb_chat_proto_rawDescOnce.Do(func() {
+		file_grpc_pb_chat_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_grpc_pb_chat_proto_rawDesc), len(file_grpc_pb_chat_proto_rawDesc)))
+	})
+	return file_grpc_pb_chat_proto_rawDescData
+}
+
+var file_grpc_pb_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_grpc_pb_chat_proto_goTypes = []any{
+	(*ChatMessage)(nil), // 0: practice.chat.v1.ChatMessage
+}
+var file_grpc_pb_chat_proto_depIdxs = []int32{
+	0, // 0: practice.chat.v1.ChatService.Chat:input_type -> practice.chat.v1.ChatMessage
+	0, // 1: practice.chat.v1.ChatService.Chat:output_type -> practice.chat.v1.ChatMessage
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_grpc_pb_chat_proto_init() }
+func file_grpc_pb_chat_proto_init() {
+	if File_grpc_pb_chat_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_grpc_pb_chat_proto_rawDesc), len(file_grpc_pb_chat_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_grpc_pb_chat_proto_goTypes,
+		DependencyIndexes: file_grpc_pb_chat_proto_depIdxs,
+		MessageInfos:      file_grpc_pb_chat_proto_msgTypes,
+	}.Build()
+	File_grpc_pb_chat_proto = out.File
+	file_grpc_pb_chat_proto_goTypes = nil
+	file_grpc_pb_chat_proto_depIdxs = nil
+}
@@ -0,0 +1,18 @@
+// Package pb holds the Go types and gRPC stubs protoc generates from the
+// demo service definitions in users.proto and chat.proto.
+package pb
+
+import "embed"
+
+// Protos holds the .proto sources, for printing the service definitions
+//
+//go:embed *.proto
+var Protos embed.FS
+
+// Regenerate with protoc, protoc-gen-go and protoc-gen-go-grpc on the PATH:
+//
+//	go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.10
+//	go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.5.1
+//	go generate ./grpc/pb
+//
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative grpc/pb/users.proto grpc/pb/chat.proto
@@ -0,0 +1,219 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jerrychou/go-practice/grpc/pb"
+)
+
+// Demo services: the messages and stubs are generated by protoc from the
+// .proto files in pb, and ServiceProto prints them.
+
+// ServiceProto returns the .proto definitions of the demo services
+func ServiceProto() string {
+	var sb strings.Builder
+	for _, name := range []string{"users.proto", "chat.proto"} {
+		data, err := fs.ReadFile(pb.Protos, name)
+		if err != nil {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "// %s\n%s", name, data)
+	}
+	return sb.String()
+}
+
+// UserService is an in-memory implementation of the users service
+type UserService struct {
+	pb.UnimplementedUserServiceServer
+
+	mu     sync.Mutex
+	users  map[int64]*pb.User
+	nextID int64
+	// failNext is the number of upcoming GetUser calls that will fail
+	failNext int
+}
+
+// NewUserService creates a service with a couple of seed users
+func NewUserService() *UserService {
+	s := &UserService{users: make(map[int64]*pb.User), nextID: 1}
+	s.add("Alice", "alice@example.com", []string{"admin"})
+	s.add("Bob", "bob@example.com", []string{"user"})
+	return s
+}
+
+func (s *UserService) add(name, email string, roles []string) *pb.User {
+	user := &pb.User{Id: s.nextID, Name: name, Email: email, Roles: roles}
+	s.users[user.Id] = user
+	s.nextID++
+	return user
+}
+
+// FailNext makes the next n GetUser calls fail with Unavailable, to simulate
+// a backend that is still starting up
+func (s *UserService) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// GetUser returns a user by ID
+func (s *UserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failNext > 0 {
+		s.failNext--
+		return nil, status.Error(codes.Unavailable, "backend warming up")
+	}
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+	user, ok := s.users[req.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "user %d not found", req.GetId())
+	}
+	return user, nil
+}
+
+// CreateUser adds a user; only admins may call it
+func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	if err := RequireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+	if req.GetName() == "" || !strings.Contains(req.GetEmail(), "@") {
+		return nil, status.Error(codes.InvalidArgument, "name and a valid email are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, req.GetEmail()) {
+			return nil, status.Errorf(codes.AlreadyExists, "email %s already registered", req.GetEmail())
+		}
+	}
+	return s.add(req.GetName(), req.GetEmail(), req.GetRoles()), nil
+}
+
+// GenerateReport simulates slow work that honours the call deadline
+func (s *UserService) GenerateReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResponse, error) {
+	for page := int32(1); page <= req.GetPages(); page++ {
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &pb.ReportResponse{Summary: fmt.Sprintf("%d users across %d pages", len(s.users), req.GetPages())}, nil
+}
+
+// ChatRoom broadcasts every message received on any Chat stream to all
+// connected streams
+type ChatRoom struct {
+	pb.UnimplementedChatServiceServer
+
+	mu      sync.Mutex
+	members map[*chatMember]struct{}
+}
+
+// chatMember is one connected stream. grpc-go streams must not be sent on
+// from several goroutines at once, nor after their handler returns, and
+// every member's handler broadcasts.
+type chatMember struct {
+	name   string
+	mu     sync.Mutex
+	stream pb.ChatService_ChatServer
+	left   bool
+}
+
+func (m *chatMember) send(msg *pb.ChatMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.left {
+		return io.ErrClosedPipe
+	}
+	return m.stream.Send(msg)
+}
+
+func (m *chatMember) leave() {
+	m.mu.Lock()
+	m.left = true
+	m.mu.Unlock()
+}
+
+// NewChatRoom creates an empty room
+func NewChatRoom() *ChatRoom {
+	return &ChatRoom{members: make(map[*chatMember]struct{})}
+}
+
+// Members returns the names of connected users
+func (room *ChatRoom) Members() []string {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	names := make([]string, 0, len(room.members))
+	for m := range room.members {
+		names = append(names, m.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (room *ChatRoom) broadcast(msg *pb.ChatMessage) {
+	room.mu.Lock()
+	members := make([]*chatMember, 0, len(room.members))
+	for m := range room.members {
+		members = append(members, m)
+	}
+	room.mu.Unlock()
+
+	for _, m := range members {
+		m.send(msg)
+	}
+}
+
+// Chat is the bidirectional streaming handler. The sender name comes from
+// the caller's JWT, not from the message, so it cannot be spoofed.
+func (room *ChatRoom) Chat(stream pb.ChatService_ChatServer) error {
+	name := "anonymous"
+	if claims, ok := ClaimsFromContext(stream.Context()); ok {
+		name = claims.Username
+	}
+	member := &chatMember{name: name, stream: stream}
+
+	room.mu.Lock()
+	room.members[member] = struct{}{}
+	room.mu.Unlock()
+	defer func() {
+		room.mu.Lock()
+		delete(room.members, member)
+		room.mu.Unlock()
+		member.leave()
+		room.broadcast(&pb.ChatMessage{From: "room", Text: name + " left", SentAt: time.Now().Unix()})
+	}()
+	room.broadcast(&pb.ChatMessage{From: "room", Text: name + " joined", SentAt: time.Now().Unix()})
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		msg.From, msg.SentAt = name, time.Now().Unix()
+		room.broadcast(msg)
+	}
+}
@@ -0,0 +1,266 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors an APIError matches with errors.Is according to its status, so
+// callers can branch on the kind of failure rather than on status codes.
+// A 429 matches ErrRateLimited.
+var (
+	ErrBadRequest   = errors.New("http: bad request")  // 400, 422
+	ErrUnauthorized = errors.New("http: unauthorized") // 401
+	ErrForbidden    = errors.New("http: forbidden")    // 403
+	ErrNotFound     = errors.New("http: not found")    // 404, 410
+	ErrConflict     = errors.New("http: conflict")     // 409, 412
+	ErrServer       = errors.New("http: server error") // 5xx
+	ErrUnavailable  = errors.New("http: unavailable")  // 502, 503, 504
+)
+
+// APIError describes a response with a non-2xx status: the request it
+// answered, the status, and what could be read from the error body
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Message and Code are extracted from a JSON error body using
+	// ErrorFields; Message falls back to the start of the body otherwise
+	Message string
+	Code    string
+	// Body is the start of the response body, up to 4KB
+	Body      []byte
+	RequestID string
+	// Retryable reports whether the same request may succeed later, as for
+	// 429 and 503, after RetryAfter if the server said how long to wait
+	Retryable  bool
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is maps the status to the Err* kinds above
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	case ErrUnavailable:
+		return isGatewayFailure(e.StatusCode)
+	}
+	return false
+}
+
+// NotFound reports whether err is an APIError with status 404 or 410
+func NotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsRetryable reports whether err is worth retrying: a retryable APIError,
+// or any error that did not come from a response at all
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+	return err != nil
+}
+
+// ErrorFields lists dotted paths into a JSON error body to take the message
+// and code from, such as "error.message" or "errors.0.code"; the first path
+// holding a string or number wins
+type ErrorFields struct {
+	Message []string
+	Code    []string
+}
+
+// DefaultErrorFields covers the common error body layouts: flat, nested
+// under "error", JSON:API and RFC 7807 problem details
+var DefaultErrorFields = ErrorFields{
+	Message: []string{"message", "error.message", "error", "errors.0.message", "errors.0.detail", "detail", "title"},
+	Code:    []string{"code", "error.code", "error_code", "errors.0.code", "type"},
+}
+
+// newAPIError reads up to 4KB of resp's body into an APIError using
+// DefaultErrorFields
+func newAPIError(resp *http.Response) *APIError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return parseAPIError(resp.Request.Method, resp.Request.URL.String(), resp.StatusCode, resp.Header, data, DefaultErrorFields)
+}
+
+func parseAPIError(method, url string, status int, header http.Header, body []byte, fields ErrorFields) *APIError {
+	if len(body) > 4096 {
+		body = body[:4096]
+	}
+	apiErr := &APIError{
+		Method:     method,
+		URL:        url,
+		StatusCode: status,
+		Body:       body,
+		RequestID:  firstHeader(header, "X-Request-Id", "X-Correlation-Id", "X-Amzn-Requestid"),
+	}
+
+	var doc interface{}
+	if json.Unmarshal(body, &doc) == nil {
+		apiErr.Message = lookupScalar(doc, fields.Message)
+		apiErr.Code = lookupScalar(doc, fields.Code)
+	}
+	if apiErr.Message == "" && apiErr.Code == "" {
+		apiErr.Message = strings.TrimSpace(string(body[:min(len(body), 200)]))
+	}
+
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		apiErr.Retryable = true
+	}
+	apiErr.RetryAfter = parseRetryAfter(header.Get("Retry-After"))
+	return apiErr
+}
+
+// lookupScalar returns the first of paths that leads to a string or number
+func lookupScalar(doc interface{}, paths []string) string {
+	for _, path := range paths {
+		v := doc
+		for _, key := range strings.Split(path, ".") {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				v = node[key]
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) {
+					v = nil
+				} else {
+					v = node[i]
+				}
+			default:
+				v = nil
+			}
+		}
+		switch s := v.(type) {
+		case string:
+			if s != "" {
+				return s
+			}
+		case float64:
+			return strconv.FormatFloat(s, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// parseRetryAfter reads Retry-After as seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if v := header.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ExampleAPIErrors makes strict requests to a server answering with the
+// error body layouts of a few real APIs and inspects the errors
+func ExampleAPIErrors() {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-"+strings.TrimPrefix(r.URL.Path, "/"))
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`)
+		case "/invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"error": {"code": "invalid_email", "message": "email is not valid"}}`)
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"type": "https://example.com/probs/version", "title": "Version conflict", "detail": "resource changed since revision 7"}`)
+		case "/busy":
+			if attempts++; attempts < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `{"errors": [{"code": 503, "message": "try again shortly"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"status": "ok"}`)
+		}
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/missing", "/invalid", "/problem"} {
+		_, err := MakeRequest(RequestOptions{Method: "GET", URL: srv.URL + path, Strict: true})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			fmt.Printf("❌ %s: expected an APIError, got %v\n", path, err)
+			continue
+		}
+		fmt.Printf("🚫 %s: %d code=%q message=%q request=%s retryable=%t\n",
+			path, apiErr.StatusCode, apiErr.Code, apiErr.Message, apiErr.RequestID, apiErr.Retryable)
+		fmt.Printf("   not found: %t, bad request: %t, conflict: %t\n",
+			errors.Is(err, ErrNotFound), errors.Is(err, ErrBadRequest), errors.Is(err, ErrConflict))
+	}
+
+	// Without Strict the response comes back with no error, as before
+	resp, err := MakeRequest(RequestOptions{Method: "GET", URL: srv.URL + "/missing"})
+	if err == nil {
+		fmt.Printf("📭 Non-strict request: status %d, err %v\n", resp.StatusCode, err)
+	}
+
+	// A custom layout: the message lives under "fault.reason"
+	fields := ErrorFields{Message: []string{"fault.reason"}, Code: []string{"fault.id"}}
+	apiErr := parseAPIError("GET", "/legacy", http.StatusBadRequest, http.Header{},
+		[]byte(`{"fault": {"id": 42, "reason": "quota exceeded"}}`), fields)
+	fmt.Printf("🧭 Custom paths: %v\n", apiErr)
+
+	resp, err = RetryRequest(RequestOptions{Method: "GET", URL: srv.URL + "/busy", Strict: true}, 3, 10*time.Millisecond)
+	if err != nil {
+		fmt.Printf("❌ Retry failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ /busy succeeded after %d attempts: %s\n", attempts, resp.Body)
+	}
+
+	_, err = RetryRequest(RequestOptions{Method: "GET", URL: srv.URL + "/invalid", Strict: true}, 3, 10*time.Millisecond)
+	fmt.Printf("⏭️  /invalid was not retried: %v\n", err)
+}
@@ -0,0 +1,335 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BalancingDialer spreads connections to a host across all of its A and
+// AAAA records in turn. An address that fails is skipped for Cooldown,
+// and the dial moves on to the next one.
+type BalancingDialer struct {
+	// Cooldown is how long a failing address is skipped, 30s if zero
+	Cooldown time.Duration
+	// LookupHost resolves a host to addresses, net.DefaultResolver if nil
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+	Dialer     net.Dialer
+
+	mu        sync.Mutex
+	next      map[string]int       // per host, where the rotation starts
+	unhealthy map[string]time.Time // address → skipped until
+
+	transportOnce sync.Once
+	transport     *FailoverTransport
+}
+
+// NewBalancingDialer creates a dialer with a 5s connect timeout per address
+func NewBalancingDialer(cooldown time.Duration) *BalancingDialer {
+	return &BalancingDialer{Cooldown: cooldown, Dialer: net.Dialer{Timeout: 5 * time.Second}}
+}
+
+// DefaultBalancer is shared by the demos so endpoint health carries over
+// between requests
+var DefaultBalancer = NewBalancingDialer(30 * time.Second)
+
+// DialContext dials the healthy addresses of addr's host in rotation and
+// returns the first connection made. When every address is cooling down it
+// tries them all anyway, as one may have recovered.
+func (d *BalancingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []string
+	if net.ParseIP(host) != nil {
+		ips = []string{host}
+	} else {
+		lookup := d.LookupHost
+		if lookup == nil {
+			lookup = net.DefaultResolver.LookupHost
+		}
+		if ips, err = lookup(ctx, host); err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses for %s", host)
+		}
+	}
+
+	var errs []error
+	for _, ip := range d.order(host, port, ips) {
+		endpoint := net.JoinHostPort(ip, port)
+		conn, err := d.Dialer.DialContext(ctx, network, endpoint)
+		if err == nil {
+			d.markHealthy(endpoint)
+			return &endpointConn{Conn: conn, dialer: d, endpoint: endpoint}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		d.markUnhealthy(endpoint)
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("all %d addresses of %s failed: %w", len(errs), host, errors.Join(errs...))
+}
+
+// order rotates ips to start at the host's next position and moves the
+// ones cooling down to the back
+func (d *BalancingDialer) order(host, port string, ips []string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.next == nil {
+		d.next = make(map[string]int)
+	}
+	start := d.next[host] % len(ips)
+	d.next[host]++
+
+	now := time.Now()
+	var healthy, cooling []string
+	for i := range ips {
+		ip := ips[(start+i)%len(ips)]
+		if until, ok := d.unhealthy[net.JoinHostPort(ip, port)]; ok && now.Before(until) {
+			cooling = append(cooling, ip)
+		} else {
+			healthy = append(healthy, ip)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+func (d *BalancingDialer) markUnhealthy(endpoint string) {
+	cooldown := d.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.unhealthy == nil {
+		d.unhealthy = make(map[string]time.Time)
+	}
+	d.unhealthy[endpoint] = time.Now().Add(cooldown)
+}
+
+func (d *BalancingDialer) markHealthy(endpoint string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.unhealthy, endpoint)
+}
+
+// Unhealthy lists the addresses currently being skipped
+func (d *BalancingDialer) Unhealthy() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	var endpoints []string
+	for endpoint, until := range d.unhealthy {
+		if now.Before(until) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// Transport returns a FailoverTransport dialing through d, shared by every
+// caller so connections are pooled
+func (d *BalancingDialer) Transport() *FailoverTransport {
+	d.transportOnce.Do(func() {
+		d.transport = &FailoverTransport{Dialer: d}
+	})
+	return d.transport
+}
+
+// endpointConn marks its address unhealthy when the connection breaks
+type endpointConn struct {
+	net.Conn
+	dialer   *BalancingDialer
+	endpoint string
+}
+
+func (c *endpointConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.check(err)
+	return n, err
+}
+
+func (c *endpointConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.check(err)
+	return n, err
+}
+
+func (c *endpointConn) check(err error) {
+	var netErr net.Error
+	if err == nil || err == io.EOF || errors.Is(err, net.ErrClosed) || errors.As(err, &netErr) && netErr.Timeout() {
+		return // normal ends of a keep-alive connection
+	}
+	c.dialer.markUnhealthy(c.endpoint)
+}
+
+// FailoverTransport sends requests over connections from a BalancingDialer
+// and retries idempotent requests that fail, or get a 502, 503 or 504, on
+// the host's next address
+type FailoverTransport struct {
+	Dialer *BalancingDialer
+	// Attempts is how many times a request is tried, 3 if zero
+	Attempts int
+
+	once sync.Once
+	base *http.Transport
+}
+
+func (t *FailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		t.base = http.DefaultTransport.(*http.Transport).Clone()
+		t.base.DialContext = t.Dialer.DialContext
+	})
+
+	attempts := t.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	if !isIdempotent(req) {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		var endpoint string
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+			if conn, ok := info.Conn.(*endpointConn); ok {
+				endpoint = conn.endpoint
+			}
+		}}
+		try := req.Clone(httptrace.WithClientTrace(req.Context(), trace))
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(try)
+		if err == nil && isGatewayFailure(resp.StatusCode) && endpoint != "" {
+			t.Dialer.markUnhealthy(endpoint)
+		}
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if attempt >= attempts || !replayable || req.Context().Err() != nil ||
+			err == nil && !isGatewayFailure(resp.StatusCode) {
+			return resp, err
+		}
+
+		if err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+			err = fmt.Errorf("%s answered %s", endpoint, resp.Status)
+		}
+		logger.Debug("Retrying on next endpoint", "url", req.URL.Redacted(), "attempt", attempt+1, "error", err)
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch strings.ToUpper(req.Method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func isGatewayFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// ExampleFailover balances requests for one host name across three local
+// addresses, one of which refuses connections and one of which starts
+// failing
+func ExampleFailover() {
+	healthy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("❌ Cannot listen on loopback: %v\n", err)
+		return
+	}
+	port := portOf(healthy)
+	// Linux routes all of 127.0.0.0/8 to loopback; elsewhere 127.0.0.2 may
+	// not exist and is then just another dead address
+	flaky, err := net.Listen("tcp", "127.0.0.2:"+port)
+	if err != nil {
+		flaky = nil
+	}
+
+	var mu sync.Mutex
+	failing := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local := r.Context().Value(http.LocalAddrContextKey).(net.Addr).String()
+		mu.Lock()
+		fail := failing && strings.HasPrefix(local, "127.0.0.2:")
+		mu.Unlock()
+		if fail {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "served by %s", local)
+	})
+	for _, l := range []net.Listener{healthy, flaky} {
+		if l != nil {
+			srv := &http.Server{Handler: handler}
+			go srv.Serve(l)
+			defer srv.Close()
+		}
+	}
+
+	balancer := NewBalancingDialer(time.Minute)
+	// 127.0.0.3 has nothing listening, so it refuses connections
+	balancer.LookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}, nil
+	}
+	url := "http://api.demo.internal:" + port + "/"
+
+	call := func(round string) {
+		var requests []RequestOptions
+		for i := 0; i < 4; i++ {
+			requests = append(requests, RequestOptions{Method: "GET", URL: url, Balancer: balancer,
+				Headers: map[string]string{"Connection": "close"}}) // a new connection, and address, each time
+		}
+		responses, err := BatchRequest(requests)
+		for i, resp := range responses {
+			if resp != nil {
+				fmt.Printf("  %s #%d: %d %s\n", round, i+1, resp.StatusCode, resp.Body)
+			}
+		}
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+		}
+		fmt.Printf("  🩺 unhealthy: %v\n", balancer.Unhealthy())
+	}
+
+	fmt.Println("🌐 All addresses of api.demo.internal in rotation:")
+	call("round 1")
+
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	fmt.Println("🌐 127.0.0.2 starts answering 503:")
+	call("round 2")
+
+	resp, err := RetryRequest(RequestOptions{Method: "GET", URL: url, Balancer: balancer}, 2, 100*time.Millisecond)
+	if err != nil {
+		fmt.Printf("❌ Retry failed: %v\n", err)
+	} else {
+		fmt.Printf("✅ Retry succeeded: %d %s\n", resp.StatusCode, resp.Body)
+	}
+}
+
+func portOf(l net.Listener) string {
+	return fmt.Sprint(l.Addr().(*net.TCPAddr).Port)
+}
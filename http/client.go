@@ -2,37 +2,64 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/metrics"
+	"github.com/jerrychou/go-practice/tracing"
 )
 
 type HTTPClient struct {
-	client  *http.Client
-	baseURL string
-	headers map[string]string
+	client   *http.Client
+	baseURL  string
+	headers  map[string]string
+	codec    encoding.Codec
+	cache    *cache.Typed[cachedResponse]
+	cacheTTL time.Duration
+
+	transport    http.RoundTripper // before interceptors
+	interceptors []Interceptor
+	compression  *CompressionTransport
+}
+
+// Interceptor wraps the client's transport to see or change every request
+// and response, such as to sign requests
+type Interceptor func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// cachedResponse is what GetJSON stores: enough to decode the body again
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
 }
 
 func NewHTTPClient(baseURL string) *HTTPClient {
-	return &HTTPClient{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL: baseURL,
-		headers: make(map[string]string),
-	}
+	return NewHTTPClientWithTimeout(baseURL, 30*time.Second)
 }
 
 func NewHTTPClientWithTimeout(baseURL string, timeout time.Duration) *HTTPClient {
+	compression := &CompressionTransport{}
 	return &HTTPClient{
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: metrics.RoundTripper(tracing.Transport(compression)),
 		},
-		baseURL: baseURL,
-		headers: make(map[string]string),
+		baseURL:     baseURL,
+		headers:     make(map[string]string),
+		codec:       encoding.JSON,
+		compression: compression,
 	}
 }
 
@@ -40,6 +67,56 @@ func (c *HTTPClient) SetHeader(key, value string) {
 	c.headers[key] = value
 }
 
+// SetCodec changes how Post and Put encode bodies (JSON by default) and which
+// format is requested in the Accept header
+func (c *HTTPClient) SetCodec(codec encoding.Codec) {
+	c.codec = codec
+}
+
+// SetCache makes GetJSON serve successful responses from store for ttl.
+// Concurrent GetJSON calls for the same URL share one request. Entries are
+// keyed by URL, so clients sending different credentials should not share
+// a store.
+func (c *HTTPClient) SetCache(store cache.Cache, ttl time.Duration) {
+	c.cache = cache.NewTyped[cachedResponse](store, nil)
+	c.cacheTTL = ttl
+}
+
+// SetValidators keeps the ETag and Last-Modified of GET responses in
+// store and revalidates them on repeat requests, so an unchanged resource
+// comes back as a 304 without its body; see ConditionalRequests. Unlike
+// SetCache, every request still reaches the server.
+func (c *HTTPClient) SetValidators(store cache.Cache) {
+	c.Use(ConditionalRequests(store))
+}
+
+// SetCompression changes how the client negotiates compression. Responses
+// are decompressed by default and request bodies are sent as they are.
+func (c *HTTPClient) SetCompression(opts CompressionOptions) {
+	c.compression.CompressionOptions = opts
+}
+
+// SetBalancer sends the client's requests through balancer, spreading them
+// across all addresses of the host and failing over between them
+func (c *HTTPClient) SetBalancer(balancer *BalancingDialer) {
+	c.compression.Base = balancer.Transport()
+}
+
+// Use adds interceptors around the transport. Interceptors see a request in
+// the order they were added, and the tracing and metrics transport sees it
+// last.
+func (c *HTTPClient) Use(interceptors ...Interceptor) {
+	if c.transport == nil {
+		c.transport = c.client.Transport
+	}
+	c.interceptors = append(c.interceptors, interceptors...)
+	transport := c.transport
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		transport = c.interceptors[i](transport)
+	}
+	c.client.Transport = transport
+}
+
 func (c *HTTPClient) SetHeaders(headers map[string]string) {
 	for k, v := range headers {
 		c.headers[k] = v
@@ -47,25 +124,46 @@ func (c *HTTPClient) SetHeaders(headers map[string]string) {
 }
 
 func (c *HTTPClient) Get(path string) (*http.Response, error) {
-	return c.request("GET", path, nil)
+	return c.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext is Get bounded by ctx; the trace in ctx, if any, is sent
+// to the server in a traceparent header
+func (c *HTTPClient) GetWithContext(ctx context.Context, path string) (*http.Response, error) {
+	return c.request(ctx, "GET", path, nil)
 }
 
 func (c *HTTPClient) Post(path string, body any) (*http.Response, error) {
-	return c.requestWithJSON("POST", path, body)
+	return c.PostWithContext(context.Background(), path, body)
+}
+
+// PostWithContext is Post bounded by ctx and joined to its trace
+func (c *HTTPClient) PostWithContext(ctx context.Context, path string, body any) (*http.Response, error) {
+	return c.requestWithBody(ctx, "POST", path, body)
 }
 
 func (c *HTTPClient) Put(path string, body any) (*http.Response, error) {
-	return c.requestWithJSON("PUT", path, body)
+	return c.requestWithBody(context.Background(), "PUT", path, body)
 }
 
 func (c *HTTPClient) Delete(path string) (*http.Response, error) {
-	return c.request("DELETE", path, nil)
+	return c.request(context.Background(), "DELETE", path, nil)
 }
 
-func (c *HTTPClient) request(method, path string, body io.Reader) (*http.Response, error) {
+func (c *HTTPClient) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Do(req)
+}
+
+// newRequest builds a request with the client's headers, for callers that
+// add their own before sending it with c.client
+func (c *HTTPClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
 	url := c.buildURL(path)
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -75,26 +173,42 @@ func (c *HTTPClient) request(method, path string, body io.Reader) (*http.Respons
 		req.Header.Set(key, value)
 	}
 
-	// Set default Content-Type if not set
+	// Set default Content-Type and Accept if not set
 	if req.Header.Get("Content-Type") == "" && body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Type", c.codec.ContentType())
 	}
-
-	return c.client.Do(req)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", c.codec.ContentType())
+	}
+	return req, nil
 }
 
-func (c *HTTPClient) requestWithJSON(method, path string, body any) (*http.Response, error) {
-	var jsonBody []byte
+func (c *HTTPClient) requestWithBody(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var encoded []byte
 	var err error
 
 	if body != nil {
-		jsonBody, err = json.Marshal(body)
+		encoded, err = c.codec.Marshal(body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+			return nil, fmt.Errorf("failed to marshal %s: %w", c.codec.Name(), err)
 		}
 	}
 
-	return c.request(method, path, bytes.NewBuffer(jsonBody))
+	return c.request(ctx, method, path, bytes.NewBuffer(encoded))
+}
+
+// Decode unmarshals a response body with the codec matching its
+// Content-Type, falling back to the client's codec
+func (c *HTTPClient) Decode(resp *http.Response, target any) error {
+	codec, ok := encoding.ForContentType(resp.Header.Get("Content-Type"))
+	if !ok {
+		codec = c.codec
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, target)
 }
 
 func (c *HTTPClient) buildURL(path string) string {
@@ -109,7 +223,16 @@ func (c *HTTPClient) buildURL(path string) string {
 }
 
 func (c *HTTPClient) GetJSON(path string, target any) error {
-	resp, err := c.Get(path)
+	return c.GetJSONWithContext(context.Background(), path, target)
+}
+
+// GetJSONWithContext is GetJSON bounded by ctx and joined to its trace
+func (c *HTTPClient) GetJSONWithContext(ctx context.Context, path string, target any) error {
+	if c.cache != nil {
+		return c.getJSONCached(ctx, path, target)
+	}
+
+	resp, err := c.GetWithContext(ctx, path)
 	if err != nil {
 		return fmt.Errorf("GET request failed: %w", err)
 	}
@@ -119,7 +242,33 @@ func (c *HTTPClient) GetJSON(path string, target any) error {
 		return fmt.Errorf("request failed with status: %d", resp.StatusCode)
 	}
 
-	return json.NewDecoder(resp.Body).Decode(target)
+	return c.Decode(resp, target)
+}
+
+func (c *HTTPClient) getJSONCached(ctx context.Context, path string, target any) error {
+	key := "http:GET:" + c.buildURL(path) + ":" + c.codec.ContentType()
+	cached, err := c.cache.GetOrLoad(ctx, key, c.cacheTTL, func(ctx context.Context) (cachedResponse, error) {
+		resp, err := c.GetWithContext(ctx, path)
+		if err != nil {
+			return cachedResponse{}, fmt.Errorf("GET request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return cachedResponse{}, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		return cachedResponse{ContentType: resp.Header.Get("Content-Type"), Body: body}, err
+	})
+	if err != nil {
+		return err
+	}
+
+	codec, ok := encoding.ForContentType(cached.ContentType)
+	if !ok {
+		codec = c.codec
+	}
+	return codec.Unmarshal(cached.Body, target)
 }
 
 func (c *HTTPClient) PostJSON(path string, body, target any) error {
@@ -134,7 +283,7 @@ func (c *HTTPClient) PostJSON(path string, body, target any) error {
 	}
 
 	if target != nil {
-		return json.NewDecoder(resp.Body).Decode(target)
+		return c.Decode(resp, target)
 	}
 	return nil
 }
@@ -271,6 +420,6 @@ func ExampleAdvancedClient() {
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Parsed JSON: %+v\n", result)
+		fmt.Printf("Parsed JSON: %s\n", format.Dump(result, format.DefaultDumpOptions))
 	}
 }
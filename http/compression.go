@@ -0,0 +1,298 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// ContentDecoder wraps a compressed response body in a reader that
+// decompresses it
+type ContentDecoder func(body io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecodersMu sync.RWMutex
+	contentDecoders   = map[string]ContentDecoder{
+		"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		"deflate": newDeflateReader,
+	}
+	// contentEncodingOrder lists encodings by preference for Accept-Encoding
+	contentEncodingOrder = []string{"gzip", "deflate"}
+)
+
+// RegisterContentDecoder adds support for a Content-Encoding, which is then
+// advertised in Accept-Encoding ahead of gzip. The standard library has no
+// brotli decoder; to accept "br" register one from a brotli package:
+//
+//	RegisterContentDecoder("br", func(r io.Reader) (io.ReadCloser, error) {
+//		return io.NopCloser(brotli.NewReader(r)), nil
+//	})
+func RegisterContentDecoder(encoding string, decoder ContentDecoder) {
+	encoding = strings.ToLower(encoding)
+	contentDecodersMu.Lock()
+	defer contentDecodersMu.Unlock()
+	if _, ok := contentDecoders[encoding]; !ok {
+		contentEncodingOrder = append([]string{encoding}, contentEncodingOrder...)
+	}
+	contentDecoders[encoding] = decoder
+}
+
+// AcceptEncoding returns the Accept-Encoding header value for every
+// registered decoder, e.g. "gzip, deflate"
+func AcceptEncoding() string {
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+	return strings.Join(contentEncodingOrder, ", ")
+}
+
+func contentDecoder(encoding string) (ContentDecoder, bool) {
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+	decoder, ok := contentDecoders[strings.ToLower(strings.TrimSpace(encoding))]
+	return decoder, ok
+}
+
+// newDeflateReader reads "deflate" bodies, which should be zlib-wrapped but
+// are raw DEFLATE from some servers
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// CompressionOptions configures CompressionTransport
+type CompressionOptions struct {
+	// DisableDecompression asks servers for uncompressed responses and
+	// leaves any Content-Encoding they still use to the caller
+	DisableDecompression bool
+	// CompressRequestsOver gzips request bodies of at least this many bytes
+	// when the server is known to accept them; 0 never compresses
+	CompressRequestsOver int
+}
+
+// CompressionTransport negotiates compressed responses with Accept-Encoding
+// and decompresses them, whatever their encoding, before the caller reads
+// the body. It can also gzip large request bodies. Sits beneath signing
+// interceptors, so a signature covers the uncompressed body.
+type CompressionTransport struct {
+	Base http.RoundTripper // http.DefaultTransport if nil
+	CompressionOptions
+}
+
+func (t *CompressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// A RoundTripper must not change the caller's request
+	out := req.Clone(req.Context())
+	if out.Header.Get("Accept-Encoding") == "" {
+		if t.DisableDecompression {
+			// keeps http.Transport from asking for gzip on its own
+			out.Header.Set("Accept-Encoding", "identity")
+		} else {
+			out.Header.Set("Accept-Encoding", AcceptEncoding())
+		}
+	}
+	if err := t.compressBody(out); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := base.RoundTrip(out)
+	if err != nil || t.DisableDecompression {
+		return resp, err
+	}
+	return decompressResponse(resp)
+}
+
+// compressBody gzips the body of req in place if it is large enough
+func (t *CompressionTransport) compressBody(req *http.Request) error {
+	if t.CompressRequestsOver <= 0 || req.Body == nil || req.Body == http.NoBody ||
+		req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	if req.ContentLength >= 0 && req.ContentLength < int64(t.CompressRequestsOver) {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading request body: %w", err)
+	}
+	if len(body) < t.CompressRequestsOver {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		return nil
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compressing request body: %w", err)
+	}
+	data := compressed.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// decompressResponse replaces a compressed body with its decoded content.
+// Encodings without a decoder are left for the caller.
+func decompressResponse(resp *http.Response) (*http.Response, error) {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, nil
+	}
+	decoder, ok := contentDecoder(encoding)
+	if !ok {
+		return resp, nil
+	}
+
+	decoded, err := decoder(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("decoding %s response: %w", encoding, err)
+	}
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decodedBody closes both the decoder and the connection's body
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
+// CompressResponses gzips or deflates responses for clients that accept it,
+// and decompresses gzip request bodies, for the demo server
+func CompressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			r.Body = zr
+			r.Header.Del("Content-Encoding")
+			r.ContentLength = -1
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		var zw io.WriteCloser
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case strings.Contains(accept, "gzip"):
+			zw = gzip.NewWriter(w)
+			w.Header().Set("Content-Encoding", "gzip")
+		case strings.Contains(accept, "deflate"):
+			zw, _ = zlib.NewWriterLevel(w, zlib.DefaultCompression)
+			w.Header().Set("Content-Encoding", "deflate")
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer zw.Close()
+		w.Header().Del("Content-Length")
+		next.ServeHTTP(&compressedWriter{ResponseWriter: w, w: zw}, r)
+	})
+}
+
+type compressedWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (c *compressedWriter) Write(b []byte) (int, error) { return c.w.Write(b) }
+
+// ExampleCompression fetches compressed responses with MakeRequest and the
+// advanced client, turns decompression off, and gzips a large upload
+func ExampleCompression() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "received %d bytes; %s", len(body), strings.Repeat("compressible ", 200))
+	})
+	var lastRequest struct {
+		sync.Mutex
+		acceptEncoding, contentEncoding string
+		wireBytes                       int64
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest.Lock()
+		lastRequest.acceptEncoding = r.Header.Get("Accept-Encoding")
+		lastRequest.contentEncoding = r.Header.Get("Content-Encoding")
+		lastRequest.wireBytes = r.ContentLength
+		lastRequest.Unlock()
+		CompressResponses(handler).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	report := func(label string, resp *ResponseData, err error) {
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", label, err)
+			return
+		}
+		lastRequest.Lock()
+		defer lastRequest.Unlock()
+		fmt.Printf("📦 %s: asked for %q, got %d bytes with Content-Encoding %q\n",
+			label, lastRequest.acceptEncoding, len(resp.Body), http.Header(resp.Headers).Get("Content-Encoding"))
+	}
+
+	resp, err := MakeRequest(RequestOptions{Method: "GET", URL: srv.URL})
+	report("MakeRequest", resp, err)
+
+	resp, err = MakeRequest(RequestOptions{Method: "GET", URL: srv.URL, Headers: map[string]string{"Accept-Encoding": "deflate"}})
+	report("MakeRequest, deflate only", resp, err)
+
+	resp, err = MakeRequest(RequestOptions{Method: "GET", URL: srv.URL, DisableCompression: true})
+	report("MakeRequest, decompression off", resp, err)
+
+	upload := strings.Repeat(`{"sku":"A-1","quantity":1},`, 400)
+	resp, err = MakeRequest(RequestOptions{Method: "POST", URL: srv.URL, Body: upload, CompressBodyOver: 1024})
+	if err == nil {
+		lastRequest.Lock()
+		fmt.Printf("🗜️  Upload of %d bytes sent as %d bytes of %s: %.40s…\n",
+			len(upload), lastRequest.wireBytes, lastRequest.contentEncoding, resp.Body)
+		lastRequest.Unlock()
+	}
+
+	client := NewHTTPClient(srv.URL)
+	httpResp, err := client.Get("/")
+	if err != nil {
+		fmt.Printf("❌ HTTPClient: %v\n", err)
+		return
+	}
+	body, _ := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	fmt.Printf("📦 HTTPClient: %d bytes, decompressed by the client: %t\n", len(body), httpResp.Uncompressed)
+}
@@ -0,0 +1,339 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+)
+
+// StrongETag returns an ETag for body that changes whenever a byte of it
+// does, e.g. `"3f2a…"`
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns an ETag from a file's size and modification time, e.g.
+// `W/"1a4-17e0…"`. It is weak because a file rewritten with other bytes
+// within the clock's resolution keeps it.
+func WeakETag(size int64, modTime time.Time) string {
+	return `W/"` + strconv.FormatInt(size, 16) + "-" + strconv.FormatInt(modTime.UnixNano(), 16) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using
+// the weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the client's copy, described by r's
+// conditional headers, is still current for a response with header h.
+// If-Modified-Since is only looked at without If-None-Match.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, h.Get("ETag"))
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(h.Get("Last-Modified"))
+	return err == nil && !modified.After(since)
+}
+
+// ConditionalResponses gives successful JSON responses to GET and HEAD a
+// strong ETag of their body, unless the handler set one, and answers
+// requests whose If-None-Match or If-Modified-Since shows the client is up
+// to date with 304 Not Modified. JSON bodies are buffered to hash them;
+// other responses stream through and only get a 304 if the handler set a
+// validator. Put it outside CompressResponses: the ETag is of the body the
+// handler wrote.
+func ConditionalResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &conditionalWriter{ResponseWriter: w, r: r}
+		next.ServeHTTP(cw, r)
+		cw.finish()
+	})
+}
+
+type conditionalWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	buffering   bool // JSON waiting for its ETag
+	discard     bool // 304 sent, the handler's body is dropped
+	body        bytes.Buffer
+}
+
+func (c *conditionalWriter) WriteHeader(code int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	h := c.Header()
+	if code == http.StatusOK {
+		switch {
+		case h.Get("ETag") == "" && strings.Contains(h.Get("Content-Type"), "json"):
+			c.buffering = true
+			return
+		case notModified(c.r, h):
+			c.discard = true
+			writeNotModified(c.ResponseWriter)
+			return
+		}
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *conditionalWriter) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	switch {
+	case c.discard:
+		return len(b), nil
+	case c.buffering:
+		return c.body.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (c *conditionalWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+// finish tags and sends a buffered JSON response
+func (c *conditionalWriter) finish() {
+	if !c.buffering {
+		return
+	}
+	h := c.Header()
+	h.Set("ETag", StrongETag(c.body.Bytes()))
+	if notModified(c.r, h) {
+		writeNotModified(c.ResponseWriter)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(c.body.Len()))
+	c.ResponseWriter.WriteHeader(http.StatusOK)
+	c.ResponseWriter.Write(c.body.Bytes())
+}
+
+// writeNotModified sends a 304, keeping the validators and caching headers
+// but none describing a body
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// StaticFiles serves dir like http.FileServer, with a WeakETag on every
+// file. The file server then answers If-None-Match as well as
+// If-Modified-Since with 304, without reading the file.
+func StaticFiles(dir string) http.Handler {
+	root := http.Dir(dir)
+	files := http.FileServer(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f, err := root.Open(path.Clean("/" + r.URL.Path)); err == nil {
+			if info, err := f.Stat(); err == nil && !info.IsDir() {
+				w.Header().Set("ETag", WeakETag(info.Size(), info.ModTime()))
+			}
+			f.Close()
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// validatedResponse is what ConditionalRequests keeps of a response to
+// revalidate it and serve it again on a 304
+type validatedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// ConditionalRequests stores the validators (ETag and Last-Modified) and
+// body of successful GET responses in store, keyed by URL and Accept, and
+// sends them back as If-None-Match and If-Modified-Since when the URL is
+// requested again. A 304 from the server is turned into the stored 200, so
+// callers see the same response without it being sent again. Requests that
+// set their own conditional headers, and responses marked no-store, are
+// left alone.
+func ConditionalRequests(store cache.Cache) Interceptor {
+	validators := cache.NewTyped[validatedResponse](store, nil)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+				return next.RoundTrip(req)
+			}
+			ctx := req.Context()
+			key := "http:validators:" + req.URL.String() + ":" + req.Header.Get("Accept")
+			stored, err := validators.Get(ctx, key)
+			found := err == nil
+
+			out := req
+			if found {
+				out = req.Clone(ctx)
+				if etag := stored.Header.Get("ETag"); etag != "" {
+					out.Header.Set("If-None-Match", etag)
+				}
+				if modified := stored.Header.Get("Last-Modified"); modified != "" {
+					out.Header.Set("If-Modified-Since", modified)
+				}
+			}
+
+			resp, err := next.RoundTrip(out)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case resp.StatusCode == http.StatusNotModified && found:
+				resp.Body.Close()
+				return replayValidated(ctx, validators, key, stored, resp), nil
+			case resp.StatusCode == http.StatusOK && storable(resp.Header):
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("reading response body: %w", err)
+				}
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				stored = validatedResponse{Header: resp.Header.Clone(), Body: body}
+				if err := validators.Set(ctx, key, stored, 0); err != nil {
+					logger.Warn("Storing validators failed", "url", req.URL.String(), "error", err)
+				}
+			}
+			return resp, nil
+		})
+	}
+}
+
+// storable reports whether a response has a validator and may be kept
+func storable(h http.Header) bool {
+	if h.Get("ETag") == "" && h.Get("Last-Modified") == "" {
+		return false
+	}
+	return !strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-store")
+}
+
+// replayValidated builds the 200 a 304 confirmed, with the stored headers
+// updated from the 304's as a cache would
+func replayValidated(ctx context.Context, validators *cache.Typed[validatedResponse], key string, stored validatedResponse, notModified *http.Response) *http.Response {
+	header := stored.Header.Clone()
+	for name, values := range notModified.Header {
+		if name != "Content-Length" {
+			header[name] = values
+		}
+	}
+	if err := validators.Set(ctx, key, validatedResponse{Header: header, Body: stored.Body}, 0); err != nil {
+		logger.Warn("Storing validators failed", "key", key, "error", err)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(stored.Body)),
+		ContentLength: int64(len(stored.Body)),
+		Request:       notModified.Request,
+	}
+}
+
+// ExampleConditionalRequests repeats requests for JSON and a static file
+// with a client that keeps validators, showing the server answer 304 until
+// the file changes
+func ExampleConditionalRequests() {
+	dir, err := os.MkdirTemp("", "static")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	stylesheet := filepath.Join(dir, "app.css")
+	os.WriteFile(stylesheet, []byte("body { font-family: sans-serif; }\n"), 0644)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users", apiUsersHandler)
+	mux.Handle("/static/", http.StripPrefix("/static/", StaticFiles(dir)))
+	handler := ConditionalResponses(mux)
+
+	var lastRequest struct {
+		sync.Mutex
+		conditions string
+		status     int
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		handler.ServeHTTP(rw, r)
+		lastRequest.Lock()
+		defer lastRequest.Unlock()
+		lastRequest.conditions = "none"
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			lastRequest.conditions = "If-None-Match " + inm
+		} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+			lastRequest.conditions = "If-Modified-Since " + ims
+		}
+		lastRequest.status = rw.statusCode
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL)
+	client.SetValidators(cache.NewMemory(cache.MemoryOptions{MaxEntries: 100}))
+	get := func(path string) {
+		resp, err := client.Get(path)
+		if err != nil {
+			fmt.Printf("❌ GET %s: %v\n", path, err)
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		lastRequest.Lock()
+		defer lastRequest.Unlock()
+		fmt.Printf("🏷️  GET %-15s sent %s\n", path, lastRequest.conditions)
+		fmt.Printf("    server %d, client got %d with %d bytes, ETag %s\n", lastRequest.status, resp.StatusCode, len(body), resp.Header.Get("ETag"))
+	}
+
+	get("/api/users")
+	get("/api/users")
+	get("/static/app.css")
+	get("/static/app.css")
+
+	fmt.Println("✏️  Changing app.css")
+	os.WriteFile(stylesheet, []byte("body { font-family: serif; }\n"), 0644)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(stylesheet, later, later)
+	get("/static/app.css")
+	get("/static/app.css")
+
+	// Without an ETag the server falls back to the modification time
+	resp, err := MakeRequest(RequestOptions{Method: "GET", URL: srv.URL + "/static/app.css",
+		Headers: map[string]string{"If-Modified-Since": later.UTC().Format(http.TimeFormat)}})
+	if err == nil {
+		fmt.Printf("📅 If-Modified-Since alone: %d with %d bytes\n", resp.StatusCode, len(resp.Body))
+	}
+}
@@ -2,8 +2,11 @@ package http
 
 import (
 	"fmt"
-	"log"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
 )
 
 func RunAllExamples() {
@@ -28,14 +31,68 @@ func RunAllExamples() {
 
 	time.Sleep(1 * time.Second)
 
-	fmt.Println("\n4. 🛠️  HTTP Utils Examples")
-	fmt.Println("--------------------------")
-	ExampleHTTPUtils()
+	fmt.Println("\n4. 🧩 Declarative REST Client Examples")
+	fmt.Println("--------------------------------------")
+	ExampleRESTClient()
 
 	time.Sleep(1 * time.Second)
 
-	fmt.Println("\n5. 📄 JSON Utils Examples")
+	fmt.Println("\n5. 📚 Pagination Examples")
 	fmt.Println("-------------------------")
+	ExamplePagination()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n6. 🔏 Request Signing Examples")
+	fmt.Println("------------------------------")
+	ExampleRequestSigning()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n7. 🗜️  Compression Examples")
+	fmt.Println("---------------------------")
+	ExampleCompression()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n8. 🏷️  Conditional Request Examples")
+	fmt.Println("-----------------------------------")
+	ExampleConditionalRequests()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n9. 🔀 DNS Load Balancing and Failover Examples")
+	fmt.Println("----------------------------------------------")
+	ExampleFailover()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n10. ⏱️  Request Hedging Examples")
+	fmt.Println("-------------------------------")
+	ExampleHedging()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n11. 🚫 API Error Examples")
+	fmt.Println("-------------------------")
+	ExampleAPIErrors()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n12. 🔌 WebSocket Examples")
+	fmt.Println("-------------------------")
+	ExampleWebSocket()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n13. 🛠️  HTTP Utils Examples")
+	fmt.Println("---------------------------")
+	ExampleHTTPUtils()
+
+	time.Sleep(1 * time.Second)
+
+	fmt.Println("\n14. 📄 JSON Utils Examples")
+	fmt.Println("--------------------------")
 	ExampleJSONUtils()
 
 	fmt.Println("\n✅ All examples completed!")
@@ -65,7 +122,7 @@ func QuickTestClient() {
 	fmt.Println("\n1. Testing basic GET request...")
 	resp, err := SimpleGet("https://httpbin.org/get")
 	if err != nil {
-		log.Printf("Error: %v", err)
+		logger.Errorf("Error: %v", err)
 	} else {
 		fmt.Printf("✅ GET request successful: %s\n", resp.Status)
 		resp.Body.Close()
@@ -78,7 +135,7 @@ func QuickTestClient() {
 	}
 	resp, err = SimplePost("https://httpbin.org/post", data)
 	if err != nil {
-		log.Printf("Error: %v", err)
+		logger.Errorf("Error: %v", err)
 	} else {
 		fmt.Printf("✅ POST request successful: %s\n", resp.Status)
 		resp.Body.Close()
@@ -88,7 +145,7 @@ func QuickTestClient() {
 	client := NewGitHubClientWithoutAuth()
 	user, err := client.GetUser("octocat")
 	if err != nil {
-		log.Printf("Error: %v", err)
+		logger.Errorf("Error: %v", err)
 	} else {
 		fmt.Printf("✅ GitHub API successful: %s (%s)\n", user.Name, user.Login)
 	}
@@ -110,14 +167,18 @@ func BenchmarkExample() {
 
 	fmt.Println("\n1. Sequential requests...")
 	start := time.Now()
+	bar := format.NewProgressBar(os.Stdout, int64(len(urls)), "Sequential")
+	bar.Unit = "req"
 	for i, url := range urls {
 		resp, err := SimpleGet(url)
 		if err != nil {
-			log.Printf("Request %d failed: %v", i+1, err)
+			logger.Warnf("Request %d failed: %v", i+1, err)
 		} else {
 			resp.Body.Close()
 		}
+		bar.Increment()
 	}
+	bar.Finish()
 	sequentialTime := time.Since(start)
 	fmt.Printf("Sequential time: %s\n", FormatDuration(sequentialTime))
 
@@ -126,15 +187,31 @@ func BenchmarkExample() {
 	var requests []RequestOptions
 	for _, url := range urls {
 		requests = append(requests, RequestOptions{
-			Method: "GET",
-			URL:    url,
+			Method:   "GET",
+			URL:      url,
+			Balancer: DefaultBalancer,
 		})
 	}
 	responses, err := BatchRequest(requests)
 	concurrentTime := time.Since(start)
 
+	table := format.NewTable("URL", "Status", "Duration", "Size").
+		SetAlign(1, format.AlignRight).
+		SetAlign(2, format.AlignRight).
+		SetAlign(3, format.AlignRight).
+		SetMaxWidth(0, 32).
+		SetColor(1, statusColor)
+	for i, resp := range responses {
+		if resp == nil {
+			table.AddRow(requests[i].URL, "error", "-", "-")
+			continue
+		}
+		table.AddRow(requests[i].URL, resp.StatusCode, FormatDuration(resp.Duration), len(resp.Body))
+	}
+	table.Render(os.Stdout)
+
 	if err != nil {
-		log.Printf("Concurrent requests failed: %v", err)
+		logger.Warnf("Concurrent requests failed: %v", err)
 	} else {
 		fmt.Printf("Concurrent time: %s\n", FormatDuration(concurrentTime))
 		fmt.Printf("Speedup: %.2fx\n", float64(sequentialTime)/float64(concurrentTime))
@@ -142,6 +219,18 @@ func BenchmarkExample() {
 	}
 }
 
+// statusColor colors a status cell by HTTP status class
+func statusColor(cell string) string {
+	switch {
+	case strings.HasPrefix(cell, "2"):
+		return format.Green
+	case strings.HasPrefix(cell, "3"):
+		return format.Yellow
+	default:
+		return format.Red
+	}
+}
+
 func ErrorHandlingExample() {
 	fmt.Println("❌ HTTP Error Handling Example")
 	fmt.Println("==============================")
@@ -210,13 +299,14 @@ func RealWorldExample() {
 	if err != nil {
 		fmt.Printf("❌ Failed to get JSON: %v\n", err)
 	} else {
-		fmt.Printf("✅ JSON downloaded: %+v\n", jsonData)
+		fmt.Printf("✅ JSON downloaded: %s\n", format.Dump(jsonData, format.DefaultDumpOptions))
 	}
 
 	fmt.Println("\n3. Retry with Exponential Backoff")
 	options := RequestOptions{
-		Method: "GET",
-		URL:    "https://httpbin.org/status/500",
+		Method:   "GET",
+		URL:      "https://httpbin.org/status/500",
+		Balancer: DefaultBalancer,
 	}
 
 	resp, err := RetryRequest(options, 3, time.Second)
@@ -236,8 +326,9 @@ func RealWorldExample() {
 	var requests []RequestOptions
 	for _, url := range urls {
 		requests = append(requests, RequestOptions{
-			Method: "GET",
-			URL:    url,
+			Method:   "GET",
+			URL:      url,
+			Balancer: DefaultBalancer,
 		})
 	}
 
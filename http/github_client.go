@@ -1,8 +1,13 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
+	reflectops "github.com/jerrychou/go-practice/reflect"
 )
 
 type GitHubUser struct {
@@ -94,8 +99,38 @@ type GitHubRepo struct {
 	PushedAt      time.Time `json:"pushed_at"`
 }
 
+// GitHubAPI is the set of GitHub operations offered by GitHubClient, so callers
+// can swap in fakes or wrap the client (e.g. with caching proxies)
+type GitHubAPI interface {
+	GetUser(username string) (*GitHubUser, error)
+	GetUserRepos(username string) ([]GitHubRepo, error)
+	GetRepo(owner, repo string) (*GitHubRepo, error)
+	SearchUsers(query string) ([]GitHubUser, error)
+	SearchRepos(query string) ([]GitHubRepo, error)
+	GetRateLimit() (map[string]any, error)
+}
+
+var _ GitHubAPI = (*GitHubClient)(nil)
+
+// githubEndpoints declares the REST calls behind GitHubClient; Bind fills
+// in the functions
+type githubEndpoints struct {
+	GetUser      func(username string) (*GitHubUser, error)        `method:"GET" path:"/users/{username}" args:"username"`
+	GetUserRepos func(username string) ([]GitHubRepo, error)       `method:"GET" path:"/users/{username}/repos" args:"username"`
+	GetRepo      func(owner, repo string) (*GitHubRepo, error)     `method:"GET" path:"/repos/{owner}/{repo}" args:"owner,repo"`
+	SearchUsers  func(q string) (*githubSearch[GitHubUser], error) `method:"GET" path:"/search/users" args:"q" query:"q"`
+	SearchRepos  func(q string) (*githubSearch[GitHubRepo], error) `method:"GET" path:"/search/repositories" args:"q" query:"q"`
+	GetRateLimit func() (map[string]any, error)                    `method:"GET" path:"/rate_limit"`
+}
+
+type githubSearch[T any] struct {
+	TotalCount int `json:"total_count"`
+	Items      []T `json:"items"`
+}
+
 type GitHubClient struct {
 	client  *HTTPClient
+	api     githubEndpoints
 	baseURL string
 	token   string
 }
@@ -109,11 +144,7 @@ func NewGitHubClient(token string) *GitHubClient {
 		"Authorization": fmt.Sprintf("token %s", token),
 	})
 
-	return &GitHubClient{
-		client:  client,
-		baseURL: "https://api.github.com",
-		token:   token,
-	}
+	return newGitHubClient(client, token)
 }
 
 func NewGitHubClientWithoutAuth() *GitHubClient {
@@ -124,77 +155,125 @@ func NewGitHubClientWithoutAuth() *GitHubClient {
 		"User-Agent": "Go-GitHub-Client/1.0",
 	})
 
-	return &GitHubClient{
+	return newGitHubClient(client, "")
+}
+
+func newGitHubClient(client *HTTPClient, token string) *GitHubClient {
+	gc := &GitHubClient{
 		client:  client,
-		baseURL: "https://api.github.com",
-		token:   "",
+		baseURL: client.baseURL,
+		token:   token,
 	}
+	if err := client.Bind(&gc.api); err != nil {
+		panic(err) // the tags above are fixed, so this is a programming error
+	}
+	return gc
 }
 
 func (gc *GitHubClient) GetUser(username string) (*GitHubUser, error) {
-	var user GitHubUser
-	err := gc.client.GetJSON(fmt.Sprintf("/users/%s", username), &user)
+	user, err := gc.api.GetUser(username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user %s: %w", username, err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (gc *GitHubClient) GetUserRepos(username string) ([]GitHubRepo, error) {
-	var repos []GitHubRepo
-	err := gc.client.GetJSON(fmt.Sprintf("/users/%s/repos", username), &repos)
+	repos, err := gc.api.GetUserRepos(username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repos for user %s: %w", username, err)
 	}
 	return repos, nil
 }
 
+// RepoPages pages through all of a user's repositories, perPage at a time
+// (GitHub allows up to 100), following GitHub's Link headers. It keeps one
+// request of the rate limit in hand, waiting for the reset instead.
+func (gc *GitHubClient) RepoPages(username string, perPage int) *Paginator[GitHubRepo] {
+	return NewPaginator[GitHubRepo](gc.client, "/users/"+url.PathEscape(username)+"/repos", PageOptions{
+		PerPage:      perPage,
+		PerPageParam: "per_page",
+		PageParam:    "page",
+		MinRemaining: 1,
+	})
+}
+
 func (gc *GitHubClient) GetRepo(owner, repo string) (*GitHubRepo, error) {
-	var repository GitHubRepo
-	err := gc.client.GetJSON(fmt.Sprintf("/repos/%s/%s", owner, repo), &repository)
+	repository, err := gc.api.GetRepo(owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repo %s/%s: %w", owner, repo, err)
 	}
-	return &repository, nil
+	return repository, nil
 }
 
 func (gc *GitHubClient) SearchUsers(query string) ([]GitHubUser, error) {
-	var result struct {
-		TotalCount int          `json:"total_count"`
-		Items      []GitHubUser `json:"items"`
-	}
-
-	err := gc.client.GetJSON(fmt.Sprintf("/search/users?q=%s", query), &result)
+	result, err := gc.api.SearchUsers(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
-
 	return result.Items, nil
 }
 
 func (gc *GitHubClient) SearchRepos(query string) ([]GitHubRepo, error) {
-	var result struct {
-		TotalCount int          `json:"total_count"`
-		Items      []GitHubRepo `json:"items"`
-	}
-
-	err := gc.client.GetJSON(fmt.Sprintf("/search/repositories?q=%s", query), &result)
+	result, err := gc.api.SearchRepos(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search repos: %w", err)
 	}
-
 	return result.Items, nil
 }
 
 func (gc *GitHubClient) GetRateLimit() (map[string]any, error) {
-	var rateLimit map[string]any
-	err := gc.client.GetJSON("/rate_limit", &rateLimit)
+	rateLimit, err := gc.api.GetRateLimit()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rate limit: %w", err)
 	}
 	return rateLimit, nil
 }
 
+// githubAPIProxy is the stub struct reflect.MakeProxy fills in for GitHubAPI
+type githubAPIProxy struct {
+	GetUserFunc      func(username string) (*GitHubUser, error)
+	GetUserReposFunc func(username string) ([]GitHubRepo, error)
+	GetRepoFunc      func(owner, repo string) (*GitHubRepo, error)
+	SearchUsersFunc  func(query string) ([]GitHubUser, error)
+	SearchReposFunc  func(query string) ([]GitHubRepo, error)
+	GetRateLimitFunc func() (map[string]any, error)
+}
+
+func (p *githubAPIProxy) GetUser(username string) (*GitHubUser, error) {
+	return p.GetUserFunc(username)
+}
+
+func (p *githubAPIProxy) GetUserRepos(username string) ([]GitHubRepo, error) {
+	return p.GetUserReposFunc(username)
+}
+
+func (p *githubAPIProxy) GetRepo(owner, repo string) (*GitHubRepo, error) {
+	return p.GetRepoFunc(owner, repo)
+}
+
+func (p *githubAPIProxy) SearchUsers(query string) ([]GitHubUser, error) {
+	return p.SearchUsersFunc(query)
+}
+
+func (p *githubAPIProxy) SearchRepos(query string) ([]GitHubRepo, error) {
+	return p.SearchReposFunc(query)
+}
+
+func (p *githubAPIProxy) GetRateLimit() (map[string]any, error) {
+	return p.GetRateLimitFunc()
+}
+
+// NewMemoizedGitHubAPI wraps api so repeated lookups are served from memory
+func NewMemoizedGitHubAPI(api GitHubAPI, ttl time.Duration) (GitHubAPI, *reflectops.Memoizer, error) {
+	memo := reflectops.NewMemoizer(ttl)
+	proxy := &githubAPIProxy{}
+	if err := reflectops.MakeProxy((*GitHubAPI)(nil), proxy, api, memo.Interceptor()); err != nil {
+		return nil, nil, err
+	}
+	return proxy, memo, nil
+}
+
 func ExampleGitHubAPI() {
 	fmt.Println("=== GitHub API Examples ===")
 
@@ -243,13 +322,33 @@ func ExampleGitHubAPI() {
 		}
 	}
 
-	fmt.Println("\n4. Check rate limit:")
+	fmt.Println("\n4. Page through all repositories:")
+	all, err := client.RepoPages("octocat", 5).Collect(context.Background(), 12)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("Collected %d repositories, 5 per page\n", len(all))
+	}
+
+	fmt.Println("\n5. Check rate limit:")
 	rateLimit, err := client.GetRateLimit()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Rate limit info: %+v\n", rateLimit)
+		fmt.Printf("Rate limit info: %s\n", format.Dump(rateLimit, format.DefaultDumpOptions))
+	}
+
+	fmt.Println("\n6. Memoize lookups:")
+	memoized, memo, err := NewMemoizedGitHubAPI(client, time.Minute)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	for i := 0; i < 3; i++ {
+		memoized.GetUser("octocat")
 	}
+	stats := memo.Stats()
+	fmt.Printf("3 lookups: %d hits, %d misses\n", stats.Hits, stats.Misses)
 }
 
 func ExampleGitHubWithAuth(token string) {
@@ -276,7 +375,7 @@ func ExampleGitHubWithAuth(token string) {
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Rate limit info: %+v\n", rateLimit)
+		fmt.Printf("Rate limit info: %s\n", format.Dump(rateLimit, format.DefaultDumpOptions))
 	}
 }
 
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// HedgingOptions configures HedgingInterceptor
+type HedgingOptions struct {
+	// Delay is how long to wait for an answer before sending a duplicate
+	// request; a request is never hedged if zero
+	Delay time.Duration
+	// MaxHedges is how many duplicates may be sent per request, 1 if zero
+	MaxHedges int
+	// Alternates are base URLs, such as a replica in another region, that
+	// hedges are sent to in turn instead of the original host
+	Alternates []string
+}
+
+// HedgingInterceptor cuts tail latency by racing requests: when no answer
+// has come after Delay, or an attempt fails, the request is sent again, to
+// the next alternate if there are any, and the first successful answer
+// (one below 500) wins. The other attempts are cancelled. Only idempotent
+// requests whose body can be sent again are hedged, since the server may
+// see every copy. Add it before signing interceptors so each copy is
+// signed for the host it goes to.
+func HedgingInterceptor(opts HedgingOptions) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+			if opts.Delay <= 0 || !isIdempotent(req) || !replayable {
+				return next.RoundTrip(req)
+			}
+			return hedge(next, req, opts)
+		})
+	}
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+	cancel  context.CancelFunc
+}
+
+func (r hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+// discard releases a losing attempt
+func (r hedgeResult) discard() {
+	r.cancel()
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+}
+
+func hedge(next http.RoundTripper, req *http.Request, opts HedgingOptions) (*http.Response, error) {
+	attempts := 1 + max(opts.MaxHedges, 1)
+	results := make(chan hedgeResult, attempts)
+	var cancels []context.CancelFunc
+	launch := func(attempt int) error {
+		ctx, cancel := context.WithCancel(req.Context())
+		try := req.Clone(ctx)
+		if attempt > 0 {
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					cancel()
+					return err
+				}
+				try.Body = body
+			}
+			if len(opts.Alternates) > 0 {
+				if err := retarget(try, opts.Alternates[(attempt-1)%len(opts.Alternates)]); err != nil {
+					cancel()
+					return err
+				}
+			}
+			logger.Debug("Sending hedged request", "url", try.URL.Redacted(), "attempt", attempt+1)
+		}
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := next.RoundTrip(try)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err, cancel: cancel}
+		}()
+		return nil
+	}
+
+	if err := launch(0); err != nil {
+		return nil, err
+	}
+	launched, pending := 1, 1
+	timer := time.NewTimer(opts.Delay)
+	defer timer.Stop()
+	var failed *hedgeResult
+
+	for {
+		hedgeNow := false
+		select {
+		case <-timer.C:
+			hedgeNow = true
+		case r := <-results:
+			pending--
+			if r.ok() {
+				for attempt, cancel := range cancels {
+					if attempt != r.attempt {
+						cancel()
+					}
+				}
+				// Cancelling the winner would cut off its body; it is
+				// released when the caller closes the body instead
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						(<-results).discard()
+					}
+				}(pending)
+				r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: r.cancel}
+				return r.resp, nil
+			}
+			if failed != nil {
+				failed.discard()
+			}
+			failed = &r
+			hedgeNow = true
+		}
+
+		if hedgeNow && launched < attempts && req.Context().Err() == nil {
+			if err := launch(launched); err != nil {
+				logger.Warn("Cannot hedge request", "url", req.URL.Redacted(), "error", err)
+				launched = attempts
+			} else {
+				launched++
+				pending++
+				timer.Reset(opts.Delay)
+			}
+		}
+		if pending == 0 {
+			// Every attempt failed; the last one is the answer
+			if failed.err != nil {
+				failed.cancel()
+				return nil, failed.err
+			}
+			failed.resp.Body = &cancelOnClose{ReadCloser: failed.resp.Body, cancel: failed.cancel}
+			return failed.resp, nil
+		}
+	}
+}
+
+// retarget points req at base, keeping its path and query
+func retarget(req *http.Request, base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("hedging alternate %q: %w", base, err)
+	}
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	req.Host = ""
+	return nil
+}
+
+// cancelOnClose releases an attempt's context once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// SetHedging races slow idempotent requests against duplicates; see
+// HedgingInterceptor
+func (c *HTTPClient) SetHedging(opts HedgingOptions) {
+	c.Use(HedgingInterceptor(opts))
+}
+
+// ExampleHedging calls a primary that is slow now and then, with and
+// without hedges to a replica, and shows a POST is never hedged
+func ExampleHedging() {
+	var calls, cancelled atomic.Int64
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every third request hits a slow path
+		if calls.Add(1)%3 == 0 {
+			select {
+			case <-time.After(300 * time.Millisecond):
+			case <-r.Context().Done():
+				cancelled.Add(1)
+				return
+			}
+		}
+		fmt.Fprint(w, "primary")
+	}))
+	defer primary.Close()
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "replica")
+	}))
+	defer replica.Close()
+
+	run := func(label string, client *HTTPClient, method string) {
+		var slowest time.Duration
+		served := map[string]int{}
+		for i := 0; i < 6; i++ {
+			start := time.Now()
+			var resp *http.Response
+			var err error
+			if method == http.MethodPost {
+				resp, err = client.Post("/orders", map[string]int{"quantity": 1})
+			} else {
+				resp, err = client.Get("/orders")
+			}
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", label, err)
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			served[string(body)]++
+			slowest = max(slowest, time.Since(start))
+		}
+		fmt.Printf("⏱️  %-22s slowest of 6 took %-6v served by %v\n", label, slowest.Round(10*time.Millisecond), served)
+	}
+
+	plain := NewHTTPClient(primary.URL)
+	run("GET without hedging", plain, http.MethodGet)
+
+	hedged := NewHTTPClient(primary.URL)
+	hedged.SetHedging(HedgingOptions{Delay: 50 * time.Millisecond, Alternates: []string{replica.URL}})
+	run("GET hedged after 50ms", hedged, http.MethodGet)
+	run("POST, never hedged", hedged, http.MethodPost)
+
+	time.Sleep(20 * time.Millisecond)
+	fmt.Printf("✂️  Slow primary requests cancelled by a winning hedge: %d\n", cancelled.Load())
+}
@@ -1,11 +1,12 @@
 package http
 
 import (
-	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -15,7 +16,7 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(wrapped, r)
 		duration := time.Since(start)
-		log.Printf("%s %s %d %v %s %s",
+		logger.Infof("%s %s %d %v %s %s",
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
@@ -53,7 +54,7 @@ func authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if auth != "Bearer demo-token" {
+		if !cryptoutil.Equal(auth, "Bearer demo-token") {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
@@ -93,7 +94,7 @@ func recoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				logger.Errorf("Panic recovered: %v", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 			}
 		}()
@@ -155,8 +156,10 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
+// generateRequestID returns a ULID so request IDs are unique across servers
+// and sort by arrival time
 func generateRequestID() string {
-	return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	return "req-" + string_op.ULID()
 }
 
 func ChainMiddleware(handler http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
@@ -185,6 +188,6 @@ func CreateServerWithMiddleware(port string) {
 		Handler: handler,
 	}
 
-	log.Printf("Server with middleware starting on port %s", port)
-	log.Fatal(server.ListenAndServe())
+	logger.Infof("Server with middleware starting on port %s", port)
+	logger.Fatal("server stopped", "error", server.ListenAndServe())
 }
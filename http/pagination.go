@@ -0,0 +1,379 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited is returned by a Paginator when the server asks it to wait
+// longer than PageOptions.MaxWait, and matched by APIErrors with status 429
+var ErrRateLimited = errors.New("http: rate limit reset is too far away")
+
+// PageOptions says how a Paginator asks for pages. A Link header with
+// rel="next" (RFC 5988), as GitHub sends, is always followed; the query
+// parameters are the fallback for APIs without one.
+type PageOptions struct {
+	// PerPage is sent as PerPageParam, such as "per_page" or "limit"
+	PerPage      int
+	PerPageParam string
+	// PageParam pages by number ("page"), OffsetParam by item offset
+	// ("offset"), and CursorParam by the cursor each page returns
+	PageParam   string
+	OffsetParam string
+	CursorParam string
+	// Interval is the least time between page requests
+	Interval time.Duration
+	// MinRemaining makes the paginator wait for the X-RateLimit-Reset time
+	// once X-RateLimit-Remaining drops to it. A 429 or a 403 with no
+	// requests remaining is waited out and retried either way.
+	MinRemaining int
+	// MaxWait caps one rate limit wait; longer waits fail with
+	// ErrRateLimited. Defaults to a minute.
+	MaxWait time.Duration
+}
+
+// Paginator walks a paged listing one page at a time:
+//
+//	for p.Next(ctx) {
+//		for _, repo := range p.Items() { ... }
+//	}
+//	if err := p.Err(); err != nil { ... }
+type Paginator[T any] struct {
+	client  *HTTPClient
+	opts    PageOptions
+	extract func(resp *http.Response) ([]T, string, error)
+
+	next    string // path or URL of the next page, "" when done
+	page    int
+	offset  int
+	items   []T
+	err     error
+	last    time.Time
+	waitFor time.Time // set when the last response spent the rate limit
+}
+
+// NewPaginator pages through path, whose responses are lists of T
+func NewPaginator[T any](c *HTTPClient, path string, opts PageOptions) *Paginator[T] {
+	return NewPaginatorFunc(c, path, opts, func(items []T) ([]T, string) { return items, "" })
+}
+
+// NewPaginatorFunc pages through path, whose responses decode into P;
+// items returns the page's items and, for cursor paging, the next cursor
+func NewPaginatorFunc[P, T any](c *HTTPClient, path string, opts PageOptions, items func(page P) ([]T, string)) *Paginator[T] {
+	if opts.MaxWait == 0 {
+		opts.MaxWait = time.Minute
+	}
+	p := &Paginator[T]{client: c, opts: opts, page: 1}
+	p.extract = func(resp *http.Response) ([]T, string, error) {
+		var page P
+		if err := c.Decode(resp, &page); err != nil {
+			return nil, "", err
+		}
+		list, cursor := items(page)
+		return list, cursor, nil
+	}
+	p.next = p.withParams(path, map[string]string{
+		opts.PerPageParam: perPage(opts.PerPage),
+		opts.PageParam:    "1",
+		opts.OffsetParam:  "0",
+	})
+	return p
+}
+
+// Next fetches the next page, reporting false at the end or on error
+func (p *Paginator[T]) Next(ctx context.Context) bool {
+	if p.next == "" || p.err != nil {
+		return false
+	}
+	if err := p.throttle(ctx); err != nil {
+		p.err = err
+		return false
+	}
+
+	resp, err := p.fetch(ctx)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	defer resp.Body.Close()
+
+	items, cursor, err := p.extract(resp)
+	if err != nil {
+		p.err = fmt.Errorf("decoding page %d: %w", p.page, err)
+		return false
+	}
+	p.items = items
+	p.advance(resp, len(items), cursor)
+	return true
+}
+
+// Items returns the items of the page Next fetched
+func (p *Paginator[T]) Items() []T { return p.items }
+
+// Page returns the number of the page Next fetched, starting at 1
+func (p *Paginator[T]) Page() int { return p.page - 1 }
+
+// Err returns the error that stopped Next, if any
+func (p *Paginator[T]) Err() error { return p.err }
+
+// Collect gathers items from the remaining pages, stopping after max items
+// when max is above zero. The items gathered so far are returned with any
+// error.
+func (p *Paginator[T]) Collect(ctx context.Context, max int) ([]T, error) {
+	var all []T
+	for p.Next(ctx) {
+		all = append(all, p.items...)
+		if max > 0 && len(all) >= max {
+			return all[:max], nil
+		}
+	}
+	return all, p.err
+}
+
+// fetch requests the next page, waiting out rate limit responses
+func (p *Paginator[T]) fetch(ctx context.Context) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := p.client.request(ctx, http.MethodGet, p.next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d: %w", p.page, err)
+		}
+		p.last = time.Now()
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		wait, limited := rateLimitWait(resp)
+		apiErr := newAPIError(resp)
+		resp.Body.Close()
+		if !limited || attempt == 2 {
+			return nil, apiErr
+		}
+		logger.Warn("Rate limited while paging", "page", p.page, "wait", wait)
+		if err := p.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// advance works out the next page from the response that was just read
+func (p *Paginator[T]) advance(resp *http.Response, count int, cursor string) {
+	p.page++
+	p.offset += count
+	p.waitFor = time.Time{}
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil && remaining <= p.opts.MinRemaining {
+		p.waitFor = rateLimitReset(resp)
+	}
+
+	current := p.next
+	switch next := parseLinkHeader(resp.Header.Get("Link"))["next"]; {
+	case next != "":
+		p.next = next
+	case p.opts.CursorParam != "":
+		p.next = ""
+		if cursor != "" {
+			p.next = p.withParams(current, map[string]string{p.opts.CursorParam: cursor})
+		}
+	case p.opts.PageParam == "" && p.opts.OffsetParam == "",
+		count == 0,
+		p.opts.PerPage > 0 && count < p.opts.PerPage:
+		p.next = ""
+	default:
+		p.next = p.withParams(current, map[string]string{
+			p.opts.PageParam:   strconv.Itoa(p.page),
+			p.opts.OffsetParam: strconv.Itoa(p.offset),
+		})
+	}
+}
+
+// throttle waits for Interval and for a spent rate limit to reset
+func (p *Paginator[T]) throttle(ctx context.Context) error {
+	var wait time.Duration
+	if !p.last.IsZero() {
+		wait = p.opts.Interval - time.Since(p.last)
+	}
+	if until := time.Until(p.waitFor); until > wait {
+		logger.Info("Rate limit nearly spent, waiting for reset", "page", p.page, "wait", until.Round(time.Millisecond))
+		wait = until
+	}
+	return p.sleep(ctx, wait)
+}
+
+func (p *Paginator[T]) sleep(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	if wait > p.opts.MaxWait {
+		return fmt.Errorf("%w: would wait %s", ErrRateLimited, wait.Round(time.Second))
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withParams sets query parameters on path, skipping unnamed ones
+func (p *Paginator[T]) withParams(path string, params map[string]string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+	query := u.Query()
+	for name, value := range params {
+		if name != "" && value != "" {
+			query.Set(name, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func perPage(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// rateLimitWait reports whether resp is a rate limit response and how long
+// it asks the client to wait
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if !limited {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if reset := rateLimitReset(resp); !reset.IsZero() {
+		return time.Until(reset), true
+	}
+	return time.Second, true
+}
+
+// rateLimitReset parses X-RateLimit-Reset, in Unix seconds
+func rateLimitReset(resp *http.Response) time.Time {
+	secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+// parseLinkHeader maps each rel of an RFC 5988 Link header to its URL
+func parseLinkHeader(header string) map[string]string {
+	links := map[string]string{}
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name != "rel" {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				links[rel] = target[1 : len(target)-1]
+			}
+		}
+	}
+	return links
+}
+
+// ExamplePagination pages through a fake GitHub with Link headers and a
+// small rate limit, then through offset and cursor style listings
+func ExamplePagination() {
+	var repos []GitHubRepo
+	for i := 1; i <= 7; i++ {
+		repos = append(repos, GitHubRepo{ID: i, Name: fmt.Sprintf("repo-%d", i), StargazersCount: i * 10})
+	}
+
+	remaining := 3
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users/octocat/repos":
+			page, _ := strconv.Atoi(query.Get("page"))
+			size, _ := strconv.Atoi(query.Get("per_page"))
+			page, size = max(page, 1), max(size, 1)
+			start, end := min((page-1)*size, len(repos)), min(page*size, len(repos))
+			if end < len(repos) {
+				next := *r.URL
+				next.Scheme, next.Host = "http", r.Host
+				query.Set("page", strconv.Itoa(page+1))
+				next.RawQuery = query.Encode()
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+			}
+			if remaining == 0 {
+				remaining = 3 // a new rate limit window
+			}
+			remaining--
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10))
+			json.NewEncoder(w).Encode(repos[start:end])
+		case "/events":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			var events []int
+			for i := offset; i < min(offset+limit, 5); i++ {
+				events = append(events, i)
+			}
+			json.NewEncoder(w).Encode(events)
+		case "/feed":
+			cursor, _ := strconv.Atoi(query.Get("cursor"))
+			page := map[string]any{"entries": []string{fmt.Sprintf("entry-%d", cursor)}}
+			if cursor < 2 {
+				page["next_cursor"] = strconv.Itoa(cursor + 1)
+			}
+			json.NewEncoder(w).Encode(page)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	fmt.Println("🔗 Link headers (GitHub repos, 3 per page):")
+	gc := newGitHubClient(NewHTTPClient(srv.URL), "")
+	pages := gc.RepoPages("octocat", 3)
+	for pages.Next(ctx) {
+		var names []string
+		for _, repo := range pages.Items() {
+			names = append(names, repo.Name)
+		}
+		fmt.Printf("   page %d: %v\n", pages.Page(), names)
+	}
+	fmt.Printf("   done, err=%v\n", pages.Err())
+
+	fmt.Println("✂️  Collect the first 4:")
+	first, err := gc.RepoPages("octocat", 3).Collect(ctx, 4)
+	fmt.Printf("   %d repos, last %s (err=%v)\n", len(first), first[len(first)-1].Name, err)
+
+	fmt.Println("🔢 Offset paging:")
+	events := NewPaginator[int](NewHTTPClient(srv.URL), "/events", PageOptions{PerPage: 2, PerPageParam: "limit", OffsetParam: "offset"})
+	all, err := events.Collect(ctx, 0)
+	fmt.Printf("   events %v over %d pages (err=%v)\n", all, events.Page(), err)
+
+	fmt.Println("🧭 Cursor paging:")
+	type feedPage struct {
+		Entries    []string `json:"entries"`
+		NextCursor string   `json:"next_cursor"`
+	}
+	feed := NewPaginatorFunc(NewHTTPClient(srv.URL), "/feed", PageOptions{CursorParam: "cursor"},
+		func(page feedPage) ([]string, string) { return page.Entries, page.NextCursor })
+	entries, err := feed.Collect(ctx, 0)
+	fmt.Printf("   entries %v (err=%v)\n", entries, err)
+}
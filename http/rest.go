@@ -0,0 +1,312 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// endpoint is a parsed func field of a struct passed to Bind
+type endpoint struct {
+	name   string
+	method string
+	path   string
+	hasCtx bool
+	// argument roles by position, not counting ctx
+	roles    []argRole
+	hasValue bool // returns (T, error) rather than error
+}
+
+type argRole struct {
+	name string
+	kind string // "path", "query" or "body"
+}
+
+// Bind implements the func fields of the struct endpoints points to as
+// HTTP calls through c, so a REST API is declared rather than wrapped by
+// hand. Go cannot create methods at runtime, so an interface is satisfied
+// by a struct whose methods call these fields, as with reflect.MakeProxy.
+//
+// Each field is tagged with the request it makes:
+//
+//	GetRepo func(ctx context.Context, owner, repo string) (*Repo, error) `method:"GET" path:"/repos/{owner}/{repo}" args:"owner,repo"`
+//	Search  func(q string, page int) (*Results, error)                   `method:"GET" path:"/search" args:"q,page" query:"q,page"`
+//	Create  func(ctx context.Context, issue Issue) (*Issue, error)       `method:"POST" path:"/issues" args:"issue" body:"issue"`
+//
+// args names the parameters after an optional leading context.Context.
+// Each name is filled into the path, sent in the query (omitted when zero
+// or a nil pointer; slices repeat the key) or encoded with the client's
+// codec as the body. Functions return error or (T, error); T is decoded
+// from the response and other statuses of 400 or above are an *APIError.
+// Fields without a method tag are left alone.
+func (c *HTTPClient) Bind(endpoints any) error {
+	v := reflect.ValueOf(endpoints)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: endpoints must be a pointer to a struct, got %T", endpoints)
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if _, ok := field.Tag.Lookup("method"); !ok {
+			continue
+		}
+		ep, err := parseEndpoint(field)
+		if err != nil {
+			return fmt.Errorf("bind: %s: %w", field.Name, err)
+		}
+		v.Field(i).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			return c.call(ep, field.Type, args)
+		}))
+	}
+	return nil
+}
+
+func parseEndpoint(field reflect.StructField) (*endpoint, error) {
+	fnType := field.Type
+	if fnType.Kind() != reflect.Func || !field.IsExported() {
+		return nil, errors.New("must be an exported func field")
+	}
+	ep := &endpoint{
+		name:   field.Name,
+		method: strings.ToUpper(field.Tag.Get("method")),
+		path:   field.Tag.Get("path"),
+	}
+	if ep.path == "" {
+		return nil, errors.New("missing path tag")
+	}
+
+	switch {
+	case fnType.NumOut() == 1 && fnType.Out(0) == errorType:
+	case fnType.NumOut() == 2 && fnType.Out(1) == errorType:
+		ep.hasValue = true
+	default:
+		return nil, errors.New("must return error or (T, error)")
+	}
+
+	first := 0
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		ep.hasCtx, first = true, 1
+	}
+	var names []string
+	if tag := field.Tag.Get("args"); tag != "" {
+		names = strings.Split(tag, ",")
+	}
+	if len(names) != fnType.NumIn()-first {
+		return nil, fmt.Errorf("args tag names %d parameters, func has %d", len(names), fnType.NumIn()-first)
+	}
+
+	query := tagSet(field.Tag.Get("query"))
+	body := field.Tag.Get("body")
+	for _, name := range names {
+		role := argRole{name: strings.TrimSpace(name)}
+		switch {
+		case strings.Contains(ep.path, "{"+role.name+"}"):
+			role.kind = "path"
+		case query[role.name]:
+			role.kind = "query"
+		case role.name == body:
+			role.kind = "body"
+		default:
+			return nil, fmt.Errorf("parameter %q is not in the path, query or body", role.name)
+		}
+		ep.roles = append(ep.roles, role)
+	}
+	return ep, nil
+}
+
+func tagSet(tag string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// call performs one request for a bound func and builds its results
+func (c *HTTPClient) call(ep *endpoint, fnType reflect.Type, args []reflect.Value) []reflect.Value {
+	ctx := context.Background()
+	if ep.hasCtx {
+		if arg := args[0]; !arg.IsNil() {
+			ctx = arg.Interface().(context.Context)
+		}
+		args = args[1:]
+	}
+
+	results := func(value reflect.Value, err error) []reflect.Value {
+		errValue := reflect.Zero(errorType)
+		if err != nil {
+			errValue = reflect.ValueOf(err)
+		}
+		if !ep.hasValue {
+			return []reflect.Value{errValue}
+		}
+		if !value.IsValid() {
+			value = reflect.Zero(fnType.Out(0))
+		}
+		return []reflect.Value{value, errValue}
+	}
+
+	path, query := ep.path, url.Values{}
+	var body any
+	for i, role := range ep.roles {
+		arg := args[i]
+		switch role.kind {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+role.name+"}", url.PathEscape(fmt.Sprint(arg.Interface())))
+		case "query":
+			addQuery(query, role.name, arg)
+		case "body":
+			body = arg.Interface()
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp *http.Response
+	var err error
+	if body != nil {
+		resp, err = c.requestWithBody(ctx, ep.method, path, body)
+	} else {
+		resp, err = c.request(ctx, ep.method, path, nil)
+	}
+	if err != nil {
+		return results(reflect.Value{}, fmt.Errorf("%s: %w", ep.name, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return results(reflect.Value{}, newAPIError(resp))
+	}
+	if !ep.hasValue {
+		return results(reflect.Value{}, nil)
+	}
+
+	// Decode into a new T, or the T a pointer result points to
+	out := fnType.Out(0)
+	target := reflect.New(out)
+	if out.Kind() == reflect.Ptr {
+		target.Elem().Set(reflect.New(out.Elem()))
+		target = target.Elem()
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return results(reflect.Value{}, nil)
+	}
+	if err := c.Decode(resp, target.Interface()); err != nil {
+		return results(reflect.Value{}, fmt.Errorf("%s: decoding response: %w", ep.name, err))
+	}
+	if out.Kind() == reflect.Ptr {
+		return results(target, nil)
+	}
+	return results(target.Elem(), nil)
+}
+
+// addQuery adds a non-zero argument. A non-nil pointer is always sent, so
+// false or 0 can be asked for; slices add one value per element.
+func addQuery(query url.Values, name string, arg reflect.Value) {
+	if arg.Kind() == reflect.Ptr {
+		if !arg.IsNil() {
+			query.Set(name, fmt.Sprint(arg.Elem().Interface()))
+		}
+		return
+	}
+	if arg.Kind() == reflect.Slice {
+		for i := 0; i < arg.Len(); i++ {
+			query.Add(name, fmt.Sprint(arg.Index(i).Interface()))
+		}
+		return
+	}
+	if !arg.IsZero() {
+		query.Set(name, fmt.Sprint(arg.Interface()))
+	}
+}
+
+// todoAPI is a declarative client for the example server below
+type todoAPI struct {
+	List   func(ctx context.Context, done *bool, tags []string) ([]todo, error) `method:"GET" path:"/todos" args:"done,tag" query:"done,tag"`
+	Get    func(ctx context.Context, id int) (*todo, error)                     `method:"GET" path:"/todos/{id}" args:"id"`
+	Create func(ctx context.Context, t todo) (*todo, error)                     `method:"POST" path:"/todos" args:"todo" body:"todo"`
+	Delete func(ctx context.Context, id int) error                              `method:"DELETE" path:"/todos/{id}" args:"id"`
+}
+
+type todo struct {
+	ID    int      `json:"id"`
+	Title string   `json:"title"`
+	Done  bool     `json:"done"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// ExampleRESTClient declares a client for a small todo API and calls it
+func ExampleRESTClient() {
+	todos := []todo{{1, "Write docs", true, []string{"work"}}, {2, "Buy milk", false, []string{"home"}}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/todos":
+			fmt.Printf("   server saw: %s %s\n", r.Method, r.URL.RequestURI())
+			var matches []todo
+			for _, t := range todos {
+				if d := r.URL.Query().Get("done"); d == "" || d == fmt.Sprint(t.Done) {
+					matches = append(matches, t)
+				}
+			}
+			json.NewEncoder(w).Encode(matches)
+		case r.Method == "POST" && r.URL.Path == "/todos":
+			var t todo
+			json.NewDecoder(r.Body).Decode(&t)
+			t.ID = len(todos) + 1
+			todos = append(todos, t)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(t)
+		case r.Method == "GET" && r.URL.Path == "/todos/1":
+			json.NewEncoder(w).Encode(todos[0])
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "no such todo"}`)
+		}
+	}))
+	defer srv.Close()
+
+	var api todoAPI
+	if err := NewHTTPClient(srv.URL).Bind(&api); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	ctx := context.Background()
+
+	open := false
+	list, err := api.List(ctx, &open, []string{"home", "errands"})
+	fmt.Printf("📋 open todos: %s (err=%v)\n", format.Dump(list, format.DefaultDumpOptions), err)
+
+	created, err := api.Create(ctx, todo{Title: "Call mom", Tags: []string{"home"}})
+	fmt.Printf("➕ created: %s (err=%v)\n", format.Dump(*created, format.DefaultDumpOptions), err)
+
+	first, _ := api.Get(ctx, 1)
+	fmt.Printf("🔎 todo 1: %q done=%v\n", first.Title, first.Done)
+
+	_, err = api.Get(ctx, 99)
+	fmt.Printf("❓ todo 99: %v (not found: %v)\n", err, NotFound(err))
+	fmt.Printf("🗑️  delete: %v\n", api.Delete(ctx, 2))
+
+	var broken struct {
+		Get func(id int) (*todo, error) `method:"GET" path:"/todos/{id}" args:"todoID"`
+	}
+	fmt.Printf("🚫 %v\n", NewHTTPClient(srv.URL).Bind(&broken))
+}
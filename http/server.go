@@ -3,12 +3,15 @@ package http
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
 )
 
+var logger = format.GetLogger("http")
+
 type User struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
@@ -22,6 +25,10 @@ type Response struct {
 	Data    any    `json:"data,omitempty"`
 }
 
+// SigningSecret is the HMAC key for /api/signed/ requests on the demo
+// server, shared with clients signing through SigningInterceptor
+var SigningSecret = []byte("demo-signing-secret")
+
 var users = []User{
 	{ID: 1, Name: "John Doe", Email: "john@example.com", CreateAt: "2024-01-01"},
 	{ID: 2, Name: "Jane Smith", Email: "jane@example.com", CreateAt: "2024-01-02"},
@@ -38,8 +45,9 @@ func StartServer(port string) {
 	mux.HandleFunc("/users/", userHandler)
 	mux.HandleFunc("/api/users", apiUsersHandler)
 	mux.HandleFunc("/api/users/", apiUserHandler)
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
-	handler := loggingMiddleware(corsMiddleware(mux))
+	mux.Handle("/api/signed/users", VerifySignatures(NewHMACSigner("demo", SigningSecret))(http.HandlerFunc(apiUsersHandler)))
+	mux.Handle("/static/", http.StripPrefix("/static/", StaticFiles("./static/")))
+	handler := loggingMiddleware(corsMiddleware(ConditionalResponses(mux)))
 
 	server := &http.Server{
 		Addr:         ":" + port,
@@ -58,8 +66,11 @@ func StartServer(port string) {
 	fmt.Printf("   GET  /users/{id} - Get user by ID\n")
 	fmt.Printf("   GET  /api/users  - API: List all users (JSON)\n")
 	fmt.Printf("   GET  /api/users/{id} - API: Get user by ID (JSON)\n")
+	fmt.Printf("   GET  /api/signed/users - API: List users, HMAC-signed requests only\n")
+	fmt.Printf("   GET  /static/... - Static files\n")
+	fmt.Printf("🏷️  JSON and static responses carry ETags; If-None-Match and If-Modified-Since get 304\n")
 
-	log.Fatal(server.ListenAndServe())
+	logger.Fatal("server stopped", "error", server.ListenAndServe())
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request) {
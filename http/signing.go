@@ -0,0 +1,459 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned by verifiers for requests that are
+// unsigned, signed with another key, altered, or signed too long ago
+var ErrInvalidSignature = errors.New("http: invalid request signature")
+
+// maxSignedBody caps the body read into memory to be hashed
+const maxSignedBody = 10 << 20
+
+const (
+	hmacAlgorithm  = "HMAC-SHA256"
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	signDateFormat = "20060102T150405Z"
+)
+
+// RequestSigner adds authentication headers to an outgoing request
+type RequestSigner interface {
+	Sign(req *http.Request) error
+}
+
+// RequestVerifier checks the signature of an incoming request
+type RequestVerifier interface {
+	Verify(req *http.Request) error
+}
+
+// SigningInterceptor signs every request the client sends:
+//
+//	client.Use(SigningInterceptor(NewHMACSigner("app", secret)))
+func SigningInterceptor(signer RequestSigner) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// A RoundTripper must not change the caller's request
+			signed := req.Clone(req.Context())
+			if err := signer.Sign(signed); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, fmt.Errorf("signing request: %w", err)
+			}
+			return next.RoundTrip(signed)
+		})
+	}
+}
+
+// VerifySignatures rejects requests whose signature verifier does not
+// accept with 401 Unauthorized
+func VerifySignatures(verifier RequestVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := verifier.Verify(r); err != nil {
+				logger.Warn("Rejected request signature", "method", r.Method, "path", r.URL.Path, "error", err)
+				http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HMACSigner signs requests with a secret shared by client and server. The
+// signature covers the method, path, query, host, date, content type and a
+// SHA-256 of the body:
+//
+//	X-Date: 20240101T120000Z
+//	X-Content-Sha256: <hex>
+//	Authorization: HMAC-SHA256 KeyId=app, SignedHeaders=host;x-content-sha256;x-date, Signature=<hex>
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+	// MaxSkew is how far X-Date may be from the verifier's clock.
+	// Defaults to five minutes.
+	MaxSkew time.Duration
+}
+
+// NewHMACSigner signs with secret, naming it keyID so servers can hold
+// several keys
+func NewHMACSigner(keyID string, secret []byte) *HMACSigner {
+	return &HMACSigner{KeyID: keyID, Secret: secret}
+}
+
+func (s *HMACSigner) Sign(req *http.Request) error {
+	return s.signAt(req, time.Now())
+}
+
+func (s *HMACSigner) signAt(req *http.Request, now time.Time) error {
+	payloadHash, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Date", now.UTC().Format(signDateFormat))
+	req.Header.Set("X-Content-Sha256", payloadHash)
+
+	signed := signedHeaderNames(req, "x-content-sha256", "x-date")
+	canonical := canonicalRequest(req, req.URL.EscapedPath(), signed, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s, SignedHeaders=%s, Signature=%s",
+		hmacAlgorithm, s.KeyID, strings.Join(signed, ";"), s.signature(canonical)))
+	return nil
+}
+
+func (s *HMACSigner) Verify(req *http.Request) error {
+	auth, err := parseAuthorization(req, hmacAlgorithm)
+	if err != nil {
+		return err
+	}
+	if auth["KeyId"] != s.KeyID {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, auth["KeyId"])
+	}
+	if err := checkSignedAt(req.Header.Get("X-Date"), s.MaxSkew); err != nil {
+		return err
+	}
+	signed, err := requireSigned(auth["SignedHeaders"], "host", "x-content-sha256", "x-date")
+	if err != nil {
+		return err
+	}
+	payloadHash, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+	if req.Header.Get("X-Content-Sha256") != payloadHash {
+		return fmt.Errorf("%w: body does not match X-Content-Sha256", ErrInvalidSignature)
+	}
+
+	canonical := canonicalRequest(req, req.URL.EscapedPath(), signed, payloadHash)
+	if !hmac.Equal([]byte(auth["Signature"]), []byte(s.signature(canonical))) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
+
+func (s *HMACSigner) signature(canonical string) string {
+	return hex.EncodeToString(hmacSHA256(s.Secret, hmacAlgorithm+"\n"+canonical))
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4, so the client
+// can call AWS-compatible APIs and the server can accept their clients
+type SigV4Signer struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+	// MaxSkew is how far X-Amz-Date may be from the verifier's clock.
+	// Defaults to five minutes, as AWS allows.
+	MaxSkew time.Duration
+}
+
+// NewSigV4Signer signs for service, such as "s3" or "execute-api", in region
+func NewSigV4Signer(accessKey, secretKey, region, service string) *SigV4Signer {
+	return &SigV4Signer{AccessKey: accessKey, SecretKey: secretKey, Region: region, Service: service}
+}
+
+func (s *SigV4Signer) Sign(req *http.Request) error {
+	return s.signAt(req, time.Now())
+}
+
+func (s *SigV4Signer) signAt(req *http.Request, now time.Time) error {
+	payloadHash, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Date", now.UTC().Format(signDateFormat))
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	signed := signedHeaderNames(req)
+	scope := s.scope(now)
+	canonical := canonicalRequest(req, s.canonicalURI(req.URL), signed, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKey, scope, strings.Join(signed, ";"), s.signature(now, scope, canonical)))
+	return nil
+}
+
+func (s *SigV4Signer) Verify(req *http.Request) error {
+	auth, err := parseAuthorization(req, sigV4Algorithm)
+	if err != nil {
+		return err
+	}
+	signedAt, err := time.Parse(signDateFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("%w: bad X-Amz-Date", ErrInvalidSignature)
+	}
+	if err := checkSignedAt(req.Header.Get("X-Amz-Date"), s.MaxSkew); err != nil {
+		return err
+	}
+	scope := s.scope(signedAt)
+	if auth["Credential"] != s.AccessKey+"/"+scope {
+		return fmt.Errorf("%w: unknown credential %q", ErrInvalidSignature, auth["Credential"])
+	}
+	signed, err := requireSigned(auth["SignedHeaders"], "host", "x-amz-date")
+	if err != nil {
+		return err
+	}
+	payloadHash, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+	if claimed := req.Header.Get("X-Amz-Content-Sha256"); claimed != "" && claimed != payloadHash {
+		return fmt.Errorf("%w: body does not match X-Amz-Content-Sha256", ErrInvalidSignature)
+	}
+
+	canonical := canonicalRequest(req, s.canonicalURI(req.URL), signed, payloadHash)
+	if !hmac.Equal([]byte(auth["Signature"]), []byte(s.signature(signedAt, scope, canonical))) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// canonicalURI encodes the path once for S3 and twice for other services,
+// as SigV4 requires
+func (s *SigV4Signer) canonicalURI(u *url.URL) string {
+	if s.Service == "s3" {
+		return uriEncode(u.Path, false)
+	}
+	return uriEncode(u.EscapedPath(), false)
+}
+
+func (s *SigV4Signer) scope(t time.Time) string {
+	return t.UTC().Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+func (s *SigV4Signer) signature(t time.Time, scope, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := sigV4Algorithm + "\n" + t.UTC().Format(signDateFormat) + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest is the string both schemes sign: method, path, sorted
+// query, the signed headers and their names, and the body hash, one per
+// line
+func canonicalRequest(req *http.Request, uri string, signed []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		headers.WriteString(name + ":" + strings.Join(strings.Fields(headerValue(req, name)), " ") + "\n")
+	}
+	if uri == "" {
+		uri = "/"
+	}
+	return strings.Join([]string{
+		req.Method, uri, canonicalQuery(req.URL.Query()), headers.String(), strings.Join(signed, ";"), payloadHash,
+	}, "\n")
+}
+
+// signedHeaderNames lists the headers to sign, sorted: host, content-type
+// when set, any x-amz-* headers, and extra
+func signedHeaderNames(req *http.Request, extra ...string) []string {
+	names := append([]string{"host"}, extra...)
+	if req.Header.Get("Content-Type") != "" {
+		names = append(names, "content-type")
+	}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func headerValue(req *http.Request, name string) string {
+	if name == "host" {
+		if req.Host != "" {
+			return req.Host
+		}
+		return req.URL.Host
+	}
+	return req.Header.Get(name)
+}
+
+// hashBody returns the hex SHA-256 of the body, leaving the body in place
+// to be sent or handled
+func hashBody(req *http.Request) (string, error) {
+	var data []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		data, err = io.ReadAll(io.LimitReader(req.Body, maxSignedBody+1))
+		req.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("reading body: %w", err)
+		}
+		if len(data) > maxSignedBody {
+			return "", fmt.Errorf("body over %d bytes is too large to sign", maxSignedBody)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// parseAuthorization splits "<scheme> K=V, K=V" from the Authorization
+// header
+func parseAuthorization(req *http.Request, scheme string) (map[string]string, error) {
+	rest, ok := strings.CutPrefix(req.Header.Get("Authorization"), scheme+" ")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s authorization", ErrInvalidSignature, scheme)
+	}
+	params := map[string]string{}
+	for _, part := range strings.Split(rest, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			params[key] = value
+		}
+	}
+	return params, nil
+}
+
+// requireSigned parses a SignedHeaders list and checks it covers required
+func requireSigned(list string, required ...string) ([]string, error) {
+	signed := strings.Split(list, ";")
+	for _, name := range required {
+		if !slices.Contains(signed, name) {
+			return nil, fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, name)
+		}
+	}
+	return signed, nil
+}
+
+// checkSignedAt rejects signing times further than maxSkew from now
+func checkSignedAt(date string, maxSkew time.Duration) error {
+	signedAt, err := time.Parse(signDateFormat, date)
+	if err != nil {
+		return fmt.Errorf("%w: bad signing date %q", ErrInvalidSignature, date)
+	}
+	if maxSkew == 0 {
+		maxSkew = 5 * time.Minute
+	}
+	if skew := time.Since(signedAt); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("%w: signed %s ago", ErrInvalidSignature, skew.Round(time.Second))
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set, as SigV4 requires
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes values sorted by key, then by value
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := slices.Clone(values[k])
+		slices.Sort(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// ExampleRequestSigning signs client requests with HMAC and SigV4 and
+// verifies them in server middleware
+func ExampleRequestSigning() {
+	secret := []byte("demo-shared-secret")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "accepted %s %s %s", r.Method, r.URL.RequestURI(), body)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/hmac/", VerifySignatures(NewHMACSigner("demo", secret))(handler))
+	mux.Handle("/sigv4/", VerifySignatures(NewSigV4Signer("AKIDEXAMPLE", "demo-secret-key", "us-east-1", "execute-api"))(handler))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	call := func(label string, client *HTTPClient, path string, body any) {
+		var resp *http.Response
+		var err error
+		if body != nil {
+			resp, err = client.Post(path, body)
+		} else {
+			resp, err = client.Get(path)
+		}
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", label, err)
+			return
+		}
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(resp.Body)
+		mark := "✅"
+		if resp.StatusCode != http.StatusOK {
+			mark = "🚫"
+		}
+		fmt.Printf("%s %s: %d %s\n", mark, label, resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+
+	signed := NewHTTPClient(srv.URL)
+	signed.Use(SigningInterceptor(NewHMACSigner("demo", secret)))
+	call("HMAC signed GET", signed, "/hmac/orders?status=open&page=2", nil)
+	call("HMAC signed POST", signed, "/hmac/orders", map[string]int{"quantity": 3})
+
+	call("unsigned GET", NewHTTPClient(srv.URL), "/hmac/orders", nil)
+
+	wrongKey := NewHTTPClient(srv.URL)
+	wrongKey.Use(SigningInterceptor(NewHMACSigner("demo", []byte("guessed-secret"))))
+	call("wrong secret", wrongKey, "/hmac/orders", nil)
+
+	// Change the body after signing, as a proxy tampering with it might
+	tampered := NewHTTPClient(srv.URL)
+	tampered.Use(SigningInterceptor(NewHMACSigner("demo", secret)), func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Body = io.NopCloser(strings.NewReader(`{"quantity":300}`))
+			req.ContentLength = int64(len(`{"quantity":300}`))
+			return next.RoundTrip(req)
+		})
+	})
+	call("tampered body", tampered, "/hmac/orders", map[string]int{"quantity": 3})
+
+	sigv4 := NewHTTPClient(srv.URL)
+	sigv4.Use(SigningInterceptor(NewSigV4Signer("AKIDEXAMPLE", "demo-secret-key", "us-east-1", "execute-api")))
+	call("SigV4 signed POST", sigv4, "/sigv4/invoke?stage=prod", map[string]string{"action": "deploy"})
+
+	// The signer works on any request, not only through the interceptor
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1/items?b=2&a=1", nil)
+	NewHMACSigner("demo", secret).signAt(req, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	fmt.Printf("🔏 %s\n", req.Header.Get("Authorization"))
+}
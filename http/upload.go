@@ -0,0 +1,318 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// UploadedFile is a file stored by the demo server's upload endpoints
+type UploadedFile struct {
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	Duplicate   bool   `json:"duplicate"`
+	URL         string `json:"url,omitempty"`
+}
+
+// UploadOptions configures Upload and UploadResumable
+type UploadOptions struct {
+	ContentType string
+	// Progress is called as bytes are sent, with the total when known
+	// (-1 otherwise)
+	Progress func(sent, total int64)
+
+	// ChunkSize is the size of each resumable PATCH. Defaults to 1 MiB.
+	ChunkSize int64
+	// Retries is how many failed chunks UploadResumable retries, asking
+	// the server where to continue each time. Defaults to 3.
+	Retries int
+	// SessionURL continues an earlier resumable upload, such as one
+	// reported to OnSession before the program was restarted
+	SessionURL string
+	// OnSession is told the URL of a new resumable upload
+	OnSession func(url string)
+}
+
+// Upload posts one file as multipart/form-data to path, such as
+// "/api/upload", streaming it rather than buffering it. When r can seek,
+// its SHA-256 is sent first in a "sha256" field so the server can check
+// what it received.
+func (c *HTTPClient) Upload(ctx context.Context, path, filename string, r io.Reader, opts UploadOptions) (*UploadedFile, error) {
+	total := int64(-1)
+	var checksum string
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		sum, size, err := hashFrom(seeker, 0)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", filename, err)
+		}
+		checksum, total = sum, size
+	}
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUploadForm(form, filename, checksum, opts.ContentType, &progressReader{r: r, total: total, report: opts.Progress}))
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("upload %s: %w", filename, newAPIError(resp))
+	}
+
+	var result struct {
+		Data []UploadedFile `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || len(result.Data) == 0 {
+		return nil, fmt.Errorf("upload %s: unexpected response", filename)
+	}
+	return &result.Data[0], nil
+}
+
+func writeUploadForm(form *multipart.Writer, filename, checksum, contentType string, r io.Reader) error {
+	if checksum != "" {
+		if err := form.WriteField("sha256", checksum); err != nil {
+			return err
+		}
+	}
+	header := make(map[string][]string)
+	header["Content-Disposition"] = []string{fmt.Sprintf(`form-data; name="file"; filename=%q`, filename)}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header["Content-Type"] = []string{contentType}
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// UploadResumable sends the first size bytes of r in chunks to a tus-style
+// upload endpoint at path, such as "/api/upload/sessions". A chunk that
+// fails is retried from the offset the server reports, and a new call with
+// opts.SessionURL picks up an upload where an earlier one stopped.
+func (c *HTTPClient) UploadResumable(ctx context.Context, path, filename string, r io.ReadSeeker, size int64, opts UploadOptions) (*UploadedFile, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1 << 20
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	session := opts.SessionURL
+	if session == "" {
+		var err error
+		if session, err = c.startUpload(ctx, path, filename, r, size, opts.ContentType); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", filename, err)
+		}
+		if opts.OnSession != nil {
+			opts.OnSession(session)
+		}
+	}
+
+	offset, err := c.uploadOffset(ctx, session)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", filename, err)
+	}
+	chunk := make([]byte, opts.ChunkSize)
+	for failures := 0; ; {
+		if opts.Progress != nil {
+			opts.Progress(offset, size)
+		}
+		n, err := readChunk(r, offset, chunk)
+		if err == nil && n == 0 {
+			err = fmt.Errorf("input ends at %d of %d bytes", offset, size)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", filename, err)
+		}
+
+		next, file, err := c.sendChunk(ctx, session, offset, chunk[:n])
+		if file != nil {
+			if opts.Progress != nil {
+				opts.Progress(size, size)
+			}
+			return file, nil
+		}
+		if err == nil && next > offset {
+			offset = next
+			continue
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode != http.StatusConflict && apiErr.StatusCode < 460 {
+			return nil, fmt.Errorf("upload %s: %w", filename, err) // a retry would fail the same way
+		}
+		if failures++; failures > opts.Retries {
+			return nil, fmt.Errorf("upload %s: giving up after %d failed chunks: %w", filename, failures, err)
+		}
+		logger.Warn("Upload chunk failed, resuming", "file", filename, "offset", offset, "error", err)
+		if offset, err = c.uploadOffset(ctx, session); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", filename, err)
+		}
+	}
+}
+
+// startUpload creates a resumable upload and returns its URL
+func (c *HTTPClient) startUpload(ctx context.Context, path, filename string, r io.ReadSeeker, size int64, contentType string) (string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sum, _, err := hashFrom(r, size)
+	if err != nil {
+		return "", err
+	}
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte(filename)) +
+		",sha256 " + base64.StdEncoding.EncodeToString([]byte(sum))
+	if contentType != "" {
+		metadata += ",filetype " + base64.StdEncoding.EncodeToString([]byte(contentType))
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	req.Header.Set("Upload-Metadata", metadata)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", newAPIError(resp)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("no upload location: %w", err)
+	}
+	return location.String(), nil
+}
+
+// uploadOffset asks the server how much of an upload it has
+func (c *HTTPClient) uploadOffset(ctx context.Context, session string) (int64, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, session, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, &APIError{Method: req.Method, URL: session, StatusCode: resp.StatusCode}
+	}
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
+
+// sendChunk PATCHes data at offset, returning the new offset, or the
+// stored file once the last chunk is in
+func (c *HTTPClient) sendChunk(ctx context.Context, session string, offset int64, data []byte) (int64, *UploadedFile, error) {
+	req, err := c.newRequest(ctx, http.MethodPatch, session, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	sum := sha256.Sum256(data)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	req.Header.Set("Upload-Checksum", "sha256 "+base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+		return next, nil, err
+	case http.StatusOK:
+		var result struct {
+			Data UploadedFile `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return 0, nil, fmt.Errorf("decoding upload result: %w", err)
+		}
+		return 0, &result.Data, nil
+	default:
+		return 0, nil, newAPIError(resp)
+	}
+}
+
+// hashFrom returns the hex SHA-256 and length of r from its current
+// position, or of its first size bytes when size is set, then seeks back
+func hashFrom(r io.ReadSeeker, size int64) (string, int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, err
+	}
+	var src io.Reader = r
+	if size > 0 {
+		src = io.LimitReader(r, size)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(hash, src)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+// readChunk fills chunk from r at offset, stopping short at the end
+func readChunk(r io.ReadSeeker, offset int64, chunk []byte) (int, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r, chunk)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// progressReader reports bytes read to report
+type progressReader struct {
+	r      io.Reader
+	sent   int64
+	total  int64
+	report func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	if p.report != nil && (n > 0 || err == io.EOF) {
+		p.report(p.sent, p.total)
+	}
+	return n, err
+}
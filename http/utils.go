@@ -1,12 +1,17 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/format"
 )
 
 type ResponseData struct {
@@ -22,6 +27,21 @@ type RequestOptions struct {
 	Headers map[string]string
 	Body    any
 	Timeout time.Duration
+	// DisableCompression asks for an uncompressed response; by default
+	// gzip and deflate responses are decompressed transparently
+	DisableCompression bool
+	// CompressBodyOver gzips request bodies of at least this many bytes,
+	// for servers that accept Content-Encoding: gzip; 0 never compresses
+	CompressBodyOver int
+	// Balancer spreads requests across all addresses of the host and fails
+	// over between them; the default transport is used if nil
+	Balancer *BalancingDialer
+	// Strict makes non-2xx responses an error: MakeRequest returns the
+	// response along with an *APIError describing it
+	Strict bool
+	// ErrorFields locates the message and code in JSON error bodies for
+	// Strict requests, DefaultErrorFields if nil
+	ErrorFields *ErrorFields
 }
 
 func MakeRequest(options RequestOptions) (*ResponseData, error) {
@@ -29,7 +49,14 @@ func MakeRequest(options RequestOptions) (*ResponseData, error) {
 		options.Timeout = 10 * time.Second
 	}
 
-	client := &http.Client{Timeout: options.Timeout}
+	transport := &CompressionTransport{CompressionOptions: CompressionOptions{
+		DisableDecompression: options.DisableCompression,
+		CompressRequestsOver: options.CompressBodyOver,
+	}}
+	if options.Balancer != nil {
+		transport.Base = options.Balancer.Transport()
+	}
+	client := &http.Client{Timeout: options.Timeout, Transport: transport}
 	start := time.Now()
 	var bodyReader io.Reader
 	if options.Body != nil {
@@ -69,12 +96,20 @@ func MakeRequest(options RequestOptions) (*ResponseData, error) {
 
 	duration := time.Since(start)
 
-	return &ResponseData{
+	data := &ResponseData{
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
 		Body:       string(bodyBytes),
 		Duration:   duration,
-	}, nil
+	}
+	if options.Strict && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		fields := DefaultErrorFields
+		if options.ErrorFields != nil {
+			fields = *options.ErrorFields
+		}
+		return data, parseAPIError(req.Method, req.URL.Redacted(), resp.StatusCode, resp.Header, bodyBytes, fields)
+	}
+	return data, nil
 }
 
 func GetJSON(url string, target any) error {
@@ -84,6 +119,7 @@ func GetJSON(url string, target any) error {
 		Headers: map[string]string{
 			"Accept": "application/json",
 		},
+		Strict: true,
 	}
 
 	resp, err := MakeRequest(options)
@@ -91,10 +127,6 @@ func GetJSON(url string, target any) error {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status: %d", resp.StatusCode)
-	}
-
 	return json.Unmarshal([]byte(resp.Body), target)
 }
 
@@ -106,7 +138,8 @@ func PostJSON(url string, body, target any) error {
 			"Content-Type": "application/json",
 			"Accept":       "application/json",
 		},
-		Body: body,
+		Body:   body,
+		Strict: true,
 	}
 
 	resp, err := MakeRequest(options)
@@ -114,10 +147,6 @@ func PostJSON(url string, body, target any) error {
 		return err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("request failed with status: %d", resp.StatusCode)
-	}
-
 	if target != nil {
 		return json.Unmarshal([]byte(resp.Body), target)
 	}
@@ -128,15 +157,12 @@ func DownloadFileWithOptions(url string) ([]byte, error) {
 	options := RequestOptions{
 		Method: "GET",
 		URL:    url,
+		Strict: true,
 	}
 
 	resp, err := MakeRequest(options)
 	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("download failed: %w", err)
 	}
 
 	return []byte(resp.Body), nil
@@ -157,41 +183,25 @@ func CheckURLStatusWithOptions(url string) (int, error) {
 	return resp.StatusCode, nil
 }
 
-func BatchRequest(requests []RequestOptions) ([]*ResponseData, error) {
-	type result struct {
-		index int
-		data  *ResponseData
-		err   error
-	}
-
-	results := make(chan result, len(requests))
+// batchConcurrency caps how many BatchRequest requests are in flight
+const batchConcurrency = 8
 
-	for i, req := range requests {
-		go func(index int, request RequestOptions) {
-			data, err := MakeRequest(request)
-			results <- result{index: index, data: data, err: err}
-		}(i, req)
-	}
-
-	responses := make([]*ResponseData, len(requests))
-	var errors []error
-
-	for i := 0; i < len(requests); i++ {
-		res := <-results
-		if res.err != nil {
-			errors = append(errors, fmt.Errorf("request %d failed: %w", res.index, res.err))
-		} else {
-			responses[res.index] = res.data
-		}
-	}
-
-	if len(errors) > 0 {
-		return responses, fmt.Errorf("batch request had %d errors: %v", len(errors), errors)
+// BatchRequest makes the requests concurrently. responses[i] answers
+// requests[i] and is nil if that request failed.
+func BatchRequest(requests []RequestOptions) ([]*ResponseData, error) {
+	responses, err := concurrency.ParallelMap(context.Background(), requests,
+		func(_ context.Context, request RequestOptions) (*ResponseData, error) {
+			return MakeRequest(request)
+		}, batchConcurrency)
+	if err != nil {
+		return responses, fmt.Errorf("batch request: %w", err)
 	}
-
 	return responses, nil
 }
 
+// RetryRequest retries failed requests with exponential backoff. With
+// options.Strict, error statuses are retried only if retryable, waiting
+// at least as long as the server's Retry-After.
 func RetryRequest(options RequestOptions, maxRetries int, delay time.Duration) (*ResponseData, error) {
 	var lastErr error
 
@@ -200,10 +210,18 @@ func RetryRequest(options RequestOptions, maxRetries int, delay time.Duration) (
 		if err == nil {
 			return resp, nil
 		}
+		if !IsRetryable(err) {
+			return resp, err
+		}
 
 		lastErr = err
 		if i < maxRetries {
-			time.Sleep(delay)
+			wait := delay
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+				wait = apiErr.RetryAfter
+			}
+			time.Sleep(wait)
 			delay *= 2
 		}
 	}
@@ -232,6 +250,10 @@ func FormatDuration(d time.Duration) string {
 func PrintResponse(resp *ResponseData) {
 	fmt.Printf("Status: %d\n", resp.StatusCode)
 	fmt.Printf("Duration: %s\n", FormatDuration(resp.Duration))
+	fmt.Printf("Size: %s (%s bytes)\n", format.HumanizeBytes(int64(len(resp.Body))), format.Comma(int64(len(resp.Body))))
+	if date, err := http.ParseTime(strings.Join(resp.Headers["Date"], "")); err == nil {
+		fmt.Printf("Server date: %s\n", format.RelTime(date))
+	}
 	fmt.Printf("Headers:\n")
 	for key, values := range resp.Headers {
 		fmt.Printf("  %s: %s\n", key, strings.Join(values, ", "))
@@ -280,9 +302,9 @@ func ExampleHTTPUtils() {
 
 	fmt.Println("\n3. Batch requests:")
 	batchOptions := []RequestOptions{
-		{Method: "GET", URL: "https://httpbin.org/get"},
-		{Method: "GET", URL: "https://httpbin.org/headers"},
-		{Method: "GET", URL: "https://httpbin.org/user-agent"},
+		{Method: "GET", URL: "https://httpbin.org/get", Balancer: DefaultBalancer},
+		{Method: "GET", URL: "https://httpbin.org/headers", Balancer: DefaultBalancer},
+		{Method: "GET", URL: "https://httpbin.org/user-agent", Balancer: DefaultBalancer},
 	}
 
 	responses, err := BatchRequest(batchOptions)
@@ -298,8 +320,10 @@ func ExampleHTTPUtils() {
 
 	fmt.Println("\n4. Retry request (with invalid URL to demonstrate retry):")
 	retryOptions := RequestOptions{
-		Method: "GET",
-		URL:    "https://httpbin.org/status/500", // This will return 500 status
+		Method:   "GET",
+		URL:      "https://httpbin.org/status/500", // This will return 500 status
+		Balancer: DefaultBalancer,
+		Strict:   true, // so the 500 is an error worth retrying
 	}
 
 	resp, err = RetryRequest(retryOptions, 3, time.Second)
@@ -319,7 +343,7 @@ func ExampleJSONUtils() {
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("JSON data: %+v\n", jsonData)
+		fmt.Printf("JSON data: %s\n", format.Dump(jsonData, format.DefaultDumpOptions))
 	}
 
 	fmt.Println("\n2. Post JSON data:")
@@ -333,6 +357,6 @@ func ExampleJSONUtils() {
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Response received: %+v\n", response)
+		fmt.Printf("Response received: %s\n", format.Dump(response, format.DefaultDumpOptions))
 	}
 }
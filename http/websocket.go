@@ -0,0 +1,498 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket message types, the opcodes of RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Close codes sent by WebSocketConn
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseMessageTooBig = 1009
+)
+
+const (
+	// closeNoStatus stands for a close frame without a code
+	closeNoStatus       = 1005
+	websocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultMaxWSMessage = 1 << 20
+)
+
+// ErrWebSocketClosed is returned when writing to a connection after Close
+var ErrWebSocketClosed = errors.New("websocket: connection closed")
+
+// WebSocketCloseError is returned by ReadMessage when the peer closes the
+// connection
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketUpgrader turns HTTP requests into WebSocket connections
+type WebSocketUpgrader struct {
+	// CheckOrigin reports whether a browser page from the request's Origin
+	// may connect. By default the Origin's host must be the request's Host,
+	// so other sites cannot open connections with the user's cookies.
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize is the largest message ReadMessage accepts, 1MB if zero
+	MaxMessageSize int64
+}
+
+// UpgradeWebSocket upgrades r with the default WebSocketUpgrader
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	return (&WebSocketUpgrader{}).Upgrade(w, r)
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+// On failure it has already answered the request with an HTTP error.
+func (u *WebSocketUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	fail := func(status int, msg string) (*WebSocketConn, error) {
+		if status == http.StatusUpgradeRequired {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+		}
+		http.Error(w, msg, status)
+		return nil, fmt.Errorf("websocket: %s", strings.ToLower(msg))
+	}
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "Handshake must be a GET request")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "Not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fail(http.StatusUpgradeRequired, "Unsupported WebSocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "Invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return fail(http.StatusForbidden, "Origin not allowed")
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, "Connection cannot be upgraded")
+	}
+	// The server's read and write timeouts were meant for the request
+	conn.SetDeadline(time.Time{})
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: writing handshake: %w", err)
+	}
+	return newWebSocketConn(conn, brw.Reader, false, u.MaxMessageSize), nil
+}
+
+// sameOrigin allows requests without an Origin, which do not come from
+// browsers, and those whose Origin host matches Host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// DialWebSocket opens a client connection to a ws:// or wss:// URL,
+// sending header with the handshake
+func DialWebSocket(ctx context.Context, rawURL string, header http.Header) (*WebSocketConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	var useTLS bool
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme, useTLS = "https", true
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if useTLS {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("websocket: %w", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{}}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: writing handshake: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: reading handshake: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		conn.Close()
+		return nil, fmt.Errorf("websocket: handshake refused: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, errors.New("websocket: handshake refused: bad Sec-WebSocket-Accept")
+	}
+	return newWebSocketConn(conn, br, true, 0), nil
+}
+
+// WebSocketConn is one end of a WebSocket connection. One goroutine may
+// read while others write; writes are serialised. Pings are answered while
+// reading, so keep a reader running.
+type WebSocketConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	client  bool // clients mask the frames they send
+	maxSize int64
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+func newWebSocketConn(conn net.Conn, br *bufio.Reader, client bool, maxSize int64) *WebSocketConn {
+	if maxSize <= 0 {
+		maxSize = defaultMaxWSMessage
+	}
+	return &WebSocketConn{conn: conn, br: br, client: client, maxSize: maxSize}
+}
+
+// RemoteAddr returns the address of the peer
+func (c *WebSocketConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetReadDeadline bounds how long ReadMessage waits
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments. It answers pings and returns a *WebSocketCloseError once the
+// peer closes.
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			closeErr := &WebSocketCloseError{Code: closeNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.closeWith(CloseNormal, "")
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.protocolError("new message inside a fragmented one")
+			}
+			messageType = opcode
+		case 0: // continuation
+			if messageType == 0 {
+				return 0, nil, c.protocolError("continuation without a message")
+			}
+		default:
+			return 0, nil, c.protocolError(fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		if int64(len(data)+len(payload)) > c.maxSize {
+			c.closeWith(CloseMessageTooBig, "message too big")
+			return 0, nil, fmt.Errorf("websocket: message larger than %d bytes", c.maxSize)
+		}
+		data = append(data, payload...)
+		if fin {
+			return messageType, data, nil
+		}
+	}
+}
+
+func (c *WebSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = header[0]&0x80 != 0, int(header[0]&0x0f)
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		// Clients must mask every frame, servers none
+		return false, 0, nil, c.protocolError("wrong frame masking")
+	}
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.protocolError("reserved bits set")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		return false, 0, nil, c.protocolError("invalid control frame")
+	}
+	if length < 0 || length > c.maxSize {
+		c.closeWith(CloseMessageTooBig, "message too big")
+		return false, 0, nil, fmt.Errorf("websocket: frame larger than %d bytes", c.maxSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *WebSocketConn) protocolError(msg string) error {
+	c.closeWith(CloseProtocolError, msg)
+	return fmt.Errorf("websocket: protocol error: %s", msg)
+}
+
+// WriteMessage sends data as one text or binary message
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: cannot write message type %d", messageType)
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteJSON sends v encoded as JSON in a text message
+func (c *WebSocketConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(TextMessage, data)
+}
+
+// Ping sends a ping, which the peer answers with a pong while reading
+func (c *WebSocketConn) Ping(data []byte) error {
+	return c.writeFrame(PingMessage, data)
+}
+
+func (c *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return ErrWebSocketClosed
+	}
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(opcode))
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a normal close frame and closes the connection
+func (c *WebSocketConn) Close() error {
+	return c.CloseWithReason(CloseNormal, "")
+}
+
+// CloseWithReason sends a close frame with code and reason, then closes the
+// connection without waiting for the peer's reply
+func (c *WebSocketConn) CloseWithReason(code int, reason string) error {
+	c.closeWith(code, reason)
+	return c.conn.Close()
+}
+
+// closeWith sends a close frame once; later writes fail
+func (c *WebSocketConn) closeWith(code int, reason string) {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason[:min(len(reason), 123)]...)
+	if c.writeFrame(CloseMessage, payload) == nil {
+		c.writeMu.Lock()
+		c.closed = true
+		c.writeMu.Unlock()
+	}
+}
+
+// ExampleWebSocket runs an echo server and talks to it
+func ExampleWebSocket() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, append([]byte("echo: "), data...))
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := DialWebSocket(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		fmt.Printf("❌ Dial failed: %v\n", err)
+		return
+	}
+	fmt.Printf("🔌 Connected to %s\n", conn.RemoteAddr())
+
+	conn.Ping([]byte("are you there?"))
+	for _, msg := range []string{"hello", strings.Repeat("big ", 100)} {
+		if err := conn.WriteMessage(TextMessage, []byte(msg)); err != nil {
+			fmt.Printf("❌ Write failed: %v\n", err)
+			return
+		}
+		_, reply, err := conn.ReadMessage()
+		if err != nil {
+			fmt.Printf("❌ Read failed: %v\n", err)
+			return
+		}
+		fmt.Printf("📨 %d bytes back: %.30s…\n", len(reply), reply)
+	}
+	conn.WriteJSON(map[string]any{"type": "greeting", "n": 1})
+	_, reply, _ := conn.ReadMessage()
+	fmt.Printf("📨 %s\n", reply)
+	conn.Close()
+
+	// Pages from other sites are refused
+	_, err = DialWebSocket(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), http.Header{"Origin": {"https://evil.example"}})
+	fmt.Printf("🚫 Cross-origin dial: %v\n", err)
+}
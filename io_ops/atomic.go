@@ -0,0 +1,91 @@
+package io_ops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile writes to a temporary file next to the target and renames it
+// into place on Commit, so readers never observe a partially written file and
+// a crash leaves the previous contents intact
+type AtomicFile struct {
+	*os.File
+	path string
+	perm os.FileMode
+	done bool
+}
+
+// CreateAtomic starts an atomic write of path. Always call Abort (a no-op
+// after a successful Commit), typically with defer.
+func CreateAtomic(path string, perm os.FileMode) (*AtomicFile, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	// The temp file must be in the same directory: rename is only atomic
+	// within a filesystem
+	f, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: f, path: path, perm: perm}, nil
+}
+
+// Commit flushes the data to disk and renames the temp file over the target
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return errors.New("atomic file already committed or aborted")
+	}
+	a.done = true
+
+	err := a.File.Sync()
+	if err == nil {
+		err = a.File.Chmod(a.perm)
+	}
+	if cerr := a.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(a.File.Name(), a.path)
+	}
+	if err != nil {
+		os.Remove(a.File.Name())
+		return err
+	}
+	syncDir(filepath.Dir(a.path))
+	return nil
+}
+
+// Abort discards the temp file; it does nothing after Commit
+func (a *AtomicFile) Abort() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	a.File.Close()
+	return os.Remove(a.File.Name())
+}
+
+// WriteFileAtomic is os.WriteFile with temp-file-and-rename semantics
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := CreateAtomic(path, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// syncDir makes the rename durable; filesystems that cannot sync a
+// directory (such as on Windows) are ignored
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
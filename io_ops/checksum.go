@@ -0,0 +1,109 @@
+package io_ops
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Supported checksum algorithms
+const (
+	MD5    = "md5"
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case MD5:
+		return md5.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// ChecksumReader returns the hex digest of everything read from r
+func ChecksumReader(r io.Reader, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := CopyBuffered(h, r, 0); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Checksum returns the hex digest of the file at path
+func Checksum(path, algorithm string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return ChecksumReader(f, algorithm)
+}
+
+// MD5File returns the hex MD5 of a file. MD5 is fine for detecting accidental
+// corruption but not for anything an attacker controls.
+func MD5File(path string) (string, error) {
+	return Checksum(path, MD5)
+}
+
+// SHA256File returns the hex SHA-256 of a file
+func SHA256File(path string) (string, error) {
+	return Checksum(path, SHA256)
+}
+
+// Checksums computes several digests in a single pass over the file
+func Checksums(path string, algorithms ...string) (map[string]string, error) {
+	hashes := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		h, err := newHash(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashes[algorithm] = h
+		writers = append(writers, h)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := CopyBuffered(io.MultiWriter(writers...), f, 0); err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]string, len(hashes))
+	for algorithm, h := range hashes {
+		sums[algorithm] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// VerifyChecksum reports whether the file's digest equals the expected hex
+// string (case-insensitive)
+func VerifyChecksum(path, algorithm, expected string) (bool, error) {
+	sum, err := Checksum(path, algorithm)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(sum), []byte(strings.ToLower(expected))) == 1, nil
+}
@@ -0,0 +1,240 @@
+package io_ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// StreamingExamples demonstrates line and chunk streaming
+func StreamingExamples(dir string) {
+	fmt.Println("\n=== Streaming IO ===")
+
+	path := filepath.Join(dir, "numbers.txt")
+	var sb strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		return
+	}
+
+	count := 0
+	ReadLines(path, 0, func(lineNo int, line string) error {
+		count++
+		if lineNo <= 2 {
+			fmt.Printf("📖 %d: %s\n", lineNo, line)
+		}
+		return nil
+	})
+	fmt.Printf("Read %d lines\n", count)
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error opening file: %v\n", err)
+		return
+	}
+	defer f.Close()
+	counter := NewCountingReader(f)
+	chunks := 0
+	ReadChunks(counter, 1024, func(chunk []byte) error {
+		chunks++
+		return nil
+	})
+	fmt.Printf("Read %s in %d chunks of up to 1KiB\n", format.HumanizeBytes(counter.Count()), chunks)
+
+	copyPath := filepath.Join(dir, "numbers-copy.txt")
+	if n, err := CopyFile(path, copyPath); err == nil {
+		fmt.Printf("📋 Copied %s to %s\n", format.HumanizeBytes(n), filepath.Base(copyPath))
+	}
+}
+
+// AtomicWriteExamples demonstrates temp-file-and-rename writes
+func AtomicWriteExamples(dir string) {
+	fmt.Println("\n=== Atomic Writes ===")
+
+	path := filepath.Join(dir, "config.json")
+	if err := WriteFileAtomic(path, []byte(`{"version": 1}`), 0o600); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		return
+	}
+	data, _ := os.ReadFile(path)
+	fmt.Printf("✅ Wrote %s: %s\n", filepath.Base(path), data)
+
+	// An aborted write leaves the old contents in place
+	f, err := CreateAtomic(path, 0o600)
+	if err != nil {
+		fmt.Printf("Error creating atomic file: %v\n", err)
+		return
+	}
+	f.WriteString(`{"version": 2, "half-writ`)
+	f.Abort()
+	data, _ = os.ReadFile(path)
+	fmt.Printf("After aborted write: %s\n", data)
+
+	entries, _ := os.ReadDir(dir)
+	temps := 0
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			temps++
+		}
+	}
+	fmt.Printf("Leftover temp files: %d\n", temps)
+}
+
+// LockExamples demonstrates inter-process file locks
+func LockExamples(dir string) {
+	fmt.Println("\n=== File Locking ===")
+
+	path := filepath.Join(dir, "app.lock")
+	first, second := NewFileLock(path), NewFileLock(path)
+	if err := first.Lock(); err != nil {
+		fmt.Printf("Error acquiring lock: %v\n", err)
+		return
+	}
+	fmt.Println("🔒 First holder acquired the lock")
+
+	if err := second.TryLock(); errors.Is(err, ErrLocked) {
+		fmt.Println("Second holder: lock is busy")
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Unlock()
+		fmt.Println("🔓 First holder released the lock")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := second.LockContext(ctx, 20*time.Millisecond); err != nil {
+		fmt.Printf("Error waiting for lock: %v\n", err)
+		return
+	}
+	fmt.Println("🔒 Second holder acquired the lock")
+	second.Unlock()
+}
+
+// WalkExamples demonstrates filtered directory walks and globbing
+func WalkExamples(dir string) {
+	fmt.Println("\n=== Directory Walking ===")
+
+	files := []string{
+		"src/main.go", "src/util/strings.go", "src/util/strings_test.go",
+		"docs/README.md", "vendor/lib/lib.go", ".git/config", "build/app.log",
+	}
+	root := filepath.Join(dir, "project")
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(name), 0o644)
+	}
+
+	fmt.Println("Tree (hidden entries skipped):")
+	Walk(root, WalkOptions{SkipHidden: true}, func(path string, d fs.DirEntry) error {
+		rel, _ := filepath.Rel(root, path)
+		depth := strings.Count(filepath.ToSlash(rel), "/")
+		suffix := ""
+		if d.IsDir() {
+			suffix = "/"
+		}
+		fmt.Printf("  %s%s%s\n", strings.Repeat("  ", depth), d.Name(), suffix)
+		return nil
+	})
+
+	goFiles, _ := Glob(root, "**/*.go")
+	fmt.Printf("**/*.go: %d files\n", len(goFiles))
+
+	fmt.Println("Go sources excluding tests and vendor/:")
+	Walk(root, WalkOptions{Include: []string{"*.go"}, Exclude: []string{"*_test.go", "vendor"}}, func(path string, d fs.DirEntry) error {
+		rel, _ := filepath.Rel(root, path)
+		fmt.Printf("  %s\n", filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+// TailExamples demonstrates reading the end of a file and following it
+func TailExamples(dir string) {
+	fmt.Println("\n=== Tailing Files ===")
+
+	path := filepath.Join(dir, "app.log")
+	var sb strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&sb, "INFO request %d served\n", i)
+	}
+	os.WriteFile(path, []byte(sb.String()), 0o644)
+
+	last, _ := LastLines(path, 3)
+	fmt.Printf("Last 3 lines: %q\n", last)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	go func() {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		for i := 1; i <= 3; i++ {
+			time.Sleep(100 * time.Millisecond)
+			fmt.Fprintf(f, "WARN slow request %d\n", i)
+		}
+	}()
+
+	fmt.Println("Following (tail -n 1 -f):")
+	err := Tail(ctx, path, TailOptions{Lines: 1, Follow: true, PollInterval: 50 * time.Millisecond}, func(line string) {
+		fmt.Printf("  📜 %s\n", line)
+	})
+	fmt.Printf("Tail stopped: %v\n", err)
+}
+
+// ChecksumExamples demonstrates file digests
+func ChecksumExamples(dir string) {
+	fmt.Println("\n=== Checksums ===")
+
+	path := filepath.Join(dir, "payload.bin")
+	os.WriteFile(path, []byte("The quick brown fox jumps over the lazy dog"), 0o644)
+
+	md5sum, _ := MD5File(path)
+	sha, _ := SHA256File(path)
+	fmt.Printf("MD5:    %s\n", md5sum)
+	fmt.Printf("SHA256: %s\n", sha)
+
+	sums, _ := Checksums(path, SHA1, SHA512)
+	fmt.Printf("SHA1:   %s\n", sums[SHA1])
+	fmt.Printf("SHA512: %s…\n", sums[SHA512][:32])
+
+	ok, _ := VerifyChecksum(path, SHA256, strings.ToUpper(sha))
+	fmt.Printf("Verify SHA256: %t\n", ok)
+	ok, _ = VerifyChecksum(path, MD5, "00000000000000000000000000000000")
+	fmt.Printf("Verify wrong MD5: %t\n", ok)
+}
+
+// RunAllIOExamples runs every io_ops example in a temporary directory
+func RunAllIOExamples() {
+	fmt.Println("🎯 Go File IO Utilities Examples")
+	fmt.Println("================================")
+
+	dir, err := os.MkdirTemp("", "io_ops-*")
+	if err != nil {
+		fmt.Printf("Error creating temp dir: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	StreamingExamples(dir)
+	AtomicWriteExamples(dir)
+	LockExamples(dir)
+	WalkExamples(dir)
+	TailExamples(dir)
+	ChecksumExamples(dir)
+
+	fmt.Println("\n✅ All IO examples completed!")
+}
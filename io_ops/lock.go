@@ -0,0 +1,94 @@
+package io_ops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("file is locked by another process")
+
+// FileLock is an advisory, exclusive inter-process lock backed by flock(2) on
+// Unix. The lock file itself is left in place; only the lock is released.
+type FileLock struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// NewFileLock creates a lock on path; nothing is opened until Lock
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// Path returns the lock file path
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+func (l *FileLock) acquire(block bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		return errors.New("lock already held by this FileLock")
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := lockFile(f, block); err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	return nil
+}
+
+// Lock blocks until the lock is acquired
+func (l *FileLock) Lock() error {
+	return l.acquire(true)
+}
+
+// TryLock acquires the lock without waiting, returning ErrLocked if it is held
+func (l *FileLock) TryLock() error {
+	return l.acquire(false)
+}
+
+// LockContext polls TryLock until it succeeds or ctx is done
+func (l *FileLock) LockContext(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 50 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := l.TryLock()
+		if !errors.Is(err, ErrLocked) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return errors.New("lock not held")
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
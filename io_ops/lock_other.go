@@ -0,0 +1,16 @@
+//go:build !unix
+
+package io_ops
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(f *os.File, block bool) error {
+	return &os.PathError{Op: "lock", Path: f.Name(), Err: errors.ErrUnsupported}
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,142 @@
+package io_ops
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the buffer size used by the streaming helpers
+const DefaultBufferSize = 64 * 1024
+
+// ReadLines streams path line by line without loading it into memory. Lines
+// may be up to maxLineSize bytes (1MB when zero); fn returning an error stops
+// the scan and that error is returned.
+func ReadLines(path string, maxLineSize int, fn func(lineNo int, line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if maxLineSize <= 0 {
+		maxLineSize = 1 << 20
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, min(maxLineSize, DefaultBufferSize)), maxLineSize)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if err := fn(lineNo, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ReadChunks reads r in chunks of up to size bytes. The slice passed to fn is
+// reused between calls, so fn must copy it to keep the data.
+func ReadChunks(r io.Reader, size int, fn func(chunk []byte) error) error {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if ferr := fn(buf[:n]); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CopyBuffered copies src to dst through a buffer of bufSize bytes
+func CopyBuffered(dst io.Writer, src io.Reader, bufSize int) (int64, error) {
+	if bufSize <= 0 {
+		bufSize = DefaultBufferSize
+	}
+	return io.CopyBuffer(dst, src, make([]byte, bufSize))
+}
+
+// CopyFile copies src to dst atomically, keeping the source permissions;
+// dst is either the complete copy or left untouched
+func CopyFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+	out, err := CreateAtomic(dst, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer out.Abort()
+
+	n, err := CopyBuffered(out, in, 0)
+	if err != nil {
+		return n, err
+	}
+	return n, out.Commit()
+}
+
+// CountingReader counts the bytes read through it and optionally reports
+// progress after every read
+type CountingReader struct {
+	r          io.Reader
+	n          atomic.Int64
+	OnProgress func(total int64)
+}
+
+// NewCountingReader wraps r
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		total := c.n.Add(int64(n))
+		if c.OnProgress != nil {
+			c.OnProgress(total)
+		}
+	}
+	return n, err
+}
+
+// Count returns the number of bytes read so far
+func (c *CountingReader) Count() int64 {
+	return c.n.Load()
+}
+
+// CountingWriter counts the bytes written through it
+type CountingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+// NewCountingWriter wraps w
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{w: w}
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes written so far
+func (c *CountingWriter) Count() int64 {
+	return c.n.Load()
+}
@@ -0,0 +1,161 @@
+package io_ops
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// TailOptions configures Tail
+type TailOptions struct {
+	Lines        int           // start with the last Lines lines; 0 starts at the end
+	Follow       bool          // keep waiting for new lines, like tail -f
+	PollInterval time.Duration // how often to check for growth; 250ms when zero
+}
+
+// LastLines returns the last n lines of a file, reading backwards in blocks
+// so large files are not read in full
+func LastLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	offset, err := lastLinesOffset(f, n)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// lastLinesOffset returns the offset at which the last n lines start
+func lastLinesOffset(f *os.File, n int) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if n <= 0 {
+		return size, nil
+	}
+
+	const blockSize = 4096
+	buf := make([]byte, blockSize)
+	pos := size
+	newlines := 0
+	for pos > 0 {
+		readSize := int64(blockSize)
+		if pos < readSize {
+			readSize = pos
+		}
+		pos -= readSize
+		if _, err := f.ReadAt(buf[:readSize], pos); err != nil {
+			return 0, err
+		}
+		for i := readSize - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			// A newline that ends the file does not start a new line
+			if pos+i == size-1 {
+				continue
+			}
+			if newlines++; newlines == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// Tail calls fn for the last opts.Lines lines of path and, in follow mode,
+// for every line appended afterwards until ctx is cancelled. Truncation
+// (copytruncate log rotation) restarts from the beginning, and a file
+// replaced by rename is reopened.
+func Tail(ctx context.Context, path string, opts TailOptions, fn func(line string)) error {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 250 * time.Millisecond
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	offset, err := lastLinesOffset(f, opts.Lines)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	var partial bytes.Buffer
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		partial.Write(chunk)
+		if err == nil {
+			offset += int64(partial.Len())
+			fn(strings.TrimRight(partial.String(), "\r\n"))
+			partial.Reset()
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !opts.Follow {
+			if partial.Len() > 0 {
+				fn(partial.String())
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+
+		current, statErr := os.Stat(path)
+		opened, _ := f.Stat()
+		switch {
+		case statErr != nil:
+			// The file is being rotated; wait for the new one to appear
+		case opened != nil && !os.SameFile(current, opened):
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f, offset = newFile, 0
+			reader.Reset(f)
+			partial.Reset()
+		case current.Size() < offset+int64(partial.Len()):
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset = 0
+			reader.Reset(f)
+			partial.Reset()
+		}
+	}
+}
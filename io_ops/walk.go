@@ -0,0 +1,116 @@
+package io_ops
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WalkOptions filters a directory walk. Patterns use forward slashes, are
+// matched against the path relative to the root and support "**" for any
+// number of directories, e.g. "**/*.go" or "vendor/**".
+type WalkOptions struct {
+	Include    []string // only report files matching one of these; all when empty
+	Exclude    []string // skip files and directories matching any of these
+	MaxDepth   int      // 0 means unlimited; 1 is the root's direct children
+	SkipHidden bool     // skip names starting with "."
+	FilesOnly  bool     // do not report directories
+}
+
+// Walk calls fn for every entry under root that passes opts, in lexical
+// order. Excluded directories are not descended into.
+func Walk(root string, opts WalkOptions, fn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if opts.SkipHidden && strings.HasPrefix(d.Name(), ".") || matchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		depth := strings.Count(rel, "/") + 1
+		if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if opts.FilesOnly || len(opts.Include) > 0 {
+				return nil
+			}
+			return fn(p, d)
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+		return fn(p, d)
+	})
+}
+
+// Glob returns the files under root matching pattern, which may contain "**"
+func Glob(root, pattern string) ([]string, error) {
+	var matches []string
+	err := Walk(root, WalkOptions{Include: []string{pattern}, FilesOnly: true}, func(p string, d fs.DirEntry) error {
+		matches = append(matches, p)
+		return nil
+	})
+	sort.Strings(matches)
+	return matches, err
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether the slash-separated name matches pattern. Each
+// segment is matched with path.Match, and a "**" segment matches zero or more
+// segments. A pattern without a slash matches the base name at any depth, as
+// in .gitignore.
+func MatchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
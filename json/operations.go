@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/jerrychou/go-practice/format"
 )
 
 type Person struct {
@@ -98,7 +100,7 @@ func BasicJSONOperations() {
 		return
 	}
 
-	fmt.Printf("Unmarshaled person: %+v\n\n", newPerson)
+	fmt.Printf("Unmarshaled person: %s\n\n", format.Dump(newPerson, format.DefaultDumpOptions))
 }
 
 // JSONWithMaps demonstrates working with JSON and maps
@@ -131,7 +133,7 @@ func JSONWithMaps() {
 		return
 	}
 
-	fmt.Printf("JSON to map: %+v\n\n", result)
+	fmt.Printf("JSON to map: %s\n\n", format.Dump(result, format.DefaultDumpOptions))
 
 	if name, ok := result["name"].(string); ok {
 		fmt.Printf("Name: %s\n", name)
@@ -202,7 +204,7 @@ func CustomJSONHandling() {
 		return
 	}
 
-	fmt.Printf("Custom unmarshaled data: %+v\n\n", newCustomData)
+	fmt.Printf("Custom unmarshaled data: %s\n\n", format.Dump(newCustomData, format.DefaultDumpOptions))
 }
 
 // JSONStreaming demonstrates streaming JSON operations
@@ -242,7 +244,7 @@ func JSONValidation() {
 	if err != nil {
 		fmt.Printf("Valid JSON error: %v\n", err)
 	} else {
-		fmt.Printf("Valid JSON parsed successfully: %+v\n", validData)
+		fmt.Printf("Valid JSON parsed successfully: %s\n", format.Dump(validData, format.DefaultDumpOptions))
 	}
 
 	var invalidData map[string]any
@@ -250,7 +252,7 @@ func JSONValidation() {
 	if err != nil {
 		fmt.Printf("Invalid JSON error: %v\n", err)
 	} else {
-		fmt.Printf("Invalid JSON parsed successfully: %+v\n", invalidData)
+		fmt.Printf("Invalid JSON parsed successfully: %s\n", format.Dump(invalidData, format.DefaultDumpOptions))
 	}
 	fmt.Println()
 }
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+)
+
+// BasicMetricsExamples demonstrates the metric types and the text format
+func BasicMetricsExamples() {
+	fmt.Println("\n=== Counters, Gauges, Histograms and Timers ===")
+	reg := NewRegistry()
+
+	orders := reg.Counter("shop_orders_total", "Orders placed", "region")
+	orders.With("eu").Inc()
+	orders.With("eu").Inc()
+	orders.With("us").Add(3)
+
+	queueDepth := reg.Gauge("shop_queue_depth", "Orders waiting to ship")
+	queueDepth.Set(7)
+	queueDepth.Dec()
+
+	sizes := reg.Histogram("shop_basket_items", "Items per basket", []float64{1, 5, 10})
+	for _, n := range []float64{1, 2, 3, 8, 12} {
+		sizes.Observe(n)
+	}
+
+	checkout := reg.Timer("shop_checkout_seconds", "Checkout latency", "step")
+	stop := checkout.Start("payment")
+	time.Sleep(20 * time.Millisecond)
+	fmt.Printf("⏱️  payment step took %v\n", stop().Round(time.Millisecond))
+	checkout.ObserveDuration(3*time.Millisecond, "validate")
+
+	fmt.Printf("🔢 eu orders: %.0f, queue depth: %.0f\n", orders.With("eu").Value(), queueDepth.With().Value())
+	fmt.Println("📄 Prometheus exposition (checkout buckets omitted):")
+	var sb strings.Builder
+	reg.WritePrometheus(&sb)
+	for _, line := range strings.Split(strings.TrimSpace(sb.String()), "\n") {
+		if strings.HasPrefix(line, "shop_checkout_seconds_bucket") {
+			continue
+		}
+		fmt.Println("   " + line)
+	}
+}
+
+// HTTPMetricsExamples instruments a server and a client
+func HTTPMetricsExamples() {
+	fmt.Println("\n=== HTTP Middleware and Client Instrumentation ===")
+	reg := NewRegistry()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/metrics", reg.Handler())
+	server := httptest.NewServer(reg.Middleware(mux))
+	defer server.Close()
+
+	client := &http.Client{Transport: reg.RoundTripper(nil)}
+	for _, path := range []string{"/ok", "/ok", "/ok", "/missing"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	resp, err := client.Get(server.URL + "/metrics")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	fmt.Printf("📡 GET /metrics -> %s\n", resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "http_server_requests_total{") || strings.HasPrefix(line, "http_client_requests_total{") {
+			fmt.Println("   " + line)
+		}
+	}
+}
+
+// ListenerMetricsExamples counts connections and bytes on a TCP listener
+func ListenerMetricsExamples() {
+	fmt.Println("\n=== Listener Instrumentation ===")
+	reg := NewRegistry()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	ln = reg.Listener("echo", ln)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		fmt.Fprintf(conn, "hello %d\n", i)
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Close()
+	}
+	time.Sleep(50 * time.Millisecond) // let the server see the closes
+
+	snapshot := reg.Snapshot()
+	fmt.Printf("🔌 accepted=%v active=%v read=%v written=%v\n",
+		snapshot["net_connections_accepted_total"], snapshot["net_connections_active"],
+		snapshot["net_bytes_read_total"], snapshot["net_bytes_written_total"])
+}
+
+// ExpvarExamples shows the JSON view published for /debug/vars
+func ExpvarExamples() {
+	fmt.Println("\n=== expvar Bridge ===")
+	reg := NewRegistry()
+	reg.Counter("jobs_processed_total", "Jobs processed", "status").With("ok").Add(41)
+	reg.Gauge("workers_busy", "Busy workers").Set(2)
+	reg.PublishExpvar("example_metrics")
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("   ", "  ")
+	fmt.Print("   ")
+	encoder.Encode(reg.Snapshot())
+}
+
+// RunAllMetricsExamples runs all metrics examples
+func RunAllMetricsExamples() {
+	BasicMetricsExamples()
+	HTTPMetricsExamples()
+	ListenerMetricsExamples()
+	ExpvarExamples()
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Middleware records request counts, latencies and in-flight requests for
+// next in the Default registry
+func Middleware(next http.Handler) http.Handler {
+	return Default.Middleware(next)
+}
+
+// Middleware records request counts, latencies and in-flight requests for
+// next. Paths are not used as labels, to keep the number of series bounded.
+func (r *Registry) Middleware(next http.Handler) http.Handler {
+	requests := r.Counter("http_server_requests_total", "HTTP requests served", "method", "code")
+	duration := r.Timer("http_server_request_duration_seconds", "HTTP request latency", "method")
+	inFlight := r.Gauge("http_server_requests_in_flight", "HTTP requests being served")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		inFlight.Inc()
+		defer inFlight.Dec()
+		stop := duration.Start(req.Method)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		stop()
+		requests.With(req.Method, strconv.Itoa(recorder.status)).Inc()
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// RoundTripper records outgoing request counts and latencies per host in
+// the Default registry; a nil next means http.DefaultTransport
+func RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return Default.RoundTripper(next)
+}
+
+// RoundTripper records outgoing request counts and latencies per host.
+// Failed requests are counted with code "error".
+func (r *Registry) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{
+		next:     next,
+		requests: r.Counter("http_client_requests_total", "HTTP requests sent", "method", "host", "code"),
+		duration: r.Timer("http_client_request_duration_seconds", "HTTP request latency until headers arrive", "method", "host"),
+	}
+}
+
+type roundTripper struct {
+	next     http.RoundTripper
+	requests *Counter
+	duration *Timer
+}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	stop := t.duration.Start(req.Method, req.URL.Host)
+	resp, err := t.next.RoundTrip(req)
+	stop()
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	t.requests.With(req.Method, req.URL.Host, code).Inc()
+	return resp, err
+}
@@ -0,0 +1,310 @@
+// Package metrics provides counters, gauges, histograms and timers with
+// labels, a registry that serves the Prometheus text format and expvar, and
+// adapters that instrument HTTP handlers and clients, listeners and SQL pools.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("metrics")
+
+// DefaultBuckets suit latencies in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Metric is a named family of values, one per combination of label values
+type Metric interface {
+	Name() string
+	Help() string
+	Type() string // "counter", "gauge" or "histogram"
+	LabelNames() []string
+	// series returns the label values and value of each child, sorted by
+	// label values
+	series() []series
+}
+
+type series struct {
+	labels []string
+	value  any // float64 or HistogramSnapshot
+}
+
+// family holds the children of a metric keyed by their label values
+type family[T any] struct {
+	name, help string
+	labels     []string
+	newValue   func() T
+
+	mu       sync.RWMutex
+	children map[string]*child[T]
+}
+
+type child[T any] struct {
+	labels []string
+	value  T
+}
+
+func newFamily[T any](name, help string, labels []string, newValue func() T) family[T] {
+	if !metricNameRE.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range labels {
+		if !labelNameRE.MatchString(label) || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q on %s", label, name))
+		}
+	}
+	return family[T]{
+		name:     name,
+		help:     help,
+		labels:   slices.Clone(labels),
+		newValue: newValue,
+		children: make(map[string]*child[T]),
+	}
+}
+
+func (f *family[T]) Name() string         { return f.name }
+func (f *family[T]) Help() string         { return f.help }
+func (f *family[T]) LabelNames() []string { return slices.Clone(f.labels) }
+
+// with returns the child for labelValues, creating it on first use
+func (f *family[T]) with(labelValues []string) T {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c.value
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c.value
+	}
+	c = &child[T]{labels: slices.Clone(labelValues), value: f.newValue()}
+	f.children[key] = c
+	return c.value
+}
+
+func (f *family[T]) collect(value func(T) any) []series {
+	f.mu.RLock()
+	out := make([]series, 0, len(f.children))
+	for _, c := range f.children {
+		out = append(out, series{labels: c.labels, value: value(c.value)})
+	}
+	f.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return slices.Compare(out[i].labels, out[j].labels) < 0 })
+	return out
+}
+
+// atomicFloat is a float64 updated with compare-and-swap
+type atomicFloat struct{ bits atomic.Uint64 }
+
+func (a *atomicFloat) Load() float64   { return math.Float64frombits(a.bits.Load()) }
+func (a *atomicFloat) Store(v float64) { a.bits.Store(math.Float64bits(v)) }
+
+func (a *atomicFloat) Add(delta float64) {
+	for {
+		old := a.bits.Load()
+		if a.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// CounterValue only goes up
+type CounterValue struct{ v atomicFloat }
+
+// Inc adds one
+func (c *CounterValue) Inc() { c.v.Add(1) }
+
+// Add adds delta, which must not be negative
+func (c *CounterValue) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.Add(delta)
+}
+
+// Value returns the current count
+func (c *CounterValue) Value() float64 { return c.v.Load() }
+
+// Counter counts events, such as requests served
+type Counter struct {
+	family[*CounterValue]
+}
+
+func newCounter(name, help string, labels []string) *Counter {
+	return &Counter{newFamily(name, help, labels, func() *CounterValue { return &CounterValue{} })}
+}
+
+// Type implements Metric
+func (c *Counter) Type() string { return "counter" }
+
+// With returns the counter for the given label values
+func (c *Counter) With(labelValues ...string) *CounterValue { return c.with(labelValues) }
+
+// Inc adds one to a counter without labels
+func (c *Counter) Inc() { c.With().Inc() }
+
+// Add adds delta to a counter without labels
+func (c *Counter) Add(delta float64) { c.With().Add(delta) }
+
+func (c *Counter) series() []series {
+	return c.collect(func(v *CounterValue) any { return v.Value() })
+}
+
+// GaugeValue goes up and down
+type GaugeValue struct{ v atomicFloat }
+
+// Set replaces the value
+func (g *GaugeValue) Set(v float64) { g.v.Store(v) }
+
+// Add adds delta, which may be negative
+func (g *GaugeValue) Add(delta float64) { g.v.Add(delta) }
+
+// Inc adds one
+func (g *GaugeValue) Inc() { g.v.Add(1) }
+
+// Dec subtracts one
+func (g *GaugeValue) Dec() { g.v.Add(-1) }
+
+// Value returns the current value
+func (g *GaugeValue) Value() float64 { return g.v.Load() }
+
+// Gauge measures a current level, such as open connections
+type Gauge struct {
+	family[*GaugeValue]
+}
+
+func newGauge(name, help string, labels []string) *Gauge {
+	return &Gauge{newFamily(name, help, labels, func() *GaugeValue { return &GaugeValue{} })}
+}
+
+// Type implements Metric
+func (g *Gauge) Type() string { return "gauge" }
+
+// With returns the gauge for the given label values
+func (g *Gauge) With(labelValues ...string) *GaugeValue { return g.with(labelValues) }
+
+// Set sets a gauge without labels
+func (g *Gauge) Set(v float64) { g.With().Set(v) }
+
+// Inc adds one to a gauge without labels
+func (g *Gauge) Inc() { g.With().Inc() }
+
+// Dec subtracts one from a gauge without labels
+func (g *Gauge) Dec() { g.With().Dec() }
+
+func (g *Gauge) series() []series {
+	return g.collect(func(v *GaugeValue) any { return v.Value() })
+}
+
+// HistogramValue counts observations into buckets
+type HistogramValue struct {
+	bounds []float64
+	counts []atomic.Uint64 // per bucket, not cumulative; the last is +Inf
+	sum    atomicFloat
+}
+
+// Observe records one value
+func (h *HistogramValue) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v) // first bound >= v
+	h.counts[i].Add(1)
+	h.sum.Add(v)
+}
+
+// HistogramSnapshot is a point-in-time copy of a histogram
+type HistogramSnapshot struct {
+	Bounds []float64 `json:"bounds"`
+	// Cumulative[i] counts observations <= Bounds[i]
+	Cumulative []uint64 `json:"cumulative"`
+	Count      uint64   `json:"count"`
+	Sum        float64  `json:"sum"`
+}
+
+// Snapshot copies the current counts
+func (h *HistogramValue) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{Bounds: h.bounds, Cumulative: make([]uint64, len(h.bounds))}
+	var total uint64
+	for i := range h.bounds {
+		total += h.counts[i].Load()
+		s.Cumulative[i] = total
+	}
+	s.Count = total + h.counts[len(h.bounds)].Load()
+	s.Sum = h.sum.Load()
+	return s
+}
+
+// Histogram tracks the distribution of values, such as response sizes
+type Histogram struct {
+	family[*HistogramValue]
+}
+
+func newHistogram(name, help string, buckets []float64, labels []string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	sort.Float64s(buckets)
+	buckets = slices.Compact(buckets)
+	if math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1] // +Inf is always present
+	}
+	return &Histogram{newFamily(name, help, labels, func() *HistogramValue {
+		return &HistogramValue{bounds: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+	})}
+}
+
+// Type implements Metric
+func (h *Histogram) Type() string { return "histogram" }
+
+// With returns the histogram for the given label values
+func (h *Histogram) With(labelValues ...string) *HistogramValue { return h.with(labelValues) }
+
+// Observe records a value in a histogram without labels
+func (h *Histogram) Observe(v float64) { h.With().Observe(v) }
+
+func (h *Histogram) series() []series {
+	return h.collect(func(v *HistogramValue) any { return v.Snapshot() })
+}
+
+// Timer is a histogram of durations in seconds
+type Timer struct {
+	*Histogram
+}
+
+// Start begins timing; the returned function records and returns the
+// elapsed time. Typical use is defer t.Start("GET")().
+func (t *Timer) Start(labelValues ...string) func() time.Duration {
+	h := t.With(labelValues...)
+	start := time.Now()
+	return func() time.Duration {
+		elapsed := time.Since(start)
+		h.Observe(elapsed.Seconds())
+		return elapsed
+	}
+}
+
+// ObserveDuration records d
+func (t *Timer) ObserveDuration(d time.Duration, labelValues ...string) {
+	t.With(labelValues...).Observe(d.Seconds())
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Listener counts accepted connections, open connections and bytes
+// transferred for ln in the Default registry, labelled server=name
+func Listener(name string, ln net.Listener) net.Listener {
+	return Default.Listener(name, ln)
+}
+
+// Listener counts accepted connections, open connections and bytes
+// transferred for ln, labelled server=name
+func (r *Registry) Listener(name string, ln net.Listener) net.Listener {
+	return &listener{
+		Listener: ln,
+		accepted: r.Counter("net_connections_accepted_total", "Connections accepted", "server").With(name),
+		active:   r.Gauge("net_connections_active", "Connections currently open", "server").With(name),
+		read:     r.Counter("net_bytes_read_total", "Bytes read from accepted connections", "server").With(name),
+		written:  r.Counter("net_bytes_written_total", "Bytes written to accepted connections", "server").With(name),
+	}
+}
+
+type listener struct {
+	net.Listener
+	accepted      *CounterValue
+	active        *GaugeValue
+	read, written *CounterValue
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.accepted.Inc()
+	l.active.Inc()
+	return &instrumentedConn{Conn: conn, l: l}, nil
+}
+
+type instrumentedConn struct {
+	net.Conn
+	l         *listener
+	closeOnce sync.Once
+}
+
+func (c *instrumentedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.l.read.Add(float64(n))
+	return n, err
+}
+
+func (c *instrumentedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.l.written.Add(float64(n))
+	return n, err
+}
+
+func (c *instrumentedConn) Close() error {
+	c.closeOnce.Do(c.l.active.Dec)
+	return c.Conn.Close()
+}
+
+// CloseWrite half-closes the connection when it supports that, as TCP and
+// TLS connections do, so proxies can pass an EOF on
+func (c *instrumentedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,264 @@
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics by name and renders them for scraping
+type Registry struct {
+	mu         sync.RWMutex
+	metrics    map[string]Metric
+	collectors map[string]func()
+}
+
+// Default is the registry used by the package-level constructors and the
+// adapters
+var Default = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]Metric), collectors: make(map[string]func())}
+}
+
+// Register adds m, failing if its name is taken
+func (r *Registry) Register(m Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.Name()]; ok {
+		return fmt.Errorf("metrics: %s is already registered", m.Name())
+	}
+	r.metrics[m.Name()] = m
+	return nil
+}
+
+// Unregister removes the metric called name
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.metrics[name]
+	delete(r.metrics, name)
+	return ok
+}
+
+// getOrCreate returns the metric called name, creating it with create if
+// needed. Asking for an existing name with a different type panics.
+func getOrCreate[M Metric](r *Registry, name string, create func() M) M {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.metrics[name]; ok {
+		m, ok := existing.(M)
+		if !ok {
+			panic(fmt.Sprintf("metrics: %s is already registered as a %s", name, existing.Type()))
+		}
+		return m
+	}
+	m := create()
+	r.metrics[name] = m
+	return m
+}
+
+// Counter returns the counter called name, creating it if needed
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	return getOrCreate(r, name, func() *Counter { return newCounter(name, help, labelNames) })
+}
+
+// Gauge returns the gauge called name, creating it if needed
+func (r *Registry) Gauge(name, help string, labelNames ...string) *Gauge {
+	return getOrCreate(r, name, func() *Gauge { return newGauge(name, help, labelNames) })
+}
+
+// Histogram returns the histogram called name, creating it with buckets
+// (DefaultBuckets if nil) if needed
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return getOrCreate(r, name, func() *Histogram { return newHistogram(name, help, buckets, labelNames) })
+}
+
+// Timer returns the duration histogram called name, which by convention
+// ends in _seconds, creating it if needed
+func (r *Registry) Timer(name, help string, labelNames ...string) *Timer {
+	return &Timer{r.Histogram(name, help, DefaultBuckets, labelNames...)}
+}
+
+// AddCollector runs fn before every scrape, typically to copy a value
+// maintained elsewhere into a gauge. A collector with the same key is
+// replaced.
+func (r *Registry) AddCollector(key string, fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[key] = fn
+}
+
+// RemoveCollector removes the collector added with key
+func (r *Registry) RemoveCollector(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.collectors, key)
+}
+
+// gather runs the collectors and returns the metrics sorted by name
+func (r *Registry) gather() []Metric {
+	r.mu.RLock()
+	collectors := make([]func(), 0, len(r.collectors))
+	for _, fn := range r.collectors {
+		collectors = append(collectors, fn)
+	}
+	r.mu.RUnlock()
+	for _, fn := range collectors {
+		fn()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	metrics := make([]Metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name() < metrics[j].Name() })
+	return metrics
+}
+
+// WritePrometheus writes every metric in the Prometheus text format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range r.gather() {
+		fmt.Fprintf(bw, "# HELP %s %s\n", m.Name(), escapeHelp(m.Help()))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", m.Name(), m.Type())
+		names := m.LabelNames()
+		for _, s := range m.series() {
+			switch v := s.value.(type) {
+			case float64:
+				writeSample(bw, m.Name(), names, s.labels, "", "", v)
+			case HistogramSnapshot:
+				for i, bound := range v.Bounds {
+					writeSample(bw, m.Name()+"_bucket", names, s.labels, "le", formatFloat(bound), float64(v.Cumulative[i]))
+				}
+				writeSample(bw, m.Name()+"_bucket", names, s.labels, "le", "+Inf", float64(v.Count))
+				writeSample(bw, m.Name()+"_sum", names, s.labels, "", "", v.Sum)
+				writeSample(bw, m.Name()+"_count", names, s.labels, "", "", float64(v.Count))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label, escapeLabel(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// Handler serves the registry in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WritePrometheus(w); err != nil {
+			logger.Error("Failed to write metrics", "error", err)
+		}
+	})
+}
+
+// Snapshot returns every metric as plain values: a number for metrics
+// without labels, otherwise a map from "label=value,..." to the value
+func (r *Registry) Snapshot() map[string]any {
+	out := make(map[string]any)
+	for _, m := range r.gather() {
+		names := m.LabelNames()
+		all := m.series()
+		if len(names) == 0 {
+			if len(all) > 0 {
+				out[m.Name()] = all[0].value
+			}
+			continue
+		}
+		byLabels := make(map[string]any, len(all))
+		for _, s := range all {
+			pairs := make([]string, len(names))
+			for i, name := range names {
+				pairs[i] = name + "=" + s.labels[i]
+			}
+			byLabels[strings.Join(pairs, ",")] = s.value
+		}
+		out[m.Name()] = byLabels
+	}
+	return out
+}
+
+// PublishExpvar exposes Snapshot under name in expvar, and so at
+// /debug/vars. Publishing the same name twice is a no-op.
+func (r *Registry) PublishExpvar(name string) {
+	if expvar.Get(name) != nil {
+		return
+	}
+	expvar.Publish(name, expvar.Func(func() any { return r.Snapshot() }))
+}
+
+// NewCounter returns a counter from the Default registry
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return Default.Counter(name, help, labelNames...)
+}
+
+// NewGauge returns a gauge from the Default registry
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	return Default.Gauge(name, help, labelNames...)
+}
+
+// NewHistogram returns a histogram from the Default registry
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return Default.Histogram(name, help, buckets, labelNames...)
+}
+
+// NewTimer returns a timer from the Default registry
+func NewTimer(name, help string, labelNames ...string) *Timer {
+	return Default.Timer(name, help, labelNames...)
+}
+
+// Handler serves the Default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
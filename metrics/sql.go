@@ -0,0 +1,42 @@
+package metrics
+
+import "database/sql"
+
+// RegisterDBStats exports db's connection pool statistics to the Default
+// registry, labelled db=name, refreshed on every scrape
+func RegisterDBStats(name string, db *sql.DB) {
+	Default.RegisterDBStats(name, db)
+}
+
+// UnregisterDBStats stops refreshing the statistics registered as name
+func UnregisterDBStats(name string) {
+	Default.UnregisterDBStats(name)
+}
+
+// RegisterDBStats exports db's connection pool statistics, labelled
+// db=name, refreshed on every scrape. Registering a name again replaces
+// the previous database.
+func (r *Registry) RegisterDBStats(name string, db *sql.DB) {
+	open := r.Gauge("db_connections_open", "Open connections, in use or idle", "db").With(name)
+	inUse := r.Gauge("db_connections_in_use", "Connections currently in use", "db").With(name)
+	idle := r.Gauge("db_connections_idle", "Idle connections", "db").With(name)
+	maxOpen := r.Gauge("db_connections_max_open", "Maximum open connections, 0 for unlimited", "db").With(name)
+	waits := r.Gauge("db_connection_waits", "Total times a connection was waited for", "db").With(name)
+	waitSeconds := r.Gauge("db_connection_wait_seconds", "Total time spent waiting for connections", "db").With(name)
+
+	r.AddCollector("db:"+name, func() {
+		stats := db.Stats()
+		open.Set(float64(stats.OpenConnections))
+		inUse.Set(float64(stats.InUse))
+		idle.Set(float64(stats.Idle))
+		maxOpen.Set(float64(stats.MaxOpenConnections))
+		waits.Set(float64(stats.WaitCount))
+		waitSeconds.Set(stats.WaitDuration.Seconds())
+	})
+}
+
+// UnregisterDBStats stops refreshing the statistics registered as name; the
+// gauges keep their last values
+func (r *Registry) UnregisterDBStats(name string) {
+	r.RemoveCollector("db:" + name)
+}
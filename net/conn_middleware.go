@@ -0,0 +1,222 @@
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// ConnHandler serves one accepted connection and closes it when done
+type ConnHandler func(conn net.Conn)
+
+// ConnMiddleware wraps a ConnHandler, the connection-level counterpart of
+// HTTP middleware. A middleware that turns a connection away closes it
+// instead of calling next.
+type ConnMiddleware func(next ConnHandler) ConnHandler
+
+// ChainConn wraps handler in middlewares; the first one sees each
+// connection first
+func ChainConn(handler ConnHandler, middlewares ...ConnMiddleware) ConnHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// LogConns logs each connection as it opens and closes, with how long it
+// lasted and the bytes read and written
+func LogConns(next ConnHandler) ConnHandler {
+	return func(conn net.Conn) {
+		counted := &countingConn{Conn: conn}
+		start := time.Now()
+		fmt.Printf("📞 %s connected\n", conn.RemoteAddr())
+		defer func() {
+			fmt.Printf("🔌 %s disconnected after %s: %d bytes in, %d out\n",
+				conn.RemoteAddr(), time.Since(start).Round(time.Millisecond), counted.read.Load(), counted.written.Load())
+		}()
+		next(counted)
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	read, written atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// ConnDeadlines gives every Read read and every Write write to complete,
+// so a client that goes quiet is dropped after read without the handler
+// managing deadlines itself. It replaces any deadline the handler sets. A
+// zero duration leaves that direction alone.
+func ConnDeadlines(read, write time.Duration) ConnMiddleware {
+	return func(next ConnHandler) ConnHandler {
+		return func(conn net.Conn) {
+			next(&deadlineConn{Conn: conn, read: read, write: write})
+		}
+	}
+}
+
+type deadlineConn struct {
+	net.Conn
+	read, write time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	if c.read > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.read))
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *deadlineConn) Write(b []byte) (int, error) {
+	if c.write > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.write))
+	}
+	return c.Conn.Write(b)
+}
+
+// ErrIdleTimeout is returned by reads and writes on a connection closed
+// for going idle
+var ErrIdleTimeout = errors.New("net: connection closed after idle timeout")
+
+// idleTimers holds the idle timeouts of every server's connections. They
+// are pushed back on each read, so a timing wheel, where that is O(1) and
+// allocation-free, beats a runtime timer or deadline per connection.
+var idleTimers = sync.OnceValue(func() *data_structure.TimingWheel {
+	return data_structure.NewTimingWheel(data_structure.TimingWheelOptions{Tick: 100 * time.Millisecond, Workers: 4})
+})
+
+// IdleTimeout closes connections that move no data either way for d,
+// unlike ConnDeadlines, which bounds each Read and Write separately
+func IdleTimeout(d time.Duration) ConnMiddleware {
+	return func(next ConnHandler) ConnHandler {
+		return func(conn net.Conn) {
+			idle := closeWhenIdle(conn, d)
+			defer idle.stop()
+			next(idle)
+		}
+	}
+}
+
+// idleConn closes Conn once it goes d without a Read or Write moving data
+type idleConn struct {
+	net.Conn
+	d       time.Duration
+	timer   *data_structure.WheelTimer
+	expired atomic.Bool
+}
+
+// closeWhenIdle starts conn's idle timeout; call stop when done with it
+func closeWhenIdle(conn net.Conn, d time.Duration) *idleConn {
+	c := &idleConn{Conn: conn, d: d}
+	c.timer = idleTimers().Schedule(d, func() {
+		c.expired.Store(true)
+		c.Conn.Close()
+	})
+	return c
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	return n, c.touch(n, err)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	return n, c.touch(n, err)
+}
+
+func (c *idleConn) touch(n int, err error) error {
+	if err != nil && c.expired.Load() {
+		return ErrIdleTimeout
+	}
+	if n > 0 && !c.expired.Load() {
+		c.timer.Reset(c.d)
+	}
+	return err
+}
+
+// stop cancels the timeout without closing the connection
+func (c *idleConn) stop() { c.timer.Cancel() }
+
+// MaxConns lets at most n connections through at once and closes the rest
+// straight away
+func MaxConns(n int) ConnMiddleware {
+	slots := make(chan struct{}, n)
+	return func(next ConnHandler) ConnHandler {
+		return func(conn net.Conn) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				next(conn)
+			default:
+				fmt.Printf("⛔ Rejected %s: %d connections already open\n", conn.RemoteAddr(), n)
+				conn.Close()
+			}
+		}
+	}
+}
+
+// RateLimitConns allows limit new connections per window from each client
+// IP. Counts live in a cache.Counter, so a Redis-backed limit is shared by
+// all instances. If the counter fails, connections are let through.
+func RateLimitConns(counter cache.Counter, limit int, window time.Duration) ConnMiddleware {
+	return func(next ConnHandler) ConnHandler {
+		return func(conn net.Conn) {
+			ip := conn.RemoteAddr().String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+
+			windowStart := time.Now().Truncate(window)
+			key := fmt.Sprintf("connlimit:%s:%d", ip, windowStart.Unix())
+			count, err := counter.Increment(context.Background(), key, 1, window+time.Second)
+			if err != nil {
+				fmt.Printf("⚠️ Connection rate limiter unavailable, allowing %s: %v\n", ip, err)
+			} else if int(count) > limit {
+				fmt.Printf("⛔ Rejected %s: more than %d connections in %s\n", ip, limit, window)
+				conn.Close()
+				return
+			}
+			next(conn)
+		}
+	}
+}
+
+// TLSConns terminates TLS, handing the handler the decrypted connection.
+// Clients that do not finish the handshake within 10 seconds are dropped.
+func TLSConns(config *tls.Config) ConnMiddleware {
+	return func(next ConnHandler) ConnHandler {
+		return func(conn net.Conn) {
+			tlsConn := tls.Server(conn, config)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err := tlsConn.HandshakeContext(ctx)
+			cancel()
+			if err != nil {
+				fmt.Printf("❌ TLS handshake with %s failed: %v\n", conn.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			next(tlsConn)
+		}
+	}
+}
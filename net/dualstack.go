@@ -0,0 +1,47 @@
+package net
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// HostPort joins host and port like net.JoinHostPort, also accepting IPv6
+// hosts already in brackets, e.g. "[::1]"
+func HostPort(host, port string) string {
+	return net.JoinHostPort(unbracket(host), port)
+}
+
+// ListenNetwork narrows network ("tcp" or "udp") to its IPv4 or IPv6 form
+// when host is an IP literal, so "0.0.0.0" listens on IPv4 only and "::1"
+// on IPv6 only. Host names, "" and "::" keep the dual-stack network, which
+// accepts both families on one socket.
+func ListenNetwork(network, host string) string {
+	if network != "tcp" && network != "udp" {
+		return network
+	}
+	addr, err := netip.ParseAddr(unbracket(host))
+	if err != nil || addr == netip.IPv6Unspecified() {
+		return network
+	}
+	if addr.Unmap().Is4() {
+		return network + "4"
+	}
+	return network + "6"
+}
+
+// serverNetwork returns network when a server sets one, else picks one
+// for host from base
+func serverNetwork(network, base, host string) string {
+	if network != "" {
+		return network
+	}
+	return ListenNetwork(base, host)
+}
+
+func unbracket(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
@@ -0,0 +1,339 @@
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+	"github.com/jerrychou/go-practice/metrics"
+)
+
+// ContextDialer opens connections to a Forwarder's target. *net.Dialer,
+// *tls.Dialer and *SOCKS5Dialer implement it.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ForwardStats describes one forwarded connection
+type ForwardStats struct {
+	ID     uint64
+	Client string
+	Opened time.Time
+	// BytesUp went from the client to the target, BytesDown back
+	BytesUp   int64
+	BytesDown int64
+}
+
+// Forwarder listens locally and forwards each TCP connection to Target,
+// like `ssh -L` without the SSH. The target can be reached over TLS or
+// through a SOCKS5 proxy.
+type Forwarder struct {
+	Listen string
+	Target string
+	// Dialer reaches the target; defaults to a net.Dialer with a 10s timeout
+	Dialer ContextDialer
+	// TLS, when set, wraps the connection to the target in TLS after it is
+	// dialed, so it also works through a SOCKS5 proxy
+	TLS *tls.Config
+	// MaxConns caps concurrent connections; extra clients are closed at
+	// once. Zero means no limit.
+	MaxConns int
+	// IdleTimeout closes connections with no traffic either way for that
+	// long. Zero means no timeout.
+	IdleTimeout time.Duration
+	// Stats counts client connections, their bytes and failed dials
+	Stats *ServerStats
+
+	ln     net.Listener
+	mu     sync.Mutex
+	conns  map[uint64]*forwardConn
+	nextID uint64
+	wg     sync.WaitGroup
+
+	total     atomic.Int64
+	rejected  atomic.Int64
+	bytesUp   atomic.Int64
+	bytesDown atomic.Int64
+}
+
+type forwardConn struct {
+	stats      ForwardStats
+	up, down   atomic.Int64
+	client     net.Conn
+	idleExpire atomic.Bool
+
+	mu     sync.Mutex // guards the fields below, which Stop may race with
+	target net.Conn
+	idle   *data_structure.WheelTimer
+	closed bool
+}
+
+// NewForwarder creates a forwarder from listen, e.g. "127.0.0.1:5433", to
+// target, e.g. "db.internal:5432"
+func NewForwarder(listen, target string) *Forwarder {
+	return &Forwarder{Listen: listen, Target: target, Stats: NewServerStats("tcp_forward")}
+}
+
+// Start accepts connections until Stop is called
+func (f *Forwarder) Start() error {
+	network := "tcp"
+	if host, _, err := net.SplitHostPort(f.Listen); err == nil {
+		network = ListenNetwork("tcp", host)
+	}
+	ln, err := net.Listen(network, f.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to start forwarder: %w", err)
+	}
+	ln = metrics.Listener("tcp_forward", ln)
+
+	f.mu.Lock()
+	f.ln = ln
+	f.conns = make(map[uint64]*forwardConn)
+	f.mu.Unlock()
+	f.Stats.register(ln.Addr().String())
+	fmt.Printf("🔀 Forwarding %s → %s\n", ln.Addr(), f.Target)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			f.Stats.Error()
+			fmt.Printf("❌ Error accepting connection: %v\n", err)
+			continue
+		}
+
+		conn = f.Stats.TrackConn(conn)
+		fc, ok := f.track(conn)
+		if !ok {
+			f.rejected.Add(1)
+			fmt.Printf("⛔ Rejected %s: %d connections already open\n", conn.RemoteAddr(), f.MaxConns)
+			conn.Close()
+			continue
+		}
+		f.wg.Add(1)
+		go f.forward(fc)
+	}
+}
+
+// Addr returns the address the forwarder listens on, or nil before Start
+func (f *Forwarder) Addr() net.Addr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ln == nil {
+		return nil
+	}
+	return f.ln.Addr()
+}
+
+// Stop closes the listener and every open connection, then waits for
+// them to finish
+func (f *Forwarder) Stop() error {
+	f.mu.Lock()
+	ln := f.ln
+	conns := make([]*forwardConn, 0, len(f.conns))
+	for _, fc := range f.conns {
+		conns = append(conns, fc)
+	}
+	f.mu.Unlock()
+	if ln == nil {
+		return nil
+	}
+
+	f.Stats.unregister()
+	err := ln.Close()
+	for _, fc := range conns {
+		fc.close()
+	}
+	f.wg.Wait()
+	return err
+}
+
+// Connections returns the open connections, oldest first
+func (f *Forwarder) Connections() []ForwardStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := make([]ForwardStats, 0, len(f.conns))
+	for _, fc := range f.conns {
+		stats = append(stats, fc.snapshot())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// Totals returns the number of connections forwarded and rejected, and the
+// bytes sent each way, since Start
+func (f *Forwarder) Totals() (conns, rejected, bytesUp, bytesDown int64) {
+	return f.total.Load(), f.rejected.Load(), f.bytesUp.Load(), f.bytesDown.Load()
+}
+
+// track registers a new client unless MaxConns are already open
+func (f *Forwarder) track(conn net.Conn) (*forwardConn, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.MaxConns > 0 && len(f.conns) >= f.MaxConns {
+		return nil, false
+	}
+	f.nextID++
+	fc := &forwardConn{
+		stats:  ForwardStats{ID: f.nextID, Client: conn.RemoteAddr().String(), Opened: time.Now()},
+		client: conn,
+	}
+	f.conns[fc.stats.ID] = fc
+	f.total.Add(1)
+	return fc, true
+}
+
+func (f *Forwarder) untrack(fc *forwardConn) {
+	f.mu.Lock()
+	delete(f.conns, fc.stats.ID)
+	f.mu.Unlock()
+	f.bytesUp.Add(fc.up.Load())
+	f.bytesDown.Add(fc.down.Load())
+}
+
+func (f *Forwarder) forward(fc *forwardConn) {
+	defer f.wg.Done()
+	defer f.untrack(fc)
+	defer fc.close()
+
+	target, err := f.dial()
+	if err != nil {
+		f.Stats.Error()
+		fmt.Printf("❌ Failed to reach %s for %s: %v\n", f.Target, fc.stats.Client, err)
+		return
+	}
+	if !fc.connected(target, f.IdleTimeout) {
+		return // Stop closed the client while dialing
+	}
+	fmt.Printf("📞 #%d %s → %s\n", fc.stats.ID, fc.stats.Client, f.Target)
+
+	done := make(chan struct{})
+	go func() {
+		fc.pipe(target, fc.client, &fc.up, f.IdleTimeout)
+		close(done)
+	}()
+	fc.pipe(fc.client, target, &fc.down, f.IdleTimeout)
+	<-done
+
+	reason := "closed"
+	if fc.idleExpire.Load() {
+		reason = "idle timeout"
+	}
+	stats := fc.snapshot()
+	fmt.Printf("🔌 #%d %s %s after %s: %d bytes up, %d down\n",
+		stats.ID, stats.Client, reason, time.Since(stats.Opened).Round(time.Millisecond), stats.BytesUp, stats.BytesDown)
+}
+
+func (f *Forwarder) dial() (net.Conn, error) {
+	dialer := f.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 10 * time.Second}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", f.Target)
+	if err != nil {
+		return nil, err
+	}
+	if f.TLS == nil {
+		return conn, nil
+	}
+
+	config := f.TLS.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(f.Target)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// pipe copies src to dst, counting bytes and pushing back the idle
+// timeout, then half-closes dst so the far side sees EOF
+func (fc *forwardConn) pipe(dst, src net.Conn, counter *atomic.Int64, idle time.Duration) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			counter.Add(int64(n))
+			fc.touch(idle)
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				fc.close()
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fc.close()
+				return
+			}
+			break
+		}
+	}
+	if cw, ok := dst.(interface{ CloseWrite() error }); !ok || cw.CloseWrite() != nil {
+		fc.close()
+	}
+}
+
+// connected records the target connection and starts the idle timer,
+// unless the connection was closed meanwhile
+func (fc *forwardConn) connected(target net.Conn, idle time.Duration) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.closed {
+		target.Close()
+		return false
+	}
+	fc.target = target
+	if idle > 0 {
+		fc.idle = idleTimers().Schedule(idle, func() {
+			fc.idleExpire.Store(true)
+			fc.close()
+		})
+	}
+	return true
+}
+
+func (fc *forwardConn) touch(idle time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.idle != nil && !fc.closed {
+		fc.idle.Reset(idle)
+	}
+}
+
+func (fc *forwardConn) close() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.closed {
+		return
+	}
+	fc.closed = true
+	if fc.idle != nil {
+		fc.idle.Cancel()
+	}
+	fc.client.Close()
+	if fc.target != nil {
+		fc.target.Close()
+	}
+}
+
+func (fc *forwardConn) snapshot() ForwardStats {
+	stats := fc.stats
+	stats.BytesUp = fc.up.Load()
+	stats.BytesDown = fc.down.Load()
+	return stats
+}
@@ -0,0 +1,522 @@
+package net
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"sync"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jerrychou/go-practice/encoding"
+)
+
+// The frame conformance checks generate random cases from a seed, so a
+// failure prints the seed and case number and replays exactly: case i of
+// seed s always draws from rand.NewPCG(s, i).
+
+// FrameCheck is the outcome of one property over many random cases
+type FrameCheck struct {
+	Name  string
+	Cases int
+	// Failures describes the first failing cases, each with the seed and
+	// case number to replay it
+	Failures []string
+	// Failed counts every failing case, including those not described
+	Failed   int
+	Duration time.Duration
+}
+
+// maxReportedFailures bounds FrameCheck.Failures and SoakReport.Errors
+const maxReportedFailures = 5
+
+// OK reports whether every case passed
+func (c FrameCheck) OK() bool { return c.Failed == 0 }
+
+func (c FrameCheck) String() string {
+	status := "✅"
+	if !c.OK() {
+		status = "❌"
+	}
+	s := fmt.Sprintf("%s %-22s %6d cases in %v", status, c.Name, c.Cases, c.Duration.Round(time.Millisecond))
+	if !c.OK() {
+		s += fmt.Sprintf(", %d failed", c.Failed)
+		for _, f := range c.Failures {
+			s += "\n     " + f
+		}
+	}
+	return s
+}
+
+func (c *FrameCheck) fail(seed int64, i int, err error) {
+	c.Failed++
+	if len(c.Failures) < maxReportedFailures {
+		c.Failures = append(c.Failures, fmt.Sprintf("seed %d case %d: %v", seed, i, err))
+	}
+}
+
+// FrameConformance runs the frame properties and the decoder fuzzer
+type FrameConformance struct {
+	// Seed picks the random cases; zero uses the clock. The seed used is
+	// in the failures so a run can be repeated.
+	Seed int64
+	// Cases is how many random cases each property runs; zero means 1000
+	Cases int
+	// MaxPayload bounds random payloads in bytes; zero means 4KiB. A few
+	// cases always go above encoding's 64KiB up-front allocation limit.
+	MaxPayload int
+}
+
+// Run checks every property and returns one FrameCheck per property
+func (fc FrameConformance) Run() []FrameCheck {
+	if fc.Seed == 0 {
+		fc.Seed = time.Now().UnixNano()
+	}
+	if fc.Cases <= 0 {
+		fc.Cases = 1000
+	}
+	if fc.MaxPayload <= 0 {
+		fc.MaxPayload = 4 << 10
+	}
+
+	properties := []struct {
+		name  string
+		check func(rng *rand.Rand) error
+	}{
+		{"payload round trip", fc.checkPayloadRoundTrip},
+		{"codec round trip", fc.checkCodecRoundTrip},
+		{"oversized frames", checkOversized},
+		{"malformed input fuzz", fc.checkMalformed},
+	}
+	checks := make([]FrameCheck, 0, len(properties))
+	for _, p := range properties {
+		check := FrameCheck{Name: p.name, Cases: fc.Cases}
+		start := time.Now()
+		for i := range fc.Cases {
+			if err := runCase(p.check, caseRand(fc.Seed, i)); err != nil {
+				check.fail(fc.Seed, i, err)
+			}
+		}
+		check.Duration = time.Since(start)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func caseRand(seed int64, i int) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(i)))
+}
+
+// runCase runs one case, turning a panic into a failure
+func runCase(check func(*rand.Rand) error, rng *rand.Rand) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return check(rng)
+}
+
+// checkPayloadRoundTrip writes random payloads back to back and reads them
+// through a reader that splits the stream at random points: every payload
+// must come back intact, followed by io.EOF
+func (fc FrameConformance) checkPayloadRoundTrip(rng *rand.Rand) error {
+	payloads := make([][]byte, rng.IntN(8))
+	var stream bytes.Buffer
+	for i := range payloads {
+		payloads[i] = randomPayload(rng, fc.MaxPayload)
+		if err := encoding.WriteFramePayload(&stream, payloads[i]); err != nil {
+			return fmt.Errorf("write frame %d of %d bytes: %w", i, len(payloads[i]), err)
+		}
+	}
+
+	r, how := splitReader(rng, stream.Bytes())
+	for i, want := range payloads {
+		got, err := encoding.ReadFramePayload(r)
+		if err != nil {
+			return fmt.Errorf("read frame %d of %d bytes (%s): %w", i, len(want), how, err)
+		}
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("frame %d (%s): got %d bytes, want %d", i, how, len(got), len(want))
+		}
+	}
+	if _, err := encoding.ReadFramePayload(r); err != io.EOF {
+		return fmt.Errorf("after %d frames (%s): got %v, want io.EOF", len(payloads), how, err)
+	}
+	return nil
+}
+
+// checkCodecRoundTrip sends a random message with each codec and requires
+// the decoded message to equal it
+func (fc FrameConformance) checkCodecRoundTrip(rng *rand.Rand) error {
+	want := randomMessage(rng)
+	for _, codec := range frameCodecs {
+		var stream bytes.Buffer
+		if err := encoding.WriteFrame(&stream, codec, want); err != nil {
+			return fmt.Errorf("%s: write %+v: %w", codec.Name(), want, err)
+		}
+		r, how := splitReader(rng, stream.Bytes())
+		var got encoding.UserProto
+		if err := encoding.ReadFrame(r, codec, &got); err != nil {
+			return fmt.Errorf("%s (%s): read %+v: %w", codec.Name(), how, want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("%s (%s): sent %+v, got %+v", codec.Name(), how, want, got)
+		}
+	}
+	return nil
+}
+
+// checkOversized requires frames over encoding.MaxFrameSize to be refused
+// on both sides: the writer writes nothing and the reader fails before
+// reading the payload
+func checkOversized(rng *rand.Rand) error {
+	size := encoding.MaxFrameSize + 1 + rng.IntN(1<<20)
+	var stream bytes.Buffer
+	if err := encoding.WriteFramePayload(&stream, oversizedPayload()[:size]); !errors.Is(err, encoding.ErrFrameTooLarge) || stream.Len() > 0 {
+		return fmt.Errorf("write of %d bytes: wrote %d bytes, err %v", size, stream.Len(), err)
+	}
+
+	header := binary.BigEndian.AppendUint32(nil, uint32(size))
+	if _, err := encoding.ReadFramePayload(bytes.NewReader(header)); !errors.Is(err, encoding.ErrFrameTooLarge) {
+		return fmt.Errorf("read of a %d byte length prefix: got %v, want encoding.ErrFrameTooLarge", size, err)
+	}
+	return nil
+}
+
+// oversizedPayload is shared by the cases of checkOversized, which only
+// need its length
+var oversizedPayload = sync.OnceValue(func() []byte { return make([]byte, encoding.MaxFrameSize+1<<20) })
+
+// checkMalformed mutates a valid stream and runs FuzzFrameDecoder on it
+func (fc FrameConformance) checkMalformed(rng *rand.Rand) error {
+	var stream bytes.Buffer
+	for range 1 + rng.IntN(4) {
+		if rng.IntN(2) == 0 {
+			encoding.WriteFramePayload(&stream, randomPayload(rng, fc.MaxPayload))
+		} else {
+			encoding.WriteFrame(&stream, frameCodecs[rng.IntN(len(frameCodecs))], randomMessage(rng))
+		}
+	}
+	data := stream.Bytes()
+	var applied []string
+	for range 1 + rng.IntN(3) {
+		var name string
+		data, name = mutate(rng, data)
+		applied = append(applied, name)
+	}
+	if err := FuzzFrameDecoder(data); err != nil {
+		return fmt.Errorf("after %s: %w", strings.Join(applied, ", "), err)
+	}
+	return nil
+}
+
+// frameCodecs are the codecs the checks send messages with. XML and CSV
+// are left out: XML drops control characters and CSV needs a slice.
+var frameCodecs = []encoding.Codec{encoding.JSON, encoding.Msgpack, encoding.Protobuf}
+
+// FuzzFrameDecoder is a fuzz target for the frame decoder. It reads frames
+// from data until an error and returns an error when an invariant breaks:
+//
+//   - decoding never panics, for the framing or for any codec's payload
+//   - the frames read are exactly the bytes they came from, and writing
+//     them again reproduces data up to where reading stopped
+//   - reading stops with io.EOF only at the end of data, with
+//     encoding.ErrFrameTooLarge for a length over encoding.MaxFrameSize, and
+//     otherwise with io.ErrUnexpectedEOF because data ended inside a frame
+//   - a payload a codec decodes re-encodes to bytes that decode to the
+//     same bytes again
+//
+// FrameConformance runs it on mutated streams, and FuzzFrames in
+// frame_fuzz_test.go runs it under native fuzzing, seeded with
+// FrameFuzzCorpus:
+//
+//	go test -fuzz=FuzzFrames ./net
+func FuzzFrameDecoder(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic decoding %s: %v", hexPrefix(data), r)
+		}
+	}()
+
+	r := bytes.NewReader(data)
+	var rewritten bytes.Buffer
+	offset := 0
+	for {
+		payload, err := encoding.ReadFramePayload(r)
+		if err != nil {
+			if err := checkStop(data[offset:], err); err != nil {
+				return fmt.Errorf("at offset %d of %d: %w", offset, len(data), err)
+			}
+			break
+		}
+		start := offset + 4
+		offset = start + len(payload)
+		if offset > len(data) || !bytes.Equal(payload, data[start:offset]) {
+			return fmt.Errorf("frame at offset %d: payload does not match the input", start-4)
+		}
+		encoding.WriteFramePayload(&rewritten, payload)
+		for _, codec := range frameCodecs {
+			if err := checkReencode(codec, payload); err != nil {
+				return fmt.Errorf("frame at offset %d: %s: %w", start-4, codec.Name(), err)
+			}
+		}
+	}
+	if !bytes.Equal(rewritten.Bytes(), data[:offset]) {
+		return fmt.Errorf("rewriting %d frames does not reproduce the input", offset)
+	}
+	return nil
+}
+
+// checkStop checks the error reading stopped with, given the bytes left
+func checkStop(rest []byte, err error) error {
+	switch {
+	case len(rest) == 0:
+		if err != io.EOF {
+			return fmt.Errorf("at the end of the input: got %v, want io.EOF", err)
+		}
+	case len(rest) >= 4 && binary.BigEndian.Uint32(rest) > encoding.MaxFrameSize:
+		if !errors.Is(err, encoding.ErrFrameTooLarge) {
+			return fmt.Errorf("length %d: got %v, want encoding.ErrFrameTooLarge", binary.BigEndian.Uint32(rest), err)
+		}
+	default:
+		if err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("%d bytes into a frame: got %v, want io.ErrUnexpectedEOF", len(rest), err)
+		}
+	}
+	return nil
+}
+
+// checkReencode decodes payload as a message and, when that succeeds,
+// requires encoding the message to be stable. Decoding is lossy (unknown
+// fields are dropped), so it is the re-encoded bytes that must round-trip.
+func checkReencode(codec encoding.Codec, payload []byte) error {
+	var first encoding.UserProto
+	if codec.Unmarshal(payload, &first) != nil {
+		return nil // rejecting malformed input is fine
+	}
+	encoded, err := codec.Marshal(first)
+	if err != nil {
+		if codec == encoding.JSON && (math.IsNaN(first.Rating) || math.IsInf(first.Rating, 0)) {
+			return nil // JSON has no NaN or infinity, which binary codecs can carry
+		}
+		return fmt.Errorf("decoded %+v but cannot encode it: %w", first, err)
+	}
+	var second encoding.UserProto
+	if err := codec.Unmarshal(encoded, &second); err != nil {
+		return fmt.Errorf("cannot decode its own encoding of %+v: %w", first, err)
+	}
+	again, _ := codec.Marshal(second)
+	if !bytes.Equal(encoded, again) {
+		return fmt.Errorf("encoding of %+v is not stable: %x then %x", first, encoded, again)
+	}
+	return nil
+}
+
+// FrameFuzzCorpus returns seed inputs for fuzzing the frame decoder: empty
+// input, frames of each codec, and the edge cases of the length prefix
+func FrameFuzzCorpus() [][]byte {
+	msg := encoding.UserProto{ID: 7, Name: "Zoë", Roles: []string{"admin"}, Scores: []int32{-1, 1 << 20}, Balance: -42, Rating: 4.5}
+	corpus := [][]byte{
+		{},
+		{0, 0, 0, 0},
+		{0, 0, 0},
+		{0, 0, 0, 5},
+		{0, 0, 0, 5, 'h', 'e'},
+		binary.BigEndian.AppendUint32(nil, encoding.MaxFrameSize),
+		binary.BigEndian.AppendUint32(nil, encoding.MaxFrameSize+1),
+		{0xff, 0xff, 0xff, 0xff},
+	}
+	for _, codec := range frameCodecs {
+		var stream bytes.Buffer
+		encoding.WriteFrame(&stream, codec, msg)
+		encoding.WriteFrame(&stream, codec, encoding.UserProto{})
+		corpus = append(corpus, stream.Bytes())
+	}
+	return corpus
+}
+
+// mutate applies one random corruption to a copy of data and names it
+func mutate(rng *rand.Rand, data []byte) ([]byte, string) {
+	out := bytes.Clone(data)
+	pos := func() int { return rng.IntN(len(out) + 1) }
+	switch rng.IntN(8) {
+	case 0:
+		n := pos()
+		return out[:n], fmt.Sprintf("truncate at %d", n)
+	case 7:
+		// Cut next to a frame boundary, where off-by-one errors live
+		starts := frameStarts(out)
+		n := min(starts[rng.IntN(len(starts))]+rng.IntN(6), len(out))
+		return out[:n], fmt.Sprintf("truncate near a boundary at %d", n)
+	case 1:
+		if len(out) == 0 {
+			break
+		}
+		i, bit := rng.IntN(len(out)), rng.IntN(8)
+		out[i] ^= 1 << bit
+		return out, fmt.Sprintf("flip bit %d of byte %d", bit, i)
+	case 2:
+		if len(out) < 4 {
+			break
+		}
+		interesting := []uint32{0, 1, 3, 4, 0x7f, 0x80, 0xffff, 0x10000, encoding.MaxFrameSize, encoding.MaxFrameSize + 1, 1 << 31, math.MaxUint32}
+		n := interesting[rng.IntN(len(interesting))]
+		binary.BigEndian.PutUint32(out, n)
+		return out, fmt.Sprintf("set first length to %d", n)
+	case 3:
+		i, junk := pos(), randomPayload(rng, 16)
+		return append(out[:i], append(junk, out[i:]...)...), fmt.Sprintf("insert %d bytes at %d", len(junk), i)
+	case 4:
+		i := pos()
+		j := i + rng.IntN(len(out)-i+1)
+		return append(out[:i], out[j:]...), fmt.Sprintf("delete bytes %d-%d", i, j)
+	case 5:
+		i := pos()
+		j := i + rng.IntN(len(out)-i+1)
+		return append(out[:j], append(bytes.Clone(out[i:j]), out[j:]...)...), fmt.Sprintf("repeat bytes %d-%d", i, j)
+	}
+	junk := randomPayload(rng, 64)
+	return junk, fmt.Sprintf("replace with %d random bytes", len(junk))
+}
+
+// frameStarts follows the length prefixes of data and returns the offsets
+// where its frames begin, including the end of the last complete frame
+func frameStarts(data []byte) []int {
+	starts := []int{0}
+	for at := 0; at+4 <= len(data); {
+		at += 4 + int(binary.BigEndian.Uint32(data[at:]))
+		if at > len(data) {
+			break
+		}
+		starts = append(starts, at)
+	}
+	return starts
+}
+
+// splitReader returns a reader over data that hands it out in pieces, the
+// way a network connection does, and describes how
+func splitReader(rng *rand.Rand, data []byte) (io.Reader, string) {
+	switch rng.IntN(5) {
+	case 0:
+		return bytes.NewReader(data), "whole"
+	case 1:
+		return iotest.OneByteReader(bytes.NewReader(data)), "one byte at a time"
+	case 2:
+		return iotest.HalfReader(bytes.NewReader(data)), "half reads"
+	case 3:
+		return iotest.DataErrReader(bytes.NewReader(data)), "EOF with the last data"
+	}
+	max := 1 + rng.IntN(64)
+	return &chunkReader{r: bytes.NewReader(data), rng: rng, max: max}, fmt.Sprintf("chunks of 1-%d bytes", max)
+}
+
+type chunkReader struct {
+	r   io.Reader
+	rng *rand.Rand
+	max int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.max {
+		p = p[:1+c.rng.IntN(c.max)]
+	}
+	return c.r.Read(p)
+}
+
+// randomPayload returns random bytes, favouring the sizes where framing
+// bugs hide: empty, tiny, and just past the up-front allocation limit
+func randomPayload(rng *rand.Rand, max int) []byte {
+	var n int
+	switch rng.IntN(20) {
+	case 0:
+		n = 0
+	case 1:
+		n = 1 + rng.IntN(4)
+	case 2:
+		n = 64<<10 + rng.IntN(3) - 1
+	default:
+		n = rng.IntN(max + 1)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.UintN(256))
+	}
+	return b
+}
+
+// randomMessage returns a message every frame codec can carry: valid UTF-8
+// strings, finite floats, and nil rather than empty slices, since JSON and
+// protobuf do not tell the two apart
+func randomMessage(rng *rand.Rand) encoding.UserProto {
+	msg := encoding.UserProto{
+		ID:      randomInt64(rng),
+		Name:    randomString(rng),
+		Email:   randomString(rng),
+		Balance: randomInt64(rng),
+	}
+	for range rng.IntN(4) {
+		msg.Roles = append(msg.Roles, randomString(rng))
+	}
+	for range rng.IntN(6) {
+		msg.Scores = append(msg.Scores, int32(randomInt64(rng)))
+	}
+	switch rng.IntN(4) {
+	case 0:
+		msg.Rating = 0
+	case 1:
+		msg.Rating = math.Float64frombits(rng.Uint64())
+		if math.IsNaN(msg.Rating) || math.IsInf(msg.Rating, 0) {
+			msg.Rating = math.MaxFloat64
+		}
+	default:
+		msg.Rating = rng.NormFloat64() * 1000
+	}
+	return msg
+}
+
+func randomInt64(rng *rand.Rand) int64 {
+	switch rng.IntN(4) {
+	case 0:
+		return []int64{0, 1, -1, math.MaxInt64, math.MinInt64, math.MaxInt32, math.MinInt32}[rng.IntN(7)]
+	case 1:
+		return rng.Int64N(256) - 128
+	}
+	return int64(rng.Uint64())
+}
+
+// randomString mixes ASCII, multi-byte runes and the characters encoders
+// escape
+func randomString(rng *rand.Rand) string {
+	var b strings.Builder
+	for range rng.IntN(24) {
+		var r rune
+		switch rng.IntN(4) {
+		case 0:
+			r = []rune{'"', '\\', '\n', '\t', 0, '<', '&', 0x7f, 0x2028}[rng.IntN(9)]
+		case 1:
+			r = rune(rng.IntN(utf8.MaxRune + 1))
+			if !utf8.ValidRune(r) {
+				r = '�'
+			}
+		default:
+			r = rune(' ' + rng.IntN(95))
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func hexPrefix(data []byte) string {
+	if len(data) > 64 {
+		return fmt.Sprintf("%x… (%d bytes)", data[:64], len(data))
+	}
+	return fmt.Sprintf("%x", data)
+}
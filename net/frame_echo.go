@@ -0,0 +1,168 @@
+package net
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/metrics"
+)
+
+// FrameEchoServer echoes length-prefixed frames, the framing written by
+// encoding.WriteFrame: every frame a client sends comes back unchanged.
+// It does not decode payloads, so it serves any codec, and it is the server
+// the frame conformance and soak checks run against.
+type FrameEchoServer struct {
+	Address string
+	Port    string
+	// Network is "tcp4", "tcp6" or "tcp" (both); empty picks from Address
+	Network string
+	// Middleware wraps each connection's handler, first outermost
+	Middleware []ConnMiddleware
+	// Stats counts connections, bytes, frames and errors
+	Stats *ServerStats
+	// Socket tunes the listener and each accepted connection
+	Socket SocketOptions
+	// IdleTimeout drops clients that send nothing for this long; zero
+	// means 30 seconds
+	IdleTimeout time.Duration
+
+	ln       net.Listener
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	stopped  bool
+	handlers sync.WaitGroup
+}
+
+func NewFrameEchoServer(address, port string, opts ...SocketOption) *FrameEchoServer {
+	return &FrameEchoServer{
+		Address: address,
+		Port:    port,
+		Stats:   NewServerStats("frame_echo"),
+		Socket:  NewSocketOptions(opts...),
+	}
+}
+
+// Listen opens the listener, so Addr is known before Serve is called; a
+// port of "0" picks a free one
+func (s *FrameEchoServer) Listen() error {
+	address := HostPort(s.Address, s.Port)
+	ln, err := s.Socket.Listen(context.Background(), serverNetwork(s.Network, "tcp", s.Address), address)
+	if err != nil {
+		return fmt.Errorf("failed to start frame echo server: %w", err)
+	}
+	s.ln = metrics.Listener("frame_echo", ln)
+	s.conns = make(map[net.Conn]struct{})
+	s.Stats.register(ln.Addr().String())
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before Listen
+func (s *FrameEchoServer) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Serve accepts connections until Stop is called
+func (s *FrameEchoServer) Serve() error {
+	handler := ChainConn(s.handleConnection, s.Middleware...)
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			s.Stats.Error()
+			fmt.Printf("❌ Error accepting connection: %v\n", err)
+			continue
+		}
+
+		conn = s.Stats.TrackConn(conn)
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.handlers.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.handlers.Done()
+			defer s.forget(conn)
+			handler(conn)
+		}()
+	}
+}
+
+// Start listens and serves until Stop is called
+func (s *FrameEchoServer) Start() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	fmt.Printf("🚀 Frame echo server started on %s (socket: %s)\n", s.Addr(), s.Socket)
+	return s.Serve()
+}
+
+// Use adds middleware to the connection handler; call it before Start
+func (s *FrameEchoServer) Use(middleware ...ConnMiddleware) *FrameEchoServer {
+	s.Middleware = append(s.Middleware, middleware...)
+	return s
+}
+
+// Stop closes the listener and every open connection, and waits for their
+// handlers to return
+func (s *FrameEchoServer) Stop() error {
+	if s.ln == nil {
+		return nil
+	}
+	s.Stats.unregister()
+	err := s.ln.Close()
+	s.mu.Lock()
+	s.stopped = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.handlers.Wait()
+	return err
+}
+
+func (s *FrameEchoServer) forget(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+// handleConnection echoes frames until the client closes the connection.
+// A frame over encoding.MaxFrameSize ends it, since the stream cannot be
+// resynchronised after a bad length prefix.
+func (s *FrameEchoServer) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	idle := closeWhenIdle(conn, cmp.Or(s.IdleTimeout, 30*time.Second))
+	defer idle.stop()
+
+	for {
+		payload, err := encoding.ReadFramePayload(idle)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				s.Stats.Error()
+			}
+			return
+		}
+		s.Stats.Message()
+		if err := encoding.WriteFramePayload(conn, payload); err != nil {
+			s.Stats.Error()
+			return
+		}
+	}
+}
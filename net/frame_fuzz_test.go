@@ -0,0 +1,21 @@
+package net_test
+
+import (
+	"testing"
+
+	"github.com/jerrychou/go-practice/net"
+)
+
+// FuzzFrames checks the frame decoder against arbitrary streams; run it with
+//
+//	go test -fuzz=FuzzFrames ./net
+func FuzzFrames(f *testing.F) {
+	for _, seed := range net.FrameFuzzCorpus() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := net.FuzzFrameDecoder(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
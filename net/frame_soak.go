@@ -0,0 +1,206 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/encoding"
+	"github.com/jerrychou/go-practice/format"
+)
+
+// SoakOptions configures a frame echo soak test
+type SoakOptions struct {
+	// Clients connect at once; zero means 1000
+	Clients int
+	// Frames each client sends and checks; zero means 20
+	Frames int
+	// MaxPayload bounds the random payloads in bytes; zero means 1KiB
+	MaxPayload int
+	// Seed picks the payloads; zero uses the clock
+	Seed int64
+	// Timeout bounds each client's whole exchange; zero means 30 seconds
+	Timeout time.Duration
+	// Socket tunes the clients' connections
+	Socket SocketOptions
+}
+
+// SoakReport is the outcome of a soak test
+type SoakReport struct {
+	Clients int
+	// Failed counts clients that could not connect or got a wrong echo
+	Failed int
+	// Errors describes the first failures
+	Errors []string
+	// Frames and Bytes count the frames echoed intact and their payloads
+	Frames   int64
+	Bytes    int64
+	Duration time.Duration
+	// P50, P99 and Max are frame round-trip times
+	P50, P99, Max time.Duration
+	// OpenConns counts server connections still open after every client
+	// closed, and ServerErrors the reads and writes that failed on the
+	// server; SoakFrameEchoServer sets both, and anything but 0 is a bug
+	OpenConns    int64
+	ServerErrors int64
+}
+
+// OK reports whether every client succeeded and the server saw no errors
+// and leaked no connections
+func (r SoakReport) OK() bool { return r.Failed == 0 && r.OpenConns == 0 && r.ServerErrors == 0 }
+
+func (r SoakReport) String() string {
+	status := "✅"
+	if !r.OK() {
+		status = "❌"
+	}
+	s := fmt.Sprintf("%s %d clients, %d failed: %d frames (%s) in %v, %.0f frames/s, round trip p50 %v p99 %v max %v",
+		status, r.Clients, r.Failed, r.Frames, format.HumanizeBytes(r.Bytes), r.Duration.Round(time.Millisecond),
+		float64(r.Frames)/r.Duration.Seconds(), r.P50, r.P99, r.Max)
+	if r.OpenConns > 0 {
+		s += fmt.Sprintf("\n     %d server connections left open", r.OpenConns)
+	}
+	if r.ServerErrors > 0 {
+		s += fmt.Sprintf("\n     %d errors on the server", r.ServerErrors)
+	}
+	for _, e := range r.Errors {
+		s += "\n     " + e
+	}
+	return s
+}
+
+// SoakFrameEcho connects opts.Clients clients to the frame echo server at
+// address at the same moment. Each sends its frames one at a time and
+// checks every echo byte for byte, so races that mix up connections or
+// corrupt buffers show up as failures. It returns when every client has
+// finished or ctx is done.
+func SoakFrameEcho(ctx context.Context, address string, opts SoakOptions) SoakReport {
+	if opts.Clients <= 0 {
+		opts.Clients = 1000
+	}
+	if opts.Frames <= 0 {
+		opts.Frames = 20
+	}
+	if opts.MaxPayload <= 0 {
+		opts.MaxPayload = 1 << 10
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	var (
+		mu        sync.Mutex
+		report    = SoakReport{Clients: opts.Clients}
+		latencies = make([]time.Duration, 0, opts.Clients*opts.Frames)
+		frames    atomic.Int64
+		bytesOK   atomic.Int64
+		wg        sync.WaitGroup
+	)
+	start := make(chan struct{})
+	for i := range opts.Clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rtts, err := soakClient(ctx, address, opts, i, &frames, &bytesOK)
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, rtts...)
+			if err != nil {
+				report.Failed++
+				if len(report.Errors) < maxReportedFailures {
+					report.Errors = append(report.Errors, fmt.Sprintf("client %d (seed %d): %v", i, opts.Seed, err))
+				}
+			}
+		}()
+	}
+
+	began := time.Now()
+	close(start)
+	wg.Wait()
+	report.Duration = time.Since(began)
+	report.Frames = frames.Load()
+	report.Bytes = bytesOK.Load()
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		report.P50 = latencies[len(latencies)/2]
+		report.P99 = latencies[len(latencies)*99/100]
+		report.Max = latencies[len(latencies)-1]
+	}
+	return report
+}
+
+// soakClient runs client i of a soak test and returns its round-trip times
+func soakClient(ctx context.Context, address string, opts SoakOptions, i int, frames, bytesOK *atomic.Int64) ([]time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	conn, err := opts.Socket.Dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	rng := caseRand(opts.Seed, i)
+	rtts := make([]time.Duration, 0, opts.Frames)
+	for n := range opts.Frames {
+		payload := randomPayload(rng, opts.MaxPayload)
+		sent := time.Now()
+		if err := encoding.WriteFramePayload(conn, payload); err != nil {
+			return rtts, fmt.Errorf("frame %d: write: %w", n, err)
+		}
+		echo, err := encoding.ReadFramePayload(conn)
+		if err != nil {
+			return rtts, fmt.Errorf("frame %d: read echo: %w", n, err)
+		}
+		rtts = append(rtts, time.Since(sent))
+		if !bytes.Equal(echo, payload) {
+			return rtts, fmt.Errorf("frame %d: sent %d bytes, echo is %d bytes and differs", n, len(payload), len(echo))
+		}
+		frames.Add(1)
+		bytesOK.Add(int64(len(payload)))
+	}
+	return rtts, nil
+}
+
+// SoakFrameEchoServer runs SoakFrameEcho against a FrameEchoServer it
+// starts on a free loopback port, then waits for the server to close every
+// connection and reports those still open in OpenConns. Combined with a
+// goroutine snapshot before and after (see testingutil.LeakedGoroutines)
+// it catches handlers that never return.
+func SoakFrameEchoServer(ctx context.Context, opts SoakOptions) (SoakReport, error) {
+	server := NewFrameEchoServer("127.0.0.1", "0")
+	if err := server.Listen(); err != nil {
+		return SoakReport{}, err
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+
+	report := SoakFrameEcho(ctx, server.Addr().String(), opts)
+
+	// Handlers see the clients' close a moment after the clients return
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Stats.Snapshot().ActiveConns > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := server.Stats.Snapshot()
+	report.OpenConns, report.ServerErrors = stats.ActiveConns, stats.Errors
+
+	server.Stop()
+	if err := <-served; err != nil {
+		return report, err
+	}
+	return report, nil
+}
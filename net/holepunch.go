@@ -0,0 +1,671 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UDP hole punching lets two peers behind NATs talk directly. Both register
+// with a RendezvousServer, which tells each the public address its NAT gave
+// it, like STUN. When one asks to connect, the server sends each the
+// other's public and private addresses; both then fire PUNCH datagrams at
+// them at once. Each peer's outgoing packets open a mapping in its own NAT
+// that lets the other's packets in, so unless a NAT is symmetric a direct
+// path opens within a few round trips. If none does, the peers fall back to
+// relaying their data through the server.
+//
+// Messages are single datagrams of space separated fields:
+//
+//	REGISTER <id> <private addr>       peer -> server, also the keepalive
+//	REGISTERED <public addr>           server -> peer
+//	CONNECT <id> <peer id>             peer -> server
+//	PEER <peer id> <public> <private>  server -> both peers
+//	ERROR <peer id> <message>          server -> peer
+//	PUNCH <id> / PUNCHED <id>          peer <-> peer
+//	PING <id>                          peer <-> peer keepalive
+//	DATA <id> <payload>                peer <-> peer, or server -> peer relayed
+//	RELAY <id> <peer id> <payload>     peer -> server
+
+var (
+	// ErrUnknownPeer is returned when connecting to a peer the rendezvous
+	// server does not know
+	ErrUnknownPeer = errors.New("peer is not registered")
+	// ErrRelayDisabled is returned when punching failed and the rendezvous
+	// server does not relay
+	ErrRelayDisabled = errors.New("hole punching failed and the server does not relay")
+)
+
+const maxDatagram = 64 * 1024
+
+// RendezvousServer introduces peers to each other and relays for those
+// that cannot punch through
+type RendezvousServer struct {
+	Address string
+	Port    string
+	// PeerTTL forgets peers that have not re-registered for that long
+	PeerTTL time.Duration
+	// Relay forwards data between peers whose NATs block a direct path
+	Relay bool
+	// Stats counts bytes, datagrams and errors
+	Stats *ServerStats
+
+	mu    sync.Mutex
+	conn  *net.UDPConn
+	peers map[string]*rendezvousPeer
+}
+
+type rendezvousPeer struct {
+	public  *net.UDPAddr
+	private string
+	seen    time.Time
+}
+
+// NewRendezvousServer creates a relaying rendezvous server that forgets
+// peers silent for a minute
+func NewRendezvousServer(address, port string) *RendezvousServer {
+	return &RendezvousServer{
+		Address: address,
+		Port:    port,
+		PeerTTL: time.Minute,
+		Relay:   true,
+		Stats:   NewServerStats("udp_rendezvous"),
+		peers:   make(map[string]*rendezvousPeer),
+	}
+}
+
+// Start serves until Stop is called
+func (s *RendezvousServer) Start() error {
+	address := HostPort(s.Address, s.Port)
+	network := serverNetwork("", "udp", s.Address)
+	udpAddr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return fmt.Errorf("failed to resolve UDP address: %w", err)
+	}
+	conn, err := net.ListenUDP(network, udpAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start rendezvous server: %w", err)
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	s.Stats.register(conn.LocalAddr().String())
+	fmt.Printf("🤝 Rendezvous Server started on %s\n", conn.LocalAddr())
+
+	buffer := make([]byte, maxDatagram)
+	for {
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			s.Stats.Error()
+			continue
+		}
+		s.Stats.AddBytes(n, 0)
+		s.Stats.Message()
+		s.handle(from, buffer[:n])
+	}
+}
+
+// Addr returns the address the server listens on, or nil before Start
+func (s *RendezvousServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Stop closes the server's socket
+func (s *RendezvousServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	s.Stats.unregister()
+	return s.conn.Close()
+}
+
+func (s *RendezvousServer) handle(from *net.UDPAddr, msg []byte) {
+	kind, rest := splitField(msg)
+	switch string(kind) {
+	case "REGISTER":
+		id, private := splitField(rest)
+		if len(id) == 0 {
+			return
+		}
+		s.mu.Lock()
+		_, known := s.peers[string(id)]
+		s.peers[string(id)] = &rendezvousPeer{public: from, private: string(private), seen: time.Now()}
+		s.mu.Unlock()
+		if !known {
+			fmt.Printf("📇 %s registered from %s (private %s)\n", id, from, private)
+		}
+		s.send(from, "REGISTERED", from.String())
+	case "CONNECT":
+		id, target := splitField(rest)
+		self, ok := s.lookup(string(id), from)
+		if !ok {
+			return
+		}
+		other, ok := s.lookup(string(target), nil)
+		if !ok {
+			s.send(from, "ERROR", string(target), ErrUnknownPeer.Error())
+			return
+		}
+		fmt.Printf("🔗 Introducing %s (%s) and %s (%s)\n", id, self.public, target, other.public)
+		s.send(self.public, "PEER", string(target), other.public.String(), other.private)
+		s.send(other.public, "PEER", string(id), self.public.String(), self.private)
+	case "RELAY":
+		id, rest := splitField(rest)
+		target, payload := splitField(rest)
+		if _, ok := s.lookup(string(id), from); !ok {
+			return
+		}
+		if !s.Relay {
+			s.send(from, "ERROR", string(target), ErrRelayDisabled.Error())
+			return
+		}
+		if other, ok := s.lookup(string(target), nil); ok {
+			s.sendData(other.public, string(id), payload)
+		}
+	}
+}
+
+// lookup returns a live peer; with from set, only if it is the peer's
+// registered address, so nobody can speak for another peer
+func (s *RendezvousServer) lookup(id string, from *net.UDPAddr) (rendezvousPeer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peer, ok := s.peers[id]
+	if !ok {
+		return rendezvousPeer{}, false
+	}
+	if s.PeerTTL > 0 && time.Since(peer.seen) > s.PeerTTL {
+		delete(s.peers, id)
+		return rendezvousPeer{}, false
+	}
+	if from != nil && peer.public.String() != from.String() {
+		return rendezvousPeer{}, false
+	}
+	return *peer, true
+}
+
+func (s *RendezvousServer) send(to *net.UDPAddr, fields ...string) {
+	s.write(to, []byte(strings.Join(fields, " ")))
+}
+
+func (s *RendezvousServer) sendData(to *net.UDPAddr, from string, payload []byte) {
+	s.write(to, append([]byte("DATA "+from+" "), payload...))
+}
+
+func (s *RendezvousServer) write(to *net.UDPAddr, msg []byte) {
+	n, err := s.conn.WriteToUDP(msg, to)
+	s.Stats.AddBytes(0, n)
+	if err != nil {
+		s.Stats.Error()
+	}
+}
+
+// HolePunchClient is a peer that registers with a rendezvous server and
+// opens PeerSessions to other peers, directly where the NATs allow and
+// through the server otherwise
+type HolePunchClient struct {
+	ID string
+	// Server is the rendezvous server's host:port
+	Server string
+	// PunchTimeout is how long to try for a direct path before relaying
+	PunchTimeout time.Duration
+	// KeepAlive is how often to re-register and ping direct peers, which
+	// keeps the NAT mappings open; most NATs drop idle UDP ones after 30s
+	KeepAlive time.Duration
+	// ForceRelay skips punching and ignores punches, e.g. behind a known
+	// symmetric NAT, so both sides of its sessions relay
+	ForceRelay bool
+
+	conn       *net.UDPConn
+	server     *net.UDPAddr
+	registered chan struct{}
+	incoming   chan *PeerSession
+	done       chan struct{}
+	closeOnce  sync.Once
+
+	mu       sync.Mutex
+	public   *net.UDPAddr
+	sessions map[string]*PeerSession
+}
+
+// PeerSession exchanges datagrams with one peer
+type PeerSession struct {
+	PeerID string
+
+	client    *HolePunchClient
+	requested bool
+	ready     chan struct{}
+	punched   chan struct{}
+	inbox     chan []byte
+	punchOnce sync.Once
+
+	mu       sync.Mutex
+	addr     *net.UDPAddr // nil while relayed
+	err      error
+	decided  bool
+	lastSeen time.Time
+}
+
+// NewHolePunchClient creates a peer called id for the rendezvous server at
+// server, e.g. "rendezvous.example.com:3478"
+func NewHolePunchClient(id, server string) *HolePunchClient {
+	return &HolePunchClient{
+		ID:           id,
+		Server:       server,
+		PunchTimeout: 5 * time.Second,
+		KeepAlive:    15 * time.Second,
+		registered:   make(chan struct{}),
+		incoming:     make(chan *PeerSession, 16),
+		done:         make(chan struct{}),
+		sessions:     make(map[string]*PeerSession),
+	}
+}
+
+// Start opens the client's socket and registers with the server, returning
+// once the server has answered with the client's public address
+func (c *HolePunchClient) Start(ctx context.Context) error {
+	if strings.ContainsAny(c.ID, " \t\r\n") || c.ID == "" {
+		return fmt.Errorf("invalid peer id %q", c.ID)
+	}
+	server, err := net.ResolveUDPAddr("udp", c.Server)
+	if err != nil {
+		return fmt.Errorf("failed to resolve rendezvous server: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	c.conn, c.server = conn, server
+	go c.readLoop()
+
+	// Registration is a datagram too, so it is repeated until answered
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		c.register()
+		select {
+		case <-c.registered:
+			go c.keepAliveLoop()
+			return nil
+		case <-ctx.Done():
+			c.Close()
+			return fmt.Errorf("rendezvous server did not answer: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublicAddr returns the client's address as the server sees it, which is
+// its NAT's mapping; nil before Start
+func (c *HolePunchClient) PublicAddr() *net.UDPAddr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.public
+}
+
+// LocalAddr returns the address of the client's socket
+func (c *HolePunchClient) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Connect opens a session to the peer called peerID, punching a direct path
+// if it can and relaying otherwise
+func (c *HolePunchClient) Connect(ctx context.Context, peerID string) (*PeerSession, error) {
+	session := c.session(peerID, true)
+	c.send(c.server, "CONNECT", c.ID, peerID)
+	select {
+	case <-session.ready:
+	case <-ctx.Done():
+		c.drop(session)
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, net.ErrClosed
+	}
+	if err := session.Err(); err != nil {
+		c.drop(session)
+		return nil, err
+	}
+	return session, nil
+}
+
+// Accept waits for a session another peer opened with Connect
+func (c *HolePunchClient) Accept(ctx context.Context) (*PeerSession, error) {
+	select {
+	case session := <-c.incoming:
+		return session, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the client's socket and ends its sessions
+func (c *HolePunchClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
+
+func (c *HolePunchClient) readLoop() {
+	buffer := make([]byte, maxDatagram)
+	for {
+		n, from, err := c.conn.ReadFromUDP(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		c.handle(from, buffer[:n])
+	}
+}
+
+func (c *HolePunchClient) handle(from *net.UDPAddr, msg []byte) {
+	fromServer := from.String() == c.server.String()
+	kind, rest := splitField(msg)
+	switch string(kind) {
+	case "REGISTERED":
+		if !fromServer {
+			return
+		}
+		public, err := net.ResolveUDPAddr("udp", string(rest))
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		first := c.public == nil
+		c.public = public
+		c.mu.Unlock()
+		if first {
+			close(c.registered)
+		}
+	case "PEER":
+		if !fromServer {
+			return
+		}
+		id, rest := splitField(rest)
+		public, private := splitField(rest)
+		session := c.session(string(id), false)
+		session.punchOnce.Do(func() { go c.punch(session, string(public), string(private)) })
+	case "ERROR":
+		if !fromServer {
+			return
+		}
+		id, message := splitField(rest)
+		c.mu.Lock()
+		session := c.sessions[string(id)]
+		c.mu.Unlock()
+		if session != nil {
+			err := errors.New(string(message))
+			switch string(message) {
+			case ErrUnknownPeer.Error():
+				err = ErrUnknownPeer
+			case ErrRelayDisabled.Error():
+				err = ErrRelayDisabled
+			}
+			session.decide(err)
+		}
+	case "PUNCH", "PUNCHED":
+		if c.ForceRelay {
+			return
+		}
+		id, _ := splitField(rest)
+		if string(kind) == "PUNCH" {
+			c.send(from, "PUNCHED", c.ID)
+		}
+		c.session(string(id), false).established(from)
+	case "PING":
+		id, _ := splitField(rest)
+		c.mu.Lock()
+		session := c.sessions[string(id)]
+		c.mu.Unlock()
+		if session != nil {
+			session.seen()
+		}
+	case "DATA":
+		id, payload := splitField(rest)
+		c.mu.Lock()
+		session := c.sessions[string(id)]
+		c.mu.Unlock()
+		if session == nil {
+			return
+		}
+		session.seen()
+		select {
+		case session.inbox <- bytes.Clone(payload):
+		default: // like any UDP receiver that falls behind, drop it
+		}
+	}
+}
+
+// session returns the session with peerID, creating it if needed
+func (c *HolePunchClient) session(peerID string, requested bool) *PeerSession {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if session, ok := c.sessions[peerID]; ok {
+		return session
+	}
+	session := &PeerSession{
+		PeerID:    peerID,
+		client:    c,
+		requested: requested,
+		ready:     make(chan struct{}),
+		punched:   make(chan struct{}),
+		inbox:     make(chan []byte, 64),
+	}
+	c.sessions[peerID] = session
+	return session
+}
+
+func (c *HolePunchClient) drop(session *PeerSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions[session.PeerID] == session {
+		delete(c.sessions, session.PeerID)
+	}
+}
+
+// punch fires PUNCH datagrams at the peer's public and private addresses
+// until one answers or PunchTimeout passes, then settles the session
+func (c *HolePunchClient) punch(session *PeerSession, public, private string) {
+	var targets []*net.UDPAddr
+	for _, address := range []string{public, private} {
+		if addr, err := net.ResolveUDPAddr("udp", address); err == nil {
+			targets = append(targets, addr)
+		}
+	}
+
+	if !c.ForceRelay {
+		timeout := time.NewTimer(c.PunchTimeout)
+		defer timeout.Stop()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+	punching:
+		for {
+			for _, addr := range targets {
+				c.send(addr, "PUNCH", c.ID)
+			}
+			select {
+			case <-session.punched:
+				break punching
+			case <-timeout.C:
+				break punching
+			case <-c.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+
+	session.decide(nil)
+	if !session.requested {
+		select {
+		case c.incoming <- session:
+		default:
+		}
+	}
+}
+
+func (c *HolePunchClient) keepAliveLoop() {
+	if c.KeepAlive <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.register()
+			c.mu.Lock()
+			var direct []*net.UDPAddr
+			for _, session := range c.sessions {
+				if addr := session.Addr(); addr != nil {
+					direct = append(direct, addr)
+				}
+			}
+			c.mu.Unlock()
+			for _, addr := range direct {
+				c.send(addr, "PING", c.ID)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *HolePunchClient) register() {
+	c.send(c.server, "REGISTER", c.ID, c.privateAddr())
+}
+
+// privateAddr is the client's address on its own network: the socket's
+// port on the interface that routes to the server
+func (c *HolePunchClient) privateAddr() string {
+	local := c.conn.LocalAddr().(*net.UDPAddr)
+	probe, err := net.DialUDP("udp", nil, c.server)
+	if err != nil {
+		return local.String()
+	}
+	defer probe.Close()
+	ip := probe.LocalAddr().(*net.UDPAddr).IP
+	return (&net.UDPAddr{IP: ip, Port: local.Port}).String()
+}
+
+func (c *HolePunchClient) send(to *net.UDPAddr, fields ...string) error {
+	_, err := c.conn.WriteToUDP([]byte(strings.Join(fields, " ")), to)
+	return err
+}
+
+// Send sends payload to the peer as one datagram, directly or relayed. A
+// relayed session whose server refused to relay returns ErrRelayDisabled.
+func (s *PeerSession) Send(payload []byte) error {
+	c := s.client
+	if addr := s.Addr(); addr != nil {
+		_, err := c.conn.WriteToUDP(append([]byte("DATA "+c.ID+" "), payload...), addr)
+		return err
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	_, err := c.conn.WriteToUDP(append([]byte("RELAY "+c.ID+" "+s.PeerID+" "), payload...), c.server)
+	return err
+}
+
+// Receive waits for the next datagram from the peer
+func (s *PeerSession) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case payload := <-s.inbox:
+		return payload, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.client.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Addr returns the peer's address on the direct path, or nil if the
+// session is relayed
+func (s *PeerSession) Addr() *net.UDPAddr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// Relayed reports whether data goes through the rendezvous server
+func (s *PeerSession) Relayed() bool {
+	return s.Addr() == nil
+}
+
+// LastSeen returns when the peer was last heard from
+func (s *PeerSession) LastSeen() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeen
+}
+
+// Err returns why the session could not be opened or relayed
+func (s *PeerSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// established records the first address the peer got through from
+func (s *PeerSession) established(addr *net.UDPAddr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen = time.Now()
+	if s.addr != nil {
+		return
+	}
+	s.addr = addr
+	close(s.punched)
+}
+
+// decide settles the session, direct if a punch got through by now and
+// relayed otherwise. An error from the server fails it, also later, when
+// the relay turns out to be disabled.
+func (s *PeerSession) decide(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = err
+	}
+	if !s.decided {
+		s.decided = true
+		close(s.ready)
+	}
+}
+
+func (s *PeerSession) seen() {
+	s.mu.Lock()
+	s.lastSeen = time.Now()
+	s.mu.Unlock()
+}
+
+// splitField splits msg at its first space
+func splitField(msg []byte) ([]byte, []byte) {
+	field, rest, _ := bytes.Cut(msg, []byte(" "))
+	return field, rest
+}
@@ -1,10 +1,20 @@
 package net
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/data_structure"
+	"github.com/jerrychou/go-practice/format"
 )
 
 type NetworkInfo struct {
@@ -20,12 +30,26 @@ func ResolveHostname(hostname string) ([]string, error) {
 		return nil, fmt.Errorf("failed to resolve hostname %s: %w", hostname, err)
 	}
 
-	var ipStrings []string
-	for _, ip := range ips {
-		ipStrings = append(ipStrings, ip.String())
-	}
+	return data_structure.Map(ips, net.IP.String), nil
+}
+
+// ResolveHostnames resolves many hostnames, at most maxConcurrency at a
+// time. Hostnames that fail to resolve map to nil and are reported in the
+// *concurrency.ParallelError returned.
+func ResolveHostnames(ctx context.Context, hostnames []string, maxConcurrency int) (map[string][]string, error) {
+	ips, err := concurrency.ParallelMap(ctx, hostnames, func(ctx context.Context, hostname string) ([]string, error) {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve hostname %s: %w", hostname, err)
+		}
+		return data_structure.Map(addrs, func(a net.IPAddr) string { return a.IP.String() }), nil
+	}, maxConcurrency)
 
-	return ipStrings, nil
+	resolved := make(map[string][]string, len(hostnames))
+	for i, hostname := range hostnames {
+		resolved[hostname] = ips[i]
+	}
+	return resolved, err
 }
 
 func ReverseDNS(ip string) ([]string, error) {
@@ -47,6 +71,60 @@ func CheckPortAvailability(host, port string) bool {
 	return false
 }
 
+// ScanPorts dials each port on host with up to workers concurrent connections
+// and returns the open ports in ascending order. onScanned, if set, is called
+// after every port from the scanning goroutines.
+func ScanPorts(host string, ports []int, timeout time.Duration, workers int, onScanned func(port int, open bool)) []int {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var (
+		mu    sync.Mutex
+		open  []int
+		group sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for port := range jobs {
+				conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+				if err == nil {
+					conn.Close()
+					mu.Lock()
+					open = append(open, port)
+					mu.Unlock()
+				}
+				if onScanned != nil {
+					onScanned(port, err == nil)
+				}
+			}
+		}()
+	}
+	for _, port := range ports {
+		jobs <- port
+	}
+	close(jobs)
+	group.Wait()
+	sort.Ints(open)
+	return open
+}
+
+var wellKnownPorts = map[int]string{
+	21: "ftp", 22: "ssh", 25: "smtp", 53: "dns", 80: "http", 443: "https",
+	3000: "dev-server", 3306: "mysql", 5432: "postgres", 6379: "redis",
+	8080: "http-alt", 9090: "prometheus", 27017: "mongodb",
+}
+
+// ServiceName returns the conventional service for a well-known port
+func ServiceName(port int) string {
+	if name, ok := wellKnownPorts[port]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 func GetLocalIPs() ([]string, error) {
 	var ips []string
 
@@ -77,8 +155,11 @@ func GetLocalIPs() ([]string, error) {
 	return ips, nil
 }
 
+// IsValidIP accepts IPv4 and IPv6 addresses, including IPv6 zones such as
+// "fe80::1%eth0"
 func IsValidIP(ip string) bool {
-	return net.ParseIP(ip) != nil
+	_, err := netip.ParseAddr(ip)
+	return err == nil
 }
 
 func IsValidIPv4(ip string) bool {
@@ -86,31 +167,24 @@ func IsValidIPv4(ip string) bool {
 	return parsedIP != nil && parsedIP.To4() != nil
 }
 
+// IsValidIPv6 accepts IPv6 addresses, with or without a zone, other than
+// IPv4-mapped ones
 func IsValidIPv6(ip string) bool {
-	parsedIP := net.ParseIP(ip)
-	return parsedIP != nil && parsedIP.To16() != nil && parsedIP.To4() == nil
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && addr.Is6() && !addr.Is4In6()
 }
 
+// IsPrivateIP reports whether ip is not publicly routable: the RFC 1918
+// ranges and IPv6 unique local addresses (fc00::/7), loopback, and IPv4 and
+// IPv6 link-local addresses. IPv6 zones ("fe80::1%eth0") and IPv4-mapped
+// IPv6 addresses are accepted.
 func IsPrivateIP(ip string) bool {
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
 		return false
 	}
-
-	privateBlocks := []*net.IPNet{
-		{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
-		{IP: net.IPv4(172, 16, 0, 0), Mask: net.CIDRMask(12, 32)},
-		{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)},
-		{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
-	}
-
-	for _, block := range privateBlocks {
-		if block.Contains(parsedIP) {
-			return true
-		}
-	}
-
-	return false
+	addr = addr.Unmap()
+	return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast()
 }
 
 func PingHost(host string, timeout time.Duration) error {
@@ -143,11 +217,7 @@ func GetNetworkInterfaces() ([]map[string]interface{}, error) {
 
 		addrs, err := iface.Addrs()
 		if err == nil {
-			var addresses []string
-			for _, addr := range addrs {
-				addresses = append(addresses, addr.String())
-			}
-			info["addresses"] = addresses
+			info["addresses"] = data_structure.Map(addrs, net.Addr.String)
 		}
 
 		result = append(result, info)
@@ -236,14 +306,17 @@ func DemonstrateNetworkOperations() {
 		"invalid-hostname-12345.com",
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	resolved, err := ResolveHostnames(ctx, hostnames, 4)
+	cancel()
 	for _, hostname := range hostnames {
-		ips, err := ResolveHostname(hostname)
-		if err != nil {
-			fmt.Printf("❌ %s: %v\n", hostname, err)
-		} else {
+		if ips := resolved[hostname]; ips != nil {
 			fmt.Printf("✅ %s: %s\n", hostname, strings.Join(ips, ", "))
 		}
 	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
 
 	fmt.Println("\n🔄 Reverse DNS Lookup:")
 	fmt.Println(strings.Repeat("-", 30))
@@ -273,6 +346,8 @@ func DemonstrateNetworkOperations() {
 		"256.256.256.256",
 		"not-an-ip",
 		"127.0.0.1",
+		"fd12:3456:789a::1",
+		"fe80::1%eth0",
 	}
 
 	for _, ip := range testIPs {
@@ -302,6 +377,30 @@ func DemonstrateNetworkOperations() {
 		fmt.Printf("%s: %s:%s\n", status, host, port)
 	}
 
+	fmt.Println("\n🔎 Port Scan (localhost:1-1024 + common services):")
+	fmt.Println(strings.Repeat("-", 30))
+
+	var scanPorts []int
+	for port := 1; port <= 1024; port++ {
+		scanPorts = append(scanPorts, port)
+	}
+	scanPorts = append(scanPorts, 3000, 3306, 5432, 6379, 8080, 9090, 27017)
+
+	bar := format.NewProgressBar(os.Stdout, int64(len(scanPorts)), "Scanning")
+	bar.Unit = "ports"
+	openPorts := ScanPorts(host, scanPorts, 200*time.Millisecond, 100, func(int, bool) { bar.Increment() })
+	bar.Finish()
+
+	if len(openPorts) == 0 {
+		fmt.Println("No open ports found")
+	} else {
+		table := format.NewTable("Port", "Service", "State").SetAlign(0, format.AlignRight)
+		for _, port := range openPorts {
+			table.AddRow(port, ServiceName(port), format.Colorize("open", format.Green))
+		}
+		table.Render(os.Stdout)
+	}
+
 	fmt.Println("\n📊 CIDR Operations:")
 	fmt.Println(strings.Repeat("-", 30))
 
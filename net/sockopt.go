@@ -0,0 +1,220 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// SocketOptions tunes the TCP sockets of a server or client. The zero
+// value keeps Go's defaults: keep-alives every 15 seconds, TCP_NODELAY on,
+// and the operating system's buffer sizes and linger behaviour.
+type SocketOptions struct {
+	// KeepAlive sets the probes when Enable is true: Idle before the
+	// first, Interval between them, and Count unanswered before the
+	// connection is dropped. Zero keeps the default, -1 the OS setting.
+	KeepAlive net.KeepAliveConfig
+	// NoKeepAlive turns keep-alive probes off
+	NoKeepAlive bool
+	// NoDelay sets TCP_NODELAY when not nil. Off, Nagle's algorithm
+	// batches small writes, saving packets at the cost of latency.
+	NoDelay *bool
+	// ReusePort sets SO_REUSEPORT on listeners, so several processes can
+	// listen on one port and the kernel spreads connections between them.
+	// Listening fails where the OS does not support it.
+	ReusePort bool
+	// ReadBuffer and WriteBuffer size SO_RCVBUF and SO_SNDBUF in bytes;
+	// the kernel may round them, and Linux doubles them for bookkeeping
+	ReadBuffer  int
+	WriteBuffer int
+	// Linger sets SO_LINGER when not nil: how long Close waits to send
+	// unsent data, in whole seconds. Zero discards it and resets the
+	// connection; negative sends it in the background, the default.
+	Linger *time.Duration
+}
+
+// SocketOption sets one of SocketOptions
+type SocketOption func(*SocketOptions)
+
+// WithKeepAlive probes an idle connection after idle, then every interval,
+// dropping it after count probes go unanswered
+func WithKeepAlive(idle, interval time.Duration, count int) SocketOption {
+	return func(o *SocketOptions) {
+		o.KeepAlive = net.KeepAliveConfig{Enable: true, Idle: idle, Interval: interval, Count: count}
+		o.NoKeepAlive = false
+	}
+}
+
+// WithoutKeepAlive turns keep-alive probes off
+func WithoutKeepAlive() SocketOption {
+	return func(o *SocketOptions) {
+		o.KeepAlive = net.KeepAliveConfig{}
+		o.NoKeepAlive = true
+	}
+}
+
+// WithNoDelay sets TCP_NODELAY; false lets Nagle's algorithm batch writes
+func WithNoDelay(on bool) SocketOption {
+	return func(o *SocketOptions) { o.NoDelay = &on }
+}
+
+// WithReusePort lets several listeners share the port
+func WithReusePort() SocketOption {
+	return func(o *SocketOptions) { o.ReusePort = true }
+}
+
+// WithBufferSizes sets the receive and send buffers; 0 keeps the default
+func WithBufferSizes(read, write int) SocketOption {
+	return func(o *SocketOptions) { o.ReadBuffer, o.WriteBuffer = read, write }
+}
+
+// WithLinger sets how long Close waits to send unsent data; see
+// SocketOptions.Linger
+func WithLinger(d time.Duration) SocketOption {
+	return func(o *SocketOptions) { o.Linger = &d }
+}
+
+// NewSocketOptions applies opts to the zero SocketOptions
+func NewSocketOptions(opts ...SocketOption) SocketOptions {
+	var o SocketOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Listen listens on address with the listener options, and sets the
+// connection options on every connection it accepts
+func (o SocketOptions) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	lc := net.ListenConfig{}
+	if o.NoKeepAlive {
+		lc.KeepAlive = -1
+	}
+	if o.ReusePort {
+		lc.Control = reusePort
+	}
+	ln, err := lc.Listen(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &tunedListener{Listener: ln, options: o}, nil
+}
+
+// Dial connects to address and sets the connection options
+func (o SocketOptions) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	d := net.Dialer{}
+	if o.NoKeepAlive {
+		d.KeepAlive = -1
+	}
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.Apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Apply sets the connection options on conn, which must be a TCP
+// connection; ReusePort only matters to listeners and is ignored
+func (o SocketOptions) Apply(conn net.Conn) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return fmt.Errorf("socket options need a TCP connection, not %T", conn)
+	}
+	var errs []error
+	if o.NoKeepAlive {
+		errs = append(errs, tcp.SetKeepAlive(false))
+	} else if o.KeepAlive.Enable {
+		errs = append(errs, tcp.SetKeepAliveConfig(o.KeepAlive))
+	}
+	if o.NoDelay != nil {
+		errs = append(errs, tcp.SetNoDelay(*o.NoDelay))
+	}
+	if o.ReadBuffer > 0 {
+		errs = append(errs, tcp.SetReadBuffer(o.ReadBuffer))
+	}
+	if o.WriteBuffer > 0 {
+		errs = append(errs, tcp.SetWriteBuffer(o.WriteBuffer))
+	}
+	if o.Linger != nil {
+		seconds := -1
+		if *o.Linger >= 0 {
+			seconds = int(o.Linger.Round(time.Second) / time.Second)
+		}
+		errs = append(errs, tcp.SetLinger(seconds))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to set socket options: %w", err)
+	}
+	return nil
+}
+
+// String describes the options that differ from the defaults, e.g.
+// "keepalive 30s/10s×5, nodelay off, reuseport"
+func (o SocketOptions) String() string {
+	var parts []string
+	switch {
+	case o.NoKeepAlive:
+		parts = append(parts, "keepalive off")
+	case o.KeepAlive.Enable:
+		parts = append(parts, fmt.Sprintf("keepalive %v/%v×%d", o.KeepAlive.Idle, o.KeepAlive.Interval, o.KeepAlive.Count))
+	}
+	if o.NoDelay != nil {
+		parts = append(parts, "nodelay "+map[bool]string{true: "on", false: "off"}[*o.NoDelay])
+	}
+	if o.ReusePort {
+		parts = append(parts, "reuseport")
+	}
+	if o.ReadBuffer > 0 {
+		parts = append(parts, "rcvbuf "+format.HumanizeBytes(int64(o.ReadBuffer)))
+	}
+	if o.WriteBuffer > 0 {
+		parts = append(parts, "sndbuf "+format.HumanizeBytes(int64(o.WriteBuffer)))
+	}
+	if o.Linger != nil {
+		parts = append(parts, fmt.Sprintf("linger %v", *o.Linger))
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tunedListener sets the connection options on each accepted connection.
+// Connections whose options cannot be set are still served, as the
+// defaults work; the failure is reported once.
+type tunedListener struct {
+	net.Listener
+	options  SocketOptions
+	reported bool
+}
+
+func (l *tunedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.options.Apply(conn); err != nil && !l.reported {
+		l.reported = true
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	return conn, nil
+}
+
+// reusePort is a ListenConfig.Control setting SO_REUSEPORT
+func reusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) { sockErr = setReusePort(fd) }); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package net
+
+import (
+	"errors"
+	"fmt"
+)
+
+func setReusePort(fd uintptr) error {
+	return fmt.Errorf("SO_REUSEPORT: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,172 @@
+package net
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5Dialer opens TCP connections through a SOCKS5 proxy (RFC 1928),
+// such as `ssh -D` or Tor, authenticating with a username and password
+// (RFC 1929) when they are set
+type SOCKS5Dialer struct {
+	ProxyAddr string
+	Username  string
+	Password  string
+	// Timeout bounds connecting to the proxy and the handshake
+	Timeout time.Duration
+}
+
+// NewSOCKS5Dialer creates a dialer for the proxy at proxyAddr
+func NewSOCKS5Dialer(proxyAddr string) *SOCKS5Dialer {
+	return &SOCKS5Dialer{ProxyAddr: proxyAddr, Timeout: 10 * time.Second}
+}
+
+var socks5Replies = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// Dial connects to address through the proxy
+func (d *SOCKS5Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to address through the proxy. Host names are
+// resolved by the proxy, so they work for names only it can see.
+func (d *SOCKS5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("socks5: network %s is not supported", network)
+	}
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: %w", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: invalid port %q", portStr)
+	}
+
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.ProxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: failed to reach proxy: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := d.handshake(conn, host, uint16(port)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks5: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (d *SOCKS5Dialer) handshake(conn net.Conn, host string, port uint16) error {
+	// Offer no authentication, and username/password when we have them
+	methods := []byte{0x00}
+	if d.Username != "" {
+		methods = []byte{0x00, 0x02}
+	}
+	if _, err := conn.Write(append([]byte{5, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 5 {
+		return fmt.Errorf("unexpected protocol version %d", reply[0])
+	}
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	default:
+		return errors.New("proxy accepted none of the offered authentication methods")
+	}
+
+	request := []byte{5, 1, 0} // CONNECT
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long")
+		}
+		request = append(append(request, 3, byte(len(host))), host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(append(request, 1), ip4...)
+	} else {
+		request = append(append(request, 4), ip.To16()...)
+	}
+	request = binary.BigEndian.AppendUint16(request, port)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	// Reply: version, status, reserved, then the bound address, which we
+	// read past
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0 {
+		if reason, ok := socks5Replies[header[1]]; ok {
+			return fmt.Errorf("connect to %s failed: %s", net.JoinHostPort(host, strconv.Itoa(int(port))), reason)
+		}
+		return fmt.Errorf("connect failed with status %d", header[1])
+	}
+	var skip int
+	switch header[3] {
+	case 1:
+		skip = net.IPv4len
+	case 4:
+		skip = net.IPv6len
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0])
+	default:
+		return fmt.Errorf("unexpected address type %d in reply", header[3])
+	}
+	_, err := io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+func (d *SOCKS5Dialer) authenticate(conn net.Conn) error {
+	if d.Username == "" || len(d.Username) > 255 || len(d.Password) > 255 {
+		return errors.New("proxy requires a username and password of at most 255 bytes")
+	}
+	request := append([]byte{1, byte(len(d.Username))}, d.Username...)
+	request = append(append(request, byte(len(d.Password))), d.Password...)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return errors.New("proxy rejected the username or password")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// ServerStats counts a server's connections, traffic, messages and errors.
+// Servers register theirs while running, so AllStats lists every live one.
+type ServerStats struct {
+	Name string
+	Addr string
+
+	started  time.Time
+	active   atomic.Int64
+	total    atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	messages atomic.Int64
+	errors   atomic.Int64
+	rate     messageRate
+}
+
+// StatsSnapshot is a point-in-time copy of a ServerStats
+type StatsSnapshot struct {
+	Name           string        `json:"name"`
+	Addr           string        `json:"addr"`
+	Uptime         time.Duration `json:"uptime_ns"`
+	ActiveConns    int64         `json:"active_connections"`
+	TotalConns     int64         `json:"total_connections"`
+	BytesIn        int64         `json:"bytes_in"`
+	BytesOut       int64         `json:"bytes_out"`
+	Messages       int64         `json:"messages"`
+	MessagesPerSec float64       `json:"messages_per_sec"`
+	Errors         int64         `json:"errors"`
+}
+
+// NewServerStats creates stats for a server of the given kind, e.g. "chat"
+func NewServerStats(name string) *ServerStats {
+	return &ServerStats{Name: name, started: time.Now()}
+}
+
+// TrackConn counts conn as open until it is closed, and the bytes read from
+// and written to it
+func (s *ServerStats) TrackConn(conn net.Conn) net.Conn {
+	s.active.Add(1)
+	s.total.Add(1)
+	return &statsConn{Conn: conn, stats: s}
+}
+
+// Message counts one message handled, for the total and the rate
+func (s *ServerStats) Message() {
+	s.messages.Add(1)
+	s.rate.add(time.Now())
+}
+
+// Error counts one failed accept, read or write
+func (s *ServerStats) Error() {
+	s.errors.Add(1)
+}
+
+// AddBytes counts traffic that does not go through a tracked connection,
+// such as UDP datagrams
+func (s *ServerStats) AddBytes(in, out int) {
+	s.bytesIn.Add(int64(in))
+	s.bytesOut.Add(int64(out))
+}
+
+// Snapshot returns the current counts
+func (s *ServerStats) Snapshot() StatsSnapshot {
+	now := time.Now()
+	return StatsSnapshot{
+		Name:           s.Name,
+		Addr:           s.Addr,
+		Uptime:         now.Sub(s.started),
+		ActiveConns:    s.active.Load(),
+		TotalConns:     s.total.Load(),
+		BytesIn:        s.bytesIn.Load(),
+		BytesOut:       s.bytesOut.Load(),
+		Messages:       s.messages.Load(),
+		MessagesPerSec: s.rate.perSecond(now, s.started),
+		Errors:         s.errors.Load(),
+	}
+}
+
+type statsConn struct {
+	net.Conn
+	stats     *ServerStats
+	closeOnce sync.Once
+}
+
+func (c *statsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.stats.bytesIn.Add(int64(n))
+	return n, err
+}
+
+func (c *statsConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.stats.bytesOut.Add(int64(n))
+	return n, err
+}
+
+func (c *statsConn) Close() error {
+	c.closeOnce.Do(func() { c.stats.active.Add(-1) })
+	return c.Conn.Close()
+}
+
+func (c *statsConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errors.ErrUnsupported
+}
+
+// rateWindow is how many seconds messages per second is averaged over
+const rateWindow = 5
+
+// messageRate counts messages in one-second buckets
+type messageRate struct {
+	mu      sync.Mutex
+	buckets [rateWindow + 1]struct{ sec, n int64 }
+}
+
+func (r *messageRate) add(now time.Time) {
+	sec := now.Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := &r.buckets[sec%int64(len(r.buckets))]
+	if b.sec != sec {
+		b.sec, b.n = sec, 0
+	}
+	b.n++
+}
+
+// perSecond averages the last rateWindow complete seconds, or fewer for a
+// server that has not been up that long
+func (r *messageRate) perSecond(now, started time.Time) float64 {
+	sec := now.Unix()
+	span := min(int64(rateWindow), sec-started.Unix())
+	if span <= 0 {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, b := range r.buckets {
+		if b.sec < sec && b.sec >= sec-span {
+			n += b.n
+		}
+	}
+	return float64(n) / float64(span)
+}
+
+var (
+	liveStatsMu sync.Mutex
+	liveStats   = map[*ServerStats]struct{}{}
+)
+
+// register lists s in AllStats while its server runs on addr
+func (s *ServerStats) register(addr string) {
+	liveStatsMu.Lock()
+	defer liveStatsMu.Unlock()
+	s.Addr = addr
+	liveStats[s] = struct{}{}
+}
+
+func (s *ServerStats) unregister() {
+	liveStatsMu.Lock()
+	defer liveStatsMu.Unlock()
+	delete(liveStats, s)
+}
+
+// AllStats returns a snapshot of every running server, by name and address
+func AllStats() []StatsSnapshot {
+	liveStatsMu.Lock()
+	snapshots := make([]StatsSnapshot, 0, len(liveStats))
+	for s := range liveStats {
+		snapshots = append(snapshots, s.Snapshot())
+	}
+	liveStatsMu.Unlock()
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Name != snapshots[j].Name {
+			return snapshots[i].Name < snapshots[j].Name
+		}
+		return snapshots[i].Addr < snapshots[j].Addr
+	})
+	return snapshots
+}
+
+// PrintStats writes a table of AllStats to w
+func PrintStats(w io.Writer) {
+	table := format.NewTable("Server", "Address", "Uptime", "Active", "Total", "In", "Out", "Messages", "Msg/s", "Errors")
+	for col := 3; col <= 9; col++ {
+		table.SetAlign(col, format.AlignRight)
+	}
+	for _, s := range AllStats() {
+		table.AddRow(s.Name, s.Addr, s.Uptime.Round(time.Second), s.ActiveConns, s.TotalConns,
+			format.HumanizeBytes(s.BytesIn), format.HumanizeBytes(s.BytesOut), s.Messages,
+			fmt.Sprintf("%.1f", s.MessagesPerSec), s.Errors)
+	}
+	table.Render(w)
+}
+
+// WatchStats redraws the stats table on w every interval until ctx is done,
+// clearing the terminal each time
+func WatchStats(ctx context.Context, w io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fmt.Fprint(w, "\033[H\033[2J")
+		fmt.Fprintf(w, "📊 Connection statistics (%s, every %s)\n\n", time.Now().Format("15:04:05"), interval)
+		PrintStats(w)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
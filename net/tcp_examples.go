@@ -2,48 +2,78 @@ package net
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/metrics"
 )
 
 type TCPServer struct {
 	Address string
 	Port    string
-	ln      net.Listener
+	// Network is "tcp4", "tcp6" or "tcp" (both); empty picks from Address
+	Network string
+	// Middleware wraps each connection's handler, first outermost
+	Middleware []ConnMiddleware
+	// Stats counts connections, bytes, messages and errors
+	Stats *ServerStats
+	// Socket tunes the listener and each accepted connection
+	Socket SocketOptions
+	ln     net.Listener
 }
 
-func NewTCPServer(address, port string) *TCPServer {
+func NewTCPServer(address, port string, opts ...SocketOption) *TCPServer {
 	return &TCPServer{
 		Address: address,
 		Port:    port,
+		Stats:   NewServerStats("tcp_echo"),
+		Socket:  NewSocketOptions(opts...),
 	}
 }
 
 func (s *TCPServer) Start() error {
-	address := net.JoinHostPort(s.Address, s.Port)
-	ln, err := net.Listen("tcp", address)
+	address := HostPort(s.Address, s.Port)
+	ln, err := s.Socket.Listen(context.Background(), serverNetwork(s.Network, "tcp", s.Address), address)
 	if err != nil {
 		return fmt.Errorf("failed to start TCP server: %w", err)
 	}
+	ln = metrics.Listener("tcp_echo", ln)
 
 	s.ln = ln
-	fmt.Printf("🚀 TCP Server started on %s\n", address)
+	s.Stats.register(ln.Addr().String())
+	fmt.Printf("🚀 TCP Server started on %s (socket: %s)\n", address, s.Socket)
+
+	handler := ChainConn(s.handleConnection, s.Middleware...)
 
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			s.Stats.Error()
 			fmt.Printf("❌ Error accepting connection: %v\n", err)
 			continue
 		}
 
-		go s.handleConnection(conn)
+		go handler(s.Stats.TrackConn(conn))
 	}
 }
 
+// Use adds middleware to the connection handler; call it before Start
+func (s *TCPServer) Use(middleware ...ConnMiddleware) *TCPServer {
+	s.Middleware = append(s.Middleware, middleware...)
+	return s
+}
+
 func (s *TCPServer) Stop() error {
 	if s.ln != nil {
+		s.Stats.unregister()
 		return s.ln.Close()
 	}
 	return nil
@@ -55,16 +85,20 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	clientAddr := conn.RemoteAddr().String()
 	fmt.Printf("📞 New connection from %s\n", clientAddr)
 
-	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	// Clients that send nothing for 30 seconds are dropped
+	idle := closeWhenIdle(conn, 30*time.Second)
+	defer idle.stop()
 
-	scanner := bufio.NewScanner(conn)
+	scanner := bufio.NewScanner(idle)
 	for scanner.Scan() {
 		message := strings.TrimSpace(scanner.Text())
 		fmt.Printf("📨 Received from %s: %s\n", clientAddr, message)
+		s.Stats.Message()
 
 		response := fmt.Sprintf("Echo: %s\n", message)
 		_, err := conn.Write([]byte(response))
 		if err != nil {
+			s.Stats.Error()
 			fmt.Printf("❌ Error writing to %s: %v\n", clientAddr, err)
 			break
 		}
@@ -76,6 +110,7 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	}
 
 	if err := scanner.Err(); err != nil {
+		s.Stats.Error()
 		fmt.Printf("❌ Error reading from %s: %v\n", clientAddr, err)
 	}
 
@@ -85,25 +120,28 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 type TCPClient struct {
 	Address string
 	Port    string
-	conn    net.Conn
+	// Socket tunes the connection; ReusePort does not apply
+	Socket SocketOptions
+	conn   net.Conn
 }
 
-func NewTCPClient(address, port string) *TCPClient {
+func NewTCPClient(address, port string, opts ...SocketOption) *TCPClient {
 	return &TCPClient{
 		Address: address,
 		Port:    port,
+		Socket:  NewSocketOptions(opts...),
 	}
 }
 
 func (c *TCPClient) Connect() error {
-	address := net.JoinHostPort(c.Address, c.Port)
-	conn, err := net.Dial("tcp", address)
+	address := HostPort(c.Address, c.Port)
+	conn, err := c.Socket.Dial(context.Background(), "tcp", address)
 	if err != nil {
 		return fmt.Errorf("failed to connect to TCP server: %w", err)
 	}
 
 	c.conn = conn
-	fmt.Printf("🔗 Connected to TCP server at %s\n", address)
+	fmt.Printf("🔗 Connected to TCP server at %s (socket: %s)\n", address, c.Socket)
 	return nil
 }
 
@@ -143,13 +181,13 @@ func (c *TCPClient) Close() error {
 	return nil
 }
 
-func SimpleEchoServer(address, port string) error {
-	server := NewTCPServer(address, port)
+func SimpleEchoServer(address, port string, opts ...SocketOption) error {
+	server := NewTCPServer(address, port, opts...)
 	return server.Start()
 }
 
-func SimpleEchoClient(address, port string, messages []string) error {
-	client := NewTCPClient(address, port)
+func SimpleEchoClient(address, port string, messages []string, opts ...SocketOption) error {
+	client := NewTCPClient(address, port, opts...)
 
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
@@ -176,104 +214,123 @@ func SimpleEchoClient(address, port string, messages []string) error {
 }
 
 type ChatServer struct {
-	Address    string
-	Port       string
-	clients    map[net.Conn]string
-	broadcast  chan string
-	register   chan net.Conn
-	unregister chan net.Conn
-	ln         net.Listener
+	Address string
+	Port    string
+	// Network is "tcp4", "tcp6" or "tcp" (both); empty picks from Address
+	Network string
+	// Middleware wraps each connection's handler, first outermost
+	Middleware []ConnMiddleware
+	// Stats counts connections, bytes, messages and errors
+	Stats *ServerStats
+	// hub fans each message out to every client's writer; a client too
+	// slow to take its share is disconnected instead of stalling the rest
+	hub *concurrency.Broadcast[chatMessage]
+	// Socket tunes the listener and each accepted connection
+	Socket SocketOptions
+	ln     net.Listener
 }
 
-func NewChatServer(address, port string) *ChatServer {
+type chatMessage struct {
+	from net.Conn // left out of the delivery, if set
+	text string
+}
+
+// chatBacklog is how many messages a client may fall behind before it is
+// disconnected
+const chatBacklog = 64
+
+func NewChatServer(address, port string, opts ...SocketOption) *ChatServer {
 	return &ChatServer{
-		Address:    address,
-		Port:       port,
-		clients:    make(map[net.Conn]string),
-		broadcast:  make(chan string),
-		register:   make(chan net.Conn),
-		unregister: make(chan net.Conn),
+		Address: address,
+		Port:    port,
+		Stats:   NewServerStats("chat"),
+		hub:     concurrency.NewBroadcast[chatMessage](),
+		Socket:  NewSocketOptions(opts...),
 	}
 }
 
 func (cs *ChatServer) Start() error {
-	address := net.JoinHostPort(cs.Address, cs.Port)
-	ln, err := net.Listen("tcp", address)
+	address := HostPort(cs.Address, cs.Port)
+	ln, err := cs.Socket.Listen(context.Background(), serverNetwork(cs.Network, "tcp", cs.Address), address)
 	if err != nil {
 		return fmt.Errorf("failed to start chat server: %w", err)
 	}
+	ln = metrics.Listener("chat", ln)
 
 	cs.ln = ln
+	cs.Stats.register(ln.Addr().String())
 	fmt.Printf("💬 Chat Server started on %s\n", address)
 
-	go cs.broadcaster()
+	// Clients join once the middleware lets them through, under the
+	// connection it hands on (the decrypted one with TLSConns)
+	handler := ChainConn(cs.handleChatConnection, cs.Middleware...)
 
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			cs.Stats.Error()
 			fmt.Printf("❌ Error accepting connection: %v\n", err)
 			continue
 		}
 
-		cs.register <- conn
-		go cs.handleChatConnection(conn)
+		go handler(cs.Stats.TrackConn(conn))
 	}
 }
 
+// Use adds middleware to the connection handler; call it before Start
+func (cs *ChatServer) Use(middleware ...ConnMiddleware) *ChatServer {
+	cs.Middleware = append(cs.Middleware, middleware...)
+	return cs
+}
+
 func (cs *ChatServer) Stop() error {
 	if cs.ln != nil {
+		cs.Stats.unregister()
 		return cs.ln.Close()
 	}
 	return nil
 }
 
-func (cs *ChatServer) broadcaster() {
-	for {
-		select {
-		case conn := <-cs.register:
-			clientAddr := conn.RemoteAddr().String()
-			cs.clients[conn] = clientAddr
-			fmt.Printf("👤 Client %s joined the chat\n", clientAddr)
-
-			message := fmt.Sprintf("👤 %s joined the chat\n", clientAddr)
-			cs.broadcastMessage(message, conn)
-
-		case conn := <-cs.unregister:
-			if clientAddr, ok := cs.clients[conn]; ok {
-				delete(cs.clients, conn)
-				conn.Close()
-				fmt.Printf("👋 Client %s left the chat\n", clientAddr)
-
-				message := fmt.Sprintf("👋 %s left the chat\n", clientAddr)
-				cs.broadcastMessage(message, nil)
-			}
-
-		case message := <-cs.broadcast:
-			cs.broadcastMessage(message, nil)
+// writeMessages copies the hub's messages to one client until it leaves,
+// a write fails or it falls too far behind
+func (cs *ChatServer) writeMessages(conn net.Conn, inbox *concurrency.Receiver[chatMessage]) {
+	defer conn.Close()
+	for msg := range inbox.C {
+		if msg.from == conn {
+			continue
 		}
-	}
-}
-
-func (cs *ChatServer) broadcastMessage(message string, exclude net.Conn) {
-	for conn := range cs.clients {
-		if conn != exclude {
-			_, err := conn.Write([]byte(message))
-			if err != nil {
-				cs.unregister <- conn
-			}
+		if _, err := conn.Write([]byte(msg.text)); err != nil {
+			inbox.Cancel()
+			return
 		}
 	}
+	if inbox.Disconnected() {
+		cs.Stats.Error()
+		fmt.Printf("🐢 Client %s disconnected for falling behind\n", conn.RemoteAddr())
+	}
 }
 
 func (cs *ChatServer) handleChatConnection(conn net.Conn) {
+	clientAddr := conn.RemoteAddr().String()
+	inbox := cs.hub.Subscribe(chatBacklog, concurrency.Disconnect)
+	go cs.writeMessages(conn, inbox)
+
+	fmt.Printf("👤 Client %s joined the chat\n", clientAddr)
+	cs.hub.Send(chatMessage{from: conn, text: fmt.Sprintf("👤 %s joined the chat\n", clientAddr)})
 	defer func() {
-		cs.unregister <- conn
+		inbox.Cancel()
+		fmt.Printf("👋 Client %s left the chat\n", clientAddr)
+		cs.hub.Send(chatMessage{text: fmt.Sprintf("👋 %s left the chat\n", clientAddr)})
 	}()
 
-	clientAddr := conn.RemoteAddr().String()
-	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	// Only what the client sends keeps it in the chat, not what it is sent
+	idle := closeWhenIdle(conn, 60*time.Second)
+	defer idle.stop()
 
-	scanner := bufio.NewScanner(conn)
+	scanner := bufio.NewScanner(idle)
 	for scanner.Scan() {
 		message := strings.TrimSpace(scanner.Text())
 		if message == "" {
@@ -284,14 +341,16 @@ func (cs *ChatServer) handleChatConnection(conn net.Conn) {
 			time.Now().Format("15:04:05"), clientAddr, message)
 
 		fmt.Printf("💬 %s", formattedMessage)
-		cs.broadcast <- formattedMessage
+		cs.Stats.Message()
+		cs.hub.Send(chatMessage{text: formattedMessage})
 
 		if strings.ToLower(message) == "quit" {
 			break
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		cs.Stats.Error()
 		fmt.Printf("❌ Error reading from %s: %v\n", clientAddr, err)
 	}
 }
@@ -305,16 +364,27 @@ func DemonstrateTCPOperations() {
 	fmt.Println("  2. Chat Server with Multiple Clients")
 	fmt.Println("  3. Connection Handling with Timeouts")
 	fmt.Println("  4. Error Handling and Recovery")
+	fmt.Println("  5. Connection Middleware: logging, deadlines, limits, TLS")
+	fmt.Println("  6. Socket Options: keep-alive, Nagle, SO_REUSEPORT, buffers, linger")
+	fmt.Println("  7. Framed Echo: property checks, decoder fuzzing and a soak test")
 
 	fmt.Println("\n💡 To test TCP operations:")
 	fmt.Println("  1. Start a server: go run run/net_main.go -mode=tcp-server")
 	fmt.Println("  2. Start a client: go run run/net_main.go -mode=tcp-client")
 	fmt.Println("  3. Start a chat server: go run run/net_main.go -mode=chat")
+	fmt.Println("  4. Tune the sockets: go run run/net_main.go -mode=tcp-server -keepalive=30s -keepalive-interval=10s -keepalive-count=3 -nodelay=false -reuseport")
+	fmt.Println("  5. Check the frame protocol: go run run/net_main.go -mode=frame-check -cases=10000")
+	fmt.Println("  6. Soak a frame echo server: go run run/net_main.go -mode=frame-soak -clients=5000 [-target=host:port]")
 
 	fmt.Println("\n🔧 Available Functions:")
-	fmt.Println("  - SimpleEchoServer(address, port)")
-	fmt.Println("  - SimpleEchoClient(address, port, messages)")
-	fmt.Println("  - NewChatServer(address, port)")
-	fmt.Println("  - NewTCPServer(address, port)")
-	fmt.Println("  - NewTCPClient(address, port)")
+	fmt.Println("  - SimpleEchoServer(address, port, opts...)")
+	fmt.Println("  - SimpleEchoClient(address, port, messages, opts...)")
+	fmt.Println("  - NewChatServer(address, port, opts...)")
+	fmt.Println("  - NewTCPServer(address, port, opts...)")
+	fmt.Println("  - NewTCPClient(address, port, opts...)")
+	fmt.Println("  - opts: WithKeepAlive(idle, interval, count), WithoutKeepAlive(), WithNoDelay(on), WithReusePort(), WithBufferSizes(r, w), WithLinger(d)")
+	fmt.Println("  - server.Use(LogConns, ConnDeadlines(...), IdleTimeout(d), MaxConns(n), RateLimitConns(...), TLSConns(cfg))")
+	fmt.Println("  - AllStats(), PrintStats(w), WatchStats(ctx, w, interval)")
+	fmt.Println("  - NewFrameEchoServer(address, port, opts...), FrameConformance{Seed, Cases}.Run(), FuzzFrameDecoder(data), FrameFuzzCorpus()")
+	fmt.Println("  - SoakFrameEcho(ctx, addr, SoakOptions{Clients, Frames}), SoakFrameEchoServer(ctx, opts)")
 }
@@ -1,6 +1,7 @@
 package net
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -10,29 +11,36 @@ import (
 type UDPServer struct {
 	Address string
 	Port    string
-	conn    *net.UDPConn
+	// Network is "udp4", "udp6" or "udp" (both); empty picks from Address
+	Network string
+	// Stats counts bytes, datagrams and errors
+	Stats *ServerStats
+	conn  *net.UDPConn
 }
 
 func NewUDPServer(address, port string) *UDPServer {
 	return &UDPServer{
 		Address: address,
 		Port:    port,
+		Stats:   NewServerStats("udp_echo"),
 	}
 }
 
 func (s *UDPServer) Start() error {
-	address := net.JoinHostPort(s.Address, s.Port)
-	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	address := HostPort(s.Address, s.Port)
+	network := serverNetwork(s.Network, "udp", s.Address)
+	udpAddr, err := net.ResolveUDPAddr(network, address)
 	if err != nil {
 		return fmt.Errorf("failed to resolve UDP address: %w", err)
 	}
 
-	conn, err := net.ListenUDP("udp", udpAddr)
+	conn, err := net.ListenUDP(network, udpAddr)
 	if err != nil {
 		return fmt.Errorf("failed to start UDP server: %w", err)
 	}
 
 	s.conn = conn
+	s.Stats.register(conn.LocalAddr().String())
 	fmt.Printf("🚀 UDP Server started on %s\n", address)
 
 	buffer := make([]byte, 1024)
@@ -40,16 +48,24 @@ func (s *UDPServer) Start() error {
 	for {
 		n, clientAddr, err := conn.ReadFromUDP(buffer)
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
+			s.Stats.Error()
 			fmt.Printf("❌ Error reading from UDP: %v\n", err)
 			continue
 		}
 
 		message := string(buffer[:n])
 		fmt.Printf("📨 Received from %s: %s\n", clientAddr.String(), message)
+		s.Stats.AddBytes(n, 0)
+		s.Stats.Message()
 
 		response := fmt.Sprintf("Echo: %s", message)
-		_, err = conn.WriteToUDP([]byte(response), clientAddr)
+		written, err := conn.WriteToUDP([]byte(response), clientAddr)
+		s.Stats.AddBytes(0, written)
 		if err != nil {
+			s.Stats.Error()
 			fmt.Printf("❌ Error writing to UDP: %v\n", err)
 		} else {
 			fmt.Printf("📤 Sent to %s: %s\n", clientAddr.String(), response)
@@ -59,6 +75,7 @@ func (s *UDPServer) Start() error {
 
 func (s *UDPServer) Stop() error {
 	if s.conn != nil {
+		s.Stats.unregister()
 		return s.conn.Close()
 	}
 	return nil
@@ -98,7 +115,7 @@ func (c *UDPClient) SendMessage(message string) error {
 		return fmt.Errorf("not connected to server")
 	}
 
-	serverAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(c.Address, c.Port))
+	serverAddr, err := net.ResolveUDPAddr("udp", HostPort(c.Address, c.Port))
 	if err != nil {
 		return fmt.Errorf("failed to resolve server address: %w", err)
 	}
@@ -180,7 +197,7 @@ func NewBroadcastServer(address, port string) *BroadcastServer {
 }
 
 func (bs *BroadcastServer) Start() error {
-	address := net.JoinHostPort(bs.Address, bs.Port)
+	address := HostPort(bs.Address, bs.Port)
 	udpAddr, err := net.ResolveUDPAddr("udp", address)
 	if err != nil {
 		return fmt.Errorf("failed to resolve UDP address: %w", err)
@@ -282,9 +299,15 @@ func (bs *BroadcastServer) Stop() error {
 }
 
 type MulticastServer struct {
+	// Address is the group to join: IPv4 such as 224.0.0.1 or IPv6 such
+	// as ff02::1
 	Address string
 	Port    string
-	conn    *net.UDPConn
+	// Interface names the interface to join on, e.g. "eth0". IPv6
+	// link-local groups (ff02::/16) need one; empty lets the system choose.
+	Interface string
+	conn      *net.UDPConn
+	group     *net.UDPAddr
 }
 
 func NewMulticastServer(address, port string) *MulticastServer {
@@ -295,18 +318,36 @@ func NewMulticastServer(address, port string) *MulticastServer {
 }
 
 func (ms *MulticastServer) Start() error {
-	address := net.JoinHostPort(ms.Address, ms.Port)
-	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	address := HostPort(ms.Address, ms.Port)
+	network := ListenNetwork("udp", ms.Address)
+	udpAddr, err := net.ResolveUDPAddr(network, address)
 	if err != nil {
 		return fmt.Errorf("failed to resolve UDP address: %w", err)
 	}
+	if !udpAddr.IP.IsMulticast() {
+		return fmt.Errorf("%s is not a multicast group address", udpAddr.IP)
+	}
+
+	var iface *net.Interface
+	if ms.Interface != "" {
+		iface, err = net.InterfaceByName(ms.Interface)
+		if err != nil {
+			return fmt.Errorf("failed to find interface %s: %w", ms.Interface, err)
+		}
+		// Link-local IPv6 groups are only meaningful on one interface, so
+		// messages sent to them must name it
+		if udpAddr.IP.To4() == nil {
+			udpAddr.Zone = iface.Name
+		}
+	}
 
-	conn, err := net.ListenMulticastUDP("udp", nil, udpAddr)
+	conn, err := net.ListenMulticastUDP(network, iface, udpAddr)
 	if err != nil {
 		return fmt.Errorf("failed to start multicast server: %w", err)
 	}
 
 	ms.conn = conn
+	ms.group = udpAddr
 	fmt.Printf("📺 Multicast Server started on %s\n", address)
 
 	go ms.multicastMessages()
@@ -315,6 +356,9 @@ func (ms *MulticastServer) Start() error {
 	for {
 		n, clientAddr, err := conn.ReadFromUDP(buffer)
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil // Stop was called
+			}
 			fmt.Printf("❌ Error reading from UDP: %v\n", err)
 			continue
 		}
@@ -333,15 +377,11 @@ func (ms *MulticastServer) multicastMessages() {
 		messageCount++
 		message := fmt.Sprintf("Multicast message #%d from server", messageCount)
 
-		address := net.JoinHostPort(ms.Address, ms.Port)
-		udpAddr, err := net.ResolveUDPAddr("udp", address)
-		if err != nil {
-			fmt.Printf("❌ Error resolving multicast address: %v\n", err)
-			continue
-		}
-
-		_, err = ms.conn.WriteToUDP([]byte(message), udpAddr)
+		_, err := ms.conn.WriteToUDP([]byte(message), ms.group)
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			fmt.Printf("❌ Error sending multicast: %v\n", err)
 		} else {
 			fmt.Printf("📺 Multicasted: %s\n", message)
@@ -366,12 +406,16 @@ func DemonstrateUDPOperations() {
 	fmt.Println("  3. UDP Multicast Server")
 	fmt.Println("  4. Connectionless Communication")
 	fmt.Println("  5. Fire-and-Forget Messaging")
+	fmt.Println("  6. NAT Traversal (UDP Hole Punching)")
 
 	fmt.Println("\n💡 To test UDP operations:")
 	fmt.Println("  1. Start a UDP server: go run run/net_main.go -mode=udp-server")
 	fmt.Println("  2. Start a UDP client: go run run/net_main.go -mode=udp-client")
 	fmt.Println("  3. Start a broadcast server: go run run/net_main.go -mode=broadcast")
 	fmt.Println("  4. Start a multicast server: go run run/net_main.go -mode=multicast")
+	fmt.Println("     IPv6 group: go run run/net_main.go -mode=multicast -address=ff02::1 -interface=eth0")
+	fmt.Println("  5. Start a rendezvous server: go run run/net_main.go -mode=rendezvous -address=0.0.0.0 -port=3478")
+	fmt.Println("     then on two machines: go run run/net_main.go -mode=punch -address=<server> -port=3478 -id=alice [-peer=bob]")
 
 	fmt.Println("\n🔧 Available Functions:")
 	fmt.Println("  - SimpleUDPEchoServer(address, port)")
@@ -380,6 +424,8 @@ func DemonstrateUDPOperations() {
 	fmt.Println("  - NewMulticastServer(address, port)")
 	fmt.Println("  - NewUDPServer(address, port)")
 	fmt.Println("  - NewUDPClient(address, port)")
+	fmt.Println("  - NewRendezvousServer(address, port)")
+	fmt.Println("  - NewHolePunchClient(id, server)")
 
 	fmt.Println("\n📊 UDP vs TCP Comparison:")
 	fmt.Println("  UDP:")
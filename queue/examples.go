@@ -0,0 +1,234 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	reflectops "github.com/jerrychou/go-practice/reflect"
+)
+
+// ConsumerGroupExamples shows fan-out across groups and load sharing within one
+func ConsumerGroupExamples() {
+	fmt.Println("\n=== Consumer Groups ===")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker := NewMemory()
+
+	// Each group sees every order; the two billing workers split them
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	var wg sync.WaitGroup
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	for _, name := range []string{"billing/worker-1", "billing/worker-2", "shipping/worker-1"} {
+		group, worker, _ := strings.Cut(name, "/")
+		consumer, _ := broker.Consumer(ConsumerOptions{Topic: "orders", Group: group, Name: worker})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Consume(workerCtx, consumer, func(ctx context.Context, msg *Message) error {
+				mu.Lock()
+				seen[name] = append(seen[name], string(msg.Body))
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+
+	for i := 1; i <= 6; i++ {
+		broker.Publish(ctx, "orders", []byte(fmt.Sprintf("order-%d", i)), nil)
+	}
+	time.Sleep(200 * time.Millisecond)
+	stopWorkers()
+	wg.Wait()
+
+	for _, name := range []string{"billing/worker-1", "billing/worker-2", "shipping/worker-1"} {
+		fmt.Printf("📦 %-18s handled %v\n", name, seen[name])
+	}
+	fmt.Printf("🧹 messages still retained: %d\n", broker.Len("orders"))
+}
+
+// RetryExamples shows redelivery after failures and lost acknowledgements,
+// and dead-lettering once attempts run out
+func RetryExamples() {
+	fmt.Println("\n=== Retries, Visibility Timeouts and Dead Letters ===")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker := NewMemory()
+
+	consumer, _ := broker.Consumer(ConsumerOptions{
+		Topic:             "emails",
+		Group:             "mailer",
+		VisibilityTimeout: 100 * time.Millisecond,
+		MaxAttempts:       3,
+		RetryDelay:        20 * time.Millisecond,
+	})
+	defer consumer.Close()
+
+	broker.Publish(ctx, "emails", []byte("to: flaky@example.com"), nil)
+	broker.Publish(ctx, "emails", []byte("to: crashy@example.com"), nil)
+	broker.Publish(ctx, "emails", []byte("to: bounce@example.com"), map[string]string{"campaign": "spring"})
+
+	sent := 0
+	for sent < 2 || broker.Len("emails.dlq") == 0 {
+		msg, err := consumer.Receive(ctx)
+		if err != nil {
+			fmt.Printf("❌ receive: %v\n", err)
+			return
+		}
+		switch string(msg.Body) {
+		case "to: flaky@example.com":
+			if msg.Attempts < 2 {
+				fmt.Printf("⚠️  %s attempt %d failed, nacking (retry in %s)\n", msg.Body, msg.Attempts, 20*time.Millisecond)
+				consumer.Nack(ctx, msg, errors.New("SMTP timeout"))
+				continue
+			}
+			fmt.Printf("✅ %s sent on attempt %d\n", msg.Body, msg.Attempts)
+			consumer.Ack(ctx, msg)
+			sent++
+		case "to: crashy@example.com":
+			if msg.Attempts < 2 {
+				fmt.Printf("💥 %s attempt %d never acknowledged (worker crashed)\n", msg.Body, msg.Attempts)
+				continue
+			}
+			fmt.Printf("✅ %s redelivered after the visibility timeout, sent on attempt %d\n", msg.Body, msg.Attempts)
+			consumer.Ack(ctx, msg)
+			sent++
+		default:
+			fmt.Printf("⚠️  %s attempt %d failed, nacking\n", msg.Body, msg.Attempts)
+			consumer.Nack(ctx, msg, errors.New("mailbox does not exist"))
+		}
+	}
+
+	dlq, _ := broker.Consumer(ConsumerOptions{Topic: "emails.dlq", Group: "ops"})
+	defer dlq.Close()
+	msg, err := dlq.Receive(ctx)
+	if err != nil {
+		fmt.Printf("❌ dead-letter receive: %v\n", err)
+		return
+	}
+	fmt.Printf("☠️  dead letter: %s (topic=%s attempts=%s error=%q campaign=%s)\n", msg.Body,
+		msg.Headers[HeaderOriginalTopic], msg.Headers[HeaderAttempts], msg.Headers[HeaderError], msg.Headers["campaign"])
+	dlq.Ack(ctx, msg)
+}
+
+// RedisStreamsExamples publishes and consumes through Redis Streams when a
+// server is reachable at $REDIS_ADDR (default localhost:6379)
+func RedisStreamsExamples() {
+	fmt.Println("\n=== Redis Streams Backend ===")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := cache.NewRedis(cache.RedisOptions{Addr: addr, DialTimeout: 500 * time.Millisecond})
+	defer client.Close()
+	if err := client.Ping(ctx); err != nil {
+		fmt.Printf("⚠️  Redis at %s unavailable (%v); skipping\n", addr, err)
+		return
+	}
+
+	broker := NewRedisStreams(client)
+	broker.MaxLen = 1000
+	topic := fmt.Sprintf("gopractice:events:%d", time.Now().UnixNano())
+	defer client.Do(context.Background(), "DEL", topic)
+
+	consumer, err := broker.Consumer(ConsumerOptions{Topic: topic, Group: "audit", VisibilityTimeout: time.Second})
+	if err != nil {
+		fmt.Printf("❌ create group: %v\n", err)
+		return
+	}
+	defer consumer.Close()
+
+	id, err := broker.Publish(ctx, topic, []byte(`{"event":"login","user":1}`), map[string]string{"source": "web"})
+	if err != nil {
+		fmt.Printf("❌ publish: %v\n", err)
+		return
+	}
+	fmt.Printf("📤 XADD %s -> %s\n", topic, id)
+
+	msg, err := consumer.Receive(ctx)
+	if err != nil {
+		fmt.Printf("❌ receive: %v\n", err)
+		return
+	}
+	fmt.Printf("📥 %s %s headers=%v attempt=%d\n", msg.ID, msg.Body, msg.Headers, msg.Attempts)
+	consumer.Nack(ctx, msg, errors.New("try again"))
+
+	msg, err = consumer.Receive(ctx)
+	if err != nil {
+		fmt.Printf("❌ receive: %v\n", err)
+		return
+	}
+	fmt.Printf("🔁 redelivered %s attempt=%d\n", msg.ID, msg.Attempts)
+	if err := consumer.Ack(ctx, msg); err == nil {
+		fmt.Println("✅ XACK done")
+	}
+}
+
+// OrderPlaced and OrderCancelled are the events in TypedPayloadExamples
+type OrderPlaced struct {
+	OrderID string  `json:"order_id"`
+	Total   float64 `json:"total"`
+}
+
+type OrderCancelled struct {
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+}
+
+// TypedPayloadExamples shows different event types sharing one topic, with
+// the handler switching on the decoded type
+func TypedPayloadExamples() {
+	fmt.Println("\n=== Typed Payloads ===")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker := NewMemory()
+
+	types := reflectops.NewTypeRegistry()
+	types.MustRegister("order.placed", OrderPlaced{})
+	types.MustRegister("order.cancelled", OrderCancelled{})
+
+	PublishValue(ctx, broker, "order-events", types, OrderPlaced{OrderID: "A-1", Total: 42.5}, nil)
+	PublishValue(ctx, broker, "order-events", types, OrderCancelled{OrderID: "A-1", Reason: "out of stock"}, nil)
+	broker.Publish(ctx, "order-events", []byte(`{"kind":"order.refunded"}`), nil)
+
+	consumer, _ := broker.Consumer(ConsumerOptions{Topic: "order-events", Group: "audit", Name: "worker-1", MaxAttempts: 1})
+	defer consumer.Close()
+	for i := 0; i < 3; i++ {
+		msg, err := consumer.Receive(ctx)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		event, err := DecodeMessage(types, msg)
+		switch event := event.(type) {
+		case *OrderPlaced:
+			fmt.Printf("🛒 %s placed for %.2f (%s)\n", event.OrderID, event.Total, msg.Body)
+		case *OrderCancelled:
+			fmt.Printf("🚫 %s cancelled: %s (%s)\n", event.OrderID, event.Reason, msg.Body)
+		default:
+			fmt.Printf("❓ %v\n", err)
+			consumer.Nack(ctx, msg, err)
+			continue
+		}
+		consumer.Ack(ctx, msg)
+	}
+	fmt.Printf("☠️  dead-lettered: %d\n", broker.Len("order-events.dlq"))
+}
+
+// RunAllQueueExamples runs all queue examples
+func RunAllQueueExamples() {
+	ConsumerGroupExamples()
+	RetryExamples()
+	TypedPayloadExamples()
+	RedisStreamsExamples()
+}
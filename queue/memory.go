@@ -0,0 +1,275 @@
+package queue
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// wakeups holds every in-memory consumer group's visibility timeouts and
+// retry delays. Nearly all of them are cancelled by an Ack, which a timing
+// wheel makes O(1).
+var wakeups = sync.OnceValue(func() *data_structure.TimingWheel {
+	return data_structure.NewTimingWheel(data_structure.TimingWheelOptions{Tick: 5 * time.Millisecond, Workers: 2})
+})
+
+// Memory is an in-process Broker. Topics are append-only logs that each
+// consumer group reads at its own offset; receivers wait on a channel that
+// is closed whenever a topic changes, including when a hidden message
+// becomes visible again.
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]*memTopic
+	seq    int64
+	now    func() time.Time
+}
+
+type memTopic struct {
+	log     []*Message
+	base    int // offset of log[0]; older messages have been read by every group
+	groups  map[string]*memGroup
+	changed chan struct{}
+}
+
+type memGroup struct {
+	next    int // offset of the next message never delivered to the group
+	pending map[string]*memPending
+}
+
+type memPending struct {
+	msg       *Message
+	offset    int
+	visibleAt time.Time
+	wake      *data_structure.WheelTimer
+}
+
+// NewMemory creates an empty in-memory broker
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]*memTopic), now: time.Now}
+}
+
+// Publish implements Producer
+func (m *Memory) Publish(ctx context.Context, topic string, body []byte, headers map[string]string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publishLocked(topic, body, headers), nil
+}
+
+func (m *Memory) publishLocked(topic string, body []byte, headers map[string]string) string {
+	m.seq++
+	msg := &Message{
+		ID:          strconv.FormatInt(m.seq, 10),
+		Topic:       topic,
+		Body:        append([]byte(nil), body...),
+		Headers:     maps.Clone(headers),
+		PublishedAt: m.now(),
+	}
+	t := m.topic(topic)
+	t.log = append(t.log, msg)
+	t.notify()
+	return msg.ID
+}
+
+// Len returns the number of messages retained for topic, i.e. those not yet
+// delivered to every group
+func (m *Memory) Len(topic string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.topics[topic]; ok {
+		return len(t.log)
+	}
+	return 0
+}
+
+func (m *Memory) topic(name string) *memTopic {
+	t, ok := m.topics[name]
+	if !ok {
+		t = &memTopic{groups: make(map[string]*memGroup), changed: make(chan struct{})}
+		m.topics[name] = t
+	}
+	return t
+}
+
+// notify wakes every receiver waiting on the topic
+func (t *memTopic) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *memTopic) group(name string) *memGroup {
+	g, ok := t.groups[name]
+	if !ok {
+		// New groups start from the oldest retained message
+		g = &memGroup{next: t.base, pending: make(map[string]*memPending)}
+		t.groups[name] = g
+	}
+	return g
+}
+
+// trim drops messages that every group has received
+func (t *memTopic) trim() {
+	low := t.base + len(t.log)
+	for _, g := range t.groups {
+		low = min(low, g.next)
+	}
+	if n := low - t.base; n > 0 {
+		clear(t.log[:n])
+		t.log = t.log[n:]
+		t.base = low
+	}
+}
+
+// Consumer implements Broker
+func (m *Memory) Consumer(opts ConsumerOptions) (Consumer, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.topic(opts.Topic).group(opts.Group)
+	m.mu.Unlock()
+	return &memConsumer{broker: m, opts: opts, done: make(chan struct{})}, nil
+}
+
+type memConsumer struct {
+	broker    *Memory
+	opts      ConsumerOptions
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *memConsumer) Receive(ctx context.Context) (*Message, error) {
+	m := c.broker
+	for {
+		m.mu.Lock()
+		select {
+		case <-c.done:
+			m.mu.Unlock()
+			return nil, ErrClosed
+		default:
+		}
+		t := m.topic(c.opts.Topic)
+		g := t.group(c.opts.Group)
+		now := m.now()
+
+		// Redeliver the oldest message whose visibility timeout has expired
+		var expired *memPending
+		for _, p := range g.pending {
+			if p.visibleAt.After(now) {
+				continue
+			}
+			if expired == nil || p.offset < expired.offset {
+				expired = p
+			}
+		}
+		if expired != nil {
+			if expired.msg.Attempts >= c.opts.MaxAttempts {
+				c.deadLetterLocked(g, expired.msg, errVisibilityTimeout)
+				m.mu.Unlock()
+				continue
+			}
+			expired.msg.Attempts++
+			m.hideLocked(t, expired, c.opts.VisibilityTimeout)
+			msg := *expired.msg
+			m.mu.Unlock()
+			return &msg, nil
+		}
+
+		if g.next < t.base+len(t.log) {
+			original := t.log[g.next-t.base]
+			delivered := *original
+			delivered.Attempts = 1
+			p := &memPending{msg: &delivered, offset: g.next}
+			m.hideLocked(t, p, c.opts.VisibilityTimeout)
+			g.pending[delivered.ID] = p
+			g.next++
+			t.trim()
+			msg := delivered
+			m.mu.Unlock()
+			return &msg, nil
+		}
+
+		changed := t.changed
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-c.done:
+		case <-changed:
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *memConsumer) Ack(ctx context.Context, msg *Message) error {
+	m := c.broker
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Acking a message that was already redelivered elsewhere is allowed;
+	// the other delivery's Ack or Nack then becomes a no-op
+	g := m.topic(c.opts.Topic).group(c.opts.Group)
+	if p, ok := g.pending[msg.ID]; ok {
+		p.wake.Cancel()
+		delete(g.pending, msg.ID)
+	}
+	return nil
+}
+
+func (c *memConsumer) Nack(ctx context.Context, msg *Message, cause error) error {
+	m := c.broker
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.topic(c.opts.Topic)
+	g := t.group(c.opts.Group)
+	p, ok := g.pending[msg.ID]
+	if !ok {
+		return nil
+	}
+	if p.msg.Attempts >= c.opts.MaxAttempts {
+		c.deadLetterLocked(g, p.msg, cause)
+		return nil
+	}
+	if delay := c.opts.retryDelay(p.msg.Attempts); delay > 0 {
+		m.hideLocked(t, p, delay)
+		return nil
+	}
+	p.wake.Cancel()
+	p.visibleAt = m.now()
+	t.notify()
+	return nil
+}
+
+// hideLocked hides p from the group for d and has the wheel wake the
+// topic's receivers when it is visible again
+func (m *Memory) hideLocked(t *memTopic, p *memPending, d time.Duration) {
+	p.visibleAt = m.now().Add(d)
+	if p.wake != nil {
+		p.wake.Reset(d)
+		return
+	}
+	p.wake = wakeups().Schedule(d, func() {
+		m.mu.Lock()
+		t.notify()
+		m.mu.Unlock()
+	})
+}
+
+func (c *memConsumer) deadLetterLocked(g *memGroup, msg *Message, cause error) {
+	logger.Warn("Dead-lettering message", "topic", msg.Topic, "id", msg.ID, "attempts", msg.Attempts, "dlq", c.opts.DeadLetterTopic)
+	if p, ok := g.pending[msg.ID]; ok {
+		p.wake.Cancel()
+		delete(g.pending, msg.ID)
+	}
+	c.broker.publishLocked(c.opts.DeadLetterTopic, msg.Body, deadLetterHeaders(msg, cause))
+}
+
+func (c *memConsumer) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
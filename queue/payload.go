@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	reflectops "github.com/jerrychou/go-practice/reflect"
+)
+
+// PublishValue publishes v as JSON tagged with the name its type is
+// registered under in types, so consumers can decode it with DecodeMessage
+// without knowing in advance which type each message holds
+func PublishValue(ctx context.Context, producer Producer, topic string, types *reflectops.TypeRegistry, v interface{}, headers map[string]string) (string, error) {
+	body, err := types.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encoding payload: %w", err)
+	}
+	return producer.Publish(ctx, topic, body, headers)
+}
+
+// DecodeMessage decodes a body published with PublishValue into a new value
+// of the registered type it names, returning a pointer to it
+func DecodeMessage(types *reflectops.TypeRegistry, msg *Message) (interface{}, error) {
+	v, err := types.Decode(msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding message %s: %w", msg.ID, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,179 @@
+// Package queue provides at-least-once message delivery with consumer
+// groups, visibility timeouts and dead-letter topics, backed by in-memory
+// channels or Redis Streams.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("queue")
+
+// ErrClosed is returned by a Consumer after Close
+var ErrClosed = errors.New("queue: consumer closed")
+
+// errVisibilityTimeout is recorded on messages dead-lettered because their
+// last delivery was never acknowledged
+var errVisibilityTimeout = errors.New("visibility timeout expired")
+
+// Header names added to dead-lettered messages
+const (
+	HeaderOriginalTopic = "x-original-topic"
+	HeaderAttempts      = "x-attempts"
+	HeaderError         = "x-error"
+)
+
+// Message is a unit of work published to a topic
+type Message struct {
+	ID          string
+	Topic       string
+	Body        []byte
+	Headers     map[string]string
+	PublishedAt time.Time
+	// Attempts counts deliveries to the group, including this one
+	Attempts int
+}
+
+// Producer publishes messages
+type Producer interface {
+	// Publish appends body to topic and returns the message ID
+	Publish(ctx context.Context, topic string, body []byte, headers map[string]string) (string, error)
+}
+
+// Consumer receives messages for one member of a consumer group. Every
+// group sees every message on its topic, and each message is delivered to
+// one consumer in the group at a time.
+type Consumer interface {
+	// Receive blocks until a message is available or ctx is done. The
+	// message is hidden from the group until it is acknowledged or its
+	// visibility timeout expires.
+	Receive(ctx context.Context) (*Message, error)
+	// Ack marks the message as processed
+	Ack(ctx context.Context, msg *Message) error
+	// Nack makes the message available again after the consumer's retry
+	// delay, or moves it to the dead-letter topic when it has used all its
+	// attempts
+	Nack(ctx context.Context, msg *Message, cause error) error
+	Close() error
+}
+
+// Broker creates consumers for a backend
+type Broker interface {
+	Producer
+	Consumer(opts ConsumerOptions) (Consumer, error)
+}
+
+// ConsumerOptions identifies a consumer and sets its delivery guarantees
+type ConsumerOptions struct {
+	Topic string
+	Group string
+	Name  string // unique within the group, defaults to the group name
+	// VisibilityTimeout is how long a received message stays hidden before
+	// it is redelivered, defaults to 30s
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the number of deliveries before a message is dead
+	// lettered, defaults to 5
+	MaxAttempts int
+	// RetryDelay is how long a nacked message waits before redelivery,
+	// doubling with each attempt up to VisibilityTimeout. Zero redelivers
+	// it at once.
+	RetryDelay time.Duration
+	// DeadLetterTopic defaults to Topic + ".dlq"
+	DeadLetterTopic string
+}
+
+func (o ConsumerOptions) withDefaults() (ConsumerOptions, error) {
+	if o.Topic == "" || o.Group == "" {
+		return o, fmt.Errorf("queue: consumer needs a topic and a group")
+	}
+	if o.Name == "" {
+		o.Name = o.Group
+	}
+	if o.VisibilityTimeout <= 0 {
+		o.VisibilityTimeout = 30 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.DeadLetterTopic == "" {
+		o.DeadLetterTopic = o.Topic + ".dlq"
+	}
+	return o, nil
+}
+
+// retryDelay is how long a message nacked after attempts deliveries
+// waits before the next
+func (o ConsumerOptions) retryDelay(attempts int) time.Duration {
+	if o.RetryDelay <= 0 {
+		return 0
+	}
+	d := o.RetryDelay
+	for i := 1; i < attempts && d < o.VisibilityTimeout; i++ {
+		d *= 2
+	}
+	return min(d, o.VisibilityTimeout)
+}
+
+// deadLetterHeaders copies msg's headers and records why it was dropped
+func deadLetterHeaders(msg *Message, cause error) map[string]string {
+	headers := make(map[string]string, len(msg.Headers)+3)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderOriginalTopic] = msg.Topic
+	headers[HeaderAttempts] = fmt.Sprint(msg.Attempts)
+	if cause != nil {
+		headers[HeaderError] = cause.Error()
+	}
+	return headers
+}
+
+// Handler processes one message; returning an error redelivers it
+type Handler func(ctx context.Context, msg *Message) error
+
+// Consume receives messages and passes them to handler until ctx is done,
+// acknowledging those it handles and nacking those that fail or panic.
+// Handlers must be idempotent: a message is redelivered if the process
+// dies before Ack.
+func Consume(ctx context.Context, consumer Consumer, handler Handler) error {
+	for {
+		msg, err := consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+				return nil
+			}
+			logger.Error("Receive failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		if err := handle(ctx, handler, msg); err != nil {
+			logger.Warn("Message failed", "topic", msg.Topic, "id", msg.ID, "attempt", msg.Attempts, "error", err)
+			if err := consumer.Nack(ctx, msg, err); err != nil {
+				logger.Error("Nack failed", "id", msg.ID, "error", err)
+			}
+			continue
+		}
+		if err := consumer.Ack(ctx, msg); err != nil {
+			logger.Error("Ack failed", "id", msg.ID, "error", err)
+		}
+	}
+}
+
+func handle(ctx context.Context, handler Handler, msg *Message) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return handler(ctx, msg)
+}
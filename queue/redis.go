@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+)
+
+// RedisStreams is a Broker backed by Redis Streams (Redis 6.2 or later).
+// Topics are streams, consumer groups are stream groups, and the pending
+// entries list tracks unacknowledged deliveries.
+type RedisStreams struct {
+	client *cache.Redis
+	// MaxLen caps each stream at roughly this many entries; 0 keeps all
+	MaxLen int64
+}
+
+// NewRedisStreams publishes and consumes through client
+func NewRedisStreams(client *cache.Redis) *RedisStreams {
+	return &RedisStreams{client: client}
+}
+
+// Publish implements Producer with XADD
+func (r *RedisStreams) Publish(ctx context.Context, topic string, body []byte, headers map[string]string) (string, error) {
+	args := []any{"XADD", topic}
+	if r.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", r.MaxLen)
+	}
+	args = append(args, "*", "body", body)
+	if len(headers) > 0 {
+		encoded, err := json.Marshal(headers)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "headers", encoded)
+	}
+	reply, err := r.client.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	id, ok := reply.([]byte)
+	if !ok {
+		return "", fmt.Errorf("queue: unexpected XADD reply %T", reply)
+	}
+	return string(id), nil
+}
+
+// Consumer implements Broker, creating the group if it does not exist
+func (r *RedisStreams) Consumer(opts ConsumerOptions) (Consumer, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.client.Do(context.Background(), "XGROUP", "CREATE", opts.Topic, opts.Group, "0", "MKSTREAM")
+	var redisErr cache.RedisError
+	if err != nil && !(errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "BUSYGROUP")) {
+		return nil, err
+	}
+	return &redisConsumer{streams: r, opts: opts}, nil
+}
+
+type redisConsumer struct {
+	streams *RedisStreams
+	opts    ConsumerOptions
+	closed  atomic.Bool
+}
+
+// maxBlock bounds each XREADGROUP so Close and expired deliveries are
+// noticed promptly
+const maxBlock = time.Second
+
+func (c *redisConsumer) Receive(ctx context.Context) (*Message, error) {
+	for {
+		if c.closed.Load() {
+			return nil, ErrClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := c.claimExpired(ctx)
+		if err != nil || msg != nil {
+			return msg, err
+		}
+
+		block := maxBlock
+		if deadline, ok := ctx.Deadline(); ok {
+			// Leave time for the reply before the connection deadline
+			block = min(block, time.Until(deadline)-50*time.Millisecond)
+		}
+		if block <= 0 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		reply, err := c.streams.client.Do(ctx, "XREADGROUP", "GROUP", c.opts.Group, c.opts.Name,
+			"COUNT", 1, "BLOCK", block.Milliseconds(), "STREAMS", c.opts.Topic, ">")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if reply == nil {
+			continue // timed out
+		}
+		// [[stream, [[id, fields]]]]
+		streams, _ := reply.([]any)
+		if len(streams) == 0 {
+			continue
+		}
+		stream, _ := streams[0].([]any)
+		if len(stream) < 2 {
+			continue
+		}
+		entries, _ := stream[1].([]any)
+		if len(entries) == 0 {
+			continue
+		}
+		msg, err = c.parseEntry(entries[0])
+		if err != nil {
+			return nil, err
+		}
+		msg.Attempts = 1
+		return msg, nil
+	}
+}
+
+// claimExpired takes over the oldest delivery whose visibility timeout has
+// passed, dead-lettering it instead if it has no attempts left
+func (c *redisConsumer) claimExpired(ctx context.Context) (*Message, error) {
+	client := c.streams.client
+	visibility := c.opts.VisibilityTimeout.Milliseconds()
+	for {
+		reply, err := client.Do(ctx, "XPENDING", c.opts.Topic, c.opts.Group, "IDLE", visibility, "-", "+", 1)
+		if err != nil {
+			return nil, err
+		}
+		// [[id, consumer, idle ms, deliveries]]
+		pending, _ := reply.([]any)
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		info, _ := pending[0].([]any)
+		if len(info) < 4 {
+			return nil, fmt.Errorf("queue: unexpected XPENDING reply %v", info)
+		}
+		id, _ := info[0].([]byte)
+		deliveries, _ := info[3].(int64)
+
+		reply, err = client.Do(ctx, "XCLAIM", c.opts.Topic, c.opts.Group, c.opts.Name, visibility, id)
+		if err != nil {
+			return nil, err
+		}
+		claimed, _ := reply.([]any)
+		if len(claimed) == 0 {
+			continue // another consumer claimed it first
+		}
+		if claimed[0] == nil {
+			// The entry was trimmed from the stream; drop the stale delivery
+			if _, err := client.Do(ctx, "XACK", c.opts.Topic, c.opts.Group, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		msg, err := c.parseEntry(claimed[0])
+		if err != nil {
+			return nil, err
+		}
+		msg.Attempts = int(deliveries)
+		if msg.Attempts >= c.opts.MaxAttempts {
+			if err := c.deadLetter(ctx, msg, errVisibilityTimeout); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		msg.Attempts++ // XCLAIM counted this delivery
+		return msg, nil
+	}
+}
+
+func (c *redisConsumer) parseEntry(entry any) (*Message, error) {
+	parts, _ := entry.([]any)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("queue: unexpected stream entry %v", entry)
+	}
+	id, _ := parts[0].([]byte)
+	fields, _ := parts[1].([]any)
+	msg := &Message{ID: string(id), Topic: c.opts.Topic}
+	if ms, _, ok := strings.Cut(msg.ID, "-"); ok {
+		if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			msg.PublishedAt = time.UnixMilli(n)
+		}
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := fields[i].([]byte)
+		value, _ := fields[i+1].([]byte)
+		switch string(name) {
+		case "body":
+			msg.Body = value
+		case "headers":
+			if err := json.Unmarshal(value, &msg.Headers); err != nil {
+				return nil, fmt.Errorf("queue: bad headers on %s: %w", msg.ID, err)
+			}
+		}
+	}
+	return msg, nil
+}
+
+func (c *redisConsumer) Ack(ctx context.Context, msg *Message) error {
+	_, err := c.streams.client.Do(ctx, "XACK", c.opts.Topic, c.opts.Group, msg.ID)
+	return err
+}
+
+func (c *redisConsumer) Nack(ctx context.Context, msg *Message, cause error) error {
+	if msg.Attempts >= c.opts.MaxAttempts {
+		return c.deadLetter(ctx, msg, cause)
+	}
+	// Back-date the idle time so claimExpired picks it up once the retry
+	// delay has passed; JUSTID leaves the delivery counter alone
+	idle := c.opts.VisibilityTimeout - c.opts.retryDelay(msg.Attempts)
+	_, err := c.streams.client.Do(ctx, "XCLAIM", c.opts.Topic, c.opts.Group, c.opts.Name, 0, msg.ID,
+		"IDLE", idle.Milliseconds(), "JUSTID")
+	return err
+}
+
+// deadLetter publishes before acknowledging, so a crash in between leaves
+// a duplicate in the dead-letter topic rather than losing the message
+func (c *redisConsumer) deadLetter(ctx context.Context, msg *Message, cause error) error {
+	logger.Warn("Dead-lettering message", "topic", msg.Topic, "id", msg.ID, "attempts", msg.Attempts, "dlq", c.opts.DeadLetterTopic)
+	if _, err := c.streams.Publish(ctx, c.opts.DeadLetterTopic, msg.Body, deadLetterHeaders(msg, cause)); err != nil {
+		return err
+	}
+	return c.Ack(ctx, msg)
+}
+
+// Close stops Receive; the shared Redis client stays open
+func (c *redisConsumer) Close() error {
+	c.closed.Store(true)
+	return nil
+}
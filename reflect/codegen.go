@@ -0,0 +1,362 @@
+package reflect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenOptions configures GenerateStructs
+type GenOptions struct {
+	Package  string // package clause of the generated file, "main" if empty
+	TypeName string // name of the root struct, "Root" if empty
+	// Output is a file to write the source to as well as returning it
+	Output string
+}
+
+// GenerateStructs infers Go struct definitions with json tags from one or
+// more sample JSON documents of the same shape, such as API responses. A
+// field missing from some samples gets omitempty, and one that is null in
+// some gets a pointer type. A top-level array is treated as a list of
+// samples of its element.
+func GenerateStructs(opts GenOptions, samples ...[]byte) (string, error) {
+	if len(samples) == 0 {
+		return "", fmt.Errorf("at least one sample is required")
+	}
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if opts.TypeName == "" {
+		opts.TypeName = "Root"
+	}
+
+	root := &jsonShape{}
+	for i, sample := range samples {
+		value, err := decodeOrdered(sample)
+		if err != nil {
+			return "", fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		if items, ok := value.([]interface{}); ok {
+			for _, item := range items {
+				root.observe(item)
+			}
+			continue
+		}
+		root.observe(value)
+	}
+	if root.kinds != kindObject {
+		return "", fmt.Errorf("samples must be JSON objects or arrays of objects")
+	}
+
+	g := &structGen{names: map[string]bool{}}
+	g.named(root, opts.TypeName, "")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated from sample JSON by reflect.GenerateStructs.\n\npackage %s\n", opts.Package)
+	for _, def := range g.defs {
+		buf.WriteString("\n")
+		buf.WriteString(def)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("formatting generated source: %w", err)
+	}
+
+	if opts.Output != "" {
+		if err := os.WriteFile(opts.Output, source, 0644); err != nil {
+			return "", fmt.Errorf("writing %s: %w", opts.Output, err)
+		}
+	}
+	return string(source), nil
+}
+
+// jsonObject keeps an object's keys in document order, which encoding/json
+// maps lose, so generated fields follow the sample
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+func decodeOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var obj jsonObject
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{Key: key.(string), Value: value})
+		}
+		_, err := dec.Token() // closing brace
+		return obj, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			item, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token() // closing bracket
+		return items, err
+	}
+	return tok, nil
+}
+
+// shapeKind is a set of the JSON types seen for one value
+type shapeKind uint8
+
+const (
+	kindBool shapeKind = 1 << iota
+	kindInt
+	kindFloat
+	kindString
+	kindObject
+	kindArray
+)
+
+// jsonShape merges every value seen at one place across the samples
+type jsonShape struct {
+	kinds    shapeKind
+	nullable bool
+
+	objects int // how many objects were seen, to tell optional fields
+	keys    []string
+	fields  map[string]*jsonField
+
+	elem *jsonShape // merged array elements
+}
+
+type jsonField struct {
+	shape *jsonShape
+	seen  int
+}
+
+func (s *jsonShape) observe(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		s.nullable = true
+	case bool:
+		s.kinds |= kindBool
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			s.kinds |= kindInt
+		} else {
+			s.kinds |= kindFloat
+		}
+	case string:
+		s.kinds |= kindString
+	case jsonObject:
+		s.kinds |= kindObject
+		s.objects++
+		if s.fields == nil {
+			s.fields = map[string]*jsonField{}
+		}
+		for _, member := range v {
+			field, ok := s.fields[member.Key]
+			if !ok {
+				field = &jsonField{shape: &jsonShape{}}
+				s.fields[member.Key] = field
+				s.keys = append(s.keys, member.Key)
+			}
+			field.seen++
+			field.shape.observe(member.Value)
+		}
+	case []interface{}:
+		s.kinds |= kindArray
+		if s.elem == nil {
+			s.elem = &jsonShape{}
+		}
+		for _, item := range v {
+			s.elem.observe(item)
+		}
+	}
+}
+
+// structGen renders shapes as Go types, naming nested structs uniquely
+type structGen struct {
+	names map[string]bool
+	defs  []string
+}
+
+// goType returns the Go type for s, defining nested structs named after
+// the field they came from. Nullable values are pointers, and so are
+// optional structs, which omitempty would not leave out.
+func (g *structGen) goType(s *jsonShape, name, parent string, optional bool) string {
+	var t string
+	switch s.kinds {
+	case 0:
+		return "interface{}" // only ever null, or an empty array
+	case kindBool:
+		t = "bool"
+	case kindInt:
+		t = "int64"
+	case kindFloat, kindInt | kindFloat:
+		t = "float64"
+	case kindString:
+		t = "string"
+	case kindObject:
+		t = g.named(s, name, parent)
+	case kindArray:
+		return "[]" + g.goType(s.elem, singular(name), parent, false)
+	default:
+		return "interface{}"
+	}
+	if s.nullable || optional && s.kinds == kindObject {
+		return "*" + t
+	}
+	return t
+}
+
+// named defines a struct for an object shape and returns its name. A name
+// already taken is prefixed with the parent type, then numbered.
+func (g *structGen) named(s *jsonShape, name, parent string) string {
+	typeName := name
+	if g.names[typeName] {
+		typeName = parent + name
+	}
+	for n := 2; g.names[typeName]; n++ {
+		typeName = fmt.Sprintf("%s%s%d", parent, name, n)
+	}
+	g.names[typeName] = true
+
+	index := len(g.defs)
+	g.defs = append(g.defs, "") // reserve the slot so parents come first
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	fieldNames := map[string]bool{}
+	for _, key := range s.keys {
+		field := s.fields[key]
+		fieldName := goIdentifier(key)
+		for n := 2; fieldNames[fieldName]; n++ {
+			fieldName = fmt.Sprintf("%s%d", goIdentifier(key), n)
+		}
+		fieldNames[fieldName] = true
+
+		tag, optional := key, field.seen < s.objects
+		if optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", fieldName, g.goType(field.shape, fieldName, typeName, optional), tag)
+	}
+	b.WriteString("}\n")
+
+	g.defs[index] = b.String()
+	return typeName
+}
+
+// commonInitialisms are written in capitals, as golint expects
+var commonInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "SHA": true, "SQL": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UI": true, "URI": true,
+	"URL": true, "UTC": true, "UUID": true, "XML": true,
+}
+
+// goIdentifier turns a JSON key such as "html_url" or "nodeId" into an
+// exported Go name such as HTMLURL or NodeID
+func goIdentifier(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "Field" + name
+	}
+	return name
+}
+
+// singular names the element type of a slice field, e.g. Labels → Label
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name + "Item"
+}
+
+func demonstrateStructGeneration() {
+	samples := [][]byte{
+		[]byte(`{"id": 1, "node_id": "I_1", "html_url": "https://github.com/o/r/issues/1",
+			"title": "Crash on start", "state": "open", "comments": 2, "score": 1,
+			"user": {"login": "octocat", "id": 583231, "site_admin": false},
+			"labels": [{"name": "bug", "color": "d73a4a"}],
+			"assignee": null, "milestone": {"title": "v1.0", "due_on": null}}`),
+		[]byte(`{"id": 2, "node_id": "I_2", "html_url": "https://github.com/o/r/issues/2",
+			"title": "Add dark mode", "state": "closed", "comments": 0, "score": 0.5,
+			"user": {"login": "hubot", "id": 2, "site_admin": true},
+			"labels": [], "assignee": {"login": "octocat", "id": 583231, "site_admin": false},
+			"closed_at": "2024-05-01T10:00:00Z"}`),
+	}
+
+	source, err := GenerateStructs(GenOptions{Package: "github", TypeName: "Issue"}, samples...)
+	if err != nil {
+		fmt.Printf("Error generating structs: %v\n", err)
+		return
+	}
+	fmt.Printf("Structs inferred from %d GitHub issue samples:\n\n%s", len(samples), source)
+}
@@ -0,0 +1,329 @@
+package reflect
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Scope controls how long a resolved instance lives
+type Scope int
+
+const (
+	// Singleton providers are built once and shared by every consumer
+	Singleton Scope = iota
+	// Transient providers are built again for every resolution
+	Transient
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type providerKey struct {
+	typ  reflect.Type
+	name string
+}
+
+func (k providerKey) String() string {
+	if k.name != "" {
+		return fmt.Sprintf("%v(%s)", k.typ, k.name)
+	}
+	return k.typ.String()
+}
+
+type provider struct {
+	constructor reflect.Value
+	instance    reflect.Value
+	scope       Scope
+}
+
+// Container is a small dependency injection container. Constructors are plain
+// functions whose parameters are resolved from the container; struct pointers
+// built by the container also get their `inject:""` fields populated.
+type Container struct {
+	mu        sync.Mutex
+	providers map[providerKey]*provider
+	resolving []providerKey
+}
+
+// NewContainer creates an empty container
+func NewContainer() *Container {
+	return &Container{providers: make(map[providerKey]*provider)}
+}
+
+// Register adds a constructor of the form func(deps...) T or func(deps...) (T, error).
+// The provided type is T.
+func (c *Container) Register(constructor interface{}, scope Scope) error {
+	return c.RegisterNamed("", constructor, scope)
+}
+
+// RegisterNamed is like Register but stores the provider under a name, so
+// several providers of the same type can coexist
+func (c *Container) RegisterNamed(name string, constructor interface{}, scope Scope) error {
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func {
+		return fmt.Errorf("constructor must be a function, got %T", constructor)
+	}
+
+	fnType := fn.Type()
+	switch {
+	case fnType.NumOut() == 1:
+	case fnType.NumOut() == 2 && fnType.Out(1) == errorType:
+	default:
+		return fmt.Errorf("constructor %v must return T or (T, error)", fnType)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := providerKey{typ: fnType.Out(0), name: name}
+	if _, exists := c.providers[key]; exists {
+		return fmt.Errorf("provider for %s already registered", key)
+	}
+	c.providers[key] = &provider{constructor: fn, scope: scope}
+	return nil
+}
+
+// RegisterInstance adds an already-built value as a singleton
+func (c *Container) RegisterInstance(instance interface{}) error {
+	return c.RegisterInstanceNamed("", instance)
+}
+
+// RegisterInstanceNamed adds an already-built value as a named singleton
+func (c *Container) RegisterInstanceNamed(name string, instance interface{}) error {
+	value := reflect.ValueOf(instance)
+	if !value.IsValid() {
+		return fmt.Errorf("cannot register nil instance")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := providerKey{typ: value.Type(), name: name}
+	if _, exists := c.providers[key]; exists {
+		return fmt.Errorf("provider for %s already registered", key)
+	}
+	c.providers[key] = &provider{instance: value, scope: Singleton}
+	return nil
+}
+
+// Resolve fills target, which must be a pointer to the wanted type
+func (c *Container) Resolve(target interface{}) error {
+	return c.ResolveNamed("", target)
+}
+
+// ResolveNamed fills target with the provider registered under name
+func (c *Container) ResolveNamed(name string, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, err := c.resolve(ptr.Type().Elem(), name)
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(value)
+	return nil
+}
+
+// Invoke calls fn with all of its parameters resolved from the container.
+// If fn's last result is an error it is returned.
+func (c *Container) Invoke(fn interface{}) error {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return fmt.Errorf("fn must be a function, got %T", fn)
+	}
+
+	c.mu.Lock()
+	args, err := c.resolveArgs(fnValue.Type())
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	results := fnValue.Call(args)
+	if n := len(results); n > 0 && fnValue.Type().Out(n-1) == errorType && !results[n-1].IsNil() {
+		return results[n-1].Interface().(error)
+	}
+	return nil
+}
+
+// InjectFields populates every `inject` tagged field of the struct pointed to by target.
+// `inject:""` resolves by type and `inject:"name"` resolves a named provider.
+func (c *Container) InjectFields(target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("target must be a pointer to a struct")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.injectFields(ptr.Elem())
+}
+
+func (c *Container) injectFields(structValue reflect.Value) error {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, ok := field.Tag.Lookup("inject")
+		if !ok {
+			continue
+		}
+		if !field.IsExported() {
+			return fmt.Errorf("cannot inject unexported field %s.%s", structType.Name(), field.Name)
+		}
+
+		value, err := c.resolve(field.Type, name)
+		if err != nil {
+			return fmt.Errorf("inject %s.%s: %w", structType.Name(), field.Name, err)
+		}
+		structValue.Field(i).Set(value)
+	}
+	return nil
+}
+
+func (c *Container) resolveArgs(fnType reflect.Type) ([]reflect.Value, error) {
+	args := make([]reflect.Value, fnType.NumIn())
+	for i := range args {
+		arg, err := c.resolve(fnType.In(i), "")
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// resolve must be called with c.mu held
+func (c *Container) resolve(typ reflect.Type, name string) (reflect.Value, error) {
+	key, p, err := c.findProvider(typ, name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if p.scope == Singleton && p.instance.IsValid() {
+		return c.adapt(p.instance, typ), nil
+	}
+
+	for _, inProgress := range c.resolving {
+		if inProgress == key {
+			chain := make([]string, 0, len(c.resolving)+1)
+			for _, k := range c.resolving {
+				chain = append(chain, k.String())
+			}
+			chain = append(chain, key.String())
+			return reflect.Value{}, fmt.Errorf("dependency cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	c.resolving = append(c.resolving, key)
+	defer func() { c.resolving = c.resolving[:len(c.resolving)-1] }()
+
+	args, err := c.resolveArgs(p.constructor.Type())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	results := p.constructor.Call(args)
+	if len(results) == 2 && !results[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("construct %s: %w", key, results[1].Interface().(error))
+	}
+
+	instance := results[0]
+	if instance.Kind() == reflect.Ptr && !instance.IsNil() && instance.Elem().Kind() == reflect.Struct {
+		if err := c.injectFields(instance.Elem()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	if p.scope == Singleton {
+		p.instance = instance
+	}
+	return c.adapt(instance, typ), nil
+}
+
+// findProvider looks up an exact match first, then any single provider
+// whose type implements the requested interface
+func (c *Container) findProvider(typ reflect.Type, name string) (providerKey, *provider, error) {
+	key := providerKey{typ: typ, name: name}
+	if p, ok := c.providers[key]; ok {
+		return key, p, nil
+	}
+
+	if typ.Kind() == reflect.Interface {
+		var matches []providerKey
+		for k := range c.providers {
+			if k.name == name && k.typ.Implements(typ) {
+				matches = append(matches, k)
+			}
+		}
+		switch len(matches) {
+		case 1:
+			return matches[0], c.providers[matches[0]], nil
+		case 0:
+		default:
+			return key, nil, fmt.Errorf("ambiguous providers for %s: %v", key, matches)
+		}
+	}
+
+	return key, nil, fmt.Errorf("no provider registered for %s", key)
+}
+
+// adapt wraps a concrete value in the requested interface type if needed
+func (c *Container) adapt(value reflect.Value, typ reflect.Type) reflect.Value {
+	if value.Type() == typ {
+		return value
+	}
+	wrapped := reflect.New(typ).Elem()
+	wrapped.Set(value)
+	return wrapped
+}
+
+// Logger and Repository are small services used to demonstrate the container
+type Logger struct {
+	Prefix string
+}
+
+type Repository struct {
+	Logger *Logger `inject:""`
+	Table  string
+}
+
+type Service struct {
+	Repo  *Repository `inject:""`
+	Audit *Logger     `inject:"audit"`
+}
+
+func demonstrateDependencyInjection() {
+	container := NewContainer()
+	container.Register(func() *Logger { return &Logger{Prefix: "[app]"} }, Singleton)
+	container.RegisterNamed("audit", func() *Logger { return &Logger{Prefix: "[audit]"} }, Singleton)
+	container.Register(func() *Repository { return &Repository{Table: "users"} }, Transient)
+	container.Register(func(repo *Repository) *Service { return &Service{} }, Singleton)
+
+	var svc *Service
+	if err := container.Resolve(&svc); err != nil {
+		fmt.Printf("Error resolving: %v\n", err)
+		return
+	}
+	fmt.Printf("Service repo table: %s, logger: %s, audit logger: %s\n",
+		svc.Repo.Table, svc.Repo.Logger.Prefix, svc.Audit.Prefix)
+
+	var repo1, repo2 *Repository
+	container.Resolve(&repo1)
+	container.Resolve(&repo2)
+	fmt.Printf("Transient repositories are distinct: %t, share singleton logger: %t\n",
+		repo1 != repo2, repo1.Logger == repo2.Logger)
+
+	// A -> B -> A is reported instead of recursing forever
+	type A struct{}
+	type B struct{}
+	cyclic := NewContainer()
+	cyclic.Register(func(*B) *A { return &A{} }, Singleton)
+	cyclic.Register(func(*A) *B { return &B{} }, Singleton)
+	var a *A
+	fmt.Printf("Cycle detection: %v\n", cyclic.Resolve(&a))
+}
@@ -0,0 +1,289 @@
+package reflect
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Cloner lets a type take over its own deep copy. Clone must return a value
+// of the same type as the receiver; pointers to a type with a value-receiver
+// Clone are copied as pointers to the element's clone.
+type Cloner interface {
+	Clone() interface{}
+}
+
+// CloneOptions controls how DeepClone copies values
+type CloneOptions struct {
+	// CopyUnexported copies unexported struct fields using unsafe. When false
+	// unexported fields are left at their zero value in the clone.
+	CopyUnexported bool
+}
+
+var (
+	clonerType   = reflect.TypeOf((*Cloner)(nil)).Elem()
+	timeType     = reflect.TypeOf(time.Time{})
+	locationType = reflect.TypeOf((*time.Location)(nil))
+)
+
+// visitKey identifies a reference value that has already been cloned
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// cloneState tracks visited references so shared and cyclic data is cloned once
+type cloneState struct {
+	opts    CloneOptions
+	visited map[visitKey]reflect.Value
+}
+
+// DeepClone returns a deep copy of original. Cyclic and shared references are
+// preserved, types implementing Cloner are copied with their own Clone method,
+// and time.Time / *time.Location values are copied as-is.
+func DeepClone(original interface{}, opts CloneOptions) (interface{}, error) {
+	if original == nil {
+		return nil, nil
+	}
+
+	state := &cloneState{opts: opts, visited: make(map[visitKey]reflect.Value)}
+	cloned, err := state.clone(reflect.ValueOf(original))
+	if err != nil {
+		return nil, err
+	}
+	return cloned.Interface(), nil
+}
+
+func deepClone(original interface{}) (interface{}, error) {
+	return DeepClone(original, CloneOptions{})
+}
+
+func (s *cloneState) clone(src reflect.Value) (reflect.Value, error) {
+	srcType := src.Type()
+
+	if srcType == timeType || srcType == locationType {
+		return src, nil
+	}
+
+	// A pointer whose element has a value-receiver Clone gets its Clone
+	// through the method set of *T, but it returns a T: copy the pointer
+	// here and let the element clone itself
+	promoted := srcType.Kind() == reflect.Ptr && srcType.Elem().Implements(clonerType)
+	if !promoted && srcType.Implements(clonerType) && src.CanInterface() && !isNilValue(src) {
+		cloned := src.Interface().(Cloner).Clone()
+		clonedValue := reflect.ValueOf(cloned)
+		if !clonedValue.IsValid() || clonedValue.Type() != srcType {
+			return reflect.Value{}, fmt.Errorf("cloner for %v returned %T", srcType, cloned)
+		}
+		return clonedValue, nil
+	}
+
+	switch src.Kind() {
+	case reflect.Ptr:
+		return s.clonePtr(src)
+	case reflect.Interface:
+		if src.IsNil() {
+			return reflect.Zero(srcType), nil
+		}
+		elem, err := s.clone(src.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		dst := reflect.New(srcType).Elem()
+		dst.Set(elem)
+		return dst, nil
+	case reflect.Struct:
+		return s.cloneStruct(src)
+	case reflect.Slice:
+		return s.cloneSlice(src)
+	case reflect.Array:
+		dst := reflect.New(srcType).Elem()
+		for i := 0; i < src.Len(); i++ {
+			elem, err := s.clone(src.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			dst.Index(i).Set(elem)
+		}
+		return dst, nil
+	case reflect.Map:
+		return s.cloneMap(src)
+	default:
+		// Scalars are copied by value; chans, funcs and unsafe pointers are shared
+		dst := reflect.New(srcType).Elem()
+		dst.Set(src)
+		return dst, nil
+	}
+}
+
+func (s *cloneState) clonePtr(src reflect.Value) (reflect.Value, error) {
+	if src.IsNil() {
+		return reflect.Zero(src.Type()), nil
+	}
+
+	key := visitKey{ptr: src.Pointer(), typ: src.Type()}
+	if dst, ok := s.visited[key]; ok {
+		return dst, nil
+	}
+
+	dst := reflect.New(src.Type().Elem())
+	s.visited[key] = dst
+
+	elem, err := s.clone(src.Elem())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	dst.Elem().Set(elem)
+	return dst, nil
+}
+
+func (s *cloneState) cloneStruct(src reflect.Value) (reflect.Value, error) {
+	srcType := src.Type()
+	dst := reflect.New(srcType).Elem()
+
+	// Unexported fields can only be reached through an addressable copy
+	if !src.CanAddr() {
+		addressable := reflect.New(srcType).Elem()
+		addressable.Set(src)
+		src = addressable
+	}
+
+	for i := 0; i < srcType.NumField(); i++ {
+		srcField := src.Field(i)
+		dstField := dst.Field(i)
+
+		if !srcType.Field(i).IsExported() {
+			if !s.opts.CopyUnexported {
+				continue
+			}
+			srcField = reflect.NewAt(srcField.Type(), unsafe.Pointer(srcField.UnsafeAddr())).Elem()
+			dstField = reflect.NewAt(dstField.Type(), unsafe.Pointer(dstField.UnsafeAddr())).Elem()
+		}
+
+		cloned, err := s.clone(srcField)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s: %w", srcType.Field(i).Name, err)
+		}
+		dstField.Set(cloned)
+	}
+
+	return dst, nil
+}
+
+func (s *cloneState) cloneSlice(src reflect.Value) (reflect.Value, error) {
+	if src.IsNil() {
+		return reflect.Zero(src.Type()), nil
+	}
+
+	key := visitKey{ptr: src.Pointer(), typ: src.Type(), len: src.Len()}
+	if dst, ok := s.visited[key]; ok {
+		return dst, nil
+	}
+
+	dst := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+	s.visited[key] = dst
+
+	for i := 0; i < src.Len(); i++ {
+		elem, err := s.clone(src.Index(i))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		dst.Index(i).Set(elem)
+	}
+	return dst, nil
+}
+
+func (s *cloneState) cloneMap(src reflect.Value) (reflect.Value, error) {
+	if src.IsNil() {
+		return reflect.Zero(src.Type()), nil
+	}
+
+	key := visitKey{ptr: src.Pointer(), typ: src.Type()}
+	if dst, ok := s.visited[key]; ok {
+		return dst, nil
+	}
+
+	dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+	s.visited[key] = dst
+
+	iter := src.MapRange()
+	for iter.Next() {
+		k, err := s.clone(iter.Key())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v, err := s.clone(iter.Value())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		dst.SetMapIndex(k, v)
+	}
+	return dst, nil
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// TreeNode is a self-referencing type used to demonstrate cyclic cloning
+type TreeNode struct {
+	Name     string
+	Parent   *TreeNode
+	Children []*TreeNode
+	created  time.Time
+}
+
+// Secret demonstrates a type that controls its own cloning
+type Secret struct {
+	Value string
+}
+
+// Clone redacts the secret instead of copying it
+func (s Secret) Clone() interface{} {
+	return Secret{Value: "[redacted]"}
+}
+
+func demonstrateAdvancedCloning() {
+	fmt.Println("\nCyclic structure cloning:")
+	root := &TreeNode{Name: "root", created: time.Now()}
+	child := &TreeNode{Name: "child", Parent: root}
+	root.Children = []*TreeNode{child}
+
+	cloned, err := DeepClone(root, CloneOptions{CopyUnexported: true})
+	if err != nil {
+		fmt.Printf("Error cloning: %v\n", err)
+		return
+	}
+	clonedRoot := cloned.(*TreeNode)
+	clonedChild := clonedRoot.Children[0]
+	fmt.Printf("Cloned root is new pointer: %t\n", clonedRoot != root)
+	fmt.Printf("Cycle preserved (child.Parent == root): %t\n", clonedChild.Parent == clonedRoot)
+	fmt.Printf("Unexported time copied: %t\n", clonedRoot.created.Equal(root.created))
+
+	withoutUnexported, _ := DeepClone(root, CloneOptions{})
+	fmt.Printf("Unexported time zeroed without CopyUnexported: %t\n", withoutUnexported.(*TreeNode).created.IsZero())
+
+	fmt.Println("\nCustom Cloner:")
+	vault := map[string]Secret{"db": {Value: "hunter2"}}
+	clonedVault, err := DeepClone(vault, CloneOptions{})
+	if err != nil {
+		fmt.Printf("Error cloning: %v\n", err)
+		return
+	}
+	fmt.Printf("Original: %v, cloned: %v\n", vault, clonedVault)
+
+	pointers := []*Secret{{Value: "hunter2"}}
+	clonedPointers, err := DeepClone(pointers, CloneOptions{})
+	if err != nil {
+		fmt.Printf("Error cloning: %v\n", err)
+		return
+	}
+	fmt.Printf("Through a pointer: %v\n", *clonedPointers.([]*Secret)[0])
+}
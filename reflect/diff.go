@@ -0,0 +1,376 @@
+package reflect
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/data_structure"
+	"github.com/jerrychou/go-practice/format"
+)
+
+// ChangeType describes what happened to a value between two versions
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a single difference at a field path such as "Database.Port",
+// "Tags[2]" or "Metadata[version]"
+type Change struct {
+	Path string
+	Type ChangeType
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s (was %v)", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Patch is an ordered list of changes that turns one value into another
+type Patch []Change
+
+// Render lists the changes one per line with aligned paths, colored green for
+// additions, red for removals and yellow for modifications when color is set
+func (p Patch) Render(color bool) string {
+	width := 0
+	for _, change := range p {
+		width = max(width, len(change.Path))
+	}
+
+	var sb strings.Builder
+	for _, change := range p {
+		var line, codes string
+		switch change.Type {
+		case ChangeAdded:
+			line, codes = fmt.Sprintf("+ %-*s  %v", width, change.Path, change.New), format.Green
+		case ChangeRemoved:
+			line, codes = fmt.Sprintf("- %-*s  %v", width, change.Path, change.Old), format.Red
+		default:
+			line, codes = fmt.Sprintf("~ %-*s  %v → %v", width, change.Path, change.Old, change.New), format.Yellow
+		}
+		if color {
+			line = format.Colorize(line, codes)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// DiffReport describes how got differs from want, for test failure messages
+// and change reports; it returns "" when the values are equal
+func DiffReport(want, got interface{}) string {
+	patch, err := Diff(want, got)
+	if err != nil {
+		return fmt.Sprintf("want %v, got %v (%v)", want, got, err)
+	}
+	if len(patch) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d %s (- want, + got):\n%s", len(patch), format.Plural(len(patch), "difference", "differences"), patch.Render(false))
+}
+
+// Diff compares a and b, which must have the same type, and returns the changed
+// paths. Nested structs, pointers, slices and maps are compared element by element.
+func Diff(a, b interface{}) (Patch, error) {
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return nil, fmt.Errorf("cannot diff nil values")
+	}
+	if va.Type() != vb.Type() {
+		return nil, fmt.Errorf("cannot diff %v against %v", va.Type(), vb.Type())
+	}
+
+	var patch Patch
+	diffValues("", va, vb, &patch)
+	return patch, nil
+}
+
+func diffValues(path string, a, b reflect.Value, patch *Patch) {
+	if a.Type() == timeType {
+		if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+			*patch = append(*patch, Change{Path: path, Type: ChangeModified, Old: a.Interface(), New: b.Interface()})
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			diffValues(joinPath(path, t.Field(i).Name), a.Field(i), b.Field(i), patch)
+		}
+	case reflect.Ptr, reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil():
+			*patch = append(*patch, Change{Path: path, Type: ChangeAdded, New: b.Elem().Interface()})
+		case b.IsNil():
+			*patch = append(*patch, Change{Path: path, Type: ChangeRemoved, Old: a.Elem().Interface()})
+		case a.Elem().Type() != b.Elem().Type():
+			*patch = append(*patch, Change{Path: path, Type: ChangeModified, Old: a.Elem().Interface(), New: b.Elem().Interface()})
+		default:
+			diffValues(path, a.Elem(), b.Elem(), patch)
+		}
+	case reflect.Slice, reflect.Array:
+		common := a.Len()
+		if b.Len() < common {
+			common = b.Len()
+		}
+		for i := 0; i < common; i++ {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), patch)
+		}
+		for i := common; i < b.Len(); i++ {
+			*patch = append(*patch, Change{Path: fmt.Sprintf("%s[%d]", path, i), Type: ChangeAdded, New: b.Index(i).Interface()})
+		}
+		for i := common; i < a.Len(); i++ {
+			*patch = append(*patch, Change{Path: fmt.Sprintf("%s[%d]", path, i), Type: ChangeRemoved, Old: a.Index(i).Interface()})
+		}
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+
+		for _, name := range data_structure.SortedKeys(keys) {
+			k := keys[name]
+			elemPath := fmt.Sprintf("%s[%s]", path, name)
+			av := a.MapIndex(k)
+			bv := b.MapIndex(k)
+			switch {
+			case !av.IsValid():
+				*patch = append(*patch, Change{Path: elemPath, Type: ChangeAdded, New: bv.Interface()})
+			case !bv.IsValid():
+				*patch = append(*patch, Change{Path: elemPath, Type: ChangeRemoved, Old: av.Interface()})
+			default:
+				diffValues(elemPath, av, bv, patch)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*patch = append(*patch, Change{Path: path, Type: ChangeModified, Old: a.Interface(), New: b.Interface()})
+		}
+	}
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// pathSegment is one step of a change path: a field name or an index/map key
+type pathSegment struct {
+	name  string
+	index bool
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in path %q", path)
+			}
+			segments = append(segments, pathSegment{name: path[1:end], index: true})
+			path = path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segments = append(segments, pathSegment{name: path[:end]})
+			path = path[end:]
+		}
+	}
+	return segments, nil
+}
+
+// ApplyPatch applies the changes in patch to target, which must be a pointer
+func ApplyPatch(target interface{}, patch Patch) error {
+	root := reflect.ValueOf(target)
+	if root.Kind() != reflect.Ptr || root.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer")
+	}
+
+	for _, change := range patch {
+		segments, err := parsePath(change.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyChange(root.Elem(), segments, change); err != nil {
+			return fmt.Errorf("apply %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// applyChange walks segments starting at v, which must be settable
+func applyChange(v reflect.Value, segments []pathSegment, change Change) error {
+	if len(segments) == 0 {
+		if change.Type == ChangeRemoved {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return assignValue(v, change.New)
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return applyChange(v.Elem(), segments, change)
+	}
+
+	seg := segments[0]
+	rest := segments[1:]
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if seg.index {
+			return fmt.Errorf("cannot index struct %v", v.Type())
+		}
+		field := v.FieldByName(seg.name)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("field %s not settable on %v", seg.name, v.Type())
+		}
+		return applyChange(field, rest, change)
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(seg.name)
+		if err != nil {
+			return fmt.Errorf("invalid index %q", seg.name)
+		}
+		if len(rest) == 0 && v.Kind() == reflect.Slice {
+			switch change.Type {
+			case ChangeRemoved:
+				if i < v.Len() {
+					v.Set(v.Slice(0, i))
+				}
+				return nil
+			case ChangeAdded:
+				if i == v.Len() {
+					elem := reflect.New(v.Type().Elem()).Elem()
+					if err := assignValue(elem, change.New); err != nil {
+						return err
+					}
+					v.Set(reflect.Append(v, elem))
+					return nil
+				}
+			}
+		}
+		if i < 0 || i >= v.Len() {
+			return fmt.Errorf("index %d out of range", i)
+		}
+		return applyChange(v.Index(i), rest, change)
+	case reflect.Map:
+		key, err := (&mapper{}).convert(reflect.ValueOf(seg.name), v.Type().Key())
+		if err != nil {
+			return fmt.Errorf("invalid map key %q: %w", seg.name, err)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		if len(rest) == 0 && change.Type == ChangeRemoved {
+			v.SetMapIndex(key, reflect.Value{})
+			return nil
+		}
+
+		// Map elements are not addressable, so edit a copy and store it back
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := applyChange(elem, rest, change); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	default:
+		return fmt.Errorf("cannot descend into %v", v.Type())
+	}
+}
+
+func assignValue(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr && src.Type().AssignableTo(dst.Type().Elem()) {
+		ptr := reflect.New(dst.Type().Elem())
+		ptr.Elem().Set(src)
+		dst.Set(ptr)
+		return nil
+	}
+	if src.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %v to %v", src.Type(), dst.Type())
+}
+
+func demonstrateDiffAndPatch() {
+	before := Product{
+		ID:       1,
+		Name:     "Go Programming Book",
+		Price:    29.99,
+		Tags:     []string{"programming", "golang", "book"},
+		Metadata: map[string]string{"author": "John Doe", "pages": "300"},
+		Active:   true,
+	}
+	after := Product{
+		ID:       1,
+		Name:     "Go Programming Book (2nd Edition)",
+		Price:    34.99,
+		Tags:     []string{"programming", "golang"},
+		Metadata: map[string]string{"author": "John Doe", "edition": "2"},
+		Active:   true,
+	}
+
+	patch, err := Diff(before, after)
+	if err != nil {
+		fmt.Printf("Error diffing: %v\n", err)
+		return
+	}
+
+	fmt.Println("Changes:")
+	fmt.Print(patch.Render(true))
+	fmt.Print(DiffReport(after, before))
+
+	cloned, _ := deepClone(before)
+	target := cloned.(Product)
+	if err := ApplyPatch(&target, patch); err != nil {
+		fmt.Printf("Error applying patch: %v\n", err)
+		return
+	}
+	fmt.Printf("Patched equals updated: %t\n", reflect.DeepEqual(target, after))
+}
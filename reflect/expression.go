@@ -0,0 +1,645 @@
+package reflect
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a compiled expression such as `user.Age >= 18 && user.Active`.
+// Identifiers are resolved against an environment of named values; struct fields,
+// map keys and slice indexes are followed with reflection.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// CompileExpression parses src into an Expression
+func CompileExpression(src string) (*Expression, error) {
+	tokens, err := tokenizeExpression(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Expression{source: src, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression. Function calls are dispatched to funcs, which may be nil.
+func (e *Expression) Evaluate(env map[string]interface{}, funcs *FunctionRegistry) (interface{}, error) {
+	return e.root.eval(&exprEnv{vars: env, funcs: funcs})
+}
+
+// EvaluateBool runs the expression and requires a boolean result
+func (e *Expression) EvaluateBool(env map[string]interface{}, funcs *FunctionRegistry) (bool, error) {
+	result, err := e.Evaluate(env, funcs)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %T, not bool", e.source, result)
+	}
+	return b, nil
+}
+
+// EvaluateExpression compiles and evaluates src in one step
+func EvaluateExpression(src string, env map[string]interface{}) (interface{}, error) {
+	expr, err := CompileExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Evaluate(env, nil)
+}
+
+// Tokenizer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOperator
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var exprOperators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ".", ","}
+
+func tokenizeExpression(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: string(runes[start:i]), pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokString, text: sb.String(), pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, exprToken{kind: tokOperator, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// Parser
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) expect(op string) error {
+	tok := p.next()
+	if tok.kind != tokOperator || tok.text != op {
+		return fmt.Errorf("expected %q at position %d, got %q", op, tok.pos, tok.text)
+	}
+	return nil
+}
+
+// parseBinary implements precedence climbing for binary operators
+func (p *exprParser) parseBinary(minPrec int) (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		prec, ok := binaryPrecedence[tok.text]
+		if tok.kind != tokOperator || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	tok := p.peek()
+	if tok.kind == tokOperator && (tok.text == "!" || tok.text == "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: tok.text, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokOperator {
+			return node, nil
+		}
+		switch tok.text {
+		case ".":
+			p.next()
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, fmt.Errorf("expected field name at position %d", name.pos)
+			}
+			node = &fieldNode{target: node, name: name.text}
+		case "[":
+			p.next()
+			index, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{target: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &literalNode{value: f}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil":
+			return &literalNode{value: nil}, nil
+		}
+
+		if next := p.peek(); next.kind == tokOperator && next.text == "(" {
+			p.next()
+			var args []exprNode
+			for !(p.peek().kind == tokOperator && p.peek().text == ")") {
+				arg, err := p.parseBinary(0)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.peek().text == "," {
+					p.next()
+				} else {
+					break
+				}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &callNode{name: tok.text, args: args}, nil
+		}
+		return &identNode{name: tok.text}, nil
+	case tokOperator:
+		if tok.text == "(" {
+			inner, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// Evaluation
+
+type exprEnv struct {
+	vars  map[string]interface{}
+	funcs *FunctionRegistry
+}
+
+type exprNode interface {
+	eval(env *exprEnv) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(*exprEnv) (interface{}, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(env *exprEnv) (interface{}, error) {
+	v, ok := env.vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undefined identifier %q", n.name)
+	}
+	return v, nil
+}
+
+type fieldNode struct {
+	target exprNode
+	name   string
+}
+
+func (n *fieldNode) eval(env *exprEnv) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot access %s on nil", n.name)
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field := v.FieldByName(n.name)
+		if !field.IsValid() {
+			field = v.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, n.name) })
+		}
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, fmt.Errorf("%v has no field %s", v.Type(), n.name)
+		}
+		return field.Interface(), nil
+	case reflect.Map:
+		return mapLookup(v, n.name)
+	}
+	return nil, fmt.Errorf("cannot access %s on %v", n.name, v.Kind())
+}
+
+type indexNode struct {
+	target exprNode
+	index  exprNode
+}
+
+func (n *indexNode) eval(env *exprEnv) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(target))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		f, ok := toFloat(index)
+		if !ok {
+			return nil, fmt.Errorf("index must be a number, got %T", index)
+		}
+		i := int(f)
+		if i < 0 || i >= v.Len() {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return v.Index(i).Interface(), nil
+	case reflect.Map:
+		return mapLookup(v, fmt.Sprint(index))
+	}
+	return nil, fmt.Errorf("cannot index %T", target)
+}
+
+func mapLookup(m reflect.Value, key string) (interface{}, error) {
+	keyValue, err := (&mapper{}).convert(reflect.ValueOf(key), m.Type().Key())
+	if err != nil {
+		return nil, err
+	}
+	v := m.MapIndex(keyValue)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	return v.Interface(), nil
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+func (n *callNode) eval(env *exprEnv) (interface{}, error) {
+	if env.funcs == nil {
+		return nil, fmt.Errorf("no functions available to call %s", n.name)
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	results, err := env.funcs.SafeCall(n.name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return results[0], nil
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(env *exprEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! needs a bool, got %T", v)
+		}
+		return !b, nil
+	default:
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("operator - needs a number, got %T", v)
+		}
+		return -f, nil
+	}
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(env *exprEnv) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit boolean operators
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %T", n.op, left)
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %T", n.op, right)
+		}
+		return rb, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	}
+
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch n.op {
+		case "<":
+			return lf < rf, nil
+		case "<=":
+			return lf <= rf, nil
+		case ">":
+			return lf > rf, nil
+		case ">=":
+			return lf >= rf, nil
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return lf / rf, nil
+		case "%":
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return math.Mod(lf, rf), nil
+		}
+	}
+
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		switch n.op {
+		case "+":
+			return ls + rs, nil
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s not supported for %T and %T", n.op, left, right)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// Account is used to demonstrate expression evaluation
+type Account struct {
+	Name    string
+	Age     int
+	Active  bool
+	Roles   []string
+	Limits  map[string]float64
+	Manager *Account
+}
+
+func demonstrateExpressionEvaluation() {
+	manager := &Account{Name: "Grace", Age: 45, Active: true, Roles: []string{"admin"}}
+	user := &Account{
+		Name:    "Alan",
+		Age:     17,
+		Active:  true,
+		Roles:   []string{"viewer", "editor"},
+		Limits:  map[string]float64{"daily": 250, "monthly": 5000},
+		Manager: manager,
+	}
+	env := map[string]interface{}{"user": user, "threshold": 200}
+
+	funcs := NewFunctionRegistry()
+	funcs.Register("len", func(v interface{}) int { return reflect.ValueOf(v).Len() })
+	funcs.Register("hasRole", func(a *Account, role string) bool {
+		for _, r := range a.Roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	})
+
+	expressions := []string{
+		`user.Age >= 18 && user.Active`,
+		`user.Age + 1 >= 18`,
+		`user.Limits["daily"] > threshold`,
+		`user.Limits.monthly / 30 < threshold`,
+		`user.Roles[1] == "editor" || hasRole(user, "admin")`,
+		`hasRole(user.Manager, "admin") && len(user.Roles) == 2`,
+		`"Hello, " + user.Manager.Name`,
+		`!(user.Age < 13)`,
+		`user.Missing > 1`,
+	}
+
+	for _, src := range expressions {
+		expr, err := CompileExpression(src)
+		if err != nil {
+			fmt.Printf("  %-55s compile error: %v\n", src, err)
+			continue
+		}
+		result, err := expr.Evaluate(env, funcs)
+		if err != nil {
+			fmt.Printf("  %-55s error: %v\n", src, err)
+			continue
+		}
+		fmt.Printf("  %-55s => %v\n", src, result)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/jerrychou/go-practice/data_structure"
 )
 
 // FunctionReflection demonstrates function reflect capabilities
@@ -328,7 +330,8 @@ func (fr *FunctionRegistry) Register(name string, fn interface{}) {
 	fr.functions[name] = reflect.ValueOf(fn)
 }
 
-// Call calls a registered function by name
+// Call calls a registered function by name. It panics if the arguments do not
+// match the function signature; use SafeCall for validated calls.
 func (fr *FunctionRegistry) Call(name string, args ...interface{}) ([]interface{}, error) {
 	fn, exists := fr.functions[name]
 	if !exists {
@@ -355,11 +358,7 @@ func (fr *FunctionRegistry) Call(name string, args ...interface{}) ([]interface{
 
 // ListFunctions returns all registered function names
 func (fr *FunctionRegistry) ListFunctions() []string {
-	names := make([]string, 0, len(fr.functions))
-	for name := range fr.functions {
-		names = append(names, name)
-	}
-	return names
+	return data_structure.Keys(fr.functions)
 }
 
 // GetFunctionInfo returns information about a registered function
@@ -441,4 +440,6 @@ func DemonstrateFunctionRegistry() {
 	} else {
 		fmt.Printf("variadicSum(1,2,3,4,5) = %v\n", results[0])
 	}
+
+	DemonstrateSafeCall()
 }
@@ -0,0 +1,250 @@
+package reflect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// CallErrorKind classifies why a safe call failed
+type CallErrorKind string
+
+const (
+	CallNotFound     CallErrorKind = "not_found"
+	CallArity        CallErrorKind = "arity"
+	CallArgumentType CallErrorKind = "argument_type"
+	CallPanic        CallErrorKind = "panic"
+	CallReturned     CallErrorKind = "returned_error"
+)
+
+// CallError is returned by SafeCall instead of panicking
+type CallError struct {
+	Function string
+	Kind     CallErrorKind
+	Arg      int // index of the offending argument, -1 if not applicable
+	Err      error
+}
+
+func (e *CallError) Error() string {
+	if e.Arg >= 0 {
+		return fmt.Sprintf("call %s: %s (argument %d): %v", e.Function, e.Kind, e.Arg, e.Err)
+	}
+	return fmt.Sprintf("call %s: %s: %v", e.Function, e.Kind, e.Err)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// SafeCall calls a registered function like Call, but validates and coerces
+// the arguments and reports every failure as a *CallError. Numbers convert
+// between types only when they fit, so 300 is not a valid int8.
+func (fr *FunctionRegistry) SafeCall(name string, args ...interface{}) ([]interface{}, error) {
+	return fr.SafeCallContext(context.Background(), name, args...)
+}
+
+// SafeCallContext is like SafeCall and passes ctx as the first argument when the
+// function's first parameter is a context.Context that was not supplied explicitly.
+// A trailing error result is removed from the results and returned as the error.
+func (fr *FunctionRegistry) SafeCallContext(ctx context.Context, name string, args ...interface{}) (results []interface{}, err error) {
+	fn, exists := fr.functions[name]
+	if !exists {
+		return nil, &CallError{Function: name, Kind: CallNotFound, Arg: -1, Err: errors.New("function not registered")}
+	}
+
+	fnType := fn.Type()
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		if len(args) == 0 || !isContextArg(args[0]) {
+			args = append([]interface{}{ctx}, args...)
+		}
+	}
+
+	callArgs, useSlice, err := coerceArgs(name, fnType, args)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			results = nil
+			err = &CallError{Function: name, Kind: CallPanic, Arg: -1, Err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	var out []reflect.Value
+	if useSlice {
+		out = fn.CallSlice(callArgs)
+	} else {
+		out = fn.Call(callArgs)
+	}
+
+	if n := len(out); n > 0 && fnType.Out(n-1) == errorType {
+		last := out[n-1]
+		out = out[:n-1]
+		if !last.IsNil() {
+			err = &CallError{Function: name, Kind: CallReturned, Arg: -1, Err: last.Interface().(error)}
+		}
+	}
+
+	results = make([]interface{}, len(out))
+	for i, v := range out {
+		results[i] = v.Interface()
+	}
+	return results, err
+}
+
+func isContextArg(arg interface{}) bool {
+	_, ok := arg.(context.Context)
+	return ok
+}
+
+// coerceArgs converts args to the parameter types of fnType. useSlice reports
+// whether the final argument is an already-built variadic slice.
+func coerceArgs(name string, fnType reflect.Type, args []interface{}) ([]reflect.Value, bool, error) {
+	numIn := fnType.NumIn()
+	fixed := numIn
+	if fnType.IsVariadic() {
+		fixed--
+	}
+
+	if len(args) < fixed || (!fnType.IsVariadic() && len(args) != numIn) {
+		want := fmt.Sprintf("%d", numIn)
+		if fnType.IsVariadic() {
+			want = fmt.Sprintf("at least %d", fixed)
+		}
+		return nil, false, &CallError{Function: name, Kind: CallArity, Arg: -1,
+			Err: fmt.Errorf("expected %s arguments, got %d", want, len(args))}
+	}
+
+	m := &mapper{opts: MapOptions{TimeFormat: time.RFC3339}}
+	convert := func(i int, arg interface{}, target reflect.Type) (reflect.Value, error) {
+		if arg == nil {
+			switch target.Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+				return reflect.Zero(target), nil
+			}
+			return reflect.Value{}, &CallError{Function: name, Kind: CallArgumentType, Arg: i,
+				Err: fmt.Errorf("nil is not a valid %v", target)}
+		}
+		v, err := m.convert(reflect.ValueOf(arg), target)
+		if err != nil {
+			return reflect.Value{}, &CallError{Function: name, Kind: CallArgumentType, Arg: i, Err: err}
+		}
+		return v, nil
+	}
+
+	values := make([]reflect.Value, 0, len(args))
+	for i := 0; i < fixed; i++ {
+		v, err := convert(i, args[i], fnType.In(i))
+		if err != nil {
+			return nil, false, err
+		}
+		values = append(values, v)
+	}
+
+	if !fnType.IsVariadic() {
+		return values, false, nil
+	}
+
+	sliceType := fnType.In(numIn - 1)
+
+	// A single slice argument in the variadic position is expanded as-is
+	if len(args) == numIn && args[fixed] != nil {
+		argType := reflect.TypeOf(args[fixed])
+		if argType.Kind() == reflect.Slice && !argType.AssignableTo(sliceType.Elem()) {
+			v, err := convert(fixed, args[fixed], sliceType)
+			if err != nil {
+				return nil, false, err
+			}
+			return append(values, v), true, nil
+		}
+	}
+
+	for i := fixed; i < len(args); i++ {
+		v, err := convert(i, args[i], sliceType.Elem())
+		if err != nil {
+			return nil, false, err
+		}
+		values = append(values, v)
+	}
+	return values, false, nil
+}
+
+// Divide returns an error for a zero divisor to demonstrate error mapping
+func Divide(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+// Point is used to demonstrate map to struct coercion
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Distance2 returns the squared distance of a point from the origin
+func Distance2(p Point) int {
+	return p.X*p.X + p.Y*p.Y
+}
+
+// Lookup demonstrates automatic context injection
+func Lookup(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "value-for-" + key, nil
+}
+
+// DemonstrateSafeCall shows how SafeCall validates, coerces and maps errors
+func DemonstrateSafeCall() {
+	fmt.Println("\n🛡️  SafeCall:")
+
+	registry := NewFunctionRegistry()
+	registry.Register("add", Add)
+	registry.Register("divide", Divide)
+	registry.Register("distance2", Distance2)
+	registry.Register("variadicSum", VariadicSum)
+	registry.Register("lookup", Lookup)
+	registry.Register("panics", func(xs []int) int { return xs[10] })
+
+	calls := []struct {
+		name string
+		args []interface{}
+	}{
+		{"add", []interface{}{int64(2), "40"}},
+		{"add", []interface{}{1}},
+		{"add", []interface{}{1, "two"}},
+		{"divide", []interface{}{10, 4}},
+		{"divide", []interface{}{1, 0}},
+		{"distance2", []interface{}{map[string]interface{}{"x": 3, "y": "4"}}},
+		{"variadicSum", []interface{}{1, "2", 3.0}},
+		{"variadicSum", []interface{}{[]int{4, 5, 6}}},
+		{"lookup", []interface{}{"answer"}},
+		{"panics", []interface{}{[]int{1}}},
+		{"missing", nil},
+	}
+
+	for _, c := range calls {
+		results, err := registry.SafeCall(c.name, c.args...)
+		var callErr *CallError
+		switch {
+		case errors.As(err, &callErr):
+			fmt.Printf("  %s%v -> error [%s]: %v\n", c.name, c.args, callErr.Kind, err)
+		case err != nil:
+			fmt.Printf("  %s%v -> error: %v\n", c.name, c.args, err)
+		default:
+			fmt.Printf("  %s%v -> %v\n", c.name, c.args, results)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := registry.SafeCallContext(ctx, "lookup", "answer")
+	fmt.Printf("  lookup with cancelled context -> %v (is context.Canceled: %t)\n", err, errors.Is(err, context.Canceled))
+}
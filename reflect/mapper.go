@@ -0,0 +1,520 @@
+package reflect
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transformer converts a source field value before it is assigned to the destination
+type Transformer func(value interface{}) (interface{}, error)
+
+// MapOptions controls how MapStruct matches and converts fields
+type MapOptions struct {
+	// TagName is the struct tag used to match fields, e.g. "map" or "json".
+	// Fields without the tag are matched by name, case-insensitively.
+	TagName string
+	// FieldMapping maps source field names to destination field names explicitly
+	FieldMapping map[string]string
+	// Ignore lists destination field names that are never written
+	Ignore []string
+	// TimeFormat is used for time.Time <-> string conversions (default time.RFC3339)
+	TimeFormat string
+	// Transformers run on the source value of the named destination field
+	Transformers map[string]Transformer
+}
+
+// ValueHolder is implemented, with a pointer receiver, by types that wrap a
+// single value, such as database.JSON. HeldValue returns a pointer to the
+// wrapped value, and MapStruct maps to and from it, so a holder field can be
+// filled from a plain DTO field and the other way around.
+type ValueHolder interface {
+	HeldValue() any
+}
+
+var valueHolderType = reflect.TypeOf((*ValueHolder)(nil)).Elem()
+
+// MapStruct copies fields from src into dst, which must be a pointer to a struct.
+// Fields are matched by explicit mapping, tag, or name, and values are converted
+// between compatible types (numbers, strings, times, nested structs, slices, maps,
+// and the values inside ValueHolders). Numbers must fit the destination type,
+// and only whole numbers convert to integers. Converted values of named string and
+// integer types that implement driver.Valuer, such as enums, are checked by
+// calling Value, so an enum that is not one of its constants is reported here
+// rather than when the model is saved.
+func MapStruct(src, dst interface{}, opts MapOptions) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() || dstValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dst must be a non-nil pointer to a struct")
+	}
+
+	srcValue := reflect.Indirect(reflect.ValueOf(src))
+	if srcValue.Kind() != reflect.Struct {
+		return fmt.Errorf("src must be a struct or pointer to a struct")
+	}
+
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339
+	}
+	m := &mapper{opts: opts, ignore: make(map[string]bool)}
+	for _, name := range opts.Ignore {
+		m.ignore[name] = true
+	}
+
+	return m.mapStruct(srcValue, dstValue.Elem(), true)
+}
+
+type mapper struct {
+	opts   MapOptions
+	ignore map[string]bool
+}
+
+// fieldKey returns the key a field is matched on
+func (m *mapper) fieldKey(field reflect.StructField) string {
+	if m.opts.TagName != "" {
+		if tag := strings.Split(field.Tag.Get(m.opts.TagName), ",")[0]; tag != "" && tag != "-" {
+			return strings.ToLower(tag)
+		}
+	}
+	return strings.ToLower(field.Name)
+}
+
+func (m *mapper) mapStruct(src, dst reflect.Value, topLevel bool) error {
+	srcType := src.Type()
+	dstType := dst.Type()
+
+	dstFields := make(map[string]int, dstType.NumField())
+	for i := 0; i < dstType.NumField(); i++ {
+		if field := dstType.Field(i); field.IsExported() {
+			dstFields[m.fieldKey(field)] = i
+		}
+	}
+
+	for i := 0; i < srcType.NumField(); i++ {
+		srcField := srcType.Field(i)
+		if !srcField.IsExported() {
+			continue
+		}
+
+		key := m.fieldKey(srcField)
+		if topLevel {
+			if target, ok := m.opts.FieldMapping[srcField.Name]; ok {
+				key = strings.ToLower(target)
+				if field, ok := dstType.FieldByName(target); ok {
+					key = m.fieldKey(field)
+				}
+			}
+		}
+
+		index, ok := dstFields[key]
+		if !ok {
+			continue
+		}
+
+		dstField := dstType.Field(index)
+		if topLevel && m.ignore[dstField.Name] {
+			continue
+		}
+
+		value := src.Field(i)
+		if transform, ok := m.opts.Transformers[dstField.Name]; ok && topLevel {
+			transformed, err := transform(value.Interface())
+			if err != nil {
+				return fmt.Errorf("transform %s: %w", dstField.Name, err)
+			}
+			if transformed == nil {
+				continue
+			}
+			value = reflect.ValueOf(transformed)
+		}
+
+		converted, err := m.convert(value, dstField.Type)
+		if err == nil {
+			err = checkValuer(converted)
+		}
+		if err != nil {
+			return fmt.Errorf("field %s -> %s: %w", srcField.Name, dstField.Name, err)
+		}
+		dst.Field(index).Set(converted)
+	}
+	return nil
+}
+
+// convert returns src converted to the target type
+func (m *mapper) convert(src reflect.Value, target reflect.Type) (reflect.Value, error) {
+	if !src.IsValid() {
+		return reflect.Zero(target), nil
+	}
+	if target.Kind() == reflect.Interface && src.Type().Implements(target) {
+		return src, nil
+	}
+
+	// Unwrap interfaces and pointers on the source side
+	for src.Kind() == reflect.Interface || (src.Kind() == reflect.Ptr && target.Kind() != reflect.Ptr) {
+		if src.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		src = src.Elem()
+	}
+
+	srcType := src.Type()
+	if srcType != target {
+		if held, ok := heldValue(target); ok {
+			elem, err := m.convert(src, held.Type())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			held.Set(elem)
+			return held.owner, nil
+		}
+		if held, ok := heldValue(srcType); ok {
+			held.owner.Set(src)
+			return m.convert(held.Value, target)
+		}
+	}
+
+	switch {
+	case srcType == timeType && target.Kind() == reflect.String:
+		return reflect.ValueOf(src.Interface().(time.Time).Format(m.opts.TimeFormat)).Convert(target), nil
+	case srcType.Kind() == reflect.String && target == timeType:
+		t, err := time.Parse(m.opts.TimeFormat, src.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(t), nil
+	case srcType.AssignableTo(target) && !needsDeepCopy(srcType):
+		return src, nil
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+		if src.Kind() == reflect.Ptr && src.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		if src.Kind() == reflect.Ptr {
+			src = src.Elem()
+		}
+		elem, err := m.convert(src, target.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(target.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Struct:
+		dst := reflect.New(target).Elem()
+		switch src.Kind() {
+		case reflect.Struct:
+			return dst, m.mapStruct(src, dst, false)
+		case reflect.Map:
+			return dst, m.mapMapToStruct(src, dst)
+		}
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			break
+		}
+		if src.Kind() == reflect.Slice && src.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		dst := reflect.MakeSlice(target, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			elem, err := m.convert(src.Index(i), target.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			dst.Index(i).Set(elem)
+		}
+		return dst, nil
+	case reflect.Map:
+		if src.Kind() != reflect.Map {
+			break
+		}
+		if src.IsNil() {
+			return reflect.Zero(target), nil
+		}
+		dst := reflect.MakeMapWithSize(target, src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			k, err := m.convert(iter.Key(), target.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v, err := m.convert(iter.Value(), target.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			dst.SetMapIndex(k, v)
+		}
+		return dst, nil
+	case reflect.String:
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.ValueOf(strconv.FormatInt(src.Int(), 10)).Convert(target), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return reflect.ValueOf(strconv.FormatUint(src.Uint(), 10)).Convert(target), nil
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(strconv.FormatFloat(src.Float(), 'f', -1, 64)).Convert(target), nil
+		case reflect.Bool:
+			return reflect.ValueOf(strconv.FormatBool(src.Bool())).Convert(target), nil
+		case reflect.String:
+			return src.Convert(target), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if src.Kind() == reflect.String {
+			n, err := strconv.ParseInt(strings.TrimSpace(src.String()), 10, target.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(n).Convert(target), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if src.Kind() == reflect.String {
+			n, err := strconv.ParseUint(strings.TrimSpace(src.String()), 10, target.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(n).Convert(target), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if src.Kind() == reflect.String {
+			f, err := strconv.ParseFloat(strings.TrimSpace(src.String()), target.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(f).Convert(target), nil
+		}
+	case reflect.Bool:
+		if src.Kind() == reflect.String {
+			b, err := strconv.ParseBool(strings.TrimSpace(src.String()))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(b).Convert(target), nil
+		}
+	}
+
+	if isNumberKind(src.Kind()) && isNumberKind(target.Kind()) {
+		return convertNumber(src, target)
+	}
+	if srcType.AssignableTo(target) {
+		return src, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert %v to %v", srcType, target)
+}
+
+// mapMapToStruct fills a struct from a map keyed by field name or tag
+func (m *mapper) mapMapToStruct(src, dst reflect.Value) error {
+	if src.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot map %v to struct: keys must be strings", src.Type())
+	}
+
+	dstType := dst.Type()
+	for i := 0; i < dstType.NumField(); i++ {
+		field := dstType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := m.fieldKey(field)
+		iter := src.MapRange()
+		for iter.Next() {
+			if strings.ToLower(iter.Key().String()) != key {
+				continue
+			}
+			converted, err := m.convert(iter.Value(), field.Type)
+			if err == nil {
+				err = checkValuer(converted)
+			}
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			dst.Field(i).Set(converted)
+			break
+		}
+	}
+	return nil
+}
+
+// holder is the value inside a new ValueHolder of some type
+type holder struct {
+	reflect.Value
+	owner reflect.Value // the holder, which the value is part of
+}
+
+// heldValue returns a new value of type t and the settable value it holds,
+// if *t is a ValueHolder
+func heldValue(t reflect.Type) (holder, bool) {
+	if t.Kind() == reflect.Ptr || !reflect.PointerTo(t).Implements(valueHolderType) {
+		return holder{}, false
+	}
+	ptr := reflect.New(t)
+	held := reflect.ValueOf(ptr.Interface().(ValueHolder).HeldValue())
+	if held.Kind() != reflect.Ptr || held.IsNil() {
+		return holder{}, false
+	}
+	return holder{Value: held.Elem(), owner: ptr.Elem()}, true
+}
+
+// checkValuer validates a named scalar value by calling its Value method
+func checkValuer(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil
+	}
+	valuer, ok := v.Interface().(driver.Valuer)
+	if !ok {
+		return nil
+	}
+	_, err := valuer.Value()
+	return err
+}
+
+func needsDeepCopy(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		return t != timeType
+	default:
+		return false
+	}
+}
+
+// convertNumber converts between numeric kinds, refusing values that would
+// wrap around or lose their fractional part in target
+func convertNumber(src reflect.Value, target reflect.Type) (reflect.Value, error) {
+	var fits bool
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fits = !target.OverflowInt(src.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fits = src.Uint() <= math.MaxInt64 && !target.OverflowInt(int64(src.Uint()))
+		default:
+			f := src.Float()
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("%v is not a whole number for %v", f, target)
+			}
+			fits = f >= math.MinInt64 && f < math.MaxInt64 && !target.OverflowInt(int64(f))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fits = src.Int() >= 0 && !target.OverflowUint(uint64(src.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fits = !target.OverflowUint(src.Uint())
+		default:
+			f := src.Float()
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("%v is not a whole number for %v", f, target)
+			}
+			fits = f >= 0 && f < math.MaxUint64 && !target.OverflowUint(uint64(f))
+		}
+	default:
+		// Integers only lose precision as floats; float64 can overflow float32
+		fits = src.Kind() != reflect.Float64 || !target.OverflowFloat(src.Float())
+	}
+	if !fits {
+		return reflect.Value{}, fmt.Errorf("%v overflows %v", src.Interface(), target)
+	}
+	return src.Convert(target), nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// UserModel is a database-style model used to demonstrate MapStruct
+type UserModel struct {
+	ID           int64
+	Email        string
+	PasswordHash string
+	Age          string
+	CreatedAt    time.Time
+	Address      AddressModel
+	Roles        []RoleModel
+}
+
+type AddressModel struct {
+	Street string
+	City   string
+}
+
+type RoleModel struct {
+	Name  string
+	Level int
+}
+
+// UserDTO is the API-facing shape of UserModel
+type UserDTO struct {
+	UserID    string     `map:"id"`
+	Contact   string     `map:"email"`
+	Age       int        `map:"age"`
+	Password  string     `map:"passwordhash"`
+	CreatedAt string     `map:"createdat"`
+	Address   AddressDTO `map:"address"`
+	Roles     []RoleDTO  `map:"roles"`
+}
+
+type AddressDTO struct {
+	City string
+}
+
+type RoleDTO struct {
+	Name  string
+	Level string
+}
+
+func demonstrateStructMapping() {
+	model := UserModel{
+		ID:           42,
+		Email:        "Jane@Example.com",
+		PasswordHash: "$2a$10$abc",
+		Age:          "31",
+		CreatedAt:    time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Address:      AddressModel{Street: "1 Main St", City: "Springfield"},
+		Roles:        []RoleModel{{Name: "admin", Level: 10}, {Name: "user", Level: 1}},
+	}
+
+	var dto UserDTO
+	err := MapStruct(model, &dto, MapOptions{
+		TagName:    "map",
+		Ignore:     []string{"Password"},
+		TimeFormat: "2006-01-02",
+		Transformers: map[string]Transformer{
+			"Contact": func(v interface{}) (interface{}, error) {
+				return strings.ToLower(v.(string)), nil
+			},
+		},
+	})
+	if err != nil {
+		fmt.Printf("Error mapping: %v\n", err)
+		return
+	}
+	fmt.Printf("Model -> DTO: %s\n", Dump(dto))
+
+	// Map the DTO back using an explicit field mapping instead of tags
+	var back UserModel
+	err = MapStruct(dto, &back, MapOptions{
+		FieldMapping: map[string]string{"UserID": "ID", "Contact": "Email"},
+		Ignore:       []string{"CreatedAt"},
+	})
+	if err != nil {
+		fmt.Printf("Error mapping: %v\n", err)
+		return
+	}
+	fmt.Printf("DTO -> Model: %s\n", Dump(back))
+}
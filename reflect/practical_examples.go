@@ -2,11 +2,13 @@ package reflect
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
-	"strconv"
 	"strings"
+
+	"github.com/jerrychou/go-practice/data_structure"
 )
 
 // PracticalExamples demonstrates real-world uses of reflect
@@ -37,6 +39,42 @@ func PracticalExamples() {
 	// 6. Plugin System
 	fmt.Println("\n🔌 6. Plugin System:")
 	demonstratePluginSystem()
+
+	// 7. Struct Mapping
+	fmt.Println("\n🔀 7. Struct Mapping:")
+	demonstrateStructMapping()
+
+	// 8. Diff and Patch
+	fmt.Println("\n🩹 8. Diff and Patch:")
+	demonstrateDiffAndPatch()
+
+	// 9. Dependency Injection
+	fmt.Println("\n💉 9. Dependency Injection:")
+	demonstrateDependencyInjection()
+
+	// 10. Expression Evaluation
+	fmt.Println("\n🧮 10. Expression Evaluation:")
+	demonstrateExpressionEvaluation()
+
+	// 11. Schema Extraction
+	fmt.Println("\n📐 11. Schema Extraction:")
+	demonstrateSchemaExtraction()
+
+	// 12. Interface Proxies
+	fmt.Println("\n🪞 12. Interface Proxies:")
+	demonstrateProxies()
+
+	// 13. Struct Generation
+	fmt.Println("\n🏭 13. Struct Generation from JSON:")
+	demonstrateStructGeneration()
+
+	// 14. Type Registry
+	fmt.Println("\n🗂️  14. Type Registry:")
+	demonstrateTypeRegistry()
+
+	// 15. Redaction
+	fmt.Println("\n🙈 15. Redaction:")
+	demonstrateRedaction()
 }
 
 // Configuration struct for demonstration
@@ -107,7 +145,7 @@ func demonstrateJSONOperations() {
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Unmarshaled product: %+v\n", newProduct)
+		fmt.Printf("Unmarshaled product: %s\n", Dump(newProduct))
 	}
 
 	// Field-specific JSON operations
@@ -174,6 +212,8 @@ func demonstrateStructValidation() {
 		}
 		fmt.Println()
 	}
+
+	demonstrateAdvancedValidation()
 }
 
 func demonstrateConfigurationLoading() {
@@ -204,7 +244,7 @@ func demonstrateConfigurationLoading() {
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 	} else {
-		fmt.Printf("Loaded config: %+v\n", config)
+		fmt.Printf("Loaded config: %s\n", Dump(config))
 	}
 
 	// Load configuration with defaults
@@ -214,7 +254,7 @@ func demonstrateConfigurationLoading() {
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 	} else {
-		fmt.Printf("Config with defaults: %+v\n", configWithDefaults)
+		fmt.Printf("Config with defaults: %s\n", Dump(configWithDefaults))
 	}
 }
 
@@ -230,7 +270,7 @@ func demonstrateObjectCloning() {
 		Active:      true,
 	}
 
-	fmt.Printf("Original product: %+v\n", original)
+	fmt.Printf("Original product: %s\n", Dump(original))
 
 	// Deep clone the product
 	cloned, err := deepClone(original)
@@ -239,7 +279,7 @@ func demonstrateObjectCloning() {
 		return
 	}
 
-	fmt.Printf("Cloned product: %+v\n", cloned)
+	fmt.Printf("Cloned product: %s\n", Dump(cloned))
 
 	// Modify the cloned product
 	clonedProduct := cloned.(Product)
@@ -248,8 +288,8 @@ func demonstrateObjectCloning() {
 	clonedProduct.Tags = append(clonedProduct.Tags, "modified")
 	clonedProduct.Metadata["version"] = "2.0"
 
-	fmt.Printf("Modified cloned product: %+v\n", clonedProduct)
-	fmt.Printf("Original product (should be unchanged): %+v\n", original)
+	fmt.Printf("Modified cloned product: %s\n", Dump(clonedProduct))
+	fmt.Printf("Original product (should be unchanged): %s\n", Dump(original))
 
 	// Shallow clone demonstration
 	fmt.Println("\nShallow clone demonstration:")
@@ -259,8 +299,10 @@ func demonstrateObjectCloning() {
 	shallowClonedProduct.Tags[0] = "shallow"
 	shallowClonedProduct.Metadata["version"] = "shallow"
 
-	fmt.Printf("Shallow cloned product: %+v\n", shallowClonedProduct)
-	fmt.Printf("Original product (may be affected): %+v\n", original)
+	fmt.Printf("Shallow cloned product: %s\n", Dump(shallowClonedProduct))
+	fmt.Printf("Original product (may be affected): %s\n", Dump(original))
+
+	demonstrateAdvancedCloning()
 }
 
 func demonstrateGenericUtilities() {
@@ -307,13 +349,13 @@ func demonstrateGenericUtilities() {
 	source := Product{ID: 1, Name: "Source", Price: 100.0, Active: true}
 	target := Product{ID: 2, Name: "Target", Price: 200.0, Active: false}
 
-	fmt.Printf("Before copy - Source: %+v\n", source)
-	fmt.Printf("Before copy - Target: %+v\n", target)
+	fmt.Printf("Before copy - Source: %s\n", Dump(source))
+	fmt.Printf("Before copy - Target: %s\n", Dump(target))
 
 	copyFields(source, &target, "Name", "Price")
 
-	fmt.Printf("After copy - Source: %+v\n", source)
-	fmt.Printf("After copy - Target: %+v\n", target)
+	fmt.Printf("After copy - Source: %s\n", Dump(source))
+	fmt.Printf("After copy - Target: %s\n", Dump(target))
 }
 
 func demonstratePluginSystem() {
@@ -463,74 +505,21 @@ func setValueFromInterface(fieldValue reflect.Value, jsonValue interface{}) erro
 
 // Validation
 func validateStruct(v interface{}) []string {
-	var errors []string
-	value := reflect.ValueOf(v)
-	if value.Kind() == reflect.Ptr {
-		value = value.Elem()
+	err := defaultValidator.Validate(v)
+	if err == nil {
+		return nil
 	}
 
-	if value.Kind() != reflect.Struct {
-		return []string{"value must be a struct"}
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []string{err.Error()}
 	}
 
-	structType := value.Type()
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
-		fieldValue := value.Field(i)
-
-		// Get validation tag
-		validateTag := field.Tag.Get("validate")
-		if validateTag == "" {
-			continue
-		}
-
-		// Parse validation rules
-		rules := strings.Split(validateTag, ",")
-		fieldErrors := validateField(field.Name, fieldValue, rules)
-		errors = append(errors, fieldErrors...)
+	messages := make([]string, len(validationErrs))
+	for i, fe := range validationErrs {
+		messages[i] = fe.Message
 	}
-
-	return errors
-}
-
-func validateField(fieldName string, fieldValue reflect.Value, rules []string) []string {
-	var errors []string
-
-	for _, rule := range rules {
-		rule = strings.TrimSpace(rule)
-
-		switch rule {
-		case "required":
-			if fieldValue.IsZero() {
-				errors = append(errors, fmt.Sprintf("%s is required", fieldName))
-			}
-		case "email":
-			if fieldValue.Kind() == reflect.String {
-				email := fieldValue.String()
-				if !isValidEmail(email) {
-					errors = append(errors, fmt.Sprintf("%s must be a valid email", fieldName))
-				}
-			}
-		default:
-			if strings.HasPrefix(rule, "min=") {
-				minStr := strings.TrimPrefix(rule, "min=")
-				if min, err := strconv.Atoi(minStr); err == nil {
-					if !validateMin(fieldValue, min) {
-						errors = append(errors, fmt.Sprintf("%s must be at least %d", fieldName, min))
-					}
-				}
-			} else if strings.HasPrefix(rule, "max=") {
-				maxStr := strings.TrimPrefix(rule, "max=")
-				if max, err := strconv.Atoi(maxStr); err == nil {
-					if !validateMax(fieldValue, max) {
-						errors = append(errors, fmt.Sprintf("%s must be at most %d", fieldName, max))
-					}
-				}
-			}
-		}
-	}
-
-	return errors
+	return messages
 }
 
 func isValidEmail(email string) bool {
@@ -539,37 +528,32 @@ func isValidEmail(email string) bool {
 	return matched
 }
 
-func validateMin(value reflect.Value, min int) bool {
-	switch value.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return value.Int() >= int64(min)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return value.Uint() >= uint64(min)
-	case reflect.Float32, reflect.Float64:
-		return value.Float() >= float64(min)
-	case reflect.String:
-		return len(value.String()) >= min
-	case reflect.Slice, reflect.Array:
-		return value.Len() >= min
-	default:
-		return true
-	}
+func validateMin(value reflect.Value, min float64) bool {
+	n, ok := validationSize(value)
+	return !ok || n >= min
 }
 
-func validateMax(value reflect.Value, max int) bool {
+func validateMax(value reflect.Value, max float64) bool {
+	n, ok := validationSize(value)
+	return !ok || n <= max
+}
+
+// validationSize is what min and max compare: the value of a number, or the
+// length of a string or collection
+func validationSize(value reflect.Value) (float64, bool) {
 	switch value.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return value.Int() <= int64(max)
+		return float64(value.Int()), true
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return value.Uint() <= uint64(max)
+		return float64(value.Uint()), true
 	case reflect.Float32, reflect.Float64:
-		return value.Float() <= float64(max)
+		return value.Float(), true
 	case reflect.String:
-		return len(value.String()) <= max
-	case reflect.Slice, reflect.Array:
-		return value.Len() <= max
+		return float64(len(value.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true
 	default:
-		return true
+		return 0, false
 	}
 }
 
@@ -663,66 +647,6 @@ func setDefaults(config interface{}) {
 }
 
 // Cloning
-func deepClone(original interface{}) (interface{}, error) {
-	originalValue := reflect.ValueOf(original)
-	originalType := originalValue.Type()
-
-	// Create new instance
-	newValue := reflect.New(originalType).Elem()
-
-	// Copy all fields
-	for i := 0; i < originalType.NumField(); i++ {
-		originalField := originalValue.Field(i)
-		newField := newValue.Field(i)
-
-		if newField.CanSet() {
-			switch originalField.Kind() {
-			case reflect.Slice:
-				if originalField.IsNil() {
-					newField.Set(reflect.Zero(originalField.Type()))
-				} else {
-					newSlice := reflect.MakeSlice(originalField.Type(), originalField.Len(), originalField.Cap())
-					reflect.Copy(newSlice, originalField)
-					newField.Set(newSlice)
-				}
-			case reflect.Map:
-				if originalField.IsNil() {
-					newField.Set(reflect.Zero(originalField.Type()))
-				} else {
-					newMap := reflect.MakeMap(originalField.Type())
-					for _, key := range originalField.MapKeys() {
-						value := originalField.MapIndex(key)
-						newMap.SetMapIndex(key, value)
-					}
-					newField.Set(newMap)
-				}
-			case reflect.Struct:
-				clonedStruct, err := deepClone(originalField.Interface())
-				if err != nil {
-					return nil, err
-				}
-				newField.Set(reflect.ValueOf(clonedStruct))
-			case reflect.Ptr:
-				if originalField.IsNil() {
-					newField.Set(reflect.Zero(originalField.Type()))
-				} else {
-					clonedPtr, err := deepClone(originalField.Elem().Interface())
-					if err != nil {
-						return nil, err
-					}
-					newPtr := reflect.New(originalField.Type().Elem())
-					newPtr.Elem().Set(reflect.ValueOf(clonedPtr))
-					newField.Set(newPtr)
-				}
-			default:
-				newField.Set(originalField)
-			}
-		}
-	}
-
-	return newValue.Interface(), nil
-}
-
 func shallowClone(original interface{}) interface{} {
 	originalValue := reflect.ValueOf(original)
 	originalType := originalValue.Type()
@@ -894,9 +818,5 @@ func (pr *PluginRegistry) ExecutePlugin(name string, context map[string]interfac
 }
 
 func (pr *PluginRegistry) ListPlugins() []string {
-	names := make([]string, 0, len(pr.plugins))
-	for name := range pr.plugins {
-		names = append(names, name)
-	}
-	return names
+	return data_structure.Keys(pr.plugins)
 }
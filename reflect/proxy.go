@@ -0,0 +1,310 @@
+package reflect
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Invocation is a method call passing through a proxy
+type Invocation struct {
+	Method string
+	Args   []reflect.Value
+	next   func() []reflect.Value
+}
+
+// Proceed calls the next interceptor, or the real method at the end of the chain
+func (inv *Invocation) Proceed() []reflect.Value {
+	return inv.next()
+}
+
+// ArgsInterface returns the arguments as plain values
+func (inv *Invocation) ArgsInterface() []interface{} {
+	args := make([]interface{}, len(inv.Args))
+	for i, arg := range inv.Args {
+		args[i] = arg.Interface()
+	}
+	return args
+}
+
+// Interceptor wraps a method call. It must call inv.Proceed to reach the target,
+// or return results of the method's result types itself.
+type Interceptor func(inv *Invocation) []reflect.Value
+
+// ChainInterceptors combines interceptors; the first one is the outermost
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(inv *Invocation) []reflect.Value {
+		proceed := inv.next
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor := interceptors[i]
+			next := proceed
+			proceed = func() []reflect.Value {
+				return interceptor(&Invocation{Method: inv.Method, Args: inv.Args, next: next})
+			}
+		}
+		return proceed()
+	}
+}
+
+// MakeProxy routes every method of the interface iface (passed as (*I)(nil))
+// through interceptor before it reaches target.
+//
+// Go cannot create new method sets at runtime, so proxy must be a pointer to a
+// stub struct with a func field named <Method>Func for each interface method,
+// whose methods simply call those fields. MakeProxy builds the field values
+// with reflect.MakeFunc.
+func MakeProxy(iface, proxy, target interface{}, interceptor Interceptor) error {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		return fmt.Errorf("iface must be a nil pointer to an interface, e.g. (*I)(nil)")
+	}
+	ifaceType = ifaceType.Elem()
+
+	proxyValue := reflect.ValueOf(proxy)
+	if proxyValue.Kind() != reflect.Ptr || proxyValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("proxy must be a pointer to a struct")
+	}
+	if !proxyValue.Type().Implements(ifaceType) {
+		return fmt.Errorf("%v does not implement %v", proxyValue.Type(), ifaceType)
+	}
+
+	targetValue := reflect.ValueOf(target)
+	if !targetValue.IsValid() || !targetValue.Type().Implements(ifaceType) {
+		return fmt.Errorf("%T does not implement %v", target, ifaceType)
+	}
+
+	stub := proxyValue.Elem()
+	for i := 0; i < ifaceType.NumMethod(); i++ {
+		method := ifaceType.Method(i)
+		field := stub.FieldByName(method.Name + "Func")
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("proxy is missing field %sFunc", method.Name)
+		}
+		if field.Type() != method.Type {
+			return fmt.Errorf("field %sFunc has type %v, want %v", method.Name, field.Type(), method.Type)
+		}
+
+		realMethod := targetValue.MethodByName(method.Name)
+		name := method.Name
+		field.Set(reflect.MakeFunc(method.Type, func(args []reflect.Value) []reflect.Value {
+			inv := &Invocation{
+				Method: name,
+				Args:   args,
+				next: func() []reflect.Value {
+					if method.Type.IsVariadic() {
+						return realMethod.CallSlice(args)
+					}
+					return realMethod.Call(args)
+				},
+			}
+			return interceptor(inv)
+		}))
+	}
+	return nil
+}
+
+// resultError returns the trailing error of a method's results, if any
+func resultError(results []reflect.Value) error {
+	if n := len(results); n > 0 && results[n-1].Type() == errorType && !results[n-1].IsNil() {
+		return results[n-1].Interface().(error)
+	}
+	return nil
+}
+
+// LoggingInterceptor prints every call and its error result
+func LoggingInterceptor(prefix string) Interceptor {
+	return func(inv *Invocation) []reflect.Value {
+		results := inv.Proceed()
+		status := "ok"
+		if err := resultError(results); err != nil {
+			status = "error: " + err.Error()
+		}
+		fmt.Printf("%s %s%v -> %s\n", prefix, inv.Method, inv.ArgsInterface(), status)
+		return results
+	}
+}
+
+// TimingInterceptor reports the duration of each call to record
+func TimingInterceptor(record func(method string, d time.Duration)) Interceptor {
+	return func(inv *Invocation) []reflect.Value {
+		start := time.Now()
+		results := inv.Proceed()
+		record(inv.Method, time.Since(start))
+		return results
+	}
+}
+
+// RetryInterceptor retries calls that return an error, up to attempts in total
+func RetryInterceptor(attempts int, delay time.Duration) Interceptor {
+	return func(inv *Invocation) []reflect.Value {
+		var results []reflect.Value
+		for attempt := 1; attempt <= attempts; attempt++ {
+			results = inv.Proceed()
+			if resultError(results) == nil || attempt == attempts {
+				break
+			}
+			time.Sleep(delay)
+		}
+		return results
+	}
+}
+
+// Memoizer caches successful results per method and arguments. With a
+// ttl, expired entries are pruned as new ones are stored, at most once per
+// ttl, so arguments that are never asked for again do not pile up.
+type Memoizer struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]memoEntry
+	hits      int
+	misses    int
+	lastPrune time.Time
+}
+
+// MemoStats counts a Memoizer's lookups and the entries it holds
+type MemoStats struct {
+	Hits, Misses, Entries int
+}
+
+type memoEntry struct {
+	results []reflect.Value
+	expires time.Time
+}
+
+// NewMemoizer creates a memoizer; a zero ttl caches results forever
+func NewMemoizer(ttl time.Duration) *Memoizer {
+	return &Memoizer{ttl: ttl, entries: make(map[string]memoEntry)}
+}
+
+// Interceptor returns the caching interceptor. Calls that return an error are not cached.
+func (m *Memoizer) Interceptor() Interceptor {
+	return func(inv *Invocation) []reflect.Value {
+		key := inv.Method + fmt.Sprintf("%#v", inv.ArgsInterface())
+
+		m.mu.Lock()
+		if entry, ok := m.entries[key]; ok {
+			if entry.expires.IsZero() || time.Now().Before(entry.expires) {
+				m.hits++
+				m.mu.Unlock()
+				return entry.results
+			}
+			delete(m.entries, key)
+		}
+		m.misses++
+		m.mu.Unlock()
+
+		results := inv.Proceed()
+		if resultError(results) == nil {
+			entry := memoEntry{results: results}
+			if m.ttl > 0 {
+				entry.expires = time.Now().Add(m.ttl)
+			}
+			m.mu.Lock()
+			m.pruneLocked()
+			m.entries[key] = entry
+			m.mu.Unlock()
+		}
+		return results
+	}
+}
+
+// Stats returns the hits and misses so far and the number of entries
+func (m *Memoizer) Stats() MemoStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MemoStats{Hits: m.hits, Misses: m.misses, Entries: len(m.entries)}
+}
+
+// pruneLocked drops expired entries, at most once per ttl
+func (m *Memoizer) pruneLocked() {
+	now := time.Now()
+	if m.ttl <= 0 || now.Sub(m.lastPrune) < m.ttl {
+		return
+	}
+	m.lastPrune = now
+	for key, entry := range m.entries {
+		if !now.Before(entry.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// demoUser and demoUserAPI stand in for a remote API in the proxy demo
+type demoUser struct {
+	Login, Name string
+}
+
+type demoUserAPI interface {
+	GetUser(username string) (*demoUser, error)
+	GetRepos(username string) ([]string, error)
+}
+
+// demoUserAPIProxy is the stub struct MakeProxy fills in for demoUserAPI
+type demoUserAPIProxy struct {
+	GetUserFunc  func(username string) (*demoUser, error)
+	GetReposFunc func(username string) ([]string, error)
+}
+
+func (p *demoUserAPIProxy) GetUser(username string) (*demoUser, error) {
+	return p.GetUserFunc(username)
+}
+
+func (p *demoUserAPIProxy) GetRepos(username string) ([]string, error) {
+	return p.GetReposFunc(username)
+}
+
+// fakeUserAPI serves canned data so the proxy demo runs without network access
+type fakeUserAPI struct {
+	calls int
+}
+
+func (f *fakeUserAPI) GetUser(username string) (*demoUser, error) {
+	f.calls++
+	if username == "" {
+		return nil, fmt.Errorf("username required")
+	}
+	return &demoUser{Login: username, Name: strings.ToUpper(username[:1]) + username[1:]}, nil
+}
+
+func (f *fakeUserAPI) GetRepos(username string) ([]string, error) {
+	f.calls++
+	return []string{"hello-world", "spoon-knife"}, nil
+}
+
+func demonstrateProxies() {
+	backend := &fakeUserAPI{}
+	memo := NewMemoizer(time.Minute)
+	api := &demoUserAPIProxy{}
+	if err := MakeProxy((*demoUserAPI)(nil), api, backend, memo.Interceptor()); err != nil {
+		fmt.Printf("Error building proxy: %v\n", err)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		user, _ := api.GetUser("octocat")
+		repos, _ := api.GetRepos("octocat")
+		fmt.Printf("  %s has %d repos\n", user.Name, len(repos))
+	}
+	api.GetUser("") // errors are never cached
+	api.GetUser("")
+	stats := memo.Stats()
+	fmt.Printf("Memoized: backend calls=%d, hits=%d, misses=%d, entries=%d\n", backend.calls, stats.Hits, stats.Misses, stats.Entries)
+
+	// Interceptors compose: logging, timing and retries around the same backend
+	durations := make(map[string]time.Duration)
+	logged := &demoUserAPIProxy{}
+	err := MakeProxy((*demoUserAPI)(nil), logged, backend, ChainInterceptors(
+		LoggingInterceptor("  [users]"),
+		TimingInterceptor(func(method string, d time.Duration) { durations[method] += d }),
+		RetryInterceptor(2, 10*time.Millisecond),
+	))
+	if err != nil {
+		fmt.Printf("Error building proxy: %v\n", err)
+		return
+	}
+	logged.GetRepos("octocat")
+	logged.GetUser("")
+	fmt.Printf("Timed methods: %d, backend calls=%d\n", len(durations), backend.calls)
+}
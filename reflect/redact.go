@@ -0,0 +1,248 @@
+package reflect
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"unsafe"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+// SensitiveTag is the struct tag Redact looks for. Its value picks the mask:
+//
+//	`sensitive:"true"`  replaces the whole value ("[redacted]" for strings)
+//	`sensitive:"url"`   keeps a URL or DSN but masks its password and secrets
+//	`sensitive:"last4"` keeps the last four characters, e.g. of a card number
+const SensitiveTag = "sensitive"
+
+// Redacted replaces sensitive strings
+const Redacted = "[redacted]"
+
+// Redact returns a deep copy of v with every field tagged sensitive masked,
+// for logging or printing values that hold passwords, tokens or DSNs. It
+// walks nested structs, pointers, slices, maps and interfaces; v itself is
+// not modified.
+func Redact(v interface{}) interface{} {
+	return RedactTag(v, SensitiveTag)
+}
+
+// Dump pretty-prints v with format.Dump and format.DefaultDumpOptions,
+// masking sensitive fields with Redact first
+func Dump(v interface{}) string {
+	opts := format.DefaultDumpOptions
+	opts.Redact = Redact
+	return format.Dump(v, opts)
+}
+
+// RedactTag is Redact with a different tag name. It fails closed: a value
+// it cannot copy, such as one whose Cloner misbehaves, comes back as a
+// placeholder naming its type rather than unmasked.
+func RedactTag(v interface{}, tag string) interface{} {
+	if v == nil {
+		return nil
+	}
+	clone, err := DeepClone(v, CloneOptions{CopyUnexported: true})
+	if err != nil || clone == nil {
+		return fmt.Sprintf("%s %T", Redacted, v)
+	}
+
+	r := &redactor{tag: tag, visited: map[uintptr]bool{}}
+	value := reflect.ValueOf(clone)
+	if value.Kind() == reflect.Ptr {
+		r.walk(value)
+		return clone
+	}
+	// Copy into addressable memory so fields can be set
+	addressable := reflect.New(value.Type()).Elem()
+	addressable.Set(value)
+	r.walk(addressable)
+	return addressable.Interface()
+}
+
+type redactor struct {
+	tag     string
+	visited map[uintptr]bool
+}
+
+func (r *redactor) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || r.visited[v.Pointer()] {
+			return
+		}
+		r.visited[v.Pointer()] = true
+		r.walk(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		// The dynamic value is not addressable; redact a copy and put it back
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		r.walk(elem)
+		if v.CanSet() {
+			v.Set(elem)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() && field.CanAddr() {
+				// Unexported fields are printed too; the clone is ours to change
+				field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+			}
+			if mode, ok := t.Field(i).Tag.Lookup(r.tag); ok && mode != "false" {
+				mask(field, mode)
+				continue
+			}
+			r.walk(field)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable, so redact copies
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			r.walk(elem)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+}
+
+// mask masks a sensitive field according to its tag value
+func mask(v reflect.Value, mode string) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() == "" {
+			return // an unset secret is worth seeing
+		}
+		switch mode {
+		case "url":
+			v.SetString(MaskURL(v.String()))
+		case "last4":
+			s := v.String()
+			if len(s) > 4 {
+				v.SetString(strings.Repeat("*", len(s)-4) + s[len(s)-4:])
+			} else {
+				v.SetString(Redacted)
+			}
+		default:
+			v.SetString(Redacted)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+		if mode == "url" || mode == "last4" {
+			// apply the mask to each string inside, e.g. a list of DSNs
+			if v.Kind() == reflect.Ptr && !v.IsNil() {
+				mask(v.Elem(), mode)
+				return
+			}
+			if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+				for i := 0; i < v.Len(); i++ {
+					mask(v.Index(i), mode)
+				}
+				return
+			}
+		}
+		v.Set(reflect.Zero(v.Type()))
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+// sensitiveParam matches query parameter and DSN keys that hold secrets
+var sensitiveParam = regexp.MustCompile(`(?i)pass(word)?|secret|token|api_?key|access_?key|signature|sslkey`)
+
+// dsnPassword matches password=... in key=value DSNs, such as PostgreSQL's
+var dsnPassword = regexp.MustCompile(`(?i)\b(password|pwd)\s*=\s*('[^']*'|\S+)`)
+
+// dsnUserPassword matches the user:password@ prefix of DSNs that are not
+// URLs, such as MySQL's "user:pw@tcp(host)/db"
+var dsnUserPassword = regexp.MustCompile(`^([^:/@\s]*):[^@\s]*@`)
+
+// MaskURL masks the password in a URL's user info and the values of
+// secret-looking query parameters, leaving the scheme, host and path
+// readable. DSNs that are not URLs, such as "host=db password=x" or
+// "user:pw@tcp(db)/app", have their password masked too.
+func MaskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		masked := dsnUserPassword.ReplaceAllString(raw, "${1}:***@")
+		return dsnPassword.ReplaceAllString(masked, "${1}=***")
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "***")
+		} else {
+			u.User = url.User("***") // a lone user is often a token
+		}
+	}
+	u.RawQuery = MaskQuery(u.RawQuery)
+	return u.String()
+}
+
+// MaskQuery masks the values of secret-looking parameters in a raw query
+// string, such as token or api_key, for access logs
+func MaskQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "***"
+	}
+	masked := false
+	for key := range query {
+		if sensitiveParam.MatchString(key) {
+			query[key] = []string{"***"}
+			masked = true
+		}
+	}
+	if !masked {
+		return rawQuery
+	}
+	return strings.ReplaceAll(query.Encode(), "%2A%2A%2A", "***")
+}
+
+type redactDemoAccount struct {
+	Username string
+	Password string `sensitive:"true"`
+	APIToken string `sensitive:"true"`
+	Card     string `sensitive:"last4"`
+	Backups  []redactDemoBackup
+	Labels   map[string]redactDemoBackup
+}
+
+type redactDemoBackup struct {
+	Name string
+	DSN  string `sensitive:"url"`
+}
+
+func demonstrateRedaction() {
+	account := &redactDemoAccount{
+		Username: "alice",
+		Password: "hunter2",
+		APIToken: "ghp_abc123",
+		Card:     "4111111111111111",
+		Backups: []redactDemoBackup{
+			{Name: "primary", DSN: "postgres://app:s3cret@db:5432/app?sslmode=disable"},
+			{Name: "legacy", DSN: "host=old-db user=app password='p@ss word' dbname=app"},
+		},
+		Labels: map[string]redactDemoBackup{
+			"cache": {Name: "redis", DSN: "redis://default:pw@cache:6379/0?token=abc"},
+		},
+	}
+
+	fmt.Printf("Redacted: %s\n", Dump(account))
+	fmt.Printf("Original untouched: password=%s\n", account.Password)
+}
@@ -0,0 +1,224 @@
+package reflect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// DefaultKindField is the JSON field TypeRegistry stores type names in
+const DefaultKindField = "kind"
+
+// TypeRegistry maps names to types, so values can be created by name and
+// JSON carrying a "kind" discriminator decoded into the type it names.
+// Registered types should not have a JSON field of their own with that name.
+type TypeRegistry struct {
+	// KindField is the discriminator field, DefaultKindField if empty
+	KindField string
+
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+// NewTypeRegistry creates an empty registry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+}
+
+// Register adds the type of sample under name. sample may be a value or a
+// pointer, e.g. LoggerPlugin{} or (*LoggerPlugin)(nil); New always returns
+// a pointer.
+func (r *TypeRegistry) Register(name string, sample interface{}) error {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		return fmt.Errorf("cannot register %q: sample is an untyped nil", name)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.byName[name]; ok {
+		return fmt.Errorf("cannot register %v as %q: already registered for %v", t, name, existing)
+	}
+	if existing, ok := r.byType[t]; ok {
+		return fmt.Errorf("cannot register %v as %q: already registered as %q", t, name, existing)
+	}
+	r.byName[name] = t
+	r.byType[t] = name
+	return nil
+}
+
+// MustRegister is Register for package initialisation, panicking on error
+func (r *TypeRegistry) MustRegister(name string, sample interface{}) {
+	if err := r.Register(name, sample); err != nil {
+		panic(err)
+	}
+}
+
+// Names returns every registered name in sorted order
+func (r *TypeRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NameOf returns the name the type of v, or of what v points to, is
+// registered under
+func (r *TypeRegistry) NameOf(v interface{}) (string, bool) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.byType[t]
+	return name, ok
+}
+
+// New returns a pointer to a new zero value of the type registered as name
+func (r *TypeRegistry) New(name string) (interface{}, error) {
+	r.mu.RLock()
+	t, ok := r.byName[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", name)
+	}
+	return reflect.New(t).Interface(), nil
+}
+
+// NewFromJSON creates the type registered as name and populates it from data
+func (r *TypeRegistry) NewFromJSON(name string, data []byte) (interface{}, error) {
+	v, err := r.New(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("decoding %q: %w", name, err)
+	}
+	return v, nil
+}
+
+func (r *TypeRegistry) kindField() string {
+	if r.KindField != "" {
+		return r.KindField
+	}
+	return DefaultKindField
+}
+
+// Marshal encodes v, a registered type or a pointer to one, as a JSON
+// object with its name in the kind field ahead of its own fields
+func (r *TypeRegistry) Marshal(v interface{}) ([]byte, error) {
+	name, ok := r.NameOf(v)
+	if !ok {
+		return nil, fmt.Errorf("type %T is not registered", v)
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) < 2 || body[0] != '{' {
+		return nil, fmt.Errorf("type %T does not encode as a JSON object", v)
+	}
+
+	var buf bytes.Buffer
+	key, _ := json.Marshal(r.kindField())
+	value, _ := json.Marshal(name)
+	fmt.Fprintf(&buf, "{%s:%s", key, value)
+	if rest := body[1:]; !bytes.Equal(rest, []byte("}")) {
+		buf.WriteByte(',')
+		buf.Write(rest)
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads the kind field of the JSON object in data and decodes the
+// object into a new value of that type, returning a pointer to it
+func (r *TypeRegistry) Decode(data []byte) (interface{}, error) {
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	raw, ok := header[r.kindField()]
+	if !ok {
+		return nil, fmt.Errorf("missing %q field", r.kindField())
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return nil, fmt.Errorf("%q field: %w", r.kindField(), err)
+	}
+	return r.NewFromJSON(name, data)
+}
+
+// Unmarshal decodes data like Decode and stores the result in target, a
+// pointer to an interface the registered type implements, e.g. *Plugin
+func (r *TypeRegistry) Unmarshal(data []byte, target interface{}) error {
+	dst := reflect.ValueOf(target)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	v, err := r.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(v)
+	elem := dst.Elem()
+	switch {
+	case value.Type().AssignableTo(elem.Type()):
+		elem.Set(value)
+	case value.Elem().Type().AssignableTo(elem.Type()):
+		elem.Set(value.Elem())
+	default:
+		return fmt.Errorf("decoded %s cannot be stored in %s", value.Type(), elem.Type())
+	}
+	return nil
+}
+
+func demonstrateTypeRegistry() {
+	types := NewTypeRegistry()
+	types.MustRegister("logger", LoggerPlugin{})
+	types.MustRegister("cache", CachePlugin{})
+	types.MustRegister("metrics", MetricsPlugin{})
+	fmt.Printf("Registered types: %v\n", types.Names())
+
+	// A config file lists plugins by kind and each entry becomes that type
+	config := []byte(`[{"kind": "cache"}, {"kind": "logger"}, {"kind": "tracer"}]`)
+	var entries []json.RawMessage
+	if err := json.Unmarshal(config, &entries); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	registry := NewPluginRegistry()
+	for _, entry := range entries {
+		var plugin Plugin
+		if err := types.Unmarshal(entry, &plugin); err != nil {
+			fmt.Printf("  Skipped %s: %v\n", entry, err)
+			continue
+		}
+		name, _ := types.NameOf(plugin)
+		registry.RegisterPlugin(name, plugin)
+		saved, _ := types.Marshal(plugin)
+		fmt.Printf("  Loaded %s as %T, saved back as %s\n", entry, plugin, saved)
+	}
+
+	result, err := registry.ExecutePlugin("logger", map[string]interface{}{"message": "loaded from config", "level": "info"})
+	fmt.Printf("Executing logger: %v %v\n", result, err)
+}
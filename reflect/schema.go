@@ -0,0 +1,342 @@
+package reflect
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldSchema describes one struct field, or the element of a slice or map
+type FieldSchema struct {
+	Name     string            `json:"name"`
+	JSONName string            `json:"json_name,omitempty"`
+	Type     string            `json:"type"`
+	Kind     string            `json:"kind"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Rules    []string          `json:"rules,omitempty"`
+	Required bool              `json:"required,omitempty"`
+	Doc      string            `json:"doc,omitempty"`
+	Fields   []FieldSchema     `json:"fields,omitempty"`
+	Elem     *FieldSchema      `json:"elem,omitempty"`
+}
+
+// StructSchema is the machine-readable description of a struct type
+type StructSchema struct {
+	Name    string        `json:"name"`
+	Package string        `json:"package"`
+	Doc     string        `json:"doc,omitempty"`
+	Fields  []FieldSchema `json:"fields"`
+}
+
+// ExtractSchema walks the struct type of v (a struct or pointer to one) and
+// describes every exported field, including nested structs, slices and maps
+func ExtractSchema(v interface{}) (*StructSchema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("value must be a struct or pointer to a struct")
+	}
+
+	visiting := map[reflect.Type]bool{t: true}
+	return &StructSchema{
+		Name:    t.Name(),
+		Package: t.PkgPath(),
+		Fields:  structFieldSchemas(t, visiting),
+	}, nil
+}
+
+func structFieldSchemas(t reflect.Type, visiting map[reflect.Type]bool) []FieldSchema {
+	var fields []FieldSchema
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		schema := typeSchema(field.Type, visiting)
+		schema.Name = field.Name
+		schema.Tags = parseTags(field.Tag)
+		schema.JSONName = field.Name
+		if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; jsonName != "" {
+			schema.JSONName = jsonName
+		}
+		if rules := field.Tag.Get("validate"); rules != "" {
+			schema.Rules = strings.Split(rules, ",")
+			for _, rule := range schema.Rules {
+				if rule == "required" {
+					schema.Required = true
+				}
+			}
+		}
+		fields = append(fields, schema)
+	}
+	return fields
+}
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) FieldSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	schema := FieldSchema{Type: t.String(), Kind: t.Kind().String()}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		// time.Time and recursive references are treated as leaves
+		if t == timeType || visiting[t] {
+			return schema
+		}
+		visiting[t] = true
+		schema.Fields = structFieldSchemas(t, visiting)
+		delete(visiting, t)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		elem := typeSchema(t.Elem(), visiting)
+		schema.Elem = &elem
+	}
+	return schema
+}
+
+// parseTags splits a struct tag into its key/value pairs
+func parseTags(tag reflect.StructTag) map[string]string {
+	tags := make(map[string]string)
+	rest := string(tag)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ")
+		colon := strings.Index(rest, ":\"")
+		if colon <= 0 {
+			break
+		}
+		key := rest[:colon]
+		rest = rest[colon+1:]
+
+		value, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			break
+		}
+		rest = rest[len(value):]
+		tags[key], _ = strconv.Unquote(value)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// LoadDocs fills in doc comments by parsing the Go source files in dir.
+// Types that are not declared in dir are left without docs.
+func (s *StructSchema) LoadDocs(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	docs := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		collectDocs(file, docs)
+	}
+
+	s.Doc = docs[s.Name]
+	applyDocs(s.Name, s.Fields, docs)
+	return nil
+}
+
+// collectDocs records type docs under "Type" and field docs under "Type.Field"
+func collectDocs(file *ast.File, docs map[string]string) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			doc := typeSpec.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			if doc != nil {
+				docs[typeSpec.Name.Name] = strings.TrimSpace(doc.Text())
+			}
+
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range structType.Fields.List {
+				text := ""
+				if field.Doc != nil {
+					text = field.Doc.Text()
+				} else if field.Comment != nil {
+					text = field.Comment.Text()
+				}
+				for _, name := range field.Names {
+					if text != "" {
+						docs[typeSpec.Name.Name+"."+name.Name] = strings.TrimSpace(text)
+					}
+				}
+			}
+		}
+	}
+}
+
+func applyDocs(typeName string, fields []FieldSchema, docs map[string]string) {
+	for i := range fields {
+		fields[i].Doc = docs[typeName+"."+fields[i].Name]
+		nested := &fields[i]
+		for nested.Elem != nil {
+			nested = nested.Elem
+		}
+		if len(nested.Fields) > 0 {
+			applyDocs(shortTypeName(nested.Type), nested.Fields, docs)
+		}
+	}
+}
+
+func shortTypeName(typeName string) string {
+	if dot := strings.LastIndex(typeName, "."); dot >= 0 {
+		return typeName[dot+1:]
+	}
+	return typeName
+}
+
+// JSONSchema converts the schema to a JSON Schema (draft 2020-12) document
+func (s *StructSchema) JSONSchema() map[string]interface{} {
+	schema := objectJSONSchema(s.Fields)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = s.Name
+	if s.Doc != "" {
+		schema["description"] = s.Doc
+	}
+	return schema
+}
+
+func objectJSONSchema(fields []FieldSchema) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for _, field := range fields {
+		if field.JSONName == "-" {
+			continue
+		}
+		properties[field.JSONName] = fieldJSONSchema(field)
+		if field.Required {
+			required = append(required, field.JSONName)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func fieldJSONSchema(field FieldSchema) map[string]interface{} {
+	var schema map[string]interface{}
+	switch field.Kind {
+	case "string":
+		schema = map[string]interface{}{"type": "string"}
+	case "bool":
+		schema = map[string]interface{}{"type": "boolean"}
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		schema = map[string]interface{}{"type": "integer"}
+	case "float32", "float64":
+		schema = map[string]interface{}{"type": "number"}
+	case "slice", "array":
+		schema = map[string]interface{}{"type": "array", "items": fieldJSONSchema(*field.Elem)}
+	case "map":
+		schema = map[string]interface{}{"type": "object", "additionalProperties": fieldJSONSchema(*field.Elem)}
+	case "struct":
+		if field.Type == timeType.String() {
+			schema = map[string]interface{}{"type": "string", "format": "date-time"}
+		} else {
+			schema = objectJSONSchema(field.Fields)
+		}
+	default:
+		schema = map[string]interface{}{}
+	}
+
+	if field.Doc != "" {
+		schema["description"] = field.Doc
+	}
+	applyRuleConstraints(schema, field)
+	return schema
+}
+
+// applyRuleConstraints maps validate rules onto JSON Schema keywords
+func applyRuleConstraints(schema map[string]interface{}, field FieldSchema) {
+	for _, rule := range field.Rules {
+		name, param, _ := strings.Cut(rule, "=")
+		n, numErr := strconv.Atoi(param)
+		switch {
+		case name == "email":
+			schema["format"] = "email"
+		case name == "oneof":
+			schema["enum"] = strings.Fields(param)
+		case (name == "min" || name == "max") && numErr == nil:
+			keyword := map[string]map[string]string{
+				"min": {"string": "minLength", "array": "minItems", "object": "minProperties"},
+				"max": {"string": "maxLength", "array": "maxItems", "object": "maxProperties"},
+			}[name][fmt.Sprint(schema["type"])]
+			if keyword == "" {
+				keyword = map[string]string{"min": "minimum", "max": "maximum"}[name]
+			}
+			schema[keyword] = n
+		}
+	}
+}
+
+// ServiceSpec describes a deployable service.
+// It is used to demonstrate schema extraction with doc comments.
+type ServiceSpec struct {
+	// Name is the unique service identifier
+	Name string `json:"name" validate:"required,min=3"`
+	// Replicas is the number of running instances
+	Replicas int    `json:"replicas" validate:"min=1,max=50"`
+	Owner    string `json:"owner" validate:"email"` // contact address of the owning team
+	Tier     string `json:"tier" validate:"oneof=frontend backend batch"`
+	// Ports exposed by the service
+	Ports  []ServicePort     `json:"ports" validate:"dive"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ServicePort is a single exposed port
+type ServicePort struct {
+	// Port number to listen on
+	Port     int    `json:"port" validate:"required,min=1,max=65535"`
+	Protocol string `json:"protocol" validate:"oneof=tcp udp"` // transport protocol
+}
+
+func demonstrateSchemaExtraction() {
+	schema, err := ExtractSchema(ServiceSpec{})
+	if err != nil {
+		fmt.Printf("Error extracting schema: %v\n", err)
+		return
+	}
+
+	// Doc comments are only available when the source is present, e.g. when
+	// running from the repository root
+	if err := schema.LoadDocs("reflect"); err != nil {
+		fmt.Printf("Doc comments unavailable: %v\n", err)
+	}
+
+	for _, field := range schema.Fields {
+		fmt.Printf("  %-9s %-22s required=%-5t %s\n", field.Name, field.Type, field.Required, field.Doc)
+	}
+
+	jsonSchema, _ := json.MarshalIndent(schema.JSONSchema(), "  ", "  ")
+	fmt.Printf("JSON Schema:\n  %s\n", jsonSchema)
+}
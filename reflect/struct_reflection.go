@@ -110,7 +110,7 @@ func demonstrateFieldAccess() {
 	value := reflect.ValueOf(user).Elem()
 	structType := value.Type()
 
-	fmt.Printf("Original user: %+v\n", user)
+	fmt.Printf("Original user: %s\n", Dump(user))
 
 	// Access and modify fields
 	for i := 0; i < structType.NumField(); i++ {
@@ -139,7 +139,7 @@ func demonstrateFieldAccess() {
 		fmt.Println()
 	}
 
-	fmt.Printf("Modified user: %+v\n", user)
+	fmt.Printf("Modified user: %s\n", Dump(user))
 }
 
 func demonstrateStructTags() {
@@ -280,7 +280,7 @@ func demonstrateDynamicStructCreation() {
 		metadataField.Set(metadata)
 	}
 
-	fmt.Printf("Dynamically created user: %+v\n", newUser.Interface())
+	fmt.Printf("Dynamically created user: %s\n", Dump(newUser.Interface()))
 }
 
 // StructAnalyzer provides utility functions for struct analysis
@@ -0,0 +1,413 @@
+package reflect
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string      // path to the field, e.g. "Items[0].Name" or "Labels[env]"
+	Code    string      // rule that failed, e.g. "required" or "min"
+	Param   string      // rule parameter, e.g. "1" for min=1
+	Value   interface{} // the offending value
+	Message string      // rendered, translated message
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is the list of all failed rules for a value
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, e := range ve {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// FieldLevel is passed to validation funcs and gives access to the field and its parent struct
+type FieldLevel struct {
+	Field  reflect.Value
+	Parent reflect.Value
+	Param  string
+	Path   string
+}
+
+// SiblingField returns the named field of the parent struct, if any
+func (fl FieldLevel) SiblingField(name string) (reflect.Value, bool) {
+	if fl.Parent.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	field := fl.Parent.FieldByName(name)
+	return field, field.IsValid()
+}
+
+// ValidationFunc reports whether the field satisfies a rule
+type ValidationFunc func(fl FieldLevel) bool
+
+// Validator validates structs using `validate` tags. Rules listed after "dive"
+// apply to each element of a slice, array or map instead of the container itself.
+type Validator struct {
+	mu       sync.RWMutex
+	rules    map[string]ValidationFunc
+	messages map[string]map[string]string
+	locale   string
+}
+
+var defaultMessages = map[string]map[string]string{
+	"en": {
+		"required":    "{field} is required",
+		"required_if": "{field} is required when {param}",
+		"email":       "{field} must be a valid email",
+		"min":         "{field} must be at least {param}",
+		"max":         "{field} must be at most {param}",
+		"len":         "{field} must have length {param}",
+		"oneof":       "{field} must be one of [{param}]",
+		"eqfield":     "{field} must equal {param}",
+		"nefield":     "{field} must not equal {param}",
+		"default":     "{field} failed the {code} rule",
+	},
+	"zh": {
+		"required":    "{field} 是必填字段",
+		"required_if": "当 {param} 时 {field} 是必填字段",
+		"email":       "{field} 必须是有效的邮箱地址",
+		"min":         "{field} 最小值为 {param}",
+		"max":         "{field} 最大值为 {param}",
+		"len":         "{field} 长度必须为 {param}",
+		"oneof":       "{field} 必须是 [{param}] 之一",
+		"eqfield":     "{field} 必须等于 {param}",
+		"nefield":     "{field} 不能等于 {param}",
+		"default":     "{field} 未通过 {code} 校验",
+	},
+}
+
+// numericParamRules are the built-in rules whose parameter is a number
+var numericParamRules = map[string]bool{"min": true, "max": true, "len": true}
+
+// NewValidator creates a validator with the built-in rules and English messages
+func NewValidator() *Validator {
+	v := &Validator{
+		rules:    make(map[string]ValidationFunc),
+		messages: make(map[string]map[string]string),
+		locale:   "en",
+	}
+
+	v.rules["required"] = func(fl FieldLevel) bool { return !fl.Field.IsZero() }
+	v.rules["email"] = func(fl FieldLevel) bool {
+		return fl.Field.Kind() != reflect.String || isValidEmail(fl.Field.String())
+	}
+	// Validate rejects a min, max or len parameter that is not a number
+	// before these run
+	v.rules["min"] = func(fl FieldLevel) bool {
+		min, _ := strconv.ParseFloat(fl.Param, 64)
+		return validateMin(fl.Field, min)
+	}
+	v.rules["max"] = func(fl FieldLevel) bool {
+		max, _ := strconv.ParseFloat(fl.Param, 64)
+		return validateMax(fl.Field, max)
+	}
+	v.rules["len"] = func(fl FieldLevel) bool {
+		n, _ := strconv.ParseFloat(fl.Param, 64)
+		return validateMin(fl.Field, n) && validateMax(fl.Field, n)
+	}
+	v.rules["oneof"] = func(fl FieldLevel) bool {
+		value := fmt.Sprint(fl.Field.Interface())
+		for _, option := range strings.Fields(fl.Param) {
+			if value == option {
+				return true
+			}
+		}
+		return false
+	}
+	v.rules["eqfield"] = func(fl FieldLevel) bool {
+		other, ok := fl.SiblingField(fl.Param)
+		return ok && reflect.DeepEqual(fl.Field.Interface(), other.Interface())
+	}
+	v.rules["nefield"] = func(fl FieldLevel) bool {
+		other, ok := fl.SiblingField(fl.Param)
+		return !ok || !reflect.DeepEqual(fl.Field.Interface(), other.Interface())
+	}
+	v.rules["required_if"] = func(fl FieldLevel) bool {
+		parts := strings.Fields(fl.Param)
+		if len(parts) != 2 {
+			return true
+		}
+		other, ok := fl.SiblingField(parts[0])
+		if !ok || fmt.Sprint(other.Interface()) != parts[1] {
+			return true
+		}
+		return !fl.Field.IsZero()
+	}
+
+	for locale, templates := range defaultMessages {
+		v.RegisterMessages(locale, templates)
+	}
+	return v
+}
+
+// RegisterValidation adds or replaces a named rule
+func (v *Validator) RegisterValidation(name string, fn ValidationFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = fn
+}
+
+// RegisterMessages adds message templates for a locale. Templates may use
+// the {field}, {param}, {value} and {code} placeholders.
+func (v *Validator) RegisterMessages(locale string, templates map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.messages[locale] == nil {
+		v.messages[locale] = make(map[string]string)
+	}
+	for code, tmpl := range templates {
+		v.messages[locale][code] = tmpl
+	}
+}
+
+// SetLocale selects the locale used to render messages
+func (v *Validator) SetLocale(locale string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.locale = locale
+}
+
+// Validate checks every `validate` tag in s. It returns nil when the struct is
+// valid, ValidationErrors when rules fail, or another error for bad input.
+func (v *Validator) Validate(s interface{}) error {
+	value := reflect.ValueOf(s)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return fmt.Errorf("cannot validate nil pointer")
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("value must be a struct, got %v", value.Kind())
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var errs ValidationErrors
+	if err := v.validateStructValue(value, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (v *Validator) validateStructValue(value reflect.Value, prefix string, errs *ValidationErrors) error {
+	structType := value.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+
+		var rules []string
+		if tag != "" {
+			rules = strings.Split(tag, ",")
+		}
+		if err := v.validateValue(value.Field(i), value, path, rules, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateValue(field, parent reflect.Value, path string, rules []string, errs *ValidationErrors) error {
+	var diveRules []string
+	for i, rule := range rules {
+		if strings.TrimSpace(rule) == "dive" {
+			diveRules = rules[i+1:]
+			rules = rules[:i]
+			break
+		}
+	}
+
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if rule == "omitempty" {
+			if field.IsZero() {
+				return nil
+			}
+			continue
+		}
+
+		name, param, _ := strings.Cut(rule, "=")
+		fn, ok := v.rules[name]
+		if !ok {
+			return fmt.Errorf("unknown validation rule %q on %s", name, path)
+		}
+		if numericParamRules[name] {
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				return fmt.Errorf("invalid validation rule %s on %s: %q is not a number", name, path, param)
+			}
+		}
+
+		fl := FieldLevel{Field: field, Parent: parent, Param: param, Path: path}
+		if !fn(fl) {
+			*errs = append(*errs, v.newFieldError(fl, name))
+		}
+	}
+
+	// Descend into nested structs, following pointers
+	inner := field
+	for inner.Kind() == reflect.Ptr || inner.Kind() == reflect.Interface {
+		if inner.IsNil() {
+			return nil
+		}
+		inner = inner.Elem()
+	}
+
+	switch inner.Kind() {
+	case reflect.Struct:
+		return v.validateStructValue(inner, path, errs)
+	case reflect.Slice, reflect.Array:
+		if diveRules == nil {
+			return nil
+		}
+		for i := 0; i < inner.Len(); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if err := v.validateValue(inner.Index(i), parent, elemPath, diveRules, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if diveRules == nil {
+			return nil
+		}
+		keys := inner.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			elemPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+			if err := v.validateValue(inner.MapIndex(key), parent, elemPath, diveRules, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) newFieldError(fl FieldLevel, code string) FieldError {
+	var value interface{}
+	if fl.Field.CanInterface() {
+		value = fl.Field.Interface()
+	}
+
+	tmpl := v.lookupMessage(code)
+	message := strings.NewReplacer(
+		"{field}", fl.Path,
+		"{param}", fl.Param,
+		"{value}", fmt.Sprint(value),
+		"{code}", code,
+	).Replace(tmpl)
+
+	return FieldError{Field: fl.Path, Code: code, Param: fl.Param, Value: value, Message: message}
+}
+
+func (v *Validator) lookupMessage(code string) string {
+	for _, locale := range []string{v.locale, "en"} {
+		if tmpl, ok := v.messages[locale][code]; ok {
+			return tmpl
+		}
+	}
+	for _, locale := range []string{v.locale, "en"} {
+		if tmpl, ok := v.messages[locale]["default"]; ok {
+			return tmpl
+		}
+	}
+	return "{field} failed the {code} rule"
+}
+
+var defaultValidator = NewValidator()
+
+// Order demonstrates nested, dive and cross-field validation
+type Order struct {
+	ID        string            `validate:"required"`
+	Email     string            `validate:"required,email"`
+	Status    string            `validate:"oneof=pending paid shipped"`
+	Password  string            `validate:"required,min=8"`
+	Confirm   string            `validate:"eqfield=Password"`
+	Shipping  string            `validate:"required_if=Status shipped"`
+	Items     []OrderItem       `validate:"required,min=1,dive"`
+	Labels    map[string]string `validate:"dive,required"`
+	Reference *ServerConfig
+}
+
+// OrderItem is an element of Order.Items
+type OrderItem struct {
+	SKU      string `validate:"required,len=6"`
+	Quantity int    `validate:"min=1,max=100"`
+	Coupon   string `validate:"omitempty,even_length"`
+}
+
+func demonstrateAdvancedValidation() {
+	validator := NewValidator()
+	validator.RegisterValidation("even_length", func(fl FieldLevel) bool {
+		return fl.Field.Kind() == reflect.String && len(fl.Field.String())%2 == 0
+	})
+	validator.RegisterMessages("en", map[string]string{"even_length": "{field} must have an even length, got {value}"})
+	validator.RegisterMessages("zh", map[string]string{"even_length": "{field} 长度必须为偶数, 当前为 {value}"})
+
+	order := Order{
+		ID:       "ord-1",
+		Email:    "not-an-email",
+		Status:   "shipped",
+		Password: "secret123",
+		Confirm:  "secret321",
+		Items: []OrderItem{
+			{SKU: "ABC123", Quantity: 2},
+			{SKU: "XY", Quantity: 500, Coupon: "odd"},
+		},
+		Labels:    map[string]string{"env": "prod", "team": ""},
+		Reference: &ServerConfig{Host: "", Port: 80},
+	}
+
+	for _, locale := range []string{"en", "zh"} {
+		validator.SetLocale(locale)
+		fmt.Printf("\nValidating order (locale=%s):\n", locale)
+
+		err := validator.Validate(order)
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, fe := range validationErrs {
+				fmt.Printf("  - [%s] %s\n", fe.Code, fe.Message)
+			}
+		} else if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+		} else {
+			fmt.Println("  ✅ All validations passed")
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/cache"
+)
+
+func main() {
+	fmt.Println("🎯 Go Caching Learning")
+	fmt.Println("======================")
+
+	cache.RunAllCacheExamples()
+}
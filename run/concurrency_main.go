@@ -7,11 +7,11 @@ import (
 	"time"
 
 	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/format"
 )
 
 func main() {
-	fmt.Println("Go Concurrency Examples")
-	fmt.Println("======================")
+	format.Stdout.Title("", "Go Concurrency Examples")
 
 	if len(os.Args) > 1 {
 		// Run specific example based on command line argument
@@ -39,13 +39,25 @@ func runSpecificExample(example string) {
 		concurrency.RunAllMutexExamples()
 	case "context":
 		concurrency.RunAllContextExamples()
+	case "contextutils":
+		concurrency.RunAllContextUtilityExamples()
 	case "workers":
 		concurrency.RunAllWorkerPoolExamples()
 	case "fan":
 		concurrency.RunAllFanPatternExamples()
+	case "stealing":
+		concurrency.WorkStealingExample()
+	case "priority":
+		concurrency.WorkerPoolWithPriority()
+	case "parallel":
+		concurrency.ParallelMapExample()
+	case "pubsub":
+		concurrency.PubSubExample()
+	case "simulation":
+		concurrency.SimulationExamples()
 	default:
-		fmt.Printf("Unknown example: %s\n", example)
-		fmt.Println("Available examples: goroutines, channels, select, waitgroups, mutexes, context, workers, fan")
+		format.Stdout.Error("Unknown example: %s", example)
+		format.Stdout.Hint("Available examples: goroutines, channels, select, waitgroups, mutexes, context, contextutils, workers, fan, stealing, priority, parallel, pubsub, simulation")
 	}
 }
 
@@ -63,8 +75,10 @@ func runAllExamples() {
 		{"WaitGroups", concurrency.RunAllWaitGroupExamples},
 		{"Mutexes", concurrency.RunAllMutexExamples},
 		{"Context", concurrency.RunAllContextExamples},
+		{"Context Utilities", concurrency.RunAllContextUtilityExamples},
 		{"Worker Pools", concurrency.RunAllWorkerPoolExamples},
 		{"Fan Patterns", concurrency.RunAllFanPatternExamples},
+		{"Deterministic Simulation", concurrency.SimulationExamples},
 	}
 
 	for i, example := range examples {
@@ -82,19 +96,20 @@ func runAllExamples() {
 
 // Interactive mode for running examples
 func runInteractiveMode() {
-	fmt.Println("Interactive Concurrency Examples")
-	fmt.Println("================================")
-	fmt.Println("Available examples:")
-	fmt.Println("1. Goroutines")
-	fmt.Println("2. Channels")
-	fmt.Println("3. Select Statements")
-	fmt.Println("4. WaitGroups")
-	fmt.Println("5. Mutexes")
-	fmt.Println("6. Context")
-	fmt.Println("7. Worker Pools")
-	fmt.Println("8. Fan Patterns")
-	fmt.Println("9. Run All")
-	fmt.Println("0. Exit")
+	format.Stdout.Title("", "Interactive Concurrency Examples")
+	format.Stdout.Heading("", "Available examples:")
+	format.Stdout.Options(
+		"1", "Goroutines",
+		"2", "Channels",
+		"3", "Select Statements",
+		"4", "WaitGroups",
+		"5", "Mutexes",
+		"6", "Context",
+		"7", "Worker Pools",
+		"8", "Fan Patterns",
+		"9", "Run All",
+		"0", "Exit",
+	)
 
 	for {
 		fmt.Print("\nEnter your choice (0-9): ")
@@ -124,15 +139,20 @@ func runInteractiveMode() {
 			fmt.Println("Goodbye!")
 			return
 		default:
-			fmt.Println("Invalid choice. Please enter 0-9.")
+			format.Stdout.Error("Invalid choice. Please enter 0-9.")
 		}
 	}
 }
 
-// Benchmark mode for performance testing
+// Benchmark mode for performance testing. It compares the channel worker
+// pool, the work-stealing scheduler and the priority pool, then times each
+// demo under a watchdog that dumps goroutine stacks if it hangs, and a leak
+// check that lists goroutines it left running.
 func runBenchmarkMode() {
-	fmt.Println("Benchmark Mode")
-	fmt.Println("==============")
+	format.Stdout.Title("", "Benchmark Mode")
+
+	concurrency.CompareSchedulers(0)
+	fmt.Println()
 
 	// Simple benchmark for different patterns
 	patterns := []struct {
@@ -145,37 +165,71 @@ func runBenchmarkMode() {
 		{"WaitGroups", concurrency.RunAllWaitGroupExamples},
 		{"Mutexes", concurrency.RunAllMutexExamples},
 		{"Context", concurrency.RunAllContextExamples},
+		{"Context Utilities", concurrency.RunAllContextUtilityExamples},
 		{"Worker Pools", concurrency.RunAllWorkerPoolExamples},
 		{"Fan Patterns", concurrency.RunAllFanPatternExamples},
+		{"Deterministic Simulation", concurrency.SimulationExamples},
 	}
 
+	type result struct {
+		name     string
+		duration time.Duration
+		overran  bool
+		leaked   []concurrency.Goroutine
+	}
+	var results []result
 	for _, pattern := range patterns {
-		start := time.Now()
-		pattern.function()
-		duration := time.Since(start)
-		fmt.Printf("%s completed in: %v\n", pattern.name, duration)
+		var r result
+		r.name = pattern.name
+		r.leaked = concurrency.LeakCheck(time.Second, func() {
+			start := time.Now()
+			r.overran = concurrency.Watchdog(os.Stderr, 30*time.Second, pattern.function)
+			r.duration = time.Since(start)
+		})
+		fmt.Printf("%s completed in: %v\n", pattern.name, r.duration)
+		results = append(results, r)
+	}
+
+	fmt.Println()
+	format.Stdout.Title("", "Summary")
+	for _, r := range results {
+		status := "ok"
+		if r.overran {
+			status = "overran watchdog"
+		}
+		fmt.Printf("%-18s %12v  %s, %d goroutines leaked\n", r.name, r.duration.Round(time.Millisecond), status, len(r.leaked))
+		for _, g := range r.leaked {
+			fmt.Printf("    %v\n", g)
+		}
 	}
 }
 
 // Usage information
 func printUsage() {
-	fmt.Println("Go Concurrency Examples")
-	fmt.Println("=======================")
-	fmt.Println("Usage:")
-	fmt.Println("  go run concurrency_main.go                    # Run all examples")
-	fmt.Println("  go run concurrency_main.go <example>          # Run specific example")
-	fmt.Println("  go run concurrency_main.go interactive        # Interactive mode")
-	fmt.Println("  go run concurrency_main.go benchmark          # Benchmark mode")
-	fmt.Println("")
-	fmt.Println("Available examples:")
-	fmt.Println("  goroutines  - Basic goroutine spawning and management")
-	fmt.Println("  channels    - Channel operations (unbuffered, buffered, directional)")
-	fmt.Println("  select      - Select statements for non-blocking operations")
-	fmt.Println("  waitgroups  - WaitGroup synchronization patterns")
-	fmt.Println("  mutexes     - Mutex and RWMutex for shared state protection")
-	fmt.Println("  context     - Context for cancellation and timeouts")
-	fmt.Println("  workers     - Worker pool patterns for concurrent processing")
-	fmt.Println("  fan         - Fan-in/Fan-out data pipeline patterns")
+	format.Stdout.Title("", "Go Concurrency Examples")
+	format.Stdout.Heading("", "Usage:")
+	format.Stdout.Options(
+		"go run concurrency_main.go", "Run all examples",
+		"go run concurrency_main.go <example>", "Run specific example",
+		"go run concurrency_main.go interactive", "Interactive mode",
+		"go run concurrency_main.go benchmark", "Benchmark mode, with leak and hang detection",
+	)
+	format.Stdout.Heading("", "Available examples:")
+	format.Stdout.Options(
+		"goroutines", "Basic goroutine spawning and management",
+		"channels", "Channel operations (unbuffered, buffered, directional)",
+		"select", "Select statements for non-blocking operations",
+		"waitgroups", "WaitGroup synchronization patterns",
+		"mutexes", "Mutex and RWMutex for shared state protection",
+		"context", "Context for cancellation and timeouts",
+		"contextutils", "Merged/detached contexts and typed context values",
+		"workers", "Worker pool patterns for concurrent processing",
+		"fan", "Fan-in/Fan-out data pipeline patterns",
+		"stealing", "Work-stealing scheduler running a fork-join task tree",
+		"parallel", "Order-preserving parallel map",
+		"pubsub", "Topic-based publish/subscribe",
+		"simulation", "Timing-dependent examples on a virtual clock",
+	)
 }
 
 // Check if running in interactive mode
@@ -214,8 +268,7 @@ func init() {
 
 // Example of how to run specific concurrency patterns programmatically
 func demonstrateSpecificPatterns() {
-	fmt.Println("Demonstrating Specific Patterns")
-	fmt.Println("==============================")
+	format.Stdout.Title("", "Demonstrating Specific Patterns")
 
 	// Example 1: Simple goroutine with channel communication
 	fmt.Println("\n1. Simple Goroutine Communication:")
@@ -29,7 +29,16 @@ func main() {
 	}
 
 	// Change to the examples directory and run main.go
-	cmd := exec.Command("go", "run", "main.go")
+	// Arguments go on to the examples binary, e.g. convert a.yaml a.toml;
+	// relative paths are relative to here, not to the examples directory
+	args := []string{"run", "main.go"}
+	for _, arg := range os.Args[1:] {
+		if arg != "convert" && !filepath.IsAbs(arg) {
+			arg = filepath.Join(currentDir, arg)
+		}
+		args = append(args, arg)
+	}
+	cmd := exec.Command("go", args...)
 	cmd.Dir = configExamplesPath
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -47,4 +56,5 @@ func main() {
 	fmt.Println("\nTo run examples directly:")
 	fmt.Printf("  cd %s\n", configExamplesPath)
 	fmt.Println("  go run main.go")
+	fmt.Println("  go run main.go convert production.toml production.yaml")
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jerrychou/go-practice/cron"
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/format"
+)
+
+func main() {
+	fmt.Println("🎯 Go Cron Scheduler Learning")
+	fmt.Println("=============================")
+
+	cron.RunAllCronExamples()
+
+	fmt.Println("\n=== Persistent Run History (SQLite) ===")
+	if err := runHistoryExample(); err != nil {
+		fmt.Printf("❌ History example failed: %v\n", err)
+	}
+}
+
+// runHistoryExample restarts a scheduler on the same database to show a
+// missed run being made up from the stored history
+func runHistoryExample() error {
+	dir, err := os.MkdirTemp("", "gopractice-cron")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "cron.db"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	format.SetPackageLevel("database", format.WarnLevel)
+	defer format.SetPackageLevel("database", format.InfoLevel)
+	if err := database.NewMigrationManager(db).SetDialect("sqlite3").MigrateUp(); err != nil {
+		return err
+	}
+	history := database.NewCronHistory(db)
+	ctx := context.Background()
+
+	// A previous process ran the job three minutes ago, then went down
+	last := time.Now().Add(-3 * time.Minute).Truncate(time.Minute)
+	if err := history.Record(ctx, cron.RunRecord{
+		Job: "backup", Trigger: cron.TriggerSchedule,
+		ScheduledAt: last, StartedAt: last, FinishedAt: last.Add(2 * time.Second),
+	}); err != nil {
+		return err
+	}
+
+	scheduler := cron.New(cron.Options{History: history})
+	scheduler.Add("backup", "* * * * *", func(ctx context.Context) error {
+		return nil
+	}, cron.WithMissedRuns(cron.MissedRunOnce))
+	if err := scheduler.Start(ctx); err != nil {
+		return err
+	}
+	time.Sleep(50 * time.Millisecond)
+	scheduler.Trigger("backup")
+	time.Sleep(50 * time.Millisecond)
+	scheduler.Stop()
+
+	runs, err := scheduler.History(ctx, "backup", 10)
+	if err != nil {
+		return err
+	}
+	fmt.Println("💾 backup runs stored in cron_runs (newest first):")
+	for _, run := range runs {
+		fmt.Printf("   #%d %-9s scheduled %s\n", run.ID, run.Trigger, run.ScheduledAt.Local().Format("15:04:05"))
+	}
+	lastScheduled, err := history.LastScheduled(ctx, "backup")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("⏱️  next start catches up from %s (manual runs do not count)\n", lastScheduled.Local().Format("15:04"))
+	return nil
+}
@@ -2,44 +2,47 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 
 	_ "github.com/go-sql-driver/mysql" // MySQL driver
+	_ "github.com/lib/pq"              // PostgreSQL driver
+	_ "github.com/mattn/go-sqlite3"    // SQLite driver
+
 	"github.com/jerrychou/go-practice/database"
-	_ "github.com/lib/pq"           // PostgreSQL driver
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"github.com/jerrychou/go-practice/format"
 )
 
+var logger = format.GetLogger("main")
+
 func main() {
-	log.Println("=== Database Operations Demo ===")
+	logger.Info("=== Database Operations Demo ===")
 
 	// Create database examples instance
 	examples := database.NewDatabaseExamples()
 
 	// Run all examples
 	if err := examples.RunAllExamples(); err != nil {
-		log.Fatalf("Failed to run database examples: %v", err)
+		logger.Fatalf("Failed to run database examples: %v", err)
 	}
 
 	// Demonstrate with SQLite (if available)
 	if err := demonstrateWithSQLite(); err != nil {
-		log.Printf("SQLite demonstration failed: %v", err)
-		log.Println("This is expected if SQLite driver is not installed")
+		logger.Warnf("SQLite demonstration failed: %v", err)
+		logger.Info("This is expected if SQLite driver is not installed")
 	}
 
 	// Demonstrate with PostgreSQL (if available)
 	if err := demonstrateWithPostgreSQL(); err != nil {
-		log.Printf("PostgreSQL demonstration failed: %v", err)
-		log.Println("This is expected if PostgreSQL is not running")
+		logger.Warnf("PostgreSQL demonstration failed: %v", err)
+		logger.Info("This is expected if PostgreSQL is not running")
 	}
 
-	log.Println("=== Database Operations Demo Completed ===")
+	logger.Info("=== Database Operations Demo Completed ===")
 }
 
 // demonstrateWithSQLite demonstrates database operations with SQLite
 func demonstrateWithSQLite() error {
-	log.Println("\n--- SQLite Demonstration ---")
+	logger.Info("\n--- SQLite Demonstration ---")
 
 	// Create SQLite database
 	dbPath := "test.db"
@@ -78,13 +81,23 @@ func demonstrateWithSQLite() error {
 		return fmt.Errorf("failed to run transaction examples: %w", err)
 	}
 
-	log.Println("SQLite demonstration completed successfully")
+	// Run query profiler examples on a database of their own
+	if err := examples.RunQueryProfilerExamples(); err != nil {
+		return fmt.Errorf("failed to run query profiler examples: %w", err)
+	}
+
+	// Run enum, JSON and array column examples through GORM
+	if err := examples.RunColumnTypeExamples(); err != nil {
+		return fmt.Errorf("failed to run column type examples: %w", err)
+	}
+
+	logger.Info("SQLite demonstration completed successfully")
 	return nil
 }
 
 // demonstrateWithPostgreSQL demonstrates database operations with PostgreSQL
 func demonstrateWithPostgreSQL() error {
-	log.Println("\n--- PostgreSQL Demonstration ---")
+	logger.Info("\n--- PostgreSQL Demonstration ---")
 
 	// Get PostgreSQL configuration
 	config := database.GetDefaultPostgreSQLConfig()
@@ -112,38 +125,38 @@ func demonstrateWithPostgreSQL() error {
 		return fmt.Errorf("failed to run connection pool examples: %w", err)
 	}
 
-	log.Println("PostgreSQL demonstration completed successfully")
+	logger.Info("PostgreSQL demonstration completed successfully")
 	return nil
 }
 
 // demonstrateDatabaseDrivers demonstrates different database drivers
 func demonstrateDatabaseDrivers() {
-	log.Println("\n--- Database Drivers Demonstration ---")
+	logger.Info("\n--- Database Drivers Demonstration ---")
 
 	// Demonstrate PostgreSQL configuration
 	postgresConfig := database.GetDefaultPostgreSQLConfig()
-	log.Printf("PostgreSQL Config: %+v", postgresConfig)
+	logger.Infof("PostgreSQL Config: %+v", postgresConfig)
 
 	// Demonstrate MySQL configuration
 	mysqlConfig := database.GetDefaultMySQLConfig()
-	log.Printf("MySQL Config: %+v", mysqlConfig)
+	logger.Infof("MySQL Config: %+v", mysqlConfig)
 
 	// Demonstrate connection pool configurations
 	defaultPoolConfig := database.GetDefaultPoolConfig()
-	log.Printf("Default Pool Config: %+v", defaultPoolConfig)
+	logger.Infof("Default Pool Config: %+v", defaultPoolConfig)
 
 	highPerfPoolConfig := database.GetHighPerformancePoolConfig()
-	log.Printf("High Performance Pool Config: %+v", highPerfPoolConfig)
+	logger.Infof("High Performance Pool Config: %+v", highPerfPoolConfig)
 
 	lowResourcePoolConfig := database.GetLowResourcePoolConfig()
-	log.Printf("Low Resource Pool Config: %+v", lowResourcePoolConfig)
+	logger.Infof("Low Resource Pool Config: %+v", lowResourcePoolConfig)
 
-	log.Println("Database drivers demonstration completed")
+	logger.Info("Database drivers demonstration completed")
 }
 
 // demonstrateORMOperations demonstrates ORM operations
 func demonstrateORMOperations() {
-	log.Println("\n--- ORM Operations Demonstration ---")
+	logger.Info("\n--- ORM Operations Demonstration ---")
 
 	// Note: This would require GORM to be installed
 	// go get gorm.io/gorm
@@ -151,59 +164,59 @@ func demonstrateORMOperations() {
 	// go get gorm.io/driver/mysql
 	// go get gorm.io/driver/sqlite
 
-	log.Println("To demonstrate ORM operations:")
-	log.Println("1. Install GORM: go get gorm.io/gorm")
-	log.Println("2. Install GORM drivers: go get gorm.io/driver/postgres")
-	log.Println("3. Set up database connection")
-	log.Println("4. Run ORM examples")
+	logger.Info("To demonstrate ORM operations:")
+	logger.Info("1. Install GORM: go get gorm.io/gorm")
+	logger.Info("2. Install GORM drivers: go get gorm.io/driver/postgres")
+	logger.Info("3. Set up database connection")
+	logger.Info("4. Run ORM examples")
 
-	log.Println("ORM operations demonstration completed")
+	logger.Info("ORM operations demonstration completed")
 }
 
 // printUsage prints usage information
 func printUsage() {
-	log.Println("\n=== Database Operations Usage ===")
-	log.Println("This demo showcases various database operations in Go:")
-	log.Println("")
-	log.Println("1. SQL Basics:")
-	log.Println("   - Query execution")
-	log.Println("   - CRUD operations")
-	log.Println("   - Complex queries with WHERE, ORDER BY, etc.")
-	log.Println("")
-	log.Println("2. Database Drivers:")
-	log.Println("   - PostgreSQL driver")
-	log.Println("   - MySQL driver")
-	log.Println("   - SQLite driver")
-	log.Println("   - Connection configuration")
-	log.Println("")
-	log.Println("3. ORM Basics (GORM):")
-	log.Println("   - Model definitions")
-	log.Println("   - CRUD operations")
-	log.Println("   - Associations (one-to-one, one-to-many)")
-	log.Println("   - Migrations")
-	log.Println("   - Soft deletes")
-	log.Println("")
-	log.Println("4. Connection Pooling:")
-	log.Println("   - Connection pool management")
-	log.Println("   - Health monitoring")
-	log.Println("   - Performance benchmarking")
-	log.Println("   - Timeout handling")
-	log.Println("")
-	log.Println("5. Migrations:")
-	log.Println("   - Schema management")
-	log.Println("   - Version control")
-	log.Println("   - Up/Down migrations")
-	log.Println("   - Rollback operations")
-	log.Println("")
-	log.Println("6. Transactions:")
-	log.Println("   - ACID operations")
-	log.Println("   - Transaction isolation")
-	log.Println("   - Rollback handling")
-	log.Println("   - Nested transactions")
-	log.Println("")
-	log.Println("To run with real databases:")
-	log.Println("1. Install required drivers")
-	log.Println("2. Set up database connections")
-	log.Println("3. Update connection strings")
-	log.Println("4. Run the examples")
+	logger.Info("\n=== Database Operations Usage ===")
+	logger.Info("This demo showcases various database operations in Go:")
+	logger.Info("")
+	logger.Info("1. SQL Basics:")
+	logger.Info("   - Query execution")
+	logger.Info("   - CRUD operations")
+	logger.Info("   - Complex queries with WHERE, ORDER BY, etc.")
+	logger.Info("")
+	logger.Info("2. Database Drivers:")
+	logger.Info("   - PostgreSQL driver")
+	logger.Info("   - MySQL driver")
+	logger.Info("   - SQLite driver")
+	logger.Info("   - Connection configuration")
+	logger.Info("")
+	logger.Info("3. ORM Basics (GORM):")
+	logger.Info("   - Model definitions")
+	logger.Info("   - CRUD operations")
+	logger.Info("   - Associations (one-to-one, one-to-many)")
+	logger.Info("   - Migrations")
+	logger.Info("   - Soft deletes")
+	logger.Info("")
+	logger.Info("4. Connection Pooling:")
+	logger.Info("   - Connection pool management")
+	logger.Info("   - Health monitoring")
+	logger.Info("   - Performance benchmarking")
+	logger.Info("   - Timeout handling")
+	logger.Info("")
+	logger.Info("5. Migrations:")
+	logger.Info("   - Schema management")
+	logger.Info("   - Version control")
+	logger.Info("   - Up/Down migrations")
+	logger.Info("   - Rollback operations")
+	logger.Info("")
+	logger.Info("6. Transactions:")
+	logger.Info("   - ACID operations")
+	logger.Info("   - Transaction isolation")
+	logger.Info("   - Rollback handling")
+	logger.Info("   - Nested transactions")
+	logger.Info("")
+	logger.Info("To run with real databases:")
+	logger.Info("1. Install required drivers")
+	logger.Info("2. Set up database connections")
+	logger.Info("3. Update connection strings")
+	logger.Info("4. Run the examples")
 }
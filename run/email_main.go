@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/email"
+)
+
+func main() {
+	fmt.Println("🎯 Go Email Learning")
+	fmt.Println("====================")
+
+	email.RunAllEmailExamples()
+}
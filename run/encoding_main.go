@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/encoding"
+)
+
+func main() {
+	fmt.Println("🎯 Go Encoding Learning")
+	fmt.Println("=======================")
+
+	encoding.RunAllEncodingExamples()
+}
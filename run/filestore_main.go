@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/filestore"
+)
+
+func main() {
+	fmt.Println("🎯 Go Object Storage Learning")
+	fmt.Println("=============================")
+
+	filestore.RunAllFilestoreExamples()
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/jerrychou/go-practice/cli"
+)
+
+// A single entry point for every module, e.g.
+//
+//	go run run/gopractice_main.go net tcp-server --port 9000
+//	go run run/gopractice_main.go db migrate --dsn practice.db
+//	go run run/gopractice_main.go help
+func main() {
+	cli.NewGoPracticeApp().Main()
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/grpc"
+)
+
+func main() {
+	fmt.Println("🎯 Go gRPC Learning")
+	fmt.Println("===================")
+
+	// Interceptor logs are noisy next to the demo output
+	format.SetPackageLevel("grpc", format.WarnLevel)
+
+	grpc.RunAllGRPCExamples()
+}
@@ -8,12 +8,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jerrychou/go-practice/format"
 	http "github.com/jerrychou/go-practice/http"
 )
 
 func main() {
-	fmt.Println("🚀 Go HTTP Package Demo")
-	fmt.Println("========================")
+	format.Stdout.Title("🚀", "Go HTTP Package Demo")
 
 	for {
 		showMenu()
@@ -38,24 +38,26 @@ func main() {
 			fmt.Println("👋 Goodbye!")
 			return
 		default:
-			fmt.Println("❌ Invalid selection, please try again")
+			format.Stdout.Error("Invalid selection, please try again")
 		}
 
-		fmt.Println("\nPress Enter to continue...")
+		format.Stdout.Hint("\nPress Enter to continue...")
 		bufio.NewReader(os.Stdin).ReadLine()
 	}
 }
 
 func showMenu() {
-	fmt.Println("\n📋 Available Operations:")
-	fmt.Println("1. 🌐 Start HTTP Server")
-	fmt.Println("2. 📡 Basic HTTP Client Examples")
-	fmt.Println("3. 🔧 Advanced HTTP Client Examples")
-	fmt.Println("4. 🐙 GitHub API Examples")
-	fmt.Println("5. 🛠️  HTTP Utility Functions Examples")
-	fmt.Println("6. 📄 JSON Utility Functions Examples")
-	fmt.Println("7. 🎯 Run All Examples")
-	fmt.Println("8. 🚪 Exit")
+	format.Stdout.Heading("📋", "Available Operations:")
+	format.Stdout.Options(
+		"1", "🌐 Start HTTP Server",
+		"2", "📡 Basic HTTP Client Examples",
+		"3", "🔧 Advanced HTTP Client Examples",
+		"4", "🐙 GitHub API Examples",
+		"5", "🛠️  HTTP Utility Functions Examples",
+		"6", "📄 JSON Utility Functions Examples",
+		"7", "🎯 Run All Examples",
+		"8", "🚪 Exit",
+	)
 }
 
 func getUserInput(prompt string) string {
@@ -80,22 +82,22 @@ func startHTTPServer() {
 }
 
 func demoBasicClient() {
-	fmt.Println("\n📡 Basic HTTP Client Examples")
-	fmt.Println("=============================")
+	fmt.Println()
+	format.Stdout.Title("📡", "Basic HTTP Client Examples")
 
 	http.ExampleBasicRequests()
 }
 
 func demoAdvancedClient() {
-	fmt.Println("\n🔧 Advanced HTTP Client Examples")
-	fmt.Println("=================================")
+	fmt.Println()
+	format.Stdout.Title("🔧", "Advanced HTTP Client Examples")
 
 	http.ExampleAdvancedClient()
 }
 
 func demoGitHubAPI() {
-	fmt.Println("\n🐙 GitHub API Examples")
-	fmt.Println("======================")
+	fmt.Println()
+	format.Stdout.Title("🐙", "GitHub API Examples")
 
 	useAuth := getUserInput("Use GitHub authentication? (y/n): ")
 
@@ -118,22 +120,22 @@ func demoGitHubAPI() {
 }
 
 func demoHTTPUtils() {
-	fmt.Println("\n🛠️  HTTP Utility Functions Examples")
-	fmt.Println("===================================")
+	fmt.Println()
+	format.Stdout.Title("🛠️", "HTTP Utility Functions Examples")
 
 	http.ExampleHTTPUtils()
 }
 
 func demoJSONUtils() {
-	fmt.Println("\n📄 JSON Utility Functions Examples")
-	fmt.Println("===================================")
+	fmt.Println()
+	format.Stdout.Title("📄", "JSON Utility Functions Examples")
 
 	http.ExampleJSONUtils()
 }
 
 func demoAllExamples() {
-	fmt.Println("\n🎯 Running All Examples")
-	fmt.Println("=======================")
+	fmt.Println()
+	format.Stdout.Title("🎯", "Running All Examples")
 
 	fmt.Println("1. Basic HTTP Client Examples")
 	http.ExampleBasicRequests()
@@ -158,12 +160,13 @@ func demoAllExamples() {
 	fmt.Println("\n5. JSON Utility Functions Examples")
 	http.ExampleJSONUtils()
 
-	fmt.Println("\n✅ All examples completed!")
+	fmt.Println()
+	format.Stdout.Success("All examples completed!")
 }
 
 func interactiveServerDemo() {
-	fmt.Println("\n🌐 Interactive Server Demo")
-	fmt.Println("==========================")
+	fmt.Println()
+	format.Stdout.Title("🌐", "Interactive Server Demo")
 
 	port := getUserInput("Please enter port number (default 8080): ")
 	if port == "" {
@@ -187,14 +190,14 @@ func interactiveServerDemo() {
 }
 
 func customRequestDemo() {
-	fmt.Println("\n🔧 Custom Request Demo")
-	fmt.Println("======================")
+	fmt.Println()
+	format.Stdout.Title("🔧", "Custom Request Demo")
 
 	method := getUserInput("Request method (GET/POST/PUT/DELETE): ")
 	url := getUserInput("Request URL: ")
 
 	if method == "" || url == "" {
-		fmt.Println("❌ Method and URL cannot be empty")
+		format.Stdout.Error("Method and URL cannot be empty")
 		return
 	}
 
@@ -217,7 +220,7 @@ func customRequestDemo() {
 	fmt.Println("\nSending request...")
 	resp, err := http.MakeRequest(options)
 	if err != nil {
-		fmt.Printf("❌ Request failed: %v\n", err)
+		format.Stdout.Error("Request failed: %v", err)
 		return
 	}
 
@@ -226,8 +229,8 @@ func customRequestDemo() {
 }
 
 func batchRequestDemo() {
-	fmt.Println("\n📦 Batch Request Demo")
-	fmt.Println("=====================")
+	fmt.Println()
+	format.Stdout.Title("📦", "Batch Request Demo")
 
 	urls := []string{
 		"https://httpbin.org/get",
@@ -263,11 +266,12 @@ func batchRequestDemo() {
 	duration := time.Since(startTime)
 
 	if err != nil {
-		fmt.Printf("❌ Batch request failed: %v\n", err)
+		format.Stdout.Error("Batch request failed: %v", err)
 		return
 	}
 
-	fmt.Printf("\n✅ Batch request completed! Total time: %s\n", http.FormatDuration(duration))
+	fmt.Println()
+	format.Stdout.Success("Batch request completed! Total time: %s", http.FormatDuration(duration))
 	for i, resp := range responses {
 		fmt.Printf("Request %d: Status %d, Time %s\n",
 			i+1, resp.StatusCode, http.FormatDuration(resp.Duration))
@@ -275,8 +279,8 @@ func batchRequestDemo() {
 }
 
 func retryRequestDemo() {
-	fmt.Println("\n🔄 Retry Request Demo")
-	fmt.Println("=====================")
+	fmt.Println()
+	format.Stdout.Title("🔄", "Retry Request Demo")
 
 	url := getUserInput("Request URL (suggest using a URL that will fail to demonstrate retry): ")
 	if url == "" {
@@ -310,10 +314,10 @@ func retryRequestDemo() {
 	duration := time.Since(startTime)
 
 	if err != nil {
-		fmt.Printf("❌ Retry request finally failed: %v\n", err)
+		format.Stdout.Error("Retry request finally failed: %v", err)
 		fmt.Printf("Total time: %s\n", http.FormatDuration(duration))
 	} else {
-		fmt.Printf("✅ Retry request succeeded!\n")
+		format.Stdout.Success("Retry request succeeded!")
 		fmt.Printf("Status code: %d\n", resp.StatusCode)
 		fmt.Printf("Total time: %s\n", http.FormatDuration(duration))
 	}
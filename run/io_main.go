@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/io_ops"
+)
+
+func main() {
+	fmt.Println("🎯 Go File IO Utilities Learning")
+	fmt.Println("================================")
+
+	io_ops.RunAllIOExamples()
+}
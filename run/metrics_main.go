@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/metrics"
+)
+
+func main() {
+	fmt.Println("🎯 Go Metrics Learning")
+	fmt.Println("======================")
+
+	metrics.RunAllMetricsExamples()
+}
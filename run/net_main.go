@@ -1,23 +1,69 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/format"
 	"github.com/jerrychou/go-practice/net"
+	"github.com/jerrychou/go-practice/server"
+	"github.com/jerrychou/go-practice/testingutil"
 )
 
+var logger = format.GetLogger("main")
+
 func main() {
-	mode := flag.String("mode", "demo", "Mode to run: demo, url, network, tcp-server, tcp-client, udp-server, udp-client, chat, broadcast, multicast")
-	address := flag.String("address", "localhost", "Server address")
+	mode := flag.String("mode", "demo", "Mode to run: demo, url, network, tcp-server, tcp-client, udp-server, udp-client, chat, broadcast, multicast, forward, rendezvous, punch, punch-demo, frame-server, frame-check, frame-soak")
+	address := flag.String("address", "localhost", "Server address; IPv6 literals such as ::1 work, and \"\" listens on IPv4 and IPv6")
 	port := flag.String("port", "8080", "Server port")
+	status := flag.String("status", "", "Serve connection statistics as JSON at http://<addr>/debug/net, e.g. -status=:9090")
+	watch := flag.Duration("watch", 0, "Redraw a live connection statistics table at this interval, e.g. -watch=1s")
+	iface := flag.String("interface", "", "Multicast mode: interface to join the group on, needed for IPv6 link-local groups such as ff02::1")
+	var fwd forwardFlags
+	flag.StringVar(&fwd.target, "target", "", "Forward mode: host:port to forward connections to; frame-soak mode: frame echo server to soak instead of an in-process one")
+	flag.BoolVar(&fwd.tls, "tls", false, "Forward mode: connect to the target over TLS")
+	flag.StringVar(&fwd.socks5, "socks5", "", "Forward mode: reach the target through this SOCKS5 proxy (host:port)")
+	flag.IntVar(&fwd.maxConns, "max-conns", 0, "TCP server, chat and forward modes: maximum concurrent connections (0 = unlimited)")
+	connRate := flag.Int("conn-rate", 0, "TCP server and chat modes: new connections allowed per minute from each IP (0 = unlimited)")
+	flag.DurationVar(&fwd.idleTimeout, "idle-timeout", 5*time.Minute, "Forward mode: close connections idle for this long (0 = never)")
+	var sock socketFlags
+	flag.DurationVar(&sock.keepAlive, "keepalive", 0, "TCP modes: idle time before keep-alive probes (0 = Go's default of 15s, negative = no keep-alives)")
+	flag.DurationVar(&sock.keepAliveInterval, "keepalive-interval", 0, "TCP modes: time between keep-alive probes (0 = default)")
+	flag.IntVar(&sock.keepAliveCount, "keepalive-count", 0, "TCP modes: unanswered keep-alive probes before the connection is dropped (0 = default)")
+	flag.BoolVar(&sock.noDelay, "nodelay", true, "TCP modes: set TCP_NODELAY; false lets Nagle's algorithm batch small writes")
+	flag.BoolVar(&sock.reusePort, "reuseport", false, "TCP server and chat modes: set SO_REUSEPORT so several servers can share the port")
+	flag.StringVar(&sock.readBuffer, "rcvbuf", "", "TCP modes: socket receive buffer, e.g. 256KiB (empty = OS default)")
+	flag.StringVar(&sock.writeBuffer, "sndbuf", "", "TCP modes: socket send buffer, e.g. 256KiB (empty = OS default)")
+	flag.DurationVar(&sock.linger, "linger", -1, "TCP modes: SO_LINGER, how long Close waits to send unsent data (0 = reset at once, negative = OS default)")
+	var punch punchFlags
+	flag.StringVar(&punch.id, "id", "", "Punch mode: this peer's name")
+	flag.StringVar(&punch.peer, "peer", "", "Punch mode: peer to connect to; empty waits for one to connect")
+	flag.BoolVar(&punch.relay, "relay", false, "Punch mode: skip hole punching and relay through the rendezvous server")
+	var frames frameFlags
+	flag.Int64Var(&frames.seed, "seed", 0, "Frame check and soak modes: random seed, printed with failures to replay them (0 = from the clock)")
+	flag.IntVar(&frames.cases, "cases", 1000, "Frame check mode: random cases per property")
+	flag.IntVar(&frames.clients, "clients", 1000, "Frame soak mode: concurrent clients")
+	flag.IntVar(&frames.frames, "frames", 20, "Frame soak mode: frames each client sends")
 	flag.Parse()
 
-	fmt.Println("🌐 Go Network Package Demo")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🌐", "Go Network Package Demo")
+
+	if *status != "" {
+		go serveStats(*status)
+	}
+	if *watch > 0 {
+		go net.WatchStats(context.Background(), os.Stdout, *watch)
+	}
 
 	switch *mode {
 	case "demo":
@@ -27,29 +73,42 @@ func main() {
 	case "network":
 		runNetworkDemo()
 	case "tcp-server":
-		runTCPServer(*address, *port)
+		runTCPServer(*address, *port, sock.options(), connMiddleware(fwd.maxConns, *connRate)...)
 	case "tcp-client":
-		runTCPClient(*address, *port)
+		runTCPClient(*address, *port, sock.options())
 	case "udp-server":
 		runUDPServer(*address, *port)
 	case "udp-client":
 		runUDPClient(*address, *port)
 	case "chat":
-		runChatServer(*address, *port)
+		runChatServer(*address, *port, sock.options(), connMiddleware(fwd.maxConns, *connRate)...)
 	case "broadcast":
 		runBroadcastServer(*address, *port)
 	case "multicast":
-		runMulticastServer(*address, *port)
+		runMulticastServer(*address, *port, *iface)
+	case "forward":
+		runForwarder(*address, *port, fwd)
+	case "rendezvous":
+		runRendezvousServer(*address, *port)
+	case "punch":
+		runHolePunchPeer(*address, *port, punch)
+	case "punch-demo":
+		runHolePunchDemo()
+	case "frame-server":
+		runFrameEchoServer(*address, *port, sock.options(), connMiddleware(fwd.maxConns, *connRate)...)
+	case "frame-check":
+		runFrameCheck(frames)
+	case "frame-soak":
+		runFrameSoak(fwd.target, frames, sock.options())
 	default:
-		fmt.Printf("❌ Unknown mode: %s\n", *mode)
-		fmt.Println("Available modes: demo, url, network, tcp-server, tcp-client, udp-server, udp-client, client, chat, broadcast, multicast")
+		format.Stdout.Error("Unknown mode: %s", *mode)
+		format.Stdout.Hint("Available modes: demo, url, network, tcp-server, tcp-client, udp-server, udp-client, client, chat, broadcast, multicast, forward, rendezvous, punch, punch-demo, frame-server, frame-check, frame-soak")
 		os.Exit(1)
 	}
 }
 
 func runDemo() {
-	fmt.Println("🎯 Running Complete Demo")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🎯", "Running Complete Demo")
 
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	net.DemonstrateURLOperations()
@@ -64,38 +123,113 @@ func runDemo() {
 	net.DemonstrateUDPOperations()
 
 	fmt.Println("\n🎉 Demo completed!")
-	fmt.Println("\n💡 To run specific demos:")
-	fmt.Println("  go run run/net_main.go -mode=url")
-	fmt.Println("  go run run/net_main.go -mode=network")
-	fmt.Println("  go run run/net_main.go -mode=tcp-server")
-	fmt.Println("  go run run/net_main.go -mode=udp-server")
+	format.Stdout.Heading("💡", "To run specific demos:")
+	format.Stdout.Commands(
+		"go run run/net_main.go -mode=url",
+		"go run run/net_main.go -mode=network",
+		"go run run/net_main.go -mode=tcp-server",
+		"go run run/net_main.go -mode=tcp-server -keepalive=30s -keepalive-interval=10s -keepalive-count=3 -nodelay=false -reuseport",
+		"go run run/net_main.go -mode=udp-server",
+		"go run run/net_main.go -mode=forward -port=5433 -target=db.internal:5432",
+		"go run run/net_main.go -mode=chat -watch=1s -status=:9090",
+		"go run run/net_main.go -mode=punch-demo",
+		"go run run/net_main.go -mode=frame-check -cases=10000",
+		"go run run/net_main.go -mode=frame-soak -clients=5000",
+	)
 }
 
 func runURLDemo() {
-	fmt.Println("🔗 URL Operations Demo")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🔗", "URL Operations Demo")
 	net.DemonstrateURLOperations()
 }
 
 func runNetworkDemo() {
-	fmt.Println("🌐 Network Operations Demo")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🌐", "Network Operations Demo")
 	net.DemonstrateNetworkOperations()
 	net.PrintNetworkInfo()
 }
 
-func runTCPServer(address, port string) {
-	fmt.Printf("🔌 Starting TCP Server on %s:%s\n", address, port)
+// serveStats serves the running servers' statistics over HTTP
+func serveStats(addr string) {
+	router := server.NewRouter()
+	router.Get("/debug/net", server.NetStatsHandler)
+	fmt.Printf("📊 Connection statistics at http://%s/debug/net\n", addr)
+	if err := http.ListenAndServe(addr, router); err != nil {
+		logger.Errorf("❌ Statistics endpoint failed: %v", err)
+	}
+}
+
+// connMiddleware builds the limits chosen by -max-conns and -conn-rate
+func connMiddleware(maxConns, perMinute int) []net.ConnMiddleware {
+	var middleware []net.ConnMiddleware
+	if perMinute > 0 {
+		counter := cache.NewMemory(cache.MemoryOptions{MaxEntries: 100000})
+		middleware = append(middleware, net.RateLimitConns(counter, perMinute, time.Minute))
+	}
+	if maxConns > 0 {
+		middleware = append(middleware, net.MaxConns(maxConns))
+	}
+	return middleware
+}
+
+// socketFlags are the socket options chosen with -keepalive, -nodelay and
+// the rest
+type socketFlags struct {
+	keepAlive         time.Duration
+	keepAliveInterval time.Duration
+	keepAliveCount    int
+	noDelay           bool
+	reusePort         bool
+	readBuffer        string
+	writeBuffer       string
+	linger            time.Duration
+}
+
+func (f socketFlags) options() []net.SocketOption {
+	var opts []net.SocketOption
+	if f.keepAlive < 0 {
+		opts = append(opts, net.WithoutKeepAlive())
+	} else if f.keepAlive > 0 || f.keepAliveInterval > 0 || f.keepAliveCount > 0 {
+		opts = append(opts, net.WithKeepAlive(f.keepAlive, f.keepAliveInterval, f.keepAliveCount))
+	}
+	if !f.noDelay {
+		opts = append(opts, net.WithNoDelay(false))
+	}
+	if f.reusePort {
+		opts = append(opts, net.WithReusePort())
+	}
+	if f.readBuffer != "" || f.writeBuffer != "" {
+		opts = append(opts, net.WithBufferSizes(parseBufferFlag("rcvbuf", f.readBuffer), parseBufferFlag("sndbuf", f.writeBuffer)))
+	}
+	if f.linger >= 0 {
+		opts = append(opts, net.WithLinger(f.linger))
+	}
+	return opts
+}
+
+func parseBufferFlag(name, value string) int {
+	if value == "" {
+		return 0
+	}
+	n, err := format.ParseBytes(value)
+	if err != nil {
+		logger.Fatalf("❌ Invalid -%s: %v", name, err)
+	}
+	return int(n)
+}
+
+func runTCPServer(address, port string, opts []net.SocketOption, middleware ...net.ConnMiddleware) {
+	fmt.Printf("🔌 Starting TCP Server on %s\n", net.HostPort(address, port))
 	fmt.Println("Press Ctrl+C to stop the server")
 
-	server := net.NewTCPServer(address, port)
+	server := net.NewTCPServer(address, port, opts...).Use(middleware...)
 	if err := server.Start(); err != nil {
-		log.Fatalf("❌ Failed to start TCP server: %v", err)
+		logger.Fatalf("❌ Failed to start TCP server: %v", err)
 	}
 }
 
-func runTCPClient(address, port string) {
-	fmt.Printf("🔗 Starting TCP Client to %s:%s\n", address, port)
+func runTCPClient(address, port string, opts []net.SocketOption) {
+	fmt.Printf("🔗 Starting TCP Client to %s\n", net.HostPort(address, port))
 
 	messages := []string{
 		"Hello, TCP Server!",
@@ -105,23 +239,95 @@ func runTCPClient(address, port string) {
 		"quit",
 	}
 
-	if err := net.SimpleEchoClient(address, port, messages); err != nil {
-		log.Fatalf("❌ TCP Client error: %v", err)
+	if err := net.SimpleEchoClient(address, port, messages, opts...); err != nil {
+		logger.Fatalf("❌ TCP Client error: %v", err)
+	}
+}
+
+// frameFlags configures the frame check and soak modes
+type frameFlags struct {
+	seed    int64
+	cases   int
+	clients int
+	frames  int
+}
+
+func runFrameEchoServer(address, port string, opts []net.SocketOption, middleware ...net.ConnMiddleware) {
+	fmt.Printf("🔁 Starting frame echo server on %s\n", net.HostPort(address, port))
+	fmt.Println("Press Ctrl+C to stop the server")
+
+	server := net.NewFrameEchoServer(address, port, opts...).Use(middleware...)
+	if err := server.Start(); err != nil {
+		logger.Fatalf("❌ Failed to start frame echo server: %v", err)
+	}
+}
+
+func runFrameCheck(f frameFlags) {
+	seed := f.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	fmt.Printf("🧪 Frame conformance checks, seed %d\n", seed)
+
+	failed := false
+	for _, check := range (net.FrameConformance{Seed: seed, Cases: f.cases}).Run() {
+		fmt.Println(check)
+		failed = failed || !check.OK()
+	}
+	for i, input := range net.FrameFuzzCorpus() {
+		if err := net.FuzzFrameDecoder(input); err != nil {
+			fmt.Printf("❌ fuzz corpus entry %d: %v\n", i, err)
+			failed = true
+		}
+	}
+	if failed {
+		format.Stdout.Hint("Replay with -seed=%d", seed)
+		os.Exit(1)
+	}
+}
+
+func runFrameSoak(target string, f frameFlags, opts []net.SocketOption) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	soak := net.SoakOptions{Clients: f.clients, Frames: f.frames, Seed: f.seed, Socket: net.NewSocketOptions(opts...)}
+
+	var report net.SoakReport
+	var leaks []string
+	if target != "" {
+		fmt.Printf("🏋️  Soaking %s with %d clients × %d frames\n", target, f.clients, f.frames)
+		report = net.SoakFrameEcho(ctx, target, soak)
+	} else {
+		fmt.Printf("🏋️  Soaking an in-process frame echo server with %d clients × %d frames\n", f.clients, f.frames)
+		before := testingutil.Snapshot()
+		var err error
+		if report, err = net.SoakFrameEchoServer(ctx, soak); err != nil {
+			logger.Fatalf("❌ Frame echo server failed: %v", err)
+		}
+		// The idle-timeout timing wheel is shared and outlives servers
+		leaks = testingutil.LeakedGoroutines(before, 2*time.Second, "data_structure.(*TimingWheel)")
+	}
+
+	fmt.Println(report)
+	for _, leak := range leaks {
+		fmt.Printf("❌ leaked goroutine:\n%s\n", leak)
+	}
+	if !report.OK() || len(leaks) > 0 {
+		os.Exit(1)
 	}
 }
 
 func runUDPServer(address, port string) {
-	fmt.Printf("📡 Starting UDP Server on %s:%s\n", address, port)
+	fmt.Printf("📡 Starting UDP Server on %s\n", net.HostPort(address, port))
 	fmt.Println("Press Ctrl+C to stop the server")
 
 	server := net.NewUDPServer(address, port)
 	if err := server.Start(); err != nil {
-		log.Fatalf("❌ Failed to start UDP server: %v", err)
+		logger.Fatalf("❌ Failed to start UDP server: %v", err)
 	}
 }
 
 func runUDPClient(address, port string) {
-	fmt.Printf("📤 Starting UDP Client to %s:%s\n", address, port)
+	fmt.Printf("📤 Starting UDP Client to %s\n", net.HostPort(address, port))
 
 	messages := []string{
 		"Hello, UDP Server!",
@@ -132,34 +338,34 @@ func runUDPClient(address, port string) {
 	}
 
 	if err := net.SimpleUDPEchoClient(address, port, messages); err != nil {
-		log.Fatalf("❌ UDP Client error: %v", err)
+		logger.Fatalf("❌ UDP Client error: %v", err)
 	}
 }
 
-func runChatServer(address, port string) {
-	fmt.Printf("💬 Starting Chat Server on %s:%s\n", address, port)
+func runChatServer(address, port string, opts []net.SocketOption, middleware ...net.ConnMiddleware) {
+	fmt.Printf("💬 Starting Chat Server on %s\n", net.HostPort(address, port))
 	fmt.Println("Multiple clients can connect and chat with each other")
 	fmt.Println("Press Ctrl+C to stop the server")
 
-	chatServer := net.NewChatServer(address, port)
+	chatServer := net.NewChatServer(address, port, opts...).Use(middleware...)
 	if err := chatServer.Start(); err != nil {
-		log.Fatalf("❌ Failed to start chat server: %v", err)
+		logger.Fatalf("❌ Failed to start chat server: %v", err)
 	}
 }
 
 func runBroadcastServer(address, port string) {
-	fmt.Printf("📡 Starting Broadcast Server on %s:%s\n", address, port)
+	fmt.Printf("📡 Starting Broadcast Server on %s\n", net.HostPort(address, port))
 	fmt.Println("Server will broadcast messages to all network interfaces")
 	fmt.Println("Press Ctrl+C to stop the server")
 
 	broadcastServer := net.NewBroadcastServer(address, port)
 	if err := broadcastServer.Start(); err != nil {
-		log.Fatalf("❌ Failed to start broadcast server: %v", err)
+		logger.Fatalf("❌ Failed to start broadcast server: %v", err)
 	}
 }
 
-func runMulticastServer(address, port string) {
-	fmt.Printf("📺 Starting Multicast Server on %s:%s\n", address, port)
+func runMulticastServer(address, port, iface string) {
+	fmt.Printf("📺 Starting Multicast Server on %s\n", net.HostPort(address, port))
 	fmt.Println("Server will send multicast messages")
 	fmt.Println("Press Ctrl+C to stop the server")
 
@@ -168,14 +374,14 @@ func runMulticastServer(address, port string) {
 	}
 
 	multicastServer := net.NewMulticastServer(address, port)
+	multicastServer.Interface = iface
 	if err := multicastServer.Start(); err != nil {
-		log.Fatalf("❌ Failed to start multicast server: %v", err)
+		logger.Fatalf("❌ Failed to start multicast server: %v", err)
 	}
 }
 
 func exampleUsage() {
-	fmt.Println("📚 Example Usage:")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("📚", "Example Usage:")
 
 	fmt.Println("\n🔗 URL Operations:")
 	fmt.Println("  // Parse a URL")
@@ -223,3 +429,181 @@ func exampleUsage() {
 	fmt.Println("  response, addr, _ := client.ReadResponse()")
 	fmt.Println("  fmt.Printf(\"Response from %s: %s\\n\", addr, response)")
 }
+
+type forwardFlags struct {
+	target      string
+	tls         bool
+	socks5      string
+	maxConns    int
+	idleTimeout time.Duration
+}
+
+func runForwarder(address, port string, opts forwardFlags) {
+	if opts.target == "" {
+		logger.Fatalf("❌ Forward mode needs -target=host:port")
+	}
+	fmt.Printf("🔀 Starting Forwarder on %s to %s\n", net.HostPort(address, port), opts.target)
+	fmt.Println("Press Ctrl+C to stop the forwarder")
+
+	forwarder := net.NewForwarder(net.HostPort(address, port), opts.target)
+	forwarder.MaxConns = opts.maxConns
+	forwarder.IdleTimeout = opts.idleTimeout
+	if opts.tls {
+		forwarder.TLS = &tls.Config{}
+	}
+	if opts.socks5 != "" {
+		forwarder.Dialer = net.NewSOCKS5Dialer(opts.socks5)
+		fmt.Printf("🧦 Via SOCKS5 proxy %s\n", opts.socks5)
+	}
+
+	// Print the totals on Ctrl+C
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		forwarder.Stop()
+	}()
+
+	if err := forwarder.Start(); err != nil {
+		logger.Fatalf("❌ Failed to start forwarder: %v", err)
+	}
+	conns, rejected, up, down := forwarder.Totals()
+	fmt.Printf("📊 %d connections forwarded, %d rejected, %d bytes up, %d down\n", conns, rejected, up, down)
+}
+
+type punchFlags struct {
+	id    string
+	peer  string
+	relay bool
+}
+
+func runRendezvousServer(address, port string) {
+	fmt.Printf("🤝 Starting Rendezvous Server on %s\n", net.HostPort(address, port))
+	fmt.Println("Peers register here, learn their public address and get introduced")
+	fmt.Println("Press Ctrl+C to stop the server")
+
+	rendezvous := net.NewRendezvousServer(address, port)
+	if err := rendezvous.Start(); err != nil {
+		logger.Fatalf("❌ Failed to start rendezvous server: %v", err)
+	}
+}
+
+// runHolePunchPeer registers with the rendezvous server at address:port,
+// then either connects to -peer or waits for a peer, and exchanges lines
+// typed on stdin with it
+func runHolePunchPeer(address, port string, opts punchFlags) {
+	if opts.id == "" {
+		logger.Fatalf("❌ Punch mode needs -id=name")
+	}
+	client := net.NewHolePunchClient(opts.id, net.HostPort(address, port))
+	client.ForceRelay = opts.relay
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := client.Start(ctx)
+	cancel()
+	if err != nil {
+		logger.Fatalf("❌ Failed to register: %v", err)
+	}
+	defer client.Close()
+	fmt.Printf("🌍 %s is %s publicly, %s locally\n", opts.id, client.PublicAddr(), client.LocalAddr())
+
+	var session *net.PeerSession
+	if opts.peer != "" {
+		fmt.Printf("🔨 Punching through to %s...\n", opts.peer)
+		session, err = client.Connect(context.Background(), opts.peer)
+	} else {
+		fmt.Println("⏳ Waiting for a peer to connect...")
+		session, err = client.Accept(context.Background())
+	}
+	if err != nil {
+		logger.Fatalf("❌ Failed to reach peer: %v", err)
+	}
+	printSession(opts.id, session)
+
+	go func() {
+		for {
+			payload, err := session.Receive(context.Background())
+			if err != nil {
+				return
+			}
+			fmt.Printf("📥 %s: %s\n", session.PeerID, payload)
+		}
+	}()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if err := session.Send(scanner.Bytes()); err != nil {
+			format.Stdout.Error("%v", err)
+		}
+	}
+}
+
+// runHolePunchDemo runs a rendezvous server and three peers on this
+// machine. There is no NAT on loopback, so alice and bob punch through at
+// once; carol relays as if she were behind a symmetric NAT.
+func runHolePunchDemo() {
+	format.Stdout.Title("🕳️", "UDP Hole Punching Demo")
+
+	rendezvous := net.NewRendezvousServer("127.0.0.1", "0")
+	go rendezvous.Start()
+	defer rendezvous.Stop()
+	for rendezvous.Addr() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	server := rendezvous.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers := map[string]*net.HolePunchClient{}
+	for _, id := range []string{"alice", "bob", "carol"} {
+		client := net.NewHolePunchClient(id, server)
+		client.ForceRelay = id == "carol"
+		client.PunchTimeout = time.Second
+		if err := client.Start(ctx); err != nil {
+			logger.Fatalf("❌ %s failed to register: %v", id, err)
+		}
+		defer client.Close()
+		fmt.Printf("🌍 %s discovered its public address %s\n", id, client.PublicAddr())
+		peers[id] = client
+	}
+
+	exchange := func(from, to string) {
+		outgoing, err := peers[from].Connect(ctx, to)
+		if err != nil {
+			logger.Errorf("❌ %s could not reach %s: %v", from, to, err)
+			return
+		}
+		incoming, err := peers[to].Accept(ctx)
+		if err != nil {
+			logger.Errorf("❌ %s never heard from %s: %v", to, from, err)
+			return
+		}
+		printSession(from, outgoing)
+		printSession(to, incoming)
+
+		outgoing.Send([]byte("hello from " + from))
+		if payload, err := incoming.Receive(ctx); err == nil {
+			fmt.Printf("📥 %s got: %s\n", to, payload)
+		}
+		incoming.Send([]byte("hi " + from + ", " + to + " here"))
+		if payload, err := outgoing.Receive(ctx); err == nil {
+			fmt.Printf("📥 %s got: %s\n", from, payload)
+		}
+	}
+
+	fmt.Println("\n🔨 alice -> bob")
+	exchange("alice", "bob")
+	fmt.Println("\n🔨 carol -> alice (relay fallback)")
+	exchange("carol", "alice")
+
+	fmt.Println("\n🔨 alice -> dave")
+	if _, err := peers["alice"].Connect(ctx, "dave"); err != nil {
+		format.Stdout.Error("%v", err)
+	}
+}
+
+func printSession(self string, session *net.PeerSession) {
+	if session.Relayed() {
+		fmt.Printf("📡 %s <-> %s relayed through the rendezvous server\n", self, session.PeerID)
+	} else {
+		format.Stdout.Success("%s <-> %s direct via %s", self, session.PeerID, session.Addr())
+	}
+}
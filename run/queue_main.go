@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/queue"
+)
+
+func main() {
+	fmt.Println("🎯 Go Message Queue Learning")
+	fmt.Println("============================")
+
+	queue.RunAllQueueExamples()
+
+	fmt.Println("\n=== Transactional Outbox (SQLite) ===")
+	if err := runOutboxExample(); err != nil {
+		fmt.Printf("❌ Outbox example failed: %v\n", err)
+	}
+}
+
+func runOutboxExample() error {
+	dir, err := os.MkdirTemp("", "gopractice-outbox")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "outbox.db"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	format.SetPackageLevel("database", format.WarnLevel)
+	if err := database.NewMigrationManager(db).SetDialect("sqlite3").MigrateUp(); err != nil {
+		return err
+	}
+	format.SetPackageLevel("database", format.InfoLevel)
+
+	return database.NewDatabaseExamples().RunOutboxExamples(db, queue.NewMemory())
+}
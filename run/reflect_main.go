@@ -6,15 +6,19 @@ import (
 	"os"
 	"strings"
 
+	"github.com/jerrychou/go-practice/format"
 	"github.com/jerrychou/go-practice/reflect"
 )
 
 func main() {
-	mode := flag.String("mode", "all", "Mode to run: all, basic, struct, function, interface, practical, utilities")
+	mode := flag.String("mode", "all", "Mode to run: all, basic, struct, function, interface, practical, utilities, codegen")
+	samples := flag.String("samples", "", "Comma-separated JSON sample files for codegen")
+	typeName := flag.String("type", "Root", "Root struct name for codegen")
+	pkg := flag.String("package", "main", "Package of the generated code")
+	out := flag.String("out", "", "File to write generated code to (default: print it)")
 	flag.Parse()
 
-	fmt.Println("🔍 Go Reflection Package Demo")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🔍", "Go Reflection Package Demo")
 
 	switch *mode {
 	case "all":
@@ -31,16 +35,17 @@ func main() {
 		runPracticalExamples()
 	case "utilities":
 		runUtilityExamples()
+	case "codegen":
+		runCodegen(*samples, reflect.GenOptions{Package: *pkg, TypeName: *typeName, Output: *out})
 	default:
-		fmt.Printf("❌ Unknown mode: %s\n", *mode)
-		fmt.Println("Available modes: all, basic, struct, function, interface, practical, utilities")
+		format.Stdout.Error("Unknown mode: %s", *mode)
+		format.Stdout.Hint("Available modes: all, basic, struct, function, interface, practical, utilities, codegen")
 		os.Exit(1)
 	}
 }
 
 func runAllExamples() {
-	fmt.Println("🎯 Running All Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🎯", "Running All Reflection Examples")
 
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	reflect.BasicReflection()
@@ -61,52 +66,49 @@ func runAllExamples() {
 	runUtilityExamples()
 
 	fmt.Println("\n🎉 All examples completed!")
-	fmt.Println("\n💡 To run specific examples:")
-	fmt.Println("  go run run/reflect_main.go -mode=basic")
-	fmt.Println("  go run run/reflect_main.go -mode=struct")
-	fmt.Println("  go run run/reflect_main.go -mode=function")
-	fmt.Println("  go run run/reflect_main.go -mode=interface")
-	fmt.Println("  go run run/reflect_main.go -mode=practical")
-	fmt.Println("  go run run/reflect_main.go -mode=utilities")
+	format.Stdout.Heading("💡", "To run specific examples:")
+	format.Stdout.Commands(
+		"go run run/reflect_main.go -mode=basic",
+		"go run run/reflect_main.go -mode=struct",
+		"go run run/reflect_main.go -mode=function",
+		"go run run/reflect_main.go -mode=interface",
+		"go run run/reflect_main.go -mode=practical",
+		"go run run/reflect_main.go -mode=utilities",
+		"go run run/reflect_main.go -mode=codegen -samples=a.json,b.json -type=Issue -out=issue.go",
+	)
 }
 
 func runBasicExamples() {
-	fmt.Println("🔍 Basic Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🔍", "Basic Reflection Examples")
 	reflect.BasicReflection()
 	reflect.DemonstrateTypeChecker()
 }
 
 func runStructExamples() {
-	fmt.Println("🏗️  Struct Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🏗️", "Struct Reflection Examples")
 	reflect.StructReflection()
 	reflect.DemonstrateStructAnalyzer()
 }
 
 func runFunctionExamples() {
-	fmt.Println("⚙️  Function Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("⚙️", "Function Reflection Examples")
 	reflect.FunctionReflection()
 	reflect.DemonstrateFunctionRegistry()
 }
 
 func runInterfaceExamples() {
-	fmt.Println("🔌 Interface Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🔌", "Interface Reflection Examples")
 	reflect.InterfaceReflection()
 	reflect.DemonstrateInterfaceAnalyzer()
 }
 
 func runPracticalExamples() {
-	fmt.Println("🛠️  Practical Reflection Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🛠️", "Practical Reflection Examples")
 	reflect.PracticalExamples()
 }
 
 func runUtilityExamples() {
-	fmt.Println("🔧 Reflection Utility Examples")
-	fmt.Println(strings.Repeat("=", 50))
+	format.Stdout.Title("🔧", "Reflection Utility Examples")
 
 	fmt.Println("\n📊 TypeChecker Utility:")
 	reflect.DemonstrateTypeChecker()
@@ -120,3 +122,33 @@ func runUtilityExamples() {
 	fmt.Println("\n🔌 InterfaceAnalyzer Utility:")
 	reflect.DemonstrateInterfaceAnalyzer()
 }
+
+// runCodegen generates structs from the sample files and prints them, or
+// writes them to opts.Output
+func runCodegen(files string, opts reflect.GenOptions) {
+	if files == "" {
+		format.Stdout.Error("-samples is required, e.g. -samples=response.json")
+		os.Exit(1)
+	}
+
+	var samples [][]byte
+	for _, file := range strings.Split(files, ",") {
+		data, err := os.ReadFile(strings.TrimSpace(file))
+		if err != nil {
+			format.Stdout.Error("%v", err)
+			os.Exit(1)
+		}
+		samples = append(samples, data)
+	}
+
+	source, err := reflect.GenerateStructs(opts, samples...)
+	if err != nil {
+		format.Stdout.Error("%v", err)
+		os.Exit(1)
+	}
+	if opts.Output != "" {
+		format.Stdout.Success("Wrote %s from %d samples", opts.Output, len(samples))
+		return
+	}
+	fmt.Print(source)
+}
@@ -1,15 +1,32 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/reflect"
 	"github.com/jerrychou/go-practice/security"
+	"github.com/jerrychou/go-practice/string_op"
 )
 
+var logger = format.GetLogger("main")
+
 func main() {
 	fmt.Println("=== Go Security Package Demo ===")
 
@@ -36,6 +53,40 @@ func main() {
 	// Input Validation Demo
 	fmt.Println("\n6. Input Validation Demo")
 	demoInputValidation()
+
+	// Login Workflow Demo
+	fmt.Println("\n7. Login Workflow Demo")
+	demoLoginWorkflow()
+
+	// Signed URL Demo
+	fmt.Println("\n8. Signed URL Demo")
+	demoSignedURLs()
+
+	// IP Filter Demo
+	fmt.Println("\n9. IP Filter Demo")
+	demoIPFilter()
+
+	// Security Headers Demo
+	fmt.Println("\n10. Security Headers Demo")
+	demoSecurityHeaders()
+
+	fmt.Println("\n11. Webhook Verification Demo")
+	demoWebhooks()
+
+	fmt.Println("\n12. CORS Policy Demo")
+	demoCORS()
+
+	fmt.Println("\n13. Password Reset Demo")
+	demoPasswordReset()
+
+	fmt.Println("\n14. Upload Scanning Demo")
+	demoUploadScanning()
+
+	fmt.Println("\n15. Crypto Utilities Demo")
+	demoCryptoUtil()
+
+	fmt.Println("\n16. Audit Log Archive Demo")
+	demoAuditArchive()
 }
 
 func demoJWT() {
@@ -45,7 +96,7 @@ func demoJWT() {
 	// Generate token
 	token, err := jwtAuth.GenerateToken("user123", "john_doe", []string{"user", "admin"}, 24)
 	if err != nil {
-		log.Printf("Error generating token: %v", err)
+		logger.Errorf("Error generating token: %v", err)
 		return
 	}
 
@@ -54,7 +105,7 @@ func demoJWT() {
 	// Validate token
 	claims, err := jwtAuth.ValidateToken(token)
 	if err != nil {
-		log.Printf("Error validating token: %v", err)
+		logger.Errorf("Error validating token: %v", err)
 		return
 	}
 
@@ -63,7 +114,7 @@ func demoJWT() {
 	// Extract user info
 	userID, username, roles, err := jwtAuth.ExtractUserInfo(token)
 	if err != nil {
-		log.Printf("Error extracting user info: %v", err)
+		logger.Errorf("Error extracting user info: %v", err)
 		return
 	}
 
@@ -83,9 +134,10 @@ func demoOAuth() {
 	})
 
 	// Generate auth URL
-	authURL, err := oauthAuth.GetAuthURL(security.GoogleProvider, "random-state")
+	state := oauthAuth.GenerateState()
+	authURL, err := oauthAuth.GetAuthURL(security.GoogleProvider, state)
 	if err != nil {
-		log.Printf("Error generating auth URL: %v", err)
+		logger.Errorf("Error generating auth URL: %v", err)
 		return
 	}
 
@@ -94,7 +146,7 @@ func demoOAuth() {
 	// Validate state parameter
 	err = oauthAuth.ValidateState("random-state", "random-state")
 	if err != nil {
-		log.Printf("State validation error: %v", err)
+		logger.Infof("State validation error: %v", err)
 		return
 	}
 
@@ -166,6 +218,16 @@ func demoRBAC() {
 	// Test resource access
 	fmt.Printf("User can access users resource: %v\n", rbac.CheckResourceAccess("user1", "users", "read"))
 	fmt.Printf("User can access admin resource: %v\n", rbac.CheckResourceAccess("user1", "admin", "write"))
+
+	// Batch checks and introspection, as a frontend would ask for them
+	fmt.Printf("Admin can delete and export reports: %v\n", rbac.CanAll("admin1", "reports:delete", "reports:export"))
+	fmt.Printf("User can read or write users: %v\n", rbac.CanAny("user1", "users:read", "users:write"))
+	summary, _ := rbac.EffectivePermissions("admin1")
+	for resource, grants := range summary.Resources {
+		for _, grant := range grants {
+			fmt.Printf("Admin %s: %s via %v\n", resource, grant.Action, grant.GrantedBy)
+		}
+	}
 }
 
 func demoPasswordHashing() {
@@ -177,7 +239,7 @@ func demoPasswordHashing() {
 	password := "SecurePassword123!"
 	hash, err := passwordManager.HashPassword(password)
 	if err != nil {
-		log.Printf("Error hashing password: %v", err)
+		logger.Errorf("Error hashing password: %v", err)
 		return
 	}
 
@@ -202,7 +264,7 @@ func demoPasswordHashing() {
 	// Generate secure password
 	securePassword, err := passwordManager.GenerateSecurePassword(16)
 	if err != nil {
-		log.Printf("Error generating secure password: %v", err)
+		logger.Errorf("Error generating secure password: %v", err)
 		return
 	}
 
@@ -225,7 +287,7 @@ func demoHTTPS() {
 	// Create server TLS config
 	serverTLSConfig, err := tlsSecurity.CreateServerTLSConfig()
 	if err != nil {
-		log.Printf("Error creating server TLS config: %v", err)
+		logger.Errorf("Error creating server TLS config: %v", err)
 		return
 	}
 
@@ -244,7 +306,7 @@ func demoHTTPS() {
 	// Generate self-signed certificate for development
 	certPEM, keyPEM, err := tlsSecurity.GenerateSelfSignedCert("localhost")
 	if err != nil {
-		log.Printf("Error generating self-signed cert: %v", err)
+		logger.Errorf("Error generating self-signed cert: %v", err)
 		return
 	}
 
@@ -254,7 +316,7 @@ func demoHTTPS() {
 	// Validate certificate
 	err = tlsSecurity.ValidateCertificate(certPEM)
 	if err != nil {
-		log.Printf("Certificate validation error: %v", err)
+		logger.Infof("Certificate validation error: %v", err)
 		return
 	}
 
@@ -306,6 +368,10 @@ func demoInputValidation() {
 	// HTML sanitization
 	sanitizedHTML := validator.SanitizeHTML(testInputs["html"])
 	fmt.Printf("HTML sanitization: %s\n", sanitizedHTML)
+	fmt.Printf("Link sanitization: %s\n", validator.SanitizeHTML(`<a href="java&#9;script:alert(1)" onclick="x()">click</a> <a href="https://example.com">ok</a>`))
+
+	// Markdown rendering
+	fmt.Printf("Markdown rendering: %s\n", validator.RenderMarkdown("**Hi** <img src=x onerror=alert(1)> [docs](https://go.dev)"))
 
 	// SQL injection prevention
 	sanitizedSQL := validator.PreventSQLInjection(testInputs["sql"])
@@ -323,48 +389,434 @@ func securityHeadersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func demoSecurityHeaders() {
-	// Create TLS security instance
-	tlsSecurity := security.NewTLSSecurity()
+	// Pages get the web-app profile, the API the strict one, and the widget
+	// may be framed by a partner site
+	policy := security.NewHeaderPolicy(security.WebAppProfile()).
+		Route("/api/", security.StrictAPIProfile()).
+		Route("/widget", security.EmbeddedIframeProfile("https://partner.example"))
 
-	// Create HTTP server with security headers
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", securityHeadersHandler)
 
-	// Add security headers middleware
-	handler := tlsSecurity.AddSecurityHeaders(mux)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logger.Errorf("Error listening: %v", err)
+		return
+	}
+	server := &http.Server{Handler: policy.Middleware(mux)}
+	go server.Serve(listener)
+	defer server.Close()
+	base := "http://" + listener.Addr().String()
+
+	for _, path := range []string{"/", "/api/users", "/widget"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			logger.Errorf("Error testing server: %v", err)
+			return
+		}
+		resp.Body.Close()
+		fmt.Printf("%-10s CSP: %s\n", path, resp.Header.Get("Content-Security-Policy"))
+	}
+
+	// The self-check compares a live response with a profile
+	issues, err := security.SelfCheck(context.Background(), nil, base+"/widget", security.StrictAPIProfile())
+	if err != nil {
+		logger.Errorf("Error checking headers: %v", err)
+		return
+	}
+	fmt.Printf("/widget against strict-api: %d issues\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("  %s\n", issue)
+	}
+}
+
+func demoLoginWorkflow() {
+	ctx := context.Background()
+	auth := security.NewAuthService(security.NewMemoryUserStore(),
+		security.NewPasswordManager(security.NewBcryptHasher(0)), security.NewJWTAuth("your-secret-key"))
+	auth.MaxFailures = 3
+	auth.Audit = security.AuditLogFunc(func(ctx context.Context, event security.AuditEvent) {
+		fmt.Printf("  audit: %-24s %s %s\n", event.Type, event.Username, event.Detail)
+	})
+	auth.Devices.Audit = auth.Audit
+
+	user, err := auth.Register(ctx, "alice", "SecurePassword123!", "user")
+	if err != nil {
+		logger.Errorf("Error registering user: %v", err)
+		return
+	}
+	fmt.Printf("Registered: %s\n", reflect.Dump(user))
+
+	// Password only
+	result, err := auth.Login(ctx, "alice", "SecurePassword123!")
+	if err != nil {
+		logger.Errorf("Error logging in: %v", err)
+		return
+	}
+	fmt.Printf("Login state: %s, session %s\n", result.State, result.Session.ID)
 
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: handler,
+	// Turn on 2FA; the demo plays the authenticator app
+	secret, uri, err := auth.EnrollTOTP(ctx, result.Token)
+	if err != nil {
+		logger.Errorf("Error enrolling TOTP: %v", err)
+		return
+	}
+	fmt.Printf("Scan this into an authenticator: %s\n", uri)
+	totp, _ := security.NewTOTP(secret)
+	if err := auth.ConfirmTOTP(ctx, result.Token, totp.Code(time.Now())); err != nil {
+		logger.Errorf("Error confirming TOTP: %v", err)
+		return
 	}
+	auth.Logout(ctx, result.Token)
 
-	fmt.Println("Starting server with security headers on :8080")
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Server error: %v", err)
+	// Password, then a code from the next period (the current one was
+	// used to confirm enrollment and would be refused as a replay)
+	result, err = auth.Login(ctx, "alice", "SecurePassword123!")
+	if err != nil {
+		logger.Errorf("Error logging in: %v", err)
+		return
+	}
+	fmt.Printf("Login state: %s\n", result.State)
+	result, err = auth.VerifyTOTP(ctx, result.Challenge, totp.Code(time.Now().Add(totp.Period)))
+	if err != nil {
+		logger.Errorf("Error verifying TOTP: %v", err)
+		return
+	}
+	fmt.Printf("Login state: %s\n", result.State)
+
+	// A password change keeps this session and ends the others
+	if err := auth.ChangePassword(ctx, result.Token, "SecurePassword123!", "EvenBetterPassword456!"); err != nil {
+		logger.Errorf("Error changing password: %v", err)
+		return
+	}
+
+	// Repeated wrong passwords lock the account, even for the right one
+	for i := 0; i < 3; i++ {
+		_, err = auth.Login(ctx, "alice", "guess")
+	}
+	_, err = auth.Login(ctx, "alice", "EvenBetterPassword456!")
+	fmt.Printf("Login after lockout: %v\n", err)
+
+	// A reset lifts the lockout and ends every session; the sender would
+	// normally mail the link
+	var token string
+	auth.Resets.Sender = security.ResetSenderFunc(func(ctx context.Context, notice security.ResetNotice) error {
+		token = notice.Token
+		return nil
+	})
+	if err := auth.RequestPasswordReset(ctx, "alice"); err != nil {
+		logger.Errorf("Error requesting a password reset: %v", err)
+		return
+	}
+	if err := auth.ResetPassword(ctx, token, "FreshStart789!"); err != nil {
+		logger.Errorf("Error resetting password: %v", err)
+		return
+	}
+	_, err = auth.Authenticate(ctx, result.Token)
+	fmt.Printf("Old session after reset: %v\n", err)
+
+	// Bob ticks "keep me signed in", then comes back once the session
+	// cookie is gone (logging out would forget the device): the remember-me
+	// token opens a new session and is swapped for a new one
+	auth.Register(ctx, "bob", "AnotherPassword123!", "user")
+	bob, err := auth.Login(ctx, "bob", "AnotherPassword123!")
+	if err != nil {
+		logger.Errorf("Error logging in: %v", err)
+		return
+	}
+	laptop := "Mozilla/5.0 (Macintosh)"
+	deviceToken, device, err := auth.RememberDevice(ctx, bob.Token, "MacBook", laptop)
+	if err != nil {
+		logger.Errorf("Error remembering device: %v", err)
+		return
+	}
+	resumed, nextToken, err := auth.ResumeSession(ctx, deviceToken, laptop)
+	if err != nil {
+		logger.Errorf("Error resuming session: %v", err)
+		return
+	}
+	fmt.Printf("Device %s resumed session %s\n", device.Name, resumed.Session.ID)
+
+	// A token that was already swapped out was copied: the device is
+	// revoked and its sessions end
+	_, _, err = auth.ResumeSession(ctx, deviceToken, laptop)
+	fmt.Printf("Replayed remember-me token: %v\n", err)
+	_, _, err = auth.ResumeSession(ctx, nextToken, laptop)
+	fmt.Printf("Current token after the replay: %v\n", err)
+	_, err = auth.Authenticate(ctx, resumed.Token)
+	fmt.Printf("Session from the revoked device: %v\n", err)
+}
+
+func demoSignedURLs() {
+	signer := security.NewURLSigner([]byte("url-signing-key"))
+
+	link, err := signer.Sign("http://localhost:8080/download/report.pdf", security.SignOptions{
+		Expires: 15 * time.Minute,
+		IP:      "203.0.113.7",
+	})
+	if err != nil {
+		logger.Errorf("Error signing URL: %v", err)
+		return
+	}
+	fmt.Printf("Signed link: %s\n", link)
+
+	u, _ := url.Parse(link)
+	fmt.Printf("GET from the bound IP: %v\n", signer.Verify(http.MethodGet, u, "203.0.113.7"))
+	fmt.Printf("GET from elsewhere: %v\n", signer.Verify(http.MethodGet, u, "198.51.100.1"))
+	fmt.Printf("DELETE with the same link: %v\n", signer.Verify(http.MethodDelete, u, "203.0.113.7"))
+
+	tampered, _ := url.Parse(strings.Replace(link, "report.pdf", "payroll.pdf", 1))
+	fmt.Printf("Tampered path: %v\n", signer.Verify(http.MethodGet, tampered, "203.0.113.7"))
+}
+
+func demoIPFilter() {
+	filter, err := security.NewIPFilter(config.IPFilterConfig{
+		Deny:           []string{"203.0.113.0/24"},
+		Allow:          []string{"10.0.0.0/8"},
+		AllowCountries: []string{"US", "DE"},
+		TrustedProxies: []string{"127.0.0.1"},
+	})
+	if err != nil {
+		logger.Errorf("Error creating IP filter: %v", err)
+		return
+	}
+	// A real deployment would look countries up in a GeoIP database
+	filter.Geo = security.StaticGeoResolver{"198.51.100.0/24": "US", "192.0.2.0/24": "FR"}
+
+	for _, ip := range []string{"10.1.2.3", "203.0.113.9", "198.51.100.7", "192.0.2.44"} {
+		decision := filter.Check(netip.MustParseAddr(ip))
+		fmt.Printf("%-13s allowed=%-5v reason=%s %s\n", ip, decision.Allowed, decision.Reason, decision.Country)
+	}
+
+	// Behind a trusted proxy the client comes from X-Forwarded-For
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:52100"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.7")
+	fmt.Printf("Client behind proxy: %s\n", filter.ClientIP(req))
+}
+
+func demoWebhooks() {
+	body := []byte(`{"action":"opened","number":42}`)
+	github := security.GitHubWebhook{Secret: "github-secret"}
+
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", security.SignGitHubWebhook("github-secret", body))
+	header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	_, err := github.Verify(header, body)
+	fmt.Printf("GitHub delivery: %v\n", err)
+	_, err = github.Verify(header, []byte(`{"action":"closed","number":42}`))
+	fmt.Printf("GitHub delivery with an altered body: %v\n", err)
+
+	stripe := security.NewStripeWebhook("whsec_demo")
+	stale := http.Header{}
+	stale.Set("Stripe-Signature", security.SignStripeWebhook("whsec_demo", body, time.Now().Add(-time.Hour)))
+	_, err = stripe.Verify(stale, body)
+	fmt.Printf("Stripe delivery signed an hour ago: %v\n", err)
+
+	// The middleware remembers deliveries, so a captured one can't be replayed
+	nonces := cache.NewMemory(cache.MemoryOptions{})
+	handler := security.WebhookMiddleware(github, nonces, 24*time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	for _, attempt := range []string{"first delivery", "replay", "replay with a new delivery ID"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(string(body)))
+		req.Header = header.Clone()
+		if attempt == "replay with a new delivery ID" {
+			// X-GitHub-Delivery is not signed, so it does not identify the delivery
+			req.Header.Set("X-GitHub-Delivery", "0b989ba4-242f-11e5-81e1-c7b6966d2516")
 		}
-	}()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		fmt.Printf("%s: %d %s\n", attempt, rec.Code, http.StatusText(rec.Code))
+	}
+}
 
-	// Give server time to start
-	time.Sleep(1 * time.Second)
+func demoCORS() {
+	// Partner subdomains may call the API with cookies; reports are open to
+	// anyone and the admin API to no other site
+	policy := security.NewCORSPolicy(security.CORSRule{
+		AllowedOrigins:   []string{"https://*.partner.example"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}).
+		Route("/reports/", security.CORSRule{AllowedOrigins: []string{"*"}}).
+		Route("/admin/", security.CORSRule{})
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	send := func(method, path, origin string, header http.Header) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header = header
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		fmt.Printf("%-7s %-14s from %-30s %d allow-origin=%q max-age=%q\n", method, path, origin, rec.Code,
+			rec.Header().Get("Access-Control-Allow-Origin"), rec.Header().Get("Access-Control-Max-Age"))
+	}
+	preflight := func(method, headers string) http.Header {
+		return http.Header{"Access-Control-Request-Method": {method}, "Access-Control-Request-Headers": {headers}}
+	}
+
+	send(http.MethodOptions, "/api/orders", "https://shop.partner.example", preflight("DELETE", "Authorization"))
+	send(http.MethodOptions, "/api/orders", "https://partner.example", preflight("DELETE", "Authorization"))
+	send(http.MethodOptions, "/api/orders", "https://shop.partner.example", preflight("PUT", ""))
+	send(http.MethodGet, "/reports/daily", "https://anyone.example", http.Header{})
+	send(http.MethodPost, "/admin/users", "https://shop.partner.example", http.Header{})
 
-	// Test the server
-	resp, err := http.Get("http://localhost:8080/")
+	// In audit-only mode the same request goes through and is only logged
+	policy.AuditOnly = true
+	send(http.MethodPost, "/admin/users", "https://shop.partner.example", http.Header{})
+}
+
+func demoPasswordReset() {
+	ctx := context.Background()
+	var link string
+	resets := security.NewPasswordResetService(security.NewMemoryResetStore(), security.ResetSenderFunc(func(ctx context.Context, notice security.ResetNotice) error {
+		link = notice.Token
+		fmt.Printf("Reset link for %s, valid for %v: /password-reset?token=%s...\n", notice.Account.Email, notice.TTL, notice.Token[:12])
+		return nil
+	}))
+	resets.Audit = nil
+	account := security.ResetAccount{Subject: "alice@example.com", Email: "alice@example.com", Name: "Alice"}
+
+	// Only MaxRequests links are sent per account and window
+	for i := 1; i <= resets.MaxRequests+1; i++ {
+		if err := resets.Request(ctx, account); err != nil {
+			fmt.Printf("Request %d: %v\n", i, err)
+		}
+	}
+
+	// Guessing burns the link after MaxAttempts wrong verifiers
+	selector, _, _ := strings.Cut(link, ".")
+	for i := 1; i <= resets.MaxAttempts; i++ {
+		_, err := resets.Check(ctx, selector+".guess")
+		fmt.Printf("Guess %d: %v\n", i, err)
+	}
+	_, err := resets.Check(ctx, link)
+	fmt.Printf("Real link after guessing: %v\n", err)
+}
+
+func demoAuditArchive() {
+	dir, err := os.MkdirTemp("", "audit")
+	if err != nil {
+		logger.Errorf("Error creating directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	store, err := filestore.NewLocalStore(dir, filestore.LocalOptions{})
+	if err != nil {
+		logger.Errorf("Error opening store: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	archive := security.NewAuditArchiver(store, nil)
+	archive.Retention = 24 * time.Hour
+	auth := security.NewAuthService(security.NewMemoryUserStore(),
+		security.NewPasswordManager(security.NewBcryptHasher(0)), security.NewJWTAuth("your-secret-key"))
+	auth.Audit = archive
+
+	auth.Register(ctx, "carol", "SecurePassword123!", "user")
+	auth.Login(ctx, "carol", "wrong password")
+	auth.Login(ctx, "mallory", "guess")
+	fmt.Printf("Buffered events: %d\n", archive.Pending())
+	if err := archive.Flush(ctx); err != nil {
+		logger.Errorf("Error flushing: %v", err)
+		return
+	}
+	auth.Login(ctx, "carol", "SecurePassword123!")
+	archive.Flush(ctx)
+
+	segments, _ := archive.Segments(ctx, time.Now())
+	for _, segment := range segments {
+		fmt.Printf("Segment %s (%d bytes)\n", segment.Key, segment.Size)
+	}
+	events, err := archive.Events(ctx, time.Now())
 	if err != nil {
-		log.Printf("Error testing server: %v", err)
+		logger.Errorf("Error reading archive: %v", err)
 		return
 	}
-	defer resp.Body.Close()
+	for _, event := range events {
+		fmt.Printf("  %s %-16s %-8s %s\n", event.Time.Format("15:04:05.000"), event.Type, event.Username, event.Detail)
+	}
 
-	fmt.Println("Security headers in response:")
-	for name, values := range resp.Header {
-		if strings.HasPrefix(strings.ToLower(name), "x-") ||
-			strings.ToLower(name) == "strict-transport-security" ||
-			strings.ToLower(name) == "content-security-policy" ||
-			strings.ToLower(name) == "referrer-policy" {
-			fmt.Printf("%s: %v\n", name, values)
+	pruned, err := archive.Prune(ctx, time.Now().Add(48*time.Hour))
+	fmt.Printf("Pruned two days later: %d segments (err=%v)\n", pruned, err)
+}
+
+func demoUploadScanning() {
+	dir, err := os.MkdirTemp("", "scan")
+	if err != nil {
+		logger.Errorf("Error creating directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 50<<20))
+	zw.Close()
+	files := map[string][]byte{
+		"notes.txt":   []byte("meeting at noon"),
+		"logo.svg":    []byte(`<svg xmlns="http://www.w3.org/2000/svg"><circle r="4"/></svg>`),
+		"avatar.svg":  []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="fetch('/api/me')"><circle r="4"/></svg>`),
+		"page.html":   []byte(`<a href="javascript:alert(1)">hi</a>`),
+		"backup.gz":   bomb.Bytes(),
+		"invoice.pdf": []byte("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"),
+	}
+
+	// A stand-in for clamscan: exit 1 and print "path: Signature FOUND"
+	clam := &security.CommandScanner{Command: []string{"sh", "-c",
+		`grep -q EICAR "$0" && echo "$0: Eicar-Test-Signature FOUND" && exit 1; exit 0`, "{}"}}
+	scanner := security.NewUploadScanner(clam)
+	scanner.Audit = security.AuditLogFunc(func(ctx context.Context, event security.AuditEvent) {
+		fmt.Printf("  audit %s: %s\n", event.Type, event.Detail)
+	})
+
+	for _, name := range []string{"notes.txt", "logo.svg", "avatar.svg", "page.html", "backup.gz", "invoice.pdf"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, files[name], 0o600)
+		err := scanner.Scan(context.Background(), security.ScannedFile{Name: name, Path: path, Size: int64(len(files[name]))}, "203.0.113.7", "alice")
+		if err != nil {
+			fmt.Printf("%-12s refused: %v\n", name, err)
+		} else {
+			fmt.Printf("%-12s accepted\n", name)
 		}
 	}
 
-	server.Close()
+	// A scanner that hangs is cut off, and the file refused unless FailOpen
+	slow := security.NewUploadScanner(&security.CommandScanner{Command: []string{"sh", "-c", "exec sleep 5", "{}"}, Timeout: 200 * time.Millisecond})
+	slow.Audit = nil
+	path := filepath.Join(dir, "notes.txt")
+	err = slow.Scan(context.Background(), security.ScannedFile{Name: "notes.txt", Path: path}, "", "")
+	fmt.Printf("Slow scanner: %v\n", err)
+	slow.FailOpen = true
+	fmt.Printf("Slow scanner, fail open: %v\n", slow.Scan(context.Background(), security.ScannedFile{Name: "notes.txt", Path: path}, "", ""))
+}
+
+func demoCryptoUtil() {
+	// One configured secret, independent keys per purpose
+	secret := []byte("a long random secret from the environment")
+	csrfKey, _ := cryptoutil.DeriveKey(secret, nil, "csrf", 32)
+	urlKey, _ := cryptoutil.DeriveKey(secret, nil, "signed-urls", 32)
+	fmt.Printf("Derived keys: csrf %x..., signed-urls %x...\n", csrfKey[:6], urlKey[:6])
+
+	mac := cryptoutil.HMACHex(urlKey, []byte("GET /files/report.pdf 1700000000"))
+	fmt.Printf("HMAC: %s...\n", mac[:16])
+	fmt.Printf("Verify genuine: %t, tampered: %t\n",
+		cryptoutil.VerifyHMACHex(urlKey, []byte("GET /files/report.pdf 1700000000"), mac),
+		cryptoutil.VerifyHMACHex(urlKey, []byte("GET /files/salaries.pdf 1700000000"), mac))
+
+	// Equal takes as long for "" as for an almost right token
+	token := string_op.Token(32)
+	fmt.Printf("Token %s...: equal to itself %t, to a prefix %t, to empty %t\n",
+		token[:8], cryptoutil.Equal(token, token), cryptoutil.Equal(token, token[:40]), cryptoutil.Equal(token, ""))
+
+	salt := string_op.RandomBytes(16)
+	key, _ := cryptoutil.PasswordKey([]byte("correct horse battery staple"), salt, 32)
+	fmt.Printf("Password key: %x... (salt %x...)\n", key[:6], salt[:4])
+	fmt.Printf("Dice roll: %d\n", cryptoutil.RandomInt(6)+1)
 }
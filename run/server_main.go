@@ -1,29 +1,180 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
 	"os"
+	"strconv"
 
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/reflect"
+	"github.com/jerrychou/go-practice/security"
 	"github.com/jerrychou/go-practice/server"
 )
 
+var logger = format.GetLogger("main")
+
+// application holds the wired dependencies of the server binary
+type application struct {
+	Config  *config.EnvConfig         `inject:""`
+	Drivers *database.DatabaseDrivers `inject:""`
+	Server  *server.Server            `inject:""`
+}
+
 func main() {
-	// Get port from environment variable or use default
+	container := reflect.NewContainer()
+
+	// Register constructors; dependencies are resolved by parameter type
+	providers := []interface{}{
+		config.LoadFromEnv,
+		database.NewDatabaseDrivers,
+		newServer,
+	}
+	for _, constructor := range providers {
+		if err := container.Register(constructor, reflect.Singleton); err != nil {
+			logger.Fatal("Failed to register provider", "error", err)
+		}
+	}
+
+	var app application
+	if err := container.InjectFields(&app); err != nil {
+		logger.Fatal("Failed to wire application", "error", err)
+	}
+	defer app.Drivers.CloseAllConnections()
+
+	// Keep cron run history across restarts so missed runs are noticed
+	if path := os.Getenv("CRON_HISTORY_DB"); path != "" {
+		db, err := openCronHistory(path)
+		if err != nil {
+			logger.Fatal("Failed to open cron history", "path", path, "error", err)
+		}
+		defer db.Close()
+	}
+
+	logger.Debugf("Configuration: %s", reflect.Dump(app.Config))
+
+	// Start the server
+	logger.Infof("Starting %s %s on port %s", app.Config.AppName, app.Config.AppVersion, app.Server.Port)
+	if err := app.Server.Start(); err != nil {
+		logger.Fatal("Server failed to start", "error", err)
+	}
+}
+
+// newServer builds the HTTP server from configuration
+func newServer(cfg *config.EnvConfig) *server.Server {
+	// PORT takes precedence over SERVER_PORT for compatibility with hosting platforms
 	port := os.Getenv("PORT")
 	if port == "" {
-		port = "8080"
+		port = strconv.Itoa(cfg.ServerPort)
+	}
+	server.MaxUploadSize = cfg.MaxUploadSize.Bytes()
+
+	// Request scopes authenticate with JWT_SECRET and run transactions on
+	// DATABASE_URL when they are set, where password reset tokens are kept
+	// too; download links are signed with URL_SIGNING_KEY so they survive
+	// restarts, IP_FILTER_CONFIG names a config file whose
+	// security.ip_filter rules are hot reloaded, and GITHUB_WEBHOOK_SECRET
+	// enables /webhooks/github
+	if cfg.JWTSecret != "" {
+		server.Auth = security.NewJWTAuth(cfg.JWTSecret)
+	}
+	if key := os.Getenv("URL_SIGNING_KEY"); key != "" {
+		server.Links = security.NewURLSigner([]byte(key))
+	}
+	server.GitHubWebhookSecret = os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if path := os.Getenv("IP_FILTER_CONFIG"); path != "" {
+		watchIPFilter(path)
+	}
+	if os.Getenv("DATABASE_URL") != "" {
+		db, err := sql.Open("postgres", cfg.DatabaseURL)
+		if err != nil {
+			logger.Fatal("Invalid DATABASE_URL", "error", err)
+		}
+		db.SetMaxOpenConns(cfg.DatabaseMaxConns)
+		server.DB = db
+		server.PasswordResets.SetStore(database.NewPasswordResetStore(db))
+		go watchChanges(db, cfg.DatabaseURL)
+		if spec := os.Getenv("BACKUP_SCHEDULE"); spec != "" {
+			scheduleBackups(cfg.DatabaseURL, spec)
+		}
 	}
 
-	// Create a new server instance
 	srv := server.New(port)
+	srv.SetHandler(server.SetupRoutesWithMiddleware())
+	return srv
+}
 
-	// Setup routes with middleware
-	handler := server.SetupRoutesWithMiddleware()
-	srv.SetHandler(handler)
+// openCronHistory stores the server's cron runs in the SQLite database at
+// path, creating it if needed
+func openCronHistory(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := database.NewMigrationManager(db).SetDialect("sqlite3").MigrateUp(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	server.Cron.SetHistory(database.NewCronHistory(db))
+	return db, nil
+}
 
-	// Start the server
-	log.Printf("Starting server on port %s", port)
-	if err := srv.Start(); err != nil {
-		log.Fatal("Server failed to start:", err)
+// watchIPFilter applies the security.ip_filter section of the config file
+// at path, reloading it whenever the file changes
+func watchIPFilter(path string) {
+	filter, err := security.NewIPFilter(config.IPFilterConfig{})
+	if err != nil {
+		logger.Fatal("Failed to create IP filter", "error", err)
+	}
+	reloadable, err := config.NewReloadableConfig(path, &config.FileConfig{}, nil)
+	if err != nil {
+		logger.Fatal("Failed to load IP filter config", "path", path, "error", err)
+	}
+	if err := reloadable.Reload(); err != nil {
+		logger.Fatal("Failed to load IP filter config", "path", path, "error", err)
+	}
+	// Bad rules stop startup; on later reloads they keep the previous rules
+	if err := filter.OnReload(reloadable.GetConfig()); err != nil {
+		logger.Fatal("Invalid IP filter rules", "path", path, "error", err)
+	}
+	reloadable.AddCallback(filter.OnReload)
+	reloader := config.NewHotReloadManager()
+	if err := reloader.AddConfig("ip_filter", path, reloadable.Reload); err != nil {
+		logger.Fatal("Failed to watch IP filter config", "path", path, "error", err)
+	}
+	if err := reloader.StartAll(context.Background()); err != nil {
+		logger.Fatal("Failed to watch IP filter config", "path", path, "error", err)
+	}
+	server.IPFilter = filter
+	server.Links.ClientIP = filter.ClientIP
+}
+
+// scheduleBackups dumps the database on spec into the FILESTORE_* store,
+// keeping the newest week of daily backups
+func scheduleBackups(dsn, spec string) {
+	store, err := filestore.FromEnv("")
+	if err != nil {
+		logger.Fatal("Failed to open backup store", "error", err)
+	}
+	backups := database.NewPostgresBackupManager(dsn, store)
+	backups.Keep = 7
+	if err := backups.Schedule(server.Cron, "database_backup", spec); err != nil {
+		logger.Fatal("Invalid BACKUP_SCHEDULE", "spec", spec, "error", err)
+	}
+}
+
+// watchChanges streams changes to the users table to /api/changes
+func watchChanges(db *sql.DB, dsn string) {
+	ctx := context.Background()
+	if err := database.NewChangeNotifier(db, "table_changes").Watch(ctx, "users", "id"); err != nil {
+		logger.Error("Live changes disabled", "error", err)
+		return
+	}
+	for event := range database.NewChangeListener(dsn, "table_changes").Listen(ctx) {
+		server.Changes.Publish(event)
 	}
 }
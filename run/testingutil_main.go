@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/testingutil"
+)
+
+func main() {
+	fmt.Println("🎯 Go Test Helpers Learning")
+	fmt.Println("===========================")
+
+	// Keep migration logging out of the example output
+	format.SetPackageLevel("database", format.WarnLevel)
+
+	testingutil.RunAllTestingExamples()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/tracing"
+)
+
+func main() {
+	fmt.Println("🎯 Go Distributed Tracing Learning")
+	fmt.Println("==================================")
+
+	tracing.RunAllTracingExamples()
+
+	fmt.Println("\n=== Traced SQL Queries (SQLite) ===")
+	if err := runSQLTracingExample(); err != nil {
+		fmt.Printf("❌ SQL tracing example failed: %v\n", err)
+	}
+}
+
+func runSQLTracingExample() error {
+	dir, err := os.MkdirTemp("", "gopractice-tracing")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(dir, "tracing.db"))
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	exporter := tracing.NewInMemoryExporter()
+	tracing.SetDefault(tracing.NewProvider(tracing.ProviderOptions{ServiceName: "users", Exporters: []tracing.Exporter{exporter}}))
+	db := database.NewTracedDB(sqlDB, "sqlite")
+
+	ctx, root := tracing.Start(context.Background(), "register_users")
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT UNIQUE)"); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", name); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// A duplicate insert shows a failed query recorded on its span
+	db.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "alice")
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return err
+	}
+	root.End()
+
+	fmt.Printf("👥 %d users inserted\n", count)
+	tracing.PrintSpanTree(exporter.Spans())
+	for _, s := range exporter.Spans() {
+		if statement, ok := s.Attribute("db.statement"); ok && s.Name == "db.select" {
+			fmt.Printf("📝 %s db.statement=%q\n", s.Name, statement)
+		}
+	}
+	return nil
+}
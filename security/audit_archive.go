@@ -0,0 +1,235 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// ErrNoAuditStore is returned by Flush before the archiver has a store
+var ErrNoAuditStore = errors.New("audit archive has no store")
+
+// AuditArchiver is an AuditLog that keeps events in a filestore.Store for
+// later review. Record passes each event on to Next and buffers it; Flush
+// writes the buffer as one gzipped JSON Lines object, keyed by the day and
+// time of its first event:
+//
+//	audit/2026/10/16/20261016T093721.123Z-3f9a1c2e-1.jsonl.gz
+//
+// The random middle part keeps apart the keys of several instances sharing
+// a store. Events stay buffered until a flush succeeds, and a full buffer
+// flushes itself in the background once there is a store.
+type AuditArchiver struct {
+	// Prefix starts every segment key
+	Prefix string
+	// MaxEvents buffered events start a background flush
+	MaxEvents int
+	// Retention is how long Prune keeps segments; zero keeps them forever
+	Retention time.Duration
+	// Next also receives every event as it is recorded; nil for none
+	Next AuditLog
+
+	mu       sync.Mutex
+	store    filestore.Store
+	pending  []AuditEvent
+	flushing bool
+	seq      uint64
+	node     string
+
+	// flushMu keeps segments in order and a failed batch ahead of newer ones
+	flushMu sync.Mutex
+}
+
+// NewAuditArchiver archives to store under "audit/", flushing every 1000
+// events, and forwards events to next, such as LoggerAuditLog. store may be
+// nil until SetStore is called, for stores opened on first use.
+func NewAuditArchiver(store filestore.Store, next AuditLog) *AuditArchiver {
+	return &AuditArchiver{
+		Prefix:    "audit/",
+		MaxEvents: 1000,
+		Next:      next,
+		store:     store,
+		node:      string_op.HexToken(4),
+	}
+}
+
+// SetStore sets where segments are written
+func (a *AuditArchiver) SetStore(store filestore.Store) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.store = store
+}
+
+// Record forwards event to Next and buffers it for the archive
+func (a *AuditArchiver) Record(ctx context.Context, event AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if a.Next != nil {
+		a.Next.Record(ctx, event)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, event)
+	if a.MaxEvents <= 0 || len(a.pending) < a.MaxEvents || a.store == nil || a.flushing {
+		return
+	}
+	a.flushing = true
+	go func() {
+		if err := a.Flush(context.WithoutCancel(ctx)); err != nil {
+			logger.Warn("Failed to archive audit events", "error", err)
+		}
+		a.mu.Lock()
+		a.flushing = false
+		a.mu.Unlock()
+	}()
+}
+
+// Pending returns the number of events not archived yet
+func (a *AuditArchiver) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pending)
+}
+
+// Flush writes the buffered events as one segment. If writing fails they
+// stay buffered, ahead of events recorded since.
+func (a *AuditArchiver) Flush(ctx context.Context) error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	a.mu.Lock()
+	batch, store := a.pending, a.store
+	if len(batch) == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	if store == nil {
+		a.mu.Unlock()
+		return ErrNoAuditStore
+	}
+	a.pending = nil
+	a.seq++
+	key := fmt.Sprintf("%s%s/%s-%s-%d.jsonl.gz", a.Prefix,
+		batch[0].Time.UTC().Format("2006/01/02"), batch[0].Time.UTC().Format("20060102T150405.000Z"), a.node, a.seq)
+	a.mu.Unlock()
+
+	if err := putAuditSegment(ctx, store, key, batch); err != nil {
+		a.mu.Lock()
+		a.pending = append(batch, a.pending...)
+		a.mu.Unlock()
+		return fmt.Errorf("archiving %d audit events: %w", len(batch), err)
+	}
+	return nil
+}
+
+func putAuditSegment(ctx context.Context, store filestore.Store, key string, events []AuditEvent) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err := store.Put(ctx, key, &buf, filestore.PutOptions{ContentType: "application/gzip", Size: int64(buf.Len())})
+	return err
+}
+
+// Segments lists the archived segments of day, in UTC, oldest first
+func (a *AuditArchiver) Segments(ctx context.Context, day time.Time) ([]filestore.ObjectInfo, error) {
+	a.mu.Lock()
+	store := a.store
+	a.mu.Unlock()
+	if store == nil {
+		return nil, ErrNoAuditStore
+	}
+	return store.List(ctx, a.Prefix+day.UTC().Format("2006/01/02/"))
+}
+
+// Events reads back the archived events of day, in UTC, in time order.
+// Events still buffered are not included; Flush first to see them.
+func (a *AuditArchiver) Events(ctx context.Context, day time.Time) ([]AuditEvent, error) {
+	segments, err := a.Segments(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	store := a.store
+	a.mu.Unlock()
+
+	var events []AuditEvent
+	for _, segment := range segments {
+		if events, err = readAuditSegment(ctx, store, segment.Key, events); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", segment.Key, err)
+		}
+	}
+	// Segments of different instances interleave
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+func readAuditSegment(ctx context.Context, store filestore.Store, key string, events []AuditEvent) ([]AuditEvent, error) {
+	body, _, err := store.Get(ctx, key)
+	if err != nil {
+		return events, err
+	}
+	defer body.Close()
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return events, err
+	}
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	for {
+		var event AuditEvent
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+// Prune deletes the segments written more than Retention before now and
+// returns how many it deleted
+func (a *AuditArchiver) Prune(ctx context.Context, now time.Time) (int, error) {
+	a.mu.Lock()
+	store := a.store
+	a.mu.Unlock()
+	if a.Retention <= 0 || store == nil {
+		return 0, nil
+	}
+	segments, err := store.List(ctx, a.Prefix)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.Add(-a.Retention)
+	deleted := 0
+	for _, segment := range segments {
+		if !segment.LastModified.Before(cutoff) {
+			continue
+		}
+		if err := store.Delete(ctx, segment.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
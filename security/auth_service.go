@@ -0,0 +1,771 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+var logger = format.GetLogger("security")
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user or a wrong
+	// password alike, so callers cannot probe which usernames exist
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountLocked is returned while an account is locked after too
+	// many failed attempts
+	ErrAccountLocked = errors.New("account temporarily locked")
+	// ErrInvalidChallenge is returned for an unknown or expired second-step
+	// login challenge
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+	// ErrInvalidTOTP is returned for a wrong or already used 2FA code
+	ErrInvalidTOTP = errors.New("invalid two-factor code")
+	// ErrInvalidSession is returned for an invalid, expired or revoked
+	// session token
+	ErrInvalidSession = errors.New("invalid or expired session")
+	// ErrUserNotFound is returned by a UserStore for an unknown username
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// AuthUser is an account as AuthService sees it
+type AuthUser struct {
+	ID           string
+	Username     string
+	PasswordHash string `sensitive:"true"`
+	Roles        []string
+	// TOTPSecret turns on two-factor login when set
+	TOTPSecret string `sensitive:"true"`
+	// LastTOTPStep is the time step of the last accepted code, which is
+	// refused if replayed
+	LastTOTPStep int64
+	FailedLogins int
+	LockedUntil  time.Time
+}
+
+// UserStore loads and saves the accounts AuthService works with
+type UserStore interface {
+	FindUser(ctx context.Context, username string) (*AuthUser, error)
+	SaveUser(ctx context.Context, user *AuthUser) error
+}
+
+// MemoryUserStore is a UserStore in a map, keyed by lowercase username
+type MemoryUserStore struct {
+	mu    sync.Mutex
+	users map[string]AuthUser
+}
+
+// NewMemoryUserStore creates an empty MemoryUserStore
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[string]AuthUser)}
+}
+
+// FindUser returns a copy of the user, or ErrUserNotFound
+func (m *MemoryUserStore) FindUser(ctx context.Context, username string) (*AuthUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[strings.ToLower(username)]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// SaveUser creates or replaces the user
+func (m *MemoryUserStore) SaveUser(ctx context.Context, user *AuthUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[strings.ToLower(user.Username)] = *user
+	return nil
+}
+
+// AuditType names an event recorded by AuthService, DeviceTokenManager,
+// PasswordResetService, IPFilter, CORSPolicy or UploadScanner
+type AuditType string
+
+const (
+	AuditLoginSucceeded         AuditType = "login_succeeded"
+	AuditLoginFailed            AuditType = "login_failed"
+	AuditTOTPRequired           AuditType = "totp_required"
+	AuditTOTPFailed             AuditType = "totp_failed"
+	AuditAccountLocked          AuditType = "account_locked"
+	AuditLogout                 AuditType = "logout"
+	AuditPasswordChanged        AuditType = "password_changed"
+	AuditPasswordResetRequested AuditType = "password_reset_requested"
+	AuditPasswordReset          AuditType = "password_reset"
+	AuditPasswordResetFailed    AuditType = "password_reset_failed"
+	AuditPasswordResetThrottled AuditType = "password_reset_throttled"
+	AuditTOTPEnabled            AuditType = "totp_enabled"
+	AuditIPBlocked              AuditType = "ip_blocked"
+	AuditCORSRefused            AuditType = "cors_refused"
+	AuditDeviceRemembered       AuditType = "device_remembered"
+	AuditDeviceTokenReused      AuditType = "device_token_reused"
+	AuditDeviceMismatch         AuditType = "device_fingerprint_mismatch"
+	AuditDeviceRevoked          AuditType = "device_revoked"
+	AuditUploadScanned          AuditType = "upload_scanned"
+	AuditUploadRejected         AuditType = "upload_rejected"
+	AuditUploadScanFailed       AuditType = "upload_scan_failed"
+)
+
+// AuditEvent is one security-relevant step of an auth flow, a request
+// blocked by an IPFilter or refused by a CORSPolicy, or an upload scan
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Type     AuditType `json:"type"`
+	UserID   string    `json:"user_id,omitempty"`
+	Username string    `json:"username,omitempty"`
+	IP       string    `json:"ip,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// AuditLog receives the audit events of AuthService, DeviceTokenManager,
+// PasswordResetService, IPFilter, CORSPolicy and UploadScanner. LoggerAuditLog
+// logs them and an AuditArchiver keeps them in a filestore.Store.
+type AuditLog interface {
+	Record(ctx context.Context, event AuditEvent)
+}
+
+// AuditLogFunc adapts a function to AuditLog
+type AuditLogFunc func(ctx context.Context, event AuditEvent)
+
+func (f AuditLogFunc) Record(ctx context.Context, event AuditEvent) { f(ctx, event) }
+
+// LoggerAuditLog writes audit events to the security logger
+var LoggerAuditLog = AuditLogFunc(func(ctx context.Context, event AuditEvent) {
+	fields := format.Fields{"event": string(event.Type)}
+	if event.Username != "" {
+		fields["username"] = event.Username
+	}
+	if event.IP != "" {
+		fields["ip"] = event.IP
+	}
+	if event.UserID != "" {
+		fields["user_id"] = event.UserID
+	}
+	if event.Detail != "" {
+		fields["detail"] = event.Detail
+	}
+	logger.With(fields).Info("Audit")
+})
+
+// Session is a signed-in user. Its ID is the ID of the JWT handed to the
+// client, so revoking the session invalidates the token before it expires.
+type Session struct {
+	ID        string
+	UserID    string
+	Username  string
+	Roles     []string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// DeviceID is the remembered device the session was opened from or for
+	DeviceID string
+}
+
+// LoginState is where a login stands after a step
+type LoginState string
+
+const (
+	// LoginComplete means the user is signed in and Token is set
+	LoginComplete LoginState = "complete"
+	// LoginTOTPRequired means the password was right and the user must
+	// pass Challenge to VerifyTOTP with a code from their authenticator
+	LoginTOTPRequired LoginState = "totp_required"
+)
+
+// LoginResult is the outcome of a successful login step
+type LoginResult struct {
+	State     LoginState
+	Challenge string
+	Token     string
+	Session   *Session
+}
+
+// AuthService runs the account flows of the demo on top of the security
+// primitives: passwords hashed by a PasswordManager, optional TOTP second
+// factor, sessions carried in JWTs, lockout after repeated failures, and
+// an audit event for every step.
+//
+// Login moves an account through these states:
+//
+//	password ──ok, no 2FA──────────────────────▶ signed in
+//	password ──ok, 2FA─▶ challenge ──valid code─▶ signed in
+//	password or code wrong MaxFailures times ──▶ locked for LockoutDuration
+//	remember-me token ─valid, account unlocked─▶ signed in
+//
+// A password change or reset ends the user's other sessions and forgets
+// their other devices.
+type AuthService struct {
+	users     UserStore
+	passwords *PasswordManager
+	tokens    *JWTAuth
+	// Resets issues and checks the tokens of RequestPasswordReset and
+	// ResetPassword; its Sender delivers the links, and its store can be
+	// swapped for a database.PasswordResetStore. Its audit events go to
+	// Audit.
+	Resets *PasswordResetService
+	// Devices holds the remember-me tokens of RememberDevice
+	Devices *DeviceTokenManager
+
+	Audit AuditLog
+	// MaxFailures wrong passwords or codes in a row lock the account
+	MaxFailures     int
+	LockoutDuration time.Duration
+	SessionTTL      time.Duration
+	// ChallengeTTL is how long the second login step stays open
+	ChallengeTTL time.Duration
+	// Issuer names the service in authenticator apps
+	Issuer string
+
+	dummyOnce sync.Once
+	dummyHash string
+
+	// userLocks serialise the flows that load, change and save one
+	// account, so parallel attempts cannot overwrite each other's failure
+	// count or TOTP step
+	userMu    sync.Mutex
+	userLocks map[string]*userLock
+
+	mu          sync.Mutex
+	sessions    map[string]*Session
+	challenges  map[string]*loginChallenge
+	pendingTOTP map[string]string
+}
+
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+type loginChallenge struct {
+	username string
+	expires  time.Time
+	attempts int
+}
+
+// maxChallengeAttempts is how many codes one challenge accepts before the
+// user has to enter their password again
+const maxChallengeAttempts = 3
+
+// NewAuthService creates an AuthService that signs session tokens with
+// tokens, keeps password reset tokens in memory and remembers devices for
+// 30 days. Set Resets.Sender before RequestPasswordReset is used.
+func NewAuthService(users UserStore, passwords *PasswordManager, tokens *JWTAuth) *AuthService {
+	s := &AuthService{
+		users:           users,
+		passwords:       passwords,
+		tokens:          tokens,
+		Devices:         NewDeviceTokenManager(30 * 24 * time.Hour),
+		Audit:           LoggerAuditLog,
+		MaxFailures:     5,
+		LockoutDuration: 15 * time.Minute,
+		SessionTTL:      12 * time.Hour,
+		ChallengeTTL:    5 * time.Minute,
+		Issuer:          "go-practice",
+		sessions:        make(map[string]*Session),
+		challenges:      make(map[string]*loginChallenge),
+		pendingTOTP:     make(map[string]string),
+		userLocks:       make(map[string]*userLock),
+	}
+	s.Resets = NewPasswordResetService(NewMemoryResetStore(), nil)
+	s.Resets.Audit = AuditLogFunc(func(ctx context.Context, event AuditEvent) {
+		if s.Audit != nil {
+			s.Audit.Record(ctx, event)
+		}
+	})
+	return s
+}
+
+// Register creates an account after checking the password's strength
+func (s *AuthService) Register(ctx context.Context, username, password string, roles ...string) (*AuthUser, error) {
+	if _, err := s.users.FindUser(ctx, username); err == nil {
+		return nil, fmt.Errorf("username %q is taken", username)
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+	if err := s.passwords.ValidatePasswordStrength(password); err != nil {
+		return nil, err
+	}
+	hash, err := s.passwords.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &AuthUser{ID: string_op.NewUUIDv7().String(), Username: username, PasswordHash: hash, Roles: roles}
+	if err := s.users.SaveUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login checks the password and either signs the user in or, when they
+// have 2FA enabled, returns a challenge for VerifyTOTP
+func (s *AuthService) Login(ctx context.Context, username, password string) (*LoginResult, error) {
+	defer s.lockUser(username)()
+	user, err := s.users.FindUser(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		// Spend as long as a real check, so timing does not reveal the user
+		// is unknown
+		s.passwords.VerifyPassword(password, s.dummy())
+		s.audit(ctx, AuditLoginFailed, &AuthUser{Username: username}, "unknown user")
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLocked(ctx, user); err != nil {
+		return nil, err
+	}
+	if !s.passwords.VerifyPassword(password, user.PasswordHash) {
+		s.audit(ctx, AuditLoginFailed, user, "wrong password")
+		return nil, s.fail(ctx, user, ErrInvalidCredentials)
+	}
+
+	if user.TOTPSecret == "" {
+		return s.complete(ctx, user, "")
+	}
+	challenge := string_op.Token(32)
+	s.mu.Lock()
+	s.challenges[hashToken(challenge)] = &loginChallenge{username: user.Username, expires: time.Now().Add(s.ChallengeTTL)}
+	s.mu.Unlock()
+	s.audit(ctx, AuditTOTPRequired, user, "")
+	return &LoginResult{State: LoginTOTPRequired, Challenge: challenge}, nil
+}
+
+// VerifyTOTP completes a login that returned LoginTOTPRequired
+func (s *AuthService) VerifyTOTP(ctx context.Context, challenge, code string) (*LoginResult, error) {
+	key := hashToken(challenge)
+	s.mu.Lock()
+	c, ok := s.challenges[key]
+	if ok && time.Now().After(c.expires) {
+		delete(s.challenges, key)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+
+	defer s.lockUser(c.username)()
+	user, err := s.users.FindUser(ctx, c.username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLocked(ctx, user); err != nil {
+		return nil, err
+	}
+	totp, err := NewTOTP(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, valid := totp.Validate(code, time.Now())
+	if !valid || step <= user.LastTOTPStep {
+		s.mu.Lock()
+		if c.attempts++; c.attempts >= maxChallengeAttempts {
+			delete(s.challenges, key)
+		}
+		s.mu.Unlock()
+		s.audit(ctx, AuditTOTPFailed, user, "")
+		return nil, s.fail(ctx, user, ErrInvalidTOTP)
+	}
+
+	s.mu.Lock()
+	delete(s.challenges, key)
+	s.mu.Unlock()
+	user.LastTOTPStep = step
+	return s.complete(ctx, user, "")
+}
+
+// Authenticate returns the live session a token belongs to
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*Session, error) {
+	claims, err := s.tokens.ValidateToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[claims.ID]
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, claims.ID)
+		return nil, ErrInvalidSession
+	}
+	return session, nil
+}
+
+// Logout ends the session of token and forgets the device it was opened
+// from, if any
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	session, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.sessions, session.ID)
+	s.mu.Unlock()
+	if session.DeviceID != "" {
+		s.Devices.Revoke(ctx, session.UserID, session.DeviceID)
+	}
+	s.audit(ctx, AuditLogout, &AuthUser{ID: session.UserID, Username: session.Username}, "")
+	return nil
+}
+
+// ChangePassword replaces the password of the session's user, who must
+// confirm the current one, and signs out their other sessions
+func (s *AuthService) ChangePassword(ctx context.Context, token, current, next string) error {
+	session, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer s.lockUser(session.Username)()
+	user, err := s.users.FindUser(ctx, session.Username)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLocked(ctx, user); err != nil {
+		return err
+	}
+	if !s.passwords.VerifyPassword(current, user.PasswordHash) {
+		return s.fail(ctx, user, ErrInvalidCredentials)
+	}
+	if err := s.setPassword(ctx, user, next); err != nil {
+		return err
+	}
+	s.revokeSessions(user.ID, session.ID)
+	s.Devices.RevokeAll(ctx, user.ID, session.DeviceID)
+	s.audit(ctx, AuditPasswordChanged, user, "")
+	return nil
+}
+
+// RequestPasswordReset has Resets issue a single-use reset token and hand
+// it to Resets.Sender. Unknown usernames and throttled accounts get no
+// token and no error, so the caller's response cannot reveal whether the
+// account exists.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, username string) error {
+	user, err := s.users.FindUser(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = s.Resets.Request(ctx, ResetAccount{Subject: strings.ToLower(user.Username), Name: user.Username})
+	if errors.Is(err, ErrResetRateLimited) {
+		return nil // audited by Resets
+	}
+	return err
+}
+
+// ResetPassword sets a new password with a token from
+// RequestPasswordReset. It also lifts a lockout and signs out every
+// session, since whoever held the old password may be an attacker. A
+// password too weak to set leaves the token usable.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := s.passwords.ValidatePasswordStrength(password); err != nil {
+		return err
+	}
+	username, err := s.Resets.Consume(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer s.lockUser(username)()
+	user, err := s.users.FindUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = time.Time{}
+	if err := s.setPassword(ctx, user, password); err != nil {
+		return err
+	}
+	s.revokeSessions(user.ID, "")
+	s.Devices.RevokeAll(ctx, user.ID, "")
+	return nil
+}
+
+// RememberDevice keeps the session's user signed in on the device with
+// fingerprint: the returned token, kept by the device, opens new sessions
+// through ResumeSession once this one ends
+func (s *AuthService) RememberDevice(ctx context.Context, token, name, fingerprint string) (string, Device, error) {
+	session, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return "", Device{}, err
+	}
+	deviceToken, device := s.Devices.Issue(ctx, session.UserID, session.Username, name, fingerprint)
+	s.mu.Lock()
+	session.DeviceID = device.ID
+	s.mu.Unlock()
+	return deviceToken, device, nil
+}
+
+// ResumeSession signs in with a remember-me token from RememberDevice,
+// without a password or 2FA code. It returns the new session and the
+// token replacing deviceToken, which must not be used again; presenting it
+// twice revokes the device and ends its sessions.
+func (s *AuthService) ResumeSession(ctx context.Context, deviceToken, fingerprint string) (*LoginResult, string, error) {
+	next, device, err := s.Devices.Use(ctx, deviceToken, fingerprint)
+	if errors.Is(err, ErrDeviceTokenReused) {
+		// One of the sessions opened from the device may be the thief's
+		s.endDeviceSessions(device.ID)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	// The replacement token is not handed out on failure, so the device is
+	// forgotten rather than left with a token it no longer holds
+	unlock := s.lockUser(device.Username)
+	user, err := s.users.FindUser(ctx, device.Username)
+	if err == nil && user.ID != device.UserID {
+		// The username now belongs to another account
+		err = ErrUserNotFound
+	}
+	if err == nil {
+		err = s.checkLocked(ctx, user)
+	}
+	var result *LoginResult
+	if err == nil {
+		result, err = s.complete(ctx, user, "remembered device "+device.ID)
+	}
+	unlock()
+	if err != nil {
+		s.Devices.Revoke(ctx, device.UserID, device.ID)
+		return nil, "", err
+	}
+	s.mu.Lock()
+	result.Session.DeviceID = device.ID
+	s.mu.Unlock()
+	return result, next, nil
+}
+
+// SignOutDevice forgets one of the user's devices and ends the sessions
+// opened from it
+func (s *AuthService) SignOutDevice(ctx context.Context, userID, deviceID string) error {
+	if err := s.Devices.Revoke(ctx, userID, deviceID); err != nil {
+		return err
+	}
+	s.endDeviceSessions(deviceID)
+	return nil
+}
+
+// EnrollTOTP starts turning on 2FA for the session's user. It returns the
+// secret and the otpauth:// URI to show as a QR code; 2FA is enabled once
+// ConfirmTOTP receives a code generated from it.
+func (s *AuthService) EnrollTOTP(ctx context.Context, token string) (secret, uri string, err error) {
+	session, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return "", "", err
+	}
+	secret = GenerateTOTPSecret()
+	totp, err := NewTOTP(secret)
+	if err != nil {
+		return "", "", err
+	}
+	s.mu.Lock()
+	s.pendingTOTP[session.UserID] = secret
+	s.mu.Unlock()
+	return secret, totp.ProvisioningURI(s.Issuer, session.Username), nil
+}
+
+// ConfirmTOTP enables 2FA with the secret from EnrollTOTP after checking
+// a code from the user's authenticator
+func (s *AuthService) ConfirmTOTP(ctx context.Context, token, code string) error {
+	session, err := s.Authenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	secret, ok := s.pendingTOTP[session.UserID]
+	s.mu.Unlock()
+	if !ok {
+		return errors.New("no two-factor enrollment in progress")
+	}
+	totp, err := NewTOTP(secret)
+	if err != nil {
+		return err
+	}
+	step, valid := totp.Validate(code, time.Now())
+	if !valid {
+		return ErrInvalidTOTP
+	}
+
+	defer s.lockUser(session.Username)()
+	user, err := s.users.FindUser(ctx, session.Username)
+	if err != nil {
+		return err
+	}
+	user.TOTPSecret = secret
+	user.LastTOTPStep = step
+	if err := s.users.SaveUser(ctx, user); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.pendingTOTP, session.UserID)
+	s.mu.Unlock()
+	s.audit(ctx, AuditTOTPEnabled, user, "")
+	return nil
+}
+
+// Purge drops expired sessions, login challenges, reset tokens and devices
+// and returns how many it removed
+func (s *AuthService) Purge() int {
+	s.mu.Lock()
+	now := time.Now()
+	removed := 0
+	for id, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, id)
+			removed++
+		}
+	}
+	for key, c := range s.challenges {
+		if now.After(c.expires) {
+			delete(s.challenges, key)
+			removed++
+		}
+	}
+	s.mu.Unlock()
+
+	// The reset store may be a database, so it is not purged under s.mu
+	resets, err := s.Resets.Purge(context.Background())
+	if err != nil {
+		logger.Warn("Failed to purge password reset tokens", "error", err)
+	}
+	return removed + resets + s.Devices.Purge()
+}
+
+// complete signs the user in: it clears their failure count and opens a
+// session
+func (s *AuthService) complete(ctx context.Context, user *AuthUser, detail string) (*LoginResult, error) {
+	user.FailedLogins = 0
+	if err := s.users.SaveUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	hours := int(math.Ceil(s.SessionTTL.Hours()))
+	token, err := s.tokens.GenerateToken(user.ID, user.Username, user.Roles, hours)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.tokens.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &Session{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Roles:     user.Roles,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.SessionTTL),
+	}
+	s.mu.Lock()
+	s.sessions[session.ID] = session
+	s.mu.Unlock()
+	s.audit(ctx, AuditLoginSucceeded, user, detail)
+	return &LoginResult{State: LoginComplete, Token: token, Session: session}, nil
+}
+
+// lockUser locks the account username for a flow that loads, changes and
+// saves it, and returns the unlock function. The lock is dropped once no
+// flow holds or waits for it.
+func (s *AuthService) lockUser(username string) func() {
+	key := strings.ToLower(username)
+	s.userMu.Lock()
+	l, ok := s.userLocks[key]
+	if !ok {
+		l = &userLock{}
+		s.userLocks[key] = l
+	}
+	l.refs++
+	s.userMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		s.userMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(s.userLocks, key)
+		}
+		s.userMu.Unlock()
+	}
+}
+
+// fail counts a failed attempt against user, locking the account once it
+// reaches MaxFailures, and returns err. The caller holds lockUser, so the
+// count it saves is not overwritten by a parallel attempt.
+func (s *AuthService) fail(ctx context.Context, user *AuthUser, err error) error {
+	user.FailedLogins++
+	if user.FailedLogins >= s.MaxFailures {
+		user.FailedLogins = 0
+		user.LockedUntil = time.Now().Add(s.LockoutDuration)
+		s.audit(ctx, AuditAccountLocked, user, "until "+user.LockedUntil.Format(time.RFC3339))
+	}
+	if saveErr := s.users.SaveUser(ctx, user); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+func (s *AuthService) checkLocked(ctx context.Context, user *AuthUser) error {
+	if time.Now().Before(user.LockedUntil) {
+		s.audit(ctx, AuditLoginFailed, user, "account locked")
+		return fmt.Errorf("%w until %s", ErrAccountLocked, user.LockedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (s *AuthService) setPassword(ctx context.Context, user *AuthUser, password string) error {
+	if err := s.passwords.ValidatePasswordStrength(password); err != nil {
+		return err
+	}
+	hash, err := s.passwords.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return s.users.SaveUser(ctx, user)
+}
+
+// revokeSessions ends every session of userID except keep
+func (s *AuthService) revokeSessions(userID, keep string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.UserID == userID && id != keep {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// endDeviceSessions ends every session opened from or for deviceID
+func (s *AuthService) endDeviceSessions(deviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.DeviceID == deviceID {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// dummy returns a hash to verify against for unknown users
+func (s *AuthService) dummy() string {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = s.passwords.HashPassword(string_op.Token(16))
+	})
+	return s.dummyHash
+}
+
+func (s *AuthService) audit(ctx context.Context, typ AuditType, user *AuthUser, detail string) {
+	if s.Audit == nil {
+		return
+	}
+	s.Audit.Record(ctx, AuditEvent{Time: time.Now(), Type: typ, UserID: user.ID, Username: user.Username, Detail: detail})
+}
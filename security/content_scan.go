@@ -0,0 +1,346 @@
+package security
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrUploadRejected is returned by UploadScanner.Scan when a scanner found
+// a threat in a file
+var ErrUploadRejected = errors.New("upload rejected by content scan")
+
+// ScannedFile is an uploaded file waiting to be stored, written to Path so
+// scanners can read it as often as they need to
+type ScannedFile struct {
+	Name        string
+	ContentType string
+	Path        string
+	Size        int64
+}
+
+// Verdict is what a Scanner found in a file; Threat is empty for a clean
+// file
+type Verdict struct {
+	Threat string
+}
+
+// Clean reports whether nothing was found
+func (v Verdict) Clean() bool { return v.Threat == "" }
+
+// Scanner inspects uploaded files. Scan returns an error only when the file
+// could not be checked, such as when an antivirus daemon is down.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, file ScannedFile) (Verdict, error)
+}
+
+// UploadScanner runs every upload through its Scanners before it is
+// stored, and records the outcome of each in the audit log: one
+// upload_scanned, upload_rejected or upload_scan_failed event per file.
+type UploadScanner struct {
+	Scanners []Scanner
+	Audit    AuditLog
+	// FailOpen accepts files a scanner failed to check; by default they
+	// are refused, so an outage of the scanner does not let files through
+	FailOpen bool
+}
+
+// NewUploadScanner returns an UploadScanner with the built-in script and
+// archive scanners, followed by extra
+func NewUploadScanner(extra ...Scanner) *UploadScanner {
+	return &UploadScanner{
+		Scanners: append([]Scanner{&ScriptScanner{}, &ArchiveScanner{}}, extra...),
+		Audit:    LoggerAuditLog,
+	}
+}
+
+// Scan runs the scanners in order, stopping at the first that finds a
+// threat. It returns an error wrapping ErrUploadRejected for a threat, and
+// the scanner's error for a file that could not be checked unless FailOpen
+// is set. ip and username go into the audit events.
+func (s *UploadScanner) Scan(ctx context.Context, file ScannedFile, ip, username string) error {
+	audit := func(typ AuditType, detail string) {
+		if s.Audit != nil {
+			s.Audit.Record(ctx, AuditEvent{Time: time.Now(), Type: typ, Username: username, IP: ip,
+				Detail: fmt.Sprintf("file=%q size=%d %s", file.Name, file.Size, detail)})
+		}
+	}
+
+	var passed []string
+	for _, scanner := range s.Scanners {
+		verdict, err := scanner.Scan(ctx, file)
+		if err != nil {
+			audit(AuditUploadScanFailed, fmt.Sprintf("scanner=%s error=%q", scanner.Name(), err))
+			if s.FailOpen {
+				continue
+			}
+			return fmt.Errorf("%s scan of %s: %w", scanner.Name(), file.Name, err)
+		}
+		if !verdict.Clean() {
+			audit(AuditUploadRejected, fmt.Sprintf("scanner=%s threat=%q", scanner.Name(), verdict.Threat))
+			return fmt.Errorf("%w: %s: %s", ErrUploadRejected, scanner.Name(), verdict.Threat)
+		}
+		passed = append(passed, scanner.Name())
+	}
+	audit(AuditUploadScanned, "scanners="+strings.Join(passed, ","))
+	return nil
+}
+
+// markupExtensions are file types browsers run scripts in
+var markupExtensions = []string{".svg", ".svgz", ".html", ".htm", ".xhtml", ".xml", ".xht", ".shtml"}
+
+var activeContent = []struct {
+	pattern *regexp.Regexp
+	threat  string
+}{
+	{regexp.MustCompile(`(?i)<\s*script\b`), "embedded script element"},
+	{regexp.MustCompile(`(?i)<[^>]*\son[a-z]+\s*=`), "inline event handler"},
+	{regexp.MustCompile(`(?i)(?:href|src|action|formaction|data|values)\s*=\s*["']?\s*(?:javascript|vbscript):`), "javascript: URL"},
+	{regexp.MustCompile(`(?i)<\s*(?:iframe|embed|object|foreignobject|handler)\b`), "embedded active content"},
+	{regexp.MustCompile(`(?i)<!ENTITY\b`), "XML entity declaration"},
+}
+
+// ScriptScanner finds script in SVG, HTML and XML files, which would run
+// in the site's origin if a browser showed the upload: script elements,
+// on* event handlers, javascript: URLs, embedded frames and objects, and
+// XML entities. Other files are not looked at.
+type ScriptScanner struct {
+	// MaxBytes is how much of a file is read, 10 MiB if zero
+	MaxBytes int64
+}
+
+func (s *ScriptScanner) Name() string { return "scripts" }
+
+func (s *ScriptScanner) Scan(ctx context.Context, file ScannedFile) (Verdict, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(io.LimitReader(f, cmp.Or(s.MaxBytes, 10<<20)))
+	head, _ := r.(*bufio.Reader).Peek(512)
+	if isGzip(head) && strings.EqualFold(filepath.Ext(file.Name), ".svgz") {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return Verdict{Threat: "corrupt compressed SVG"}, nil
+		}
+		defer zr.Close()
+		r = bufio.NewReader(io.LimitReader(zr, cmp.Or(s.MaxBytes, 10<<20)))
+		head, _ = r.(*bufio.Reader).Peek(512)
+	}
+	if !isMarkup(file, head) {
+		return Verdict{}, nil
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return Verdict{}, err
+	}
+	for _, active := range activeContent {
+		if active.pattern.Match(content) {
+			return Verdict{Threat: active.threat}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// isMarkup reports whether a browser could treat the file as SVG or HTML,
+// going by its name, declared type and first bytes
+func isMarkup(file ScannedFile, head []byte) bool {
+	if slices.Contains(markupExtensions, strings.ToLower(filepath.Ext(file.Name))) {
+		return true
+	}
+	contentType := strings.ToLower(file.ContentType + " " + http.DetectContentType(head))
+	for _, kind := range []string{"svg", "html", "xml"} {
+		if strings.Contains(contentType, kind) {
+			return true
+		}
+	}
+	trimmed := bytes.TrimSpace(head)
+	return bytes.HasPrefix(trimmed, []byte("<"))
+}
+
+// ArchiveScanner refuses zip and gzip files that unpack to far more than
+// their size, the mark of a decompression bomb. Entries are decompressed,
+// without being written anywhere, rather than trusting the sizes the
+// archive declares, and scanning stops as soon as a limit is passed.
+type ArchiveScanner struct {
+	// MaxRatio is the largest unpacked to packed size ratio, 100 if zero
+	MaxRatio float64
+	// MaxUnpacked is the most an archive may unpack to, 1 GiB if zero
+	MaxUnpacked int64
+	// MaxEntries is the most files a zip may hold, 10000 if zero
+	MaxEntries int
+}
+
+func (s *ArchiveScanner) Name() string { return "archives" }
+
+func (s *ArchiveScanner) Scan(ctx context.Context, file ScannedFile) (Verdict, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Verdict{}, err
+	}
+	head := make([]byte, 4)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+
+	// Unpacking may take at most what the ratio allows for this file
+	limit := min(cmp.Or(s.MaxUnpacked, 1<<30), int64(float64(max(info.Size(), 1))*s.maxRatio()))
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return Verdict{Threat: "corrupt zip archive"}, nil
+		}
+		if len(zr.File) > cmp.Or(s.MaxEntries, 10000) {
+			return Verdict{Threat: fmt.Sprintf("zip archive with %d entries", len(zr.File))}, nil
+		}
+		var unpacked int64
+		for _, entry := range zr.File {
+			if err := ctx.Err(); err != nil {
+				return Verdict{}, err
+			}
+			rc, err := entry.Open()
+			if err != nil {
+				return Verdict{Threat: "corrupt zip entry " + entry.Name}, nil
+			}
+			n, err := io.Copy(io.Discard, io.LimitReader(rc, limit-unpacked+1))
+			rc.Close()
+			unpacked += n
+			if unpacked > limit {
+				return s.bomb(info.Size()), nil
+			}
+			if err != nil {
+				return Verdict{Threat: "corrupt zip entry " + entry.Name}, nil
+			}
+		}
+	case isGzip(head):
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return Verdict{}, err
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return Verdict{Threat: "corrupt gzip stream"}, nil
+		}
+		defer zr.Close()
+		n, err := io.Copy(io.Discard, io.LimitReader(zr, limit+1))
+		if n > limit {
+			return s.bomb(info.Size()), nil
+		}
+		if err != nil {
+			return Verdict{Threat: "corrupt gzip stream"}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+func (s *ArchiveScanner) maxRatio() float64 {
+	if s.MaxRatio <= 0 {
+		return 100
+	}
+	return s.MaxRatio
+}
+
+func (s *ArchiveScanner) bomb(size int64) Verdict {
+	return Verdict{Threat: fmt.Sprintf("decompression bomb: %d bytes unpack to more than %d bytes",
+		size, min(cmp.Or(s.MaxUnpacked, 1<<30), int64(float64(max(size, 1))*s.maxRatio())))}
+}
+
+func isGzip(head []byte) bool {
+	return len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b
+}
+
+// CommandScanner runs an external scanner such as ClamAV on each file. The
+// file's path replaces a "{}" argument of Command, or is appended. Exit
+// code 0 means clean and InfectedCodes a threat, named by the output line
+// ending in "FOUND" when there is one; anything else, or running longer
+// than Timeout, is a failed scan.
+//
+//	&CommandScanner{Command: []string{"clamdscan", "--no-summary", "--fdpass"}}
+type CommandScanner struct {
+	Command []string
+	// Timeout bounds one run, 30 seconds if zero
+	Timeout time.Duration
+	// InfectedCodes are the exit codes reporting a threat, [1] if empty
+	InfectedCodes []int
+}
+
+func (s *CommandScanner) Name() string {
+	if len(s.Command) == 0 {
+		return "command"
+	}
+	return filepath.Base(s.Command[0])
+}
+
+func (s *CommandScanner) Scan(ctx context.Context, file ScannedFile) (Verdict, error) {
+	if len(s.Command) == 0 {
+		return Verdict{}, errors.New("no scan command configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(s.Timeout, 30*time.Second))
+	defer cancel()
+
+	args := slices.Clone(s.Command[1:])
+	if i := slices.Index(args, "{}"); i >= 0 {
+		args[i] = file.Path
+	} else {
+		args = append(args, file.Path)
+	}
+	cmd := exec.CommandContext(ctx, s.Command[0], args...)
+	// A scanner that forks must not keep the pipes open past the timeout
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Verdict{}, fmt.Errorf("timed out after %v", cmp.Or(s.Timeout, 30*time.Second))
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return Verdict{}, nil
+	case errors.As(err, &exitErr) && slices.Contains(s.infectedCodes(), exitErr.ExitCode()):
+		threat := "reported by " + s.Name()
+		for _, line := range strings.Split(string(output), "\n") {
+			if name, found := strings.CutSuffix(strings.TrimSpace(line), " FOUND"); found {
+				// clamscan writes "path: Signature FOUND"
+				_, signature, _ := strings.Cut(name, ": ")
+				threat = strings.TrimSpace(cmp.Or(signature, name))
+				break
+			}
+		}
+		return Verdict{Threat: threat}, nil
+	}
+	detail := strings.TrimSpace(string(output))
+	if len(detail) > 200 {
+		detail = detail[:200] + "…"
+	}
+	return Verdict{}, fmt.Errorf("%w: %s", err, detail)
+}
+
+func (s *CommandScanner) infectedCodes() []int {
+	if len(s.InfectedCodes) == 0 {
+		return []int{1}
+	}
+	return s.InfectedCodes
+}
@@ -0,0 +1,341 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/config"
+)
+
+// CORSRule lists what cross-origin callers of some paths may do. A rule
+// without AllowedOrigins refuses every cross-origin request.
+type CORSRule struct {
+	// AllowedOrigins are "*", exact origins such as
+	// "https://app.example.com", or subdomain wildcards such as
+	// "https://*.example.com", which match any subdomain but not the
+	// domain itself
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD and POST
+	AllowedMethods []string
+	// AllowedHeaders are the request headers callers may send; "*" allows
+	// any
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets listed origins send cookies. It cannot be
+	// combined with the "*" origin, which would hand every site the
+	// caller's session; browsers refuse the pair too.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer. Browsers
+	// cap it, Chrome at two hours.
+	MaxAge time.Duration
+}
+
+// Validate reports origin patterns that can never match, and the "*"
+// origin combined with AllowCredentials
+func (rule CORSRule) Validate() error {
+	for _, origin := range rule.AllowedOrigins {
+		if _, err := parseOriginPattern(origin); err != nil {
+			return err
+		}
+	}
+	if rule.AllowCredentials && slices.Contains(rule.AllowedOrigins, "*") {
+		return fmt.Errorf(`the "*" origin cannot allow credentials; list the origins instead`)
+	}
+	return nil
+}
+
+// CORSPolicy picks a CORSRule by request path, answers preflight requests
+// itself and refuses cross-origin requests its rules do not allow.
+// Same-origin requests and those without an Origin header pass untouched.
+type CORSPolicy struct {
+	fallback corsRoute
+	routes   []corsRoute
+	// AuditOnly lets requests the rules would refuse through, as if they
+	// were allowed, after recording them, so rules can be tried out before
+	// they are enforced
+	AuditOnly bool
+	// Audit receives an event for every refused, or in AuditOnly mode
+	// would-be refused, request
+	Audit AuditLog
+}
+
+type corsRoute struct {
+	prefix  string
+	rule    CORSRule
+	origins []originPattern
+	methods []string
+	headers []string
+}
+
+// NewCORSPolicy uses rule for paths without a more specific route
+func NewCORSPolicy(rule CORSRule) *CORSPolicy {
+	return &CORSPolicy{fallback: newCORSRoute("", rule), Audit: LoggerAuditLog}
+}
+
+// NewCORSPolicyFromConfig builds a policy from the security.cors section
+// of a config file
+func NewCORSPolicyFromConfig(cfg config.CORSConfig) (*CORSPolicy, error) {
+	rule := corsRuleFromConfig(cfg.Default)
+	if err := rule.Validate(); err != nil {
+		return nil, fmt.Errorf("cors default: %w", err)
+	}
+	p := NewCORSPolicy(rule)
+	p.AuditOnly = cfg.Audit
+	for prefix, routeCfg := range cfg.Routes {
+		rule := corsRuleFromConfig(routeCfg)
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("cors route %s: %w", prefix, err)
+		}
+		p.Route(prefix, rule)
+	}
+	return p, nil
+}
+
+func corsRuleFromConfig(cfg config.CORSRuleConfig) CORSRule {
+	return CORSRule{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge.Duration(),
+	}
+}
+
+// Route uses rule for paths starting with prefix; the longest matching
+// prefix wins
+func (p *CORSPolicy) Route(prefix string, rule CORSRule) *CORSPolicy {
+	p.routes = append(p.routes, newCORSRoute(prefix, rule))
+	slices.SortStableFunc(p.routes, func(a, b corsRoute) int { return len(b.prefix) - len(a.prefix) })
+	return p
+}
+
+// Rule returns the rule applied to path
+func (p *CORSPolicy) Rule(path string) CORSRule {
+	return p.route(path).rule
+}
+
+func (p *CORSPolicy) route(path string) *corsRoute {
+	for i := range p.routes {
+		if strings.HasPrefix(path, p.routes[i].prefix) {
+			return &p.routes[i]
+		}
+	}
+	return &p.fallback
+}
+
+func newCORSRoute(prefix string, rule CORSRule) corsRoute {
+	route := corsRoute{prefix: prefix, rule: rule, methods: slices.Clone(rule.AllowedMethods)}
+	for _, origin := range rule.AllowedOrigins {
+		// Invalid patterns, reported by Validate, never match
+		if pattern, err := parseOriginPattern(origin); err == nil {
+			route.origins = append(route.origins, pattern)
+		}
+	}
+	if len(route.methods) == 0 {
+		route.methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	for i, method := range route.methods {
+		route.methods[i] = strings.ToUpper(method)
+	}
+	for _, header := range rule.AllowedHeaders {
+		route.headers = append(route.headers, strings.ToLower(header))
+	}
+	return route
+}
+
+// check returns why the route refuses origin calling with method and
+// requested headers, or "" if it allows it
+func (route *corsRoute) check(origin, method string, headers []string) string {
+	if !slices.ContainsFunc(route.origins, func(p originPattern) bool { return p.match(origin) }) {
+		return "origin_not_allowed"
+	}
+	if !slices.Contains(route.methods, method) {
+		return "method_not_allowed"
+	}
+	if !slices.Contains(route.headers, "*") {
+		for _, header := range headers {
+			if !slices.Contains(route.headers, header) {
+				return "header_not_allowed"
+			}
+		}
+	}
+	return ""
+}
+
+// Middleware answers preflight requests with 204 No Content and the
+// route's rule, or 403 Forbidden when the rule refuses them, without
+// calling next. Other cross-origin requests are refused with 403 too, so a
+// simple request from a foreign page never reaches a handler.
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || isSameOrigin(r, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		route := p.route(r.URL.Path)
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || requestMethod == "" {
+			if reason := route.check(origin, r.Method, nil); reason != "" && !p.allowRefused(r, origin, reason) {
+				http.Error(w, "Cross-origin request not allowed", http.StatusForbidden)
+				return
+			}
+			route.setOrigin(h, origin)
+			if len(route.rule.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(route.rule.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		requestHeaders := headerList(r.Header.Values("Access-Control-Request-Headers"))
+		if reason := route.check(origin, requestMethod, requestHeaders); reason != "" && !p.allowRefused(r, origin, "preflight_"+reason) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		route.setOrigin(h, origin)
+		methods := route.methods
+		if !slices.Contains(methods, requestMethod) {
+			// Only reachable in AuditOnly mode
+			methods = append(slices.Clone(methods), requestMethod)
+		}
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(requestHeaders) > 0 {
+			// Naming the requested headers works with credentials, where
+			// "*" is taken literally
+			h.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+		}
+		if route.rule.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(route.rule.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowRefused records a request the rules refuse and reports whether it
+// may go on anyway, which it may only in AuditOnly mode
+func (p *CORSPolicy) allowRefused(r *http.Request, origin, reason string) bool {
+	if p.Audit != nil {
+		detail := reason + " " + r.Method + " " + r.URL.Path + " origin=" + origin
+		if p.AuditOnly {
+			detail = "audit only, allowed: " + detail
+		}
+		p.Audit.Record(r.Context(), AuditEvent{Type: AuditCORSRefused, IP: remoteIP(r), Detail: detail})
+	}
+	return p.AuditOnly
+}
+
+// setOrigin never echoes an arbitrary origin with credentials: a rule
+// with "*" answers "*" and no credentials, even if Validate was skipped
+func (route *corsRoute) setOrigin(h http.Header, origin string) {
+	if slices.Contains(route.rule.AllowedOrigins, "*") {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if route.rule.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// isSameOrigin reports whether origin is the scheme and host the request
+// was sent to, as browsers send Origin with same-origin POSTs too. Behind
+// a proxy that ends TLS the scheme comes from X-Forwarded-Proto.
+func isSameOrigin(r *http.Request, origin string) bool {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || !strings.EqualFold(host, r.Host) {
+		return false
+	}
+	requestScheme := "http"
+	if r.TLS != nil {
+		requestScheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		requestScheme, _, _ = strings.Cut(proto, ",")
+	}
+	return strings.EqualFold(scheme, strings.TrimSpace(requestScheme))
+}
+
+// headerList splits comma-separated header names, lowercased
+func headerList(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// originPattern is a parsed entry of CORSRule.AllowedOrigins
+type originPattern struct {
+	any    bool
+	scheme string
+	host   string
+	// wildcard matches subdomains of host
+	wildcard bool
+	port     string
+}
+
+func parseOriginPattern(s string) (originPattern, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return originPattern{any: true}, nil
+	}
+	p, err := parseOrigin(s)
+	if err != nil {
+		return originPattern{}, err
+	}
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.wildcard = rest, true
+	}
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, fmt.Errorf("cors origin %q: only a leading *. wildcard is supported", s)
+	}
+	return p, nil
+}
+
+// parseOrigin splits scheme://host[:port], dropping the scheme's default
+// port
+func parseOrigin(s string) (originPattern, error) {
+	scheme, hostport, ok := strings.Cut(strings.ToLower(s), "://")
+	if !ok || scheme == "" || hostport == "" || strings.ContainsAny(hostport, "/?#") {
+		return originPattern{}, fmt.Errorf("cors origin %q: want scheme://host[:port]", s)
+	}
+	host, port := hostport, ""
+	if i := strings.LastIndex(hostport, ":"); i > strings.LastIndex(hostport, "]") {
+		host, port = hostport[:i], hostport[i+1:]
+	}
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	return originPattern{scheme: scheme, host: host, port: port}, nil
+}
+
+func (p originPattern) match(origin string) bool {
+	if p.any {
+		return true
+	}
+	o, err := parseOrigin(origin)
+	if err != nil || o.scheme != p.scheme || o.port != p.port {
+		return false
+	}
+	if p.wildcard {
+		sub, ok := strings.CutSuffix(o.host, "."+p.host)
+		return ok && sub != ""
+	}
+	return o.host == p.host
+}
@@ -0,0 +1,118 @@
+package security
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// ErrCSRFToken is returned for a form post whose CSRF token is missing or
+// does not match the one in its cookie
+var ErrCSRFToken = errors.New("missing or invalid CSRF token")
+
+// CSRFProtector guards form posts with signed double-submit tokens: a token
+// is set in a cookie and must come back in the form, which another site can
+// make the browser post but cannot read the cookie to fill in. A sibling
+// subdomain can plant a cookie, and signing alone does not stop it planting
+// a token this site issued to the attacker; binding tokens to the session
+// (see Session) does.
+type CSRFProtector struct {
+	key []byte
+
+	// CookieName and FieldName default to "csrf_token". The token is also
+	// accepted in an X-CSRF-Token header, for scripts.
+	CookieName string
+	FieldName  string
+
+	// Session returns the ID of the request's session, which tokens are
+	// signed over: a token issued in one session, or before signing in, is
+	// refused in another. Without it tokens are unbound, and only the
+	// cookie's SameSite attribute keeps other sites from planting one.
+	Session func(r *http.Request) string
+}
+
+// NewCSRFProtector creates a protector signing tokens with key. An empty
+// key is replaced with a random one, so tokens stop working when the
+// process restarts.
+func NewCSRFProtector(key []byte) *CSRFProtector {
+	if len(key) == 0 {
+		key = string_op.RandomBytes(32)
+	}
+	return &CSRFProtector{key: key, CookieName: "csrf_token", FieldName: "csrf_token"}
+}
+
+// Token returns the request's token for embedding in a form, setting the
+// cookie when the request has no valid one yet
+func (c *CSRFProtector) Token(w http.ResponseWriter, r *http.Request) string {
+	session := c.session(r)
+	if cookie, err := r.Cookie(c.CookieName); err == nil && c.valid(cookie.Value, session) {
+		return cookie.Value
+	}
+	nonce := string_op.Token(24)
+	token := nonce + "." + c.sign(nonce, session)
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.CookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// Verify checks that the form field or header repeats the cookie's token,
+// and that the token was issued to the request's session
+func (c *CSRFProtector) Verify(r *http.Request) error {
+	cookie, err := r.Cookie(c.CookieName)
+	if err != nil || !c.valid(cookie.Value, c.session(r)) {
+		return ErrCSRFToken
+	}
+	given := r.Header.Get("X-CSRF-Token")
+	if given == "" {
+		given = r.PostFormValue(c.FieldName)
+	}
+	if !cryptoutil.Equal(given, cookie.Value) {
+		return ErrCSRFToken
+	}
+	return nil
+}
+
+// Middleware refuses POST, PUT, PATCH and DELETE requests that fail Verify
+// with 403 Forbidden
+func (c *CSRFProtector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		default:
+			if err := c.Verify(r); err != nil {
+				logger.Warn("CSRF check failed", "method", r.Method, "path", r.URL.Path, "ip", remoteIP(r))
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CSRFProtector) session(r *http.Request) string {
+	if c.Session == nil {
+		return ""
+	}
+	return c.Session(r)
+}
+
+// sign signs nonce for session; nonces are base64url, so the ":" after
+// them keeps the two apart
+func (c *CSRFProtector) sign(nonce, session string) string {
+	return base64.RawURLEncoding.EncodeToString(cryptoutil.HMAC(c.key, []byte("csrf:"+nonce+":"+session)))
+}
+
+func (c *CSRFProtector) valid(token, session string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	return ok && nonce != "" && cryptoutil.Equal(signature, c.sign(nonce, session))
+}
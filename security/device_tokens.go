@@ -0,0 +1,264 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+var (
+	// ErrInvalidDeviceToken is returned for an unknown, expired or revoked
+	// remember-me token, or one presented by a different device
+	ErrInvalidDeviceToken = errors.New("invalid or expired remember-me token")
+	// ErrDeviceTokenReused is returned when a remember-me token that was
+	// already rotated away is presented again. Either the user's or an
+	// attacker's copy was stolen, so the device is revoked.
+	ErrDeviceTokenReused = errors.New("remember-me token reused; device revoked")
+	// ErrDeviceNotFound is returned when revoking a device the user does not
+	// have
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// Device is a browser or app a user stays signed in on
+type Device struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	// Name describes the device to its owner, such as its user agent
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// DeviceTokenManager issues long-lived remember-me tokens, each bound to
+// one device by a fingerprint, and rotates them on every use.
+//
+// A token is "<device id>.<secret>". Only hashes of the secret and the
+// fingerprint are kept. When a token is used it is swapped for a new one;
+// if a swapped-out token turns up again, whoever holds it copied it, and the
+// device is revoked so neither copy works any longer. Browsers sending two
+// requests with the same token at once trip this too and have to sign in
+// again.
+type DeviceTokenManager struct {
+	// TTL is how long a device stays signed in after its last use
+	TTL time.Duration
+	// MaxDevices per user; issuing one more drops the least recently used
+	MaxDevices int
+	// Audit receives remember, reuse, mismatch and revoke events
+	Audit AuditLog
+
+	mu      sync.Mutex
+	devices map[string]*deviceRecord
+}
+
+type deviceRecord struct {
+	Device
+	fingerprint string
+	secret      string
+	// retired are the hashes of rotated-out secrets, newest last
+	retired []string
+}
+
+// maxRetiredSecrets bounds how many rotated-out secrets of a device are
+// remembered to detect reuse
+const maxRetiredSecrets = 32
+
+// NewDeviceTokenManager creates a manager whose devices stay signed in for
+// ttl after their last use, ten per user at most
+func NewDeviceTokenManager(ttl time.Duration) *DeviceTokenManager {
+	return &DeviceTokenManager{
+		TTL:        ttl,
+		MaxDevices: 10,
+		Audit:      LoggerAuditLog,
+		devices:    make(map[string]*deviceRecord),
+	}
+}
+
+// DeviceFingerprint identifies the browser behind a request by its user
+// agent. It only needs to stay the same between visits; a token copied to
+// another browser then stops working.
+func DeviceFingerprint(r *http.Request) string {
+	return hashToken("ua:" + r.UserAgent())
+}
+
+// Issue remembers a new device of the user and returns its token
+func (m *DeviceTokenManager) Issue(ctx context.Context, userID, username, name, fingerprint string) (string, Device) {
+	secret := string_op.Token(32)
+	now := time.Now()
+	record := &deviceRecord{
+		Device: Device{
+			ID:         string_op.NewUUIDv7().String(),
+			UserID:     userID,
+			Username:   username,
+			Name:       name,
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(m.TTL),
+		},
+		fingerprint: hashToken(fingerprint),
+		secret:      hashToken(secret),
+	}
+
+	m.mu.Lock()
+	m.devices[record.ID] = record
+	var evicted []Device
+	if m.MaxDevices > 0 {
+		owned := m.owned(userID)
+		for len(owned) > m.MaxDevices {
+			oldest := owned[len(owned)-1]
+			delete(m.devices, oldest.ID)
+			evicted = append(evicted, oldest.Device)
+			owned = owned[:len(owned)-1]
+		}
+	}
+	m.mu.Unlock()
+
+	m.audit(ctx, AuditDeviceRemembered, record.Device, name)
+	for _, device := range evicted {
+		m.audit(ctx, AuditDeviceRevoked, device, "too many devices")
+	}
+	return record.ID + "." + secret, record.Device
+}
+
+// Use checks a token presented by the device with fingerprint and returns
+// its replacement, which the caller must hand back to the device, and the
+// device it belongs to. With ErrDeviceTokenReused it returns the revoked
+// device, so sessions opened from it can be ended too.
+func (m *DeviceTokenManager) Use(ctx context.Context, token, fingerprint string) (string, Device, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return "", Device{}, ErrInvalidDeviceToken
+	}
+	hash := hashToken(secret)
+
+	m.mu.Lock()
+	record, ok := m.devices[id]
+	if !ok {
+		m.mu.Unlock()
+		return "", Device{}, ErrInvalidDeviceToken
+	}
+	now := time.Now()
+	if now.After(record.ExpiresAt) {
+		delete(m.devices, id)
+		m.mu.Unlock()
+		return "", Device{}, ErrInvalidDeviceToken
+	}
+	if !cryptoutil.Equal(hash, record.secret) {
+		reused := slices.ContainsFunc(record.retired, func(retired string) bool { return cryptoutil.Equal(retired, hash) })
+		if reused {
+			delete(m.devices, id)
+		}
+		device := record.Device
+		m.mu.Unlock()
+		if !reused {
+			return "", Device{}, ErrInvalidDeviceToken
+		}
+		m.audit(ctx, AuditDeviceTokenReused, device, "device "+device.ID+" revoked")
+		return "", device, ErrDeviceTokenReused
+	}
+	if !cryptoutil.Equal(hashToken(fingerprint), record.fingerprint) {
+		device := record.Device
+		m.mu.Unlock()
+		m.audit(ctx, AuditDeviceMismatch, device, "device "+device.ID)
+		return "", Device{}, ErrInvalidDeviceToken
+	}
+
+	next := string_op.Token(32)
+	record.retired = append(record.retired, record.secret)
+	if len(record.retired) > maxRetiredSecrets {
+		record.retired = record.retired[len(record.retired)-maxRetiredSecrets:]
+	}
+	record.secret = hashToken(next)
+	record.LastUsedAt = now
+	record.ExpiresAt = now.Add(m.TTL)
+	device := record.Device
+	m.mu.Unlock()
+	return id + "." + next, device, nil
+}
+
+// Devices returns the user's devices, most recently used first
+func (m *DeviceTokenManager) Devices(userID string) []Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var devices []Device
+	for _, record := range m.owned(userID) {
+		devices = append(devices, record.Device)
+	}
+	return devices
+}
+
+// Revoke signs one of the user's devices out
+func (m *DeviceTokenManager) Revoke(ctx context.Context, userID, deviceID string) error {
+	m.mu.Lock()
+	record, ok := m.devices[deviceID]
+	if !ok || record.UserID != userID {
+		m.mu.Unlock()
+		return ErrDeviceNotFound
+	}
+	delete(m.devices, deviceID)
+	m.mu.Unlock()
+	m.audit(ctx, AuditDeviceRevoked, record.Device, "")
+	return nil
+}
+
+// RevokeAll signs out every device of the user except keep and returns
+// how many it revoked
+func (m *DeviceTokenManager) RevokeAll(ctx context.Context, userID, keep string) int {
+	m.mu.Lock()
+	var revoked []Device
+	for id, record := range m.devices {
+		if record.UserID == userID && id != keep {
+			delete(m.devices, id)
+			revoked = append(revoked, record.Device)
+		}
+	}
+	m.mu.Unlock()
+	for _, device := range revoked {
+		m.audit(ctx, AuditDeviceRevoked, device, "all devices signed out")
+	}
+	return len(revoked)
+}
+
+// Purge drops expired devices and returns how many it removed
+func (m *DeviceTokenManager) Purge() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	removed := 0
+	for id, record := range m.devices {
+		if now.After(record.ExpiresAt) {
+			delete(m.devices, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// owned returns the user's live devices, most recently used first; m.mu
+// must be held
+func (m *DeviceTokenManager) owned(userID string) []*deviceRecord {
+	now := time.Now()
+	var records []*deviceRecord
+	for _, record := range m.devices {
+		if record.UserID == userID && !now.After(record.ExpiresAt) {
+			records = append(records, record)
+		}
+	}
+	slices.SortFunc(records, func(a, b *deviceRecord) int { return b.LastUsedAt.Compare(a.LastUsedAt) })
+	return records
+}
+
+func (m *DeviceTokenManager) audit(ctx context.Context, typ AuditType, device Device, detail string) {
+	if m.Audit == nil {
+		return
+	}
+	m.Audit.Record(ctx, AuditEvent{Time: time.Now(), Type: typ, UserID: device.UserID, Username: device.Username, Detail: detail})
+}
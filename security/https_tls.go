@@ -230,17 +230,10 @@ func (t *TLSSecurity) CreateSecureServer(addr string, handler http.Handler) (*ht
 	return server, nil
 }
 
-// AddSecurityHeaders adds security headers to HTTP responses
+// AddSecurityHeaders adds the headers of WebAppProfile to HTTP responses
+//
+// Deprecated: use a HeaderProfile or a HeaderPolicy, which pick the headers
+// to suit each route.
 func (t *TLSSecurity) AddSecurityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Add security headers
-		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-		w.Header().Set("Content-Security-Policy", "default-src 'self'")
-
-		next.ServeHTTP(w, r)
-	})
+	return WebAppProfile().Middleware(next)
 }
@@ -8,6 +8,8 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 // ValidationRule represents a validation rule
@@ -28,16 +30,23 @@ type ValidationResult struct {
 
 // InputValidator handles input validation and sanitization
 type InputValidator struct {
-	rules map[string]ValidationRule
+	rules      map[string]ValidationRule
+	htmlPolicy *string_op.Policy
 }
 
 // NewInputValidator creates a new input validator
 func NewInputValidator() *InputValidator {
 	return &InputValidator{
-		rules: make(map[string]ValidationRule),
+		rules:      make(map[string]ValidationRule),
+		htmlPolicy: string_op.UGCPolicy(),
 	}
 }
 
+// SetHTMLPolicy replaces the allow-list used by SanitizeHTML and RenderMarkdown
+func (v *InputValidator) SetHTMLPolicy(policy *string_op.Policy) {
+	v.htmlPolicy = policy
+}
+
 // AddRule adds a validation rule for a field
 func (v *InputValidator) AddRule(field string, rule ValidationRule) {
 	v.rules[field] = rule
@@ -68,8 +77,16 @@ func (v *InputValidator) ValidateString(field, value string) ValidationResult {
 		return result
 	}
 
-	// Sanitize input
-	sanitized := v.SanitizeString(value)
+	// Sanitize input; rich-text fields keep the markup the HTML policy allows
+	var sanitized string
+	switch rule.Type {
+	case "html":
+		sanitized = v.SanitizeHTML(value)
+	case "markdown":
+		sanitized = v.RenderMarkdown(value)
+	default:
+		sanitized = v.SanitizeString(value)
+	}
 	result.Sanitized = sanitized
 
 	// Check length
@@ -131,21 +148,15 @@ func (v *InputValidator) SanitizeString(input string) string {
 	return sanitized
 }
 
-// SanitizeHTML sanitizes HTML content while preserving safe tags
+// SanitizeHTML removes every tag, attribute and URL protocol that the HTML
+// policy does not allow (UGCPolicy by default)
 func (v *InputValidator) SanitizeHTML(input string) string {
-	// Remove script tags and their content
-	scriptRegex := regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
-	sanitized := scriptRegex.ReplaceAllString(input, "")
-
-	// Remove javascript: protocols
-	jsRegex := regexp.MustCompile(`(?i)javascript:`)
-	sanitized = jsRegex.ReplaceAllString(sanitized, "")
-
-	// Remove on* event handlers
-	eventRegex := regexp.MustCompile(`(?i)\s+on\w+\s*=\s*["'][^"']*["']`)
-	sanitized = eventRegex.ReplaceAllString(sanitized, "")
+	return v.htmlPolicy.Sanitize(input)
+}
 
-	return sanitized
+// RenderMarkdown converts user-supplied Markdown to HTML that is safe to serve
+func (v *InputValidator) RenderMarkdown(input string) string {
+	return string_op.RenderMarkdownWithPolicy(input, v.htmlPolicy)
 }
 
 // IsValidEmail validates email format
@@ -319,4 +330,10 @@ func (v *InputValidator) CreateCommonRules() {
 		MaxLen:   500,
 		Type:     "url",
 	})
+
+	v.AddRule("html", ValidationRule{
+		Required: false,
+		MaxLen:   10000,
+		Type:     "html",
+	})
 }
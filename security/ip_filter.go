@@ -0,0 +1,258 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jerrychou/go-practice/config"
+)
+
+// GeoResolver looks up the country of an IP address, such as a wrapper
+// around a MaxMind GeoLite2 database
+type GeoResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 code of ip's country, or ""
+	// when it is unknown
+	Country(ip netip.Addr) (string, error)
+}
+
+// GeoResolverFunc adapts a function to GeoResolver
+type GeoResolverFunc func(ip netip.Addr) (string, error)
+
+func (f GeoResolverFunc) Country(ip netip.Addr) (string, error) { return f(ip) }
+
+// StaticGeoResolver maps CIDR ranges to countries, for tests and demos
+type StaticGeoResolver map[string]string
+
+func (m StaticGeoResolver) Country(ip netip.Addr) (string, error) {
+	for cidr, country := range m {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return "", err
+		}
+		if prefix.Contains(ip) {
+			return country, nil
+		}
+	}
+	return "", nil
+}
+
+// IPDecision is the outcome of IPFilter.Check
+type IPDecision struct {
+	Allowed bool
+	// Reason says which rule decided: "denied", "allowed", "country_denied",
+	// "country_not_allowed", "not_allowed" or "default"
+	Reason  string
+	Country string
+}
+
+// ipRules are the compiled form of config.IPFilterConfig
+type ipRules struct {
+	allow, deny                   []netip.Prefix
+	allowCountries, denyCountries []string
+	trusted                       []netip.Prefix
+}
+
+// IPFilter allows or blocks clients by address and country. Rules are
+// checked in this order, and the first that matches decides:
+//
+//  1. an address in Deny is blocked
+//  2. an address in Allow is let through
+//  3. a country in DenyCountries is blocked
+//  4. with AllowCountries set, a country in it is let through and any
+//     other, or an unknown one, is blocked
+//  5. with Allow set, anything else is blocked; otherwise it is let through
+//
+// Country rules need a Geo resolver and are skipped without one. The rules
+// can be replaced while requests are being checked, for instance from a
+// config file being hot reloaded.
+type IPFilter struct {
+	rules atomic.Pointer[ipRules]
+
+	Geo GeoResolver
+	// Audit receives an event for every blocked request
+	Audit AuditLog
+}
+
+// NewIPFilter creates a filter with the rules in cfg
+func NewIPFilter(cfg config.IPFilterConfig) (*IPFilter, error) {
+	f := &IPFilter{Audit: LoggerAuditLog}
+	if err := f.Update(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update replaces the rules. Invalid rules leave the current ones in place.
+func (f *IPFilter) Update(cfg config.IPFilterConfig) error {
+	rules := &ipRules{
+		allowCountries: upper(cfg.AllowCountries),
+		denyCountries:  upper(cfg.DenyCountries),
+	}
+	for _, list := range []struct {
+		name    string
+		entries []string
+		dst     *[]netip.Prefix
+	}{
+		{"allow", cfg.Allow, &rules.allow},
+		{"deny", cfg.Deny, &rules.deny},
+		{"trusted_proxies", cfg.TrustedProxies, &rules.trusted},
+	} {
+		for _, entry := range list.entries {
+			prefix, err := parsePrefix(entry)
+			if err != nil {
+				return fmt.Errorf("ip_filter %s: %w", list.name, err)
+			}
+			*list.dst = append(*list.dst, prefix)
+		}
+	}
+	f.rules.Store(rules)
+	return nil
+}
+
+// OnReload is a config.ConfigReloadCallback that applies the ip_filter
+// section of a reloaded *config.FileConfig
+func (f *IPFilter) OnReload(cfg interface{}) error {
+	fc, ok := cfg.(*config.FileConfig)
+	if !ok {
+		return fmt.Errorf("ip filter: unexpected config type %T", cfg)
+	}
+	if err := f.Update(fc.Security.IPFilter); err != nil {
+		return err
+	}
+	logger.Info("IP filter rules reloaded",
+		"allow", len(fc.Security.IPFilter.Allow), "deny", len(fc.Security.IPFilter.Deny))
+	return nil
+}
+
+// Check decides whether ip may connect
+func (f *IPFilter) Check(ip netip.Addr) IPDecision {
+	rules := f.rules.Load()
+	ip = ip.Unmap()
+	if containsAddr(rules.deny, ip) {
+		return IPDecision{Reason: "denied"}
+	}
+	if containsAddr(rules.allow, ip) {
+		return IPDecision{Allowed: true, Reason: "allowed"}
+	}
+
+	if f.Geo != nil && (len(rules.allowCountries) > 0 || len(rules.denyCountries) > 0) {
+		country, err := f.Geo.Country(ip)
+		if err != nil {
+			logger.Warn("GeoIP lookup failed", "ip", ip.String(), "error", err)
+		}
+		country = strings.ToUpper(country)
+		if country != "" && slices.Contains(rules.denyCountries, country) {
+			return IPDecision{Reason: "country_denied", Country: country}
+		}
+		if len(rules.allowCountries) > 0 {
+			if country != "" && slices.Contains(rules.allowCountries, country) {
+				return IPDecision{Allowed: true, Reason: "allowed", Country: country}
+			}
+			return IPDecision{Reason: "country_not_allowed", Country: country}
+		}
+	}
+
+	if len(rules.allow) > 0 {
+		return IPDecision{Reason: "not_allowed"}
+	}
+	return IPDecision{Allowed: true, Reason: "default"}
+}
+
+// ClientIP returns the address of r's client. X-Forwarded-For is used only
+// when the connection comes from a trusted proxy, and then read from the
+// right, skipping further trusted proxies, since a client can put anything
+// at the left of the header.
+func (f *IPFilter) ClientIP(r *http.Request) string {
+	return f.clientAddr(r).String()
+}
+
+func (f *IPFilter) clientAddr(r *http.Request) netip.Addr {
+	rules := f.rules.Load()
+	addr, err := netip.ParseAddr(remoteIP(r))
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	if !containsAddr(rules.trusted, addr) {
+		return addr
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !containsAddr(rules.trusted, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// Middleware answers requests from blocked clients with 403 Forbidden and
+// records an audit event for each
+func (f *IPFilter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr := f.clientAddr(r)
+		decision := IPDecision{Reason: "invalid_address"}
+		if addr.IsValid() {
+			decision = f.Check(addr)
+		}
+		if !decision.Allowed {
+			if f.Audit != nil {
+				detail := decision.Reason + " " + r.Method + " " + r.URL.Path
+				if decision.Country != "" {
+					detail += " country=" + decision.Country
+				}
+				f.Audit.Record(r.Context(), AuditEvent{Type: AuditIPBlocked, IP: addr.String(), Detail: detail})
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parsePrefix accepts a CIDR range or a single address
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func upper(codes []string) []string {
+	out := make([]string, len(codes))
+	for i, code := range codes {
+		out[i] = strings.ToUpper(strings.TrimSpace(code))
+	}
+	return out
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 // JWTClaims represents the claims structure for JWT tokens
@@ -40,6 +42,7 @@ func (j *JWTAuth) GenerateToken(userID, username string, roles []string, expirat
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "go-practice-app",
 			Subject:   userID,
+			ID:        string_op.NewUUIDv7().String(),
 		},
 	}
 
@@ -8,6 +8,9 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 // OAuthProvider represents different OAuth providers
@@ -193,9 +196,16 @@ func (o *OAuthAuth) getProviderUserInfoURL(provider OAuthProvider) string {
 	}
 }
 
-// ValidateState validates the state parameter to prevent CSRF attacks
+// GenerateState returns an unguessable state value to store in the user's
+// session before redirecting to the provider
+func (o *OAuthAuth) GenerateState() string {
+	return string_op.Token(32)
+}
+
+// ValidateState validates the state parameter to prevent CSRF attacks. An
+// empty expected state, from a session that lost it, never matches.
 func (o *OAuthAuth) ValidateState(expectedState, receivedState string) error {
-	if expectedState != receivedState {
+	if expectedState == "" || !cryptoutil.Equal(expectedState, receivedState) {
 		return fmt.Errorf("invalid state parameter")
 	}
 	return nil
@@ -2,7 +2,6 @@ package security
 
 import (
 	"crypto/rand"
-	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"strconv"
@@ -10,6 +9,9 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/scrypt"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 // PasswordHasher interface for different hashing algorithms
@@ -103,7 +105,7 @@ func (s *ScryptHasher) Verify(password, hash string) bool {
 	}
 
 	// Compare hashes using constant time comparison
-	return subtle.ConstantTimeCompare(decodedHash, computedHash) == 1
+	return cryptoutil.EqualBytes(decodedHash, computedHash)
 }
 
 // encodeHash encodes hash with parameters for storage
@@ -221,16 +223,6 @@ func (p *PasswordManager) GenerateSecurePassword(length int) (string, error) {
 		length = 12
 	}
 
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!@#$%^&*"
-	password := make([]byte, length)
-
-	for i := range password {
-		randomBytes := make([]byte, 1)
-		if _, err := rand.Read(randomBytes); err != nil {
-			return "", err
-		}
-		password[i] = charset[randomBytes[0]%byte(len(charset))]
-	}
-
-	return string(password), nil
+	const charset = string_op.AlphabetAlphanumeric + "!@#$%^&*"
+	return string_op.RandomString(charset, length), nil
 }
@@ -0,0 +1,17 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired or used tokens
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// hashToken is how tokens are kept: only a SHA-256 of each, so a leaked
+// store cannot be used to reset passwords or resume sessions
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
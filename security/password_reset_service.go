@@ -0,0 +1,363 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/email"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// ErrResetRateLimited is returned when an account asked for more reset
+// links than PasswordResetService allows. Callers should answer as if the
+// link was sent, so the limit does not reveal which accounts exist.
+var ErrResetRateLimited = errors.New("too many password reset requests")
+
+// ResetRecord is a reset token as a ResetStore keeps it. The token handed
+// to the user is "<selector>.<verifier>"; only a hash of the verifier is
+// stored.
+type ResetRecord struct {
+	Selector     string
+	VerifierHash string
+	// Subject is the account the token resets
+	Subject   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// UsedAt is set once the token reset a password, was replaced by a
+	// newer one or was burned by wrong guesses
+	UsedAt time.Time
+	// Attempts counts wrong verifiers presented with the selector
+	Attempts int
+}
+
+// ResetStore persists the tokens of a PasswordResetService. MemoryResetStore
+// keeps them in memory; database.PasswordResetStore in a SQL table.
+type ResetStore interface {
+	// SaveResetToken stores a new token and marks the subject's earlier
+	// unused tokens used, so only the latest link works. If the subject
+	// already has limit tokens created since the given time it stores
+	// nothing and returns ErrResetRateLimited; a limit of 0 means none.
+	// Counting and saving are one atomic step, so parallel requests cannot
+	// slip past the limit. It returns how many tokens it counted.
+	SaveResetToken(ctx context.Context, record ResetRecord, since time.Time, limit int) (int, error)
+	// FindResetToken returns the token with selector, or
+	// ErrInvalidResetToken
+	FindResetToken(ctx context.Context, selector string) (ResetRecord, error)
+	// UseResetToken marks an unused token used at the given time. It
+	// returns ErrInvalidResetToken if the token was already used, so two
+	// concurrent resets cannot both succeed.
+	UseResetToken(ctx context.Context, selector string, at time.Time) error
+	// RecordResetFailure counts a wrong verifier for selector and returns
+	// the new count
+	RecordResetFailure(ctx context.Context, selector string) (int, error)
+	// PurgeResetTokens deletes tokens created before the given time that
+	// are used or expired at now, and returns how many it deleted
+	PurgeResetTokens(ctx context.Context, before, now time.Time) (int, error)
+}
+
+// ResetAccount names the account a reset link is for
+type ResetAccount struct {
+	// Subject is the stable key of the account, such as its lowercased
+	// email address; Consume returns it
+	Subject string
+	Email   string
+	Name    string
+}
+
+// ResetNotice is a new reset token for a ResetSender to deliver
+type ResetNotice struct {
+	Account   ResetAccount
+	Token     string
+	ExpiresAt time.Time
+	TTL       time.Duration
+}
+
+// ResetSender delivers reset tokens to the account owner
+type ResetSender interface {
+	SendReset(ctx context.Context, notice ResetNotice) error
+}
+
+// ResetSenderFunc adapts a function to ResetSender
+type ResetSenderFunc func(ctx context.Context, notice ResetNotice) error
+
+func (f ResetSenderFunc) SendReset(ctx context.Context, notice ResetNotice) error {
+	return f(ctx, notice)
+}
+
+// PasswordResetService issues and checks password reset tokens, resisting
+// brute force and abuse:
+//
+//   - each account gets at most MaxRequests links per RequestWindow, so the
+//     endpoint cannot be used to flood someone's inbox
+//   - tokens are a public selector and a secret verifier, compared in
+//     constant time after looking the selector up, and MaxAttempts wrong
+//     verifiers burn the token
+//   - tokens are single use, expire after TTL, and a new one replaces the
+//     earlier ones of the same account
+//
+// Tokens live in a ResetStore, so they survive restarts and are shared by
+// every instance when the store is a database.
+type PasswordResetService struct {
+	store ResetStore
+
+	// Sender delivers new tokens; Request fails without one
+	Sender        ResetSender
+	TTL           time.Duration
+	MaxRequests   int
+	RequestWindow time.Duration
+	MaxAttempts   int
+	Audit         AuditLog
+}
+
+// NewPasswordResetService creates a service with one-hour tokens, three
+// requests per account an hour and five guesses per token
+func NewPasswordResetService(store ResetStore, sender ResetSender) *PasswordResetService {
+	return &PasswordResetService{
+		store:         store,
+		Sender:        sender,
+		TTL:           time.Hour,
+		MaxRequests:   3,
+		RequestWindow: time.Hour,
+		MaxAttempts:   5,
+		Audit:         LoggerAuditLog,
+	}
+}
+
+// SetStore replaces where tokens are kept, such as with a
+// database.PasswordResetStore once the database is open. Tokens in the
+// previous store stop working.
+func (s *PasswordResetService) SetStore(store ResetStore) {
+	s.store = store
+}
+
+// Request issues a token for account and hands it to Sender
+func (s *PasswordResetService) Request(ctx context.Context, account ResetAccount) error {
+	if s.Sender == nil {
+		return errors.New("password reset: no sender configured")
+	}
+	now := time.Now()
+	selector, verifier := string_op.Token(16), string_op.Token(32)
+	record := ResetRecord{
+		Selector:     selector,
+		VerifierHash: hashToken(verifier),
+		Subject:      account.Subject,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.TTL),
+	}
+	n, err := s.store.SaveResetToken(ctx, record, now.Add(-s.RequestWindow), s.MaxRequests)
+	if errors.Is(err, ErrResetRateLimited) {
+		s.audit(ctx, AuditPasswordResetThrottled, account.Subject, fmt.Sprintf("%d requests in %s", n, s.RequestWindow))
+		return ErrResetRateLimited
+	}
+	if err != nil {
+		return err
+	}
+	s.audit(ctx, AuditPasswordResetRequested, account.Subject, "")
+	return s.Sender.SendReset(ctx, ResetNotice{
+		Account:   account,
+		Token:     selector + "." + verifier,
+		ExpiresAt: record.ExpiresAt,
+		TTL:       s.TTL,
+	})
+}
+
+// Check returns the subject of a valid token without using it up, for
+// showing the new-password form. Wrong verifiers count towards MaxAttempts.
+func (s *PasswordResetService) Check(ctx context.Context, token string) (string, error) {
+	record, err := s.verify(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return record.Subject, nil
+}
+
+// Consume returns the subject of a valid token and invalidates it
+func (s *PasswordResetService) Consume(ctx context.Context, token string) (string, error) {
+	record, err := s.verify(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.UseResetToken(ctx, record.Selector, time.Now()); err != nil {
+		return "", err
+	}
+	s.audit(ctx, AuditPasswordReset, record.Subject, "")
+	return record.Subject, nil
+}
+
+// Purge deletes used and expired tokens once they no longer count towards
+// the request limit
+func (s *PasswordResetService) Purge(ctx context.Context) (int, error) {
+	now := time.Now()
+	return s.store.PurgeResetTokens(ctx, now.Add(-max(s.RequestWindow, s.TTL)), now)
+}
+
+func (s *PasswordResetService) verify(ctx context.Context, token string) (ResetRecord, error) {
+	selector, verifier, ok := strings.Cut(token, ".")
+	if !ok || selector == "" || verifier == "" {
+		return ResetRecord{}, ErrInvalidResetToken
+	}
+	record, err := s.store.FindResetToken(ctx, selector)
+	if err != nil {
+		return ResetRecord{}, err
+	}
+	if !record.UsedAt.IsZero() || time.Now().After(record.ExpiresAt) {
+		return ResetRecord{}, ErrInvalidResetToken
+	}
+	if !cryptoutil.Equal(hashToken(verifier), record.VerifierHash) {
+		attempts, err := s.store.RecordResetFailure(ctx, selector)
+		if err != nil {
+			return ResetRecord{}, err
+		}
+		detail := fmt.Sprintf("wrong verifier, attempt %d", attempts)
+		if s.MaxAttempts > 0 && attempts >= s.MaxAttempts {
+			s.store.UseResetToken(ctx, selector, time.Now())
+			detail += ", token burned"
+		}
+		s.audit(ctx, AuditPasswordResetFailed, record.Subject, detail)
+		return ResetRecord{}, ErrInvalidResetToken
+	}
+	return record, nil
+}
+
+func (s *PasswordResetService) audit(ctx context.Context, typ AuditType, subject, detail string) {
+	if s.Audit == nil {
+		return
+	}
+	s.Audit.Record(ctx, AuditEvent{Time: time.Now(), Type: typ, Username: subject, Detail: detail})
+}
+
+// MemoryResetStore is a ResetStore in a map, for tests and single-instance
+// demos
+type MemoryResetStore struct {
+	mu      sync.Mutex
+	records map[string]*ResetRecord
+}
+
+// NewMemoryResetStore creates an empty MemoryResetStore
+func NewMemoryResetStore() *MemoryResetStore {
+	return &MemoryResetStore{records: make(map[string]*ResetRecord)}
+}
+
+// SaveResetToken implements ResetStore
+func (m *MemoryResetStore) SaveResetToken(ctx context.Context, record ResetRecord, since time.Time, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, r := range m.records {
+		if r.Subject == record.Subject && !r.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	if limit > 0 && n >= limit {
+		return n, ErrResetRateLimited
+	}
+	for _, r := range m.records {
+		if r.Subject == record.Subject && r.UsedAt.IsZero() {
+			r.UsedAt = record.CreatedAt
+		}
+	}
+	m.records[record.Selector] = &record
+	return n, nil
+}
+
+// FindResetToken implements ResetStore
+func (m *MemoryResetStore) FindResetToken(ctx context.Context, selector string) (ResetRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[selector]
+	if !ok {
+		return ResetRecord{}, ErrInvalidResetToken
+	}
+	return *r, nil
+}
+
+// UseResetToken implements ResetStore
+func (m *MemoryResetStore) UseResetToken(ctx context.Context, selector string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[selector]
+	if !ok || !r.UsedAt.IsZero() {
+		return ErrInvalidResetToken
+	}
+	r.UsedAt = at
+	return nil
+}
+
+// RecordResetFailure implements ResetStore
+func (m *MemoryResetStore) RecordResetFailure(ctx context.Context, selector string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[selector]
+	if !ok {
+		return 0, ErrInvalidResetToken
+	}
+	r.Attempts++
+	return r.Attempts, nil
+}
+
+// PurgeResetTokens implements ResetStore
+func (m *MemoryResetStore) PurgeResetTokens(ctx context.Context, before, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for selector, r := range m.records {
+		if r.CreatedAt.Before(before) && (!r.UsedAt.IsZero() || now.After(r.ExpiresAt)) {
+			delete(m.records, selector)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ResetEmail is the data EmailResetSender renders its templates with
+type ResetEmail struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	ResetURL  string `json:"reset_url"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+// EmailResetSender mails reset links through an email.Transport. The
+// bodies are the Template+".txt" and Template+".html" templates, rendered
+// with a ResetEmail.
+type EmailResetSender struct {
+	Transport email.Transport
+	Renderer  email.Renderer
+	From      string
+	Subject   string
+	Template  string
+	// Link turns a token into the URL the user opens
+	Link func(token string) string
+}
+
+// Email returns the template data for notice
+func (e *EmailResetSender) Email(notice ResetNotice) ResetEmail {
+	return ResetEmail{
+		Name:      notice.Account.Name,
+		Email:     notice.Account.Email,
+		ResetURL:  e.Link(notice.Token),
+		ExpiresIn: format.HumanizeDuration(notice.TTL),
+	}
+}
+
+// SendReset implements ResetSender
+func (e *EmailResetSender) SendReset(ctx context.Context, notice ResetNotice) error {
+	data := e.Email(notice)
+	msg, err := email.NewMessage().
+		From(e.From).
+		To(notice.Account.Email).
+		Subject(e.Subject).
+		RenderText(e.Renderer, e.Template+".txt", data).
+		RenderHTML(e.Renderer, e.Template+".html", data).
+		Build()
+	if err != nil {
+		return err
+	}
+	return e.Transport.Send(ctx, msg)
+}
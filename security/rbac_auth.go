@@ -252,3 +252,107 @@ func (r *RBACManager) CreatePermissionFromString(permissionStr, description stri
 		Description: description,
 	}, nil
 }
+
+// GrantedPermission is a permission a user holds and the roles granting it
+type GrantedPermission struct {
+	Name        string   `json:"name"`
+	Action      string   `json:"action"`
+	Description string   `json:"description,omitempty"`
+	GrantedBy   []string `json:"granted_by"`
+}
+
+// PermissionSummary is everything a user may do, grouped by resource, so a
+// frontend can decide what to show with one request
+type PermissionSummary struct {
+	UserID    string                         `json:"user_id"`
+	Roles     []string                       `json:"roles"`
+	Resources map[string][]GrantedPermission `json:"resources"`
+}
+
+// EffectivePermissions returns the user's permissions grouped by resource,
+// each with the roles that grant it, in role order
+func (r *RBACManager) EffectivePermissions(userID string) (*PermissionSummary, error) {
+	user, exists := r.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user %s does not exist", userID)
+	}
+
+	summary := &PermissionSummary{
+		UserID:    userID,
+		Roles:     append([]string{}, user.Roles...),
+		Resources: make(map[string][]GrantedPermission),
+	}
+	index := make(map[string]int)
+	for _, roleName := range user.Roles {
+		role, exists := r.roles[roleName]
+		if !exists {
+			continue
+		}
+
+		for _, permName := range role.Permissions {
+			resource, action := r.resourceAction(permName)
+			grants := summary.Resources[resource]
+			if i, seen := index[permName]; seen {
+				grants[i].GrantedBy = append(grants[i].GrantedBy, roleName)
+				continue
+			}
+			granted := GrantedPermission{Name: permName, Action: action, GrantedBy: []string{roleName}}
+			if permission, exists := r.permissions[permName]; exists {
+				granted.Description = permission.Description
+			}
+			index[permName] = len(grants)
+			summary.Resources[resource] = append(grants, granted)
+		}
+	}
+
+	return summary, nil
+}
+
+// resourceAction looks up the resource and action of a permission, falling
+// back to splitting a "resource:action" name
+func (r *RBACManager) resourceAction(permName string) (string, string) {
+	if permission, exists := r.permissions[permName]; exists {
+		return permission.Resource, permission.Action
+	}
+	resource, action, _ := strings.Cut(permName, ":")
+	return resource, action
+}
+
+// Can reports whether the user holds permissionName, either directly or,
+// for a "resource:action" name, through a wildcard permission such as "*:*"
+func (r *RBACManager) Can(userID, permissionName string) bool {
+	if r.HasPermission(userID, permissionName) {
+		return true
+	}
+	resource, action, ok := strings.Cut(permissionName, ":")
+	return ok && r.CheckResourceAccess(userID, resource, action)
+}
+
+// CanAll reports whether the user holds every one of the permissions
+func (r *RBACManager) CanAll(userID string, permissions ...string) bool {
+	for _, permName := range permissions {
+		if !r.Can(userID, permName) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanAny reports whether the user holds at least one of the permissions
+func (r *RBACManager) CanAny(userID string, permissions ...string) bool {
+	for _, permName := range permissions {
+		if r.Can(userID, permName) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPermissions answers Can for each of the permissions in one call
+func (r *RBACManager) CheckPermissions(userID string, permissions ...string) map[string]bool {
+	results := make(map[string]bool, len(permissions))
+	for _, permName := range permissions {
+		results[permName] = r.Can(userID, permName)
+	}
+	return results
+}
@@ -0,0 +1,377 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderProfile bundles the security headers suited to one kind of
+// response. Start from StrictAPIProfile, WebAppProfile or
+// EmbeddedIframeProfile and adjust with the With methods.
+type HeaderProfile struct {
+	Name string
+	// HSTSMaxAge turns on Strict-Transport-Security; zero leaves it off
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// CSP maps Content-Security-Policy directives to their values
+	CSP map[string]string
+	// FrameAncestors lists who may embed the page in a frame: "'none'",
+	// "'self'" or origins. It becomes the CSP frame-ancestors directive,
+	// and X-Frame-Options for browsers that predate it.
+	FrameAncestors            []string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginResourcePolicy string
+	// CacheControl is set only when the handler does not set its own
+	CacheControl string
+}
+
+// StrictAPIProfile is for JSON APIs: nothing may load, frame or cache the
+// response, and no referrer leaks
+func StrictAPIProfile() HeaderProfile {
+	return HeaderProfile{
+		Name:                      "strict-api",
+		HSTSMaxAge:                2 * 365 * 24 * time.Hour,
+		HSTSIncludeSubdomains:     true,
+		CSP:                       map[string]string{"default-src": "'none'"},
+		FrameAncestors:            []string{"'none'"},
+		ReferrerPolicy:            "no-referrer",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+		CacheControl:              "no-store",
+	}
+}
+
+// WebAppProfile is for server-rendered pages: resources come from the same
+// origin, inline styles are allowed but inline scripts are not, and only
+// the site itself may frame the page
+func WebAppProfile() HeaderProfile {
+	return HeaderProfile{
+		Name:                  "web-app",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		CSP: map[string]string{
+			"default-src": "'self'",
+			"img-src":     "'self' data:",
+			"style-src":   "'self' 'unsafe-inline'",
+			"object-src":  "'none'",
+			"base-uri":    "'self'",
+			"form-action": "'self'",
+		},
+		FrameAncestors:            []string{"'self'"},
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// EmbeddedIframeProfile is WebAppProfile for pages, such as widgets, that
+// the given origins embed in an iframe
+func EmbeddedIframeProfile(origins ...string) HeaderProfile {
+	p := WebAppProfile()
+	p.Name = "embedded-iframe"
+	p.FrameAncestors = append([]string{"'self'"}, origins...)
+	p.CrossOriginOpenerPolicy = "unsafe-none"
+	p.CrossOriginResourcePolicy = "cross-origin"
+	return p
+}
+
+// HeaderProfileByName returns the built-in profile called name:
+// "strict-api", "web-app" or "embedded-iframe" (framable by any origin
+// listed in origins)
+func HeaderProfileByName(name string, origins ...string) (HeaderProfile, error) {
+	switch name {
+	case "strict-api":
+		return StrictAPIProfile(), nil
+	case "web-app":
+		return WebAppProfile(), nil
+	case "embedded-iframe":
+		return EmbeddedIframeProfile(origins...), nil
+	}
+	return HeaderProfile{}, fmt.Errorf("unknown header profile %q (want strict-api, web-app or embedded-iframe)", name)
+}
+
+// WithCSP returns a copy of p with the CSP directive set to value, or
+// removed when value is empty
+func (p HeaderProfile) WithCSP(directive, value string) HeaderProfile {
+	p.CSP = maps.Clone(p.CSP)
+	if p.CSP == nil {
+		p.CSP = map[string]string{}
+	}
+	if value == "" {
+		delete(p.CSP, directive)
+	} else {
+		p.CSP[directive] = value
+	}
+	return p
+}
+
+// WithFrameAncestors returns a copy of p that may be framed by ancestors
+func (p HeaderProfile) WithFrameAncestors(ancestors ...string) HeaderProfile {
+	p.FrameAncestors = ancestors
+	return p
+}
+
+// Headers returns the headers the profile sends
+func (p HeaderProfile) Headers() http.Header {
+	h := http.Header{}
+	h.Set("X-Content-Type-Options", "nosniff")
+	if p.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(int(p.HSTSMaxAge.Seconds()))
+		if p.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if p.HSTSPreload {
+			hsts += "; preload"
+		}
+		h.Set("Strict-Transport-Security", hsts)
+	}
+
+	csp := maps.Clone(p.CSP)
+	if len(p.FrameAncestors) > 0 {
+		if csp == nil {
+			csp = map[string]string{}
+		}
+		csp["frame-ancestors"] = strings.Join(p.FrameAncestors, " ")
+		// X-Frame-Options cannot name other origins; browsers that
+		// understand frame-ancestors ignore it anyway
+		switch {
+		case slices.Equal(p.FrameAncestors, []string{"'none'"}):
+			h.Set("X-Frame-Options", "DENY")
+		case slices.Equal(p.FrameAncestors, []string{"'self'"}):
+			h.Set("X-Frame-Options", "SAMEORIGIN")
+		}
+	}
+	if len(csp) > 0 {
+		h.Set("Content-Security-Policy", formatCSP(csp))
+	}
+
+	set := func(name, value string) {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+	set("Referrer-Policy", p.ReferrerPolicy)
+	set("Permissions-Policy", p.PermissionsPolicy)
+	set("Cross-Origin-Opener-Policy", p.CrossOriginOpenerPolicy)
+	set("Cross-Origin-Resource-Policy", p.CrossOriginResourcePolicy)
+	set("Cache-Control", p.CacheControl)
+	return h
+}
+
+// Middleware sets the profile's headers on every response. Handlers can
+// still replace any of them.
+func (p HeaderProfile) Middleware(next http.Handler) http.Handler {
+	return NewHeaderPolicy(p).Middleware(next)
+}
+
+// HeaderPolicy picks a HeaderProfile by request path, so one server can
+// send strict headers for its API and looser ones for its pages
+type HeaderPolicy struct {
+	fallback headerRoute
+	routes   []headerRoute
+}
+
+type headerRoute struct {
+	prefix  string
+	headers http.Header
+}
+
+// NewHeaderPolicy uses profile for paths without a more specific route
+func NewHeaderPolicy(profile HeaderProfile) *HeaderPolicy {
+	return &HeaderPolicy{fallback: headerRoute{headers: profile.Headers()}}
+}
+
+// Route uses profile for paths starting with prefix; the longest matching
+// prefix wins
+func (p *HeaderPolicy) Route(prefix string, profile HeaderProfile) *HeaderPolicy {
+	p.routes = append(p.routes, headerRoute{prefix: prefix, headers: profile.Headers()})
+	slices.SortStableFunc(p.routes, func(a, b headerRoute) int { return len(b.prefix) - len(a.prefix) })
+	return p
+}
+
+// Headers returns the headers sent for path
+func (p *HeaderPolicy) Headers(path string) http.Header {
+	for _, route := range p.routes {
+		if strings.HasPrefix(path, route.prefix) {
+			return route.headers
+		}
+	}
+	return p.fallback.headers
+}
+
+// Middleware sets the headers of the request path's profile before calling
+// next. Cache-Control is left to a handler that sets its own.
+func (p *HeaderPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := p.Headers(r.URL.Path)
+		for name, values := range headers {
+			if name != "Cache-Control" {
+				w.Header()[name] = slices.Clone(values)
+			}
+		}
+		if cc := headers.Get("Cache-Control"); cc != "" {
+			w = &cacheControlWriter{ResponseWriter: w, value: cc}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheControlWriter adds a default Cache-Control if the handler set none
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value   string
+	checked bool
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if !w.checked && code >= http.StatusOK {
+		w.checked = true
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.checked {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the headers, with the default added, before streaming
+func (w *cacheControlWriter) Flush() {
+	if !w.checked {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HeaderIssue is a difference between a response and a profile found by
+// CheckHeaders
+type HeaderIssue struct {
+	Header string
+	// Problem is "missing", "weaker" or "different"
+	Problem string
+	Want    string
+	Got     string
+}
+
+func (i HeaderIssue) String() string {
+	if i.Problem == "missing" {
+		return fmt.Sprintf("%s is missing (want %q)", i.Header, i.Want)
+	}
+	return fmt.Sprintf("%s is %s: got %q, want %q", i.Header, i.Problem, i.Got, i.Want)
+}
+
+// CheckHeaders compares response headers with what profile sends and
+// reports each header that is missing or differs. CSP directives are
+// compared one by one, and an HSTS max-age shorter than the profile's is
+// reported as weaker.
+func CheckHeaders(got http.Header, profile HeaderProfile) []HeaderIssue {
+	want := profile.Headers()
+	var issues []HeaderIssue
+	for _, name := range slices.Sorted(maps.Keys(want)) {
+		w, g := want.Get(name), got.Get(name)
+		switch {
+		case g == "":
+			issues = append(issues, HeaderIssue{Header: name, Problem: "missing", Want: w})
+		case name == "Content-Security-Policy":
+			wantCSP, gotCSP := parseCSP(w), parseCSP(g)
+			for _, directive := range slices.Sorted(maps.Keys(wantCSP)) {
+				issue := HeaderIssue{Header: name + " " + directive, Want: wantCSP[directive], Got: gotCSP[directive]}
+				switch {
+				case issue.Got == "":
+					issue.Problem = "missing"
+				case issue.Got != issue.Want:
+					issue.Problem = "different"
+				default:
+					continue
+				}
+				issues = append(issues, issue)
+			}
+		case name == "Strict-Transport-Security":
+			if hstsMaxAge(g) < hstsMaxAge(w) {
+				issues = append(issues, HeaderIssue{Header: name, Problem: "weaker", Want: w, Got: g})
+			}
+		case !strings.EqualFold(g, w):
+			issues = append(issues, HeaderIssue{Header: name, Problem: "different", Want: w, Got: g})
+		}
+	}
+	return issues
+}
+
+// SelfCheck requests url with client, or http.DefaultClient when nil, and
+// checks the live response's headers against profile
+func SelfCheck(ctx context.Context, client *http.Client, url string, profile HeaderProfile) ([]HeaderIssue, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return CheckHeaders(resp.Header, profile), nil
+}
+
+// formatCSP joins directives with default-src first and the rest sorted
+func formatCSP(csp map[string]string) string {
+	names := slices.Sorted(maps.Keys(csp))
+	slices.SortStableFunc(names, func(a, b string) int {
+		return boolToInt(b == "default-src") - boolToInt(a == "default-src")
+	})
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+" "+csp[name])
+	}
+	return strings.Join(parts, "; ")
+}
+
+func parseCSP(value string) map[string]string {
+	csp := map[string]string{}
+	for _, part := range strings.Split(value, ";") {
+		name, rest, _ := strings.Cut(strings.TrimSpace(part), " ")
+		if name != "" {
+			csp[strings.ToLower(name)] = strings.Join(strings.Fields(rest), " ")
+		}
+	}
+	return csp
+}
+
+func hstsMaxAge(value string) int {
+	for _, part := range strings.Split(value, ";") {
+		if age, ok := strings.CutPrefix(strings.TrimSpace(part), "max-age="); ok {
+			n, _ := strconv.Atoi(age)
+			return n
+		}
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,193 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+var (
+	// ErrSignedURLExpired is returned for a signed URL past its expiry
+	ErrSignedURLExpired = errors.New("signed URL has expired")
+	// ErrSignedURLInvalid is returned for a URL that is unsigned, altered,
+	// or used with another method or from another IP than it was signed for
+	ErrSignedURLInvalid = errors.New("signed URL is invalid")
+)
+
+// Query parameters added by URLSigner
+const (
+	signedExpiresParam   = "expires"
+	signedMethodParam    = "method"
+	signedIPParam        = "ip"
+	signedSignatureParam = "signature"
+)
+
+// SignOptions restricts what a signed URL may be used for
+type SignOptions struct {
+	// Method is the HTTP method the URL is good for. Defaults to GET,
+	// which also allows HEAD.
+	Method string
+	// Expires is how long the URL stays valid
+	Expires time.Duration
+	// IP binds the URL to one client address, so a leaked link is useless
+	// elsewhere. Empty allows any client.
+	IP string
+}
+
+// URLSigner creates and checks expiring URLs signed with HMAC-SHA256. The
+// signature covers the path, every query parameter, the expiry, the method
+// and the bound IP, but not the scheme or host, so links survive a proxy
+// that rewrites them.
+type URLSigner struct {
+	key []byte
+
+	// ClientIP returns the address of a request's client, for IP-bound
+	// URLs. Defaults to the host of RemoteAddr; behind a proxy, use one
+	// that trusts its forwarding header.
+	ClientIP func(r *http.Request) string
+}
+
+// NewURLSigner creates a signer with key. An empty key is replaced with a
+// random one, so signed URLs stop working when the process restarts.
+func NewURLSigner(key []byte) *URLSigner {
+	if len(key) == 0 {
+		key = string_op.RandomBytes(32)
+	}
+	return &URLSigner{key: key, ClientIP: remoteIP}
+}
+
+// Sign returns rawURL with an expiry, the options and a signature added
+// to its query
+func (s *URLSigner) Sign(rawURL string, opts SignOptions) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL to sign: %w", err)
+	}
+	if opts.Expires <= 0 {
+		return "", errors.New("signed URL needs a positive expiry")
+	}
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+
+	query := u.Query()
+	for _, param := range []string{signedExpiresParam, signedMethodParam, signedIPParam, signedSignatureParam} {
+		query.Del(param)
+	}
+	query.Set(signedExpiresParam, strconv.FormatInt(time.Now().Add(opts.Expires).Unix(), 10))
+	query.Set(signedMethodParam, opts.Method)
+	if opts.IP != "" {
+		ip := net.ParseIP(opts.IP)
+		if ip == nil {
+			return "", fmt.Errorf("invalid IP to bind: %q", opts.IP)
+		}
+		query.Set(signedIPParam, ip.String())
+	}
+	query.Set(signedSignatureParam, s.signature(u.EscapedPath(), query))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// SignRequest signs req's URL in place for its method, so a client can
+// hand the request to a third party to perform
+func (s *URLSigner) SignRequest(req *http.Request, expires time.Duration) error {
+	signed, err := s.Sign(req.URL.String(), SignOptions{Method: req.Method, Expires: expires})
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		return err
+	}
+	req.URL = u
+	return nil
+}
+
+// Verify checks that u was signed by s, has not expired, and is being
+// used with method from clientIP
+func (s *URLSigner) Verify(method string, u *url.URL, clientIP string) error {
+	query := u.Query()
+	given, err := base64.RawURLEncoding.DecodeString(query.Get(signedSignatureParam))
+	if err != nil || len(given) == 0 {
+		return ErrSignedURLInvalid
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.signature(u.EscapedPath(), query))
+	if !hmac.Equal(given, expected) {
+		return ErrSignedURLInvalid
+	}
+
+	// The parameters below are covered by the signature, so they are the
+	// signer's and can be trusted
+	expires, err := strconv.ParseInt(query.Get(signedExpiresParam), 10, 64)
+	if err != nil {
+		return ErrSignedURLInvalid
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignedURLExpired
+	}
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if query.Get(signedMethodParam) != method {
+		return fmt.Errorf("%w: signed for %s", ErrSignedURLInvalid, query.Get(signedMethodParam))
+	}
+	if bound := query.Get(signedIPParam); bound != "" {
+		if ip := net.ParseIP(clientIP); ip == nil || ip.String() != bound {
+			return fmt.Errorf("%w: bound to another client", ErrSignedURLInvalid)
+		}
+	}
+	return nil
+}
+
+// VerifyRequest checks the signed URL of an incoming request
+func (s *URLSigner) VerifyRequest(r *http.Request) error {
+	return s.Verify(r.Method, r.URL, s.ClientIP(r))
+}
+
+// Middleware lets only requests with a valid signed URL through to next,
+// answering others with 403 Forbidden, or 410 Gone for an expired link
+func (s *URLSigner) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.VerifyRequest(r); err != nil {
+			status := http.StatusForbidden
+			if errors.Is(err, ErrSignedURLExpired) {
+				status = http.StatusGone
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signature is the HMAC of the path and every query parameter except the
+// signature itself, in url.Values.Encode's sorted order
+func (s *URLSigner) signature(path string, query url.Values) string {
+	signed := url.Values{}
+	for name, values := range query {
+		if name != signedSignatureParam {
+			signed[name] = values
+		}
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path + "?" + signed.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// remoteIP returns the host part of r.RemoteAddr
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,99 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// totpEncoding is the unpadded base32 authenticator apps expect secrets in
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP generates and checks RFC 6238 time-based one-time passwords, the
+// six-digit codes shown by authenticator apps
+type TOTP struct {
+	secret []byte
+
+	Digits int
+	Period time.Duration
+	// Skew is how many periods either side of now are accepted, to allow
+	// for clock drift and slow typing
+	Skew int
+}
+
+// GenerateTOTPSecret returns a new random 160-bit secret in base32
+func GenerateTOTPSecret() string {
+	return totpEncoding.EncodeToString(string_op.RandomBytes(20))
+}
+
+// NewTOTP creates a TOTP for a base32 secret with the usual six digits,
+// 30 second period and one period of skew
+func NewTOTP(secret string) (*TOTP, error) {
+	key, err := totpEncoding.DecodeString(strings.TrimRight(strings.ToUpper(strings.ReplaceAll(secret, " ", "")), "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return &TOTP{secret: key, Digits: 6, Period: 30 * time.Second, Skew: 1}, nil
+}
+
+// Code returns the code for the period containing at
+func (t *TOTP) Code(at time.Time) string {
+	return t.code(t.step(at))
+}
+
+// Validate checks code against the periods around at and returns the
+// matching time step, which callers should remember to refuse the same
+// code a second time
+func (t *TOTP) Validate(code string, at time.Time) (int64, bool) {
+	if len(code) != t.Digits {
+		return 0, false
+	}
+	now := t.step(at)
+	for step := now - int64(t.Skew); step <= now+int64(t.Skew); step++ {
+		if cryptoutil.Equal(t.code(step), code) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth:// URI that authenticator apps read
+// from a QR code
+func (t *TOTP) ProvisioningURI(issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", totpEncoding.EncodeToString(t.secret))
+	params.Set("issuer", issuer)
+	params.Set("digits", fmt.Sprint(t.Digits))
+	params.Set("period", fmt.Sprint(int(t.Period.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func (t *TOTP) step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period.Seconds())
+}
+
+// code is the HOTP value (RFC 4226) of counter step
+func (t *TOTP) code(step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, t.secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%mod)
+}
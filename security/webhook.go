@@ -0,0 +1,295 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWebhookSignature is returned for a delivery that is unsigned or
+	// whose signature does not match its body
+	ErrWebhookSignature = errors.New("webhook signature does not match")
+	// ErrWebhookExpired is returned for a delivery signed outside the
+	// verifier's tolerance window
+	ErrWebhookExpired = errors.New("webhook timestamp outside tolerance")
+	// ErrWebhookReplayed is returned for a delivery that was already
+	// accepted
+	ErrWebhookReplayed = errors.New("webhook delivery replayed")
+)
+
+// MaxWebhookBody caps the body read to check a webhook's signature
+var MaxWebhookBody int64 = 5 << 20
+
+// WebhookVerifier checks the signature of a webhook delivery and returns
+// an ID unique to it, used to spot replays. The ID must come from signed
+// data: an attacker replaying a captured delivery can change any header
+// the signature does not cover, or re-encode the signature itself.
+type WebhookVerifier interface {
+	Verify(header http.Header, body []byte) (deliveryID string, err error)
+}
+
+// GitHubWebhook verifies GitHub deliveries: X-Hub-Signature-256 holds
+// "sha256=" and the hex HMAC-SHA256 of the body. X-GitHub-Delivery is not
+// signed, so the delivery is identified by its MAC; GitHub payloads differ
+// from one event to the next, so only a replay repeats it.
+type GitHubWebhook struct {
+	Secret string
+}
+
+func (g GitHubWebhook) Verify(header http.Header, body []byte) (string, error) {
+	mac, err := verifyGitHubSignature(g.Secret, header.Get("X-Hub-Signature-256"), body)
+	if err != nil {
+		return "", err
+	}
+	return "github:" + hex.EncodeToString(mac), nil
+}
+
+// VerifyGitHubSignature checks an X-Hub-Signature-256 header against body
+func VerifyGitHubSignature(secret, signature string, body []byte) error {
+	_, err := verifyGitHubSignature(secret, signature, body)
+	return err
+}
+
+// verifyGitHubSignature returns the MAC that matched
+func verifyGitHubSignature(secret, signature string, body []byte) ([]byte, error) {
+	sum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return nil, ErrWebhookSignature
+	}
+	expected := hmacSum(sha256.New, secret, body)
+	if err := compareHex(sum, expected); err != nil {
+		return nil, err
+	}
+	return expected, nil
+}
+
+// StripeWebhook verifies Stripe-style deliveries, whose Stripe-Signature
+// header is "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Several
+// v1 entries are allowed while a secret is being rolled. Signing the time
+// lets Tolerance bound how late a delivery may be replayed.
+type StripeWebhook struct {
+	Secret    string
+	Tolerance time.Duration
+}
+
+// NewStripeWebhook accepts deliveries signed with secret up to five
+// minutes ago, Stripe's own default
+func NewStripeWebhook(secret string) StripeWebhook {
+	return StripeWebhook{Secret: secret, Tolerance: 5 * time.Minute}
+}
+
+// Verify identifies the delivery by its timestamp and the v1 signature that
+// matched; the header's other entries are not signed and may be altered
+func (s StripeWebhook) Verify(header http.Header, body []byte) (string, error) {
+	timestamp, mac, err := verifyStripeSignature(s.Secret, header.Get("Stripe-Signature"), body, s.Tolerance)
+	if err != nil {
+		return "", err
+	}
+	return "stripe:" + timestamp + ":" + hex.EncodeToString(mac), nil
+}
+
+// VerifyStripeSignature checks a Stripe-Signature header against body,
+// accepting timestamps up to tolerance away from now
+func VerifyStripeSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	_, _, err := verifyStripeSignature(secret, header, body, tolerance)
+	return err
+}
+
+// verifyStripeSignature returns the signed timestamp and the MAC that matched
+func verifyStripeSignature(secret, header string, body []byte, tolerance time.Duration) (string, []byte, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return "", nil, ErrWebhookSignature
+	}
+
+	signed := append([]byte(timestamp+"."), body...)
+	expected := hmacSum(sha256.New, secret, signed)
+	matched := false
+	for _, signature := range signatures {
+		if compareHex(signature, expected) == nil {
+			matched = true
+		}
+	}
+	if !matched {
+		return "", nil, ErrWebhookSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return "", nil, fmt.Errorf("%w: signed %s ago", ErrWebhookExpired, age.Round(time.Second))
+	}
+	return timestamp, expected, nil
+}
+
+// HMACWebhook verifies providers that put an HMAC of the body in a header,
+// as hex or base64, optionally behind a prefix such as "sha256="
+type HMACWebhook struct {
+	Secret string
+	// Header holds the signature. Defaults to X-Signature.
+	Header string
+	Prefix string
+	// Hash defaults to sha256.New; sha1.New suits older providers
+	Hash func() hash.Hash
+	// Base64 reads the signature as standard base64 instead of hex
+	Base64 bool
+}
+
+// Verify identifies the delivery by its decoded MAC, so re-encoding the
+// signature, such as switching the case of its hex, is still a replay
+func (h HMACWebhook) Verify(header http.Header, body []byte) (string, error) {
+	name := h.Header
+	if name == "" {
+		name = "X-Signature"
+	}
+	newHash := h.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	signature, ok := strings.CutPrefix(header.Get(name), h.Prefix)
+	if !ok || signature == "" {
+		return "", ErrWebhookSignature
+	}
+
+	expected := hmacSum(newHash, h.Secret, body)
+	if h.Base64 {
+		given, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(given, expected) {
+			return "", ErrWebhookSignature
+		}
+	} else if err := compareHex(signature, expected); err != nil {
+		return "", err
+	}
+
+	return "hmac:" + hex.EncodeToString(expected), nil
+}
+
+// VerifyWebhook reads r's body, checks it with verifier and puts the body
+// back for the handler. It returns the body and the delivery ID.
+func VerifyWebhook(r *http.Request, verifier WebhookVerifier) ([]byte, string, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxWebhookBody+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(body)) > MaxWebhookBody {
+		return nil, "", fmt.Errorf("webhook body exceeds %d bytes", MaxWebhookBody)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	id, err := verifier.Verify(r.Header, body)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, id, nil
+}
+
+// NonceCache remembers delivery IDs for WebhookMiddleware. cache.Memory and
+// cache.Redis implement it; Redis shares it between server instances.
+type NonceCache interface {
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// WebhookMiddleware lets through deliveries that verifier accepts and
+// that nonces has not seen within window, answering others with 401, or
+// 409 Conflict for a replay. A delivery whose handler fails with a 5xx is
+// forgotten again, so the provider's retry is accepted.
+func WebhookMiddleware(verifier WebhookVerifier, nonces NonceCache, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, id, err := VerifyWebhook(r, verifier)
+			if err != nil {
+				logger.Warn("Rejected webhook", "path", r.URL.Path, "error", err)
+				http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+				return
+			}
+
+			key := "webhook:" + id
+			seen, err := nonces.Increment(r.Context(), key, 1, window)
+			if err != nil {
+				logger.Error("Webhook nonce cache unavailable", "error", err)
+				http.Error(w, "Webhook could not be processed", http.StatusServiceUnavailable)
+				return
+			}
+			if seen > 1 {
+				logger.Warn("Rejected webhook", "path", r.URL.Path, "error", ErrWebhookReplayed, "delivery", id)
+				http.Error(w, ErrWebhookReplayed.Error(), http.StatusConflict)
+				return
+			}
+
+			sw := &webhookStatusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			if sw.status >= http.StatusInternalServerError {
+				if err := nonces.Delete(context.WithoutCancel(r.Context()), key); err != nil {
+					logger.Warn("Failed to forget webhook delivery", "delivery", id, "error", err)
+				}
+			}
+		})
+	}
+}
+
+type webhookStatusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *webhookStatusWriter) WriteHeader(code int) {
+	if !w.wrote && code >= http.StatusOK {
+		w.wrote = true
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *webhookStatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SignGitHubWebhook returns the X-Hub-Signature-256 value for body, for
+// tests and for sending webhooks of one's own
+func SignGitHubWebhook(secret string, body []byte) string {
+	return "sha256=" + hex.EncodeToString(hmacSum(sha256.New, secret, body))
+}
+
+// SignStripeWebhook returns a Stripe-Signature value for body signed at t
+func SignStripeWebhook(secret string, body []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	signed := append([]byte(timestamp+"."), body...)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(hmacSum(sha256.New, secret, signed))
+}
+
+func hmacSum(newHash func() hash.Hash, secret string, data []byte) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func compareHex(signature string, expected []byte) error {
+	given, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || !hmac.Equal(given, expected) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/cron"
+	"github.com/jerrychou/go-practice/cryptoutil"
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/format"
+)
+
+// Cron runs the server's periodic jobs, started by Server.Start. Its run
+// history is kept in memory unless replaced with Cron.SetHistory, for
+// example with a database.CronHistory.
+var Cron = cron.New(cron.Options{})
+
+func init() {
+	Cron.Add("purge_reset_tokens", "@every 15m", func(ctx context.Context) error {
+		n, err := PasswordResets.Purge(ctx)
+		if n > 0 {
+			logger.Info("Purged expired reset tokens", "count", n)
+		}
+		return err
+	})
+	Cron.Add("purge_upload_sessions", "@hourly", func(ctx context.Context) error {
+		if n := PurgeUploadSessions(); n > 0 {
+			logger.Info("Purged abandoned uploads", "count", n)
+		}
+		return nil
+	})
+	Cron.Add("runtime_stats", "@hourly", func(ctx context.Context) error {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		logger.Info("Runtime stats",
+			"goroutines", runtime.NumGoroutine(),
+			"heap", format.HumanizeBytes(int64(mem.HeapAlloc)),
+			"gc_cycles", mem.NumGC)
+		return nil
+	}, cron.WithMissedRuns(cron.MissedRunOnce))
+}
+
+// QueryProfiler records the queries on DB when Bootstrap turns profiling
+// on, as it does in the development environment
+var QueryProfiler *database.QueryProfiler
+
+// AdminMiddleware guards admin endpoints. Requests must carry
+// "Authorization: Bearer $ADMIN_TOKEN"; when ADMIN_TOKEN is unset, only
+// requests from the loopback interface are allowed.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminAllowed(r, os.Getenv("ADMIN_TOKEN")) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Admin access required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func adminAllowed(r *http.Request, token string) bool {
+	if token == "" {
+		ip := net.ParseIP(clientIP(r))
+		return ip != nil && ip.IsLoopback()
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && cryptoutil.Equal(given, token)
+}
+
+// CronJobsHandler lists the scheduled jobs
+func CronJobsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use GET to list jobs"})
+		return
+	}
+	jobs := Cron.List()
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: strconv.Itoa(len(jobs)) + " scheduled jobs",
+		Data:    jobs,
+	})
+}
+
+// CronJobHandler serves one job:
+//
+//	GET  /admin/cron/jobs/{name}?limit=20 - state and recent runs
+//	POST /admin/cron/jobs/{name}/trigger  - run now
+//	POST /admin/cron/jobs/{name}/pause    - stop scheduled runs
+//	POST /admin/cron/jobs/{name}/resume   - restart scheduled runs
+func CronJobHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/cron/jobs/"), "/")
+
+	if action == "" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Use GET to inspect a job"})
+			return
+		}
+		cronJobDetails(w, r, name)
+		return
+	}
+
+	var act func(string) error
+	var done string
+	switch action {
+	case "trigger":
+		act, done = Cron.Trigger, "Job triggered"
+	case "pause":
+		act, done = Cron.Pause, "Job paused"
+	case "resume":
+		act, done = Cron.Resume, "Job resumed"
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Unknown action " + strconv.Quote(action)})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to " + action + " a job"})
+		return
+	}
+
+	if err := act(name); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, cron.ErrJobNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, cron.ErrJobRunning):
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+	logger.Info("Cron job changed from admin API", "job", name, "action", action, "client", clientIP(r))
+	info, _ := Cron.Get(name)
+	status := http.StatusOK
+	if action == "trigger" {
+		status = http.StatusAccepted
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: done, Data: info})
+}
+
+func cronJobDetails(w http.ResponseWriter, r *http.Request, name string) {
+	info, err := Cron.Get(name)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 1000 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	runs, err := Cron.History(ctx, name, limit)
+	if err != nil {
+		logger.Error("Failed to read cron history", "job", name, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Could not read run history"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: "Job retrieved successfully",
+		Data: map[string]any{
+			"job":  info,
+			"runs": runs,
+		},
+	})
+}
+
+// QueriesHandler shows the queries recorded by QueryProfiler:
+//
+//	GET    /admin/queries?limit=50&sort=slowest&slow=true - latest, or slowest, queries
+//	DELETE /admin/queries                                 - forget them
+func QueriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if QueryProfiler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Query profiling is off; set database.profile.enabled or run in development"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodDelete:
+		QueryProfiler.Reset()
+		logger.Info("Query profile cleared from admin API", "client", clientIP(r))
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Query profile cleared"})
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use GET to list queries or DELETE to clear them"})
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 1000 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	var queries []database.QueryProfile
+	switch query.Get("sort") {
+	case "", "recent":
+		queries = QueryProfiler.Recent(math.MaxInt)
+	case "slowest":
+		queries = QueryProfiler.Slowest(math.MaxInt)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "sort must be recent or slowest"})
+		return
+	}
+	if query.Get("slow") == "true" {
+		queries = slices.DeleteFunc(queries, func(q database.QueryProfile) bool { return !q.Slow })
+	}
+	queries = queries[:min(limit, len(queries))]
+
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: strconv.Itoa(len(queries)) + " queries",
+		Data:    queries,
+	})
+}
@@ -0,0 +1,77 @@
+// Live dashboard: renders the stats and log events sent over /dashboard/ws
+(function () {
+  "use strict";
+  var maxLogLines = 200;
+  var $ = function (id) { return document.getElementById(id); };
+
+  function renderStats(s) {
+    $("rps").textContent = s.requests_per_second.toFixed(1);
+    $("total").textContent = s.requests_total;
+    $("inflight").textContent = s.in_flight;
+    $("goroutines").textContent = s.goroutines;
+    $("heap").textContent = s.heap;
+    $("viewers").textContent = s.viewers;
+
+    var pools = s.pools || {};
+    var names = Object.keys(pools).sort();
+    var table = $("pools");
+    table.textContent = "";
+    if (names.length === 0) {
+      table.insertRow().insertCell().textContent = "No database pools registered";
+      return;
+    }
+    var columns = ["open", "in_use", "idle", "max_open"];
+    var head = table.insertRow();
+    ["pool"].concat(columns).forEach(function (c) {
+      var th = document.createElement("th");
+      th.textContent = c;
+      head.appendChild(th);
+    });
+    names.forEach(function (name) {
+      var row = table.insertRow();
+      row.insertCell().textContent = name;
+      columns.forEach(function (c) { row.insertCell().textContent = pools[name][c] || 0; });
+    });
+  }
+
+  function appendLog(l) {
+    var div = document.createElement("div");
+    div.className = l.level;
+    var time = new Date(l.time).toLocaleTimeString();
+    div.textContent = time + " " + l.level.toUpperCase() + " " +
+      (l.logger ? "[" + l.logger + "] " : "") + l.message + (l.fields ? " " + l.fields : "");
+    var logs = $("logs");
+    var atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 4;
+    logs.appendChild(div);
+    while (logs.childNodes.length > maxLogLines) {
+      logs.removeChild(logs.firstChild);
+    }
+    if (atBottom) {
+      logs.scrollTop = logs.scrollHeight;
+    }
+  }
+
+  function connect() {
+    var scheme = location.protocol === "https:" ? "wss://" : "ws://";
+    var ws = new WebSocket(scheme + location.host + "/dashboard/ws");
+    ws.onopen = function () {
+      $("status").textContent = "Live";
+      $("status").className = "live";
+    };
+    ws.onmessage = function (msg) {
+      var event = JSON.parse(msg.data);
+      if (event.type === "stats") {
+        renderStats(event.stats);
+      } else if (event.type === "log") {
+        appendLog(event.log);
+      }
+    };
+    ws.onclose = function () {
+      $("status").textContent = "Disconnected, retrying…";
+      $("status").className = "";
+      setTimeout(connect, 2000);
+    };
+  }
+
+  connect();
+})();
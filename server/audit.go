@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/jerrychou/go-practice/security"
+)
+
+// Audit records the security events of WebAuth, PasswordResets, IPFilter,
+// CORS and UploadScanning: it logs them and archives them to Files under
+// "audit/" for 90 days. The archive_audit_log job flushes it every minute,
+// and App.Stop flushes what is left.
+var Audit = func() *security.AuditArchiver {
+	a := security.NewAuditArchiver(nil, security.LoggerAuditLog)
+	a.Retention = 90 * 24 * time.Hour
+	return a
+}()
+
+func init() {
+	PasswordResets.Audit = Audit
+	CORS.Audit = Audit
+	UploadScanning.Audit = Audit
+
+	Cron.Add("archive_audit_log", "@every 1m", func(ctx context.Context) error {
+		if _, err := files(); err != nil {
+			return err
+		}
+		return Audit.Flush(ctx)
+	})
+	Cron.Add("prune_audit_log", "@daily", func(ctx context.Context) error {
+		if _, err := files(); err != nil {
+			return err
+		}
+		n, err := Audit.Prune(ctx, time.Now())
+		if n > 0 {
+			logger.Info("Pruned audit log segments", "count", n)
+		}
+		return err
+	})
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/security"
+)
+
+// App is the demo application wired from one config file by Bootstrap
+type App struct {
+	// Overrides layers the admin API's temporary overrides over the
+	// configuration; read the result with Config
+	Overrides *config.Overrides[config.FileConfig]
+	Server    *Server
+	// DB is nil without database.url
+	DB *sql.DB
+	// Cache is Redis when services.redis.url is set, else in memory; nil
+	// unless features.enable_cache is on
+	Cache cache.Cache
+
+	closers []io.Closer
+}
+
+// Bootstrap builds the application from cfg: logging, the database pool,
+// the cache, security settings and the HTTP server. Sections left empty
+// keep the server's defaults. It sets the package's globals, so only one
+// App should run per process.
+func Bootstrap(cfg config.FileConfig) (*App, error) {
+	app := &App{Overrides: config.NewOverrides(cfg)}
+	if err := app.wire(); err != nil {
+		app.close()
+		return nil, err
+	}
+	app.Overrides.OnChange(app.apply)
+	ConfigOverrides = app.Overrides
+	return app, nil
+}
+
+// Config returns the configuration in effect: the one loaded, with any
+// active overrides applied
+func (a *App) Config() config.FileConfig {
+	return a.Overrides.Get()
+}
+
+func (a *App) wire() error {
+	cfg := a.Config()
+	if cfg.Server.Port < 0 || cfg.Server.Port > 65535 {
+		return fmt.Errorf("server.port must be between 1 and 65535")
+	}
+
+	// Logging first, so the rest of the wiring logs through it
+	if cfg.Logging != (config.LoggingConfig{}) {
+		l, closer, err := format.NewLoggerFromOptions(format.LoggerOptions(cfg.Logging))
+		if err != nil {
+			return fmt.Errorf("logging: %w", err)
+		}
+		format.SetDefault(l)
+		a.closers = append(a.closers, closer)
+	}
+
+	if cfg.Database.URL != "" {
+		var profiler *database.QueryProfiler
+		if profile := cfg.Database.Profile; profile.Enabled || cfg.IsDevelopment() {
+			profiler = database.NewQueryProfiler(database.ProfilerOptions{
+				Capacity:      profile.Capacity,
+				SlowThreshold: profile.SlowThreshold.Duration(),
+				Explain:       profile.Explain,
+				Analyze:       profile.Analyze,
+			})
+		}
+		pool, err := openPool(cfg.Database, profiler)
+		if err != nil {
+			return fmt.Errorf("database: %w", err)
+		}
+		a.DB = pool.GetDB()
+		a.closers = append(a.closers, pool)
+		DB = a.DB
+		QueryProfiler = profiler
+		PasswordResets.SetStore(database.NewPasswordResetStore(a.DB))
+	}
+
+	if cfg.Features.EnableCache {
+		if err := a.openCache(cfg.Services); err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+	}
+
+	if err := configureSecurity(cfg.Security); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+
+	port := cfg.Server.Port
+	if port == 0 {
+		port = 8080
+	}
+	a.Server = New(strconv.Itoa(port))
+	a.Server.Host = cfg.Server.Host
+	a.Server.ReadTimeout = cfg.Server.ReadTimeout.Duration()
+	a.Server.WriteTimeout = cfg.Server.WriteTimeout.Duration()
+	a.Server.IdleTimeout = cfg.Server.IdleTimeout.Duration()
+	if cfg.Server.MaxUploadSize > 0 {
+		MaxUploadSize = cfg.Server.MaxUploadSize.Bytes()
+	}
+	a.Server.SetHandler(SetupRoutesWithFeatures(cfg.Features))
+
+	logger.Info("Application configured",
+		"app", cfg.App.Name, "version", cfg.App.Version, "environment", cfg.App.Environment,
+		"database", a.DB != nil, "cache", cacheKind(a.Cache))
+	return nil
+}
+
+// openPool opens the database at cfg.URL, through profiler when it is not
+// nil, and checks that it is reachable within the connection timeout
+func openPool(cfg config.DatabaseConfig, profiler *database.QueryProfiler) (*database.ConnectionPoolManager, error) {
+	driver, dsn, err := driverFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	open := sql.Open
+	if profiler != nil {
+		open = profiler.Open
+	}
+	db, err := open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig := database.GetDefaultPoolConfig()
+	poolConfig.MetricsName = "app"
+	if cfg.MaxConnections > 0 {
+		poolConfig.MaxOpenConns = cfg.MaxConnections
+		poolConfig.MaxIdleConns = min(poolConfig.MaxIdleConns, cfg.MaxConnections)
+	}
+	if cfg.MinConnections > 0 {
+		poolConfig.MaxIdleConns = cfg.MinConnections
+	}
+	if driver == "sqlite3" {
+		poolConfig.MaxOpenConns, poolConfig.MaxIdleConns = 1, 1
+	}
+	pool := database.NewConnectionPoolManager(db, poolConfig)
+
+	timeout := cfg.ConnectionTimeout.Duration()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", driver, err)
+	}
+	return pool, nil
+}
+
+// driverFor picks the SQL driver from the database URL's scheme
+func driverFor(cfg config.DatabaseConfig) (driver, dsn string, err error) {
+	scheme, rest, _ := strings.Cut(cfg.URL, "://")
+	switch scheme {
+	case "postgres", "postgresql":
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return "", "", err
+		}
+		if q := u.Query(); cfg.SSLMode != "" && q.Get("sslmode") == "" {
+			q.Set("sslmode", cfg.SSLMode)
+			u.RawQuery = q.Encode()
+		}
+		return "postgres", u.String(), nil
+	case "mysql":
+		return "mysql", rest, nil
+	case "sqlite", "sqlite3":
+		return "sqlite3", rest, nil
+	}
+	if strings.HasPrefix(cfg.URL, "file:") || slices.Contains([]string{".db", ".sqlite", ".sqlite3"}, strings.ToLower(filepath.Ext(cfg.URL))) {
+		return "sqlite3", cfg.URL, nil
+	}
+	return "", "", fmt.Errorf("cannot tell the driver of %q; use a postgres://, mysql:// or sqlite:// URL", cfg.URL)
+}
+
+func (a *App) openCache(cfg config.ServiceConfig) error {
+	if cfg.Redis.URL == "" {
+		a.Cache = cache.NewMemory(cache.MemoryOptions{MaxEntries: cfg.Cache.MaxSize, MaxBytes: cfg.Cache.MaxBytes.Bytes()})
+	} else {
+		opts, err := cache.ParseRedisURL(cfg.Redis.URL)
+		if err != nil {
+			return err
+		}
+		opts.PoolSize = cfg.Redis.PoolSize
+		opts.DialTimeout = cfg.Redis.Timeout.Duration()
+		opts.Timeout = cfg.Redis.Timeout.Duration()
+		opts.Prefix = "go-practice:"
+		redis := cache.NewRedis(opts)
+		a.closers = append(a.closers, redis)
+		a.Cache = redis
+	}
+
+	// Counters shared through the cache let instances agree on rate limits,
+	// on which webhook deliveries they have seen and on idempotency keys
+	if counter, ok := a.Cache.(interface {
+		cache.Counter
+		Delete(ctx context.Context, key string) error
+	}); ok {
+		RateLimitCounter = counter
+		WebhookNonces = counter
+	}
+	if store, ok := a.Cache.(IdempotencyStore); ok {
+		Idempotency.Store = store
+	}
+	return nil
+}
+
+func configureSecurity(cfg config.SecurityConfig) error {
+	if cfg.JWTSecret != "" {
+		Auth = security.NewJWTAuth(cfg.JWTSecret)
+	}
+	if cfg.BCryptCost > 0 {
+		passwords = security.NewPasswordManager(security.NewBcryptHasher(cfg.BCryptCost))
+		WebAuth = newWebAuth()
+	}
+	rules := cfg.IPFilter
+	if len(rules.Allow)+len(rules.Deny)+len(rules.AllowCountries)+len(rules.DenyCountries)+len(rules.TrustedProxies) > 0 {
+		filter, err := security.NewIPFilter(rules)
+		if err != nil {
+			return err
+		}
+		filter.Audit = Audit
+		IPFilter = filter
+		Links.ClientIP = filter.ClientIP
+	}
+	if cors := cfg.CORS; len(cors.Default.AllowedOrigins)+len(cors.Routes) > 0 {
+		policy, err := security.NewCORSPolicyFromConfig(cors)
+		if err != nil {
+			return err
+		}
+		policy.Audit = Audit
+		CORS = policy
+	}
+	CORS.AuditOnly = cfg.CORS.Audit
+
+	scan := cfg.UploadScan
+	scanners := []security.Scanner{
+		&security.ScriptScanner{},
+		&security.ArchiveScanner{MaxRatio: scan.MaxCompressionRatio, MaxUnpacked: int64(scan.MaxUnpackedSize)},
+	}
+	if len(scan.Command) > 0 {
+		scanners = append(scanners, &security.CommandScanner{Command: scan.Command, Timeout: time.Duration(scan.Timeout)})
+	}
+	UploadScanning = &security.UploadScanner{Scanners: scanners, Audit: Audit, FailOpen: scan.FailOpen}
+	return nil
+}
+
+func cacheKind(c cache.Cache) string {
+	switch c.(type) {
+	case *cache.Redis:
+		return "redis"
+	case *cache.Memory:
+		return "memory"
+	}
+	return "none"
+}
+
+// Reload makes cfg the configuration underneath the active overrides and
+// applies the parts that can change while the server runs: the log level,
+// the feature flags that switch middleware, and security settings such as
+// IP filter and CORS rules. The database, cache and listening address need
+// a restart. If applying fails, the previous configuration stays.
+func (a *App) Reload(cfg config.FileConfig) error {
+	if err := a.Overrides.SetBase(cfg); err != nil {
+		return err
+	}
+	logger.Info("Configuration reloaded", "app", cfg.App.Name, "version", cfg.App.Version, "overrides", len(a.Overrides.Active()))
+	return nil
+}
+
+// apply puts the runtime-changeable parts of cfg into effect, for Reload
+// and for overrides being set and expiring
+func (a *App) apply(cfg config.FileConfig) error {
+	if cfg.Logging.Level != "" {
+		level, err := format.ParseLevel(cfg.Logging.Level)
+		if err != nil {
+			return fmt.Errorf("logging: %w", err)
+		}
+		format.SetLevel(level)
+	}
+	if err := configureSecurity(cfg.Security); err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+	for name, enabled := range map[string]bool{
+		"cors":       cfg.Features.EnableCORS,
+		"rate_limit": cfg.Features.EnableRateLimit,
+		"metrics":    cfg.Features.EnableMetrics,
+	} {
+		Features.Set(name, enabled)
+	}
+	if cfg.Server.MaxUploadSize > 0 {
+		MaxUploadSize = cfg.Server.MaxUploadSize.Bytes()
+	}
+	return nil
+}
+
+// Start serves HTTP until Stop is called, returning nil then
+func (a *App) Start() error {
+	cfg := a.Config()
+	logger.Infof("Starting %s %s", cfg.App.Name, cfg.App.Version)
+	if err := a.Server.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop lets active requests finish until ctx is done, archives the audit
+// events still buffered, then closes the database, the cache and the log
+// file
+func (a *App) Stop(ctx context.Context) error {
+	err := a.Server.Shutdown(ctx)
+	if _, filesErr := files(); filesErr == nil {
+		if flushErr := Audit.Flush(ctx); flushErr != nil {
+			err = errors.Join(err, fmt.Errorf("audit log: %w", flushErr))
+		}
+	}
+	return errors.Join(err, a.close())
+}
+
+// close releases resources in the reverse order they were opened
+func (a *App) close() error {
+	a.Overrides.Stop()
+	var errs []error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		errs = append(errs, a.closers[i].Close())
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/database"
+)
+
+// Changes carries database change events to the /api/changes stream. The
+// server binary feeds it from a database.ChangeListener when DATABASE_URL
+// is set.
+var Changes = NewChangeFeed()
+
+// ChangeFeed fans change events out to subscribers. A subscriber that
+// falls too far behind is dropped rather than slowing the others down; an
+// EventSource client then reconnects on its own.
+type ChangeFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan database.ChangeEvent]struct{}
+}
+
+// NewChangeFeed creates a feed with no subscribers
+func NewChangeFeed() *ChangeFeed {
+	return &ChangeFeed{subscribers: make(map[chan database.ChangeEvent]struct{})}
+}
+
+// Subscribe returns a channel of events published from now on, and a
+// function that ends the subscription
+func (f *ChangeFeed) Subscribe() (<-chan database.ChangeEvent, func()) {
+	ch := make(chan database.ChangeEvent, 64)
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() { f.remove(ch) }
+}
+
+// Publish sends event to every subscriber without blocking
+func (f *ChangeFeed) Publish(event database.ChangeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logger.Warn("Dropping slow change subscriber", "buffered", len(ch))
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (f *ChangeFeed) remove(ch chan database.ChangeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// ChangesHandler streams Changes as server-sent events: "change" events
+// carrying a database.ChangeEvent as JSON, and "resync" when events may
+// have been missed. ?table=users limits the stream to one table.
+func ChangesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		WriteProblem(w, r, NewProblem(http.StatusMethodNotAllowed, "method_not_allowed", "Use GET with an EventSource"))
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger.Debug("Cannot lift write deadline for change stream", "error", err)
+	}
+	events, unsubscribe := Changes.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		logger.Warn("Change stream cannot flush", "error", err)
+		return
+	}
+
+	table := r.URL.Query().Get("table")
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return // dropped for falling behind
+			}
+			if event.Op == database.ChangeResync {
+				fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+			} else if table == "" || event.Table == table {
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(w, "event: change\ndata: %s\n\n", data)
+			} else {
+				continue
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
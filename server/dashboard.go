@@ -0,0 +1,277 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+	"github.com/jerrychou/go-practice/format"
+	httpops "github.com/jerrychou/go-practice/http"
+	"github.com/jerrychou/go-practice/metrics"
+)
+
+//go:embed assets
+var assetFS embed.FS
+
+// dashboardLogLines is how many recent log lines a new viewer is sent
+const dashboardLogLines = 50
+
+// Dashboard feeds the /dashboard page: server stats every second and log
+// lines as they are written, published through a PubSub to one WebSocket
+// per viewer. Stats are only gathered while someone is watching.
+var Dashboard = newLiveDashboard()
+
+type liveDashboard struct {
+	events *concurrency.PubSub[dashboardEvent]
+
+	hookOnce sync.Once
+
+	mu      sync.Mutex
+	recent  []dashboardLogLine // ring of the last dashboardLogLines
+	viewers int
+	stop    chan struct{}
+}
+
+// dashboardEvent is one WebSocket message: {"type": "stats", "stats": ...}
+// or {"type": "log", "log": ...}
+type dashboardEvent struct {
+	Type  string            `json:"type"`
+	Stats *dashboardStats   `json:"stats,omitempty"`
+	Log   *dashboardLogLine `json:"log,omitempty"`
+}
+
+type dashboardStats struct {
+	Time              time.Time                     `json:"time"`
+	RequestsPerSecond float64                       `json:"requests_per_second"`
+	RequestsTotal     float64                       `json:"requests_total"`
+	InFlight          float64                       `json:"in_flight"`
+	Goroutines        int                           `json:"goroutines"`
+	Heap              string                        `json:"heap"`
+	Viewers           int                           `json:"viewers"`
+	Pools             map[string]map[string]float64 `json:"pools,omitempty"`
+}
+
+type dashboardLogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Logger  string    `json:"logger,omitempty"`
+	Message string    `json:"message"`
+	Fields  string    `json:"fields,omitempty"`
+}
+
+func newLiveDashboard() *liveDashboard {
+	return &liveDashboard{events: concurrency.NewPubSub[dashboardEvent]()}
+}
+
+// captureLogs starts keeping recent log lines; Server.Start calls it so the
+// first viewer sees what happened before they opened the page
+func (d *liveDashboard) captureLogs() {
+	d.hookOnce.Do(func() {
+		format.AddHook(d.record)
+	})
+}
+
+// record is the log hook. It must not log.
+func (d *liveDashboard) record(entry format.Entry) {
+	line := dashboardLogLine{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Logger:  entry.Logger,
+		Message: entry.Message,
+		Fields:  formatFields(entry.Fields),
+	}
+	d.mu.Lock()
+	d.recent = append(d.recent, line)
+	if len(d.recent) > dashboardLogLines {
+		d.recent = d.recent[len(d.recent)-dashboardLogLines:]
+	}
+	d.mu.Unlock()
+	d.events.Publish("logs", dashboardEvent{Type: "log", Log: &line})
+}
+
+func formatFields(fields format.Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, fields[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// join counts a viewer in, starting the stats loop for the first one, and
+// returns the recent log lines
+func (d *liveDashboard) join() []dashboardLogLine {
+	d.captureLogs()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.viewers++
+	if d.viewers == 1 {
+		d.stop = make(chan struct{})
+		go d.publishStats(d.stop)
+	}
+	return append([]dashboardLogLine(nil), d.recent...)
+}
+
+// leave counts a viewer out, stopping the stats loop after the last one
+func (d *liveDashboard) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.viewers--
+	if d.viewers == 0 {
+		close(d.stop)
+	}
+}
+
+func (d *liveDashboard) publishStats(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	last, lastTime := -1.0, time.Now()
+	for {
+		stats := d.collect()
+		now := time.Now()
+		if last >= 0 {
+			stats.RequestsPerSecond = (stats.RequestsTotal - last) / now.Sub(lastTime).Seconds()
+		}
+		last, lastTime = stats.RequestsTotal, now
+		d.events.Publish("stats", dashboardEvent{Type: "stats", Stats: stats})
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect reads the server's request metrics and the database pool gauges
+// from the metrics registry, and the runtime's own numbers
+func (d *liveDashboard) collect() *dashboardStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	d.mu.Lock()
+	viewers := d.viewers
+	d.mu.Unlock()
+	stats := &dashboardStats{
+		Time:       time.Now(),
+		Goroutines: runtime.NumGoroutine(),
+		Heap:       format.HumanizeBytes(int64(mem.HeapAlloc)),
+		Viewers:    viewers,
+	}
+
+	snapshot := metrics.Default.Snapshot()
+	if byLabels, ok := snapshot["http_server_requests_total"].(map[string]any); ok {
+		for _, v := range byLabels {
+			stats.RequestsTotal += v.(float64)
+		}
+	}
+	stats.InFlight, _ = snapshot["http_server_requests_in_flight"].(float64)
+
+	// db_connections_open{db="app"} and friends become pools["app"]["open"]
+	for name, value := range snapshot {
+		stat, ok := strings.CutPrefix(name, "db_connections_")
+		byLabels, isMap := value.(map[string]any)
+		if !ok || !isMap {
+			continue
+		}
+		for labels, v := range byLabels {
+			pool := strings.TrimPrefix(labels, "db=")
+			if stats.Pools == nil {
+				stats.Pools = make(map[string]map[string]float64)
+			}
+			if stats.Pools[pool] == nil {
+				stats.Pools[pool] = make(map[string]float64)
+			}
+			stats.Pools[pool][stat] = v.(float64)
+		}
+	}
+	return stats
+}
+
+// dashboardAllowed lets in admin API callers, or signed-in users allowed
+// admin:view, as log lines can be sensitive
+func dashboardAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if adminAllowed(r, os.Getenv("ADMIN_TOKEN")) {
+		return true
+	}
+	session, ok := requireSession(w, r)
+	if !ok {
+		return false
+	}
+	if !pageAccess.CheckResourceAccess(session.UserID, "admin", "view") {
+		http.Error(w, "The dashboard needs the admin:view permission", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// DashboardHandler serves the live dashboard page
+func DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if !dashboardAllowed(w, r) {
+		return
+	}
+	Templates.HTML(w, http.StatusOK, "dashboard.html", nil)
+}
+
+// DashboardSocketHandler upgrades to a WebSocket and streams dashboard
+// events until the browser goes away
+func DashboardSocketHandler(w http.ResponseWriter, r *http.Request) {
+	if !dashboardAllowed(w, r) {
+		return
+	}
+	conn, err := httpops.UpgradeWebSocket(w, r)
+	if err != nil {
+		logger.Warn("Dashboard WebSocket refused", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	stats := Dashboard.events.Subscribe("stats", 4)
+	defer stats.Cancel()
+	logs := Dashboard.events.Subscribe("logs", 64)
+	defer logs.Cancel()
+	for _, line := range Dashboard.join() {
+		if err := conn.WriteJSON(dashboardEvent{Type: "log", Log: &line}); err != nil {
+			Dashboard.leave()
+			return
+		}
+	}
+	defer Dashboard.leave()
+
+	// The page sends nothing, but reading answers pings and notices close
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var event dashboardEvent
+		select {
+		case <-gone:
+			return
+		case event = <-stats.C:
+		case event = <-logs.C:
+		}
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+}
+
+// AssetsHandler serves the scripts embedded under server/assets
+var AssetsHandler = http.StripPrefix("/assets/", http.FileServerFS(mustSub(assetFS, "assets")))
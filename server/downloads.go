@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/security"
+)
+
+// Links signs the download links handed out by DownloadLinkHandler. The
+// server binary keys it with URL_SIGNING_KEY; the default random key
+// invalidates links on restart.
+var Links = security.NewURLSigner(nil)
+
+// MaxDownloadLinkTTL caps how long a download link can stay valid
+var MaxDownloadLinkTTL = 24 * time.Hour
+
+type downloadLinkRequest struct {
+	Key string `json:"key"`
+	// ExpiresIn is the link's lifetime in seconds; defaults to 15 minutes
+	ExpiresIn int `json:"expires_in"`
+	// BindIP restricts the link to the requesting client's address
+	BindIP bool `json:"bind_ip"`
+}
+
+// DownloadLinkHandler issues a pre-signed /download link for a stored file
+// to an authenticated user. Unlike the file store's own signed URLs, these
+// work for any store and can be bound to the requester's IP.
+func DownloadLinkHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		return NewProblem(http.StatusMethodNotAllowed, "method_not_allowed", "Use POST to request a download link")
+	}
+	scope := ScopeFrom(r.Context())
+	claims, err := scope.User()
+	if err != nil {
+		return err
+	}
+
+	var req downloadLinkRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		return NewProblem(http.StatusBadRequest, "invalid_body", "The body must be a JSON object").Wrap(err)
+	}
+	if err := filestore.ValidateKey(req.Key); err != nil {
+		return NewProblem(http.StatusBadRequest, "invalid_key", err.Error())
+	}
+	ttl := 15 * time.Minute
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > MaxDownloadLinkTTL {
+		return NewProblem(http.StatusBadRequest, "invalid_expiry", "Links can be valid for at most "+MaxDownloadLinkTTL.String())
+	}
+
+	store, err := uploads()
+	if err != nil {
+		return err
+	}
+	if _, err := store.Store().Stat(r.Context(), req.Key); err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			return NewProblem(http.StatusNotFound, "file_not_found", "No file is stored under "+req.Key)
+		}
+		return err
+	}
+
+	opts := security.SignOptions{Expires: ttl}
+	if req.BindIP {
+		opts.IP = Links.ClientIP(r)
+	}
+	link, err := Links.Sign(PublicURL+"/download/"+req.Key, opts)
+	if err != nil {
+		return err
+	}
+	scope.Logger().Info("Download link issued", "user_id", claims.UserID, "key", req.Key, "ttl", ttl, "bound_ip", opts.IP)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: "Download link created",
+		Data: map[string]any{
+			"url":        link,
+			"expires_at": time.Now().Add(ttl).UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// DownloadHandler streams a stored file to the holder of a link from
+// DownloadLinkHandler. Mount it behind Links.Middleware.
+func DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	store, err := uploads()
+	if err != nil {
+		http.Error(w, "File storage is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/download/")
+	body, info, err := store.Store().Get(r.Context(), key)
+	if errors.Is(err, filestore.ErrNotFound) || errors.Is(err, filestore.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logger.Error("Download failed", "key", key, "error", err)
+		http.Error(w, "Download failed", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Cache-Control", "private, no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		logger.Warn("Download interrupted", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/security"
+)
+
+// Files holds uploaded files. When nil, the first request that needs it
+// opens the store configured by the FILESTORE_* variables (see
+// filestore.FromEnv).
+var Files filestore.Store
+
+// MaxUploadSize caps the body of one upload request
+var MaxUploadSize int64 = 32 << 20
+
+// UploadScanning checks every upload for scripts in SVG and HTML files and
+// for decompression bombs before it is stored. Bootstrap adds the external
+// scanner configured under security.upload_scan; nil stores uploads
+// unchecked.
+var UploadScanning = security.NewUploadScanner()
+
+var (
+	filesOnce sync.Once
+	filesErr  error
+)
+
+// files returns Files, opening it on first use, and archives the audit
+// log to it
+func files() (filestore.Store, error) {
+	filesOnce.Do(func() {
+		if Files == nil {
+			Files, filesErr = filestore.FromEnv(PublicURL + "/files")
+		}
+		if filesErr == nil {
+			Audit.SetStore(Files)
+		}
+	})
+	return Files, filesErr
+}
+
+// uploads returns the content-addressed view of Files that upload
+// endpoints write to, so uploading the same file twice stores it once
+func uploads() (*filestore.ContentStore, error) {
+	store, err := files()
+	if err != nil {
+		return nil, err
+	}
+	return filestore.NewContentStore(store, "uploads/"), nil
+}
+
+type uploadedFile struct {
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	Duplicate   bool   `json:"duplicate"`
+	URL         string `json:"url,omitempty"`
+}
+
+// UploadHandler stores the files of a multipart/form-data POST, streaming
+// each part to the file store, and lists stored uploads on GET. A "sha256"
+// field before a file gives its hex SHA-256; a file that does not match is
+// rejected with 422. Large files can use resumable uploads instead (see
+// UploadSessionsHandler).
+func UploadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	store, err := uploads()
+	if err != nil {
+		logger.Error("File store unavailable", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "File storage is unavailable"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		objects, err := store.Store().List(r.Context(), "uploads/")
+		if err != nil {
+			logger.Error("Failed to list uploads", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Could not list uploads"})
+			return
+		}
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Uploads retrieved successfully", Data: objects})
+	case http.MethodPost:
+		saveUploads(w, r, store)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to upload files"})
+	}
+}
+
+func saveUploads(w http.ResponseWriter, r *http.Request, store *filestore.ContentStore) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Body must be multipart/form-data"})
+		return
+	}
+
+	var saved []uploadedFile
+	var checksum string // from a "sha256" field, for the file after it
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			uploadFailed(w, err)
+			return
+		}
+		if part.FileName() == "" {
+			if part.FormName() == "sha256" {
+				value, _ := io.ReadAll(io.LimitReader(part, 128))
+				checksum = strings.TrimSpace(string(value))
+			}
+			part.Close()
+			continue // ordinary form field
+		}
+		opts := filestore.PutOptions{ContentType: part.Header.Get("Content-Type")}
+		content, err := scannedPart(w, r, part, saved)
+		if err != nil {
+			return
+		}
+		var info filestore.ObjectInfo
+		var existed bool
+		if checksum != "" {
+			info, existed, err = store.PutSum(r.Context(), content, opts, checksum)
+			checksum = ""
+		} else {
+			info, existed, err = store.Put(r.Context(), content, opts)
+		}
+		content.Close()
+		if errors.Is(err, filestore.ErrChecksumMismatch) {
+			logger.Warn("Upload checksum mismatch", "name", part.FileName(), "error", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Checksum mismatch for " + part.FileName(), Data: saved})
+			return
+		}
+		if err != nil {
+			uploadFailed(w, err)
+			return
+		}
+		saved = append(saved, uploaded(r.Context(), store, part.FileName(), info, existed))
+	}
+
+	if len(saved) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "No files in the request"})
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Files uploaded", Data: saved})
+}
+
+// scannedPart returns the content of a file part, written to disk and
+// scanned first when UploadScanning is set. The request has been answered
+// when it returns an error.
+func scannedPart(w http.ResponseWriter, r *http.Request, part *multipart.Part, saved []uploadedFile) (io.ReadCloser, error) {
+	if UploadScanning == nil {
+		return part, nil
+	}
+	// Scanners need the whole file, and may read it more than once
+	spooled, err := spoolUpload(part)
+	part.Close()
+	if err != nil {
+		uploadFailed(w, err)
+		return nil, err
+	}
+	info, err := spooled.Stat()
+	if err != nil {
+		spooled.Close()
+		uploadFailed(w, err)
+		return nil, err
+	}
+	file := security.ScannedFile{Name: part.FileName(), ContentType: part.Header.Get("Content-Type"), Path: spooled.Name(), Size: info.Size()}
+	if err := scanUpload(w, r, file, saved); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// spoolUpload copies an upload into a temporary file, which is removed
+// when it is closed
+func spoolUpload(r io.Reader) (*spooledFile, error) {
+	f, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledFile{f}
+	if _, err := io.Copy(f, r); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+type spooledFile struct{ *os.File }
+
+func (f *spooledFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// scanUpload runs file through UploadScanning. When it may not be stored,
+// the request is answered, with 422 for a rejected file and 503 for one
+// that could not be checked, listing the files already stored, and the
+// scan's error is returned.
+func scanUpload(w http.ResponseWriter, r *http.Request, file security.ScannedFile, saved []uploadedFile) error {
+	var username string
+	if user, err := ScopeFrom(r.Context()).User(); err == nil {
+		username = user.Username
+	}
+	err := UploadScanning.Scan(r.Context(), file, clientIP(r), username)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, security.ErrUploadRejected):
+		logger.Warn("Upload rejected", "name", file.Name, "error", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload rejected: " + strings.TrimPrefix(err.Error(), security.ErrUploadRejected.Error()+": "), Data: saved})
+	default:
+		logger.Error("Upload scan failed", "name", file.Name, "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Could not scan " + file.Name + ", try again later", Data: saved})
+	}
+	return err
+}
+
+// uploaded describes a stored upload in responses, with a download link
+func uploaded(ctx context.Context, store *filestore.ContentStore, name string, info filestore.ObjectInfo, existed bool) uploadedFile {
+	file := uploadedFile{
+		Name:        name,
+		Key:         info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		Duplicate:   existed,
+	}
+	if url, err := store.Store().SignedURL(ctx, http.MethodGet, info.Key, 15*time.Minute); err == nil {
+		file.URL = url
+	}
+	logger.Info("File uploaded", "name", file.Name, "key", file.Key, "size", file.Size, "duplicate", existed)
+	return file
+}
+
+func uploadFailed(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload exceeds the size limit"})
+		return
+	}
+	logger.Error("Upload failed", "error", err)
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload failed"})
+}
+
+// FilesHandler serves the signed URLs of a local file store; object
+// storage such as S3 serves its own
+func FilesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := files(); err != nil {
+		http.Error(w, "File storage is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	local, ok := Files.(*filestore.LocalStore)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.StripPrefix("/files", local.Handler()).ServeHTTP(w, r)
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
+
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/string_op"
 )
 
 // User represents a user in the system
@@ -75,9 +79,57 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
     <div class="endpoint">
         <span class="method">GET</span> /api/users/{id} - Get user by ID (JSON)
     </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /api/v2/users - Paged users, following next_cursor; pick a version with /api/v{N} or Accept: application/vnd.api.v2+json
+    </div>
+    <div class="endpoint">
+        <span class="method">GET/POST</span> /preview - Render Markdown safely
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /api/me - Current user from a JWT bearer token (JSON)
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /api/changes - Live database changes (server-sent events)
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /api/jobs - Enqueue a background job (JSON)
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /api/password-reset - Email a password reset link (JSON)
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /api/upload - Upload files (multipart/form-data)
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /api/upload/sessions - Resumable upload in chunks (tus-style)
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /api/download-links - Pre-signed download link, optionally bound to your IP
+    </div>
+    <div class="endpoint">
+        <span class="method">POST</span> /webhooks/github - GitHub webhook (signed, replay-protected)
+    </div>
+    <div class="endpoint">
+        <span class="method">GET/POST</span> /login - Sign in with a session cookie (CSRF-protected form)
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /profile - Signed-in user, roles and permissions; POST /logout signs out
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /admin - Admin page for roles granting admin:view
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /admin/cron/jobs - Scheduled jobs and run history (admin)
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /metrics - Prometheus metrics
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /debug/net - TCP/UDP server connection statistics
+    </div>
     
     <h2>🔗 Quick Links:</h2>
-    <p><a href="/health">Health Check</a> | <a href="/time">Current Time</a> | <a href="/users">Users</a> | <a href="/api/users">API Users</a></p>
+    <p><a href="/health">Health Check</a> | <a href="/time">Current Time</a> | <a href="/users">Users</a> | <a href="/api/users">API Users</a> | <a href="/preview">Markdown Preview</a> | <a href="/login">Sign In</a></p>
 </body>
 </html>`
 
@@ -86,8 +138,20 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-// HealthHandler handles health check requests
+// HealthHandler handles health check requests. It fails with 503 while
+// the server drains, so load balancers take it out of rotation.
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if srv := running.Load(); srv != nil && srv.Draining() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(Response{
+			Success: false,
+			Message: "Server is draining",
+			Data:    map[string]any{"status": "draining", "timestamp": time.Now().Format(time.RFC3339)},
+		})
+		return
+	}
+
 	response := Response{
 		Success: true,
 		Message: "Server is healthy",
@@ -238,19 +302,62 @@ func APIUsersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// APIUserHandler handles individual user API requests (JSON)
-func APIUserHandler(w http.ResponseWriter, r *http.Request) {
+// userPages orders the sample users by id
+var userPages = database.Pagination[User]{
+	Keys:         []database.SortKey{{Column: "id"}},
+	Key:          func(u User) []any { return []any{u.ID} },
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+// APIUsersV2Handler lists users a page at a time for version 2 of the API,
+// wrapping them with the total so clients can page without guessing. Pages
+// after the first follow the cursor each one returns, also linked as
+// rel="next"; a page number past 1 pages by offset instead.
+func APIUsersV2Handler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	req := database.PageRequest{Limit: perPage, Cursor: query.Get("cursor"), Count: database.CountAlways}
+	if page > 1 {
+		req.Page = page
+	}
+	result, err := userPages.Slice(users, req)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	data := map[string]any{
+		"users":    result.Items,
+		"total":    result.Total,
+		"per_page": result.Limit,
+		"has_more": result.HasMore,
+	}
+	if req.Cursor == "" {
+		data["page"] = max(page, 1)
+	}
+	if result.NextCursor != "" {
+		data["next_cursor"] = result.NextCursor
+		next := url.Values{"cursor": {result.NextCursor}, "per_page": {strconv.Itoa(result.Limit)}}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+	response := Response{
+		Success: true,
+		Message: "Users retrieved successfully",
+		Data:    data,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// APIUserHandler handles individual user API requests (JSON). Errors are
+// returned as problems, so register it wrapped in ErrorHandlerFunc.
+func APIUserHandler(w http.ResponseWriter, r *http.Request) error {
 	idStr := r.URL.Path[len("/api/users/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		response := Response{
-			Success: false,
-			Message: "Invalid user ID",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return NewProblem(http.StatusBadRequest, "invalid_user_id", fmt.Sprintf("%q is not a user ID", idStr))
 	}
 
 	var foundUser *User
@@ -262,14 +369,7 @@ func APIUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if foundUser == nil {
-		response := Response{
-			Success: false,
-			Message: "User not found",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(response)
-		return
+		return NewProblem(http.StatusNotFound, "user_not_found", fmt.Sprintf("No user has ID %d", id))
 	}
 
 	response := Response{
@@ -278,5 +378,71 @@ func APIUserHandler(w http.ResponseWriter, r *http.Request) {
 		Data:    foundUser,
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return json.NewEncoder(w).Encode(response)
+}
+
+// MeHandler returns the user authenticated by the request's bearer token
+func MeHandler(w http.ResponseWriter, r *http.Request) error {
+	scope := ScopeFrom(r.Context())
+	claims, err := scope.User()
+	if err != nil {
+		return err
+	}
+	scope.Logger().Debug("Current user requested", "user_id", claims.UserID)
+
+	response := Response{
+		Success: true,
+		Message: "Authenticated user",
+		Data: map[string]any{
+			"id":       claims.UserID,
+			"username": claims.Username,
+			"roles":    claims.Roles,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(response)
+}
+
+const previewSample = "# Hello\n\nThis is **Markdown** with a [link](https://go.dev) and `code`.\n\n- one\n- two\n\n<script>alert('xss')</script>"
+
+// PreviewHandler renders user-supplied Markdown through the sanitizer, so
+// scripts, event handlers and javascript: links never reach the page
+func PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	source := r.FormValue("text")
+	if source == "" {
+		source = previewSample
+	}
+
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Markdown Preview</title>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; }
+        textarea { width: 100%%; height: 160px; font-family: monospace; }
+        .preview { border: 1px solid #ddd; padding: 20px; border-radius: 5px; margin-top: 20px; }
+    </style>
+</head>
+<body>
+    <h1>📝 Markdown Preview</h1>
+    <form method="POST" action="/preview">
+        <textarea name="text">%s</textarea>
+        <p><button type="submit">Render</button></p>
+    </form>
+    <div class="preview">%s</div>
+    <p><a href="/">← Back to Home</a></p>
+</body>
+</html>`, string_op.HTMLEncode(source), string_op.RenderMarkdown(source))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
 }
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+)
+
+// IdempotencyHeader is the header clients send so a POST is safe to retry:
+// every request with the same key gets the first one's response
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotentBody limits the request bodies fingerprinted for replays
+const maxIdempotentBody = 1 << 20
+
+// IdempotencyStore keeps responses and in-flight claims for IdempotencyKeys.
+// cache.Memory and cache.Redis implement it; Redis shares the keys between
+// server instances.
+type IdempotencyStore interface {
+	cache.Cache
+	cache.Counter
+}
+
+// IdempotencyKeys makes POST and PATCH requests carrying an Idempotency-Key
+// run once. The response of the first request is stored for TTL and sent
+// again, with "Idempotent-Replayed: true", for later requests with the key;
+// one arriving while the first still runs gets 409 Conflict, and one
+// reusing the key with another body 422. Keys are scoped to the caller's
+// Authorization header and the request path. Responses of 500 and above
+// are not kept, so the client can retry them. Only headers the handler set
+// are kept, so a replay gets its own request ID and cookies.
+type IdempotencyKeys struct {
+	Store IdempotencyStore
+	// TTL is how long responses are replayed, 24 hours if zero
+	TTL time.Duration
+	// LockTTL frees the key of a request that never finished, such as one
+	// whose server crashed, after this long; a minute if zero
+	LockTTL time.Duration
+}
+
+// Idempotency honors Idempotency-Key on every route and is required by
+// /api/transfers. Bootstrap points its Store at the application cache.
+var Idempotency = &IdempotencyKeys{Store: cache.NewMemory(cache.MemoryOptions{MaxEntries: 100000})}
+
+// idempotentResponse is what is kept of the first response for a key
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// Middleware applies keys to requests that send one
+func (k *IdempotencyKeys) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k.serve(w, r, next, false)
+	})
+}
+
+// Require is Middleware that refuses POST and PATCH requests without a key
+// with 400, for endpoints such as money transfers that must not run twice
+func (k *IdempotencyKeys) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k.serve(w, r, next, true)
+	})
+}
+
+func (k *IdempotencyKeys) serve(w http.ResponseWriter, r *http.Request, next http.Handler, required bool) {
+	key := r.Header.Get(IdempotencyHeader)
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if key == "" {
+		if required {
+			writeProblem(w, r, *NewProblem(http.StatusBadRequest, "idempotency_key_required",
+				"Send an "+IdempotencyHeader+" header, such as a random UUID, so the request can be retried safely"))
+			return
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+	if len(key) > 255 {
+		writeProblem(w, r, *NewProblem(http.StatusBadRequest, "idempotency_key_invalid", IdempotencyHeader+" is limited to 255 characters"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+	r.Body.Close()
+	if err != nil {
+		writeProblem(w, r, *NewProblem(http.StatusBadRequest, "unreadable_body", "The request body could not be read"))
+		return
+	}
+	if len(body) > maxIdempotentBody {
+		writeProblem(w, r, *NewProblem(http.StatusRequestEntityTooLarge, "request_too_large",
+			IdempotencyHeader+" is only supported for bodies up to "+strconv.Itoa(maxIdempotentBody)+" bytes"))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := requestFingerprint(r, body)
+
+	ctx := r.Context()
+	scope := scopeHash(r.Header.Get("Authorization"), r.Method, r.URL.Path, key)
+	stored := cache.NewTyped[idempotentResponse](k.Store, nil)
+	responseKey, lockKey := "idempotency:response:"+scope, "idempotency:lock:"+scope
+
+	if k.replay(w, r, stored, responseKey, fingerprint) {
+		return
+	}
+	claims, err := k.Store.Increment(ctx, lockKey, 1, orDefault(k.LockTTL, time.Minute))
+	if err != nil {
+		WriteProblem(w, r, err)
+		return
+	}
+	if claims > 1 {
+		// The first request may have finished since the lookup above
+		if k.replay(w, r, stored, responseKey, fingerprint) {
+			return
+		}
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, *NewProblem(http.StatusConflict, "idempotency_key_in_use",
+			"A request with this "+IdempotencyHeader+" is still being processed"))
+		return
+	}
+	// Release the claim even if the client goes away before the end
+	defer k.Store.Delete(context.WithoutCancel(ctx), lockKey)
+
+	outer := w.Header().Clone()
+	rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	if rec.status >= http.StatusInternalServerError {
+		return
+	}
+	response := idempotentResponse{Fingerprint: fingerprint, Status: rec.status, Header: handlerHeader(outer, w.Header()), Body: rec.body.Bytes()}
+	if err := stored.Set(context.WithoutCancel(ctx), responseKey, response, orDefault(k.TTL, 24*time.Hour)); err != nil {
+		ScopeFrom(ctx).Logger().Error("Failed to store idempotent response", "error", err)
+	}
+}
+
+// replay sends the stored response for the key, if any, and reports
+// whether the request was answered
+func (k *IdempotencyKeys) replay(w http.ResponseWriter, r *http.Request, stored *cache.Typed[idempotentResponse], responseKey, fingerprint string) bool {
+	response, err := stored.Get(r.Context(), responseKey)
+	if errors.Is(err, cache.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		WriteProblem(w, r, err)
+		return true
+	}
+	if response.Fingerprint != fingerprint {
+		writeProblem(w, r, *NewProblem(http.StatusUnprocessableEntity, "idempotency_key_reused",
+			"This "+IdempotencyHeader+" was already used for a different request"))
+		return true
+	}
+
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(response.Status)
+	w.Write(response.Body)
+	ScopeFrom(r.Context()).Logger().Info("Replayed idempotent response", "path", r.URL.Path, "status", response.Status)
+	return true
+}
+
+// perRequestHeaders describe one exchange rather than its result, so a
+// replay never repeats them even when the handler set them
+var perRequestHeaders = []string{"Set-Cookie", "X-Request-ID", "Date"}
+
+// handlerHeader returns the headers of header that the handler set or
+// changed, leaving out those outer middleware had set beforehand, such as
+// the request ID, which a replay gets afresh
+func handlerHeader(outer, header http.Header) http.Header {
+	kept := make(http.Header)
+	for name, values := range header {
+		if !slices.Equal(outer[name], values) {
+			kept[name] = slices.Clone(values)
+		}
+	}
+	for _, name := range perRequestHeaders {
+		kept.Del(name)
+	}
+	return kept
+}
+
+// requestFingerprint identifies what a request asks for, to tell a retry
+// from a different request reusing the key
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n"+r.Header.Get("Content-Type")+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// scopeHash keys a record by caller, method, path and key without putting
+// credentials into the store
+func scopeHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		io.WriteString(h, strconv.Itoa(len(part))+":"+part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter passes a response through while keeping a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
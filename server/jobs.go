@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/jerrychou/go-practice/queue"
+)
+
+// JobHandler runs one background job given its JSON payload
+type JobHandler func(ctx context.Context, payload json.RawMessage) error
+
+// JobQueue runs registered job types on background workers, so handlers
+// can respond before slow work such as sending email finishes
+type JobQueue struct {
+	broker   queue.Broker
+	topic    string
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+}
+
+type job struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ErrUnknownJob is returned when enqueueing a job type with no handler
+var ErrUnknownJob = errors.New("unknown job type")
+
+// Jobs is the queue behind /api/jobs, started by Server.Start
+var Jobs = NewJobQueue(queue.NewMemory(), "jobs")
+
+// NewJobQueue creates a queue that publishes jobs to topic on broker
+func NewJobQueue(broker queue.Broker, topic string) *JobQueue {
+	return &JobQueue{broker: broker, topic: topic, handlers: make(map[string]JobHandler)}
+}
+
+// Register sets the handler for a job type
+func (q *JobQueue) Register(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+func (q *JobQueue) handler(jobType string) (JobHandler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	handler, ok := q.handlers[jobType]
+	return handler, ok
+}
+
+// Enqueue schedules a job and returns its message ID
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload any) (string, error) {
+	if _, ok := q.handler(jobType); !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownJob, jobType)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(job{Type: jobType, Payload: raw})
+	if err != nil {
+		return "", err
+	}
+	return q.broker.Publish(ctx, q.topic, body, map[string]string{"job-type": jobType})
+}
+
+// Start launches workers that run jobs until ctx is cancelled. Failed jobs
+// are retried and eventually moved to the topic's dead-letter queue.
+func (q *JobQueue) Start(ctx context.Context, workers int) error {
+	for i := 1; i <= workers; i++ {
+		consumer, err := q.broker.Consumer(queue.ConsumerOptions{
+			Topic: q.topic,
+			Group: "workers",
+			Name:  fmt.Sprintf("worker-%d", i),
+		})
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer consumer.Close()
+			queue.Consume(ctx, consumer, q.run)
+		}()
+	}
+	logger.Infof("Started %d job workers on %q", workers, q.topic)
+	return nil
+}
+
+func (q *JobQueue) run(ctx context.Context, msg *queue.Message) error {
+	var j job
+	if err := json.Unmarshal(msg.Body, &j); err != nil {
+		return fmt.Errorf("malformed job %s: %w", msg.ID, err)
+	}
+	handler, ok := q.handler(j.Type)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownJob, j.Type)
+	}
+	return handler(ctx, j.Payload)
+}
+
+// JobsHandler enqueues a job from a POST body such as
+// {"type": "welcome_email", "payload": {"name": "Ann", "email": "ann@example.com"}}
+func JobsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to enqueue a job"})
+		return
+	}
+
+	var j job
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&j); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Invalid JSON body"})
+		return
+	}
+	id, err := Jobs.Enqueue(r.Context(), j.Type, j.Payload)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownJob) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: "Job enqueued",
+		Data:    map[string]string{"id": id, "type": j.Type},
+	})
+}
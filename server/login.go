@@ -0,0 +1,395 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/security"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+const (
+	// sessionCookie carries the session token of the HTML pages
+	sessionCookie = "session"
+	// rememberCookie carries the remember-me token of a device whose user
+	// ticked "Keep me signed in"; it opens a new session when the last one
+	// has ended
+	rememberCookie = "remember"
+)
+
+// WebAuth signs users in to /login, /profile and /admin. Its session tokens
+// are signed with a key of its own, so sessions end when the server
+// restarts and are not valid as API bearer tokens.
+var WebAuth = newWebAuth()
+
+func newWebAuth() *security.AuthService {
+	auth := security.NewAuthService(security.NewMemoryUserStore(), passwords, security.NewJWTAuth(string_op.Token(32)))
+	auth.Audit = Audit
+	auth.Devices.Audit = Audit
+	return auth
+}
+
+// CSRF protects the forms of the login pages. Its tokens are bound to the
+// session cookie, so signing in or out issues a new one.
+var CSRF = func() *security.CSRFProtector {
+	c := security.NewCSRFProtector(nil)
+	c.Session = func(r *http.Request) string {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}
+	return c
+}()
+
+var (
+	// loginValidator checks the login form before any password is hashed
+	loginValidator = func() *security.InputValidator {
+		v := security.NewInputValidator()
+		v.CreateCommonRules()
+		return v
+	}()
+
+	// pageAccess decides what signed-in users may see; the admin page
+	// needs admin:view and the runtime admin API admin:manage
+	pageAccess = func() *security.RBACManager {
+		rbac := security.NewRBACManager()
+		for _, perm := range []string{"profile:view", "admin:view", "admin:manage", "users:manage"} {
+			p, _ := rbac.CreatePermissionFromString(perm, "")
+			rbac.AddPermission(p)
+		}
+		rbac.AddRole(&security.Role{Name: "user", Permissions: []string{"profile:view"}})
+		rbac.AddRole(&security.Role{Name: "admin", Permissions: []string{"profile:view", "admin:view", "admin:manage", "users:manage"}})
+		return rbac
+	}()
+
+	demoAccountsOnce sync.Once
+)
+
+// ensureDemoAccounts registers an "admin" and a "demo" account on first
+// use, with the password in DEMO_PASSWORD or a random one that is logged
+func ensureDemoAccounts(ctx context.Context) {
+	demoAccountsOnce.Do(func() {
+		password := os.Getenv("DEMO_PASSWORD")
+		if password == "" {
+			password = "Demo!" + string_op.Token(9) + "7"
+			logger.Warn("DEMO_PASSWORD is not set; generated a password for the demo accounts",
+				"usernames", "admin, demo", "password", password)
+		}
+		for username, roles := range map[string][]string{"admin": {"admin"}, "demo": {"user"}} {
+			user, err := WebAuth.Register(ctx, username, password, roles...)
+			if err != nil {
+				logger.Error("Failed to register demo account", "username", username, "error", err)
+				continue
+			}
+			pageAccess.AddUser(&security.User{ID: user.ID, Username: user.Username, Roles: user.Roles})
+		}
+	})
+}
+
+type loginPage struct {
+	CSRFToken string
+	Next      string
+	Username  string
+	// Challenge is set when the password was right and a 2FA code is due
+	Challenge string
+	Remember  bool
+	Errors    []string
+}
+
+// LoginHandler shows the login form on GET and signs in on POST, setting
+// the session cookie and redirecting to the page the user came from
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ensureDemoAccounts(r.Context())
+	page := loginPage{CSRFToken: CSRF.Token(w, r), Next: localPath(r.FormValue("next"))}
+	if r.Method != http.MethodPost {
+		if _, ok := webSession(w, r); ok {
+			http.Redirect(w, r, page.Next, http.StatusSeeOther)
+			return
+		}
+		Templates.HTML(w, http.StatusOK, "login.html", page)
+		return
+	}
+
+	page.Remember = r.PostFormValue("remember") != ""
+	var result *security.LoginResult
+	var err error
+	if challenge := r.PostFormValue("challenge"); challenge != "" {
+		page.Challenge = challenge
+		result, err = WebAuth.VerifyTOTP(r.Context(), challenge, strings.TrimSpace(r.PostFormValue("code")))
+	} else {
+		username, password := r.PostFormValue("username"), r.PostFormValue("password")
+		page.Username = username
+		for _, field := range [][2]string{{"username", username}, {"password", password}} {
+			page.Errors = append(page.Errors, loginValidator.ValidateString(field[0], field[1]).Errors...)
+		}
+		if len(page.Errors) > 0 {
+			Templates.HTML(w, http.StatusBadRequest, "login.html", page)
+			return
+		}
+		result, err = WebAuth.Login(r.Context(), username, password)
+	}
+
+	status := http.StatusUnauthorized
+	switch {
+	case errors.Is(err, security.ErrAccountLocked):
+		status = http.StatusTooManyRequests
+		fallthrough
+	case err != nil:
+		if errors.Is(err, security.ErrInvalidChallenge) {
+			page.Challenge = "" // start again from the password
+		}
+		page.Errors = []string{err.Error()}
+		Templates.HTML(w, status, "login.html", page)
+	case result.State == security.LoginTOTPRequired:
+		page.Challenge = result.Challenge
+		Templates.HTML(w, http.StatusOK, "login.html", page)
+	default:
+		setAuthCookie(w, r, sessionCookie, result.Token, result.Session.ExpiresAt)
+		if page.Remember {
+			token, device, err := WebAuth.RememberDevice(r.Context(), result.Token, r.UserAgent(), security.DeviceFingerprint(r))
+			if err == nil {
+				setAuthCookie(w, r, rememberCookie, token, device.ExpiresAt)
+			}
+		}
+		logger.Info("Signed in", "username", result.Session.Username, "ip", clientIP(r), "remember", page.Remember)
+		http.Redirect(w, r, page.Next, http.StatusSeeOther)
+	}
+}
+
+// LogoutHandler ends the session, forgets the device and clears their
+// cookies
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		WebAuth.Logout(r.Context(), cookie.Value)
+	}
+	clearAuthCookie(w, r, sessionCookie)
+	clearAuthCookie(w, r, rememberCookie)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+type profilePage struct {
+	CSRFToken   string
+	Session     *security.Session
+	Permissions []string
+	IsAdmin     bool
+	Expires     string
+	Devices     []security.Device
+}
+
+// ProfileHandler shows the signed-in user's session and permissions
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	permissions, _ := pageAccess.GetUserPermissions(session.UserID)
+	Templates.HTML(w, http.StatusOK, "profile.html", profilePage{
+		CSRFToken:   CSRF.Token(w, r),
+		Session:     session,
+		Permissions: permissions,
+		IsAdmin:     pageAccess.HasPermission(session.UserID, "admin:view"),
+		Expires:     session.ExpiresAt.Format(time.RFC1123),
+		Devices:     WebAuth.Devices.Devices(session.UserID),
+	})
+}
+
+// MyDevicesHandler lists the devices the signed-in user stays signed in on
+func MyDevicesHandler(w http.ResponseWriter, r *http.Request) error {
+	session, ok := webSession(w, r)
+	if !ok {
+		return NewProblem(http.StatusUnauthorized, "unauthenticated", "Sign in at /login first")
+	}
+	devices := WebAuth.Devices.Devices(session.UserID)
+	data := make([]map[string]any, 0, len(devices))
+	for _, device := range devices {
+		data = append(data, map[string]any{
+			"id":           device.ID,
+			"name":         device.Name,
+			"created_at":   device.CreatedAt,
+			"last_used_at": device.LastUsedAt,
+			"expires_at":   device.ExpiresAt,
+			"current":      device.ID == session.DeviceID,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	return json.NewEncoder(w).Encode(Response{Success: true, Message: "Remembered devices", Data: data})
+}
+
+// RevokeDeviceHandler signs out the device named by the device_id form
+// field, or every other device when it is "others", ending the sessions
+// opened from them, then returns to the profile page
+func RevokeDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	ids := []string{r.PostFormValue("device_id")}
+	if ids[0] == "others" {
+		ids = ids[:0]
+		for _, device := range WebAuth.Devices.Devices(session.UserID) {
+			if device.ID != session.DeviceID {
+				ids = append(ids, device.ID)
+			}
+		}
+	}
+	for _, id := range ids {
+		if err := WebAuth.SignOutDevice(r.Context(), session.UserID, id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if id == session.DeviceID {
+			clearAuthCookie(w, r, sessionCookie)
+			clearAuthCookie(w, r, rememberCookie)
+		}
+	}
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// AdminPageHandler is the admin page, for users whose roles grant
+// admin:view
+func AdminPageHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	type page struct {
+		CSRFToken string
+		Session   *security.Session
+		Allowed   bool
+		Users     []User
+	}
+	data := page{CSRFToken: CSRF.Token(w, r), Session: session}
+	if !pageAccess.CheckResourceAccess(session.UserID, "admin", "view") {
+		logger.Warn("Admin page refused", "username", session.Username, "roles", session.Roles)
+		Templates.HTML(w, http.StatusForbidden, "admin.html", data)
+		return
+	}
+	data.Allowed, data.Users = true, users
+	Templates.HTML(w, http.StatusOK, "admin.html", data)
+}
+
+// MyPermissionsHandler lets the pages' scripts ask once what the signed-in
+// user may do: GET returns their permissions grouped by resource with the
+// roles granting each, and ?check=users:manage,admin:view adds the answer
+// for each listed permission and whether all or any of them are held
+func MyPermissionsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return NewProblem(http.StatusMethodNotAllowed, "method_not_allowed", "Use GET")
+	}
+	session, ok := webSession(w, r)
+	if !ok {
+		return NewProblem(http.StatusUnauthorized, "unauthenticated", "Sign in at /login first")
+	}
+	summary, err := pageAccess.EffectivePermissions(session.UserID)
+	if err != nil {
+		return NewProblem(http.StatusForbidden, "no_permissions", "No roles are known for this account").Wrap(err)
+	}
+
+	data := map[string]any{"permissions": summary}
+	if check := r.URL.Query().Get("check"); check != "" {
+		var names []string
+		for _, name := range strings.Split(check, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		data["checks"] = pageAccess.CheckPermissions(session.UserID, names...)
+		data["all"] = pageAccess.CanAll(session.UserID, names...)
+		data["any"] = pageAccess.CanAny(session.UserID, names...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	return json.NewEncoder(w).Encode(Response{Success: true, Message: "Effective permissions", Data: data})
+}
+
+// webSession returns the live session of the request's cookie. Without
+// one, a remember-me cookie opens a new session, and both cookies are
+// replaced.
+func webSession(w http.ResponseWriter, r *http.Request) (*security.Session, bool) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+		if session, err := WebAuth.Authenticate(r.Context(), cookie.Value); err == nil {
+			return session, true
+		}
+	}
+
+	cookie, err := r.Cookie(rememberCookie)
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+	result, next, err := WebAuth.ResumeSession(r.Context(), cookie.Value, security.DeviceFingerprint(r))
+	if err != nil {
+		logger.Warn("Remember-me token refused", "ip", clientIP(r), "error", err)
+		clearAuthCookie(w, r, rememberCookie)
+		return nil, false
+	}
+	setAuthCookie(w, r, sessionCookie, result.Token, result.Session.ExpiresAt)
+	useSessionCookie(r, result.Token)
+	// Using the device pushed its expiry back by the TTL
+	setAuthCookie(w, r, rememberCookie, next, time.Now().Add(WebAuth.Devices.TTL))
+	logger.Info("Signed in from a remembered device", "username", result.Session.Username, "ip", clientIP(r))
+	return result.Session, true
+}
+
+// useSessionCookie makes the rest of r see token as its session cookie, as
+// the browser's next request will, so the CSRF token a page embeds is bound
+// to the session the form will be posted with
+func useSessionCookie(r *http.Request, token string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != sessionCookie {
+			r.AddCookie(cookie)
+		}
+	}
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+}
+
+func setAuthCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearAuthCookie(w http.ResponseWriter, r *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+}
+
+// requireSession redirects to the login page, coming back afterwards, when
+// the request is not signed in
+func requireSession(w http.ResponseWriter, r *http.Request) (*security.Session, bool) {
+	session, ok := webSession(w, r)
+	if !ok {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.Path), http.StatusSeeOther)
+		return nil, false
+	}
+	return session, true
+}
+
+// localPath keeps redirects on this site: next must be a path, not a URL
+// or a scheme-relative "//host". Browsers also drop tabs and newlines from
+// URLs and read backslashes as slashes, so paths with control characters,
+// which url.Parse refuses, or backslashes are refused too.
+func localPath(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil ||
+		!strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return "/profile"
+	}
+	return next
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jerrychou/go-practice/email"
+)
+
+// Mailer sends the server's email. It is configured from the SMTP_*
+// variables (see email.TransportFromEnv) and captures messages in memory
+// when SMTP_HOST is unset.
+var Mailer email.Transport
+
+// MailFrom is the sender of the server's email
+var MailFrom = "Go Practice <noreply@example.com>"
+
+// PublicURL is the base of links in emails
+var PublicURL = "http://localhost:8080"
+
+func init() {
+	transport, err := email.TransportFromEnv()
+	if err != nil {
+		logger.Error("Invalid SMTP settings, capturing email instead", "error", err)
+		transport = email.NewMockTransport()
+	}
+	Mailer = transport
+
+	Jobs.Register("welcome_email", func(ctx context.Context, payload json.RawMessage) error {
+		var user User
+		if err := json.Unmarshal(payload, &user); err != nil {
+			return err
+		}
+		if user.Email == "" {
+			return fmt.Errorf("welcome_email: missing email")
+		}
+		data := map[string]string{"Name": user.Name, "Email": user.Email, "BaseURL": PublicURL}
+		return sendTemplate(ctx, user.Email, "Welcome to Go Practice", "welcome_email", data)
+	})
+}
+
+// sendTemplate sends an email whose bodies are the name.html and name.txt
+// templates
+func sendTemplate(ctx context.Context, to, subject, name string, data any) error {
+	msg, err := email.NewMessage().
+		From(MailFrom).
+		To(to).
+		Subject(subject).
+		RenderText(Templates, name+".txt", data).
+		RenderHTML(Templates, name+".html", data).
+		Build()
+	if err != nil {
+		return err
+	}
+	return Mailer.Send(ctx, msg)
+}
@@ -1,11 +1,22 @@
 package server
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/reflect"
+	"github.com/jerrychou/go-practice/security"
 )
 
+var logger = format.GetLogger("server")
+
 // LoggingMiddleware logs HTTP requests
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -17,52 +28,155 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapped, r)
 
 		duration := time.Since(start)
-		log.Printf("%s %s %d %v %s", r.Method, r.URL.Path, wrapped.statusCode, duration, r.RemoteAddr)
+		path := r.URL.Path
+		if r.URL.RawQuery != "" {
+			path += "?" + reflect.MaskQuery(r.URL.RawQuery)
+		}
+		logger.Infof("%s %s %d %v %s", r.Method, path, wrapped.statusCode, duration, r.RemoteAddr)
 	})
 }
 
-// CORSMiddleware adds CORS headers to responses
+// CORS holds the cross-origin rules: any site may call the API and the
+// tus upload endpoints without credentials, while the admin API and
+// session pages are closed to other origins. Bootstrap replaces it with the
+// security.cors section of the config file when there is one.
+var CORS = security.NewCORSPolicy(security.CORSRule{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+	AllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Checksum"},
+	ExposedHeaders: []string{"Location", "Tus-Resumable", "Upload-Offset", "Upload-Length"},
+	MaxAge:         10 * time.Minute,
+}).
+	Route("/admin", security.CORSRule{}).
+	Route("/login", security.CORSRule{}).
+	Route("/logout", security.CORSRule{}).
+	Route("/profile", security.CORSRule{}).
+	Route("/dashboard", security.CORSRule{})
+
+// CORSMiddleware applies CORS, answering preflight requests itself. CORS
+// is read per request, so Bootstrap can replace it after routes are set up.
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
+		CORS.Middleware(next).ServeHTTP(w, r)
 	})
 }
 
-// SecurityMiddleware adds security headers
+// SecurityHeaders chooses the security headers of each response: the
+// web-app profile for pages and the strict-api profile for JSON and file
+// downloads
+var SecurityHeaders = security.NewHeaderPolicy(security.WebAppProfile()).
+	Route("/api/", security.StrictAPIProfile()).
+	Route("/files/", security.StrictAPIProfile()).
+	Route("/download/", security.StrictAPIProfile()).
+	Route("/metrics", security.StrictAPIProfile()).
+	Route("/debug/", security.StrictAPIProfile())
+
+// SecurityMiddleware adds the headers chosen by SecurityHeaders
 func SecurityMiddleware(next http.Handler) http.Handler {
+	return SecurityHeaders.Middleware(next)
+}
+
+// RateLimiter allows a fixed number of requests per client in each window.
+// Counts live in a cache.Counter, so a Redis-backed limiter is shared by all
+// server instances.
+type RateLimiter struct {
+	counter cache.Counter
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+}
+
+// NewRateLimiter allows limit requests per window for each client IP
+func NewRateLimiter(counter cache.Counter, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{counter: counter, limit: limit, window: window, now: time.Now}
+}
+
+// Allow counts a request for key and reports whether it is within the limit,
+// how many requests remain and when the window resets
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (allowed bool, remaining int, reset time.Duration, err error) {
+	now := rl.now()
+	windowStart := now.Truncate(rl.window)
+	reset = windowStart.Add(rl.window).Sub(now)
+
+	counterKey := fmt.Sprintf("ratelimit:%s:%d", key, windowStart.Unix())
+	// The counter outlives its window slightly so clock skew between
+	// instances does not reset it early
+	count, err := rl.counter.Increment(ctx, counterKey, 1, rl.window+time.Second)
+	if err != nil {
+		return false, 0, reset, err
+	}
+	remaining = max(rl.limit-int(count), 0)
+	return int(count) <= rl.limit, remaining, reset, nil
+}
+
+// Middleware rejects clients over the limit with 429 Too Many Requests. If the
+// counter store fails, requests are let through rather than taking the site
+// down with it.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Add security headers
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		allowed, remaining, reset, err := rl.Allow(r.Context(), clientIP(r))
+		if err != nil {
+			logger.Warn("Rate limiter unavailable, allowing request", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		resetSeconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", resetSeconds)
+		if !allowed {
+			w.Header().Set("Retry-After", resetSeconds)
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// RateLimitMiddleware provides basic rate limiting (simplified implementation)
-func RateLimitMiddleware(next http.Handler) http.Handler {
-	// In a real application, you'd use a proper rate limiting library
-	// This is a simplified version for demonstration
+// IPFilter blocks clients by address or country when set. Its trusted
+// proxies also decide whose X-Forwarded-For header clientIP believes.
+var IPFilter *security.IPFilter
+
+// IPFilterMiddleware applies IPFilter, letting everything through while it
+// is nil
+func IPFilterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// For demo purposes, we'll just pass through
-		// In production, you'd check against a rate limiter
-		next.ServeHTTP(w, r)
+		if IPFilter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		IPFilter.Middleware(next).ServeHTTP(w, r)
 	})
 }
 
+// clientIP returns the remote address without its port, or the forwarded
+// client address when the request came through a trusted proxy
+func clientIP(r *http.Request) string {
+	if IPFilter != nil {
+		return IPFilter.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimitCounter holds the rate limit counts; nil counts in memory.
+// Bootstrap points it at Redis so instances share their limits.
+var RateLimitCounter cache.Counter
+
+// RateLimitMiddleware limits each client IP to 100 requests per minute
+func RateLimitMiddleware(next http.Handler) http.Handler {
+	counter := RateLimitCounter
+	if counter == nil {
+		counter = cache.NewMemory(cache.MemoryOptions{MaxEntries: 100000})
+	}
+	limiter := NewRateLimiter(counter, 100, time.Minute)
+	return limiter.Middleware(next)
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -73,3 +187,8 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	netops "github.com/jerrychou/go-practice/net"
+)
+
+// NetStatsHandler reports connection statistics for the TCP, UDP, chat and
+// forwarding servers from the net package running in this process
+func NetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	servers := netops.AllStats()
+	response := Response{
+		Success: true,
+		Message: "Network server statistics",
+		Data: map[string]any{
+			"servers":   servers,
+			"count":     len(servers),
+			"timestamp": time.Now().Format(time.RFC3339),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/security"
+)
+
+// PasswordResets issues the links of /api/password-reset and checks them
+// in /password-reset. Its tokens are kept in memory unless replaced with
+// PasswordResets.SetStore, for example with a database.PasswordResetStore.
+var PasswordResets = security.NewPasswordResetService(security.NewMemoryResetStore(), security.ResetSenderFunc(enqueueResetEmail))
+
+var (
+	passwords      = security.NewPasswordManager(security.NewBcryptHasher(0))
+	passwordMu     sync.RWMutex
+	passwordHashes = map[string]string{} // by lowercased email
+)
+
+func init() {
+	// Issuing runs in the background, so the response time is the same for
+	// known and unknown addresses and for throttled accounts
+	Jobs.Register("password_reset_request", func(ctx context.Context, payload json.RawMessage) error {
+		var account security.ResetAccount
+		if err := json.Unmarshal(payload, &account); err != nil {
+			return err
+		}
+		err := PasswordResets.Request(ctx, account)
+		if errors.Is(err, security.ErrResetRateLimited) {
+			return nil // audited; retrying would not help
+		}
+		return err
+	})
+	Jobs.Register("password_reset_email", func(ctx context.Context, payload json.RawMessage) error {
+		var data security.ResetEmail
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return err
+		}
+		return sendTemplate(ctx, data.Email, "Reset your Go Practice password", "password_reset", data)
+	})
+}
+
+// enqueueResetEmail sends a new reset link as a job of its own, so a
+// failing mail server is retried without issuing another token
+func enqueueResetEmail(ctx context.Context, notice security.ResetNotice) error {
+	data := security.ResetEmail{
+		Name:      notice.Account.Name,
+		Email:     notice.Account.Email,
+		ResetURL:  PublicURL + "/password-reset?token=" + url.QueryEscape(notice.Token),
+		ExpiresIn: format.HumanizeDuration(notice.TTL),
+	}
+	_, err := Jobs.Enqueue(ctx, "password_reset_email", data)
+	return err
+}
+
+// PasswordResetRequestHandler emails a reset link for a POST body such as
+// {"email": "john@example.com"}. It answers 202 whether or not the address
+// belongs to a user, so it cannot be used to discover accounts.
+func PasswordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to request a reset link"})
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil || body.Email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Body must be {\"email\": \"...\"}"})
+		return
+	}
+
+	if user, ok := findUserByEmail(body.Email); ok {
+		account := security.ResetAccount{Subject: strings.ToLower(user.Email), Email: user.Email, Name: user.Name}
+		if _, err := Jobs.Enqueue(r.Context(), "password_reset_request", account); err != nil {
+			logger.Error("Failed to enqueue password reset request", "error", err)
+		}
+	} else {
+		logger.Info("Password reset requested for unknown address", "email", body.Email)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "If the address is registered, a reset link is on its way"})
+}
+
+// PasswordResetHandler shows the form from the emailed link on GET and
+// sets the new password on POST
+func PasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	type page struct {
+		Token, Error string
+		Done         bool
+	}
+	switch r.Method {
+	case http.MethodGet:
+		token := r.URL.Query().Get("token")
+		if _, err := PasswordResets.Check(r.Context(), token); err != nil {
+			Templates.HTML(w, http.StatusBadRequest, "password_reset_form.html", page{Error: err.Error()})
+			return
+		}
+		Templates.HTML(w, http.StatusOK, "password_reset_form.html", page{Token: token})
+	case http.MethodPost:
+		token, password := r.PostFormValue("token"), r.PostFormValue("password")
+		if err := resetPassword(r.Context(), token, password); err != nil {
+			Templates.HTML(w, http.StatusBadRequest, "password_reset_form.html", page{Token: token, Error: err.Error()})
+			return
+		}
+		Templates.HTML(w, http.StatusOK, "password_reset_form.html", page{Done: true})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// resetPassword checks the new password before using up the token, so a
+// weak password can be corrected with the same link
+func resetPassword(ctx context.Context, token, password string) error {
+	if token == "" {
+		return security.ErrInvalidResetToken
+	}
+	if err := passwords.ValidatePasswordStrength(password); err != nil {
+		return err
+	}
+	subject, err := PasswordResets.Consume(ctx, token)
+	if err != nil {
+		return err
+	}
+	hash, err := passwords.HashPassword(password)
+	if err != nil {
+		return errors.New("could not save the new password")
+	}
+	passwordMu.Lock()
+	passwordHashes[subject] = hash
+	passwordMu.Unlock()
+	logger.Info("Password reset", "email", subject)
+	return nil
+}
+
+// CheckPassword reports whether password is the one last set for the user
+// with this email address
+func CheckPassword(address, password string) bool {
+	passwordMu.RLock()
+	hash, ok := passwordHashes[strings.ToLower(address)]
+	passwordMu.RUnlock()
+	return ok && passwords.VerifyPassword(password, hash)
+}
+
+func findUserByEmail(address string) (User, bool) {
+	for _, user := range users {
+		if strings.EqualFold(user.Email, address) {
+			return user, true
+		}
+	}
+	return User{}, false
+}
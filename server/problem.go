@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/reflect"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details error. Handlers return one to
+// choose the status and wording of an error response; WriteProblem turns
+// any other error into one with ProblemFrom.
+type Problem struct {
+	// Type is a URI naming the kind of problem; empty means "about:blank",
+	// where Title is just the status text
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is a stable machine-readable name such as "user_not_found"
+	Code   string         `json:"code,omitempty"`
+	Fields []ProblemField `json:"fields,omitempty"`
+
+	err error
+}
+
+// ProblemField is one invalid field of a request
+type ProblemField struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewProblem creates a problem titled with the status text
+func NewProblem(status int, code, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Code: code, Detail: detail}
+}
+
+// Wrap records err as the cause of the problem, for logs and errors.Is
+func (p *Problem) Wrap(err error) *Problem {
+	p.err = err
+	return p
+}
+
+func (p *Problem) Error() string {
+	msg := fmt.Sprintf("%d %s", p.Status, p.Title)
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	if p.err != nil {
+		msg += ": " + p.err.Error()
+	}
+	return msg
+}
+
+func (p *Problem) Unwrap() error {
+	return p.err
+}
+
+// ProblemFrom maps err to a problem: a *Problem anywhere in the chain is
+// used as is, validation errors become 422 with the failed fields, database
+// errors 404 for a missing row or 409 for a constraint conflict, and
+// anything else a 500 whose detail is kept out of the response
+func ProblemFrom(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	var validation reflect.ValidationErrors
+	if errors.As(err, &validation) {
+		problem = NewProblem(http.StatusUnprocessableEntity, "validation_failed", "The request has invalid fields")
+		for _, fe := range validation {
+			problem.Fields = append(problem.Fields, ProblemField{Field: fe.Field, Code: fe.Code, Message: fe.Message})
+		}
+		return problem.Wrap(err)
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		problem = NewProblem(http.StatusRequestEntityTooLarge, "request_too_large",
+			fmt.Sprintf("The request body is limited to %d bytes", tooLarge.Limit))
+	case database.IsNotFound(err):
+		problem = NewProblem(http.StatusNotFound, "not_found", "The requested resource does not exist")
+	case database.IsConflict(err):
+		problem = NewProblem(http.StatusConflict, "conflict", "The request conflicts with existing data")
+	default:
+		problem = NewProblem(http.StatusInternalServerError, "internal_error", "")
+	}
+	return problem.Wrap(err)
+}
+
+// WriteProblem sends err as an application/problem+json response. Server
+// errors are logged with their cause, which the client never sees.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := ProblemFrom(err)
+	if problem.Status >= http.StatusInternalServerError {
+		ScopeFrom(r.Context()).Logger().Error("Request failed", "status", problem.Status, "error", err)
+	}
+	writeProblem(w, r, *problem)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// ErrorHandlerFunc is a handler that returns its error instead of writing
+// it. An error returned before the handler wrote anything is sent as a
+// problem; after that the response is already on its way, so it is only
+// logged.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f ErrorHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tw := &trackingWriter{ResponseWriter: w}
+	if err := f(tw, r); err != nil {
+		if tw.wrote {
+			ScopeFrom(r.Context()).Logger().Error("Handler failed after writing", "error", err)
+			return
+		}
+		WriteProblem(w, r, err)
+	}
+}
+
+// ProblemMiddleware turns a panic in next into a 500 problem response, or
+// into the problem it carries when the panic value is an error
+func ProblemMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err, ok := v.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", v)
+			}
+			ScopeFrom(r.Context()).Logger().Error("Handler panicked", "panic", v, "stack", string(debug.Stack()))
+			if !tw.wrote {
+				writeProblem(w, r, *ProblemFrom(err))
+			}
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// trackingWriter records whether a response has started
+type trackingWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (tw *trackingWriter) WriteHeader(code int) {
+	tw.wrote = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *trackingWriter) Write(b []byte) (int, error) {
+	tw.wrote = true
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (tw *trackingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
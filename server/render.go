@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates renders the pages and emails under server/templates
+var Templates = MustNewRenderer(mustSub(templateFS, "templates"))
+
+// Renderer executes templates parsed from a file system: *.html files with
+// html/template, which escapes data for the context it appears in, and
+// *.txt files with text/template for plain text such as email bodies
+type Renderer struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// NewRenderer parses the *.html and *.txt files at the root of fsys; a
+// template is named after its file, e.g. "password_reset.html"
+func NewRenderer(fsys fs.FS) (*Renderer, error) {
+	r := &Renderer{html: htmltemplate.New(""), text: texttemplate.New("")}
+	if matches, _ := fs.Glob(fsys, "*.html"); len(matches) > 0 {
+		if _, err := r.html.ParseFS(fsys, "*.html"); err != nil {
+			return nil, err
+		}
+	}
+	if matches, _ := fs.Glob(fsys, "*.txt"); len(matches) > 0 {
+		if _, err := r.text.ParseFS(fsys, "*.txt"); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// MustNewRenderer is NewRenderer that panics on error, for templates
+// embedded in the binary
+func MustNewRenderer(fsys fs.FS) *Renderer {
+	r, err := NewRenderer(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ExecuteTemplate renders the named template, choosing the engine by its
+// extension
+func (r *Renderer) ExecuteTemplate(w io.Writer, name string, data any) error {
+	switch path.Ext(name) {
+	case ".html":
+		return r.html.ExecuteTemplate(w, name, data)
+	case ".txt":
+		return r.text.ExecuteTemplate(w, name, data)
+	}
+	return fmt.Errorf("server: no template %q", name)
+}
+
+// HTML renders an HTML template as the response. The page is rendered
+// before anything is written, so a template error becomes a clean 500.
+func (r *Renderer) HTML(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := r.ExecuteTemplate(&buf, name, data); err != nil {
+		logger.Error("Template failed", "template", name, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jerrychou/go-practice/data_structure"
+)
+
+// route holds the handlers registered for one pattern
+type route struct {
+	pattern  string
+	any      http.Handler            // handler for every method
+	byMethod map[string]http.Handler // method-specific handlers
+}
+
+func (rt *route) handler(method string) http.Handler {
+	if h, ok := rt.byMethod[method]; ok {
+		return h
+	}
+	if method == http.MethodHead {
+		if h, ok := rt.byMethod[http.MethodGet]; ok {
+			return h
+		}
+	}
+	return rt.any
+}
+
+func (rt *route) allowed() string {
+	return strings.Join(data_structure.SortedKeys(rt.byMethod), ", ")
+}
+
+// Router matches request paths with radix trees instead of ServeMux's linear
+// scan over subtree patterns, so lookup cost depends on the path length rather
+// than the number of routes.
+//
+// Patterns follow ServeMux rules: "/users" matches only that path, while a
+// pattern ending in "/" such as "/users/" matches the whole subtree, with the
+// longest registered pattern winning.
+type Router struct {
+	exact   *data_structure.RadixTree[*route]
+	subtree *data_structure.RadixTree[*route]
+}
+
+// NewRouter creates an empty router
+func NewRouter() *Router {
+	return &Router{
+		exact:   data_structure.NewRadixTree[*route](),
+		subtree: data_structure.NewRadixTree[*route](),
+	}
+}
+
+func (r *Router) route(pattern string) *route {
+	if pattern == "" || pattern[0] != '/' {
+		panic("server: invalid pattern " + pattern)
+	}
+	if rt, ok := r.exact.Get(pattern); ok {
+		return rt
+	}
+	rt := &route{pattern: pattern, byMethod: make(map[string]http.Handler)}
+	r.exact.Insert(pattern, rt)
+	if strings.HasSuffix(pattern, "/") {
+		r.subtree.Insert(pattern, rt)
+	}
+	return rt
+}
+
+// Handle registers handler for every method on pattern
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.route(pattern).any = handler
+}
+
+// HandleFunc registers a handler function for every method on pattern
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	r.Handle(pattern, handler)
+}
+
+// HandleMethod registers handler for a single method on pattern; other methods
+// get 405 Method Not Allowed unless a catch-all handler is registered too
+func (r *Router) HandleMethod(method, pattern string, handler http.Handler) {
+	r.route(pattern).byMethod[method] = handler
+}
+
+// Get registers a GET (and HEAD) handler function on pattern
+func (r *Router) Get(pattern string, handler http.HandlerFunc) {
+	r.HandleMethod(http.MethodGet, pattern, handler)
+}
+
+// Post registers a POST handler function on pattern
+func (r *Router) Post(pattern string, handler http.HandlerFunc) {
+	r.HandleMethod(http.MethodPost, pattern, handler)
+}
+
+// Match returns the route pattern and handler a request would be served by
+func (r *Router) Match(method, path string) (string, http.Handler, bool) {
+	rt, ok := r.exact.Get(path)
+	if !ok {
+		if _, rt, ok = r.subtree.LongestPrefixMatch(path); !ok {
+			return "", nil, false
+		}
+	}
+	if h := rt.handler(method); h != nil {
+		return rt.pattern, h, true
+	}
+	return rt.pattern, methodNotAllowed(rt.allowed()), true
+}
+
+// Routes lists the registered patterns in lexical order
+func (r *Router) Routes() []string {
+	var patterns []string
+	r.exact.WalkPrefix("", func(pattern string, _ *route) bool {
+		patterns = append(patterns, pattern)
+		return true
+	})
+	return patterns
+}
+
+// ServeHTTP dispatches the request to the best matching route
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	pattern, h, ok := r.Match(req.Method, req.URL.Path)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	// Like ServeMux, record the match for middleware such as tracing
+	req.Pattern = pattern
+	h.ServeHTTP(w, req)
+}
+
+func methodNotAllowed(allow string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Allow", allow)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
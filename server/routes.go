@@ -1,12 +1,22 @@
 package server
 
 import (
+	"expvar"
 	"net/http"
+	"time"
+
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/metrics"
+	"github.com/jerrychou/go-practice/tracing"
 )
 
 // SetupRoutes configures all the routes for the server
 func SetupRoutes() http.Handler {
-	mux := http.NewServeMux()
+	return routes()
+}
+
+func routes() *Router {
+	mux := NewRouter()
 
 	// Home page
 	mux.HandleFunc("/", HomeHandler)
@@ -19,9 +29,72 @@ func SetupRoutes() http.Handler {
 	mux.HandleFunc("/users", UsersHandler)
 	mux.HandleFunc("/users/", UserHandler)
 
+	// Markdown preview (sanitized user content)
+	mux.HandleFunc("/preview", PreviewHandler)
+
+	// Versioned user API: /api/v1/users, /api/v2/users, or /api/users with
+	// an Accept header naming the version. v2 pages the list and inherits
+	// the single-user route from v1.
+	api := NewVersionedAPI("api")
+	api.Version(1).HandleFunc("/api/users", APIUsersHandler)
+	api.Version(1).Handle("/api/users/", ErrorHandlerFunc(APIUserHandler))
+	api.Version(2).HandleFunc("/api/users", APIUsersV2Handler)
+	api.Deprecate(1, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC))
+	api.Mount(mux, "/api")
+
 	// API endpoints (JSON)
-	mux.HandleFunc("/api/users", APIUsersHandler)
-	mux.HandleFunc("/api/users/", APIUserHandler)
+	mux.Handle("/api/me", ErrorHandlerFunc(MeHandler))
+	mux.HandleFunc("/api/changes", ChangesHandler)
+	mux.Handle("/api/jobs", Idempotency.Middleware(http.HandlerFunc(JobsHandler)))
+	mux.Handle("/api/transfers", Idempotency.Require(ErrorHandlerFunc(TransfersHandler)))
+	mux.HandleFunc("/api/password-reset", PasswordResetRequestHandler)
+	mux.HandleFunc("/api/upload", UploadHandler)
+	mux.HandleFunc("/api/upload/sessions", UploadSessionsHandler)
+	mux.HandleFunc("/api/upload/sessions/", UploadSessionHandler)
+	mux.Handle("/api/download-links", ErrorHandlerFunc(DownloadLinkHandler))
+
+	// Signed download and upload URLs of the local file store
+	mux.HandleFunc("/files/", FilesHandler)
+
+	// Pre-signed download links from /api/download-links, for any store
+	mux.Handle("/download/", Links.Middleware(http.HandlerFunc(DownloadHandler)))
+
+	// Integration webhooks, verified by signature and checked for replays
+	if GitHubWebhookSecret != "" {
+		mux.Handle("/webhooks/github", githubWebhook())
+	}
+
+	// Password reset form linked from the reset email
+	mux.HandleFunc("/password-reset", PasswordResetHandler)
+
+	// Session-backed pages; their form posts must carry the CSRF token
+	login := CSRF.Middleware(http.HandlerFunc(LoginHandler))
+	mux.HandleMethod(http.MethodGet, "/login", login)
+	mux.HandleMethod(http.MethodPost, "/login", login)
+	mux.HandleMethod(http.MethodPost, "/logout", CSRF.Middleware(http.HandlerFunc(LogoutHandler)))
+	mux.Get("/profile", ProfileHandler)
+	mux.Get("/admin", AdminPageHandler)
+	mux.Handle("/api/me/permissions", ErrorHandlerFunc(MyPermissionsHandler))
+	mux.Handle("/api/me/devices", ErrorHandlerFunc(MyDevicesHandler))
+	mux.HandleMethod(http.MethodPost, "/profile/devices", CSRF.Middleware(http.HandlerFunc(RevokeDeviceHandler)))
+
+	// Live dashboard of server stats and log lines over a WebSocket
+	mux.Get("/dashboard", DashboardHandler)
+	mux.Get("/dashboard/ws", DashboardSocketHandler)
+	mux.Handle("/assets/", AssetsHandler)
+
+	// Admin API for scheduled jobs
+	mux.Handle("/admin/cron/jobs", AdminMiddleware(http.HandlerFunc(CronJobsHandler)))
+	mux.Handle("/admin/cron/jobs/", AdminMiddleware(http.HandlerFunc(CronJobHandler)))
+
+	// Admin API for the queries recorded in development
+	mux.Handle("/admin/queries", AdminMiddleware(http.HandlerFunc(QueriesHandler)))
+
+	// Metrics for Prometheus, and the same values as JSON via expvar
+	metrics.Default.PublishExpvar("metrics")
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/net", NetStatsHandler)
 
 	// Static file serving (if needed)
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
@@ -31,14 +104,34 @@ func SetupRoutes() http.Handler {
 
 // SetupRoutesWithMiddleware configures routes with middleware
 func SetupRoutesWithMiddleware() http.Handler {
+	return SetupRoutesWithFeatures(config.FeatureConfig{EnableCORS: true, EnableRateLimit: true, EnableMetrics: true})
+}
+
+// SetupRoutesWithFeatures configures routes with the middleware that
+// features turns on; problem details, scopes, security headers, IP
+// filtering, logging and tracing are always applied. CORS, rate limiting
+// and metrics can be switched later through Features, and the runtime
+// admin API is only served with EnableAdminAPI.
+func SetupRoutesWithFeatures(features config.FeatureConfig) http.Handler {
 	// Get the base routes
-	handler := SetupRoutes()
+	mux := routes()
+	adminAPIEnabled = features.EnableAdminAPI
+	if features.EnableAdminAPI {
+		mux.Handle("/admin/runtime", RuntimeAdminMiddleware(http.HandlerFunc(RuntimeHandler)))
+		mux.Handle("/admin/runtime/", RuntimeAdminMiddleware(http.HandlerFunc(RuntimeHandler)))
+	}
 
 	// Apply middleware in order (last applied is outermost)
+	var handler http.Handler = mux
+	handler = ProblemMiddleware(handler)
+	handler = ScopeMiddleware(handler)
 	handler = SecurityMiddleware(handler)
-	handler = CORSMiddleware(handler)
-	handler = RateLimitMiddleware(handler)
+	handler = featureGate("cors", features.EnableCORS, CORSMiddleware)(handler)
+	handler = featureGate("rate_limit", features.EnableRateLimit, RateLimitMiddleware)(handler)
+	handler = IPFilterMiddleware(handler)
 	handler = LoggingMiddleware(handler)
+	handler = tracing.Middleware(handler)
+	handler = featureGate("metrics", features.EnableMetrics, metrics.Middleware)(handler)
 
 	return handler
 }
@@ -0,0 +1,422 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/config"
+	"github.com/jerrychou/go-practice/format"
+)
+
+// ErrUnknownFeature is returned by FeatureFlags.Set for a flag that was
+// never registered
+var ErrUnknownFeature = errors.New("unknown feature flag")
+
+// FeatureFlags are switches the admin API can flip while the server runs
+type FeatureFlags struct {
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// Features holds the server's flags, seeded from config by
+// SetupRoutesWithFeatures: "cors", "rate_limit" and "metrics" turn their
+// middleware on and off
+var Features = &FeatureFlags{flags: make(map[string]bool)}
+
+// Register adds a flag, or sets an existing one
+func (f *FeatureFlags) Register(name string, enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[name] = enabled
+}
+
+// Enabled reports whether the flag is on; unknown flags are off
+func (f *FeatureFlags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flags[name]
+}
+
+// Set turns a registered flag on or off
+func (f *FeatureFlags) Set(name string, enabled bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.flags[name]; !ok {
+		return ErrUnknownFeature
+	}
+	f.flags[name] = enabled
+	return nil
+}
+
+// All returns a copy of every flag
+func (f *FeatureFlags) All() map[string]bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	all := make(map[string]bool, len(f.flags))
+	for name, enabled := range f.flags {
+		all[name] = enabled
+	}
+	return all
+}
+
+// featureGate applies middleware to requests only while the flag is on
+func featureGate(name string, enabled bool, middleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	Features.Register(name, enabled)
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if Features.Enabled(name) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// adminAPIEnabled records that SetupRoutesWithFeatures mounted the runtime
+// controls, for the startup banner
+var adminAPIEnabled bool
+
+// ConfigReloader, when set, reads the configuration again and applies what
+// can change at runtime, for the admin API's reload control. The server
+// command sets it to reload its config file through App.Reload.
+var ConfigReloader func(ctx context.Context) error
+
+// ConfigOverrides holds the temporary overrides of the configuration the
+// server was bootstrapped from; nil without a config file
+var ConfigOverrides *config.Overrides[config.FileConfig]
+
+// adminActorKey holds who is using the runtime controls, for the
+// overrides' audit trail
+type adminActorKey struct{}
+
+// adminActor returns who RuntimeAdminMiddleware let in: a username, or
+// "admin-token@" and the client's address
+func adminActor(r *http.Request) string {
+	if actor, ok := r.Context().Value(adminActorKey{}).(string); ok {
+		return actor
+	}
+	return "admin-token@" + clientIP(r)
+}
+
+// RuntimeAdminMiddleware guards the runtime controls. Callers need the
+// admin token, as for AdminMiddleware, or a session whose roles grant
+// admin:manage, in which case changes must also carry the CSRF token.
+func RuntimeAdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminAllowed(r, os.Getenv("ADMIN_TOKEN")) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminActorKey{}, "admin-token@"+clientIP(r))))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		session, ok := webSession(w, r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Admin access required"})
+			return
+		}
+		if !pageAccess.HasPermission(session.UserID, "admin:manage") {
+			logger.Warn("Runtime admin API refused", "username", session.Username, "roles", session.Roles)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "The admin:manage permission is required"})
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if err := CSRF.Verify(r); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminActorKey{}, session.Username)))
+	})
+}
+
+// RuntimeHandler serves the runtime controls:
+//
+//	GET  /admin/runtime                - log levels, flags, draining, goroutines
+//	PUT  /admin/runtime/log-level      - {"level": "debug", "package": "database"}; no package sets all
+//	GET  /admin/runtime/features       - feature flags
+//	PUT  /admin/runtime/features/{name} - {"enabled": false}
+//	POST /admin/runtime/reload         - read the configuration again
+//	GET  /admin/runtime/overrides      - active config overrides and who set them
+//	PUT  /admin/runtime/overrides/{path} - {"value": "debug", "ttl": "15m", "reason": "..."}
+//	DELETE /admin/runtime/overrides/{path} - revert an override before it expires
+//	POST /admin/runtime/drain          - stop keep-alives and fail /health; DELETE resumes
+//	GET  /admin/runtime/pools          - database pool statistics
+//	GET  /admin/runtime/goroutines?debug=1 - goroutine stacks, grouped with debug=1
+func RuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	action, name, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/runtime"), "/"), "/")
+	if action == "goroutines" {
+		if allowMethods(w, r, http.MethodGet) {
+			goroutineStacks(w, r)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch action {
+	case "":
+		if allowMethods(w, r, http.MethodGet) {
+			json.NewEncoder(w).Encode(Response{Success: true, Message: "Runtime state", Data: runtimeState()})
+		}
+	case "log-level":
+		if allowMethods(w, r, http.MethodPut) {
+			setLogLevel(w, r)
+		}
+	case "features":
+		if name == "" && allowMethods(w, r, http.MethodGet) {
+			json.NewEncoder(w).Encode(Response{Success: true, Message: "Feature flags", Data: Features.All()})
+		} else if name != "" && allowMethods(w, r, http.MethodPut) {
+			setFeature(w, r, name)
+		}
+	case "reload":
+		if allowMethods(w, r, http.MethodPost) {
+			reloadConfig(w, r)
+		}
+	case "overrides":
+		if name == "" && allowMethods(w, r, http.MethodGet) {
+			listOverrides(w, r)
+		} else if name != "" && allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+			changeOverride(w, r, name)
+		}
+	case "drain":
+		if allowMethods(w, r, http.MethodPost, http.MethodDelete) {
+			drain(w, r)
+		}
+	case "pools":
+		if allowMethods(w, r, http.MethodGet) {
+			json.NewEncoder(w).Encode(Response{Success: true, Message: "Pool statistics", Data: poolStats()})
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Unknown control " + strconv.Quote(action)})
+	}
+}
+
+// allowMethods answers 405 unless r uses one of methods; GET allows HEAD
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m || m == http.MethodGet && r.Method == http.MethodHead {
+			return true
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(Response{Success: false, Message: "Use " + strings.Join(methods, " or ")})
+	return false
+}
+
+func runtimeState() map[string]any {
+	levels := map[string]string{"default": format.Default().Level().String()}
+	for _, name := range format.PackageLoggers() {
+		levels[name] = format.GetLogger(name).Level().String()
+	}
+	srv := running.Load()
+	return map[string]any{
+		"log_levels": levels,
+		"features":   Features.All(),
+		"draining":   srv != nil && srv.Draining(),
+		"reloadable": ConfigReloader != nil,
+		"overrides":  overrideCount(),
+		"goroutines": runtime.NumGoroutine(),
+		"go_version": runtime.Version(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"cron_jobs":  len(Cron.List()),
+	}
+}
+
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level   string `json:"level"`
+		Package string `json:"package"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil || req.Level == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: `Send {"level": "debug"}, optionally with "package"`})
+		return
+	}
+	level, err := format.ParseLevel(req.Level)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+
+	if req.Package == "" {
+		format.SetLevel(level)
+	} else {
+		format.SetPackageLevel(req.Package, level)
+	}
+	logger.Info("Log level changed from admin API", "level", level, "package", req.Package, "client", clientIP(r))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Log level set to " + level.String()})
+}
+
+func setFeature(w http.ResponseWriter, r *http.Request, name string) {
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil || req.Enabled == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: `Send {"enabled": true} or {"enabled": false}`})
+		return
+	}
+	if err := Features.Set(name, *req.Enabled); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error() + " " + strconv.Quote(name)})
+		return
+	}
+	logger.Info("Feature flag changed from admin API", "feature", name, "enabled", *req.Enabled, "client", clientIP(r))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Feature flag updated", Data: Features.All()})
+}
+
+func reloadConfig(w http.ResponseWriter, r *http.Request) {
+	if ConfigReloader == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "The server was not started from a config file"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	if err := ConfigReloader(ctx); err != nil {
+		logger.Error("Config reload from admin API failed", "error", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Reload failed: " + err.Error()})
+		return
+	}
+	logger.Info("Config reloaded from admin API", "client", clientIP(r))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Configuration reloaded", Data: runtimeState()})
+}
+
+func overrideCount() int {
+	if ConfigOverrides == nil {
+		return 0
+	}
+	return len(ConfigOverrides.Active())
+}
+
+func listOverrides(w http.ResponseWriter, r *http.Request) {
+	if ConfigOverrides == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "The server was not started from a config file"})
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Config overrides", Data: map[string]any{
+		"active":  ConfigOverrides.Active(),
+		"history": ConfigOverrides.History(cmp.Or(limit, 50)),
+	}})
+}
+
+// changeOverride sets or clears the override of the setting at path, a
+// dotted path such as logging.level
+func changeOverride(w http.ResponseWriter, r *http.Request, path string) {
+	if ConfigOverrides == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "The server was not started from a config file"})
+		return
+	}
+	actor := adminActor(r)
+	if r.Method == http.MethodDelete {
+		if err := ConfigOverrides.Clear(path, actor); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+			return
+		}
+		logger.Info("Config override cleared from admin API", "path", path, "by", actor, "client", clientIP(r))
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Override of " + path + " cleared", Data: ConfigOverrides.Active()})
+		return
+	}
+
+	var req struct {
+		Value  json.RawMessage `json:"value"`
+		TTL    string          `json:"ttl"`
+		Reason string          `json:"reason"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&req); err != nil || req.Value == nil || req.TTL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: `Send {"value": "debug", "ttl": "15m"}, optionally with "reason"`})
+		return
+	}
+	ttl, err := config.ParseDuration(req.TTL)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+	var value any
+	json.Unmarshal(req.Value, &value)
+
+	override, err := ConfigOverrides.Set(path, value, ttl, actor, req.Reason)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, config.ErrInvalidOverride) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: err.Error()})
+		return
+	}
+	logger.Warn("Config overridden from admin API", "path", path, "value", override.Value,
+		"expires_at", override.ExpiresAt, "by", actor, "reason", req.Reason, "client", clientIP(r))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Override of " + path + " set until " + override.ExpiresAt.Format(time.RFC3339), Data: override})
+}
+
+func drain(w http.ResponseWriter, r *http.Request) {
+	srv := running.Load()
+	if srv == nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "The server is not running"})
+		return
+	}
+	on := r.Method == http.MethodPost
+	srv.SetDraining(on)
+	message := "Draining: keep-alives are off and /health fails"
+	if !on {
+		message = "Serving normally again"
+	}
+	logger.Warn("Drain changed from admin API", "draining", on, "client", clientIP(r))
+	json.NewEncoder(w).Encode(Response{Success: true, Message: message})
+}
+
+// poolStats reports the database pool and the pools exported as
+// db_connections_* metrics, as the dashboard shows them
+func poolStats() map[string]any {
+	stats := map[string]any{"pools": Dashboard.collect().Pools}
+	if DB != nil {
+		stats["database"] = DB.Stats()
+	}
+	return stats
+}
+
+// goroutineStacks writes every goroutine's stack as text; debug=1 groups
+// identical stacks with a count, debug=2 (the default) lists each one
+func goroutineStacks(w http.ResponseWriter, r *http.Request) {
+	debug := 2
+	if s := r.URL.Query().Get("debug"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 2 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "debug must be 1 or 2"})
+			return
+		}
+		debug = n
+	}
+	logger.Info("Goroutine dump from admin API", "goroutines", runtime.NumGoroutine(), "client", clientIP(r))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	pprof.Lookup("goroutine").WriteTo(w, debug)
+}
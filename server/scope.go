@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jerrychou/go-practice/format"
+	"github.com/jerrychou/go-practice/security"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// DB is the database that request scopes open transactions on. Requests
+// that ask for a transaction while it is nil fail with ErrNoDatabase.
+var DB *sql.DB
+
+// Auth validates the bearer tokens behind RequestScope.User. It is set from
+// JWT_SECRET by the server binary; while nil, no request is authenticated.
+var Auth *security.JWTAuth
+
+// ErrNoDatabase is returned by RequestScope.Tx when DB is not configured
+var ErrNoDatabase = errors.New("server: no database configured")
+
+// RequestScope holds the resources of one request: its ID, a logger that
+// carries it, the authenticated user and a database transaction. Each is
+// created on first use, so a request that never touches the database never
+// begins a transaction.
+//
+// The transaction ends when the handler starts its response: it commits
+// for a status below 400 and rolls back otherwise, or when the handler
+// panics. A failed commit replaces the response with a 500 problem, so
+// finish all database work before writing anything.
+type RequestScope struct {
+	ID string
+
+	req *http.Request
+	db  *sql.DB
+
+	loggerOnce sync.Once
+	logger     *format.Logger
+
+	userOnce sync.Once
+	user     *security.JWTClaims
+	userErr  error
+
+	mu    sync.Mutex
+	tx    *sql.Tx
+	ended bool
+}
+
+type scopeKey struct{}
+
+// ScopeFrom returns the scope of the request ctx belongs to. Outside
+// ScopeMiddleware it returns a new scope with no transaction support.
+func ScopeFrom(ctx context.Context) *RequestScope {
+	if scope, ok := ctx.Value(scopeKey{}).(*RequestScope); ok {
+		return scope
+	}
+	req := (&http.Request{URL: &url.URL{}, Header: http.Header{}}).WithContext(ctx)
+	return &RequestScope{ID: newRequestID(), req: req}
+}
+
+// ScopeMiddleware gives each request a RequestScope, reachable from
+// handlers with ScopeFrom(r.Context()), and echoes its ID in X-Request-ID.
+// A well-formed X-Request-ID from the client is kept so logs line up
+// across services. Wrap it around ProblemMiddleware, so a panic is logged
+// with the request ID and its 500 rolls the transaction back.
+func ScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		scope := &RequestScope{ID: id, db: DB}
+		r = r.WithContext(context.WithValue(r.Context(), scopeKey{}, scope))
+		scope.req = r
+		w.Header().Set("X-Request-ID", id)
+
+		sw := &scopeWriter{ResponseWriter: w, scope: scope}
+		defer func() {
+			// A panic that got past ProblemMiddleware, or an empty 200
+			if p := recover(); p != nil {
+				scope.end(false)
+				panic(p)
+			}
+			if !sw.wrote {
+				sw.WriteHeader(http.StatusOK)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// Logger returns the server logger with the request's ID, method and path
+func (s *RequestScope) Logger() *format.Logger {
+	s.loggerOnce.Do(func() {
+		fields := format.Fields{"request_id": s.ID}
+		if s.req.Method != "" {
+			fields["method"] = s.req.Method
+			fields["path"] = s.req.URL.Path
+		}
+		s.logger = logger.With(fields)
+	})
+	return s.logger
+}
+
+// User returns the claims of the request's bearer token, or a 401 problem
+// when it has none or the token is invalid
+func (s *RequestScope) User() (*security.JWTClaims, error) {
+	s.userOnce.Do(func() {
+		token, ok := strings.CutPrefix(s.req.Header.Get("Authorization"), "Bearer ")
+		switch {
+		case !ok || token == "":
+			s.userErr = NewProblem(http.StatusUnauthorized, "unauthenticated", "A bearer token is required")
+		case Auth == nil:
+			s.userErr = NewProblem(http.StatusUnauthorized, "unauthenticated", "Authentication is not configured")
+		default:
+			s.user, s.userErr = Auth.ValidateToken(token)
+			if s.userErr != nil {
+				s.userErr = NewProblem(http.StatusUnauthorized, "invalid_token", "The bearer token is invalid or expired").Wrap(s.userErr)
+			}
+		}
+	})
+	return s.user, s.userErr
+}
+
+// Tx returns the request's transaction, beginning it on first use with the
+// request's context
+func (s *RequestScope) Tx() (*sql.Tx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		return s.tx, nil
+	}
+	if s.ended {
+		return nil, sql.ErrTxDone
+	}
+	if s.db == nil {
+		return nil, ErrNoDatabase
+	}
+	tx, err := s.db.BeginTx(s.req.Context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin request transaction: %w", err)
+	}
+	s.tx = tx
+	return tx, nil
+}
+
+// end commits or rolls back the transaction, if one was begun
+func (s *RequestScope) end(commit bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+	tx := s.tx
+	s.tx = nil
+	if tx == nil {
+		return nil
+	}
+	if !commit {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			s.Logger().Warn("Rollback failed", "error", err)
+		}
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit request transaction: %w", err)
+	}
+	return nil
+}
+
+// scopeWriter ends the scope's transaction before the status line goes out
+type scopeWriter struct {
+	http.ResponseWriter
+	scope  *RequestScope
+	wrote  bool
+	failed bool
+}
+
+func (w *scopeWriter) WriteHeader(code int) {
+	if w.wrote || code < http.StatusOK {
+		if code < http.StatusOK {
+			w.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	w.wrote = true
+	if err := w.scope.end(code < http.StatusBadRequest); err != nil {
+		w.failed = true
+		WriteProblem(w.ResponseWriter, w.scope.req, err)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write discards the body of a response replaced by a failed commit
+func (w *scopeWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush commits before streaming starts
+func (w *scopeWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack ends the scope, committing, before a handler takes over the
+// connection, e.g. for a WebSocket
+func (w *scopeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && !w.wrote {
+		w.wrote = true
+		if err := w.scope.end(true); err != nil {
+			w.scope.Logger().Error("Commit before hijack failed", "error", err)
+		}
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *scopeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func newRequestID() string {
+	return "req-" + string_op.ULID()
+}
+
+// validRequestID accepts short IDs of letters, digits, '-' and '_', so a
+// client cannot inject anything odd into logs or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
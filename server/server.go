@@ -1,17 +1,36 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/jerrychou/go-practice/tracing"
 )
 
 // Server represents the HTTP server configuration
 type Server struct {
+	Host    string // empty listens on all interfaces
 	Port    string
 	Handler http.Handler
+
+	// Timeouts default to 15s for reads and writes and 60s when idle
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	mu       sync.Mutex
+	http     *http.Server
+	draining atomic.Bool
 }
 
+// running is the server last started, for the admin API's drain control
+var running atomic.Pointer[Server]
+
 // New creates a new server instance
 func New(port string) *Server {
 	return &Server{
@@ -22,14 +41,18 @@ func New(port string) *Server {
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	server := &http.Server{
-		Addr:         ":" + s.Port,
+		Addr:         net.JoinHostPort(s.Host, s.Port),
 		Handler:      s.Handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  orDefault(s.ReadTimeout, 15*time.Second),
+		WriteTimeout: orDefault(s.WriteTimeout, 15*time.Second),
+		IdleTimeout:  orDefault(s.IdleTimeout, 60*time.Second),
 	}
+	s.mu.Lock()
+	s.http = server
+	s.mu.Unlock()
+	running.Store(s)
 
-	fmt.Printf("🚀 HTTP Server starting on port %s\n", s.Port)
+	fmt.Printf("🚀 HTTP Server starting on %s\n", server.Addr)
 	fmt.Printf("📋 Available endpoints:\n")
 	fmt.Printf("   GET  /           - Home page\n")
 	fmt.Printf("   GET  /health     - Health check\n")
@@ -38,10 +61,90 @@ func (s *Server) Start() error {
 	fmt.Printf("   GET  /users/{id} - Get user by ID\n")
 	fmt.Printf("   GET  /api/users  - API: List all users (JSON)\n")
 	fmt.Printf("   GET  /api/users/{id} - API: Get user by ID (JSON)\n")
+	fmt.Printf("   GET  /api/v{1,2}/users - API: Versioned users (or Accept: application/vnd.api.v2+json)\n")
+	fmt.Printf("   GET  /api/me     - API: Current user from a JWT bearer token (JSON)\n")
+	fmt.Printf("   GET  /api/changes - API: Stream database changes (server-sent events)\n")
+	fmt.Printf("   POST /api/jobs   - API: Enqueue a background job (JSON, optional Idempotency-Key)\n")
+	fmt.Printf("   GET  /api/transfers - API: Demo ledger; POST moves money, Idempotency-Key required (JSON)\n")
+	fmt.Printf("   POST /api/password-reset - API: Email a password reset link (JSON)\n")
+	fmt.Printf("   GET  /password-reset - Password reset form\n")
+	fmt.Printf("   POST /api/upload - API: Upload files as multipart/form-data (JSON)\n")
+	fmt.Printf("   POST /api/upload/sessions - API: Start a resumable upload, then PATCH chunks to its Location\n")
+	fmt.Printf("   GET  /files/{key} - Download through a signed URL\n")
+	fmt.Printf("   POST /api/download-links - API: Pre-signed, optionally IP-bound download link (JWT, JSON)\n")
+	fmt.Printf("   GET  /download/{key} - Download through a pre-signed link\n")
+	fmt.Printf("   POST /webhooks/github - GitHub webhook, signed with GITHUB_WEBHOOK_SECRET\n")
+	fmt.Printf("   GET  /admin/cron/jobs - Admin: List scheduled jobs (JSON)\n")
+	fmt.Printf("   GET  /admin/cron/jobs/{name} - Admin: Job state and run history (JSON)\n")
+	fmt.Printf("   POST /admin/cron/jobs/{name}/{trigger|pause|resume} - Admin: Control a job (JSON)\n")
+	fmt.Printf("   GET  /admin/queries - Admin: Recorded queries with timings and plans (JSON)\n")
+	if adminAPIEnabled {
+		fmt.Printf("   GET  /admin/runtime/... - Admin: Log levels, feature flags, reload, drain, pools, goroutines (JSON)\n")
+	}
+	fmt.Printf("   GET  /metrics    - Prometheus metrics\n")
+	fmt.Printf("   GET  /debug/vars - expvar metrics (JSON)\n")
+	fmt.Printf("   GET  /debug/net  - TCP/UDP server connection statistics\n")
+	fmt.Printf("   GET  /login, /profile, /admin - Session-cookie pages; POST /logout\n")
+	fmt.Printf("   GET  /api/me/permissions - API: Signed-in user's permissions, ?check=a,b (JSON)\n")
+	fmt.Printf("   GET  /api/me/devices - API: Signed-in user's remembered devices; POST /profile/devices signs one out\n")
+	fmt.Printf("   GET  /dashboard  - Live stats and log lines over a WebSocket (admin)\n")
+
+	Dashboard.captureLogs()
+
+	provider := tracing.SetupFromEnv("go-practice-server")
+	defer provider.Shutdown(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Jobs.Start(ctx, 2); err != nil {
+		return err
+	}
+	if err := Cron.Start(ctx); err != nil {
+		return err
+	}
+	defer Cron.Stop()
 
 	return server.ListenAndServe()
 }
 
+// Shutdown stops accepting connections and waits for active requests to
+// finish until ctx is done; Start then returns http.ErrServerClosed
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.http
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// SetDraining prepares the server to be taken out of rotation, or puts it
+// back: while draining, /health answers 503 so load balancers stop sending
+// traffic, and connections are closed after their current request instead
+// of being kept alive
+func (s *Server) SetDraining(on bool) {
+	s.draining.Store(on)
+	s.mu.Lock()
+	server := s.http
+	s.mu.Unlock()
+	if server != nil {
+		server.SetKeepAlivesEnabled(!on)
+	}
+}
+
+// Draining reports whether SetDraining turned draining on
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
 // SetHandler sets the HTTP handler for the server
 func (s *Server) SetHandler(handler http.Handler) {
 	s.Handler = handler
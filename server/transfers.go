@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Account is a balance in the demo ledger, in cents
+type Account struct {
+	ID      int    `json:"id"`
+	Owner   string `json:"owner"`
+	Balance int64  `json:"balance_cents"`
+}
+
+// Transfer moves money between two accounts of the demo ledger
+type Transfer struct {
+	ID        int       `json:"id"`
+	From      int       `json:"from_account_id"`
+	To        int       `json:"to_account_id"`
+	Amount    int64     `json:"amount_cents"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ledger keeps the accounts behind /api/transfers in memory; it is the
+// in-process counterpart of database.TransactionManager.TransferMoney
+type ledger struct {
+	mu        sync.Mutex
+	accounts  map[int]*Account
+	transfers []Transfer
+}
+
+var demoLedger = &ledger{accounts: map[int]*Account{
+	1: {ID: 1, Owner: "alice", Balance: 100000},
+	2: {ID: 2, Owner: "bob", Balance: 50000},
+	3: {ID: 3, Owner: "carol", Balance: 0},
+}}
+
+// transfer debits from and credits to as one step
+func (l *ledger) transfer(from, to int, amount int64) (Transfer, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	source, ok := l.accounts[from]
+	if !ok {
+		return Transfer{}, NewProblem(http.StatusNotFound, "account_not_found", "No account "+strconv.Itoa(from))
+	}
+	destination, ok := l.accounts[to]
+	if !ok {
+		return Transfer{}, NewProblem(http.StatusNotFound, "account_not_found", "No account "+strconv.Itoa(to))
+	}
+	if source.Balance < amount {
+		return Transfer{}, NewProblem(http.StatusUnprocessableEntity, "insufficient_funds",
+			"Account "+strconv.Itoa(from)+" has "+strconv.FormatInt(source.Balance, 10)+" cents")
+	}
+	source.Balance -= amount
+	destination.Balance += amount
+	t := Transfer{ID: len(l.transfers) + 1, From: from, To: to, Amount: amount, CreatedAt: time.Now().UTC()}
+	l.transfers = append(l.transfers, t)
+	return t, nil
+}
+
+func (l *ledger) snapshot() ([]Account, []Transfer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	accounts := make([]Account, 0, len(l.accounts))
+	for _, a := range l.accounts {
+		accounts = append(accounts, *a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, append([]Transfer(nil), l.transfers...)
+}
+
+// TransfersHandler serves the demo ledger. Mount it behind
+// Idempotency.Require, so a client retrying a transfer after a timeout
+// does not move the money twice:
+//
+//	GET  /api/transfers - balances and transfers so far
+//	POST /api/transfers - {"from_account_id": 1, "to_account_id": 2, "amount_cents": 2500}
+func TransfersHandler(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		accounts, transfers := demoLedger.snapshot()
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(Response{
+			Success: true,
+			Message: strconv.Itoa(len(transfers)) + " transfers",
+			Data:    map[string]any{"accounts": accounts, "transfers": transfers},
+		})
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		return NewProblem(http.StatusMethodNotAllowed, "method_not_allowed", "Use GET to list or POST to transfer")
+	}
+
+	var req struct {
+		From   int   `json:"from_account_id"`
+		To     int   `json:"to_account_id"`
+		Amount int64 `json:"amount_cents"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		return NewProblem(http.StatusBadRequest, "invalid_body", "The body must be a JSON object").Wrap(err)
+	}
+	if req.Amount <= 0 {
+		return NewProblem(http.StatusBadRequest, "invalid_amount", "amount_cents must be positive")
+	}
+	if req.From == req.To {
+		return NewProblem(http.StatusBadRequest, "same_account", "Cannot transfer to the same account")
+	}
+
+	t, err := demoLedger.transfer(req.From, req.To, req.Amount)
+	if err != nil {
+		return err
+	}
+	ScopeFrom(r.Context()).Logger().Info("Transfer made", "id", t.ID, "from", t.From, "to", t.To, "amount_cents", t.Amount)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(Response{Success: true, Message: "Transfer made", Data: t})
+}
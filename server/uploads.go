@@ -0,0 +1,381 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/filestore"
+	"github.com/jerrychou/go-practice/security"
+	"github.com/jerrychou/go-practice/string_op"
+)
+
+// Resumable uploads follow the core of the tus protocol (tus.io): POST
+// creates an upload of known length, PATCH requests append chunks at the
+// offset the server holds, and HEAD tells a client whose connection broke
+// where to resume. The finished file goes to the same store as /api/upload.
+
+const (
+	tusVersion = "1.0.0"
+	// statusChecksumMismatch is tus's status for a chunk that does not
+	// match its Upload-Checksum
+	statusChecksumMismatch = 460
+)
+
+// UploadDir holds unfinished resumable uploads
+var UploadDir = filepath.Join(os.TempDir(), "go-practice-uploads")
+
+// MaxResumableUploadSize caps the Upload-Length of a resumable upload
+var MaxResumableUploadSize int64 = 1 << 30
+
+// UploadSessionTTL is how long an unfinished resumable upload is kept
+var UploadSessionTTL = 24 * time.Hour
+
+// uploadSession is a resumable upload. Its metadata is saved next to the
+// partial file, so uploads survive a server restart; the offset is the
+// partial file's size.
+type uploadSession struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`
+	Offset      int64     `json:"offset"`
+	SHA256      string    `json:"sha256,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// sessionLocks serializes requests for one upload, by session ID
+var sessionLocks sync.Map
+
+func (s *uploadSession) partPath() string { return filepath.Join(UploadDir, s.ID+".part") }
+func (s *uploadSession) metaPath() string { return filepath.Join(UploadDir, s.ID+".json") }
+
+// UploadSessionsHandler starts a resumable upload. The request carries
+// Upload-Length and, optionally, Upload-Metadata with base64 "filename",
+// "filetype" and "sha256" (hex) values; the response's Location is where
+// chunks are sent.
+func UploadSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to start an upload"})
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload-Length must be a positive number of bytes"})
+		return
+	}
+	if size > MaxResumableUploadSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload exceeds the size limit"})
+		return
+	}
+	meta, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err == nil && meta["sha256"] != "" && !isHex(meta["sha256"], sha256.Size*2) {
+		err = errors.New("sha256 must be 64 hex digits")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Invalid Upload-Metadata: " + err.Error()})
+		return
+	}
+
+	session := &uploadSession{
+		ID:          string_op.RandomString(string_op.AlphabetLowerHex, 32),
+		Name:        meta["filename"],
+		ContentType: meta["filetype"],
+		Size:        size,
+		SHA256:      strings.ToLower(meta["sha256"]),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if session.Name == "" {
+		session.Name = "upload"
+	}
+	if err := createSession(session); err != nil {
+		logger.Error("Failed to start upload", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Could not start the upload"})
+		return
+	}
+
+	logger.Info("Resumable upload started", "id", session.ID, "name", session.Name, "size", session.Size)
+	w.Header().Set("Location", "/api/upload/sessions/"+session.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Upload started", Data: session})
+}
+
+// UploadSessionHandler serves one resumable upload: HEAD or GET reports its
+// offset, PATCH appends a chunk, and DELETE cancels it. The PATCH that
+// completes the upload stores the file and returns it like /api/upload.
+func UploadSessionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Tus-Resumable", tusVersion)
+	id := strings.TrimPrefix(r.URL.Path, "/api/upload/sessions/")
+	if !isHex(id, 32) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload not found"})
+		return
+	}
+
+	lock, _ := sessionLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	if !mu.TryLock() {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Another request is writing to this upload"})
+		return
+	}
+	defer mu.Unlock()
+
+	session, err := loadSession(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		sessionLocks.Delete(id)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load upload", "id", id, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Could not load the upload"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(session.Size, 10))
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Upload in progress", Data: session})
+	case http.MethodPatch:
+		appendChunk(w, r, session)
+	case http.MethodDelete:
+		removeSession(session)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PATCH, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use PATCH to send a chunk"})
+	}
+}
+
+// appendChunk writes a PATCH body at the session's offset. A chunk that
+// fails its Upload-Checksum is dropped; one cut off by a broken connection
+// is kept without a checksum, so the client resumes after what arrived.
+func appendChunk(w http.ResponseWriter, r *http.Request, session *uploadSession) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Chunks must be sent as application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != session.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: fmt.Sprintf("Upload-Offset must be %d", session.Offset)})
+		return
+	}
+	var wantSum []byte
+	if header := r.Header.Get("Upload-Checksum"); header != "" {
+		algorithm, encoded, _ := strings.Cut(header, " ")
+		if wantSum, err = base64.StdEncoding.DecodeString(encoded); algorithm != "sha256" || err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Upload-Checksum must be \"sha256 <base64 digest>\""})
+			return
+		}
+	}
+
+	file, err := os.OpenFile(session.partPath(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		uploadFailed(w, err)
+		return
+	}
+	hash := sha256.New()
+	n, copyErr := io.Copy(io.MultiWriter(file, hash), http.MaxBytesReader(w, r.Body, session.Size-session.Offset))
+	if copyErr != nil || wantSum != nil && !bytes.Equal(hash.Sum(nil), wantSum) {
+		var tooLarge *http.MaxBytesError
+		if copyErr == nil || wantSum != nil || errors.As(copyErr, &tooLarge) {
+			file.Truncate(session.Offset)
+			n = 0
+		}
+		file.Close()
+		switch {
+		case errors.As(copyErr, &tooLarge):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Chunk goes past Upload-Length"})
+		case copyErr != nil:
+			logger.Warn("Upload chunk interrupted", "id", session.ID, "kept", n, "error", copyErr)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Chunk was not received completely"})
+		default:
+			w.WriteHeader(statusChecksumMismatch)
+			json.NewEncoder(w).Encode(Response{Success: false, Message: "Chunk does not match Upload-Checksum"})
+		}
+		return
+	}
+	if err := file.Close(); err != nil {
+		uploadFailed(w, err)
+		return
+	}
+
+	session.Offset += n
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	logger.Debug("Upload chunk received", "id", session.ID, "offset", session.Offset, "size", session.Size)
+	if session.Offset < session.Size {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	finishUpload(w, r, session)
+}
+
+// finishUpload moves a complete upload into the file store
+func finishUpload(w http.ResponseWriter, r *http.Request, session *uploadSession) {
+	store, err := uploads()
+	if err != nil {
+		logger.Error("File store unavailable", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "File storage is unavailable"})
+		return
+	}
+	part, err := os.Open(session.partPath())
+	if err != nil {
+		uploadFailed(w, err)
+		return
+	}
+	defer part.Close()
+	if UploadScanning != nil {
+		file := security.ScannedFile{Name: session.Name, ContentType: session.ContentType, Path: part.Name(), Size: session.Size}
+		if err := scanUpload(w, r, file, nil); err != nil {
+			// A rejected file is dropped; after a failed scan the data
+			// stays, so a PATCH with an empty body retries
+			if errors.Is(err, security.ErrUploadRejected) {
+				removeSession(session)
+			}
+			return
+		}
+	}
+
+	opts := filestore.PutOptions{ContentType: session.ContentType}
+	var info filestore.ObjectInfo
+	var existed bool
+	if session.SHA256 != "" {
+		info, existed, err = store.PutSum(r.Context(), part, opts, session.SHA256)
+	} else {
+		info, existed, err = store.Put(r.Context(), part, opts)
+	}
+	if errors.Is(err, filestore.ErrChecksumMismatch) {
+		logger.Warn("Upload checksum mismatch", "id", session.ID, "name", session.Name, "error", err)
+		removeSession(session)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Checksum mismatch for " + session.Name})
+		return
+	}
+	if err != nil {
+		// The data stays, so a PATCH with an empty body retries this step
+		uploadFailed(w, err)
+		return
+	}
+
+	removeSession(session)
+	json.NewEncoder(w).Encode(Response{Success: true, Message: "Upload complete", Data: uploaded(r.Context(), store, session.Name, info, existed)})
+}
+
+func createSession(session *uploadSession) error {
+	if err := os.MkdirAll(UploadDir, 0o755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(session.partPath(), nil, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(session.metaPath(), meta, 0o644)
+}
+
+func loadSession(id string) (*uploadSession, error) {
+	session := &uploadSession{ID: id}
+	data, err := os.ReadFile(session.metaPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(session.partPath())
+	if err != nil {
+		return nil, err
+	}
+	session.Offset = info.Size()
+	return session, nil
+}
+
+func removeSession(session *uploadSession) {
+	os.Remove(session.partPath())
+	os.Remove(session.metaPath())
+}
+
+// PurgeUploadSessions deletes resumable uploads started more than
+// UploadSessionTTL ago and returns how many it removed
+func PurgeUploadSessions() int {
+	entries, err := os.ReadDir(UploadDir)
+	if err != nil {
+		return 0
+	}
+	purged := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !isHex(id, 32) {
+			continue
+		}
+		session, err := loadSession(id)
+		if err != nil || time.Since(session.CreatedAt) > UploadSessionTTL {
+			removeSession(&uploadSession{ID: id})
+			sessionLocks.Delete(id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// parseUploadMetadata decodes tus's "key base64,key base64" header
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("value of %q is not base64", key)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+func isHex(s string, length int) bool {
+	_, err := hex.DecodeString(s)
+	return len(s) == length && err == nil
+}
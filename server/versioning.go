@@ -0,0 +1,180 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionedAPI serves several versions of an API side by side. A request
+// picks its version with a path prefix such as /api/v2/users, or on the
+// plain path with an Accept header such as application/vnd.api.v2+json.
+//
+// Each version has its own Router. A version only needs the routes that
+// changed: a path it does not handle is served by the closest earlier
+// version that does.
+type VersionedAPI struct {
+	// Vendor names the media type: "api" negotiates with
+	// application/vnd.api.v{N}+json
+	Vendor string
+	// Default serves requests that name no version. Zero means the oldest
+	// version, so clients that predate versioning keep their responses.
+	Default int
+
+	versions map[int]*apiVersion
+	prefix   string
+}
+
+type apiVersion struct {
+	router     *Router
+	deprecated time.Time
+	sunset     time.Time
+}
+
+// NewVersionedAPI creates an API negotiated with the given media type
+// vendor
+func NewVersionedAPI(vendor string) *VersionedAPI {
+	return &VersionedAPI{Vendor: vendor, versions: make(map[int]*apiVersion)}
+}
+
+// Version returns the router for version v, creating it on first use.
+// Patterns are full paths without the version, such as "/api/users".
+func (a *VersionedAPI) Version(v int) *Router {
+	if v <= 0 {
+		panic("server: API versions start at 1")
+	}
+	if _, ok := a.versions[v]; !ok {
+		a.versions[v] = &apiVersion{router: NewRouter()}
+	}
+	return a.versions[v].router
+}
+
+// Deprecate marks version v as deprecated since deprecated and removed at
+// sunset. Its responses then carry Deprecation (RFC 9745), Sunset (RFC
+// 8594) and a Link to the latest version; a zero sunset is left out.
+func (a *VersionedAPI) Deprecate(v int, deprecated, sunset time.Time) {
+	a.Version(v)
+	a.versions[v].deprecated = deprecated
+	a.versions[v].sunset = sunset
+}
+
+// Mount registers the API on r: prefix + "/v{N}/" for each version, and
+// each route of any version on its plain path for Accept negotiation.
+// Register every version's routes before calling Mount.
+func (a *VersionedAPI) Mount(r *Router, prefix string) {
+	a.prefix = strings.TrimSuffix(prefix, "/")
+	seen := make(map[string]bool)
+	for _, v := range a.numbers() {
+		r.Handle(fmt.Sprintf("%s/v%d/", a.prefix, v), a)
+		for _, pattern := range a.versions[v].router.Routes() {
+			if !seen[pattern] {
+				seen[pattern] = true
+				r.Handle(pattern, a)
+			}
+		}
+	}
+}
+
+// ServeHTTP serves a request with the version it asks for
+func (a *VersionedAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v, path, negotiated, err := a.requestedVersion(r)
+	if negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+	if err != nil {
+		status := http.StatusNotFound
+		if negotiated {
+			status = http.StatusNotAcceptable
+		}
+		WriteProblem(w, r, NewProblem(status, "unsupported_version", err.Error()))
+		return
+	}
+
+	w.Header().Set("API-Version", strconv.Itoa(v))
+	if version := a.versions[v]; !version.deprecated.IsZero() {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(version.deprecated.Unix(), 10))
+		if !version.sunset.IsZero() {
+			w.Header().Set("Sunset", version.sunset.UTC().Format(http.TimeFormat))
+		}
+		latest := a.numbers()[len(a.versions)-1]
+		successor := fmt.Sprintf("%s/v%d%s", a.prefix, latest, strings.TrimPrefix(path, a.prefix))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+	}
+
+	// Fall back through earlier versions for routes v does not change
+	numbers := a.numbers()
+	for i := slices.Index(numbers, v); i >= 0; i-- {
+		pattern, h, ok := a.versions[numbers[i]].router.Match(r.Method, path)
+		if !ok {
+			continue
+		}
+		req := r
+		if path != r.URL.Path {
+			req = r.Clone(r.Context())
+			req.URL.Path, req.URL.RawPath = path, ""
+		}
+		req.Pattern = pattern
+		h.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// requestedVersion returns the version a request asks for and its path
+// without the version prefix. negotiated reports that the version came
+// from the Accept header or that the header could have chosen it.
+func (a *VersionedAPI) requestedVersion(r *http.Request) (v int, path string, negotiated bool, err error) {
+	path = r.URL.Path
+	if rest, ok := strings.CutPrefix(path, a.prefix+"/v"); ok {
+		digits, tail, _ := strings.Cut(rest, "/")
+		if n, convErr := strconv.Atoi(digits); convErr == nil && n > 0 {
+			if _, ok := a.versions[n]; !ok {
+				return 0, "", false, fmt.Errorf("API version %d does not exist", n)
+			}
+			return n, a.prefix + "/" + tail, false, nil
+		}
+	}
+
+	if n, ok := a.acceptVersion(r.Header.Get("Accept")); ok {
+		if _, ok := a.versions[n]; !ok {
+			return 0, "", true, fmt.Errorf("API version %d does not exist; supported: %v", n, a.numbers())
+		}
+		return n, path, true, nil
+	}
+	if a.Default != 0 {
+		return a.Default, path, true, nil
+	}
+	return a.numbers()[0], path, true, nil
+}
+
+// acceptVersion finds application/vnd.<vendor>.v{N}+json in an Accept
+// header
+func (a *VersionedAPI) acceptVersion(accept string) (int, bool) {
+	prefix := "application/vnd." + a.Vendor + ".v"
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if digits, ok := strings.CutPrefix(mediaType, prefix); ok {
+			if n, err := strconv.Atoi(strings.TrimSuffix(digits, "+json")); err == nil && n > 0 {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// numbers returns the registered versions in ascending order
+func (a *VersionedAPI) numbers() []int {
+	numbers := make([]int, 0, len(a.versions))
+	for v := range a.versions {
+		numbers = append(numbers, v)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
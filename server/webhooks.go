@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jerrychou/go-practice/cache"
+	"github.com/jerrychou/go-practice/security"
+)
+
+// GitHubWebhookSecret enables /webhooks/github when set; the server binary
+// reads it from GITHUB_WEBHOOK_SECRET
+var GitHubWebhookSecret string
+
+// WebhookNonces remembers accepted deliveries so replays are rejected.
+// Point it at a cache.Redis when running several instances.
+var WebhookNonces security.NonceCache = cache.NewMemory(cache.MemoryOptions{MaxEntries: 100000})
+
+// WebhookReplayWindow is how long a delivery ID is remembered. GitHub
+// redelivers for up to three days, but only after a failed attempt.
+var WebhookReplayWindow = 72 * time.Hour
+
+type githubEvent struct {
+	Event    string          `json:"event"`
+	Delivery string          `json:"delivery"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+func init() {
+	Jobs.Register("github_event", func(ctx context.Context, payload json.RawMessage) error {
+		var event githubEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return err
+		}
+		logger.Info("GitHub event processed", "event", event.Event, "delivery", event.Delivery, "bytes", len(event.Payload))
+		return nil
+	})
+}
+
+// githubWebhook verifies deliveries to /webhooks/github before
+// GitHubWebhookHandler sees them
+func githubWebhook() http.Handler {
+	verify := security.WebhookMiddleware(security.GitHubWebhook{Secret: GitHubWebhookSecret}, WebhookNonces, WebhookReplayWindow)
+	return verify(http.HandlerFunc(GitHubWebhookHandler))
+}
+
+// GitHubWebhookHandler queues a verified GitHub delivery as a github_event
+// job and acknowledges it at once, since GitHub gives up after 10 seconds
+func GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Use POST to deliver a webhook"})
+		return
+	}
+
+	event := githubEvent{
+		Event:    r.Header.Get("X-GitHub-Event"),
+		Delivery: r.Header.Get("X-GitHub-Delivery"),
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(body) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "The payload must be JSON"})
+		return
+	}
+	event.Payload = body
+	if event.Event == "ping" {
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "pong"})
+		return
+	}
+
+	id, err := Jobs.Enqueue(r.Context(), "github_event", event)
+	if err != nil {
+		logger.Error("Failed to queue GitHub event", "delivery", event.Delivery, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Response{Success: false, Message: "Failed to queue the event"})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{
+		Success: true,
+		Message: "Event queued",
+		Data:    map[string]string{"id": id, "event": event.Event},
+	})
+}
@@ -0,0 +1,310 @@
+package string_op
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"html"
+	"io"
+	"mime/quotedprintable"
+	"net/url"
+	"strings"
+)
+
+// URLEncode escapes s for use as a query parameter ("a b&c" -> "a+b%26c")
+func URLEncode(s string) string {
+	return url.QueryEscape(s)
+}
+
+// URLDecode reverses URLEncode
+func URLDecode(s string) (string, error) {
+	return url.QueryUnescape(s)
+}
+
+// PathEncode escapes s for use as a single path segment ("a b/c" -> "a%20b%2Fc")
+func PathEncode(s string) string {
+	return url.PathEscape(s)
+}
+
+// PathDecode reverses PathEncode
+func PathDecode(s string) (string, error) {
+	return url.PathUnescape(s)
+}
+
+// HTMLEncode escapes <, >, &, ' and "
+func HTMLEncode(s string) string {
+	return html.EscapeString(s)
+}
+
+// HTMLDecode unescapes entities such as "&lt;", "&#39;" and "&eacute;"
+func HTMLDecode(s string) string {
+	return html.UnescapeString(s)
+}
+
+// HexEncode returns the lowercase hex encoding of s
+func HexEncode(s string) string {
+	return hex.EncodeToString([]byte(s))
+}
+
+// HexDecode reverses HexEncode
+func HexDecode(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	return string(b), err
+}
+
+// Base32Encode returns the padded RFC 4648 base32 encoding of s
+func Base32Encode(s string) string {
+	return base32.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Base32Decode reverses Base32Encode
+func Base32Decode(s string) (string, error) {
+	b, err := base32.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+// Base64Encode returns the standard padded base64 encoding of s
+func Base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Base64Decode reverses Base64Encode
+func Base64Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+// QuotedPrintableEncode encodes s as used in MIME email bodies: ASCII stays
+// readable, other bytes become =XX and lines are wrapped at 76 characters
+func QuotedPrintableEncode(s string) string {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	io.WriteString(w, s)
+	w.Close()
+	return buf.String()
+}
+
+// QuotedPrintableDecode reverses QuotedPrintableEncode
+func QuotedPrintableDecode(s string) (string, error) {
+	b, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(s)))
+	return string(b), err
+}
+
+// Codec is one reversible stage of an encoding Pipeline
+type Codec interface {
+	Name() string
+	// Encoder returns a writer that encodes into w; Close flushes it
+	// without closing w
+	Encoder(w io.Writer) io.WriteCloser
+	// Decoder returns a reader that decodes from r
+	Decoder(r io.Reader) (io.Reader, error)
+}
+
+type base64Codec struct{ enc *base64.Encoding }
+
+func (c base64Codec) Name() string { return "base64" }
+func (c base64Codec) Encoder(w io.Writer) io.WriteCloser {
+	return base64.NewEncoder(c.enc, w)
+}
+func (c base64Codec) Decoder(r io.Reader) (io.Reader, error) {
+	return base64.NewDecoder(c.enc, r), nil
+}
+
+type base32Codec struct{}
+
+func (base32Codec) Name() string { return "base32" }
+func (base32Codec) Encoder(w io.Writer) io.WriteCloser {
+	return base32.NewEncoder(base32.StdEncoding, w)
+}
+func (base32Codec) Decoder(r io.Reader) (io.Reader, error) {
+	return base32.NewDecoder(base32.StdEncoding, r), nil
+}
+
+type hexCodec struct{}
+
+func (hexCodec) Name() string { return "hex" }
+func (hexCodec) Encoder(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{hex.NewEncoder(w)}
+}
+func (hexCodec) Decoder(r io.Reader) (io.Reader, error) {
+	return hex.NewDecoder(r), nil
+}
+
+type gzipCodec struct{ level int }
+
+func (gzipCodec) Name() string { return "gzip" }
+func (c gzipCodec) Encoder(w io.Writer) io.WriteCloser {
+	zw, err := gzip.NewWriterLevel(w, c.level)
+	if err != nil {
+		zw = gzip.NewWriter(w)
+	}
+	return zw
+}
+func (gzipCodec) Decoder(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+type quotedPrintableCodec struct{}
+
+func (quotedPrintableCodec) Name() string { return "quoted-printable" }
+func (quotedPrintableCodec) Encoder(w io.Writer) io.WriteCloser {
+	return quotedprintable.NewWriter(w)
+}
+func (quotedPrintableCodec) Decoder(r io.Reader) (io.Reader, error) {
+	return quotedprintable.NewReader(r), nil
+}
+
+// stringCodec adapts whole-string transforms such as URL escaping; it
+// buffers the input because the transforms are not streaming
+type stringCodec struct {
+	name   string
+	encode func(string) string
+	decode func(string) (string, error)
+}
+
+func (c stringCodec) Name() string { return c.name }
+func (c stringCodec) Encoder(w io.Writer) io.WriteCloser {
+	return &bufferedEncoder{w: w, encode: c.encode}
+}
+func (c stringCodec) Decoder(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := c.decode(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decoded), nil
+}
+
+type bufferedEncoder struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	encode func(string) string
+}
+
+func (b *bufferedEncoder) Write(p []byte) (int, error) { return b.buf.Write(p) }
+func (b *bufferedEncoder) Close() error {
+	_, err := io.WriteString(b.w, b.encode(b.buf.String()))
+	return err
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Codecs for use in a Pipeline
+var (
+	Base64Codec          Codec = base64Codec{enc: base64.StdEncoding}
+	Base64URLCodec       Codec = base64Codec{enc: base64.RawURLEncoding}
+	Base32Codec          Codec = base32Codec{}
+	HexCodec             Codec = hexCodec{}
+	GzipCodec            Codec = gzipCodec{level: gzip.DefaultCompression}
+	QuotedPrintableCodec Codec = quotedPrintableCodec{}
+	URLCodec             Codec = stringCodec{name: "url", encode: URLEncode, decode: URLDecode}
+	HTMLCodec            Codec = stringCodec{name: "html", encode: HTMLEncode, decode: func(s string) (string, error) { return HTMLDecode(s), nil }}
+)
+
+// Pipeline applies codecs in order when encoding and in reverse when
+// decoding, e.g. NewPipeline(GzipCodec, Base64Codec) compresses then encodes
+type Pipeline struct {
+	stages []Codec
+}
+
+// NewPipeline creates a pipeline from the given stages
+func NewPipeline(stages ...Codec) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Then returns a new pipeline with c appended
+func (p *Pipeline) Then(c Codec) *Pipeline {
+	stages := append(append([]Codec(nil), p.stages...), c)
+	return &Pipeline{stages: stages}
+}
+
+func (p *Pipeline) String() string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+	return strings.Join(names, "→")
+}
+
+// NewEncoder returns a writer that streams data through every stage into w.
+// Close must be called to flush the stages; it does not close w.
+func (p *Pipeline) NewEncoder(w io.Writer) io.WriteCloser {
+	writers := make([]io.WriteCloser, len(p.stages))
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		writers[i] = p.stages[i].Encoder(w)
+		w = writers[i]
+	}
+	return &chainWriter{head: w, writers: writers}
+}
+
+// NewDecoder returns a reader that streams r back through the stages
+func (p *Pipeline) NewDecoder(r io.Reader) (io.Reader, error) {
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		var err error
+		if r, err = p.stages[i].Decoder(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Encode runs data through the pipeline in memory
+func (p *Pipeline) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := p.NewEncoder(&buf)
+	if _, err := enc.Write(data); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reverses Encode
+func (p *Pipeline) Decode(data []byte) ([]byte, error) {
+	r, err := p.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// EncodeString is Encode for strings
+func (p *Pipeline) EncodeString(s string) (string, error) {
+	b, err := p.Encode([]byte(s))
+	return string(b), err
+}
+
+// DecodeString is Decode for strings
+func (p *Pipeline) DecodeString(s string) (string, error) {
+	b, err := p.Decode([]byte(s))
+	return string(b), err
+}
+
+// chainWriter writes to the first stage and closes stages first to last, so
+// each flush reaches the next stage before that one is closed
+type chainWriter struct {
+	head    io.Writer
+	writers []io.WriteCloser
+}
+
+func (c *chainWriter) Write(p []byte) (int, error) {
+	return c.head.Write(p)
+}
+
+func (c *chainWriter) Close() error {
+	for _, w := range c.writers {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package string_op
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdUnordered   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdOrdered     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdRule        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdImage       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold        = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic      = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	mdStrike      = regexp.MustCompile(`~~([^~]+)~~`)
+	mdPlaceholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// RenderMarkdown converts a small subset of Markdown to HTML: ATX headings,
+// paragraphs, unordered and ordered lists, blockquotes, horizontal rules,
+// fenced code blocks, inline code, links, images, bold, italic and
+// strikethrough. The result is passed through UGCPolicy, so it is safe to
+// serve even when src comes from a user.
+func RenderMarkdown(src string) string {
+	return RenderMarkdownWithPolicy(src, UGCPolicy())
+}
+
+// RenderMarkdownWithPolicy is RenderMarkdown with a custom sanitization policy
+func RenderMarkdownWithPolicy(src string, policy *Policy) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var (
+		sb        strings.Builder
+		paragraph []string
+		listTag   string
+	)
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			sb.WriteString("<p>" + renderInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			sb.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			sb.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence, ok := fenceMarker(trimmed); ok {
+			flushParagraph()
+			closeList()
+			language := strings.TrimSpace(trimmed[len(fence):])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			sb.WriteString("<pre><code")
+			if language != "" {
+				sb.WriteString(` class="language-` + html.EscapeString(language) + `"`)
+			}
+			sb.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case mdRule.MatchString(line):
+			flushParagraph()
+			closeList()
+			sb.WriteString("<hr>\n")
+		case mdHeading.MatchString(line):
+			flushParagraph()
+			closeList()
+			m := mdHeading.FindStringSubmatch(line)
+			level := string('0' + byte(len(m[1])))
+			sb.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			closeList()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			sb.WriteString("<blockquote>\n" + RenderMarkdownWithPolicy(strings.Join(quoted, "\n"), policy) + "</blockquote>\n")
+		case mdUnordered.MatchString(line):
+			flushParagraph()
+			openList("ul")
+			sb.WriteString("<li>" + renderInline(mdUnordered.FindStringSubmatch(line)[1]) + "</li>\n")
+		case mdOrdered.MatchString(line):
+			flushParagraph()
+			openList("ol")
+			sb.WriteString("<li>" + renderInline(mdOrdered.FindStringSubmatch(line)[1]) + "</li>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushParagraph()
+	closeList()
+
+	return policy.Sanitize(sb.String())
+}
+
+func fenceMarker(line string) (string, bool) {
+	for _, fence := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, fence) {
+			return fence, true
+		}
+	}
+	return "", false
+}
+
+// renderInline escapes text and converts inline markup. Code spans are swapped
+// out for placeholders first so their contents are not formatted.
+func renderInline(text string) string {
+	var spans []string
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '`')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '`')
+		if end < 0 {
+			break
+		}
+		sb.WriteString(text[:start])
+		sb.WriteString("\x00" + strconv.Itoa(len(spans)) + "\x00")
+		spans = append(spans, "<code>"+html.EscapeString(text[start+1:start+1+end])+"</code>")
+		text = text[start+end+2:]
+	}
+	sb.WriteString(text)
+
+	out := html.EscapeString(sb.String())
+	out = mdImage.ReplaceAllString(out, `<img src="$2" alt="$1">`)
+	out = mdLink.ReplaceAllString(out, `<a href="$2">$1</a>`)
+	out = mdBold.ReplaceAllString(out, "<strong>$1$2</strong>")
+	out = mdItalic.ReplaceAllString(out, "<em>$1$2</em>")
+	out = mdStrike.ReplaceAllString(out, "<del>$1</del>")
+
+	return mdPlaceholder.ReplaceAllStringFunc(out, func(m string) string {
+		index, err := strconv.Atoi(strings.Trim(m, "\x00"))
+		if err == nil && index < len(spans) {
+			return spans[index]
+		}
+		return ""
+	})
+}
+
+// SanitizeOperations demonstrates the HTML sanitizer and Markdown renderer
+func SanitizeOperations() {
+	fmt.Println("\n=== HTML Sanitization and Markdown ===")
+
+	inputs := []string{
+		`<p onclick="steal()">Hello <b>world</b></p><script>alert('xss')</script>`,
+		`<a href="javascript:alert(1)">bad</a> <a href="JaVa&#x09;Script:alert(1)">sneaky</a>`,
+		`<a href="https://go.dev" target="_blank">good</a> <img src="data:image/svg+xml,x" alt="x">`,
+		`<div><em>unclosed <strong>tags`,
+	}
+	ugc := UGCPolicy()
+	for _, input := range inputs {
+		fmt.Printf("Input:  %s\n", input)
+		fmt.Printf("UGC:    %s\n", ugc.Sanitize(input))
+		fmt.Printf("Strict: %s\n", StrictPolicy().Sanitize(input))
+	}
+
+	custom := NewPolicy().AllowTags("a").AllowAttrs("a", "href").AllowProtocols("https")
+	fmt.Printf("https-only policy: %s\n", custom.Sanitize(`<a href="http://example.com">http</a> <a href="https://example.com">https</a>`))
+
+	markdown := "# Release notes\n\nSupports **bold**, *italic* and `inline <code>`.\n\n" +
+		"1. First\n2. [Second](https://example.com)\n\n> Quoted text\n\n```go\nfmt.Println(\"<hi>\")\n```\n\n" +
+		"[click](javascript:alert(1)) <iframe src=\"https://evil\"></iframe>"
+	fmt.Println("Markdown:")
+	fmt.Println(RenderMarkdown(markdown))
+}
@@ -3,11 +3,14 @@ package string_op
 import (
 	"encoding/base64"
 	"fmt"
+	"io"
 	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/text/language"
 )
 
 func BasicOperations() {
@@ -23,7 +26,7 @@ func BasicOperations() {
 
 	upper := strings.ToUpper(text)
 	lower := strings.ToLower(text)
-	title := strings.Title(text)
+	title := TitleCase(text, language.English)
 	fmt.Printf("Uppercase: '%s'\n", upper)
 	fmt.Printf("Lowercase: '%s'\n", lower)
 	fmt.Printf("Title case: '%s'\n", title)
@@ -148,12 +151,33 @@ func EncodingOperations() {
 		fmt.Printf("Base64 decoded: '%s'\n", string(base64Decoded))
 	}
 
-	urlEncoded := strings.ReplaceAll(text, " ", "%20")
-	fmt.Printf("URL encoded: %s\n", urlEncoded)
+	fmt.Printf("URL encoded: %s\n", URLEncode(text))
+	fmt.Printf("Path encoded: %s\n", PathEncode("reports/2024 Q1.pdf"))
+	fmt.Printf("HTML escaped: %s\n", HTMLEncode(`<a href="x">Tom & Jerry's</a>`))
+	fmt.Printf("HTML unescaped: %s\n", HTMLDecode("caf&eacute; &lt;b&gt; &#128640;"))
+	fmt.Printf("Hex: %s\n", HexEncode(text))
+	fmt.Printf("Base32: %s\n", Base32Encode(text))
+	fmt.Printf("Quoted-printable: %s\n", QuotedPrintableEncode("Grüße, naïve café = ok"))
+	if decoded, err := URLDecode(URLEncode(text)); err == nil {
+		fmt.Printf("URL round-trip: '%s'\n", decoded)
+	}
 
-	htmlEscaped := strings.ReplaceAll(text, "<", "&lt;")
-	htmlEscaped = strings.ReplaceAll(htmlEscaped, ">", "&gt;")
-	fmt.Printf("HTML escaped: %s\n", htmlEscaped)
+	payload := strings.Repeat(`{"event":"page_view","path":"/products"}`, 20)
+	pipeline := NewPipeline(GzipCodec).Then(Base64Codec)
+	encoded, err := pipeline.EncodeString(payload)
+	if err != nil {
+		fmt.Println("Pipeline error:", err)
+		return
+	}
+	decoded, err := pipeline.DecodeString(encoded)
+	fmt.Printf("Pipeline %s: %d bytes -> %d bytes, round-trip ok: %t\n", pipeline, len(payload), len(encoded), err == nil && decoded == payload)
+
+	// Streaming: hex-encode a reader without holding the whole input in memory
+	var out strings.Builder
+	stream := NewPipeline(HexCodec).NewEncoder(&out)
+	io.Copy(stream, strings.NewReader("streamed"))
+	stream.Close()
+	fmt.Printf("Streamed hex: %s\n", out.String())
 }
 
 func AdvancedOperations() {
@@ -286,7 +310,7 @@ func findLongestWord(text string) string {
 	longest := ""
 	for _, word := range words {
 		word = strings.Trim(word, "!.,?")
-		if len(word) > len(longest) {
+		if GraphemeCount(word) > GraphemeCount(longest) {
 			longest = word
 		}
 	}
@@ -294,6 +318,12 @@ func findLongestWord(text string) string {
 }
 
 func reverseString(s string) string {
+	return ReverseGraphemes(s)
+}
+
+// reverseRunes reverses code points, which detaches combining marks and
+// breaks flags and emoji sequences
+func reverseRunes(s string) string {
 	runes := []rune(s)
 	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
 		runes[i], runes[j] = runes[j], runes[i]
@@ -302,23 +332,22 @@ func reverseString(s string) string {
 }
 
 func isPalindrome(s string) bool {
-	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	s = FoldCase(strings.ReplaceAll(s, " ", ""))
 	return s == reverseString(s)
 }
 
 func areAnagrams(s1, s2 string) bool {
-	if len(s1) != len(s2) {
-		return false
-	}
+	count1 := make(map[string]int)
+	count2 := make(map[string]int)
 
-	count1 := make(map[rune]int)
-	count2 := make(map[rune]int)
-
-	for _, r := range strings.ToLower(s1) {
-		count1[r]++
+	for _, g := range Graphemes(FoldCase(NFC(s1))) {
+		count1[g]++
+	}
+	for _, g := range Graphemes(FoldCase(NFC(s2))) {
+		count2[g]++
 	}
-	for _, r := range strings.ToLower(s2) {
-		count2[r]++
+	if len(count1) != len(count2) {
+		return false
 	}
 
 	for r, count := range count1 {
@@ -337,26 +366,23 @@ func normalizeString(s string) string {
 }
 
 func createSlug(s string) string {
-	s = strings.ToLower(s)
-	s = strings.ReplaceAll(s, " ", "-")
-	s = strings.ReplaceAll(s, ",", "")
-	s = strings.ReplaceAll(s, "!", "")
-	s = strings.ReplaceAll(s, ".", "")
-	return s
+	s = strings.ToLower(StripAccents(NFKC(s)))
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
 }
 
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
+	return TruncateGraphemes(s, maxLen, "...")
 }
 
 func padString(s string, length int, pad string) string {
-	if len(s) >= length {
+	width := GraphemeCount(s)
+	if width >= length {
 		return s
 	}
-	padding := strings.Repeat(pad, length-len(s))
+	padding := strings.Repeat(pad, length-width)
 	return s + padding
 }
 
@@ -381,6 +407,9 @@ func RunAllStringExamples() {
 	AdvancedOperations()
 	RegularExpressionOperations()
 	UtilityOperations()
+	UnicodeOperations()
+	RandomOperations()
+	SanitizeOperations()
 
 	fmt.Println("\n✅ All string operations completed!")
 }
@@ -0,0 +1,265 @@
+package string_op
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"time"
+)
+
+// Alphabets for RandomString
+const (
+	AlphabetAlphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	AlphabetLowerHex     = "0123456789abcdef"
+	// AlphabetURLSafe is the nanoid alphabet: 64 symbols, 6 bits per character
+	AlphabetURLSafe = "useandom-26T198340PX75pxJACKVERYMINDBUSHWOLF_GQZbfghjklqvwyzrict"
+	// AlphabetCrockford is Crockford's base32, used by ULIDs; it drops I, L, O
+	// and U to avoid confusion with 1, 0 and accidental words
+	AlphabetCrockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// All generators read from crypto/rand, which since Go 1.24 never fails; a
+// broken system random source crashes the program instead of returning weak
+// values, so the functions below have no error results.
+
+// RandomBytes returns n cryptographically random bytes
+func RandomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+// RandomString returns n characters drawn uniformly from alphabet. Bytes are
+// masked to the next power of two and out-of-range values are rejected, so
+// there is no modulo bias toward the start of the alphabet.
+func RandomString(alphabet string, n int) string {
+	symbols := []rune(alphabet)
+	if len(symbols) == 0 {
+		panic("string_op: RandomString with empty alphabet")
+	}
+	if len(symbols) > 256 {
+		return randomStringLarge(symbols, n)
+	}
+
+	mask := byte(1<<bits.Len(uint(len(symbols)-1)) - 1)
+	result := make([]rune, 0, n)
+	// Read a little more than needed so most calls take a single batch
+	buf := make([]byte, n+n/2+8)
+	for len(result) < n {
+		rand.Read(buf)
+		for _, b := range buf {
+			if index := int(b & mask); index < len(symbols) {
+				result = append(result, symbols[index])
+				if len(result) == n {
+					break
+				}
+			}
+		}
+	}
+	return string(result)
+}
+
+func randomStringLarge(symbols []rune, n int) string {
+	result := make([]rune, n)
+	limit := big.NewInt(int64(len(symbols)))
+	for i := range result {
+		index, _ := rand.Int(rand.Reader, limit)
+		result[i] = symbols[index.Int64()]
+	}
+	return string(result)
+}
+
+// Token returns a URL-safe base64 token carrying nBytes of randomness, e.g.
+// for OAuth state, CSRF tokens or password reset links. 32 bytes (256 bits)
+// is a good default.
+func Token(nBytes int) string {
+	return base64.RawURLEncoding.EncodeToString(RandomBytes(nBytes))
+}
+
+// HexToken returns a lowercase hex token carrying nBytes of randomness
+func HexToken(nBytes int) string {
+	return hex.EncodeToString(RandomBytes(nBytes))
+}
+
+// NanoID returns a nanoid-style ID of size characters from AlphabetURLSafe.
+// The default size of 21 carries 126 bits, about as many as a UUIDv4 (122).
+func NanoID(size int) string {
+	if size <= 0 {
+		size = 21
+	}
+	return RandomString(AlphabetURLSafe, size)
+}
+
+// UUID is a 128-bit RFC 9562 identifier
+type UUID [16]byte
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Version returns the UUID version number
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time returns the embedded timestamp of a version 7 UUID
+func (u UUID) Time() (time.Time, bool) {
+	if u.Version() != 7 {
+		return time.Time{}, false
+	}
+	ms := binary.BigEndian.Uint64(append([]byte{0, 0}, u[:6]...))
+	return time.UnixMilli(int64(ms)), true
+}
+
+// ParseUUID parses the canonical 36-character form
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	compact := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(compact)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+func setVersion(u *UUID, version byte) {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+}
+
+// NewUUIDv4 returns a random UUID with 122 random bits
+func NewUUIDv4() UUID {
+	var u UUID
+	rand.Read(u[:])
+	setVersion(&u, 4)
+	return u
+}
+
+// NewUUIDv7 returns a time-ordered UUID: a 48-bit Unix millisecond timestamp
+// followed by 74 random bits, so IDs sort by creation time (to the
+// millisecond) and index well as database keys
+func NewUUIDv7() UUID {
+	return newUUIDv7(time.Now())
+}
+
+func newUUIDv7(t time.Time) UUID {
+	var u UUID
+	rand.Read(u[6:])
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(u[:6], ms[2:])
+	setVersion(&u, 7)
+	return u
+}
+
+// ULID returns a 26-character Crockford base32 identifier: 48 bits of Unix
+// milliseconds followed by 80 random bits. ULIDs sort lexically by time and
+// avoid the hyphens and ambiguous letters of UUIDs.
+func ULID() string {
+	return ulidAt(time.Now())
+}
+
+func ulidAt(t time.Time) string {
+	var id [16]byte
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(id[:6], ms[2:])
+	rand.Read(id[6:])
+
+	// 128 bits fill 26 characters of 5 bits with the top two bits zero
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = AlphabetCrockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ULIDTime extracts the timestamp from a ULID
+func ULIDTime(id string) (time.Time, error) {
+	if len(id) != 26 {
+		return time.Time{}, fmt.Errorf("invalid ULID %q", id)
+	}
+	var ms uint64
+	// The first 10 characters hold the 48-bit timestamp (50 bits, top 2 zero)
+	for _, c := range id[:10] {
+		index := -1
+		for i := 0; i < len(AlphabetCrockford); i++ {
+			if AlphabetCrockford[i] == byte(c) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return time.Time{}, fmt.Errorf("invalid ULID character %q", c)
+		}
+		ms = ms<<5 | uint64(index)
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+// CollisionProbability estimates the chance that at least two of count IDs
+// collide when each is length symbols from an alphabet of alphabetSize, using
+// the birthday bound p ≈ 1 - e^(-n²/2N). For example:
+//
+//	NanoID(21)  (126 bits): 1e9 IDs -> ~6e-21
+//	UUIDv4      (122 bits): 1e9 IDs -> ~9e-20
+//	Token(16)   (128 bits): 1e9 IDs -> ~1.5e-21
+//	NanoID(8)   ( 48 bits): 1e6 IDs -> ~0.0018
+func CollisionProbability(alphabetSize, length int, count float64) float64 {
+	bitsPerID := float64(length) * math.Log2(float64(alphabetSize))
+	exponent := 2*math.Log2(count) - 1 - bitsPerID
+	return -math.Expm1(-math.Exp2(exponent))
+}
+
+// RandomOperations demonstrates the secure random generators
+func RandomOperations() {
+	fmt.Println("\n=== Secure Random Strings and IDs ===")
+
+	fmt.Printf("RandomString(alphanumeric, 16): %s\n", RandomString(AlphabetAlphanumeric, 16))
+	fmt.Printf("RandomString(\"ACGT\", 12):       %s\n", RandomString("ACGT", 12))
+	fmt.Printf("Token(32):    %s\n", Token(32))
+	fmt.Printf("HexToken(16): %s\n", HexToken(16))
+	fmt.Printf("NanoID(21):   %s\n", NanoID(21))
+
+	v4 := NewUUIDv4()
+	fmt.Printf("UUIDv4: %s (version %d)\n", v4, v4.Version())
+	first, second := NewUUIDv7(), NewUUIDv7()
+	created, _ := first.Time()
+	fmt.Printf("UUIDv7: %s, %s (created %s)\n", first, second, created.Format(time.RFC3339Nano))
+	if parsed, err := ParseUUID(first.String()); err == nil {
+		fmt.Printf("Parsed UUIDv7 round-trips: %t\n", parsed == first)
+	}
+
+	earlier := ulidAt(time.Now().Add(-time.Hour))
+	now := ULID()
+	fmt.Printf("ULID: %s (an hour ago: %s, sorts first: %t)\n", now, earlier, earlier < now)
+	if ts, err := ULIDTime(now); err == nil {
+		fmt.Printf("ULID timestamp: %s\n", ts.Format(time.RFC3339Nano))
+	}
+
+	fmt.Println("Collision probability for 1 billion IDs:")
+	fmt.Printf("  NanoID(21): %.2e\n", CollisionProbability(64, 21, 1e9))
+	fmt.Printf("  NanoID(10): %.2e\n", CollisionProbability(64, 10, 1e9))
+	fmt.Printf("  Hex(8):     %.2e\n", CollisionProbability(16, 8, 1e9))
+}
@@ -0,0 +1,310 @@
+package string_op
+
+import (
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Policy is an allow-list HTML sanitizer: tags, attributes and URL protocols
+// that are not explicitly allowed are removed. Text is always re-escaped, so
+// the output is safe to embed in a page even if the input was malformed.
+type Policy struct {
+	tags        map[string]bool
+	attrs       map[string]map[string]bool // tag -> attributes; "*" applies to all tags
+	protocols   map[string]bool
+	dropContent map[string]bool
+	noFollow    bool
+}
+
+// NewPolicy creates a policy that allows nothing; text content is kept
+func NewPolicy() *Policy {
+	return &Policy{
+		tags:      make(map[string]bool),
+		attrs:     make(map[string]map[string]bool),
+		protocols: map[string]bool{"http": true, "https": true, "mailto": true},
+		// Content of these elements is code or markup, never user-visible text
+		dropContent: map[string]bool{"script": true, "style": true, "iframe": true, "object": true, "noscript": true, "template": true, "textarea": true},
+	}
+}
+
+// StrictPolicy strips every tag and keeps only text
+func StrictPolicy() *Policy {
+	return NewPolicy()
+}
+
+// UGCPolicy allows the formatting commonly accepted in user-generated content
+// such as comments and markdown output
+func UGCPolicy() *Policy {
+	return NewPolicy().
+		AllowTags("p", "br", "hr", "b", "strong", "i", "em", "del", "code", "pre", "blockquote",
+			"ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6", "a", "img", "table", "thead",
+			"tbody", "tr", "th", "td").
+		AllowAttrs("a", "href", "title").
+		AllowAttrs("img", "src", "alt", "title").
+		AllowAttrs("code", "class").
+		RequireNoFollowLinks()
+}
+
+// AllowTags permits the given elements
+func (p *Policy) AllowTags(tags ...string) *Policy {
+	for _, tag := range tags {
+		p.tags[strings.ToLower(tag)] = true
+	}
+	return p
+}
+
+// AllowAttrs permits attributes on tag, or on every allowed tag when tag is "*"
+func (p *Policy) AllowAttrs(tag string, attrs ...string) *Policy {
+	tag = strings.ToLower(tag)
+	if p.attrs[tag] == nil {
+		p.attrs[tag] = make(map[string]bool)
+	}
+	for _, attr := range attrs {
+		p.attrs[tag][strings.ToLower(attr)] = true
+	}
+	return p
+}
+
+// AllowProtocols replaces the URL schemes permitted in href and src;
+// relative URLs are always allowed
+func (p *Policy) AllowProtocols(protocols ...string) *Policy {
+	p.protocols = make(map[string]bool)
+	for _, protocol := range protocols {
+		p.protocols[strings.ToLower(protocol)] = true
+	}
+	return p
+}
+
+// RequireNoFollowLinks adds rel="nofollow noopener" to every link
+func (p *Policy) RequireNoFollowLinks() *Policy {
+	p.noFollow = true
+	return p
+}
+
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true, "input": true, "meta": true, "link": true, "wbr": true}
+
+func (p *Policy) attrAllowed(tag, attr string) bool {
+	return p.attrs[tag][attr] || p.attrs["*"][attr]
+}
+
+// SafeURL reports whether value is a relative URL or uses an allowed protocol.
+// Whitespace and control characters are removed first, since browsers ignore
+// them and "java\tscript:" would otherwise slip through.
+func (p *Policy) SafeURL(value string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(value))
+
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		// "//evil.com" is protocol-relative, not relative
+		return !strings.HasPrefix(cleaned, "//") || p.protocols["https"]
+	}
+	return p.protocols[strings.ToLower(u.Scheme)]
+}
+
+type htmlAttr struct {
+	name  string
+	value string
+}
+
+// Sanitize returns s with everything outside the policy removed
+func (p *Policy) Sanitize(s string) string {
+	var (
+		sb   strings.Builder
+		open []string // allowed elements currently open
+		skip string   // element whose content is being dropped
+	)
+	text := func(t string) {
+		if skip == "" {
+			sb.WriteString(html.EscapeString(html.UnescapeString(t)))
+		}
+	}
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			text(s)
+			break
+		}
+		text(s[:lt])
+		s = s[lt:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				s = ""
+			} else {
+				s = s[end+3:]
+			}
+			continue
+		case strings.HasPrefix(s, "<!"), strings.HasPrefix(s, "<?"):
+			s = skipPast(s, '>')
+			continue
+		}
+
+		name, attrs, closing, selfClosing, rest, ok := parseTag(s)
+		if !ok {
+			// A lone "<" is text
+			text("<")
+			s = s[1:]
+			continue
+		}
+		s = rest
+
+		if skip != "" {
+			if closing && name == skip {
+				skip = ""
+			}
+			continue
+		}
+		if p.dropContent[name] && !closing && !selfClosing && !p.tags[name] {
+			skip = name
+			continue
+		}
+		if !p.tags[name] {
+			continue
+		}
+
+		if closing {
+			// Close everything opened after the matching element
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					for j := len(open) - 1; j >= i; j-- {
+						sb.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		sb.WriteString("<" + name)
+		for _, attr := range attrs {
+			if !p.attrAllowed(name, attr.name) {
+				continue
+			}
+			if (attr.name == "href" || attr.name == "src") && !p.SafeURL(attr.value) {
+				continue
+			}
+			sb.WriteString(" " + attr.name + `="` + html.EscapeString(html.UnescapeString(attr.value)) + `"`)
+		}
+		if name == "a" && p.noFollow {
+			sb.WriteString(` rel="nofollow noopener"`)
+		}
+		sb.WriteString(">")
+		if !voidElements[name] && !selfClosing {
+			open = append(open, name)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString("</" + open[i] + ">")
+	}
+	return sb.String()
+}
+
+func skipPast(s string, c byte) string {
+	if i := strings.IndexByte(s, c); i >= 0 {
+		return s[i+1:]
+	}
+	return ""
+}
+
+// parseTag parses a start or end tag at the beginning of s
+func parseTag(s string) (name string, attrs []htmlAttr, closing, selfClosing bool, rest string, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || (i > start && s[i] >= '0' && s[i] <= '9')) {
+		i++
+	}
+	if i == start {
+		return "", nil, false, false, s, false
+	}
+	name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '>':
+			return name, attrs, closing, selfClosing, s[i+1:], true
+		case '/':
+			selfClosing = true
+			i++
+			continue
+		}
+
+		nameStart := i
+		for i < len(s) && !isTagSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := htmlAttr{name: strings.ToLower(s[nameStart:i])}
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isTagSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return name, attrs, closing, selfClosing, "", true
+				}
+				attr.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				valueStart := i
+				for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = s[valueStart:i]
+			}
+		}
+		if attr.name != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	// Unterminated tag: drop the remainder
+	return name, attrs, closing, selfClosing, "", true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// AllowedTags lists the permitted elements in sorted order
+func (p *Policy) AllowedTags() []string {
+	tags := make([]string, 0, len(p.tags))
+	for tag := range p.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,240 @@
+package string_op
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NFC returns s in canonical composed form ("e" + U+0301 becomes "é")
+func NFC(s string) string {
+	return norm.NFC.String(s)
+}
+
+// NFD returns s in canonical decomposed form ("é" becomes "e" + U+0301)
+func NFD(s string) string {
+	return norm.NFD.String(s)
+}
+
+// NFKC returns s in compatibility composed form, which also folds look-alike
+// characters such as "ﬁ" to "fi" and full-width digits to ASCII
+func NFKC(s string) string {
+	return norm.NFKC.String(s)
+}
+
+// StripAccents removes combining marks after decomposition, e.g. "Crème Brûlée"
+// becomes "Creme Brulee"
+func StripAccents(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// FoldCase returns a caseless, normalized form of s for comparisons; unlike
+// strings.ToLower it maps "ß" to "ss" and "ﬁ" to "fi"
+func FoldCase(s string) string {
+	return cases.Fold().String(NFKC(s))
+}
+
+// EqualFold reports whether a and b are equal under full Unicode case folding
+// and normalization, so "Straße" equals "STRASSE" and "é" equals "é"
+func EqualFold(a, b string) bool {
+	return FoldCase(a) == FoldCase(b)
+}
+
+// TitleCase upper-cases the first letter of each word using the rules of the
+// given language (e.g. language.Dutch turns "ijssel" into "IJssel"). It
+// replaces the deprecated strings.Title.
+func TitleCase(s string, tag language.Tag) string {
+	return cases.Title(tag).String(s)
+}
+
+// graphemeClass is a simplified Grapheme_Cluster_Break property (UAX #29)
+type graphemeClass int
+
+const (
+	gcOther graphemeClass = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcSpacingMark
+	gcRegional
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+	gcPictographic
+)
+
+func classifyRune(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r == 0x200D:
+		return gcZWJ
+	case r == 0x200C, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F,
+		unicode.In(r, unicode.Mn, unicode.Me):
+		return gcExtend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gcControl
+	case unicode.Is(unicode.Mc, r):
+		return gcSpacingMark
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gcRegional
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gcL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gcV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gcT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gcLV
+		}
+		return gcLVT
+	case isPictographic(r):
+		return gcPictographic
+	}
+	return gcOther
+}
+
+// isPictographic approximates Extended_Pictographic with the emoji blocks
+func isPictographic(r rune) bool {
+	switch {
+	case r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122, r == 0x2139,
+		r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	case r >= 0x2194 && r <= 0x21AA, r >= 0x2300 && r <= 0x23FF, r >= 0x25A0 && r <= 0x27BF,
+		r >= 0x2B00 && r <= 0x2BFF, r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	}
+	return false
+}
+
+// graphemeBoundary reports whether a cluster boundary falls between prev and
+// next; riRun is the number of regional indicators ending at prev and
+// afterPictZWJ is set when prev is a ZWJ that follows a pictograph
+func graphemeBoundary(prev, next graphemeClass, riRun int, afterPictZWJ bool) bool {
+	switch {
+	case prev == gcCR && next == gcLF:
+		return false
+	case prev == gcCR, prev == gcLF, prev == gcControl,
+		next == gcCR, next == gcLF, next == gcControl:
+		return true
+	case prev == gcL && (next == gcL || next == gcV || next == gcLV || next == gcLVT),
+		(prev == gcLV || prev == gcV) && (next == gcV || next == gcT),
+		(prev == gcLVT || prev == gcT) && next == gcT:
+		return false
+	case next == gcExtend, next == gcZWJ, next == gcSpacingMark:
+		return false
+	case afterPictZWJ && next == gcPictographic:
+		return false
+	case prev == gcRegional && next == gcRegional:
+		return riRun%2 == 0
+	}
+	return true
+}
+
+// Graphemes splits s into user-perceived characters: a base character with
+// its combining marks, a flag, or an emoji ZWJ sequence stays together
+func Graphemes(s string) []string {
+	var (
+		clusters     []string
+		start        int
+		prev         graphemeClass
+		riRun        int
+		inPict       bool // cluster so far is a pictograph followed by Extend*
+		afterPictZWJ bool
+	)
+	for i, r := range s {
+		class := classifyRune(r)
+		if i > 0 && graphemeBoundary(prev, class, riRun, afterPictZWJ) {
+			clusters = append(clusters, s[start:i])
+			start = i
+		}
+
+		if class == gcRegional {
+			riRun++
+		} else {
+			riRun = 0
+		}
+		switch {
+		case class == gcPictographic:
+			inPict, afterPictZWJ = true, false
+		case class == gcExtend && inPict:
+		case class == gcZWJ && inPict:
+			inPict, afterPictZWJ = false, true
+		default:
+			inPict, afterPictZWJ = false, false
+		}
+		prev = class
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// GraphemeCount returns the number of user-perceived characters in s
+func GraphemeCount(s string) int {
+	return len(Graphemes(s))
+}
+
+// TruncateGraphemes shortens s to at most n user-perceived characters,
+// appending ellipsis when text was cut; it never splits a character. A
+// negative n counts as 0, leaving only the ellipsis for non-empty s.
+func TruncateGraphemes(s string, n int, ellipsis string) string {
+	n = max(n, 0)
+	clusters := Graphemes(s)
+	if len(clusters) <= n {
+		return s
+	}
+	return strings.Join(clusters[:n], "") + ellipsis
+}
+
+// ReverseGraphemes reverses s by user-perceived characters, so accents stay on
+// their letters and flags are not turned into different flags
+func ReverseGraphemes(s string) string {
+	clusters := Graphemes(s)
+	for i, j := 0, len(clusters)-1; i < j; i, j = i+1, j-1 {
+		clusters[i], clusters[j] = clusters[j], clusters[i]
+	}
+	return strings.Join(clusters, "")
+}
+
+// UnicodeOperations demonstrates normalization, grapheme clusters and folding
+func UnicodeOperations() {
+	fmt.Println("\n=== Unicode Text Processing ===")
+
+	composed, decomposed := "café", "café"
+	fmt.Printf("'%s' == '%s': %t (bytes %d vs %d)\n", composed, decomposed, composed == decomposed, len(composed), len(decomposed))
+	fmt.Printf("After NFC: %t, NFD length: %d bytes\n", NFC(decomposed) == composed, len(NFD(composed)))
+	fmt.Printf("NFKC: '%s' -> '%s'\n", "ﬁle №１２", NFKC("ﬁle №１２"))
+
+	text := "🇯🇵 naïve 👨‍👩‍👧 éclair"
+	fmt.Printf("Text: '%s'\n", text)
+	fmt.Printf("Bytes: %d, runes: %d, graphemes: %d\n", len(text), len([]rune(text)), GraphemeCount(text))
+	fmt.Printf("Graphemes: %q\n", Graphemes(text))
+	fmt.Printf("Truncated to 8: '%s'\n", TruncateGraphemes(text, 8, "…"))
+	fmt.Printf("Reversed (runes):     '%s'\n", reverseRunes(text))
+	fmt.Printf("Reversed (graphemes): '%s'\n", ReverseGraphemes(text))
+
+	fmt.Printf("EqualFold(\"Straße\", \"STRASSE\"): %t (strings.EqualFold: %t)\n", EqualFold("Straße", "STRASSE"), strings.EqualFold("Straße", "STRASSE"))
+	fmt.Printf("Title case: '%s', Dutch: '%s'\n", TitleCase("hello wörld", language.English), TitleCase("ijssel meer", language.Dutch))
+	fmt.Printf("Strip accents: '%s'\n", StripAccents("Crème Brûlée à São Paulo"))
+	fmt.Printf("Slug: '%s'\n", createSlug("Crème Brûlée: Ünïcode Édition!"))
+}
@@ -0,0 +1,37 @@
+package testingutil
+
+import (
+	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+)
+
+// Clock is the time source code under test should depend on instead of
+// calling time.Now and time.After directly. It is concurrency.Clock, so
+// the same code runs on RealClock, a FakeClock or a VirtualClock.
+type Clock = concurrency.Clock
+
+// RealClock is backed by the time package
+var RealClock = concurrency.RealClock
+
+// FakeClock only moves when Advance or Set is called. Timers created by After
+// and Sleep fire once the clock reaches their deadline. It is a
+// concurrency.VirtualClock with the names tests have used.
+type FakeClock struct {
+	*concurrency.VirtualClock
+}
+
+// NewFakeClock creates a clock stopped at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{concurrency.NewVirtualClock(start)}
+}
+
+// Set moves the clock to t; moving it backwards does nothing
+func (c *FakeClock) Set(t time.Time) {
+	c.AdvanceTo(t)
+}
+
+// Waiters reports how many After or Sleep calls are still pending
+func (c *FakeClock) Waiters() int {
+	return c.Pending()
+}
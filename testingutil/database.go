@@ -0,0 +1,116 @@
+package testingutil
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jerrychou/go-practice/database"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// PostgresDSNEnv names the environment variable holding the DSN used by
+// NewPostgresDB; tests that need PostgreSQL are skipped when it is unset
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// NewSQLiteDB opens a fresh SQLite database in a temporary directory, applies
+// the default migrations plus any extra ones and closes it when the test ends
+func NewSQLiteDB(t TB, migrations ...database.Migration) *sql.DB {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// A single connection keeps writes serialized, as in DatabaseDrivers
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db, "sqlite3", migrations)
+	return db
+}
+
+// NewPostgresDB connects to the server named by TEST_POSTGRES_DSN, creates a
+// schema private to the test, migrates it and drops it when the test ends
+func NewPostgresDB(t TB, migrations ...database.Migration) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set; skipping PostgreSQL test", PostgresDSNEnv)
+		return nil
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := schemaName(t.Name())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	// Registered after the schema cleanup, so it runs first
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db, "postgres", migrations)
+	return db
+}
+
+func migrate(t TB, db *sql.DB, dialect string, migrations []database.Migration) {
+	t.Helper()
+
+	mm := database.NewMigrationManager(db).SetDialect(dialect)
+	for _, migration := range migrations {
+		mm.AddMigration(migration)
+	}
+	if err := mm.MigrateUp(); err != nil {
+		t.Fatalf("migrate %s database: %v", dialect, err)
+	}
+}
+
+// schemaName derives a valid, unique identifier from a test name such as
+// "TestUsers/create_admin"
+func schemaName(testName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, testName)
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return fmt.Sprintf("test_%s_%d", name, os.Getpid())
+}
+
+// withSearchPath points unqualified table names at schema; lib/pq passes
+// unknown DSN parameters through as run-time settings
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
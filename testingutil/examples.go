@@ -0,0 +1,409 @@
+package testingutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jerrychou/go-practice/database"
+	"github.com/jerrychou/go-practice/filestore"
+	httpclient "github.com/jerrychou/go-practice/http"
+	"github.com/jerrychou/go-practice/server"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// recorder is a TB for running the helpers outside `go test`: failures are
+// collected, and Fatalf/Skipf stop the example with a panic that run recovers
+type recorder struct {
+	name     string
+	tempDir  string
+	failures []string
+	skipped  string
+	cleanups []func()
+}
+
+type stopExample struct{}
+
+func (r *recorder) Helper()      {}
+func (r *recorder) Name() string { return r.name }
+func (r *recorder) Logf(format string, args ...any) {
+	fmt.Printf("    log: %s\n", fmt.Sprintf(format, args...))
+}
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	panic(stopExample{})
+}
+func (r *recorder) Skipf(format string, args ...any) {
+	r.skipped = fmt.Sprintf(format, args...)
+	panic(stopExample{})
+}
+func (r *recorder) Cleanup(fn func()) { r.cleanups = append(r.cleanups, fn) }
+func (r *recorder) TempDir() string {
+	if r.tempDir == "" {
+		dir, err := os.MkdirTemp("", "testingutil-")
+		if err != nil {
+			r.Fatalf("TempDir: %v", err)
+		}
+		r.tempDir = dir
+		r.Cleanup(func() { os.RemoveAll(dir) })
+	}
+	return r.tempDir
+}
+
+// run executes fn like a test and prints its outcome
+func run(name string, fn func(t TB)) {
+	r := &recorder{name: name}
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				if _, ok := p.(stopExample); !ok {
+					panic(p)
+				}
+			}
+		}()
+		fn(r)
+	}()
+	// Cleanups run last-registered first, as in the testing package
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+
+	switch {
+	case r.skipped != "":
+		fmt.Printf("⏭️  %s: skipped (%s)\n", name, r.skipped)
+	case len(r.failures) > 0:
+		fmt.Printf("❌ %s: %d failure(s)\n", name, len(r.failures))
+		for _, failure := range r.failures {
+			fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimRight(failure, "\n"), "\n", "\n    "))
+		}
+	default:
+		fmt.Printf("✅ %s\n", name)
+	}
+}
+
+// DatabaseExamples demonstrates the migrated database harness
+func DatabaseExamples() {
+	fmt.Println("\n=== Database Harness ===")
+
+	run("SQLite users table", func(t TB) {
+		db := NewSQLiteDB(t, database.Migration{
+			Version: 100,
+			Name:    "create_tags_table",
+			UpSQL:   `CREATE TABLE tags (id SERIAL PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+			DownSQL: `DROP TABLE IF EXISTS tags`,
+		})
+
+		result, err := db.Exec(`INSERT INTO users (name, email, age) VALUES (?, ?, ?)`, "Alice", "alice@example.com", 30)
+		if err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		id, _ := result.LastInsertId()
+
+		var name string
+		if err := db.QueryRow(`SELECT name FROM users WHERE id = ?`, id).Scan(&name); err != nil {
+			t.Fatalf("select user: %v", err)
+		}
+		if name != "Alice" {
+			t.Errorf("name = %q, want Alice", name)
+		}
+		if _, err := db.Exec(`INSERT INTO tags (name) VALUES ('go')`); err != nil {
+			t.Errorf("extra migration not applied: %v", err)
+		}
+		t.Logf("user %d stored in %s", id, t.TempDir())
+	})
+
+	run("repository writes roll back", func(t TB) {
+		db := NewSQLiteDB(t)
+		countUsers := func(q database.DBTX) int {
+			var count int
+			if err := q.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+				t.Fatalf("count users: %v", err)
+			}
+			return count
+		}
+
+		WithTestTransaction(t, db, func(tx *sql.Tx) {
+			users := database.NewSQLBasics(tx)
+			if _, err := users.InsertUser("Alice", "alice@example.com", 30); err != nil {
+				t.Fatalf("insert user: %v", err)
+			}
+
+			// A savepoint undoes the inner writes and keeps Alice
+			WithTestTransaction(t, tx, func(tx *sql.Tx) {
+				if _, err := database.NewSQLBasics(tx).InsertUser("Bob", "bob@example.com", 25); err != nil {
+					t.Fatalf("insert user: %v", err)
+				}
+				if n := countUsers(tx); n != 2 {
+					t.Errorf("inside savepoint: %d users, want 2", n)
+				}
+			})
+			if n := countUsers(tx); n != 1 {
+				t.Errorf("after savepoint: %d users, want 1", n)
+			}
+		})
+		if n := countUsers(db); n != 0 {
+			t.Errorf("after rollback: %d users, want 0", n)
+		}
+
+		gdb, err := gorm.Open(&sqlite.Dialector{Conn: db}, &gorm.Config{Logger: gormlogger.Discard})
+		if err != nil {
+			t.Fatalf("open gorm: %v", err)
+		}
+		WithTestGORMTransaction(t, gdb, func(tx *gorm.DB) {
+			// SQLite DDL is transactional, so the table goes away too
+			if err := tx.AutoMigrate(&database.GORMUser{}); err != nil {
+				t.Fatalf("migrate gorm users: %v", err)
+			}
+			user, err := database.NewORMBasics(tx).CreateUser("Carol", "carol@example.com", 41)
+			if err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+			t.Logf("created user %d through GORM", user.ID)
+		})
+		if gdb.Migrator().HasTable(&database.GORMUser{}) {
+			t.Errorf("gorm_users survived the rollback")
+		}
+	})
+
+	run("PostgreSQL users table", func(t TB) {
+		db := NewPostgresDB(t)
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+			t.Fatalf("count users: %v", err)
+		}
+	})
+}
+
+// ServerExamples demonstrates the test server against the demo routes
+func ServerExamples() {
+	fmt.Println("\n=== Test Server ===")
+
+	run("GET /health", func(t TB) {
+		srv := NewTestServer(t, nil)
+		var body struct {
+			Success bool           `json:"success"`
+			Data    map[string]any `json:"data"`
+		}
+		srv.Get("/health").
+			ExpectStatus(http.StatusOK).
+			ExpectHeader("Content-Type", "application/json").
+			DecodeJSON(&body)
+		if !body.Success || body.Data["status"] != "ok" {
+			t.Errorf("unexpected health response: %+v", body)
+		}
+	})
+
+	run("POST /preview sanitizes markdown", func(t TB) {
+		srv := NewTestServer(t, nil)
+		srv.PostForm("/preview", url.Values{"text": {"**hi** <script>alert(1)</script>"}}).
+			ExpectStatus(http.StatusOK).
+			ExpectContains("<strong>hi</strong>")
+	})
+
+	run("resumable upload survives a dropped chunk", func(t TB) {
+		dir := t.TempDir()
+		store, err := filestore.NewLocalStore(filepath.Join(dir, "files"), filestore.LocalOptions{})
+		if err != nil {
+			t.Fatalf("file store: %v", err)
+		}
+		files, uploadDir := server.Files, server.UploadDir
+		server.Files, server.UploadDir = store, filepath.Join(dir, "partial")
+		t.Cleanup(func() { server.Files, server.UploadDir = files, uploadDir })
+		srv := NewTestServer(t, nil)
+
+		// Drop the second chunk once, as a flaky connection would
+		client := httpclient.NewHTTPClient(srv.URL)
+		dropped := false
+		client.Use(func(next http.RoundTripper) http.RoundTripper {
+			return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPatch && req.Header.Get("Upload-Offset") == "65536" && !dropped {
+					dropped = true
+					return nil, errors.New("connection reset")
+				}
+				return next.RoundTrip(req)
+			})
+		})
+
+		data := bytes.Repeat([]byte("resumable "), 20000)
+		var progress []string
+		file, err := client.UploadResumable(context.Background(), "/api/upload/sessions", "notes.txt", bytes.NewReader(data), int64(len(data)), httpclient.UploadOptions{
+			ContentType: "text/plain",
+			ChunkSize:   64 << 10,
+			Progress:    func(sent, total int64) { progress = append(progress, fmt.Sprintf("%d%%", sent*100/total)) },
+		})
+		if err != nil {
+			t.Fatalf("resumable upload: %v", err)
+		}
+		t.Logf("progress %s, stored as %s", strings.Join(progress, " "), file.Key)
+		if file.Size != int64(len(data)) {
+			t.Errorf("stored %d bytes, want %d", file.Size, len(data))
+		}
+
+		// The same bytes through the multipart endpoint are recognized
+		again, err := client.Upload(context.Background(), "/api/upload", "copy.txt", bytes.NewReader(data), httpclient.UploadOptions{})
+		if err != nil || !again.Duplicate || again.Key != file.Key {
+			t.Errorf("multipart upload of the same bytes: %+v, %v", again, err)
+		}
+	})
+
+	run("request scope commits only successful requests", func(t TB) {
+		db := NewSQLiteDB(t)
+		original := server.DB
+		server.DB = db
+		t.Cleanup(func() { server.DB = original })
+
+		// Each request inserts a user, then answers with the status it was asked for
+		insert := server.ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			tx, err := server.ScopeFrom(r.Context()).Tx()
+			if err != nil {
+				return err
+			}
+			name := r.URL.Query().Get("name")
+			if _, err := tx.Exec(`INSERT INTO users (name, email, age) VALUES (?, ?, 30)`, name, name+"@example.com"); err != nil {
+				return err
+			}
+			if r.URL.Query().Get("fail") != "" {
+				return server.NewProblem(http.StatusBadRequest, "rejected", "rolled back")
+			}
+			w.WriteHeader(http.StatusCreated)
+			return nil
+		})
+		srv := NewTestServer(t, server.ScopeMiddleware(server.ProblemMiddleware(insert)))
+
+		srv.Get("/?name=kept").ExpectStatus(http.StatusCreated)
+		srv.Get("/?name=dropped&fail=1").
+			ExpectStatus(http.StatusBadRequest).
+			ExpectHeader("Content-Type", server.ProblemContentType)
+		srv.Get("/?name=kept").ExpectStatus(http.StatusConflict) // unique email
+
+		var names []string
+		rows, err := db.Query(`SELECT name FROM users ORDER BY id`)
+		if err != nil {
+			t.Fatalf("list users: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			rows.Scan(&name)
+			names = append(names, name)
+		}
+		if len(names) != 1 || names[0] != "kept" {
+			t.Errorf("stored users = %v, want [kept]", names)
+		}
+	})
+
+	run("GET /api/users/abc (expected to fail)", func(t TB) {
+		srv := NewTestServer(t, nil)
+		srv.Get("/api/users/abc").ExpectStatus(http.StatusOK)
+	})
+}
+
+// ClockExamples demonstrates driving time-dependent code with FakeClock
+func ClockExamples() {
+	fmt.Println("\n=== Fake Clock ===")
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	start := clock.Now()
+
+	done := make(chan time.Time)
+	go func() {
+		clock.Sleep(time.Hour)
+		done <- clock.Now()
+	}()
+
+	clock.BlockUntil(1)
+	fmt.Printf("Goroutine sleeping, pending timers: %d\n", clock.Waiters())
+	clock.Advance(30 * time.Minute)
+	fmt.Printf("After 30m: still sleeping (%d pending)\n", clock.Waiters())
+	clock.Advance(30 * time.Minute)
+	woke := <-done
+	fmt.Printf("After 1h: woke at %s, elapsed %s\n", woke.Format(time.Kitchen), clock.Since(start))
+
+	timeout := clock.After(5 * time.Second)
+	select {
+	case <-timeout:
+		fmt.Println("Timeout fired early")
+	default:
+		fmt.Println("Timeout pending until the clock is advanced")
+	}
+	clock.Advance(5 * time.Second)
+	fmt.Printf("Timeout fired at %s\n", (<-timeout).Format(time.TimeOnly))
+}
+
+// LeakExamples demonstrates the goroutine leak detector
+func LeakExamples() {
+	fmt.Println("\n=== Goroutine Leak Detection ===")
+
+	saved := LeakCheckTimeout
+	LeakCheckTimeout = 200 * time.Millisecond
+	defer func() { LeakCheckTimeout = saved }()
+
+	run("worker exits when stopped", func(t TB) {
+		VerifyNoLeaks(t)
+		stop := make(chan struct{})
+		go func() { <-stop }()
+		t.Cleanup(func() { close(stop) })
+	})
+
+	block := make(chan struct{})
+	run("worker blocked forever (expected to fail)", func(t TB) {
+		VerifyNoLeaks(t)
+		go func() { <-block }()
+	})
+	close(block)
+}
+
+// GoldenExamples demonstrates golden-file comparisons
+func GoldenExamples() {
+	fmt.Println("\n=== Golden Files ===")
+
+	dir, err := os.MkdirTemp("", "golden-")
+	if err != nil {
+		fmt.Printf("Error creating temp dir: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	report := map[string]any{"users": 3, "active": []string{"alice", "bob"}}
+
+	golden := &Golden{Dir: dir, Update: true}
+	run("record golden file", func(t TB) {
+		golden.AssertJSON(t, "report", report)
+	})
+
+	golden.Update = false
+	run("output matches golden file", func(t TB) {
+		golden.AssertJSON(t, "report", report)
+	})
+
+	report["users"] = 4
+	run("output changed (expected to fail)", func(t TB) {
+		golden.AssertJSON(t, "report", report)
+	})
+
+	run("missing golden file (expected to fail)", func(t TB) {
+		golden.Assert(t, "missing", []byte("data"))
+	})
+}
+
+// RunAllTestingExamples runs all testingutil examples
+func RunAllTestingExamples() {
+	DatabaseExamples()
+	ServerExamples()
+	ClockExamples()
+	LeakExamples()
+	GoldenExamples()
+}
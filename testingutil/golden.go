@@ -0,0 +1,122 @@
+package testingutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UpdateGoldenEnv names the environment variable that rewrites golden files
+// instead of comparing against them:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// Golden compares output against files stored under Dir
+type Golden struct {
+	Dir    string
+	Update bool
+}
+
+// NewGolden stores golden files in dir; Update follows UPDATE_GOLDEN
+func NewGolden(dir string) *Golden {
+	update := os.Getenv(UpdateGoldenEnv)
+	return &Golden{Dir: dir, Update: update != "" && update != "0" && update != "false"}
+}
+
+// Path returns the file holding the golden output for name
+func (g *Golden) Path(name string) string {
+	return filepath.Join(g.Dir, name+".golden")
+}
+
+// Assert reports an error when got differs from the golden file for name. In
+// update mode the file is (re)written instead.
+func (g *Golden) Assert(t TB, name string, got []byte) {
+	t.Helper()
+
+	path := g.Path(name)
+	if g.Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		t.Logf("updated golden file %s", path)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist; run with %s=1 to create it", path, UpdateGoldenEnv)
+		return
+	}
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run with %s=1 to accept):\n%s", path, UpdateGoldenEnv, lineDiff(string(want), string(got)))
+	}
+}
+
+// AssertJSON compares v, marshaled as indented JSON, with the golden file
+func (g *Golden) AssertJSON(t TB, name string, v any) {
+	t.Helper()
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	g.Assert(t, name, append(data, '\n'))
+}
+
+// AssertGolden compares got with testdata/<name>.golden
+func AssertGolden(t TB, name string, got []byte) {
+	t.Helper()
+	NewGolden("testdata").Assert(t, name, got)
+}
+
+// AssertGoldenJSON compares v as indented JSON with testdata/<name>.golden
+func AssertGoldenJSON(t TB, name string, v any) {
+	t.Helper()
+	NewGolden("testdata").AssertJSON(t, name, v)
+}
+
+// lineDiff lists the lines that differ, marking expected lines with "-" and
+// actual lines with "+"; it compares line by line rather than finding a
+// minimal edit script, which is enough to spot the change in test output
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	var sb strings.Builder
+	shown := 0
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		hasWant, hasGot := i < len(wantLines), i < len(gotLines)
+		if hasWant {
+			w = wantLines[i]
+		}
+		if hasGot {
+			g = gotLines[i]
+		}
+		if hasWant && hasGot && w == g {
+			continue
+		}
+		if shown == 20 {
+			sb.WriteString("...\n")
+			break
+		}
+		shown++
+		if hasWant {
+			fmt.Fprintf(&sb, "%4d - %s\n", i+1, w)
+		}
+		if hasGot {
+			fmt.Fprintf(&sb, "%4d + %s\n", i+1, g)
+		}
+	}
+	return sb.String()
+}
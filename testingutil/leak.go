@@ -0,0 +1,46 @@
+package testingutil
+
+import (
+	"time"
+
+	"github.com/jerrychou/go-practice/concurrency"
+)
+
+// LeakCheckTimeout is how long VerifyNoLeaks waits for goroutines to exit
+var LeakCheckTimeout = 2 * time.Second
+
+// VerifyNoLeaks records the running goroutines and, when the test finishes,
+// reports every goroutine started since then that is still running. ignore
+// lists extra function names to allow; a goroutine is ignored when any
+// frame of its stack contains one of them.
+//
+// Register it first so it runs after the test's other cleanups:
+//
+//	testingutil.VerifyNoLeaks(t)
+//	srv := testingutil.NewTestServer(t, nil)
+func VerifyNoLeaks(t TB, ignore ...string) {
+	t.Helper()
+
+	before := concurrency.GoroutineIDs()
+	t.Cleanup(func() {
+		t.Helper()
+		for _, leak := range concurrency.LeakedGoroutines(before, LeakCheckTimeout, ignore...) {
+			t.Errorf("leaked goroutine:\n%s", leak.Stack)
+		}
+	})
+}
+
+// Snapshot returns the IDs of the goroutines running now, for LeakedGoroutines
+func Snapshot() map[int64]bool {
+	return concurrency.GoroutineIDs()
+}
+
+// LeakedGoroutines returns the stacks of goroutines that are not in before,
+// waiting up to timeout for them to exit; see concurrency.LeakedGoroutines
+func LeakedGoroutines(before map[int64]bool, timeout time.Duration, ignore ...string) []string {
+	var stacks []string
+	for _, g := range concurrency.LeakedGoroutines(before, timeout, ignore...) {
+		stacks = append(stacks, g.Stack)
+	}
+	return stacks
+}
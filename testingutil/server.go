@@ -0,0 +1,153 @@
+package testingutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/jerrychou/go-practice/server"
+)
+
+// TestServer is an httptest server bound to a test; requests that fail to
+// complete end the test, and the server is closed during cleanup
+type TestServer struct {
+	*httptest.Server
+	t TB
+}
+
+// NewTestServer serves handler, or the demo routes from server.SetupRoutes
+// when handler is nil
+func NewTestServer(t TB, handler http.Handler) *TestServer {
+	t.Helper()
+
+	if handler == nil {
+		handler = server.SetupRoutes()
+	}
+	ts := &TestServer{Server: httptest.NewServer(handler), t: t}
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// Do sends req, resolving a relative URL against the server
+func (ts *TestServer) Do(req *http.Request) *Response {
+	ts.t.Helper()
+
+	if req.URL.Host == "" {
+		full := ts.URL + req.URL.String()
+		var err error
+		if req.URL, err = req.URL.Parse(full); err != nil {
+			ts.t.Fatalf("%s %s: %v", req.Method, full, err)
+		}
+		req.Host = req.URL.Host
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		ts.t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ts.t.Fatalf("%s %s: read body: %v", req.Method, req.URL, err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, t: ts.t}
+}
+
+// Get requests path from the server
+func (ts *TestServer) Get(path string) *Response {
+	ts.t.Helper()
+	return ts.Do(ts.newRequest(http.MethodGet, path, nil))
+}
+
+// PostForm sends form-encoded values to path
+func (ts *TestServer) PostForm(path string, form url.Values) *Response {
+	ts.t.Helper()
+
+	req := ts.newRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return ts.Do(req)
+}
+
+// PostJSON sends body encoded as JSON to path
+func (ts *TestServer) PostJSON(path string, body any) *Response {
+	ts.t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		ts.t.Fatalf("marshal request body: %v", err)
+	}
+	req := ts.newRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return ts.Do(req)
+}
+
+func (ts *TestServer) newRequest(method, path string, body io.Reader) *http.Request {
+	ts.t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, body)
+	if err != nil {
+		ts.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return req
+}
+
+// Response is a fully read HTTP response with assertion helpers
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	t          TB
+}
+
+// String returns the body as text
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// ExpectStatus reports an error unless the response has the given status
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Errorf("status = %d %s, want %d %s", r.StatusCode, http.StatusText(r.StatusCode), code, http.StatusText(code))
+	}
+	return r
+}
+
+// ExpectHeader reports an error unless header key has the given value
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.t.Errorf("header %s = %q, want %q", key, got, value)
+	}
+	return r
+}
+
+// ExpectContains reports an error unless the body contains substr
+func (r *Response) ExpectContains(substr string) *Response {
+	r.t.Helper()
+	if !bytes.Contains(r.Body, []byte(substr)) {
+		r.t.Errorf("body does not contain %q:\n%s", substr, truncate(r.String(), 500))
+	}
+	return r
+}
+
+// DecodeJSON unmarshals the body into v, failing the test on error
+func (r *Response) DecodeJSON(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("decode JSON response: %v\n%s", err, truncate(r.String(), 500))
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,19 @@
+// Package testingutil provides helpers shared by tests across the modules:
+// a migrated SQLite or PostgreSQL database, transactions rolled back after
+// each test, an httptest server running the demo routes, a fake clock, a
+// goroutine leak detector and golden files.
+package testingutil
+
+// TB is the subset of testing.TB used by the helpers. *testing.T and
+// *testing.B satisfy it; the examples use a recorder so the helpers can be
+// demonstrated outside `go test`.
+type TB interface {
+	Helper()
+	Name() string
+	Logf(format string, args ...any)
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+	Skipf(format string, args ...any)
+	Cleanup(func())
+	TempDir() string
+}
@@ -0,0 +1,93 @@
+package testingutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jerrychou/go-practice/database"
+	"gorm.io/gorm"
+)
+
+var savepointSeq atomic.Int64
+
+// WithTestTransaction runs fn in a transaction on db and rolls it back once
+// fn returns, fails or panics, so tests can write through the repositories
+// against a real database and leave it as they found it. Given a *sql.Tx
+// instead, it runs fn inside a savepoint of that transaction and rolls back
+// to it, which lets a subtest undo its writes while keeping the fixtures of
+// the enclosing one.
+//
+// fn must not commit the handle; the helper reports it if it does.
+func WithTestTransaction(t TB, db database.DBTX, fn func(tx *sql.Tx)) {
+	t.Helper()
+
+	switch db := db.(type) {
+	case *sql.DB:
+		tx, err := db.BeginTx(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("begin test transaction: %v", err)
+		}
+		defer func() {
+			if err := tx.Rollback(); errors.Is(err, sql.ErrTxDone) {
+				t.Errorf("test transaction was committed; its writes were kept")
+			} else if err != nil {
+				t.Errorf("roll back test transaction: %v", err)
+			}
+		}()
+		fn(tx)
+	case *sql.Tx:
+		name := fmt.Sprintf("test_savepoint_%d", savepointSeq.Add(1))
+		if _, err := db.Exec(`SAVEPOINT ` + name); err != nil {
+			t.Fatalf("create savepoint: %v", err)
+		}
+		defer func() {
+			if _, err := db.Exec(`ROLLBACK TO SAVEPOINT ` + name); err != nil {
+				t.Errorf("roll back to savepoint %s: %v", name, err)
+				return
+			}
+			if _, err := db.Exec(`RELEASE SAVEPOINT ` + name); err != nil {
+				t.Errorf("release savepoint %s: %v", name, err)
+			}
+		}()
+		fn(db)
+	default:
+		t.Fatalf("WithTestTransaction needs a *sql.DB or *sql.Tx, got %T", db)
+	}
+}
+
+// WithTestGORMTransaction is WithTestTransaction for GORM: fn gets a session
+// bound to a transaction, or to a savepoint when db is already one, that is
+// rolled back afterwards
+func WithTestGORMTransaction(t TB, db *gorm.DB, fn func(tx *gorm.DB)) {
+	t.Helper()
+
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		name := fmt.Sprintf("test_savepoint_%d", savepointSeq.Add(1))
+		if err := db.SavePoint(name).Error; err != nil {
+			t.Fatalf("create savepoint: %v", err)
+		}
+		defer func() {
+			if err := db.RollbackTo(name).Error; err != nil {
+				t.Errorf("roll back to savepoint %s: %v", name, err)
+			}
+		}()
+		fn(db.Session(&gorm.Session{}))
+		return
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("begin test transaction: %v", tx.Error)
+	}
+	defer func() {
+		if err := tx.Rollback().Error; errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("test transaction was committed; its writes were kept")
+		} else if err != nil {
+			t.Errorf("roll back test transaction: %v", err)
+		}
+	}()
+	fn(tx)
+}
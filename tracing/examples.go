@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PrintSpanTree prints spans indented under their parents, roots first
+func PrintSpanTree(spans []SpanData) {
+	children := map[SpanID][]SpanData{}
+	known := map[SpanID]bool{}
+	for _, s := range spans {
+		known[s.SpanContext.SpanID] = true
+	}
+	var roots []SpanData
+	for _, s := range spans {
+		if known[s.Parent] {
+			children[s.Parent] = append(children[s.Parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	var printSpan func(s SpanData, depth int)
+	printSpan = func(s SpanData, depth int) {
+		status := ""
+		if s.Status.Code == StatusError {
+			status = " ❌ " + s.Status.Description
+		}
+		fmt.Printf("   %s%s [%s, %s] %v%s\n", strings.Repeat("  ", depth), s.Name, s.Service, s.Kind,
+			s.Duration().Round(time.Millisecond), status)
+		// Children end before their parent, so sort them back into start order
+		kids := children[s.SpanContext.SpanID]
+		sort.Slice(kids, func(i, j int) bool { return kids[i].Start.Before(kids[j].Start) })
+		for _, child := range kids {
+			printSpan(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		printSpan(root, 0)
+	}
+}
+
+// SpanExamples builds a small span tree with attributes, events and an error
+func SpanExamples() {
+	fmt.Println("\n=== Spans, Attributes and Events ===")
+	exporter := NewInMemoryExporter()
+	provider := NewProvider(ProviderOptions{ServiceName: "checkout", Exporters: []Exporter{exporter}})
+
+	ctx, root := provider.Start(context.Background(), "checkout", WithAttributes("user.id", 42))
+	for _, item := range []string{"book", "pen"} {
+		_, span := provider.Start(ctx, "reserve_stock", WithAttributes("item", item))
+		time.Sleep(5 * time.Millisecond)
+		span.AddEvent("reserved", "quantity", 1)
+		span.End()
+	}
+	_, payment := provider.Start(ctx, "charge_card", WithKind(KindClient))
+	time.Sleep(10 * time.Millisecond)
+	payment.RecordError(errors.New("card declined"))
+	payment.End()
+	root.SetStatus(StatusError, "payment failed")
+	root.End()
+
+	spans := exporter.Spans()
+	fmt.Printf("🧵 trace %s has %d spans:\n", root.SpanContext().TraceID, len(spans))
+	PrintSpanTree(spans)
+	for _, s := range spans {
+		for _, e := range s.Events {
+			fmt.Printf("   📌 %s event %q %v\n", s.Name, e.Name, e.Attributes)
+		}
+	}
+}
+
+// PropagationExamples follows one trace through two HTTP services
+func PropagationExamples() {
+	fmt.Println("\n=== W3C Trace Context Propagation ===")
+	exporter := NewInMemoryExporter()
+	inventory := NewProvider(ProviderOptions{ServiceName: "inventory", Exporters: []Exporter{exporter}})
+	frontend := NewProvider(ProviderOptions{ServiceName: "frontend", Exporters: []Exporter{exporter}})
+
+	// The downstream service continues whatever trace the caller sends
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stock/{item}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("📨 inventory received traceparent: %s\n", r.Header.Get(TraceparentHeader))
+		_, span := inventory.Start(r.Context(), "lookup_stock")
+		time.Sleep(3 * time.Millisecond)
+		span.End()
+		fmt.Fprintln(w, "3 in stock")
+	})
+	server := httptest.NewServer(inventory.Middleware(mux))
+	defer server.Close()
+
+	client := &http.Client{Transport: frontend.Transport(nil)}
+	ctx, root := frontend.Start(context.Background(), "render_product_page")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stock/book", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	root.End()
+
+	traces := map[TraceID]bool{}
+	for _, s := range exporter.Spans() {
+		traces[s.SpanContext.TraceID] = true
+	}
+	fmt.Printf("🔗 %d spans across 2 services share %d trace ID\n", len(exporter.Spans()), len(traces))
+	PrintSpanTree(exporter.Spans())
+
+	if _, err := ParseTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01"); err != nil {
+		fmt.Printf("🚫 rejected: %v\n", err)
+	}
+}
+
+// OTLPExamples batches spans to a stand-in collector and shows the payload
+func OTLPExamples() {
+	fmt.Println("\n=== OTLP Export (Jaeger, OpenTelemetry Collector) ===")
+	received := make(chan map[string]any, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer collector.Close()
+
+	batcher := NewBatcher(NewOTLPExporter(collector.URL+"/v1/traces"), BatchOptions{FlushEvery: time.Hour})
+	provider := NewProvider(ProviderOptions{ServiceName: "reports", Exporters: []Exporter{batcher}})
+	ctx, root := provider.Start(context.Background(), "nightly_report")
+	for i := 0; i < 3; i++ {
+		_, span := provider.Start(ctx, "render_page", WithAttributes("page", i+1, "cached", i > 0))
+		span.End()
+	}
+	root.End()
+
+	// Shutdown flushes the batch that the hourly timer never would
+	if err := provider.Shutdown(context.Background()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	select {
+	case payload := <-received:
+		data, _ := json.Marshal(payload)
+		fmt.Printf("📦 collector received %d bytes of OTLP JSON in one request\n", len(data))
+		resource := payload["resourceSpans"].([]any)[0].(map[string]any)
+		spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+		first, _ := json.MarshalIndent(spans[0], "   ", "  ")
+		fmt.Printf("   %s\n", first)
+	case <-time.After(time.Second):
+		fmt.Println("❌ collector received nothing")
+	}
+	fmt.Printf("💡 Set OTEL_EXPORTER_OTLP_ENDPOINT=%s to send server traces to Jaeger\n",
+		strings.TrimSuffix(JaegerEndpoint, "/v1/traces"))
+}
+
+// RunAllTracingExamples runs all tracing examples
+func RunAllTracingExamples() {
+	SpanExamples()
+	PropagationExamples()
+	OTLPExamples()
+}
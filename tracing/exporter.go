@@ -0,0 +1,343 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter receives ended spans
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	// Shutdown flushes buffered spans and releases resources
+	Shutdown(ctx context.Context) error
+}
+
+// InMemoryExporter keeps spans for inspection in tests and examples
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans implements Exporter
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Shutdown implements Exporter
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error { return nil }
+
+// Spans returns the exported spans in the order they ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset drops the exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// BatchOptions tunes a Batcher
+type BatchOptions struct {
+	MaxBatch      int           // spans per export, defaults to 512
+	MaxQueue      int           // spans buffered before new ones are dropped, defaults to 2048
+	FlushEvery    time.Duration // defaults to 5s
+	ExportTimeout time.Duration // per export, defaults to 30s
+}
+
+// Batcher buffers spans and exports them in the background, so ending a
+// span never waits on the network
+type Batcher struct {
+	next  Exporter
+	opts  BatchOptions
+	queue chan SpanData
+	flush chan chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewBatcher wraps next and starts its background goroutine
+func NewBatcher(next Exporter, opts BatchOptions) *Batcher {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 512
+	}
+	if opts.MaxQueue <= 0 {
+		opts.MaxQueue = 2048
+	}
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = 5 * time.Second
+	}
+	if opts.ExportTimeout <= 0 {
+		opts.ExportTimeout = 30 * time.Second
+	}
+	b := &Batcher{
+		next:  next,
+		opts:  opts,
+		queue: make(chan SpanData, opts.MaxQueue),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// ExportSpans queues spans, dropping them if the queue is full
+func (b *Batcher) ExportSpans(ctx context.Context, spans []SpanData) error {
+	for _, span := range spans {
+		select {
+		case b.queue <- span:
+		default:
+			logger.Warn("Span queue full, dropping span", "span", span.Name)
+		}
+	}
+	return nil
+}
+
+// ForceFlush exports everything queued so far
+func (b *Batcher) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case b.flush <- flushed:
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown flushes the queue and shuts down the wrapped exporter
+func (b *Batcher) Shutdown(ctx context.Context) error {
+	if err := b.ForceFlush(ctx); err != nil {
+		return err
+	}
+	b.once.Do(func() { close(b.done) })
+	return b.next.Shutdown(ctx)
+}
+
+func (b *Batcher) run() {
+	ticker := time.NewTicker(b.opts.FlushEvery)
+	defer ticker.Stop()
+	batch := make([]SpanData, 0, b.opts.MaxBatch)
+
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.ExportTimeout)
+		defer cancel()
+		if err := b.next.ExportSpans(ctx, batch); err != nil {
+			logger.Warn("Span export failed", "spans", len(batch), "error", err)
+		}
+		batch = make([]SpanData, 0, b.opts.MaxBatch)
+	}
+
+	for {
+		select {
+		case span := <-b.queue:
+			batch = append(batch, span)
+			if len(batch) >= b.opts.MaxBatch {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-b.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-b.queue:
+					batch = append(batch, span)
+					if len(batch) >= b.opts.MaxBatch {
+						export()
+					}
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(flushed)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Default OTLP/HTTP endpoints; Jaeger accepts OTLP on the same port
+const (
+	OTLPEndpoint   = "http://localhost:4318/v1/traces"
+	JaegerEndpoint = OTLPEndpoint
+)
+
+// OTLPExporter sends spans to an OpenTelemetry collector, Jaeger or any
+// other backend that accepts OTLP/HTTP with JSON encoding. Wrap it in a
+// Batcher rather than exporting one span per request.
+type OTLPExporter struct {
+	Endpoint string
+	Headers  map[string]string // e.g. an API key for a hosted backend
+	Client   *http.Client
+}
+
+// NewOTLPExporter posts to endpoint, or OTLPEndpoint if empty
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	if endpoint == "" {
+		endpoint = OTLPEndpoint
+	}
+	return &OTLPExporter{Endpoint: endpoint, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ExportSpans implements Exporter
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracing: export to %s: %w", e.Endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracing: export to %s: %s: %s", e.Endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Shutdown implements Exporter
+func (e *OTLPExporter) Shutdown(ctx context.Context) error { return nil }
+
+// SetupFromEnv installs a default provider for service that batches spans
+// to the collector at $OTEL_EXPORTER_OTLP_ENDPOINT (e.g.
+// http://localhost:4318 for Jaeger). Without the variable spans are still
+// propagated but not exported. Call Shutdown on the result before exiting.
+func SetupFromEnv(service string) *Provider {
+	var exporters []Exporter
+	if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+		endpoint := strings.TrimSuffix(base, "/") + "/v1/traces"
+		exporters = append(exporters, NewBatcher(NewOTLPExporter(endpoint), BatchOptions{}))
+		logger.Info("Exporting traces", "service", service, "endpoint", endpoint)
+	}
+	provider := NewProvider(ProviderOptions{ServiceName: service, Exporters: exporters})
+	SetDefault(provider)
+	return provider
+}
+
+// The OTLP JSON encoding uses lowerCamelCase names, hex IDs and decimal
+// strings for 64-bit integers
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpRequest(spans []SpanData) map[string]any {
+	// Group spans by service, which OTLP carries as a resource attribute
+	byService := map[string][]map[string]any{}
+	var services []string
+	for _, s := range spans {
+		if _, ok := byService[s.Service]; !ok {
+			services = append(services, s.Service)
+		}
+		byService[s.Service] = append(byService[s.Service], otlpSpan(s))
+	}
+
+	resourceSpans := make([]map[string]any, 0, len(services))
+	for _, service := range services {
+		resourceSpans = append(resourceSpans, map[string]any{
+			"resource": map[string]any{"attributes": []otlpKeyValue{otlpAttribute("service.name", service)}},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "github.com/jerrychou/go-practice/tracing"},
+				"spans": byService[service],
+			}},
+		})
+	}
+	return map[string]any{"resourceSpans": resourceSpans}
+}
+
+func otlpSpan(s SpanData) map[string]any {
+	span := map[string]any{
+		"traceId":           s.SpanContext.TraceID.String(),
+		"spanId":            s.SpanContext.SpanID.String(),
+		"name":              s.Name,
+		"kind":              int(s.Kind),
+		"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.Attributes),
+		"status":            map[string]any{"code": int(s.Status.Code), "message": s.Status.Description},
+	}
+	if s.Parent.IsValid() {
+		span["parentSpanId"] = hex.EncodeToString(s.Parent[:])
+	}
+	if s.SpanContext.TraceState != "" {
+		span["traceState"] = s.SpanContext.TraceState
+	}
+	events := make([]map[string]any, 0, len(s.Events))
+	for _, e := range s.Events {
+		events = append(events, map[string]any{
+			"name":         e.Name,
+			"timeUnixNano": strconv.FormatInt(e.Time.UnixNano(), 10),
+			"attributes":   otlpAttributes(e.Attributes),
+		})
+	}
+	span["events"] = events
+	return span
+}
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, otlpAttribute(a.Key, a.Value))
+	}
+	return out
+}
+
+func otlpAttribute(key string, value any) otlpKeyValue {
+	var v map[string]any
+	switch x := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": x}
+	case bool:
+		v = map[string]any{"boolValue": x}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(x)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": x}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(x)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Middleware starts a server span for each request in the Default provider,
+// continuing the caller's trace when a traceparent header is present
+func Middleware(next http.Handler) http.Handler {
+	return middleware(nil, next)
+}
+
+// Middleware starts a server span for each request, continuing the caller's
+// trace when a traceparent header is present. The span is in the request
+// context, so handlers can add events or start children.
+func (p *Provider) Middleware(next http.Handler) http.Handler {
+	return middleware(p, next)
+}
+
+// middleware resolves a nil provider on each request, so SetDefault after
+// the routes are built still takes effect
+func middleware(p *Provider, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider := p
+		if provider == nil {
+			provider = Default()
+		}
+		ctx := Extract(r.Context(), r.Header)
+		ctx, span := provider.Start(ctx, "HTTP "+r.Method,
+			WithKind(KindServer),
+			WithAttributes("http.method", r.Method, "http.target", r.URL.Path, "net.peer.addr", r.RemoteAddr))
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		traced := r.WithContext(ctx)
+		next.ServeHTTP(recorder, traced)
+
+		// A ServeMux records the matched pattern, which names the route
+		// without its parameters
+		if traced.Pattern != "" {
+			name := traced.Pattern
+			if !strings.Contains(name, " ") {
+				name = r.Method + " " + name
+			}
+			span.SetName(name)
+			span.SetAttributes("http.route", traced.Pattern)
+		}
+		span.SetAttributes("http.status_code", recorder.status)
+		if recorder.status >= 500 {
+			span.SetStatus(StatusError, http.StatusText(recorder.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Transport starts a client span for each request using the Default
+// provider and sends its traceparent; a nil next means http.DefaultTransport.
+// Requests must carry a context (http.NewRequestWithContext) for the span
+// to join the caller's trace.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next     http.RoundTripper
+	provider *Provider // nil means Default()
+}
+
+// Transport starts a client span for each request and sends its traceparent
+func (p *Provider) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, provider: p}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := t.provider
+	if provider == nil {
+		provider = Default()
+	}
+	ctx, span := provider.Start(req.Context(), "HTTP "+req.Method,
+		WithKind(KindClient),
+		WithAttributes("http.method", req.Method, "http.url", req.URL.Redacted(), "net.peer.name", req.URL.Host))
+	defer span.End()
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.SetStatus(StatusError, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// WithSpan runs fn inside a span named name, recording its error
+func WithSpan(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...StartOption) error {
+	ctx, span := Start(ctx, name, opts...)
+	defer span.End()
+	if err := fn(ctx); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
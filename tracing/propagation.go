@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// FormatTraceparent renders sc as "00-<trace id>-<span id>-<flags>"
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("tracing: malformed traceparent %q", value)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	// Version ff is forbidden; version 00 has exactly four fields, while
+	// later versions may append more that we ignore
+	if len(version) != 2 || version == "ff" || (version == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("tracing: unsupported traceparent version %q", version)
+	}
+
+	var sc SpanContext
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 ||
+		decodeHex(sc.TraceID[:], traceID) != nil || decodeHex(sc.SpanID[:], spanID) != nil {
+		return SpanContext{}, fmt.Errorf("tracing: malformed traceparent %q", value)
+	}
+	var flagBits [1]byte
+	if decodeHex(flagBits[:], flags) != nil {
+		return SpanContext{}, fmt.Errorf("tracing: malformed traceparent flags %q", flags)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("tracing: traceparent has a zero ID")
+	}
+	sc.Sampled = flagBits[0]&1 == 1
+	sc.Remote = true
+	return sc, nil
+}
+
+func decodeHex(dst []byte, s string) error {
+	// The spec requires lowercase hex
+	if strings.ToLower(s) != s {
+		return fmt.Errorf("uppercase hex")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// Inject writes the span context in ctx to header
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceparentHeader, FormatTraceparent(sc))
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// Extract returns ctx with the remote span context from header as parent.
+// A missing or invalid header leaves ctx unchanged, starting a new trace.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	value := header.Get(TraceparentHeader)
+	if value == "" {
+		return ctx
+	}
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		logger.Debug("Ignoring invalid traceparent", "error", err)
+		return ctx
+	}
+	sc.TraceState = header.Get(TracestateHeader)
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
@@ -0,0 +1,420 @@
+// Package tracing records OpenTelemetry-style spans, propagates them across
+// process boundaries with W3C traceparent headers, and exports them to memory
+// or to an OTLP collector such as Jaeger.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jerrychou/go-practice/format"
+)
+
+var logger = format.GetLogger("tracing")
+
+// TraceID identifies a whole trace across services
+type TraceID [16]byte
+
+// SpanID identifies one span within a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid reports whether the ID is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string // vendor data from the tracestate header, passed through
+	Remote     bool   // extracted from an incoming request
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// SpanKind describes a span's role in a request
+type SpanKind int
+
+const (
+	KindInternal SpanKind = iota + 1
+	KindServer
+	KindClient
+	KindProducer
+	KindConsumer
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	case KindProducer:
+		return "producer"
+	case KindConsumer:
+		return "consumer"
+	}
+	return "internal"
+}
+
+// StatusCode is the outcome of a span
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Status is a span's outcome and, for errors, a description
+type Status struct {
+	Code        StatusCode
+	Description string
+}
+
+// Attribute is a key-value pair attached to a span or event
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Event is a timestamped annotation within a span
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanData is the immutable record of an ended span handed to exporters
+type SpanData struct {
+	Name        string
+	SpanContext SpanContext
+	Parent      SpanID // zero for a root span
+	Kind        SpanKind
+	Start, End  time.Time
+	Attributes  []Attribute
+	Events      []Event
+	Status      Status
+	Service     string
+}
+
+// Duration returns End - Start
+func (d SpanData) Duration() time.Duration { return d.End.Sub(d.Start) }
+
+// Attribute returns the value of the attribute called key
+func (d SpanData) Attribute(key string) (any, bool) {
+	for _, a := range d.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Span is an operation being timed. All methods are safe on a nil span,
+// so SpanFromContext(ctx).AddEvent(...) works without a check, and do
+// nothing once the span has ended.
+type Span struct {
+	provider *Provider
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+}
+
+// SpanContext returns the IDs to propagate to children
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// IsRecording reports whether the span will be exported
+func (s *Span) IsRecording() bool {
+	return s != nil && s.data.SpanContext.Sampled
+}
+
+// SetAttributes adds attributes given as alternating keys and values,
+// replacing existing keys
+func (s *Span) SetAttributes(keyvals ...any) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, a := range toAttributes(keyvals) {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == a.Key {
+				s.data.Attributes[i].Value = a.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, a)
+		}
+	}
+}
+
+// AddEvent records a named point in time with optional attributes
+func (s *Span) AddEvent(name string, keyvals ...any) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Events = append(s.data.Events, Event{Name: name, Time: time.Now(), Attributes: toAttributes(keyvals)})
+}
+
+// RecordError adds an "exception" event and marks the span as failed
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.AddEvent("exception", "exception.type", fmt.Sprintf("%T", err), "exception.message", err.Error())
+	s.SetStatus(StatusError, err.Error())
+}
+
+// SetStatus sets the outcome; an error status is never downgraded to OK
+func (s *Span) SetStatus(code StatusCode, description string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Status.Code == StatusError && code != StatusError {
+		return
+	}
+	s.data.Status = Status{Code: code, Description: description}
+}
+
+// SetName renames the span, e.g. once a route has been matched
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Name = name
+}
+
+// End finishes the span and exports it; later calls do nothing
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.provider.export(data)
+}
+
+func toAttributes(keyvals []any) []Attribute {
+	attrs := make([]Attribute, 0, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value any = "(MISSING)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		attrs = append(attrs, Attribute{Key: key, Value: value})
+	}
+	return attrs
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteSpanContext makes sc, usually extracted from a request,
+// the parent of spans started from the returned context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the current span's context, falling back to
+// a remote parent
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// StartOption configures a new span
+type StartOption func(*SpanData)
+
+// WithKind sets the span kind, KindInternal by default
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+// WithAttributes sets initial attributes as alternating keys and values
+func WithAttributes(keyvals ...any) StartOption {
+	return func(d *SpanData) { d.Attributes = append(d.Attributes, toAttributes(keyvals)...) }
+}
+
+// ProviderOptions configures a Provider
+type ProviderOptions struct {
+	ServiceName string
+	Exporters   []Exporter
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	// Child spans follow their parent's decision. Zero means 1.
+	SampleRatio float64
+}
+
+// Provider creates spans and sends ended spans to its exporters
+type Provider struct {
+	opts ProviderOptions
+}
+
+// NewProvider creates a provider; with no exporters spans are still
+// created and propagated but not exported
+func NewProvider(opts ProviderOptions) *Provider {
+	if opts.ServiceName == "" {
+		opts.ServiceName = "go-practice"
+	}
+	if opts.SampleRatio <= 0 || opts.SampleRatio > 1 {
+		opts.SampleRatio = 1
+	}
+	return &Provider{opts: opts}
+}
+
+// Start begins a span that is a child of the span in ctx, if any, and
+// returns a context carrying it. Callers must End the span.
+func (p *Provider) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{provider: p, data: SpanData{Name: name, Kind: KindInternal, Start: time.Now(), Service: p.opts.ServiceName}}
+	for _, opt := range opts {
+		opt(&span.data)
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		sc.TraceState = parent.TraceState
+		span.data.Parent = parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = p.sample(sc.TraceID)
+	}
+	span.data.SpanContext = sc
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample decides from the trace ID, so every service that sees the trace
+// with the same ratio agrees
+func (p *Provider) sample(id TraceID) bool {
+	if p.opts.SampleRatio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])) < p.opts.SampleRatio*math.MaxUint64
+}
+
+func (p *Provider) export(data SpanData) {
+	for _, exporter := range p.opts.Exporters {
+		if err := exporter.ExportSpans(context.Background(), []SpanData{data}); err != nil {
+			logger.Warn("Span export failed", "span", data.Name, "error", err)
+		}
+	}
+}
+
+// Shutdown flushes and stops the exporters
+func (p *Provider) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, exporter := range p.opts.Exporters {
+		errs = append(errs, exporter.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultProvider = NewProvider(ProviderOptions{})
+)
+
+// SetDefault replaces the provider used by Start and the instrumentation
+// in this package
+func SetDefault(p *Provider) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultProvider = p
+}
+
+// Default returns the provider set with SetDefault
+func Default() *Provider {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultProvider
+}
+
+// Start begins a span with the default provider
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return Default().Start(ctx, name, opts...)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}